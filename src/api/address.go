@@ -5,6 +5,8 @@ import (
 	"net/http"
 
 	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/readable"
+	"github.com/skycoin/skycoin/src/visor"
 )
 
 // VerifyAddressRequest is the request data for POST /api/v2/address/verify
@@ -60,3 +62,58 @@ func addressVerifyHandler(w http.ResponseWriter, r *http.Request) {
 		},
 	})
 }
+
+// addressHistoryHandler returns the transaction history of addresses, with the direction of
+// each transaction, its net coin and hour change, the running balance after it and its confirmations.
+// Unconfirmed transactions are listed first, followed by confirmed transactions, most recent first.
+// Method: GET, POST
+// URI: /api/v2/address/history
+// Args:
+//	addrs: comma-separated list of addresses [required]
+func addressHistoryHandler(gateway Gatewayer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodPost {
+			resp := NewHTTPErrorResponse(http.StatusMethodNotAllowed, "")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		addrs, err := parseAddressesFromStr(r.FormValue("addrs"))
+		if err != nil {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, err.Error())
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		if len(addrs) == 0 {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, "addrs is required")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		histories, err := gateway.GetAddressesHistory(addrs)
+		if err != nil {
+			status := http.StatusInternalServerError
+			switch {
+			case visor.IsErrPruned(err):
+				status = http.StatusGone
+			case visor.IsErrBackfilling(err):
+				status = http.StatusServiceUnavailable
+			}
+			resp := NewHTTPErrorResponse(status, err.Error())
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		rHistories, err := readable.NewAddressHistories(histories)
+		if err != nil {
+			resp := NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		writeHTTPResponse(w, HTTPResponse{
+			Data: rHistories,
+		})
+	}
+}
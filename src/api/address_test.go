@@ -2,12 +2,21 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/readable"
+	"github.com/skycoin/skycoin/src/testutil"
+	"github.com/skycoin/skycoin/src/visor"
+	"github.com/skycoin/skycoin/src/wallet"
 )
 
 func toJSON(t *testing.T, r interface{}) string {
@@ -146,3 +155,154 @@ func TestVerifyAddress(t *testing.T) {
 		})
 	}
 }
+
+func TestAddressHistory(t *testing.T) {
+	addr := testutil.MakeAddress()
+	txn := coin.Transaction{
+		Out: []coin.TransactionOutput{
+			{
+				Address: addr,
+				Coins:   10e6,
+				Hours:   100,
+			},
+		},
+	}
+
+	histories := []visor.AddressHistory{
+		{
+			Address: addr,
+			Entries: []visor.AddressHistoryEntry{
+				{
+					Transaction: visor.Transaction{
+						Transaction: txn,
+						Status:      visor.NewConfirmedTransactionStatus(2, 4),
+						Time:        1500000000,
+					},
+					Direction: visor.TxnDirectionIn,
+					Received:  wallet.Balance{Coins: 10e6, Hours: 100},
+					Balance:   wallet.Balance{Coins: 10e6, Hours: 120},
+				},
+			},
+		},
+	}
+
+	rHistories, err := readable.NewAddressHistories(histories)
+	require.NoError(t, err)
+
+	cases := []struct {
+		name                 string
+		method               string
+		status               int
+		addrs                string
+		gatewayGetHistoryArg []cipher.Address
+		gatewayGetHistoryRet []visor.AddressHistory
+		gatewayGetHistoryErr error
+		httpResponse         HTTPResponse
+	}{
+		{
+			name:         "405",
+			method:       http.MethodDelete,
+			status:       http.StatusMethodNotAllowed,
+			httpResponse: NewHTTPErrorResponse(http.StatusMethodNotAllowed, ""),
+		},
+		{
+			name:         "400 - missing addrs",
+			method:       http.MethodGet,
+			status:       http.StatusBadRequest,
+			httpResponse: NewHTTPErrorResponse(http.StatusBadRequest, "addrs is required"),
+		},
+		{
+			name:         "400 - invalid address",
+			method:       http.MethodGet,
+			status:       http.StatusBadRequest,
+			addrs:        "foo",
+			httpResponse: NewHTTPErrorResponse(http.StatusBadRequest, "address \"foo\" is invalid: Invalid address length"),
+		},
+		{
+			name:                 "500 - gateway error",
+			method:               http.MethodGet,
+			status:               http.StatusInternalServerError,
+			addrs:                addr.String(),
+			gatewayGetHistoryArg: []cipher.Address{addr},
+			gatewayGetHistoryErr: errors.New("GetAddressesHistory failed"),
+			httpResponse:         NewHTTPErrorResponse(http.StatusInternalServerError, "GetAddressesHistory failed"),
+		},
+		{
+			name:                 "200 - GET",
+			method:               http.MethodGet,
+			status:               http.StatusOK,
+			addrs:                addr.String(),
+			gatewayGetHistoryArg: []cipher.Address{addr},
+			gatewayGetHistoryRet: histories,
+			httpResponse: HTTPResponse{
+				Data: rHistories,
+			},
+		},
+		{
+			name:                 "200 - POST",
+			method:               http.MethodPost,
+			status:               http.StatusOK,
+			addrs:                addr.String(),
+			gatewayGetHistoryArg: []cipher.Address{addr},
+			gatewayGetHistoryRet: histories,
+			httpResponse: HTTPResponse{
+				Data: rHistories,
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			gateway := &MockGatewayer{}
+			gateway.On("GetAddressesHistory", tc.gatewayGetHistoryArg).Return(tc.gatewayGetHistoryRet, tc.gatewayGetHistoryErr)
+
+			endpoint := "/api/v2/address/history"
+
+			v := url.Values{}
+			if tc.addrs != "" {
+				v.Add("addrs", tc.addrs)
+			}
+
+			var req *http.Request
+			var err error
+			if tc.method == http.MethodPost {
+				req, err = http.NewRequest(tc.method, endpoint, strings.NewReader(v.Encode()))
+				require.NoError(t, err)
+				req.Header.Set("Content-Type", ContentTypeForm)
+			} else {
+				if len(v) > 0 {
+					endpoint += "?" + v.Encode()
+				}
+				req, err = http.NewRequest(tc.method, endpoint, nil)
+				require.NoError(t, err)
+			}
+
+			setCSRFParameters(t, tokenValid, req)
+
+			rr := httptest.NewRecorder()
+			handler := newServerMux(defaultMuxConfig(), gateway)
+			handler.ServeHTTP(rr, req)
+
+			status := rr.Code
+			require.Equal(t, tc.status, status, "got `%v` want `%v`", status, tc.status)
+
+			var rsp ReceivedHTTPResponse
+			err = json.NewDecoder(rr.Body).Decode(&rsp)
+			require.NoError(t, err)
+
+			require.Equal(t, tc.httpResponse.Error, rsp.Error)
+
+			if rsp.Data == nil {
+				require.Nil(t, tc.httpResponse.Data)
+			} else {
+				require.NotNil(t, tc.httpResponse.Data)
+
+				var historyRsp []readable.AddressHistory
+				err := json.Unmarshal(rsp.Data, &historyRsp)
+				require.NoError(t, err)
+
+				require.Equal(t, tc.httpResponse.Data.([]readable.AddressHistory), historyRsp)
+			}
+		})
+	}
+}
@@ -5,6 +5,7 @@ package api
 import (
 	"errors"
 	"fmt"
+	"math"
 	"net/http"
 	"strconv"
 	"strings"
@@ -84,6 +85,43 @@ func parseBoolFlag(v string) (bool, error) {
 	return strconv.ParseBool(v)
 }
 
+// parseTimeRange parses the start_time and end_time unix timestamp parameters.
+// start_time defaults to 0 and end_time defaults to the maximum time.
+// Returns false if neither parameter is set.
+func parseTimeRange(r *http.Request) (uint64, uint64, bool, error) {
+	sStart := r.FormValue("start_time")
+	sEnd := r.FormValue("end_time")
+
+	if sStart == "" && sEnd == "" {
+		return 0, 0, false, nil
+	}
+
+	var start uint64
+	end := uint64(math.MaxUint64)
+
+	if sStart != "" {
+		var err error
+		start, err = strconv.ParseUint(sStart, 10, 64)
+		if err != nil {
+			return 0, 0, false, fmt.Errorf("Invalid start_time value %q", sStart)
+		}
+	}
+
+	if sEnd != "" {
+		var err error
+		end, err = strconv.ParseUint(sEnd, 10, 64)
+		if err != nil {
+			return 0, 0, false, fmt.Errorf("Invalid end_time value %q", sEnd)
+		}
+	}
+
+	if start > end {
+		return 0, 0, false, errors.New("start_time must not be after end_time")
+	}
+
+	return start, end, true, nil
+}
+
 // blockHandler returns a block by hash or seq
 // Method: GET
 // URI: /api/v1/block
@@ -148,7 +186,7 @@ func blockHandler(gateway Gatewayer) http.HandlerFunc {
 			}
 
 			if err != nil {
-				wh.Error500(w, err.Error())
+				errorUnavailableOr500(w, err)
 				return
 			}
 
@@ -176,7 +214,7 @@ func blockHandler(gateway Gatewayer) http.HandlerFunc {
 		}
 
 		if err != nil {
-			wh.Error500(w, err.Error())
+			errorUnavailableOr500(w, err)
 			return
 		}
 
@@ -292,7 +330,7 @@ func blocksHandler(gateway Gatewayer) http.HandlerFunc {
 				case visor.ErrBlockNotExist:
 					wh.Error404(w, err.Error())
 				default:
-					wh.Error500(w, err.Error())
+					errorUnavailableOr500(w, err)
 				}
 				return
 			}
@@ -319,7 +357,7 @@ func blocksHandler(gateway Gatewayer) http.HandlerFunc {
 				case visor.ErrBlockNotExist:
 					wh.Error404(w, err.Error())
 				default:
-					wh.Error500(w, err.Error())
+					errorUnavailableOr500(w, err)
 				}
 				return
 			}
@@ -335,6 +373,78 @@ func blocksHandler(gateway Gatewayer) http.HandlerFunc {
 	}
 }
 
+// blocksHandlerV2 returns the blocks with a time between start_time and end_time,
+// including both start_time and end_time.
+// Method: GET
+// URI: /api/v2/blocks
+// Args:
+//	start_time [int] unix timestamp, defaults to 0
+//	end_time [int] unix timestamp, defaults to the maximum time
+//	verbose [bool]
+//	Note: at least one of start_time or end_time is required
+func blocksHandlerV2(gateway Gatewayer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			resp := NewHTTPErrorResponse(http.StatusMethodNotAllowed, "")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		verbose, err := parseBoolFlag(r.FormValue("verbose"))
+		if err != nil {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, "Invalid value for verbose")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		start, end, ok, err := parseTimeRange(r)
+		if err != nil {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, err.Error())
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		if !ok {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, "At least one of start_time or end_time is required")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		var data interface{}
+		if verbose {
+			var blocks []coin.SignedBlock
+			var inputs [][][]visor.TransactionInput
+			blocks, inputs, err = gateway.GetBlocksInTimeRangeVerbose(start, end)
+			if err == nil {
+				data, err = readable.NewBlocksVerbose(blocks, inputs)
+			}
+		} else {
+			var blocks []coin.SignedBlock
+			blocks, err = gateway.GetBlocksInTimeRange(start, end)
+			if err == nil {
+				data, err = readable.NewBlocks(blocks)
+			}
+		}
+
+		if err != nil {
+			status := http.StatusInternalServerError
+			switch {
+			case visor.IsErrPruned(err):
+				status = http.StatusGone
+			case visor.IsErrBackfilling(err):
+				status = http.StatusServiceUnavailable
+			}
+			resp := NewHTTPErrorResponse(status, err.Error())
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		writeHTTPResponse(w, HTTPResponse{
+			Data: data,
+		})
+	}
+}
+
 // lastBlocksHandler returns the most recent N blocks on the blockchain
 // Method: GET
 // URI: /api/v1/last_blocks
@@ -364,7 +474,7 @@ func lastBlocksHandler(gateway Gatewayer) http.HandlerFunc {
 		if verbose {
 			blocks, inputs, err := gateway.GetLastBlocksVerbose(n)
 			if err != nil {
-				wh.Error500(w, err.Error())
+				errorUnavailableOr500(w, err)
 				return
 			}
 
@@ -380,7 +490,7 @@ func lastBlocksHandler(gateway Gatewayer) http.HandlerFunc {
 
 		blocks, err := gateway.GetLastBlocks(n)
 		if err != nil {
-			wh.Error500(w, err.Error())
+			errorUnavailableOr500(w, err)
 			return
 		}
 
@@ -393,3 +503,19 @@ func lastBlocksHandler(gateway Gatewayer) http.HandlerFunc {
 		wh.SendJSONOr500(logger, w, rb)
 	}
 }
+
+// errorUnavailableOr500 responds with a 410 error if err was caused by requesting data that a pruned node
+// does not keep, with a 503 error if err was caused by requesting data that a node started from a snapshot
+// has not downloaded yet, otherwise responds with a 500 error
+func errorUnavailableOr500(w http.ResponseWriter, err error) {
+	switch {
+	case visor.IsErrPruned(err):
+		wh.Error410(w, err.Error())
+		return
+	case visor.IsErrBackfilling(err):
+		wh.Error503(w, err.Error())
+		return
+	}
+
+	wh.Error500(w, err.Error())
+}
@@ -164,6 +164,23 @@ func TestGetBlockchainProgress(t *testing.T) {
 				},
 				Current: 99,
 				Highest: 102,
+				Sync: daemon.SyncProgress{
+					RequestsInFlight: 2,
+					BlocksInFlight:   40,
+					BlocksBuffered:   15,
+					Timeouts:         1,
+					Peers: []daemon.PeerSyncProgress{
+						{
+							Address:          addr1.String(),
+							RequestsInFlight: 2,
+							BlocksReceived:   35,
+						},
+						{
+							Address:  addr2.String(),
+							Timeouts: 1,
+						},
+					},
+				},
 			},
 			result: readable.BlockchainProgress{
 				Peers: []readable.PeerBlockchainHeight{
@@ -178,6 +195,23 @@ func TestGetBlockchainProgress(t *testing.T) {
 				},
 				Current: 99,
 				Highest: 102,
+				Sync: readable.SyncProgress{
+					RequestsInFlight: 2,
+					BlocksInFlight:   40,
+					BlocksBuffered:   15,
+					Timeouts:         1,
+					Peers: []readable.PeerSyncProgress{
+						{
+							Address:          addr1.String(),
+							RequestsInFlight: 2,
+							BlocksReceived:   35,
+						},
+						{
+							Address:  addr2.String(),
+							Timeouts: 1,
+						},
+					},
+				},
 			},
 		},
 	}
@@ -1220,3 +1254,239 @@ func TestGetLastBlocks(t *testing.T) {
 		})
 	}
 }
+
+func TestGetBlocksV2(t *testing.T) {
+	type httpBody struct {
+		StartTime string
+		EndTime   string
+		Verbose   string
+	}
+
+	type verboseResult struct {
+		Blocks []coin.SignedBlock
+		Inputs [][][]visor.TransactionInput
+	}
+
+	rBlocks, err := readable.NewBlocks([]coin.SignedBlock{{}})
+	require.NoError(t, err)
+
+	rBlocksVerbose, err := readable.NewBlocksVerbose([]coin.SignedBlock{{}}, [][][]visor.TransactionInput{{}})
+	require.NoError(t, err)
+
+	tt := []struct {
+		name                                     string
+		method                                   string
+		status                                   int
+		body                                     httpBody
+		start                                    uint64
+		end                                      uint64
+		verbose                                  bool
+		gatewayGetBlocksInTimeRangeResult        []coin.SignedBlock
+		gatewayGetBlocksInTimeRangeError         error
+		gatewayGetBlocksInTimeRangeVerboseResult verboseResult
+		gatewayGetBlocksInTimeRangeVerboseError  error
+		httpResponse                             HTTPResponse
+	}{
+		{
+			name:         "405",
+			method:       http.MethodPost,
+			status:       http.StatusMethodNotAllowed,
+			httpResponse: NewHTTPErrorResponse(http.StatusMethodNotAllowed, ""),
+		},
+		{
+			name:         "400 - no time range",
+			method:       http.MethodGet,
+			status:       http.StatusBadRequest,
+			httpResponse: NewHTTPErrorResponse(http.StatusBadRequest, "At least one of start_time or end_time is required"),
+		},
+		{
+			name:   "400 - bad start_time",
+			method: http.MethodGet,
+			status: http.StatusBadRequest,
+			body: httpBody{
+				StartTime: "foo",
+			},
+			httpResponse: NewHTTPErrorResponse(http.StatusBadRequest, "Invalid start_time value \"foo\""),
+		},
+		{
+			name:   "400 - bad end_time",
+			method: http.MethodGet,
+			status: http.StatusBadRequest,
+			body: httpBody{
+				EndTime: "-1",
+			},
+			httpResponse: NewHTTPErrorResponse(http.StatusBadRequest, "Invalid end_time value \"-1\""),
+		},
+		{
+			name:   "400 - start_time after end_time",
+			method: http.MethodGet,
+			status: http.StatusBadRequest,
+			body: httpBody{
+				StartTime: "2",
+				EndTime:   "1",
+			},
+			httpResponse: NewHTTPErrorResponse(http.StatusBadRequest, "start_time must not be after end_time"),
+		},
+		{
+			name:   "400 - bad verbose",
+			method: http.MethodGet,
+			status: http.StatusBadRequest,
+			body: httpBody{
+				StartTime: "1",
+				Verbose:   "foo",
+			},
+			httpResponse: NewHTTPErrorResponse(http.StatusBadRequest, "Invalid value for verbose"),
+		},
+		{
+			name:   "500 - gatewayGetBlocksInTimeRangeError",
+			method: http.MethodGet,
+			status: http.StatusInternalServerError,
+			body: httpBody{
+				StartTime: "1",
+				EndTime:   "2",
+			},
+			start:                            1,
+			end:                              2,
+			gatewayGetBlocksInTimeRangeError: errors.New("gatewayGetBlocksInTimeRangeError"),
+			httpResponse:                     NewHTTPErrorResponse(http.StatusInternalServerError, "gatewayGetBlocksInTimeRangeError"),
+		},
+		{
+			name:   "410 - pruned",
+			method: http.MethodGet,
+			status: http.StatusGone,
+			body: httpBody{
+				StartTime: "1",
+				EndTime:   "2",
+			},
+			start:                            1,
+			end:                              2,
+			gatewayGetBlocksInTimeRangeError: visor.ErrHistoryPruned,
+			httpResponse:                     NewHTTPErrorResponse(http.StatusGone, visor.ErrHistoryPruned.Error()),
+		},
+		{
+			name:   "500 - gatewayGetBlocksInTimeRangeVerboseError",
+			method: http.MethodGet,
+			status: http.StatusInternalServerError,
+			body: httpBody{
+				StartTime: "1",
+				EndTime:   "2",
+				Verbose:   "1",
+			},
+			start:                                   1,
+			end:                                     2,
+			verbose:                                 true,
+			gatewayGetBlocksInTimeRangeVerboseError: errors.New("gatewayGetBlocksInTimeRangeVerboseError"),
+			httpResponse:                            NewHTTPErrorResponse(http.StatusInternalServerError, "gatewayGetBlocksInTimeRangeVerboseError"),
+		},
+		{
+			name:   "200 - start_time only",
+			method: http.MethodGet,
+			status: http.StatusOK,
+			body: httpBody{
+				StartTime: "1",
+			},
+			start:                             1,
+			end:                               math.MaxUint64,
+			gatewayGetBlocksInTimeRangeResult: []coin.SignedBlock{{}},
+			httpResponse: HTTPResponse{
+				Data: rBlocks,
+			},
+		},
+		{
+			name:   "200 - end_time only",
+			method: http.MethodGet,
+			status: http.StatusOK,
+			body: httpBody{
+				EndTime: "2",
+			},
+			start:                             0,
+			end:                               2,
+			gatewayGetBlocksInTimeRangeResult: []coin.SignedBlock{{}},
+			httpResponse: HTTPResponse{
+				Data: rBlocks,
+			},
+		},
+		{
+			name:   "200 - verbose",
+			method: http.MethodGet,
+			status: http.StatusOK,
+			body: httpBody{
+				StartTime: "1",
+				EndTime:   "2",
+				Verbose:   "1",
+			},
+			start:   1,
+			end:     2,
+			verbose: true,
+			gatewayGetBlocksInTimeRangeVerboseResult: verboseResult{
+				Blocks: []coin.SignedBlock{{}},
+				Inputs: [][][]visor.TransactionInput{{}},
+			},
+			httpResponse: HTTPResponse{
+				Data: rBlocksVerbose,
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			endpoint := "/api/v2/blocks"
+			gateway := &MockGatewayer{}
+
+			gateway.On("GetBlocksInTimeRange", tc.start, tc.end).Return(tc.gatewayGetBlocksInTimeRangeResult, tc.gatewayGetBlocksInTimeRangeError)
+			gateway.On("GetBlocksInTimeRangeVerbose", tc.start, tc.end).Return(tc.gatewayGetBlocksInTimeRangeVerboseResult.Blocks,
+				tc.gatewayGetBlocksInTimeRangeVerboseResult.Inputs, tc.gatewayGetBlocksInTimeRangeVerboseError)
+
+			v := url.Values{}
+			if tc.body.StartTime != "" {
+				v.Add("start_time", tc.body.StartTime)
+			}
+			if tc.body.EndTime != "" {
+				v.Add("end_time", tc.body.EndTime)
+			}
+			if tc.body.Verbose != "" {
+				v.Add("verbose", tc.body.Verbose)
+			}
+			if len(v) > 0 {
+				endpoint += "?" + v.Encode()
+			}
+
+			req, err := http.NewRequest(tc.method, endpoint, nil)
+			require.NoError(t, err)
+
+			setCSRFParameters(t, tokenValid, req)
+
+			rr := httptest.NewRecorder()
+			handler := newServerMux(defaultMuxConfig(), gateway)
+			handler.ServeHTTP(rr, req)
+
+			status := rr.Code
+			require.Equal(t, tc.status, status, "got `%v` want `%v`", status, tc.status)
+
+			var rsp ReceivedHTTPResponse
+			err = json.NewDecoder(rr.Body).Decode(&rsp)
+			require.NoError(t, err)
+
+			require.Equal(t, tc.httpResponse.Error, rsp.Error)
+
+			if rsp.Data == nil {
+				require.Nil(t, tc.httpResponse.Data)
+				return
+			}
+
+			require.NotNil(t, tc.httpResponse.Data)
+
+			if tc.verbose {
+				var msg *readable.BlocksVerbose
+				err = json.Unmarshal(rsp.Data, &msg)
+				require.NoError(t, err)
+				require.Equal(t, tc.httpResponse.Data, msg)
+			} else {
+				var msg *readable.Blocks
+				err = json.Unmarshal(rsp.Data, &msg)
+				require.NoError(t, err)
+				require.Equal(t, tc.httpResponse.Data, msg)
+			}
+		})
+	}
+}
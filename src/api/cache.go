@@ -0,0 +1,477 @@
+package api
+
+import (
+	"bytes"
+	"container/list"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/skycoin/skycoin/src/cipher"
+)
+
+const (
+	// immutableDepth is the number of blocks on top of a block after which responses that only depend on
+	// the block may be cached by clients without revalidation
+	immutableDepth = 10
+	// immutableMaxAge is the Cache-Control max-age of responses that only depend on a block at least immutableDepth deep
+	immutableMaxAge = 24 * 60 * 60
+)
+
+var (
+	cacheHits = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "skycoin",
+		Subsystem: "api_cache",
+		Name:      "hits_total",
+		Help:      "Number of API responses served from the response cache",
+	})
+	cacheMisses = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "skycoin",
+		Subsystem: "api_cache",
+		Name:      "misses_total",
+		Help:      "Number of cacheable API requests that were not in the response cache",
+	})
+	cacheNotModified = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "skycoin",
+		Subsystem: "api_cache",
+		Name:      "not_modified_total",
+		Help:      "Number of conditional API requests answered with 304 Not Modified",
+	})
+	cacheEvictions = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "skycoin",
+		Subsystem: "api_cache",
+		Name:      "evictions_total",
+		Help:      "Number of responses evicted from the response cache because it was full",
+	})
+	cacheInvalidations = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "skycoin",
+		Subsystem: "api_cache",
+		Name:      "invalidations_total",
+		Help:      "Number of responses removed from the response cache because the blockchain head changed",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(cacheHits, cacheMisses, cacheNotModified, cacheEvictions, cacheInvalidations)
+}
+
+// cacheTag identifies the block that a response depends on
+type cacheTag struct {
+	Seq  uint64
+	Hash cipher.SHA256
+	// DepthDependent is true if the response changes with the depth of the block,
+	// such as the confirmations of a transaction
+	DepthDependent bool
+	// Depth is the number of blocks on top of the block when the response was created. Only set if DepthDependent
+	Depth uint64
+}
+
+// ETag returns the weak entity tag of responses with the cacheTag.
+// Weak tags are used because the gzip middleware may change the encoding of the response.
+func (t cacheTag) ETag() string {
+	if t.DepthDependent {
+		return fmt.Sprintf(`W/"%s-%d"`, t.Hash.Hex(), t.Depth)
+	}
+	return fmt.Sprintf(`W/"%s"`, t.Hash.Hex())
+}
+
+// cacheTagFunc returns the cacheTag of a successful response body.
+// ok is false if the response must not be cached, because it can change without the head changing,
+// such as an unconfirmed transaction.
+type cacheTagFunc func(gateway Gatewayer, body []byte) (tag cacheTag, ok bool, err error)
+
+type cacheEntry struct {
+	key         string
+	tag         cacheTag
+	contentType string
+	body        []byte
+}
+
+// cacheHead is the blockchain head that the cache entries were created at
+type cacheHead struct {
+	seq  uint64
+	hash cipher.SHA256
+	ok   bool
+}
+
+// responseCache is an LRU cache of encoded responses that depend on confirmed blocks.
+// When the head changes, the responses that depend on the depth of a block are removed.
+// If the previous head is no longer in the blockchain, all responses are removed.
+type responseCache struct {
+	sync.Mutex
+	gateway Gatewayer
+	size    int
+	lru     *list.List
+	entries map[string]*list.Element
+	head    cacheHead
+}
+
+// newResponseCache creates a responseCache that holds up to size responses
+func newResponseCache(gateway Gatewayer, size int) *responseCache {
+	return &responseCache{
+		gateway: gateway,
+		size:    size,
+		lru:     list.New(),
+		entries: make(map[string]*list.Element, size),
+	}
+}
+
+// get returns a cached response and marks it as recently used
+func (c *responseCache) get(key string) *cacheEntry {
+	c.Lock()
+	defer c.Unlock()
+
+	e, ok := c.entries[key]
+	if !ok {
+		return nil
+	}
+
+	c.lru.MoveToFront(e)
+	return e.Value.(*cacheEntry)
+}
+
+// add adds a response created at head to the cache, evicting the least recently used response if the cache is full.
+// The response is not added if the head changed while it was created.
+func (c *responseCache) add(entry *cacheEntry, head cacheHead) {
+	c.Lock()
+	defer c.Unlock()
+
+	if c.head != head {
+		return
+	}
+
+	if e, ok := c.entries[entry.key]; ok {
+		e.Value = entry
+		c.lru.MoveToFront(e)
+		return
+	}
+
+	c.entries[entry.key] = c.lru.PushFront(entry)
+
+	for c.lru.Len() > c.size {
+		c.remove(c.lru.Back())
+		cacheEvictions.Inc()
+	}
+}
+
+func (c *responseCache) remove(e *list.Element) {
+	c.lru.Remove(e)
+	delete(c.entries, e.Value.(*cacheEntry).key)
+}
+
+// len returns the number of cached responses
+func (c *responseCache) len() int {
+	c.Lock()
+	defer c.Unlock()
+	return c.lru.Len()
+}
+
+// updateHead reads the blockchain head and invalidates cache entries if it has changed
+func (c *responseCache) updateHead() (cacheHead, error) {
+	seq, ok, err := c.gateway.HeadBkSeq()
+	if err != nil {
+		return cacheHead{}, err
+	}
+
+	head := cacheHead{
+		seq: seq,
+		ok:  ok,
+	}
+
+	if ok {
+		b, err := c.gateway.GetSignedBlockBySeq(seq)
+		if err != nil {
+			return cacheHead{}, err
+		}
+		if b == nil {
+			return cacheHead{}, fmt.Errorf("head block %d not found", seq)
+		}
+		head.hash = b.HashHeader()
+	}
+
+	c.Lock()
+	prevHead := c.head
+	c.Unlock()
+
+	if head == prevHead {
+		return head, nil
+	}
+
+	// If the previous head is still in the blockchain, new blocks were added on top of it
+	// and only responses that depend on the depth of a block change. Otherwise, the blockchain was reorganized.
+	reorg := false
+	if prevHead.ok {
+		if !ok || prevHead.seq > seq {
+			reorg = true
+		} else {
+			b, err := c.gateway.GetSignedBlockBySeq(prevHead.seq)
+			if err != nil {
+				return cacheHead{}, err
+			}
+			reorg = b == nil || b.HashHeader() != prevHead.hash
+		}
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	// Another request may have updated the head in the meantime
+	if c.head != prevHead {
+		return head, nil
+	}
+
+	if reorg {
+		logger.Infof("Blockchain head %d %s is no longer in the blockchain, clearing the API response cache", prevHead.seq, prevHead.hash.Hex())
+	}
+
+	for e := c.lru.Front(); e != nil; {
+		next := e.Next()
+		if reorg || e.Value.(*cacheEntry).tag.DepthDependent {
+			c.remove(e)
+			cacheInvalidations.Inc()
+		}
+		e = next
+	}
+
+	c.head = head
+
+	return head, nil
+}
+
+// middleware caches the successful GET responses of handler. tagFunc identifies the block a response depends on.
+// Responses include ETag and Cache-Control headers, and conditional requests are answered with 304 Not Modified.
+func (c *responseCache) middleware(tagFunc cacheTagFunc, handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			handler.ServeHTTP(w, r)
+			return
+		}
+
+		head, err := c.updateHead()
+		if err != nil {
+			logger.WithError(err).Error("responseCache.updateHead failed")
+		}
+		if err != nil || !head.ok {
+			handler.ServeHTTP(w, r)
+			return
+		}
+
+		key := cacheKey(r)
+		if entry := c.get(key); entry != nil {
+			cacheHits.Inc()
+			writeCachedResponse(w, r, entry, head.seq)
+			return
+		}
+
+		cacheMisses.Inc()
+
+		rw := newBufferedResponseWriter()
+		handler.ServeHTTP(rw, r)
+
+		if rw.status != http.StatusOK {
+			rw.writeTo(w)
+			return
+		}
+
+		tag, ok, err := tagFunc(c.gateway, rw.body.Bytes())
+		if err != nil {
+			logger.WithError(err).Error("responseCache: failed to tag response")
+		}
+		if err != nil || !ok {
+			rw.writeTo(w)
+			return
+		}
+
+		entry := &cacheEntry{
+			key:         key,
+			tag:         tag,
+			contentType: rw.Header().Get("Content-Type"),
+			body:        rw.body.Bytes(),
+		}
+
+		c.add(entry, head)
+
+		writeCachedResponse(w, r, entry, head.seq)
+	})
+}
+
+// cacheKey returns the cache key of a request. The query parameters are sorted by url.Values.Encode
+func cacheKey(r *http.Request) string {
+	return r.URL.Path + "?" + r.URL.Query().Encode()
+}
+
+// writeCachedResponse writes a cached response, or 304 Not Modified if the request's If-None-Match matches its ETag
+func writeCachedResponse(w http.ResponseWriter, r *http.Request, entry *cacheEntry, headSeq uint64) {
+	etag := entry.tag.ETag()
+
+	w.Header().Set("ETag", etag)
+
+	var depth uint64
+	if headSeq > entry.tag.Seq {
+		depth = headSeq - entry.tag.Seq
+	}
+
+	if !entry.tag.DepthDependent && depth >= immutableDepth {
+		w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", immutableMaxAge))
+	} else {
+		w.Header().Set("Cache-Control", "no-cache")
+	}
+
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		cacheNotModified.Inc()
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", entry.contentType)
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(entry.body); err != nil {
+		logger.WithError(err).Error("http Write failed")
+	}
+}
+
+// etagMatches returns true if an If-None-Match header value matches etag, using the weak comparison
+func etagMatches(ifNoneMatch, etag string) bool {
+	if ifNoneMatch == "" {
+		return false
+	}
+
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, t := range strings.Split(ifNoneMatch, ",") {
+		t = strings.TrimSpace(t)
+		if t == "*" || strings.TrimPrefix(t, "W/") == etag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// bufferedResponseWriter is an http.ResponseWriter that buffers the response
+type bufferedResponseWriter struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func newBufferedResponseWriter() *bufferedResponseWriter {
+	return &bufferedResponseWriter{
+		header: make(http.Header),
+	}
+}
+
+func (rw *bufferedResponseWriter) Header() http.Header {
+	return rw.header
+}
+
+func (rw *bufferedResponseWriter) WriteHeader(status int) {
+	if rw.status == 0 {
+		rw.status = status
+	}
+}
+
+func (rw *bufferedResponseWriter) Write(b []byte) (int, error) {
+	rw.WriteHeader(http.StatusOK)
+	return rw.body.Write(b)
+}
+
+// writeTo writes the buffered response to w
+func (rw *bufferedResponseWriter) writeTo(w http.ResponseWriter) {
+	for k, v := range rw.header {
+		w.Header()[k] = v
+	}
+
+	status := rw.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	w.WriteHeader(status)
+
+	if _, err := w.Write(rw.body.Bytes()); err != nil {
+		logger.WithError(err).Error("http Write failed")
+	}
+}
+
+// blockCacheTagBySeq returns the cacheTag of the block with seq, looking up its hash
+func blockCacheTagBySeq(gateway Gatewayer, seq uint64, depthDependent bool, depth uint64) (cacheTag, bool, error) {
+	b, err := gateway.GetSignedBlockBySeq(seq)
+	if err != nil {
+		return cacheTag{}, false, err
+	}
+	if b == nil {
+		return cacheTag{}, false, nil
+	}
+
+	return cacheTag{
+		Seq:            seq,
+		Hash:           b.HashHeader(),
+		DepthDependent: depthDependent,
+		Depth:          depth,
+	}, true, nil
+}
+
+// blockCacheTag tags /api/v1/block responses, which only depend on the block
+func blockCacheTag(gateway Gatewayer, body []byte) (cacheTag, bool, error) {
+	var b struct {
+		Head struct {
+			BkSeq uint64 `json:"seq"`
+			Hash  string `json:"block_hash"`
+		} `json:"header"`
+	}
+	if err := json.Unmarshal(body, &b); err != nil {
+		return cacheTag{}, false, err
+	}
+
+	hash, err := cipher.SHA256FromHex(b.Head.Hash)
+	if err != nil {
+		return cacheTag{}, false, err
+	}
+
+	return cacheTag{
+		Seq:  b.Head.BkSeq,
+		Hash: hash,
+	}, true, nil
+}
+
+// transactionCacheTag tags /api/v1/transaction responses. Only confirmed transactions are cached,
+// and their responses depend on the depth of their block, which is included in the status
+func transactionCacheTag(gateway Gatewayer, body []byte) (cacheTag, bool, error) {
+	var txn struct {
+		Status struct {
+			Confirmed bool   `json:"confirmed"`
+			Height    uint64 `json:"height"`
+			BlockSeq  uint64 `json:"block_seq"`
+		} `json:"status"`
+	}
+	if err := json.Unmarshal(body, &txn); err != nil {
+		return cacheTag{}, false, err
+	}
+
+	if !txn.Status.Confirmed || txn.Status.Height == 0 {
+		return cacheTag{}, false, nil
+	}
+
+	return blockCacheTagBySeq(gateway, txn.Status.BlockSeq, true, txn.Status.Height-1)
+}
+
+// uxOutCacheTag tags /api/v1/uxout responses. Only spent outputs are cached, since an unspent output
+// changes when it is spent. The response only depends on the block that spent the output.
+func uxOutCacheTag(gateway Gatewayer, body []byte) (cacheTag, bool, error) {
+	var ux struct {
+		SpentBlockSeq uint64 `json:"spent_block_seq"`
+	}
+	if err := json.Unmarshal(body, &ux); err != nil {
+		return cacheTag{}, false, err
+	}
+
+	// The genesis block has no inputs, so a zero spent_block_seq means the output is unspent
+	if ux.SpentBlockSeq == 0 {
+		return cacheTag{}, false, nil
+	}
+
+	return blockCacheTagBySeq(gateway, ux.SpentBlockSeq, false, 0)
+}
@@ -0,0 +1,269 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/coin"
+)
+
+// testChain is a blockchain that the MockGatewayer head and block queries read from
+type testChain struct {
+	blocks []coin.SignedBlock
+}
+
+func newTestChain(n int) *testChain {
+	c := &testChain{}
+	c.extend(n)
+	return c
+}
+
+func (c *testChain) extend(n int) {
+	for i := 0; i < n; i++ {
+		c.blocks = append(c.blocks, coin.SignedBlock{
+			Block: coin.Block{
+				Head: coin.BlockHeader{
+					BkSeq: uint64(len(c.blocks)),
+					Time:  1500000000,
+				},
+			},
+		})
+	}
+}
+
+// reorg replaces the blocks after seq with n new blocks
+func (c *testChain) reorg(seq uint64, n int) {
+	c.blocks = c.blocks[:seq+1]
+	for i := 0; i < n; i++ {
+		c.blocks = append(c.blocks, coin.SignedBlock{
+			Block: coin.Block{
+				Head: coin.BlockHeader{
+					BkSeq: uint64(len(c.blocks)),
+					Time:  1600000000,
+				},
+			},
+		})
+	}
+}
+
+func (c *testChain) block(seq uint64) *coin.SignedBlock {
+	if seq >= uint64(len(c.blocks)) {
+		return nil
+	}
+	return &c.blocks[seq]
+}
+
+func (c *testChain) mock(gateway *MockGatewayer) {
+	gateway.On("HeadBkSeq").Return(func() uint64 {
+		return uint64(len(c.blocks) - 1)
+	}, func() bool {
+		return len(c.blocks) > 0
+	}, nil)
+	gateway.On("GetSignedBlockBySeq", mock.Anything).Return(c.block, nil)
+}
+
+func TestResponseCacheBlockHandler(t *testing.T) {
+	chain := newTestChain(20)
+	gateway := &MockGatewayer{}
+	chain.mock(gateway)
+
+	mc := defaultMuxConfig()
+	mc.responseCacheSize = 10
+	handler := newServerMux(mc, gateway)
+
+	get := func(seq uint64, ifNoneMatch string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("/api/v1/block?seq=%d", seq), nil)
+		require.NoError(t, err)
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	// A block deeper than immutableDepth can be cached by clients
+	rr := get(5, "")
+	require.Equal(t, http.StatusOK, rr.Code)
+	etag := fmt.Sprintf(`W/"%s"`, chain.blocks[5].HashHeader().Hex())
+	require.Equal(t, etag, rr.Header().Get("ETag"))
+	require.Equal(t, "public, max-age=86400", rr.Header().Get("Cache-Control"))
+	require.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+	body := rr.Body.String()
+
+	// The cached response is the same
+	rr = get(5, "")
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.Equal(t, body, rr.Body.String())
+
+	// A matching conditional request is not modified
+	rr = get(5, etag)
+	require.Equal(t, http.StatusNotModified, rr.Code)
+	require.Empty(t, rr.Body.String())
+	require.Equal(t, etag, rr.Header().Get("ETag"))
+
+	rr = get(5, `W/"abc", `+etag)
+	require.Equal(t, http.StatusNotModified, rr.Code)
+
+	rr = get(5, `W/"abc"`)
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	// A recent block must be revalidated
+	rr = get(15, "")
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.Equal(t, "no-cache", rr.Header().Get("Cache-Control"))
+
+	// Missing blocks are not cached
+	rr = get(100, "")
+	require.Equal(t, http.StatusNotFound, rr.Code)
+	require.Empty(t, rr.Header().Get("ETag"))
+}
+
+func TestResponseCacheMiddleware(t *testing.T) {
+	chain := newTestChain(20)
+	gateway := &MockGatewayer{}
+	chain.mock(gateway)
+
+	// The handler echoes the seq query parameter, and responds with 404 for seq=404.
+	// Responses for seq=0 are not cacheable, and responses for depth=1 depend on the depth of the block.
+	calls := 0
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if r.FormValue("seq") == "404" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", ContentTypeJSON)
+		fmt.Fprintf(w, "%s-%d", r.FormValue("seq"), calls)
+	})
+
+	tagFunc := func(gateway Gatewayer, body []byte) (cacheTag, bool, error) {
+		var seq, n uint64
+		if _, err := fmt.Sscanf(string(body), "%d-%d", &seq, &n); err != nil {
+			return cacheTag{}, false, err
+		}
+		if seq == 0 {
+			return cacheTag{}, false, nil
+		}
+		head, _, err := gateway.HeadBkSeq()
+		if err != nil {
+			return cacheTag{}, false, err
+		}
+		return blockCacheTagBySeq(gateway, seq, seq%2 == 1, head-seq)
+	}
+
+	cache := newResponseCache(gateway, 3)
+	h := cache.middleware(tagFunc, handler)
+
+	get := func(query string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest(http.MethodGet, "/test?"+query, nil)
+		require.NoError(t, err)
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+		return rr
+	}
+
+	requireResponse := func(query, body string, expectedCalls int) {
+		rr := get(query)
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.Equal(t, body, rr.Body.String())
+		require.Equal(t, expectedCalls, calls)
+	}
+
+	// Hit
+	requireResponse("seq=2", "2-1", 1)
+	requireResponse("seq=2", "2-1", 1)
+	// The query order does not matter
+	requireResponse("seq=4&x=1", "4-2", 2)
+	requireResponse("x=1&seq=4", "4-2", 2)
+	require.Equal(t, 2, cache.len())
+
+	// Not cacheable
+	requireResponse("seq=0", "0-3", 3)
+	requireResponse("seq=0", "0-4", 4)
+	rr := get("seq=404")
+	require.Equal(t, http.StatusNotFound, rr.Code)
+	require.Equal(t, 5, calls)
+	require.Equal(t, 2, cache.len())
+
+	// Depth dependent
+	rr = get("seq=3")
+	require.Equal(t, fmt.Sprintf(`W/"%s-16"`, chain.blocks[3].HashHeader().Hex()), rr.Header().Get("ETag"))
+	require.Equal(t, "no-cache", rr.Header().Get("Cache-Control"))
+	require.Equal(t, 6, calls)
+	require.Equal(t, 3, cache.len())
+
+	// The least recently used response is evicted, which is seq=4 since seq=2 was used after it
+	requireResponse("seq=2", "2-1", 6)
+	requireResponse("seq=6", "6-7", 7)
+	require.Equal(t, 3, cache.len())
+	requireResponse("seq=2", "2-1", 7)
+	requireResponse("seq=4&x=1", "4-8", 8)
+	require.Equal(t, 3, cache.len())
+
+	// Adding a block invalidates the depth dependent responses
+	chain.extend(1)
+	requireResponse("seq=2", "2-1", 8)
+	rr = get("seq=3")
+	require.Equal(t, "3-9", rr.Body.String())
+	require.Equal(t, fmt.Sprintf(`W/"%s-17"`, chain.blocks[3].HashHeader().Hex()), rr.Header().Get("ETag"))
+
+	// Reorganizing the blockchain invalidates all responses
+	chain.reorg(15, 5)
+	requireResponse("seq=2", "2-10", 10)
+	require.Equal(t, 1, cache.len())
+
+	// Other methods are not cached
+	req, err := http.NewRequest(http.MethodPost, "/test?seq=2", nil)
+	require.NoError(t, err)
+	rr = httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+	require.Equal(t, "2-11", rr.Body.String())
+	require.Empty(t, rr.Header().Get("ETag"))
+}
+
+func TestTransactionCacheTag(t *testing.T) {
+	chain := newTestChain(20)
+	gateway := &MockGatewayer{}
+	chain.mock(gateway)
+
+	tag, ok, err := transactionCacheTag(gateway, []byte(`{"status":{"confirmed":true,"height":3,"block_seq":17},"txn":{}}`))
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, cacheTag{
+		Seq:            17,
+		Hash:           chain.blocks[17].HashHeader(),
+		DepthDependent: true,
+		Depth:          2,
+	}, tag)
+
+	_, ok, err = transactionCacheTag(gateway, []byte(`{"status":{"confirmed":false,"unconfirmed":true},"txn":{}}`))
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	_, _, err = transactionCacheTag(gateway, []byte(`{"status":`))
+	require.Error(t, err)
+}
+
+func TestUxOutCacheTag(t *testing.T) {
+	chain := newTestChain(20)
+	gateway := &MockGatewayer{}
+	chain.mock(gateway)
+
+	tag, ok, err := uxOutCacheTag(gateway, []byte(`{"src_block_seq":3,"spent_block_seq":7}`))
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, cacheTag{
+		Seq:  7,
+		Hash: chain.blocks[7].HashHeader(),
+	}, tag)
+
+	_, ok, err = uxOutCacheTag(gateway, []byte(`{"src_block_seq":3,"spent_block_seq":0}`))
+	require.NoError(t, err)
+	require.False(t, ok)
+}
@@ -2,6 +2,8 @@ package api
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -10,12 +12,15 @@ import (
 	"net"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/skycoin/skycoin/src/coin"
 	"github.com/skycoin/skycoin/src/daemon"
 	"github.com/skycoin/skycoin/src/readable"
+	wh "github.com/skycoin/skycoin/src/util/http"
+	"github.com/skycoin/skycoin/src/visor"
 )
 
 const (
@@ -27,6 +32,10 @@ const (
 	ContentTypeJSON = "application/json"
 	// ContentTypeForm form data content type header
 	ContentTypeForm = "application/x-www-form-urlencoded"
+	// ContentTypeOctetStream binary data content type header
+	ContentTypeOctetStream = "application/octet-stream"
+	// ContentTypeGzip gzip compressed data content type header
+	ContentTypeGzip = "application/gzip"
 )
 
 // ClientError is used for non-200 API responses
@@ -141,6 +150,54 @@ func (c *Client) get(endpoint string) (*http.Response, error) {
 	return c.HTTPClient.Do(req)
 }
 
+// GetV2 makes a GET request to an endpoint and parses the standard JSON response.
+func (c *Client) GetV2(endpoint string, respObj interface{}) (bool, error) {
+	resp, err := c.get(endpoint)
+	if err != nil {
+		return false, err
+	}
+
+	defer resp.Body.Close()
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return false, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(respBody))
+	decoder.DisallowUnknownFields()
+
+	var wrapObj ReceivedHTTPResponse
+	if err := decoder.Decode(&wrapObj); err != nil {
+		// In some cases, the server can send an error response in a non-JSON format,
+		// such as a 404 when the endpoint is not registered.
+		// If this happens, treat the entire response body as the error message.
+		if resp.StatusCode != http.StatusOK {
+			return false, NewClientError(resp.Status, resp.StatusCode, string(respBody))
+		}
+
+		return false, err
+	}
+
+	var rspErr error
+	if resp.StatusCode != http.StatusOK {
+		rspErr = NewClientError(resp.Status, resp.StatusCode, wrapObj.Error.Message)
+	}
+
+	if wrapObj.Data == nil {
+		return false, rspErr
+	}
+
+	decoder = json.NewDecoder(bytes.NewReader(wrapObj.Data))
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(respObj); err != nil {
+		return false, err
+	}
+
+	return true, rspErr
+}
+
 // PostForm makes a POST request to an endpoint with body of ContentTypeForm formated data.
 func (c *Client) PostForm(endpoint string, body io.Reader, obj interface{}) error {
 	return c.Post(endpoint, ContentTypeForm, body, obj)
@@ -365,6 +422,30 @@ func (c *Client) OutputsForHashes(hashes []string) (*readable.UnspentOutputsSumm
 	return &o, nil
 }
 
+// OutputsForAddressesV2 makes a request to POST /api/v2/outputs with addrs
+func (c *Client) OutputsForAddressesV2(addrs []string) (*readable.UnspentOutputsSummary, error) {
+	return c.outputsV2(OutputsRequestV2{
+		Addrs: addrs,
+	})
+}
+
+// OutputsForHashesV2 makes a request to POST /api/v2/outputs with hashes
+func (c *Client) OutputsForHashesV2(hashes []string) (*readable.UnspentOutputsSummary, error) {
+	return c.outputsV2(OutputsRequestV2{
+		Hashes: hashes,
+	})
+}
+
+func (c *Client) outputsV2(req OutputsRequestV2) (*readable.UnspentOutputsSummary, error) {
+	var rsp readable.UnspentOutputsSummary
+	ok, err := c.PostJSONV2("/api/v2/outputs", req, &rsp)
+	if ok {
+		return &rsp, err
+	}
+
+	return nil, err
+}
+
 // CoinSupply makes a request to GET /api/v1/coinSupply
 func (c *Client) CoinSupply() (*CoinSupply, error) {
 	var cs CoinSupply
@@ -494,6 +575,39 @@ func (c *Client) BlocksInRangeVerbose(start, end uint64) (*readable.BlocksVerbos
 	return &b, nil
 }
 
+// BlocksInTimeRange makes a request to GET /api/v2/blocks?start_time=&end_time=
+func (c *Client) BlocksInTimeRange(start, end uint64) (*readable.Blocks, error) {
+	v := url.Values{}
+	v.Add("start_time", fmt.Sprint(start))
+	v.Add("end_time", fmt.Sprint(end))
+	endpoint := "/api/v2/blocks?" + v.Encode()
+
+	var b readable.Blocks
+	ok, err := c.GetV2(endpoint, &b)
+	if ok {
+		return &b, err
+	}
+
+	return nil, err
+}
+
+// BlocksInTimeRangeVerbose makes a request to GET /api/v2/blocks?verbose=1&start_time=&end_time=
+func (c *Client) BlocksInTimeRangeVerbose(start, end uint64) (*readable.BlocksVerbose, error) {
+	v := url.Values{}
+	v.Add("start_time", fmt.Sprint(start))
+	v.Add("end_time", fmt.Sprint(end))
+	v.Add("verbose", "1")
+	endpoint := "/api/v2/blocks?" + v.Encode()
+
+	var b readable.BlocksVerbose
+	ok, err := c.GetV2(endpoint, &b)
+	if ok {
+		return &b, err
+	}
+
+	return nil, err
+}
+
 // LastBlocks makes a request to GET /api/v1/last_blocks
 func (c *Client) LastBlocks(n uint64) (*readable.Blocks, error) {
 	v := url.Values{}
@@ -552,6 +666,21 @@ func (c *Client) Balance(addrs []string) (*BalanceResponse, error) {
 	return &b, nil
 }
 
+// BalanceV2 makes a request to POST /api/v2/balance
+func (c *Client) BalanceV2(addrs []string) (*BalanceResponseV2, error) {
+	req := BalanceRequestV2{
+		Addrs: addrs,
+	}
+
+	var rsp BalanceResponseV2
+	ok, err := c.PostJSONV2("/api/v2/balance", req, &rsp)
+	if ok {
+		return &rsp, err
+	}
+
+	return nil, err
+}
+
 // UxOut makes a request to GET /api/v1/uxout?uxid=xxx
 func (c *Client) UxOut(uxID string) (*readable.SpentOutput, error) {
 	v := url.Values{}
@@ -1045,6 +1174,21 @@ func (c *Client) UnconfirmedTransactionsVerbose(addrs []string) ([]readable.Tran
 	return r, nil
 }
 
+// TransactionsInTimeRange makes a request to POST /api/v1/transactions?start_time=&end_time=
+func (c *Client) TransactionsInTimeRange(addrs []string, start, end uint64) ([]readable.TransactionWithStatus, error) {
+	v := url.Values{}
+	v.Add("addrs", strings.Join(addrs, ","))
+	v.Add("start_time", fmt.Sprint(start))
+	v.Add("end_time", fmt.Sprint(end))
+	endpoint := "/api/v1/transactions"
+
+	var r []readable.TransactionWithStatus
+	if err := c.PostForm(endpoint, strings.NewReader(v.Encode()), &r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
 // InjectTransaction makes a request to POST /api/v1/injectTransaction.
 func (c *Client) InjectTransaction(txn *coin.Transaction) (string, error) {
 	rawTxn, err := txn.SerializeHex()
@@ -1121,9 +1265,26 @@ func (c *Client) VerifyAddress(addr string) (*VerifyAddressResponse, error) {
 	return nil, err
 }
 
+// AddressHistory makes a request to GET /api/v2/address/history
+func (c *Client) AddressHistory(addrs []string) ([]readable.AddressHistory, error) {
+	v := url.Values{}
+	v.Add("addrs", strings.Join(addrs, ","))
+	endpoint := "/api/v2/address/history?" + v.Encode()
+
+	var rsp []readable.AddressHistory
+	ok, err := c.GetV2(endpoint, &rsp)
+	if ok {
+		return rsp, err
+	}
+
+	return nil, err
+}
+
 // RichlistParams are arguments to the /richlist endpoint
 type RichlistParams struct {
-	N                   int
+	N int
+	// Page is the page of N results to return, starting at 1. The first page is returned if Page is 0
+	Page                int
 	IncludeDistribution bool
 }
 
@@ -1134,6 +1295,9 @@ func (c *Client) Richlist(params *RichlistParams) (*Richlist, error) {
 	if params != nil {
 		v := url.Values{}
 		v.Add("n", fmt.Sprint(params.N))
+		if params.Page != 0 {
+			v.Add("page", fmt.Sprint(params.Page))
+		}
 		v.Add("include-distribution", fmt.Sprint(params.IncludeDistribution))
 		endpoint = "/api/v1/richlist?" + v.Encode()
 	}
@@ -1157,6 +1321,40 @@ func (c *Client) AddressCount() (uint64, error) {
 
 }
 
+// StatsSeriesParams are arguments to the /api/v2/stats/series endpoint
+type StatsSeriesParams struct {
+	Metric string
+	// Interval is "block" or "day". The daily series is returned if Interval is empty
+	Interval string
+	// StartTime and EndTime are the unix times of the range, inclusive. They are not sent if zero
+	StartTime uint64
+	EndTime   uint64
+}
+
+// StatsSeries makes a request to GET /api/v2/stats/series
+func (c *Client) StatsSeries(params StatsSeriesParams) (*readable.StatsSeries, error) {
+	v := url.Values{}
+	v.Add("metric", params.Metric)
+	if params.Interval != "" {
+		v.Add("interval", params.Interval)
+	}
+	if params.StartTime != 0 {
+		v.Add("start_time", fmt.Sprint(params.StartTime))
+	}
+	if params.EndTime != 0 {
+		v.Add("end_time", fmt.Sprint(params.EndTime))
+	}
+	endpoint := "/api/v2/stats/series?" + v.Encode()
+
+	var s readable.StatsSeries
+	ok, err := c.GetV2(endpoint, &s)
+	if ok {
+		return &s, err
+	}
+
+	return nil, err
+}
+
 // UnloadWallet makes a request to POST /api/v1/wallet/unload
 func (c *Client) UnloadWallet(id string) error {
 	v := url.Values{}
@@ -1227,3 +1425,139 @@ func (c *Client) Disconnect(id uint64) error {
 	var obj struct{}
 	return c.PostForm("/api/v1/network/connection/disconnect", strings.NewReader(v.Encode()), &obj)
 }
+
+// NetworkStats makes a request to GET /api/v2/network/stats
+func (c *Client) NetworkStats() (*readable.NetworkStats, error) {
+	var rsp readable.NetworkStats
+	ok, err := c.GetV2("/api/v2/network/stats", &rsp)
+	if ok {
+		return &rsp, err
+	}
+
+	return nil, err
+}
+
+// Bans makes a request to GET /api/v2/network/bans
+func (c *Client) Bans() ([]readable.Ban, error) {
+	var rsp []readable.Ban
+	ok, err := c.GetV2("/api/v2/network/bans", &rsp)
+	if ok {
+		return rsp, err
+	}
+
+	return nil, err
+}
+
+// BanPeer makes a request to POST /api/v2/network/bans/add.
+// addr is an IP or IP:Port string. If duration is 0, the node's default ban duration is used.
+func (c *Client) BanPeer(addr string, duration time.Duration, reason string) (*readable.Ban, error) {
+	req := BanPeerRequest{
+		Address:  addr,
+		Duration: wh.FromDuration(duration),
+		Reason:   reason,
+	}
+
+	var rsp readable.Ban
+	ok, err := c.PostJSONV2("/api/v2/network/bans/add", req, &rsp)
+	if ok {
+		return &rsp, err
+	}
+
+	return nil, err
+}
+
+// UnbanPeer makes a request to POST /api/v2/network/bans/remove
+func (c *Client) UnbanPeer(addr string) error {
+	req := UnbanPeerRequest{
+		Address: addr,
+	}
+
+	_, err := c.PostJSONV2("/api/v2/network/bans/remove", req, &struct{}{})
+	return err
+}
+
+// BackupDB makes a request to POST /api/v2/db/backup and writes the backup to w.
+// Returns an error if the checksum of the received backup does not match the checksum sent by the node,
+// which happens if the connection is closed before the whole backup is received.
+func (c *Client) BackupDB(w io.Writer, compress bool) (*visor.BackupManifest, error) {
+	body, err := json.Marshal(DBBackupRequest{
+		Compress: compress,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	csrf, err := c.CSRF()
+	if err != nil {
+		return nil, err
+	}
+
+	endpoint := c.Addr + "api/v2/db/backup"
+	req, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	c.applyAuth(req)
+
+	if csrf != "" {
+		req.Header.Set(CSRFHeaderName, csrf)
+	}
+
+	req.Header.Set("Content-Type", ContentTypeJSON)
+
+	// Downloading the backup of a large database takes longer than the client timeout
+	httpClient := *c.HTTPClient
+	httpClient.Timeout = 0
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			return nil, err
+		}
+
+		var wrapObj ReceivedHTTPResponse
+		if err := json.Unmarshal(respBody, &wrapObj); err != nil || wrapObj.Error == nil {
+			return nil, NewClientError(resp.Status, resp.StatusCode, string(respBody))
+		}
+
+		return nil, NewClientError(resp.Status, resp.StatusCode, wrapObj.Error.Message)
+	}
+
+	m := visor.BackupManifest{
+		HeadHash:   resp.Header.Get(backupHeadHashHeader),
+		Compressed: resp.Header.Get("Content-Type") == ContentTypeGzip,
+		SHA256:     resp.Header.Get(backupSHA256Header),
+	}
+
+	if m.CreatedAt, err = strconv.ParseInt(resp.Header.Get(backupCreatedAtHeader), 10, 64); err != nil {
+		return nil, fmt.Errorf("invalid %s header: %v", backupCreatedAtHeader, err)
+	}
+	if m.HeadSeq, err = strconv.ParseUint(resp.Header.Get(backupHeadSeqHeader), 10, 64); err != nil {
+		return nil, fmt.Errorf("invalid %s header: %v", backupHeadSeqHeader, err)
+	}
+	if m.SchemaVersion, err = strconv.ParseUint(resp.Header.Get(backupSchemaVersionHeader), 10, 64); err != nil {
+		return nil, fmt.Errorf("invalid %s header: %v", backupSchemaVersionHeader, err)
+	}
+	if m.DBSize, err = strconv.ParseInt(resp.Header.Get(backupDBSizeHeader), 10, 64); err != nil {
+		return nil, fmt.Errorf("invalid %s header: %v", backupDBSizeHeader, err)
+	}
+
+	h := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(w, h), resp.Body); err != nil {
+		return nil, err
+	}
+
+	if sum := hex.EncodeToString(h.Sum(nil)); sum != m.SHA256 {
+		return nil, fmt.Errorf("backup checksum %s does not match the checksum %q sent by the node, the backup is incomplete", sum, m.SHA256)
+	}
+
+	return &m, nil
+}
@@ -0,0 +1,106 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/skycoin/skycoin/src/visor"
+)
+
+const (
+	// Headers of the POST /api/v2/db/backup response, with the fields of the backup manifest
+	backupCreatedAtHeader     = "X-Backup-Created-At"
+	backupHeadSeqHeader       = "X-Backup-Head-Seq"
+	backupHeadHashHeader      = "X-Backup-Head-Hash"
+	backupSchemaVersionHeader = "X-Backup-Schema-Version"
+	backupDBSizeHeader        = "X-Backup-DB-Size"
+	backupSHA256Header        = "X-Backup-Sha256"
+)
+
+// DBBackupRequest is the request data for POST /api/v2/db/backup
+type DBBackupRequest struct {
+	// Compress the backup with gzip
+	Compress bool `json:"compress"`
+}
+
+// dbBackupHandler sends a consistent copy of the database, made from a read-only transaction
+// while the node keeps running. The copy is written to a temporary file next to the database
+// before it is sent, so the transaction is not held open by a slow client.
+// The manifest of the backup, with its checksum, is sent in the X-Backup-* headers.
+// The -http-write-timeout option must be long enough to send the whole database.
+// URI: /api/v2/db/backup
+// Method: POST
+// Content-Type: application/json
+// Body: {"compress": true}
+func dbBackupHandler(gateway Gatewayer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			resp := NewHTTPErrorResponse(http.StatusMethodNotAllowed, "")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		if r.Header.Get("Content-Type") != ContentTypeJSON {
+			resp := NewHTTPErrorResponse(http.StatusUnsupportedMediaType, "")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		var req DBBackupRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, err.Error())
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		path, m, err := gateway.BackupDBToTempFile(req.Compress)
+		if err != nil {
+			var resp HTTPResponse
+			switch err {
+			case visor.ErrBackupUnsupported:
+				resp = NewHTTPErrorResponse(http.StatusForbidden, err.Error())
+			default:
+				resp = NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())
+			}
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		defer func() {
+			if err := os.Remove(path); err != nil {
+				logger.WithError(err).WithField("path", path).Error("Failed to remove the temporary backup file")
+			}
+		}()
+
+		f, err := os.Open(path)
+		if err != nil {
+			resp := NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())
+			writeHTTPResponse(w, resp)
+			return
+		}
+		defer f.Close()
+
+		contentType := ContentTypeOctetStream
+		filename := "data.db"
+		if m.Compressed {
+			contentType = ContentTypeGzip
+			filename += ".gz"
+		}
+
+		h := w.Header()
+		h.Set("Content-Type", contentType)
+		h.Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+		h.Set(backupCreatedAtHeader, strconv.FormatInt(m.CreatedAt, 10))
+		h.Set(backupHeadSeqHeader, strconv.FormatUint(m.HeadSeq, 10))
+		h.Set(backupHeadHashHeader, m.HeadHash)
+		h.Set(backupSchemaVersionHeader, strconv.FormatUint(m.SchemaVersion, 10))
+		h.Set(backupDBSizeHeader, strconv.FormatInt(m.DBSize, 10))
+		h.Set(backupSHA256Header, m.SHA256)
+
+		http.ServeContent(w, r, filename, time.Unix(m.CreatedAt, 0), f)
+	}
+}
@@ -0,0 +1,169 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/visor"
+)
+
+func TestDBBackup(t *testing.T) {
+	manifest := visor.BackupManifest{
+		CreatedAt:     1500000000,
+		HeadSeq:       10,
+		HeadHash:      "7b8ec8dd836b564f0c85ad088fc744de820345204e154bc1503e04e9d6fdd9f1",
+		SchemaVersion: 2,
+		DBSize:        4,
+	}
+
+	cases := []struct {
+		name         string
+		method       string
+		status       int
+		contentType  string
+		httpBody     string
+		compress     bool
+		data         string
+		backupErr    error
+		httpResponse HTTPResponse
+		headers      map[string]string
+		sha256       string
+	}{
+		{
+			name:         "405",
+			method:       http.MethodGet,
+			status:       http.StatusMethodNotAllowed,
+			httpResponse: NewHTTPErrorResponse(http.StatusMethodNotAllowed, ""),
+		},
+		{
+			name:         "415",
+			method:       http.MethodPost,
+			status:       http.StatusUnsupportedMediaType,
+			contentType:  ContentTypeForm,
+			httpResponse: NewHTTPErrorResponse(http.StatusUnsupportedMediaType, ""),
+		},
+		{
+			name:         "400 EOF",
+			method:       http.MethodPost,
+			status:       http.StatusBadRequest,
+			httpResponse: NewHTTPErrorResponse(http.StatusBadRequest, "EOF"),
+		},
+		{
+			name:         "403 in-memory db",
+			method:       http.MethodPost,
+			status:       http.StatusForbidden,
+			httpBody:     `{}`,
+			backupErr:    visor.ErrBackupUnsupported,
+			httpResponse: NewHTTPErrorResponse(http.StatusForbidden, "the database has no file to back up"),
+		},
+		{
+			name:         "500 backup failed",
+			method:       http.MethodPost,
+			status:       http.StatusInternalServerError,
+			httpBody:     `{}`,
+			backupErr:    errors.New("db closed"),
+			httpResponse: NewHTTPErrorResponse(http.StatusInternalServerError, "db closed"),
+		},
+		{
+			name:     "200",
+			method:   http.MethodPost,
+			status:   http.StatusOK,
+			httpBody: `{}`,
+			data:     "data",
+			headers: map[string]string{
+				"Content-Type":            ContentTypeOctetStream,
+				"Content-Disposition":     `attachment; filename="data.db"`,
+				"X-Backup-Created-At":     "1500000000",
+				"X-Backup-Head-Seq":       "10",
+				"X-Backup-Head-Hash":      manifest.HeadHash,
+				"X-Backup-Schema-Version": "2",
+				"X-Backup-DB-Size":        "4",
+				"X-Backup-Sha256":         "3a6eb0790f39ac87c94f3856b2dd2c5d110e6811602261a9a923d3bb23adc8b7",
+				"Content-Length":          "4",
+			},
+			sha256: "3a6eb0790f39ac87c94f3856b2dd2c5d110e6811602261a9a923d3bb23adc8b7",
+		},
+		{
+			name:     "200 compressed",
+			method:   http.MethodPost,
+			status:   http.StatusOK,
+			httpBody: `{"compress":true}`,
+			compress: true,
+			data:     "gzip",
+			headers: map[string]string{
+				"Content-Type":        ContentTypeGzip,
+				"Content-Disposition": `attachment; filename="data.db.gz"`,
+				"X-Backup-Sha256":     "aa",
+			},
+			sha256: "aa",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			gateway := &MockGatewayer{}
+
+			var path string
+			var result *visor.BackupManifest
+			if tc.backupErr == nil {
+				f, err := ioutil.TempFile("", "backup")
+				require.NoError(t, err)
+				defer os.Remove(f.Name())
+
+				_, err = f.Write([]byte(tc.data))
+				require.NoError(t, err)
+				require.NoError(t, f.Close())
+
+				path = f.Name()
+				m := manifest
+				m.Compressed = tc.compress
+				m.SHA256 = tc.sha256
+				result = &m
+			}
+
+			gateway.On("BackupDBToTempFile", tc.compress).Return(path, result, tc.backupErr)
+
+			req, err := http.NewRequest(tc.method, "/api/v2/db/backup", strings.NewReader(tc.httpBody))
+			require.NoError(t, err)
+
+			contentType := tc.contentType
+			if contentType == "" {
+				contentType = ContentTypeJSON
+			}
+			req.Header.Set("Content-Type", contentType)
+			setCSRFParameters(t, tokenValid, req)
+
+			rr := httptest.NewRecorder()
+			handler := newServerMux(defaultMuxConfig(), gateway)
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.status, rr.Code)
+
+			if tc.status != http.StatusOK {
+				var rsp ReceivedHTTPResponse
+				err = json.NewDecoder(rr.Body).Decode(&rsp)
+				require.NoError(t, err)
+				require.Equal(t, tc.httpResponse.Error, rsp.Error)
+				return
+			}
+
+			require.Equal(t, tc.data, rr.Body.String())
+
+			for k, v := range tc.headers {
+				require.Equal(t, v, rr.Header().Get(k), k)
+			}
+
+			// The temporary backup file is removed once it is sent
+			_, err = os.Stat(path)
+			require.True(t, os.IsNotExist(err))
+		})
+	}
+}
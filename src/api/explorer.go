@@ -2,6 +2,7 @@ package api
 
 import (
 	"fmt"
+	"math"
 	"net/http"
 	"strconv"
 
@@ -157,13 +158,15 @@ func coinSupplyHandler(gateway Gatewayer) http.HandlerFunc {
 // Richlist contains top address balances
 type Richlist struct {
 	Richlist []readable.RichlistBalance `json:"richlist"`
+	Total    uint64                     `json:"total"`
 }
 
 // richlistHandler returns the top skycoin holders
 // Method: GET
-// URI: /richlist?n=${number}&include-distribution=${bool}
+// URI: /richlist?n=${number}&page=${number}&include-distribution=${bool}
 // Args:
 //	n [int, number of results to include]
+//	page [int, page of n results to return, starting at 1]
 //  include-distribution [bool, include the distribution addresses in the richlist]
 func richlistHandler(gateway Gatewayer) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -198,14 +201,35 @@ func richlistHandler(gateway Gatewayer) http.HandlerFunc {
 			}
 		}
 
-		richlist, err := gateway.GetRichlist(includeDistribution)
-		if err != nil {
-			wh.Error500(w, err.Error())
+		page := uint64(1)
+		pageStr := r.FormValue("page")
+		if pageStr != "" {
+			var err error
+			page, err = strconv.ParseUint(pageStr, 10, 64)
+			if err != nil || page == 0 {
+				wh.Error400(w, "invalid page")
+				return
+			}
+		}
+
+		// A non-positive n returns the whole richlist, which has a single page
+		var offset, n uint64
+		if topn > 0 {
+			n = uint64(topn)
+			if page-1 > math.MaxUint64/n {
+				wh.Error400(w, "invalid page")
+				return
+			}
+			offset = (page - 1) * n
+		} else if page != 1 {
+			wh.Error400(w, "page requires a positive n")
 			return
 		}
 
-		if topn > 0 && topn < len(richlist) {
-			richlist = richlist[:topn]
+		richlist, total, err := gateway.GetRichlist(includeDistribution, offset, n)
+		if err != nil {
+			wh.Error500(w, err.Error())
+			return
 		}
 
 		readableRichlist, err := readable.NewRichlistBalances(richlist)
@@ -216,6 +240,7 @@ func richlistHandler(gateway Gatewayer) http.HandlerFunc {
 
 		wh.SendJSONOr500(logger, w, Richlist{
 			Richlist: readableRichlist,
+			Total:    total,
 		})
 	}
 }
@@ -243,6 +243,7 @@ func TestCoinSupply(t *testing.T) {
 func TestGetRichlist(t *testing.T) {
 	type httpParams struct {
 		topn                string
+		page                string
 		includeDistribution string
 	}
 	tt := []struct {
@@ -252,7 +253,10 @@ func TestGetRichlist(t *testing.T) {
 		err                      string
 		httpParams               *httpParams
 		includeDistribution      bool
+		offset                   uint64
+		n                        uint64
 		gatewayGetRichlistResult visor.Richlist
+		gatewayGetRichlistTotal  uint64
 		gatewayGetRichlistErr    error
 		result                   Richlist
 		csrfDisabled             bool
@@ -282,6 +286,36 @@ func TestGetRichlist(t *testing.T) {
 				includeDistribution: "bad include-distribution",
 			},
 		},
+		{
+			name:   "400 - bad page param",
+			method: http.MethodGet,
+			status: http.StatusBadRequest,
+			err:    "400 Bad Request - invalid page",
+			httpParams: &httpParams{
+				topn: "1",
+				page: "0",
+			},
+		},
+		{
+			name:   "400 - page overflow",
+			method: http.MethodGet,
+			status: http.StatusBadRequest,
+			err:    "400 Bad Request - invalid page",
+			httpParams: &httpParams{
+				topn: "2",
+				page: "18446744073709551615",
+			},
+		},
+		{
+			name:   "400 - page without n",
+			method: http.MethodGet,
+			status: http.StatusBadRequest,
+			err:    "400 Bad Request - page requires a positive n",
+			httpParams: &httpParams{
+				topn: "0",
+				page: "2",
+			},
+		},
 		{
 			name:   "500 - gw GetRichlist error",
 			method: http.MethodGet,
@@ -291,6 +325,7 @@ func TestGetRichlist(t *testing.T) {
 				topn:                "1",
 				includeDistribution: "false",
 			},
+			n:                     1,
 			gatewayGetRichlistErr: errors.New("gatewayGetRichlistErr"),
 		},
 		{
@@ -301,6 +336,7 @@ func TestGetRichlist(t *testing.T) {
 				topn:                "3",
 				includeDistribution: "false",
 			},
+			n: 3,
 			gatewayGetRichlistResult: visor.Richlist{
 				{
 					Address: cipher.MustDecodeBase58Address("2fGC7kwAM9yZyEF1QqBqp8uo9RUsF6ENGJF"),
@@ -317,17 +353,8 @@ func TestGetRichlist(t *testing.T) {
 					Coins:   500000e6,
 					Locked:  false,
 				},
-				{
-					Address: cipher.MustDecodeBase58Address("2TmvdBWJgxMwGs84R4drS9p5fYkva4dGdfs"),
-					Coins:   244458e6,
-					Locked:  false,
-				},
-				{
-					Address: cipher.MustDecodeBase58Address("24gvUHXHtSg5drKiFsMw7iMgoN2PbLub53C"),
-					Coins:   195503e6,
-					Locked:  false,
-				},
 			},
+			gatewayGetRichlistTotal: 5,
 			result: Richlist{
 				Richlist: []readable.RichlistBalance{
 					{
@@ -346,6 +373,48 @@ func TestGetRichlist(t *testing.T) {
 						Locked:  false,
 					},
 				},
+				Total: 5,
+			},
+		},
+		{
+			name:   "200 page",
+			method: http.MethodGet,
+			status: http.StatusOK,
+			httpParams: &httpParams{
+				topn:                "2",
+				page:                "2",
+				includeDistribution: "true",
+			},
+			includeDistribution: true,
+			offset:              2,
+			n:                   2,
+			gatewayGetRichlistResult: visor.Richlist{
+				{
+					Address: cipher.MustDecodeBase58Address("2fGi2jhvp6ppHg3DecguZgzqvpJj2Gd4KHW"),
+					Coins:   500000e6,
+					Locked:  true,
+				},
+				{
+					Address: cipher.MustDecodeBase58Address("2TmvdBWJgxMwGs84R4drS9p5fYkva4dGdfs"),
+					Coins:   244458e6,
+					Locked:  false,
+				},
+			},
+			gatewayGetRichlistTotal: 5,
+			result: Richlist{
+				Richlist: []readable.RichlistBalance{
+					{
+						Address: "2fGi2jhvp6ppHg3DecguZgzqvpJj2Gd4KHW",
+						Coins:   "500000.000000",
+						Locked:  true,
+					},
+					{
+						Address: "2TmvdBWJgxMwGs84R4drS9p5fYkva4dGdfs",
+						Coins:   "244458.000000",
+						Locked:  false,
+					},
+				},
+				Total: 5,
 			},
 		},
 		{
@@ -383,6 +452,7 @@ func TestGetRichlist(t *testing.T) {
 					Locked:  false,
 				},
 			},
+			gatewayGetRichlistTotal: 5,
 			result: Richlist{
 				Richlist: []readable.RichlistBalance{
 					{
@@ -411,6 +481,7 @@ func TestGetRichlist(t *testing.T) {
 						Locked:  false,
 					},
 				},
+				Total: 5,
 			},
 		},
 	}
@@ -419,13 +490,16 @@ func TestGetRichlist(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			endpoint := "/api/v1/richlist"
 			gateway := &MockGatewayer{}
-			gateway.On("GetRichlist", tc.includeDistribution).Return(tc.gatewayGetRichlistResult, tc.gatewayGetRichlistErr)
+			gateway.On("GetRichlist", tc.includeDistribution, tc.offset, tc.n).Return(tc.gatewayGetRichlistResult, tc.gatewayGetRichlistTotal, tc.gatewayGetRichlistErr)
 
 			v := url.Values{}
 			if tc.httpParams != nil {
 				if tc.httpParams.topn != "" {
 					v.Add("n", tc.httpParams.topn)
 				}
+				if tc.httpParams.page != "" {
+					v.Add("page", tc.httpParams.page)
+				}
 				if tc.httpParams.includeDistribution != "" {
 					v.Add("include-distribution", tc.httpParams.includeDistribution)
 				}
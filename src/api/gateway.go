@@ -6,6 +6,8 @@ import (
 	"github.com/skycoin/skycoin/src/cipher"
 	"github.com/skycoin/skycoin/src/coin"
 	"github.com/skycoin/skycoin/src/daemon"
+	"github.com/skycoin/skycoin/src/daemon/gnet"
+	"github.com/skycoin/skycoin/src/daemon/pex"
 	"github.com/skycoin/skycoin/src/transaction"
 	"github.com/skycoin/skycoin/src/visor"
 	"github.com/skycoin/skycoin/src/visor/historydb"
@@ -46,6 +48,11 @@ type Daemoner interface {
 	GetDefaultConnections() []string
 	GetTrustConnections() []string
 	GetExchgConnection() []string
+	GetNetworkStats() (gnet.NetworkStats, error)
+	GetInventoryStats() daemon.InventoryStats
+	GetBans() []pex.Ban
+	BanPeer(addr string, duration time.Duration, reason string) (pex.Ban, error)
+	UnbanPeer(addr string) error
 	GetBlockchainProgress(headSeq uint64) *daemon.BlockchainProgress
 	InjectBroadcastTransaction(txn coin.Transaction) error
 }
@@ -64,16 +71,24 @@ type Visorer interface {
 	GetBlocksVerbose(seqs []uint64) ([]coin.SignedBlock, [][][]visor.TransactionInput, error)
 	GetBlocksInRange(start, end uint64) ([]coin.SignedBlock, error)
 	GetBlocksInRangeVerbose(start, end uint64) ([]coin.SignedBlock, [][][]visor.TransactionInput, error)
+	GetBlocksInTimeRange(start, end uint64) ([]coin.SignedBlock, error)
+	GetBlocksInTimeRangeVerbose(start, end uint64) ([]coin.SignedBlock, [][][]visor.TransactionInput, error)
 	GetLastBlocks(num uint64) ([]coin.SignedBlock, error)
 	GetLastBlocksVerbose(num uint64) ([]coin.SignedBlock, [][][]visor.TransactionInput, error)
 	GetUnspentOutputsSummary(filters []visor.OutputsFilter) (*visor.UnspentOutputsSummary, error)
 	GetBalanceOfAddrs(addrs []cipher.Address) ([]wallet.BalancePair, error)
+	GetAddressBalances(addrs []cipher.Address) (*visor.AddressBalances, error)
+	GetUnspentOutputsSummaryOfAddrs(addrs []cipher.Address) (*visor.UnspentOutputsSummary, error)
+	GetUnspentOutputsSummaryOfHashes(hashes []cipher.SHA256) (*visor.UnspentOutputsSummary, error)
 	VerifyTxnVerbose(txn *coin.Transaction, signed visor.TxnSignedFlag) ([]visor.TransactionInput, bool, error)
 	AddressCount() (uint64, error)
 	GetUxOutByID(id cipher.SHA256) (*historydb.UxOut, error)
 	GetSpentOutputsForAddresses(addr []cipher.Address) ([][]historydb.UxOut, error)
 	GetVerboseTransactionsForAddress(a cipher.Address) ([]visor.Transaction, [][]visor.TransactionInput, error)
-	GetRichlist(includeDistribution bool) (visor.Richlist, error)
+	GetAddressesHistory(addrs []cipher.Address) ([]visor.AddressHistory, error)
+	GetRichlist(includeDistribution bool, offset, n uint64) (visor.Richlist, uint64, error)
+	GetStatsSeries(metric visor.StatsMetric, interval visor.StatsInterval, start, end uint64) ([]visor.StatsPoint, error)
+	BackupDBToTempFile(compress bool) (string, *visor.BackupManifest, error)
 	GetAllUnconfirmedTransactions() ([]visor.UnconfirmedTransaction, error)
 	GetAllUnconfirmedTransactionsVerbose() ([]visor.UnconfirmedTransaction, [][]visor.TransactionInput, error)
 	GetTransaction(txid cipher.SHA256) (*visor.Transaction, error)
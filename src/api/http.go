@@ -11,12 +11,12 @@ import (
 	"net"
 	"net/http"
 	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 	"unicode"
 
 	"github.com/NYTimes/gziphandler"
-	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/rs/cors"
 
 	"github.com/skycoin/skycoin/src/cipher"
@@ -57,6 +57,8 @@ const (
 	EndpointsPrometheus = "PROMETHEUS"
 	// EndpointsNetCtrl endpoints for managing network connections
 	EndpointsNetCtrl = "NET_CTRL"
+	// EndpointsDBAdmin endpoints for database administration
+	EndpointsDBAdmin = "DB_ADMIN"
 )
 
 // Server exposes an HTTP API
@@ -81,6 +83,8 @@ type Config struct {
 	EnabledAPISets     map[string]struct{}
 	Username           string
 	Password           string
+	// ResponseCacheSize is the number of block, transaction and uxout responses to cache. 0 disables the cache
+	ResponseCacheSize int
 }
 
 // HealthConfig configuration data exposed in /health
@@ -102,6 +106,7 @@ type muxConfig struct {
 	username           string
 	password           string
 	health             HealthConfig
+	responseCacheSize  int
 }
 
 // HTTPResponse represents the http response struct
@@ -155,6 +160,89 @@ func writeHTTPResponse(w http.ResponseWriter, resp HTTPResponse) {
 	}
 }
 
+// streamFlushInterval is the number of array elements written by writeStreamedHTTPResponse between flushes
+const streamFlushInterval = 1000
+
+// jsonArrayField is an array field of a response data object, written by writeStreamedHTTPResponse
+type jsonArrayField struct {
+	Name string
+	Len  int
+	// Elem returns the i-th element of the array
+	Elem func(i int) interface{}
+}
+
+// writeStreamedHTTPResponse writes a successful HTTPResponse whose data is the JSON object fields
+// extended with arrays. The array elements are encoded and written one at a time, so that the response
+// to a large request is not held in memory in full. Unlike writeHTTPResponse, the JSON is not indented.
+func writeStreamedHTTPResponse(w http.ResponseWriter, fields interface{}, arrays ...jsonArrayField) {
+	obj, err := json.Marshal(fields)
+	if err != nil || len(obj) < 2 || obj[0] != '{' {
+		resp := NewHTTPErrorResponse(http.StatusInternalServerError, "json.Marshal failed")
+		writeHTTPResponse(w, resp)
+		return
+	}
+
+	w.Header().Add("Content-Type", ContentTypeJSON)
+	w.WriteHeader(http.StatusOK)
+
+	// Once the header is written the status can't change, so a failure leaves the response incomplete
+	write := func(b []byte) bool {
+		if _, err := w.Write(b); err != nil {
+			logger.WithError(err).Error("http Write failed")
+			return false
+		}
+		return true
+	}
+
+	// Write the fields without the closing brace, followed by the arrays
+	if !write([]byte(`{"data":`)) || !write(obj[:len(obj)-1]) {
+		return
+	}
+
+	hasFields := len(obj) > 2
+	for i, a := range arrays {
+		name, err := json.Marshal(a.Name)
+		if err != nil {
+			logger.WithError(err).Error("json.Marshal failed")
+			return
+		}
+
+		if (hasFields || i > 0) && !write([]byte(",")) {
+			return
+		}
+		if !write(name) || !write([]byte(":[")) {
+			return
+		}
+
+		for j := 0; j < a.Len; j++ {
+			elem, err := json.Marshal(a.Elem(j))
+			if err != nil {
+				logger.WithError(err).Error("json.Marshal failed")
+				return
+			}
+
+			if j > 0 && !write([]byte(",")) {
+				return
+			}
+			if !write(elem) {
+				return
+			}
+
+			if (j+1)%streamFlushInterval == 0 {
+				if f, ok := w.(http.Flusher); ok {
+					f.Flush()
+				}
+			}
+		}
+
+		if !write([]byte("]")) {
+			return
+		}
+	}
+
+	write([]byte("}}"))
+}
+
 func create(host string, c Config, gateway Gatewayer) (*Server, error) {
 	var appLoc string
 	if c.EnableGUI {
@@ -196,6 +284,7 @@ func create(host string, c Config, gateway Gatewayer) (*Server, error) {
 		hostWhitelist:      c.HostWhitelist,
 		username:           c.Username,
 		password:           c.Password,
+		responseCacheSize:  c.ResponseCacheSize,
 	}
 
 	srvMux := newServerMux(mc, gateway)
@@ -311,7 +400,14 @@ func (s *Server) Shutdown() {
 
 // newServerMux creates an http.ServeMux with handlers registered
 func newServerMux(c muxConfig, gateway Gatewayer) *http.ServeMux {
+	mux, _ := newServerMuxWithRoutes(c, gateway)
+	return mux
+}
+
+// newServerMuxWithRoutes creates the server mux and returns the API routes registered on it
+func newServerMuxWithRoutes(c muxConfig, gateway Gatewayer) (*http.ServeMux, []apiRoute) {
 	mux := http.NewServeMux()
+	var routes []apiRoute
 
 	allowedOrigins := []string{fmt.Sprintf("http://%s", c.host)}
 	for _, s := range c.hostWhitelist {
@@ -376,6 +472,29 @@ func newServerMux(c muxConfig, gateway Gatewayer) *http.ServeMux {
 		})
 	}
 
+	addRoute := func(apiVersion, endpoint string, methodAPISets map[string][]string) {
+		// Only API endpoints are recorded, not the index page or GUI static files
+		if !strings.HasPrefix(endpoint, "/api/") {
+			return
+		}
+
+		// Endpoints without API sets only accept GET
+		methods := []string{http.MethodGet}
+		if methodAPISets != nil {
+			methods = make([]string, 0, len(methodAPISets))
+			for m := range methodAPISets {
+				methods = append(methods, m)
+			}
+			sort.Strings(methods)
+		}
+
+		routes = append(routes, apiRoute{
+			Path:    endpoint,
+			Version: apiVersion,
+			Methods: methods,
+		})
+	}
+
 	webHandlerWithOptionals := func(apiVersion, endpoint string, handlerFunc http.Handler, checkCSRF, checkHeaders bool) {
 		handler := wh.ElapsedHandler(logger, handlerFunc)
 
@@ -402,6 +521,7 @@ func newServerMux(c muxConfig, gateway Gatewayer) *http.ServeMux {
 		}
 
 		webHandlerWithOptionals(apiVersion, endpoint, handler, true, !c.disableHeaderCheck)
+		addRoute(apiVersion, endpoint, methodAPISets)
 	}
 
 	webHandlerV1 := func(endpoint string, handler http.Handler, methodAPISets map[string][]string) {
@@ -412,6 +532,18 @@ func newServerMux(c muxConfig, gateway Gatewayer) *http.ServeMux {
 		webHandler(apiVersion2, "/api/v2"+endpoint, handler, methodAPISets)
 	}
 
+	// cached wraps handlers of confirmed blockchain data with the response cache, if it is enabled
+	var cache *responseCache
+	if c.responseCacheSize > 0 {
+		cache = newResponseCache(gateway, c.responseCacheSize)
+	}
+	cached := func(tagFunc cacheTagFunc, handler http.Handler) http.Handler {
+		if cache == nil {
+			return handler
+		}
+		return cache.middleware(tagFunc, handler)
+	}
+
 	indexHandler := newIndexHandler(c.appLoc, c.enableGUI)
 	if !c.disableCSP {
 		indexHandler = CSPHandler(indexHandler)
@@ -442,6 +574,7 @@ func newServerMux(c muxConfig, gateway Gatewayer) *http.ServeMux {
 	// get the current CSRF token
 	csrfHandlerV1 := func(endpoint string, handler http.Handler) {
 		webHandlerWithOptionals(apiVersion1, "/api/v1"+endpoint, handler, false, !c.disableHeaderCheck)
+		addRoute(apiVersion1, "/api/v1"+endpoint, nil)
 	}
 	csrfHandlerV1("/csrf", getCSRFToken(c.disableCSRF)) // csrf is always available, regardless of the API set
 
@@ -512,7 +645,7 @@ func newServerMux(c muxConfig, gateway Gatewayer) *http.ServeMux {
 	webHandlerV1("/blockchain/progress", blockchainProgressHandler(gateway), map[string][]string{
 		http.MethodGet: []string{EndpointsRead, EndpointsStatus},
 	})
-	webHandlerV1("/block", blockHandler(gateway), map[string][]string{
+	webHandlerV1("/block", cached(blockCacheTag, blockHandler(gateway)), map[string][]string{
 		http.MethodGet: []string{EndpointsRead},
 	})
 	webHandlerV1("/blocks", blocksHandler(gateway), map[string][]string{
@@ -522,6 +655,9 @@ func newServerMux(c muxConfig, gateway Gatewayer) *http.ServeMux {
 	webHandlerV1("/last_blocks", lastBlocksHandler(gateway), map[string][]string{
 		http.MethodGet: []string{EndpointsRead},
 	})
+	webHandlerV2("/blocks", blocksHandlerV2(gateway), map[string][]string{
+		http.MethodGet: []string{EndpointsRead},
+	})
 
 	// Network stats endpoints
 	webHandlerV1("/network/connection", connectionHandler(gateway), map[string][]string{
@@ -544,12 +680,29 @@ func newServerMux(c muxConfig, gateway Gatewayer) *http.ServeMux {
 	webHandlerV1("/network/connection/disconnect", disconnectHandler(gateway), map[string][]string{
 		http.MethodPost: []string{EndpointsNetCtrl},
 	})
+	webHandlerV2("/network/stats", networkStatsHandler(gateway), map[string][]string{
+		http.MethodGet: []string{EndpointsRead, EndpointsStatus},
+	})
+	webHandlerV2("/network/bans", bansHandler(gateway), map[string][]string{
+		http.MethodGet: []string{EndpointsRead, EndpointsStatus},
+	})
+	webHandlerV2("/network/bans/add", banPeerHandler(gateway), map[string][]string{
+		http.MethodPost: []string{EndpointsNetCtrl},
+	})
+	webHandlerV2("/network/bans/remove", unbanPeerHandler(gateway), map[string][]string{
+		http.MethodPost: []string{EndpointsNetCtrl},
+	})
+
+	// Database admin endpoints
+	webHandlerV2("/db/backup", dbBackupHandler(gateway), map[string][]string{
+		http.MethodPost: []string{EndpointsDBAdmin},
+	})
 
 	// Transaction related endpoints
 	webHandlerV1("/pendingTxs", pendingTxnsHandler(gateway), map[string][]string{
 		http.MethodGet: []string{EndpointsRead},
 	})
-	webHandlerV1("/transaction", transactionHandler(gateway), map[string][]string{
+	webHandlerV1("/transaction", cached(transactionCacheTag, transactionHandler(gateway)), map[string][]string{
 		http.MethodGet: []string{EndpointsRead},
 	})
 	webHandlerV2("/transaction", transactionHandlerV2(gateway), map[string][]string{
@@ -582,15 +735,21 @@ func newServerMux(c muxConfig, gateway Gatewayer) *http.ServeMux {
 		http.MethodGet:  []string{EndpointsRead},
 		http.MethodPost: []string{EndpointsRead},
 	})
-	webHandlerV1("/uxout", uxOutHandler(gateway), map[string][]string{
+	webHandlerV2("/outputs", outputsHandlerV2(gateway), map[string][]string{
+		http.MethodPost: []string{EndpointsRead},
+	})
+	webHandlerV2("/balance", balanceHandlerV2(gateway), map[string][]string{
+		http.MethodPost: []string{EndpointsRead},
+	})
+	webHandlerV1("/uxout", cached(uxOutCacheTag, uxOutHandler(gateway)), map[string][]string{
 		http.MethodGet: []string{EndpointsRead},
 	})
 	webHandlerV1("/address_uxouts", addrUxOutsHandler(gateway), map[string][]string{
 		http.MethodGet: []string{EndpointsRead},
 	})
 
-	// golang process internal metrics for Prometheus
-	webHandlerV2("/metrics", promhttp.Handler().(http.Handler), map[string][]string{
+	// golang process internal metrics and network metrics for Prometheus
+	webHandlerV2("/metrics", metricsHandler(gateway), map[string][]string{
 		http.MethodGet: []string{EndpointsPrometheus},
 	})
 
@@ -598,6 +757,10 @@ func newServerMux(c muxConfig, gateway Gatewayer) *http.ServeMux {
 	webHandlerV2("/address/verify", http.HandlerFunc(addressVerifyHandler), map[string][]string{
 		http.MethodPost: []string{EndpointsRead},
 	})
+	webHandlerV2("/address/history", addressHistoryHandler(gateway), map[string][]string{
+		http.MethodGet:  []string{EndpointsRead},
+		http.MethodPost: []string{EndpointsRead},
+	})
 
 	// Explorer endpoints
 	webHandlerV1("/coinSupply", coinSupplyHandler(gateway), map[string][]string{
@@ -609,8 +772,16 @@ func newServerMux(c muxConfig, gateway Gatewayer) *http.ServeMux {
 	webHandlerV1("/addresscount", addressCountHandler(gateway), map[string][]string{
 		http.MethodGet: []string{EndpointsRead},
 	})
+	webHandlerV2("/stats/series", statsSeriesHandler(gateway), map[string][]string{
+		http.MethodGet: []string{EndpointsRead},
+	})
 
-	return mux
+	// OpenAPI specification of the routes registered above
+	webHandlerV2("/openapi.json", openAPIHandler(c.health.BuildInfo.Version, func() []apiRoute {
+		return routes
+	}), nil) // openapi.json is always available, regardless of the API set
+
+	return mux, routes
 }
 
 // newIndexHandler returns a http.Handler for index.html, where index.html is in appLoc
@@ -672,6 +843,56 @@ func parseAddressesFromStr(s string) ([]cipher.Address, error) {
 	return addrs, nil
 }
 
+// parseAddressList parses a list of address strings into []cipher.Address, removing duplicates
+func parseAddressList(addrsStr []string) ([]cipher.Address, error) {
+	addrs := make([]cipher.Address, 0, len(addrsStr))
+	seen := make(map[cipher.Address]struct{}, len(addrsStr))
+	for _, s := range addrsStr {
+		a, err := cipher.DecodeBase58Address(s)
+		if err != nil {
+			return nil, fmt.Errorf("address %q is invalid: %v", s, err)
+		}
+
+		if _, ok := seen[a]; ok {
+			continue
+		}
+		seen[a] = struct{}{}
+
+		addrs = append(addrs, a)
+	}
+
+	return addrs, nil
+}
+
+// checkBulkRequestItems returns an error if a bulk request has more than maxBulkRequestItems items
+func checkBulkRequestItems(name string, n int) error {
+	if n > maxBulkRequestItems {
+		return fmt.Errorf("too many %s, the maximum is %d", name, maxBulkRequestItems)
+	}
+	return nil
+}
+
+// parseHashList parses a list of hex hash strings into []cipher.SHA256, removing duplicates
+func parseHashList(hashesStr []string) ([]cipher.SHA256, error) {
+	hashes := make([]cipher.SHA256, 0, len(hashesStr))
+	seen := make(map[cipher.SHA256]struct{}, len(hashesStr))
+	for _, s := range hashesStr {
+		h, err := cipher.SHA256FromHex(s)
+		if err != nil {
+			return nil, fmt.Errorf("SHA256 hash %q is invalid: %v", s, err)
+		}
+
+		if _, ok := seen[h]; ok {
+			continue
+		}
+		seen[h] = struct{}{}
+
+		hashes = append(hashes, h)
+	}
+
+	return hashes, nil
+}
+
 // parseAddressesFromStr parses comma-separated hashes string into []cipher.SHA256
 func parseHashesFromStr(s string) ([]cipher.SHA256, error) {
 	hashesStr := splitCommaString(s)
@@ -22,6 +22,7 @@ var allAPISetsEnabled = map[string]struct{}{
 	EndpointsInsecureWalletSeed: struct{}{},
 	EndpointsPrometheus:         struct{}{},
 	EndpointsNetCtrl:            struct{}{},
+	EndpointsDBAdmin:            struct{}{},
 }
 
 func defaultMuxConfig() muxConfig {
@@ -1441,6 +1441,133 @@ func testBlocksInRange(t *testing.T, start, end uint64) *readable.Blocks {
 	return blocks
 }
 
+func TestStableBlocksInTimeRange(t *testing.T) {
+	if !doStable(t) {
+		return
+	}
+
+	testBlocksInTimeRange(t)
+}
+
+func TestLiveBlocksInTimeRange(t *testing.T) {
+	if !doLive(t) {
+		return
+	}
+
+	testBlocksInTimeRange(t)
+}
+
+func testBlocksInTimeRange(t *testing.T) {
+	c := newClient()
+
+	blocks := testBlocksInRange(t, 1, 10)
+	start := blocks.Blocks[0].Head.Time
+	end := blocks.Blocks[len(blocks.Blocks)-1].Head.Time
+
+	// The blocks in the time range of blocks 1 to 10 include blocks 1 to 10,
+	// and the blocks before or after them with the same times
+	timeBlocks, err := c.BlocksInTimeRange(start, end)
+	require.NoError(t, err)
+	require.True(t, len(timeBlocks.Blocks) >= len(blocks.Blocks))
+
+	for _, b := range timeBlocks.Blocks {
+		require.True(t, b.Head.Time >= start)
+		require.True(t, b.Head.Time <= end)
+	}
+
+	for _, b := range blocks.Blocks {
+		found := false
+		for _, tb := range timeBlocks.Blocks {
+			if tb.Head.BkSeq == b.Head.BkSeq {
+				require.Equal(t, b, tb)
+				found = true
+				break
+			}
+		}
+		require.True(t, found)
+	}
+
+	// No blocks are before the genesis block
+	genesis, err := c.BlockBySeq(0)
+	require.NoError(t, err)
+	if genesis.Head.Time > 0 {
+		timeBlocks, err = c.BlocksInTimeRange(0, genesis.Head.Time-1)
+		require.NoError(t, err)
+		require.Empty(t, timeBlocks.Blocks)
+	}
+}
+
+func TestStableStatsSeries(t *testing.T) {
+	if !doStable(t) {
+		return
+	}
+
+	testStatsSeries(t)
+}
+
+func TestLiveStatsSeries(t *testing.T) {
+	if !doLive(t) {
+		return
+	}
+
+	testStatsSeries(t)
+}
+
+func testStatsSeries(t *testing.T) {
+	c := newClient()
+
+	blocks := testBlocksInRange(t, 0, 10)
+	start := blocks.Blocks[0].Head.Time
+	end := blocks.Blocks[len(blocks.Blocks)-1].Head.Time
+
+	// The block series has a point for each block in the time range
+	blockSeries, err := c.StatsSeries(api.StatsSeriesParams{
+		Metric:    "transactions",
+		Interval:  "block",
+		StartTime: start,
+		EndTime:   end,
+	})
+	require.NoError(t, err)
+	require.Equal(t, "transactions", blockSeries.Metric)
+	require.Equal(t, "block", blockSeries.Interval)
+	require.True(t, len(blockSeries.Points) >= len(blocks.Blocks))
+
+	for _, b := range blocks.Blocks {
+		found := false
+		for _, p := range blockSeries.Points {
+			if p.Seq == b.Head.BkSeq {
+				require.Equal(t, b.Head.Time, p.Time)
+				require.Equal(t, strconv.Itoa(len(b.Body.Transactions)), p.Value)
+				found = true
+				break
+			}
+		}
+		require.True(t, found)
+	}
+
+	// The daily series has a point for each day with blocks
+	daySeries, err := c.StatsSeries(api.StatsSeriesParams{
+		Metric: "coin_supply",
+	})
+	require.NoError(t, err)
+	require.Equal(t, "day", daySeries.Interval)
+	require.NotEmpty(t, daySeries.Points)
+
+	for i, p := range daySeries.Points {
+		require.Equal(t, uint64(0), p.Time%86400)
+		require.NotEqual(t, "0", p.Value)
+		if i > 0 {
+			require.True(t, p.Time > daySeries.Points[i-1].Time)
+			require.True(t, p.Seq > daySeries.Points[i-1].Seq)
+		}
+	}
+
+	_, err = c.StatsSeries(api.StatsSeriesParams{
+		Metric: "foo",
+	})
+	assertResponseError(t, err, http.StatusBadRequest, `Invalid metric "foo", must be one of [coin_supply coin_hour_supply transactions fees_burned active_addresses]`)
+}
+
 func TestStableBlocksInRangeVerbose(t *testing.T) {
 	if !doStable(t) {
 		return
@@ -3290,6 +3417,16 @@ func TestStableRichlist(t *testing.T) {
 	expected = api.Richlist{}
 	checkGoldenFile(t, "richlist-8.golden", TestData{*richlist, &expected})
 
+	richlist, err = c.Richlist(&api.RichlistParams{
+		N:                   8,
+		Page:                2,
+		IncludeDistribution: false,
+	})
+	require.NoError(t, err)
+
+	expected = api.Richlist{}
+	checkGoldenFile(t, "richlist-8-page-2.golden", TestData{*richlist, &expected})
+
 	richlist, err = c.Richlist(&api.RichlistParams{
 		N:                   150,
 		IncludeDistribution: true,
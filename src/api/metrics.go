@@ -0,0 +1,147 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/skycoin/skycoin/src/daemon"
+	"github.com/skycoin/skycoin/src/daemon/gnet"
+)
+
+const (
+	directionSent     = "sent"
+	directionReceived = "received"
+)
+
+// metricsHandler serves the metrics of the default Prometheus registry,
+// which include the Go process metrics, and the network metrics of the gateway
+// URI: /api/v2/metrics
+// Method: GET
+func metricsHandler(gateway Gatewayer) http.Handler {
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(newNetworkCollector(gateway))
+
+	return promhttp.HandlerFor(prometheus.Gatherers{
+		prometheus.DefaultGatherer,
+		reg,
+	}, promhttp.HandlerOpts{})
+}
+
+// networkCollector exports the traffic of the node and of each connection, and the traffic saved by
+// not announcing inventory to peers that already have it, read from the gateway on each scrape
+type networkCollector struct {
+	gateway      Gatewayer
+	messages     *prometheus.Desc
+	bytes        *prometheus.Desc
+	rate         *prometheus.Desc
+	peerMessages *prometheus.Desc
+	peerBytes    *prometheus.Desc
+	peerRate     *prometheus.Desc
+
+	suppressedTxnHashes *prometheus.Desc
+	suppressedBlocks    *prometheus.Desc
+	suppressedMessages  *prometheus.Desc
+	savedBytes          *prometheus.Desc
+}
+
+func newNetworkCollector(gateway Gatewayer) *networkCollector {
+	name := func(n string) string {
+		return prometheus.BuildFQName("skycoin", "network", n)
+	}
+
+	return &networkCollector{
+		gateway: gateway,
+		messages: prometheus.NewDesc(name("messages_total"),
+			"Number of messages sent and received since the node started, by message prefix",
+			[]string{"direction", "message"}, nil),
+		bytes: prometheus.NewDesc(name("bytes_total"),
+			"Number of bytes sent and received since the node started, by message prefix",
+			[]string{"direction", "message"}, nil),
+		rate: prometheus.NewDesc(name("bytes_per_second"),
+			"Bytes per second sent and received, averaged over the last minute",
+			[]string{"direction"}, nil),
+		peerMessages: prometheus.NewDesc(name("peer_messages_total"),
+			"Number of messages sent to and received from a connected peer",
+			[]string{"direction", "address"}, nil),
+		peerBytes: prometheus.NewDesc(name("peer_bytes_total"),
+			"Number of bytes sent to and received from a connected peer",
+			[]string{"direction", "address"}, nil),
+		peerRate: prometheus.NewDesc(name("peer_bytes_per_second"),
+			"Bytes per second sent to and received from a connected peer, averaged over the last minute",
+			[]string{"direction", "address"}, nil),
+		suppressedTxnHashes: prometheus.NewDesc(name("inventory_suppressed_txn_hashes_total"),
+			"Number of transaction hashes not announced to peers that already had them",
+			nil, nil),
+		suppressedBlocks: prometheus.NewDesc(name("inventory_suppressed_blocks_total"),
+			"Number of blocks not relayed to peers that already had them",
+			nil, nil),
+		suppressedMessages: prometheus.NewDesc(name("inventory_suppressed_messages_total"),
+			"Number of announcement and block messages not sent, because the peer already had all of their inventory",
+			nil, nil),
+		savedBytes: prometheus.NewDesc(name("inventory_saved_bytes_total"),
+			"Estimated number of bytes not sent to peers that already had the inventory",
+			nil, nil),
+	}
+}
+
+// Describe implements prometheus.Collector
+func (c *networkCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.messages
+	ch <- c.bytes
+	ch <- c.rate
+	ch <- c.peerMessages
+	ch <- c.peerBytes
+	ch <- c.peerRate
+	ch <- c.suppressedTxnHashes
+	ch <- c.suppressedBlocks
+	ch <- c.suppressedMessages
+	ch <- c.savedBytes
+}
+
+// Collect implements prometheus.Collector
+func (c *networkCollector) Collect(ch chan<- prometheus.Metric) {
+	stats, err := c.gateway.GetNetworkStats()
+	if err != nil {
+		logger.WithError(err).Error("networkCollector: GetNetworkStats failed")
+		ch <- prometheus.NewInvalidMetric(c.bytes, err)
+		return
+	}
+
+	for prefix, s := range stats.Messages {
+		c.collectTraffic(ch, c.messages, c.bytes, s, prefix)
+	}
+	ch <- prometheus.MustNewConstMetric(c.rate, prometheus.GaugeValue, stats.SendRate, directionSent)
+	ch <- prometheus.MustNewConstMetric(c.rate, prometheus.GaugeValue, stats.ReceiveRate, directionReceived)
+
+	inv := c.gateway.GetInventoryStats()
+	ch <- prometheus.MustNewConstMetric(c.suppressedTxnHashes, prometheus.CounterValue, float64(inv.SuppressedTxnHashes))
+	ch <- prometheus.MustNewConstMetric(c.suppressedBlocks, prometheus.CounterValue, float64(inv.SuppressedBlocks))
+	ch <- prometheus.MustNewConstMetric(c.suppressedMessages, prometheus.CounterValue, float64(inv.SuppressedMessages))
+	ch <- prometheus.MustNewConstMetric(c.savedBytes, prometheus.CounterValue, float64(inv.SavedBytes))
+
+	conns, err := c.gateway.GetConnections(func(conn daemon.Connection) bool {
+		return conn.State != daemon.ConnectionStatePending
+	})
+	if err != nil {
+		logger.WithError(err).Error("networkCollector: GetConnections failed")
+		ch <- prometheus.NewInvalidMetric(c.peerBytes, err)
+		return
+	}
+
+	for _, conn := range conns {
+		s := conn.Gnet.Stats
+		c.collectTraffic(ch, c.peerMessages, c.peerBytes, s.TrafficStats, conn.Addr)
+		ch <- prometheus.MustNewConstMetric(c.peerRate, prometheus.GaugeValue, s.SendRate, directionSent, conn.Addr)
+		ch <- prometheus.MustNewConstMetric(c.peerRate, prometheus.GaugeValue, s.ReceiveRate, directionReceived, conn.Addr)
+	}
+}
+
+// collectTraffic sends the message and byte counters of s. The descriptions are labeled by direction and label
+func (c *networkCollector) collectTraffic(ch chan<- prometheus.Metric, messages, bytes *prometheus.Desc, s gnet.TrafficStats, label string) {
+	ch <- prometheus.MustNewConstMetric(messages, prometheus.CounterValue, float64(s.MessagesSent), directionSent, label)
+	ch <- prometheus.MustNewConstMetric(messages, prometheus.CounterValue, float64(s.MessagesReceived), directionReceived, label)
+	ch <- prometheus.MustNewConstMetric(bytes, prometheus.CounterValue, float64(s.BytesSent), directionSent, label)
+	ch <- prometheus.MustNewConstMetric(bytes, prometheus.CounterValue, float64(s.BytesReceived), directionReceived, label)
+}
@@ -0,0 +1,132 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/daemon"
+	"github.com/skycoin/skycoin/src/daemon/gnet"
+)
+
+func TestMetricsNetworkStats(t *testing.T) {
+	stats := gnet.NetworkStats{
+		TrafficStats: gnet.TrafficStats{
+			MessagesSent:     3,
+			BytesSent:        3060,
+			MessagesReceived: 1,
+			BytesReceived:    120,
+		},
+		SendRate:    51,
+		ReceiveRate: 2,
+		Messages: map[string]gnet.TrafficStats{
+			"GIVB": {
+				MessagesSent: 2,
+				BytesSent:    3000,
+			},
+			"PING": {
+				MessagesSent:     1,
+				BytesSent:        60,
+				MessagesReceived: 1,
+				BytesReceived:    120,
+			},
+		},
+	}
+
+	inventory := daemon.InventoryStats{
+		SuppressedTxnHashes: 20,
+		SuppressedBlocks:    1,
+		SuppressedMessages:  2,
+		SavedBytes:          1200,
+	}
+
+	conns := []daemon.Connection{
+		{
+			Addr: "127.0.0.1:6061",
+			Gnet: daemon.GnetConnectionDetails{
+				ID: 1,
+				Stats: gnet.NetworkStats{
+					TrafficStats: gnet.TrafficStats{
+						MessagesSent: 2,
+						BytesSent:    3000,
+					},
+					SendRate: 50,
+				},
+			},
+		},
+	}
+
+	cases := []struct {
+		name               string
+		getNetworkStatsErr error
+		getConnectionsErr  error
+		contains           []string
+	}{
+		{
+			name: "ok",
+			contains: []string{
+				`skycoin_network_messages_total{direction="sent",message="GIVB"} 2`,
+				`skycoin_network_bytes_total{direction="sent",message="GIVB"} 3000`,
+				`skycoin_network_bytes_total{direction="received",message="PING"} 120`,
+				`skycoin_network_bytes_per_second{direction="sent"} 51`,
+				`skycoin_network_bytes_per_second{direction="received"} 2`,
+				`skycoin_network_peer_messages_total{address="127.0.0.1:6061",direction="sent"} 2`,
+				`skycoin_network_peer_bytes_total{address="127.0.0.1:6061",direction="sent"} 3000`,
+				`skycoin_network_peer_bytes_per_second{address="127.0.0.1:6061",direction="sent"} 50`,
+				`skycoin_network_inventory_suppressed_txn_hashes_total 20`,
+				`skycoin_network_inventory_suppressed_blocks_total 1`,
+				`skycoin_network_inventory_suppressed_messages_total 2`,
+				`skycoin_network_inventory_saved_bytes_total 1200`,
+				// Go process metrics from the default registry are still served
+				`go_goroutines`,
+			},
+		},
+		{
+			name:               "GetNetworkStats failed",
+			getNetworkStatsErr: errors.New("GetNetworkStats failed"),
+			contains: []string{
+				`GetNetworkStats failed`,
+			},
+		},
+		{
+			name:              "GetConnections failed",
+			getConnectionsErr: errors.New("GetConnections failed"),
+			contains: []string{
+				`GetConnections failed`,
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			gateway := &MockGatewayer{}
+			gateway.On("GetNetworkStats").Return(stats, tc.getNetworkStatsErr)
+			gateway.On("GetInventoryStats").Return(inventory)
+			gateway.On("GetConnections", mock.Anything).Return(conns, tc.getConnectionsErr)
+
+			req, err := http.NewRequest(http.MethodGet, "/api/v2/metrics", nil)
+			require.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			handler := newServerMux(defaultMuxConfig(), gateway)
+			handler.ServeHTTP(rr, req)
+
+			body := rr.Body.String()
+
+			// A failed collection fails the scrape
+			if tc.getNetworkStatsErr != nil || tc.getConnectionsErr != nil {
+				require.Equal(t, http.StatusInternalServerError, rr.Code)
+			} else {
+				require.Equal(t, http.StatusOK, rr.Code)
+			}
+
+			for _, s := range tc.contains {
+				require.Contains(t, body, s)
+			}
+		})
+	}
+}
@@ -5,8 +5,10 @@ package api
 import cipher "github.com/skycoin/skycoin/src/cipher"
 import coin "github.com/skycoin/skycoin/src/coin"
 import daemon "github.com/skycoin/skycoin/src/daemon"
+import gnet "github.com/skycoin/skycoin/src/daemon/gnet"
 import historydb "github.com/skycoin/skycoin/src/visor/historydb"
 import mock "github.com/stretchr/testify/mock"
+import pex "github.com/skycoin/skycoin/src/daemon/pex"
 import time "time"
 import transaction "github.com/skycoin/skycoin/src/transaction"
 import visor "github.com/skycoin/skycoin/src/visor"
@@ -38,6 +40,57 @@ func (_m *MockGatewayer) AddressCount() (uint64, error) {
 	return r0, r1
 }
 
+// BackupDBToTempFile provides a mock function with given fields: compress
+func (_m *MockGatewayer) BackupDBToTempFile(compress bool) (string, *visor.BackupManifest, error) {
+	ret := _m.Called(compress)
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func(bool) string); ok {
+		r0 = rf(compress)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	var r1 *visor.BackupManifest
+	if rf, ok := ret.Get(1).(func(bool) *visor.BackupManifest); ok {
+		r1 = rf(compress)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(*visor.BackupManifest)
+		}
+	}
+
+	var r2 error
+	if rf, ok := ret.Get(2).(func(bool) error); ok {
+		r2 = rf(compress)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// BanPeer provides a mock function with given fields: addr, duration, reason
+func (_m *MockGatewayer) BanPeer(addr string, duration time.Duration, reason string) (pex.Ban, error) {
+	ret := _m.Called(addr, duration, reason)
+
+	var r0 pex.Ban
+	if rf, ok := ret.Get(0).(func(string, time.Duration, string) pex.Ban); ok {
+		r0 = rf(addr, duration, reason)
+	} else {
+		r0 = ret.Get(0).(pex.Ban)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, time.Duration, string) error); ok {
+		r1 = rf(addr, duration, reason)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // CreateTransaction provides a mock function with given fields: p, wp
 func (_m *MockGatewayer) CreateTransaction(p transaction.Params, wp visor.CreateTransactionParams) (*coin.Transaction, []visor.TransactionInput, error) {
 	ret := _m.Called(p, wp)
@@ -167,6 +220,52 @@ func (_m *MockGatewayer) EncryptWallet(wltID string, password []byte) (*wallet.W
 	return r0, r1
 }
 
+// GetAddressBalances provides a mock function with given fields: addrs
+func (_m *MockGatewayer) GetAddressBalances(addrs []cipher.Address) (*visor.AddressBalances, error) {
+	ret := _m.Called(addrs)
+
+	var r0 *visor.AddressBalances
+	if rf, ok := ret.Get(0).(func([]cipher.Address) *visor.AddressBalances); ok {
+		r0 = rf(addrs)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*visor.AddressBalances)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func([]cipher.Address) error); ok {
+		r1 = rf(addrs)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetAddressesHistory provides a mock function with given fields: addrs
+func (_m *MockGatewayer) GetAddressesHistory(addrs []cipher.Address) ([]visor.AddressHistory, error) {
+	ret := _m.Called(addrs)
+
+	var r0 []visor.AddressHistory
+	if rf, ok := ret.Get(0).(func([]cipher.Address) []visor.AddressHistory); ok {
+		r0 = rf(addrs)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]visor.AddressHistory)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func([]cipher.Address) error); ok {
+		r1 = rf(addrs)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetAllUnconfirmedTransactions provides a mock function with given fields:
 func (_m *MockGatewayer) GetAllUnconfirmedTransactions() ([]visor.UnconfirmedTransaction, error) {
 	ret := _m.Called()
@@ -245,6 +344,22 @@ func (_m *MockGatewayer) GetBalanceOfAddrs(addrs []cipher.Address) ([]wallet.Bal
 	return r0, r1
 }
 
+// GetBans provides a mock function with given fields:
+func (_m *MockGatewayer) GetBans() []pex.Ban {
+	ret := _m.Called()
+
+	var r0 []pex.Ban
+	if rf, ok := ret.Get(0).(func() []pex.Ban); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]pex.Ban)
+		}
+	}
+
+	return r0
+}
+
 // GetBlockchainMetadata provides a mock function with given fields:
 func (_m *MockGatewayer) GetBlockchainMetadata() (*visor.BlockchainMetadata, error) {
 	ret := _m.Called()
@@ -362,6 +477,61 @@ func (_m *MockGatewayer) GetBlocksInRangeVerbose(start uint64, end uint64) ([]co
 	return r0, r1, r2
 }
 
+// GetBlocksInTimeRange provides a mock function with given fields: start, end
+func (_m *MockGatewayer) GetBlocksInTimeRange(start uint64, end uint64) ([]coin.SignedBlock, error) {
+	ret := _m.Called(start, end)
+
+	var r0 []coin.SignedBlock
+	if rf, ok := ret.Get(0).(func(uint64, uint64) []coin.SignedBlock); ok {
+		r0 = rf(start, end)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]coin.SignedBlock)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(uint64, uint64) error); ok {
+		r1 = rf(start, end)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetBlocksInTimeRangeVerbose provides a mock function with given fields: start, end
+func (_m *MockGatewayer) GetBlocksInTimeRangeVerbose(start uint64, end uint64) ([]coin.SignedBlock, [][][]visor.TransactionInput, error) {
+	ret := _m.Called(start, end)
+
+	var r0 []coin.SignedBlock
+	if rf, ok := ret.Get(0).(func(uint64, uint64) []coin.SignedBlock); ok {
+		r0 = rf(start, end)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]coin.SignedBlock)
+		}
+	}
+
+	var r1 [][][]visor.TransactionInput
+	if rf, ok := ret.Get(1).(func(uint64, uint64) [][][]visor.TransactionInput); ok {
+		r1 = rf(start, end)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).([][][]visor.TransactionInput)
+		}
+	}
+
+	var r2 error
+	if rf, ok := ret.Get(2).(func(uint64, uint64) error); ok {
+		r2 = rf(start, end)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
 // GetBlocksVerbose provides a mock function with given fields: seqs
 func (_m *MockGatewayer) GetBlocksVerbose(seqs []uint64) ([]coin.SignedBlock, [][][]visor.TransactionInput, error) {
 	ret := _m.Called(seqs)
@@ -472,6 +642,20 @@ func (_m *MockGatewayer) GetExchgConnection() []string {
 	return r0
 }
 
+// GetInventoryStats provides a mock function with given fields:
+func (_m *MockGatewayer) GetInventoryStats() daemon.InventoryStats {
+	ret := _m.Called()
+
+	var r0 daemon.InventoryStats
+	if rf, ok := ret.Get(0).(func() daemon.InventoryStats); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(daemon.InventoryStats)
+	}
+
+	return r0
+}
+
 // GetLastBlocks provides a mock function with given fields: num
 func (_m *MockGatewayer) GetLastBlocks(num uint64) ([]coin.SignedBlock, error) {
 	ret := _m.Called(num)
@@ -527,27 +711,55 @@ func (_m *MockGatewayer) GetLastBlocksVerbose(num uint64) ([]coin.SignedBlock, [
 	return r0, r1, r2
 }
 
-// GetRichlist provides a mock function with given fields: includeDistribution
-func (_m *MockGatewayer) GetRichlist(includeDistribution bool) (visor.Richlist, error) {
-	ret := _m.Called(includeDistribution)
+// GetNetworkStats provides a mock function with given fields:
+func (_m *MockGatewayer) GetNetworkStats() (gnet.NetworkStats, error) {
+	ret := _m.Called()
+
+	var r0 gnet.NetworkStats
+	if rf, ok := ret.Get(0).(func() gnet.NetworkStats); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(gnet.NetworkStats)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetRichlist provides a mock function with given fields: includeDistribution, offset, n
+func (_m *MockGatewayer) GetRichlist(includeDistribution bool, offset uint64, n uint64) (visor.Richlist, uint64, error) {
+	ret := _m.Called(includeDistribution, offset, n)
 
 	var r0 visor.Richlist
-	if rf, ok := ret.Get(0).(func(bool) visor.Richlist); ok {
-		r0 = rf(includeDistribution)
+	if rf, ok := ret.Get(0).(func(bool, uint64, uint64) visor.Richlist); ok {
+		r0 = rf(includeDistribution, offset, n)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(visor.Richlist)
 		}
 	}
 
-	var r1 error
-	if rf, ok := ret.Get(1).(func(bool) error); ok {
-		r1 = rf(includeDistribution)
+	var r1 uint64
+	if rf, ok := ret.Get(1).(func(bool, uint64, uint64) uint64); ok {
+		r1 = rf(includeDistribution, offset, n)
 	} else {
-		r1 = ret.Error(1)
+		r1 = ret.Get(1).(uint64)
 	}
 
-	return r0, r1
+	var r2 error
+	if rf, ok := ret.Get(2).(func(bool, uint64, uint64) error); ok {
+		r2 = rf(includeDistribution, offset, n)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
 }
 
 // GetSignedBlockByHash provides a mock function with given fields: hash
@@ -683,6 +895,29 @@ func (_m *MockGatewayer) GetSpentOutputsForAddresses(addr []cipher.Address) ([][
 	return r0, r1
 }
 
+// GetStatsSeries provides a mock function with given fields: metric, interval, start, end
+func (_m *MockGatewayer) GetStatsSeries(metric visor.StatsMetric, interval visor.StatsInterval, start uint64, end uint64) ([]visor.StatsPoint, error) {
+	ret := _m.Called(metric, interval, start, end)
+
+	var r0 []visor.StatsPoint
+	if rf, ok := ret.Get(0).(func(visor.StatsMetric, visor.StatsInterval, uint64, uint64) []visor.StatsPoint); ok {
+		r0 = rf(metric, interval, start, end)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]visor.StatsPoint)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(visor.StatsMetric, visor.StatsInterval, uint64, uint64) error); ok {
+		r1 = rf(metric, interval, start, end)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetTransaction provides a mock function with given fields: txid
 func (_m *MockGatewayer) GetTransaction(txid cipher.SHA256) (*visor.Transaction, error) {
 	ret := _m.Called(txid)
@@ -832,6 +1067,52 @@ func (_m *MockGatewayer) GetUnspentOutputsSummary(filters []visor.OutputsFilter)
 	return r0, r1
 }
 
+// GetUnspentOutputsSummaryOfAddrs provides a mock function with given fields: addrs
+func (_m *MockGatewayer) GetUnspentOutputsSummaryOfAddrs(addrs []cipher.Address) (*visor.UnspentOutputsSummary, error) {
+	ret := _m.Called(addrs)
+
+	var r0 *visor.UnspentOutputsSummary
+	if rf, ok := ret.Get(0).(func([]cipher.Address) *visor.UnspentOutputsSummary); ok {
+		r0 = rf(addrs)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*visor.UnspentOutputsSummary)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func([]cipher.Address) error); ok {
+		r1 = rf(addrs)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetUnspentOutputsSummaryOfHashes provides a mock function with given fields: hashes
+func (_m *MockGatewayer) GetUnspentOutputsSummaryOfHashes(hashes []cipher.SHA256) (*visor.UnspentOutputsSummary, error) {
+	ret := _m.Called(hashes)
+
+	var r0 *visor.UnspentOutputsSummary
+	if rf, ok := ret.Get(0).(func([]cipher.SHA256) *visor.UnspentOutputsSummary); ok {
+		r0 = rf(hashes)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*visor.UnspentOutputsSummary)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func([]cipher.SHA256) error); ok {
+		r1 = rf(hashes)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetUxOutByID provides a mock function with given fields: id
 func (_m *MockGatewayer) GetUxOutByID(id cipher.SHA256) (*historydb.UxOut, error) {
 	ret := _m.Called(id)
@@ -1164,6 +1445,20 @@ func (_m *MockGatewayer) StartedAt() time.Time {
 	return r0
 }
 
+// UnbanPeer provides a mock function with given fields: addr
+func (_m *MockGatewayer) UnbanPeer(addr string) error {
+	ret := _m.Called(addr)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string) error); ok {
+		r0 = rf(addr)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // UnloadWallet provides a mock function with given fields: wltID
 func (_m *MockGatewayer) UnloadWallet(wltID string) error {
 	ret := _m.Called(wltID)
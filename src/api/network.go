@@ -3,6 +3,7 @@ package api
 // APIs for network-related information
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"sort"
@@ -10,6 +11,7 @@ import (
 	"strings"
 
 	"github.com/skycoin/skycoin/src/daemon"
+	"github.com/skycoin/skycoin/src/daemon/pex"
 	"github.com/skycoin/skycoin/src/readable"
 	wh "github.com/skycoin/skycoin/src/util/http"
 )
@@ -226,3 +228,171 @@ func disconnectHandler(gateway Gatewayer) http.HandlerFunc {
 		wh.SendJSONOr500(logger, w, struct{}{})
 	}
 }
+
+// networkStatsHandler returns the messages and bytes sent and received over all connections
+// since the node started, by message prefix, and the current send and receive rates
+// URI: /api/v2/network/stats
+// Method: GET
+func networkStatsHandler(gateway Gatewayer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			resp := NewHTTPErrorResponse(http.StatusMethodNotAllowed, "")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		stats, err := gateway.GetNetworkStats()
+		if err != nil {
+			resp := NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		writeHTTPResponse(w, HTTPResponse{
+			Data: readable.NewNetworkStats(stats),
+		})
+	}
+}
+
+// bansHandler returns the banned IPs
+// URI: /api/v2/network/bans
+// Method: GET
+func bansHandler(gateway Gatewayer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			resp := NewHTTPErrorResponse(http.StatusMethodNotAllowed, "")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		writeHTTPResponse(w, HTTPResponse{
+			Data: readable.NewBans(gateway.GetBans()),
+		})
+	}
+}
+
+// BanPeerRequest is the request data for POST /api/v2/network/bans/add
+type BanPeerRequest struct {
+	// Address is an IP or IP:Port string. All connections from the IP are banned
+	Address string `json:"address"`
+	// Duration of the ban, defaults to the node's ban duration for misbehaving peers
+	Duration wh.Duration `json:"duration"`
+	Reason   string      `json:"reason"`
+}
+
+// banPeerHandler bans an IP and disconnects all connections from it
+// URI: /api/v2/network/bans/add
+// Method: POST
+// Content-Type: application/json
+// Body: {"address": "<ip>", "duration": "24h", "reason": "<reason>"}
+func banPeerHandler(gateway Gatewayer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			resp := NewHTTPErrorResponse(http.StatusMethodNotAllowed, "")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		if r.Header.Get("Content-Type") != ContentTypeJSON {
+			resp := NewHTTPErrorResponse(http.StatusUnsupportedMediaType, "")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		var req BanPeerRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, err.Error())
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		if req.Address == "" {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, "address is required")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		duration := req.Duration.Duration
+		if duration == 0 {
+			duration = gateway.DaemonConfig().BanDuration
+		}
+
+		reason := req.Reason
+		if reason == "" {
+			reason = "banned by the node operator"
+		}
+
+		b, err := gateway.BanPeer(req.Address, duration, reason)
+		if err != nil {
+			var resp HTTPResponse
+			switch err {
+			case pex.ErrInvalidAddress, pex.ErrInvalidBanDuration:
+				resp = NewHTTPErrorResponse(http.StatusBadRequest, err.Error())
+			default:
+				resp = NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())
+			}
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		writeHTTPResponse(w, HTTPResponse{
+			Data: readable.NewBan(b),
+		})
+	}
+}
+
+// UnbanPeerRequest is the request data for POST /api/v2/network/bans/remove
+type UnbanPeerRequest struct {
+	// Address is an IP or IP:Port string
+	Address string `json:"address"`
+}
+
+// unbanPeerHandler removes the ban of an IP
+// URI: /api/v2/network/bans/remove
+// Method: POST
+// Content-Type: application/json
+// Body: {"address": "<ip>"}
+func unbanPeerHandler(gateway Gatewayer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			resp := NewHTTPErrorResponse(http.StatusMethodNotAllowed, "")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		if r.Header.Get("Content-Type") != ContentTypeJSON {
+			resp := NewHTTPErrorResponse(http.StatusUnsupportedMediaType, "")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		var req UnbanPeerRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, err.Error())
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		if req.Address == "" {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, "address is required")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		if err := gateway.UnbanPeer(req.Address); err != nil {
+			var resp HTTPResponse
+			switch err {
+			case pex.ErrInvalidAddress:
+				resp = NewHTTPErrorResponse(http.StatusBadRequest, err.Error())
+			case pex.ErrNotBanned:
+				resp = NewHTTPErrorResponse(http.StatusNotFound, err.Error())
+			default:
+				resp = NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())
+			}
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		writeHTTPResponse(w, HTTPResponse{Data: struct{}{}})
+	}
+}
@@ -14,6 +14,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/skycoin/skycoin/src/daemon"
+	"github.com/skycoin/skycoin/src/daemon/gnet"
 	"github.com/skycoin/skycoin/src/daemon/pex"
 	"github.com/skycoin/skycoin/src/readable"
 	"github.com/skycoin/skycoin/src/util/useragent"
@@ -57,6 +58,26 @@ func TestConnection(t *testing.T) {
 					ID:           1,
 					LastSent:     time.Unix(99999, 0),
 					LastReceived: time.Unix(1111111, 0),
+					Stats: gnet.NetworkStats{
+						TrafficStats: gnet.TrafficStats{
+							MessagesSent:     2,
+							BytesSent:        100,
+							MessagesReceived: 1,
+							BytesReceived:    20,
+						},
+						SendRate:    1.5,
+						ReceiveRate: 0.25,
+						Messages: map[string]gnet.TrafficStats{
+							"GIVB": {
+								MessagesSent: 2,
+								BytesSent:    100,
+							},
+							"PING": {
+								MessagesReceived: 1,
+								BytesReceived:    20,
+							},
+						},
+					},
 				},
 				ConnectionDetails: daemon.ConnectionDetails{
 					Outgoing:    true,
@@ -84,6 +105,26 @@ func TestConnection(t *testing.T) {
 				Height:        1234,
 				UserAgent:     useragent.MustParse("skycoin:0.25.1(foo)"),
 				IsTrustedPeer: false,
+				Stats: readable.NetworkStats{
+					TrafficStats: readable.TrafficStats{
+						MessagesSent:     2,
+						BytesSent:        100,
+						MessagesReceived: 1,
+						BytesReceived:    20,
+					},
+					SendRate:    1.5,
+					ReceiveRate: 0.25,
+					Messages: map[string]readable.TrafficStats{
+						"GIVB": {
+							MessagesSent: 2,
+							BytesSent:    100,
+						},
+						"PING": {
+							MessagesReceived: 1,
+							BytesReceived:    20,
+						},
+					},
+				},
 			},
 		},
 
@@ -193,6 +234,9 @@ func TestConnections(t *testing.T) {
 		Height:        1234,
 		UserAgent:     useragent.MustParse("skycoin:0.25.1(foo)"),
 		IsTrustedPeer: true,
+		Stats: readable.NetworkStats{
+			Messages: map[string]readable.TrafficStats{},
+		},
 	}
 
 	readIntrIn := readable.Connection{
@@ -208,6 +252,9 @@ func TestConnections(t *testing.T) {
 		Height:        1234,
 		UserAgent:     useragent.MustParse("skycoin:0.25.1(foo)"),
 		IsTrustedPeer: false,
+		Stats: readable.NetworkStats{
+			Messages: map[string]readable.TrafficStats{},
+		},
 	}
 
 	conns := []daemon.Connection{intrOut, intrIn}
@@ -628,3 +675,424 @@ func TestDisconnect(t *testing.T) {
 		})
 	}
 }
+
+func TestNetworkStats(t *testing.T) {
+	stats := gnet.NetworkStats{
+		TrafficStats: gnet.TrafficStats{
+			MessagesSent:     3,
+			BytesSent:        3060,
+			MessagesReceived: 1,
+			BytesReceived:    120,
+		},
+		SendRate:    51,
+		ReceiveRate: 2,
+		Messages: map[string]gnet.TrafficStats{
+			"GIVB": {
+				MessagesSent: 2,
+				BytesSent:    3000,
+			},
+			"PING": {
+				MessagesSent:     1,
+				BytesSent:        60,
+				MessagesReceived: 1,
+				BytesReceived:    120,
+			},
+		},
+	}
+
+	cases := []struct {
+		name               string
+		method             string
+		status             int
+		getNetworkStats    gnet.NetworkStats
+		getNetworkStatsErr error
+		httpResponse       HTTPResponse
+	}{
+		{
+			name:         "405",
+			method:       http.MethodPost,
+			status:       http.StatusMethodNotAllowed,
+			httpResponse: NewHTTPErrorResponse(http.StatusMethodNotAllowed, ""),
+		},
+		{
+			name:               "500",
+			method:             http.MethodGet,
+			status:             http.StatusInternalServerError,
+			getNetworkStatsErr: errors.New("GetNetworkStats failed"),
+			httpResponse:       NewHTTPErrorResponse(http.StatusInternalServerError, "GetNetworkStats failed"),
+		},
+		{
+			name:            "200",
+			method:          http.MethodGet,
+			status:          http.StatusOK,
+			getNetworkStats: stats,
+			httpResponse: HTTPResponse{
+				Data: readable.NetworkStats{
+					TrafficStats: readable.TrafficStats{
+						MessagesSent:     3,
+						BytesSent:        3060,
+						MessagesReceived: 1,
+						BytesReceived:    120,
+					},
+					SendRate:    51,
+					ReceiveRate: 2,
+					Messages: map[string]readable.TrafficStats{
+						"GIVB": {
+							MessagesSent: 2,
+							BytesSent:    3000,
+						},
+						"PING": {
+							MessagesSent:     1,
+							BytesSent:        60,
+							MessagesReceived: 1,
+							BytesReceived:    120,
+						},
+					},
+				},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			gateway := &MockGatewayer{}
+			gateway.On("GetNetworkStats").Return(tc.getNetworkStats, tc.getNetworkStatsErr)
+
+			req, err := http.NewRequest(tc.method, "/api/v2/network/stats", nil)
+			require.NoError(t, err)
+			setCSRFParameters(t, tokenValid, req)
+
+			rr := httptest.NewRecorder()
+			handler := newServerMux(defaultMuxConfig(), gateway)
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.status, rr.Code)
+
+			var rsp ReceivedHTTPResponse
+			err = json.NewDecoder(rr.Body).Decode(&rsp)
+			require.NoError(t, err)
+
+			require.Equal(t, tc.httpResponse.Error, rsp.Error)
+
+			if rsp.Data == nil {
+				require.Nil(t, tc.httpResponse.Data)
+			} else {
+				var rstats readable.NetworkStats
+				err := json.Unmarshal(rsp.Data, &rstats)
+				require.NoError(t, err)
+				require.Equal(t, tc.httpResponse.Data, rstats)
+			}
+		})
+	}
+}
+
+func TestBans(t *testing.T) {
+	created := time.Unix(1500000000, 0).UTC()
+	bans := []pex.Ban{
+		{
+			IP:      "1.2.3.4",
+			Reason:  "invalid block",
+			Created: created,
+			Expires: created.Add(time.Hour),
+		},
+	}
+
+	cases := []struct {
+		name         string
+		method       string
+		status       int
+		bans         []pex.Ban
+		httpResponse HTTPResponse
+	}{
+		{
+			name:         "405",
+			method:       http.MethodPost,
+			status:       http.StatusMethodNotAllowed,
+			httpResponse: NewHTTPErrorResponse(http.StatusMethodNotAllowed, ""),
+		},
+		{
+			name:   "200 no bans",
+			method: http.MethodGet,
+			status: http.StatusOK,
+			httpResponse: HTTPResponse{
+				Data: []readable.Ban{},
+			},
+		},
+		{
+			name:   "200",
+			method: http.MethodGet,
+			status: http.StatusOK,
+			bans:   bans,
+			httpResponse: HTTPResponse{
+				Data: []readable.Ban{
+					{
+						IP:      "1.2.3.4",
+						Reason:  "invalid block",
+						Created: 1500000000,
+						Expires: 1500003600,
+					},
+				},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			gateway := &MockGatewayer{}
+			gateway.On("GetBans").Return(tc.bans)
+
+			req, err := http.NewRequest(tc.method, "/api/v2/network/bans", nil)
+			require.NoError(t, err)
+			setCSRFParameters(t, tokenValid, req)
+
+			rr := httptest.NewRecorder()
+			handler := newServerMux(defaultMuxConfig(), gateway)
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.status, rr.Code)
+
+			var rsp ReceivedHTTPResponse
+			err = json.NewDecoder(rr.Body).Decode(&rsp)
+			require.NoError(t, err)
+
+			require.Equal(t, tc.httpResponse.Error, rsp.Error)
+
+			if rsp.Data == nil {
+				require.Nil(t, tc.httpResponse.Data)
+			} else {
+				var rbans []readable.Ban
+				err := json.Unmarshal(rsp.Data, &rbans)
+				require.NoError(t, err)
+				require.Equal(t, tc.httpResponse.Data, rbans)
+			}
+		})
+	}
+}
+
+func TestBanPeer(t *testing.T) {
+	created := time.Unix(1500000000, 0).UTC()
+
+	cases := []struct {
+		name         string
+		method       string
+		status       int
+		contentType  string
+		httpBody     string
+		addr         string
+		duration     time.Duration
+		reason       string
+		ban          pex.Ban
+		banErr       error
+		httpResponse HTTPResponse
+	}{
+		{
+			name:         "405",
+			method:       http.MethodGet,
+			status:       http.StatusMethodNotAllowed,
+			httpResponse: NewHTTPErrorResponse(http.StatusMethodNotAllowed, ""),
+		},
+		{
+			name:         "415",
+			method:       http.MethodPost,
+			status:       http.StatusUnsupportedMediaType,
+			contentType:  ContentTypeForm,
+			httpResponse: NewHTTPErrorResponse(http.StatusUnsupportedMediaType, ""),
+		},
+		{
+			name:         "400 EOF",
+			method:       http.MethodPost,
+			status:       http.StatusBadRequest,
+			httpResponse: NewHTTPErrorResponse(http.StatusBadRequest, "EOF"),
+		},
+		{
+			name:         "400 missing address",
+			method:       http.MethodPost,
+			status:       http.StatusBadRequest,
+			httpBody:     "{}",
+			httpResponse: NewHTTPErrorResponse(http.StatusBadRequest, "address is required"),
+		},
+		{
+			name:         "400 invalid address",
+			method:       http.MethodPost,
+			status:       http.StatusBadRequest,
+			httpBody:     `{"address":"foo"}`,
+			addr:         "foo",
+			duration:     time.Hour * 24,
+			reason:       "banned by the node operator",
+			banErr:       pex.ErrInvalidAddress,
+			httpResponse: NewHTTPErrorResponse(http.StatusBadRequest, "Invalid address"),
+		},
+		{
+			name:         "500 ban failed",
+			method:       http.MethodPost,
+			status:       http.StatusInternalServerError,
+			httpBody:     `{"address":"1.2.3.4:6000","duration":"1h","reason":"spam"}`,
+			addr:         "1.2.3.4:6000",
+			duration:     time.Hour,
+			reason:       "spam",
+			banErr:       errors.New("save blacklist failed"),
+			httpResponse: NewHTTPErrorResponse(http.StatusInternalServerError, "save blacklist failed"),
+		},
+		{
+			name:     "200",
+			method:   http.MethodPost,
+			status:   http.StatusOK,
+			httpBody: `{"address":"1.2.3.4:6000","duration":"1h","reason":"spam"}`,
+			addr:     "1.2.3.4:6000",
+			duration: time.Hour,
+			reason:   "spam",
+			ban: pex.Ban{
+				IP:      "1.2.3.4",
+				Reason:  "spam",
+				Created: created,
+				Expires: created.Add(time.Hour),
+			},
+			httpResponse: HTTPResponse{
+				Data: readable.Ban{
+					IP:      "1.2.3.4",
+					Reason:  "spam",
+					Created: 1500000000,
+					Expires: 1500003600,
+				},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			gateway := &MockGatewayer{}
+			gateway.On("DaemonConfig").Return(daemon.DaemonConfig{
+				BanDuration: time.Hour * 24,
+			})
+			gateway.On("BanPeer", tc.addr, tc.duration, tc.reason).Return(tc.ban, tc.banErr)
+
+			req, err := http.NewRequest(tc.method, "/api/v2/network/bans/add", strings.NewReader(tc.httpBody))
+			require.NoError(t, err)
+
+			contentType := tc.contentType
+			if contentType == "" {
+				contentType = ContentTypeJSON
+			}
+			req.Header.Set("Content-Type", contentType)
+			setCSRFParameters(t, tokenValid, req)
+
+			rr := httptest.NewRecorder()
+			handler := newServerMux(defaultMuxConfig(), gateway)
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.status, rr.Code)
+
+			var rsp ReceivedHTTPResponse
+			err = json.NewDecoder(rr.Body).Decode(&rsp)
+			require.NoError(t, err)
+
+			require.Equal(t, tc.httpResponse.Error, rsp.Error)
+
+			if rsp.Data == nil {
+				require.Nil(t, tc.httpResponse.Data)
+			} else {
+				var b readable.Ban
+				err := json.Unmarshal(rsp.Data, &b)
+				require.NoError(t, err)
+				require.Equal(t, tc.httpResponse.Data, b)
+			}
+		})
+	}
+}
+
+func TestUnbanPeer(t *testing.T) {
+	cases := []struct {
+		name         string
+		method       string
+		status       int
+		contentType  string
+		httpBody     string
+		addr         string
+		unbanErr     error
+		httpResponse HTTPResponse
+	}{
+		{
+			name:         "405",
+			method:       http.MethodGet,
+			status:       http.StatusMethodNotAllowed,
+			httpResponse: NewHTTPErrorResponse(http.StatusMethodNotAllowed, ""),
+		},
+		{
+			name:         "415",
+			method:       http.MethodPost,
+			status:       http.StatusUnsupportedMediaType,
+			contentType:  ContentTypeForm,
+			httpResponse: NewHTTPErrorResponse(http.StatusUnsupportedMediaType, ""),
+		},
+		{
+			name:         "400 missing address",
+			method:       http.MethodPost,
+			status:       http.StatusBadRequest,
+			httpBody:     "{}",
+			httpResponse: NewHTTPErrorResponse(http.StatusBadRequest, "address is required"),
+		},
+		{
+			name:         "400 invalid address",
+			method:       http.MethodPost,
+			status:       http.StatusBadRequest,
+			httpBody:     `{"address":"foo"}`,
+			addr:         "foo",
+			unbanErr:     pex.ErrInvalidAddress,
+			httpResponse: NewHTTPErrorResponse(http.StatusBadRequest, "Invalid address"),
+		},
+		{
+			name:         "404 not banned",
+			method:       http.MethodPost,
+			status:       http.StatusNotFound,
+			httpBody:     `{"address":"1.2.3.4"}`,
+			addr:         "1.2.3.4",
+			unbanErr:     pex.ErrNotBanned,
+			httpResponse: NewHTTPErrorResponse(http.StatusNotFound, "IP is not banned"),
+		},
+		{
+			name:     "200",
+			method:   http.MethodPost,
+			status:   http.StatusOK,
+			httpBody: `{"address":"1.2.3.4"}`,
+			addr:     "1.2.3.4",
+			httpResponse: HTTPResponse{
+				Data: struct{}{},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			gateway := &MockGatewayer{}
+			gateway.On("UnbanPeer", tc.addr).Return(tc.unbanErr)
+
+			req, err := http.NewRequest(tc.method, "/api/v2/network/bans/remove", strings.NewReader(tc.httpBody))
+			require.NoError(t, err)
+
+			contentType := tc.contentType
+			if contentType == "" {
+				contentType = ContentTypeJSON
+			}
+			req.Header.Set("Content-Type", contentType)
+			setCSRFParameters(t, tokenValid, req)
+
+			rr := httptest.NewRecorder()
+			handler := newServerMux(defaultMuxConfig(), gateway)
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.status, rr.Code)
+
+			var rsp ReceivedHTTPResponse
+			err = json.NewDecoder(rr.Body).Decode(&rsp)
+			require.NoError(t, err)
+
+			require.Equal(t, tc.httpResponse.Error, rsp.Error)
+
+			if rsp.Data == nil {
+				require.Nil(t, tc.httpResponse.Data)
+			} else {
+				require.NotNil(t, tc.httpResponse.Data)
+			}
+		})
+	}
+}
@@ -0,0 +1,974 @@
+package api
+
+import (
+	"encoding"
+	"encoding/json"
+	"net/http"
+	"path"
+	"reflect"
+	"strings"
+
+	"github.com/skycoin/skycoin/src/readable"
+	wh "github.com/skycoin/skycoin/src/util/http"
+)
+
+const openAPIVersion = "3.0.0"
+
+var (
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// apiRoute is an endpoint registered on the server mux, with the methods it accepts
+type apiRoute struct {
+	Path    string
+	Version string
+	Methods []string
+}
+
+// openAPIParam is a query or form parameter of an API operation
+type openAPIParam struct {
+	Name        string
+	Description string
+	// Type is an OpenAPI primitive type, "string" if empty
+	Type     string
+	Required bool
+}
+
+// openAPIOperation documents one method of an API endpoint
+type openAPIOperation struct {
+	Summary string
+	// Params are sent in the query string for GET and as a form body for POST
+	Params []openAPIParam
+	// Request is an instance of the JSON request body, nil if the operation has no JSON body
+	Request interface{}
+	// Responses are instances of the possible 200 response bodies, e.g. the plain and verbose forms of an endpoint.
+	// Responses of v2 endpoints are wrapped in an HTTPResponse
+	Responses []interface{}
+	// ContentType of the response, if it is not JSON
+	ContentType string
+	// Unwrapped is true if a v2 endpoint does not wrap its response in an HTTPResponse
+	Unwrapped bool
+}
+
+var (
+	verboseParam = openAPIParam{
+		Name:        "verbose",
+		Description: "Include transaction inputs with their owner address, coins and calculated coin hours",
+		Type:        "boolean",
+	}
+	walletIDParam = openAPIParam{
+		Name:        "id",
+		Description: "Wallet id",
+		Required:    true,
+	}
+	walletPasswordParam = openAPIParam{
+		Name:        "password",
+		Description: "Wallet password",
+	}
+	addrsParam = openAPIParam{
+		Name:        "addrs",
+		Description: "Comma-separated list of addresses",
+		Required:    true,
+	}
+)
+
+// openAPIOperations documents the operations of each route registered in newServerMux.
+// Every registered route and method must have an entry here, which is enforced by TestOpenAPISpecRoutes
+var openAPIOperations = map[string]map[string]openAPIOperation{
+	"/api/v1/csrf": {
+		http.MethodGet: {
+			Summary:   "Returns a CSRF token to use in POST requests",
+			Responses: []interface{}{map[string]string{}},
+		},
+	},
+
+	// Status endpoints
+	"/api/v1/version": {
+		http.MethodGet: {
+			Summary:   "Returns the application version info",
+			Responses: []interface{}{readable.BuildInfo{}},
+		},
+	},
+	"/api/v1/health": {
+		http.MethodGet: {
+			Summary:   "Returns node health data",
+			Responses: []interface{}{HealthResponse{}},
+		},
+	},
+
+	// Wallet endpoints
+	"/api/v1/wallet": {
+		http.MethodGet: {
+			Summary:   "Returns a wallet by id",
+			Params:    []openAPIParam{walletIDParam},
+			Responses: []interface{}{WalletResponse{}},
+		},
+	},
+	"/api/v1/wallet/create": {
+		http.MethodPost: {
+			Summary: "Creates a wallet from a seed",
+			Params: []openAPIParam{
+				{Name: "seed", Description: "Wallet seed", Required: true},
+				{Name: "label", Description: "Wallet label", Required: true},
+				{Name: "scan", Description: "The number of addresses to scan ahead for balances", Type: "integer"},
+				{Name: "encrypt", Description: "Encrypt the wallet", Type: "boolean"},
+				{Name: "password", Description: "Password for encrypting the wallet, required if encrypt is set"},
+			},
+			Responses: []interface{}{WalletResponse{}},
+		},
+	},
+	"/api/v1/wallet/newAddress": {
+		http.MethodPost: {
+			Summary: "Generates new addresses in a wallet",
+			Params: []openAPIParam{
+				walletIDParam,
+				{Name: "num", Description: "Number of addresses to create, defaults to 1", Type: "integer"},
+				walletPasswordParam,
+			},
+			Responses: []interface{}{struct {
+				Addresses []string `json:"addresses"`
+			}{}},
+		},
+	},
+	"/api/v1/wallet/balance": {
+		http.MethodGet: {
+			Summary:   "Returns the confirmed and predicted balance of a wallet",
+			Params:    []openAPIParam{walletIDParam},
+			Responses: []interface{}{BalanceResponse{}},
+		},
+	},
+	"/api/v1/wallet/transaction": {
+		http.MethodPost: {
+			Summary:   "Creates a signed transaction from a wallet",
+			Request:   walletCreateTransactionRequest{},
+			Responses: []interface{}{CreateTransactionResponse{}},
+		},
+	},
+	"/api/v2/wallet/transaction/sign": {
+		http.MethodPost: {
+			Summary:   "Signs an unsigned transaction with a wallet",
+			Request:   WalletSignTransactionRequest{},
+			Responses: []interface{}{CreateTransactionResponse{}},
+		},
+	},
+	"/api/v1/wallet/transactions": {
+		http.MethodGet: {
+			Summary:   "Returns the unconfirmed transactions of a wallet",
+			Params:    []openAPIParam{walletIDParam, verboseParam},
+			Responses: []interface{}{UnconfirmedTxnsResponse{}, UnconfirmedTxnsVerboseResponse{}},
+		},
+	},
+	"/api/v1/wallet/update": {
+		http.MethodPost: {
+			Summary: "Updates the label of a wallet",
+			Params: []openAPIParam{
+				walletIDParam,
+				{Name: "label", Description: "The new wallet label", Required: true},
+			},
+			Responses: []interface{}{""},
+		},
+	},
+	"/api/v1/wallets": {
+		http.MethodGet: {
+			Summary:   "Returns all loaded wallets",
+			Responses: []interface{}{[]WalletResponse{}},
+		},
+	},
+	"/api/v1/wallets/folderName": {
+		http.MethodGet: {
+			Summary:   "Returns the wallet directory path",
+			Responses: []interface{}{WalletFolder{}},
+		},
+	},
+	"/api/v1/wallet/newSeed": {
+		http.MethodGet: {
+			Summary: "Generates a wallet seed",
+			Params: []openAPIParam{
+				{Name: "entropy", Description: "Entropy bitsize, 128 or 256, defaults to 128", Type: "integer"},
+			},
+			Responses: []interface{}{struct {
+				Seed string `json:"seed"`
+			}{}},
+		},
+	},
+	"/api/v1/wallet/seed": {
+		http.MethodPost: {
+			Summary: "Returns the seed of an encrypted wallet",
+			Params: []openAPIParam{
+				walletIDParam,
+				walletPasswordParam,
+			},
+			Responses: []interface{}{struct {
+				Seed string `json:"seed"`
+			}{}},
+		},
+	},
+	"/api/v2/wallet/seed/verify": {
+		http.MethodPost: {
+			Summary:   "Verifies a wallet seed",
+			Request:   VerifySeedRequest{},
+			Responses: []interface{}{struct{}{}},
+		},
+	},
+	"/api/v1/wallet/unload": {
+		http.MethodPost: {
+			Summary: "Unloads a wallet from the wallet service",
+			Params:  []openAPIParam{walletIDParam},
+		},
+	},
+	"/api/v1/wallet/encrypt": {
+		http.MethodPost: {
+			Summary:   "Encrypts a wallet",
+			Params:    []openAPIParam{walletIDParam, walletPasswordParam},
+			Responses: []interface{}{WalletResponse{}},
+		},
+	},
+	"/api/v1/wallet/decrypt": {
+		http.MethodPost: {
+			Summary:   "Decrypts a wallet",
+			Params:    []openAPIParam{walletIDParam, walletPasswordParam},
+			Responses: []interface{}{WalletResponse{}},
+		},
+	},
+	"/api/v2/wallet/recover": {
+		http.MethodPost: {
+			Summary:   "Recovers an encrypted wallet by providing its seed",
+			Request:   WalletRecoverRequest{},
+			Responses: []interface{}{WalletResponse{}},
+		},
+	},
+
+	// Blockchain endpoints
+	"/api/v1/blockchain/metadata": {
+		http.MethodGet: {
+			Summary:   "Returns the blockchain metadata",
+			Responses: []interface{}{readable.BlockchainMetadata{}},
+		},
+	},
+	"/api/v1/blockchain/progress": {
+		http.MethodGet: {
+			Summary:   "Returns the blockchain sync progress",
+			Responses: []interface{}{readable.BlockchainProgress{}},
+		},
+	},
+	"/api/v1/block": {
+		http.MethodGet: {
+			Summary: "Returns a block by hash or seq",
+			Params: []openAPIParam{
+				{Name: "hash", Description: "Block hash, only one of hash or seq is allowed"},
+				{Name: "seq", Description: "Block seq, only one of hash or seq is allowed", Type: "integer"},
+				verboseParam,
+			},
+			Responses: []interface{}{readable.Block{}, readable.BlockVerbose{}},
+		},
+	},
+	"/api/v1/blocks": {
+		http.MethodGet:  blocksOperation,
+		http.MethodPost: blocksOperation,
+	},
+	"/api/v1/last_blocks": {
+		http.MethodGet: {
+			Summary: "Returns the most recent N blocks",
+			Params: []openAPIParam{
+				{Name: "num", Description: "Number of blocks", Type: "integer", Required: true},
+				verboseParam,
+			},
+			Responses: []interface{}{readable.Blocks{}, readable.BlocksVerbose{}},
+		},
+	},
+	"/api/v2/blocks": {
+		http.MethodGet: {
+			Summary: "Returns the blocks in a range of block times",
+			Params: []openAPIParam{
+				{Name: "start_time", Description: "Unix timestamp of the start of the range, defaults to 0", Type: "integer"},
+				{Name: "end_time", Description: "Unix timestamp of the end of the range, inclusive, defaults to the latest time", Type: "integer"},
+				verboseParam,
+			},
+			Responses: []interface{}{readable.Blocks{}, readable.BlocksVerbose{}},
+		},
+	},
+
+	// Network endpoints
+	"/api/v1/network/connection": {
+		http.MethodGet: {
+			Summary: "Returns a connection by address",
+			Params: []openAPIParam{
+				{Name: "addr", Description: "IP:Port of the connection", Required: true},
+			},
+			Responses: []interface{}{readable.Connection{}},
+		},
+	},
+	"/api/v1/network/connections": {
+		http.MethodGet: {
+			Summary: "Returns all connections",
+			Params: []openAPIParam{
+				{Name: "states", Description: "Comma-separated list of connection states, defaults to connected,introduced"},
+				{Name: "direction", Description: "Filter by connection direction, outgoing or incoming"},
+			},
+			Responses: []interface{}{Connections{}},
+		},
+	},
+	"/api/v1/network/defaultConnections": {
+		http.MethodGet: {
+			Summary:   "Returns the default hardcoded bootstrap addresses",
+			Responses: []interface{}{[]string{}},
+		},
+	},
+	"/api/v1/network/connections/trust": {
+		http.MethodGet: {
+			Summary:   "Returns all trusted connections",
+			Responses: []interface{}{[]string{}},
+		},
+	},
+	"/api/v1/network/connections/exchange": {
+		http.MethodGet: {
+			Summary:   "Returns all connections found through peer exchange",
+			Responses: []interface{}{[]string{}},
+		},
+	},
+	"/api/v1/network/connection/disconnect": {
+		http.MethodPost: {
+			Summary: "Disconnects a connection by id",
+			Params: []openAPIParam{
+				{Name: "id", Description: "Connection id", Type: "integer", Required: true},
+			},
+			Responses: []interface{}{struct{}{}},
+		},
+	},
+	"/api/v2/network/stats": {
+		http.MethodGet: {
+			Summary:   "Returns the messages and bytes sent and received over all connections, by message prefix",
+			Responses: []interface{}{readable.NetworkStats{}},
+		},
+	},
+	"/api/v2/network/bans": {
+		http.MethodGet: {
+			Summary:   "Returns the banned IPs",
+			Responses: []interface{}{[]readable.Ban{}},
+		},
+	},
+	"/api/v2/network/bans/add": {
+		http.MethodPost: {
+			Summary:   "Bans an IP and disconnects all connections from it",
+			Request:   BanPeerRequest{},
+			Responses: []interface{}{readable.Ban{}},
+		},
+	},
+	"/api/v2/network/bans/remove": {
+		http.MethodPost: {
+			Summary:   "Removes the ban of an IP",
+			Request:   UnbanPeerRequest{},
+			Responses: []interface{}{struct{}{}},
+		},
+	},
+
+	// Database admin endpoints
+	"/api/v2/db/backup": {
+		http.MethodPost: {
+			Summary:     "Streams a consistent copy of the database, made while the node keeps running",
+			Request:     DBBackupRequest{},
+			ContentType: ContentTypeOctetStream,
+			Unwrapped:   true,
+		},
+	},
+
+	// Transaction endpoints
+	"/api/v1/pendingTxs": {
+		http.MethodGet: {
+			Summary:   "Returns all unconfirmed transactions",
+			Params:    []openAPIParam{verboseParam},
+			Responses: []interface{}{[]readable.UnconfirmedTransactions{}, []readable.UnconfirmedTransactionVerbose{}},
+		},
+	},
+	"/api/v1/transaction": {
+		http.MethodGet: {
+			Summary: "Returns a transaction by txid",
+			Params: []openAPIParam{
+				{Name: "txid", Description: "Transaction id", Required: true},
+				verboseParam,
+				{Name: "encoded", Description: "Return the transaction as a hex-encoded serialized string", Type: "boolean"},
+			},
+			Responses: []interface{}{
+				readable.TransactionWithStatus{},
+				readable.TransactionWithStatusVerbose{},
+				TransactionEncodedResponse{},
+			},
+		},
+	},
+	"/api/v2/transaction": {
+		http.MethodPost: {
+			Summary:   "Creates an unsigned transaction from addresses or unspent outputs",
+			Request:   createTransactionRequest{},
+			Responses: []interface{}{CreateTransactionResponse{}},
+		},
+	},
+	"/api/v2/transaction/verify": {
+		http.MethodPost: {
+			Summary:   "Decodes and verifies an encoded transaction",
+			Request:   VerifyTransactionRequest{},
+			Responses: []interface{}{VerifyTransactionResponse{}},
+		},
+	},
+	"/api/v1/transactions": {
+		http.MethodGet:  transactionsOperation,
+		http.MethodPost: transactionsOperation,
+	},
+	"/api/v1/injectTransaction": {
+		http.MethodPost: {
+			Summary: "Broadcasts a hex-encoded, serialized transaction to the network",
+			Request: struct {
+				Rawtx string `json:"rawtx"`
+			}{},
+			Responses: []interface{}{""},
+		},
+	},
+	"/api/v1/resendUnconfirmedTxns": {
+		http.MethodPost: {
+			Summary:   "Broadcasts all unconfirmed transactions from the unconfirmed transaction pool",
+			Responses: []interface{}{ResendResult{}},
+		},
+	},
+	"/api/v1/rawtx": {
+		http.MethodGet: {
+			Summary: "Returns the hex-encoded serialized transaction by txid",
+			Params: []openAPIParam{
+				{Name: "txid", Description: "Transaction id", Required: true},
+			},
+			Responses: []interface{}{""},
+		},
+	},
+
+	// Unspent output endpoints
+	"/api/v1/outputs": {
+		http.MethodGet:  outputsOperation,
+		http.MethodPost: outputsOperation,
+	},
+	"/api/v1/balance": {
+		http.MethodGet:  balanceOperation,
+		http.MethodPost: balanceOperation,
+	},
+	"/api/v2/outputs": {
+		http.MethodPost: {
+			Summary:   "Returns the unspent outputs of a large number of addresses or hashes, read in one database transaction",
+			Request:   OutputsRequestV2{},
+			Responses: []interface{}{readable.UnspentOutputsSummary{}},
+		},
+	},
+	"/api/v2/balance": {
+		http.MethodPost: {
+			Summary:   "Returns the balances of a large number of addresses, read in one database transaction",
+			Request:   BalanceRequestV2{},
+			Responses: []interface{}{BalanceResponseV2{}},
+		},
+	},
+	"/api/v1/uxout": {
+		http.MethodGet: {
+			Summary: "Returns an unspent output by id",
+			Params: []openAPIParam{
+				{Name: "uxid", Description: "Unspent output hash", Required: true},
+			},
+			Responses: []interface{}{readable.SpentOutput{}},
+		},
+	},
+	"/api/v1/address_uxouts": {
+		http.MethodGet: {
+			Summary: "Returns the historical, spent outputs associated with an address",
+			Params: []openAPIParam{
+				{Name: "address", Description: "Address", Required: true},
+			},
+			Responses: []interface{}{[]readable.SpentOutput{}},
+		},
+	},
+
+	"/api/v2/metrics": {
+		http.MethodGet: {
+			Summary:     "Returns Prometheus metrics of the node",
+			ContentType: "text/plain",
+			Unwrapped:   true,
+		},
+	},
+
+	// Address endpoints
+	"/api/v2/address/verify": {
+		http.MethodPost: {
+			Summary:   "Verifies an address",
+			Request:   VerifyAddressRequest{},
+			Responses: []interface{}{VerifyAddressResponse{}},
+		},
+	},
+	"/api/v2/address/history": {
+		http.MethodGet:  addressHistoryOperation,
+		http.MethodPost: addressHistoryOperation,
+	},
+
+	// Explorer endpoints
+	"/api/v1/coinSupply": {
+		http.MethodGet: {
+			Summary:   "Returns coin distribution supply stats",
+			Responses: []interface{}{CoinSupply{}},
+		},
+	},
+	"/api/v1/richlist": {
+		http.MethodGet: {
+			Summary: "Returns the top address balances",
+			Params: []openAPIParam{
+				{Name: "n", Description: "Number of results to include, defaults to 20", Type: "integer"},
+				{Name: "page", Description: "Page of n results to return, starting at 1", Type: "integer"},
+				{Name: "include-distribution", Description: "Include the distribution addresses", Type: "boolean"},
+			},
+			Responses: []interface{}{Richlist{}},
+		},
+	},
+	"/api/v1/addresscount": {
+		http.MethodGet: {
+			Summary:   "Returns the number of unique addresses that have coins",
+			Responses: []interface{}{map[string]uint64{}},
+		},
+	},
+	"/api/v2/stats/series": {
+		http.MethodGet: {
+			Summary: "Returns the time series of a chain statistic",
+			Params: []openAPIParam{
+				{Name: "metric", Description: "One of coin_supply, coin_hour_supply, transactions, fees_burned or active_addresses", Required: true},
+				{Name: "interval", Description: "block or day, defaults to day. A block interval series has at most 10000 points"},
+				{Name: "start_time", Description: "Unix timestamp of the start of the range, defaults to 0", Type: "integer"},
+				{Name: "end_time", Description: "Unix timestamp of the end of the range, inclusive, defaults to the latest time", Type: "integer"},
+			},
+			Responses: []interface{}{readable.StatsSeries{}},
+		},
+	},
+
+	"/api/v2/openapi.json": {
+		http.MethodGet: {
+			Summary:   "Returns the OpenAPI specification of the API",
+			Responses: []interface{}{map[string]interface{}{}},
+			Unwrapped: true,
+		},
+	},
+}
+
+var (
+	blocksOperation = openAPIOperation{
+		Summary: "Returns blocks in a range of seqs, or a list of seqs",
+		Params: []openAPIParam{
+			{Name: "start", Description: "Start seq of the range", Type: "integer"},
+			{Name: "end", Description: "End seq of the range, inclusive", Type: "integer"},
+			{Name: "seqs", Description: "Comma-separated list of block seqs, cannot be combined with start and end"},
+			verboseParam,
+		},
+		Responses: []interface{}{readable.Blocks{}, readable.BlocksVerbose{}},
+	}
+
+	transactionsOperation = openAPIOperation{
+		Summary: "Returns transactions that involve the given addresses",
+		Params: []openAPIParam{
+			{Name: "addrs", Description: "Comma-separated list of addresses"},
+			{Name: "confirmed", Description: "Only return confirmed (true) or unconfirmed (false) transactions", Type: "boolean"},
+			{Name: "start_time", Description: "Only return transactions at or after this unix timestamp", Type: "integer"},
+			{Name: "end_time", Description: "Only return transactions at or before this unix timestamp", Type: "integer"},
+			verboseParam,
+		},
+		Responses: []interface{}{[]readable.TransactionWithStatus{}, []readable.TransactionWithStatusVerbose{}},
+	}
+
+	outputsOperation = openAPIOperation{
+		Summary: "Returns unspent outputs filtered by addresses or hashes",
+		Params: []openAPIParam{
+			{Name: "addrs", Description: "Comma-separated list of addresses, cannot be combined with hashes"},
+			{Name: "hashes", Description: "Comma-separated list of unspent output hashes, cannot be combined with addrs"},
+		},
+		Responses: []interface{}{readable.UnspentOutputsSummary{}},
+	}
+
+	balanceOperation = openAPIOperation{
+		Summary:   "Returns the confirmed and predicted balance of addresses",
+		Params:    []openAPIParam{addrsParam},
+		Responses: []interface{}{BalanceResponse{}},
+	}
+
+	addressHistoryOperation = openAPIOperation{
+		Summary:   "Returns the transaction history of addresses with running balances",
+		Params:    []openAPIParam{addrsParam},
+		Responses: []interface{}{[]readable.AddressHistory{}},
+	}
+)
+
+// openAPIDocument is an OpenAPI 3 document
+type openAPIDocument struct {
+	OpenAPI    string                                  `json:"openapi"`
+	Info       openAPIInfo                             `json:"info"`
+	Paths      map[string]map[string]openAPIPathMethod `json:"paths"`
+	Components openAPIComponents                       `json:"components"`
+}
+
+type openAPIInfo struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+type openAPIComponents struct {
+	Schemas         map[string]*openAPISchema        `json:"schemas"`
+	SecuritySchemes map[string]openAPISecurityScheme `json:"securitySchemes"`
+}
+
+type openAPISecurityScheme struct {
+	Type string `json:"type"`
+	In   string `json:"in"`
+	Name string `json:"name"`
+}
+
+type openAPIPathMethod struct {
+	Summary     string                     `json:"summary"`
+	OperationID string                     `json:"operationId"`
+	Parameters  []openAPIParameter         `json:"parameters,omitempty"`
+	RequestBody *openAPIRequestBody        `json:"requestBody,omitempty"`
+	Responses   map[string]openAPIResponse `json:"responses"`
+	Security    []map[string][]string      `json:"security,omitempty"`
+}
+
+type openAPIParameter struct {
+	Name        string         `json:"name"`
+	In          string         `json:"in"`
+	Description string         `json:"description,omitempty"`
+	Required    bool           `json:"required,omitempty"`
+	Schema      *openAPISchema `json:"schema"`
+}
+
+type openAPIRequestBody struct {
+	Required bool                        `json:"required"`
+	Content  map[string]openAPIMediaType `json:"content"`
+}
+
+type openAPIResponse struct {
+	Description string                      `json:"description"`
+	Content     map[string]openAPIMediaType `json:"content,omitempty"`
+}
+
+type openAPIMediaType struct {
+	Schema *openAPISchema `json:"schema"`
+}
+
+type openAPISchema struct {
+	Ref                  string                    `json:"$ref,omitempty"`
+	Type                 string                    `json:"type,omitempty"`
+	Format               string                    `json:"format,omitempty"`
+	Description          string                    `json:"description,omitempty"`
+	Items                *openAPISchema            `json:"items,omitempty"`
+	Properties           map[string]*openAPISchema `json:"properties,omitempty"`
+	Required             []string                  `json:"required,omitempty"`
+	AdditionalProperties *openAPISchema            `json:"additionalProperties,omitempty"`
+	OneOf                []*openAPISchema          `json:"oneOf,omitempty"`
+}
+
+// newOpenAPIDocument creates an OpenAPI document for the registered routes.
+// Routes without an entry in openAPIOperations are left out of the document.
+func newOpenAPIDocument(version string, routes []apiRoute) openAPIDocument {
+	schemas := make(map[string]*openAPISchema)
+
+	doc := openAPIDocument{
+		OpenAPI: openAPIVersion,
+		Info: openAPIInfo{
+			Title:   "Skycoin REST API",
+			Version: version,
+		},
+		Paths: make(map[string]map[string]openAPIPathMethod),
+		Components: openAPIComponents{
+			Schemas: schemas,
+			SecuritySchemes: map[string]openAPISecurityScheme{
+				"csrf": {
+					Type: "apiKey",
+					In:   "header",
+					Name: CSRFHeaderName,
+				},
+			},
+		},
+	}
+
+	for _, rt := range routes {
+		ops, ok := openAPIOperations[rt.Path]
+		if !ok {
+			continue
+		}
+
+		for _, method := range rt.Methods {
+			op, ok := ops[method]
+			if !ok {
+				continue
+			}
+
+			if doc.Paths[rt.Path] == nil {
+				doc.Paths[rt.Path] = make(map[string]openAPIPathMethod)
+			}
+
+			doc.Paths[rt.Path][strings.ToLower(method)] = newOpenAPIPathMethod(schemas, rt, method, op)
+		}
+	}
+
+	return doc
+}
+
+func newOpenAPIPathMethod(schemas map[string]*openAPISchema, rt apiRoute, method string, op openAPIOperation) openAPIPathMethod {
+	pm := openAPIPathMethod{
+		Summary:     op.Summary,
+		OperationID: openAPIOperationID(rt.Path, method),
+		Responses:   make(map[string]openAPIResponse),
+	}
+
+	if method == http.MethodPost {
+		pm.Security = []map[string][]string{{"csrf": {}}}
+	}
+
+	switch {
+	case op.Request != nil:
+		pm.RequestBody = &openAPIRequestBody{
+			Required: true,
+			Content: map[string]openAPIMediaType{
+				ContentTypeJSON: {
+					Schema: openAPISchemaOf(schemas, reflect.TypeOf(op.Request)),
+				},
+			},
+		}
+	case len(op.Params) > 0 && method == http.MethodPost:
+		form := &openAPISchema{
+			Type:       "object",
+			Properties: make(map[string]*openAPISchema, len(op.Params)),
+		}
+		for _, p := range op.Params {
+			form.Properties[p.Name] = &openAPISchema{
+				Type:        openAPIParamType(p),
+				Description: p.Description,
+			}
+			if p.Required {
+				form.Required = append(form.Required, p.Name)
+			}
+		}
+
+		pm.RequestBody = &openAPIRequestBody{
+			Required: len(form.Required) > 0,
+			Content: map[string]openAPIMediaType{
+				ContentTypeForm: {
+					Schema: form,
+				},
+			},
+		}
+	default:
+		for _, p := range op.Params {
+			pm.Parameters = append(pm.Parameters, openAPIParameter{
+				Name:        p.Name,
+				In:          "query",
+				Description: p.Description,
+				Required:    p.Required,
+				Schema: &openAPISchema{
+					Type: openAPIParamType(p),
+				},
+			})
+		}
+	}
+
+	ok := openAPIResponse{
+		Description: "OK",
+	}
+
+	var schema *openAPISchema
+	switch len(op.Responses) {
+	case 0:
+	case 1:
+		schema = openAPISchemaOf(schemas, reflect.TypeOf(op.Responses[0]))
+	default:
+		schema = &openAPISchema{}
+		for _, r := range op.Responses {
+			schema.OneOf = append(schema.OneOf, openAPISchemaOf(schemas, reflect.TypeOf(r)))
+		}
+	}
+
+	wrapped := rt.Version == apiVersion2 && !op.Unwrapped
+	if wrapped {
+		if schema == nil {
+			schema = &openAPISchema{Type: "object"}
+		}
+		schema = &openAPISchema{
+			Type: "object",
+			Properties: map[string]*openAPISchema{
+				"data": schema,
+			},
+		}
+	}
+
+	switch {
+	case op.ContentType != "":
+		ok.Content = map[string]openAPIMediaType{
+			op.ContentType: {
+				Schema: &openAPISchema{Type: "string"},
+			},
+		}
+	case schema != nil:
+		ok.Content = map[string]openAPIMediaType{
+			ContentTypeJSON: {
+				Schema: schema,
+			},
+		}
+	}
+
+	pm.Responses["200"] = ok
+
+	errResp := openAPIResponse{
+		Description: "Error",
+	}
+	if rt.Version == apiVersion2 {
+		errResp.Content = map[string]openAPIMediaType{
+			ContentTypeJSON: {
+				Schema: openAPISchemaOf(schemas, reflect.TypeOf(HTTPResponse{})),
+			},
+		}
+	} else {
+		errResp.Content = map[string]openAPIMediaType{
+			"text/plain": {
+				Schema: &openAPISchema{Type: "string"},
+			},
+		}
+	}
+	pm.Responses["default"] = errResp
+
+	return pm
+}
+
+// openAPIOperationID creates an operationId from a path and method, e.g. GET /api/v1/wallet/balance -> getV1WalletBalance
+func openAPIOperationID(p, method string) string {
+	id := strings.ToLower(method)
+	for _, s := range strings.FieldsFunc(strings.TrimPrefix(p, "/api"), func(r rune) bool {
+		return r == '/' || r == '_' || r == '.' || r == '-'
+	}) {
+		id += strings.ToUpper(s[:1]) + s[1:]
+	}
+	return id
+}
+
+func openAPIParamType(p openAPIParam) string {
+	if p.Type == "" {
+		return "string"
+	}
+	return p.Type
+}
+
+// openAPISchemaOf returns the schema of the JSON encoding of a type.
+// Named struct types are added to schemas and referenced
+func openAPISchemaOf(schemas map[string]*openAPISchema, t reflect.Type) *openAPISchema {
+	// Types with custom JSON encoding in this codebase marshal to a string
+	if t.Implements(jsonMarshalerType) || reflect.PtrTo(t).Implements(jsonMarshalerType) ||
+		t.Implements(textMarshalerType) || reflect.PtrTo(t).Implements(textMarshalerType) {
+		return &openAPISchema{Type: "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		return openAPISchemaOf(schemas, t.Elem())
+	case reflect.Interface:
+		return &openAPISchema{}
+	case reflect.Bool:
+		return &openAPISchema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &openAPISchema{Type: "integer", Format: "int32"}
+	case reflect.Int64, reflect.Uint64:
+		return &openAPISchema{Type: "integer", Format: "int64"}
+	case reflect.Float32:
+		return &openAPISchema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &openAPISchema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &openAPISchema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &openAPISchema{Type: "string", Format: "byte"}
+		}
+		return &openAPISchema{
+			Type:  "array",
+			Items: openAPISchemaOf(schemas, t.Elem()),
+		}
+	case reflect.Map:
+		return &openAPISchema{
+			Type:                 "object",
+			AdditionalProperties: openAPISchemaOf(schemas, t.Elem()),
+		}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return openAPIStructSchema(schemas, t)
+		}
+
+		name := path.Base(t.PkgPath()) + "." + t.Name()
+		if _, ok := schemas[name]; !ok {
+			// Reserve the name before recursing, in case the type refers to itself
+			schemas[name] = nil
+			schemas[name] = openAPIStructSchema(schemas, t)
+		}
+
+		return &openAPISchema{
+			Ref: "#/components/schemas/" + name,
+		}
+	default:
+		return &openAPISchema{}
+	}
+}
+
+func openAPIStructSchema(schemas map[string]*openAPISchema, t reflect.Type) *openAPISchema {
+	s := &openAPISchema{
+		Type:       "object",
+		Properties: make(map[string]*openAPISchema),
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+
+		name := strings.Split(tag, ",")[0]
+
+		// Fields of embedded structs without a JSON name are promoted to the parent object
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				embedded := openAPIStructSchema(schemas, ft)
+				for k, v := range embedded.Properties {
+					if _, ok := s.Properties[k]; !ok {
+						s.Properties[k] = v
+					}
+				}
+				continue
+			}
+		}
+
+		if f.PkgPath != "" {
+			continue
+		}
+
+		if name == "" {
+			name = f.Name
+		}
+
+		s.Properties[name] = openAPISchemaOf(schemas, f.Type)
+	}
+
+	return s
+}
+
+// openAPIHandler returns the OpenAPI specification of the API
+// Method: GET
+// URI: /api/v2/openapi.json
+func openAPIHandler(version string, routes func() []apiRoute) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			resp := NewHTTPErrorResponse(http.StatusMethodNotAllowed, "")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		wh.SendJSONOr500(logger, w, newOpenAPIDocument(version, routes()))
+	}
+}
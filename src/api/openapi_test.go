@@ -0,0 +1,143 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenAPISpecRoutes(t *testing.T) {
+	gateway := &MockGatewayer{}
+	mux, routes := newServerMuxWithRoutes(defaultMuxConfig(), gateway)
+	require.NotEmpty(t, routes)
+
+	req, err := http.NewRequest(http.MethodGet, "/api/v2/openapi.json", nil)
+	require.NoError(t, err)
+
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+	var doc openAPIDocument
+	err = json.NewDecoder(rr.Body).Decode(&doc)
+	require.NoError(t, err)
+
+	require.Equal(t, openAPIVersion, doc.OpenAPI)
+
+	// Every registered route and method must be in the spec
+	registered := make(map[string]map[string]struct{})
+	for _, rt := range routes {
+		registered[rt.Path] = make(map[string]struct{})
+		for _, m := range rt.Methods {
+			method := strings.ToLower(m)
+			registered[rt.Path][method] = struct{}{}
+
+			ops, ok := doc.Paths[rt.Path]
+			require.True(t, ok, "route %s is missing from the OpenAPI spec, add it to openAPIOperations", rt.Path)
+
+			op, ok := ops[method]
+			require.True(t, ok, "method %s of route %s is missing from the OpenAPI spec, add it to openAPIOperations", m, rt.Path)
+			require.NotEmpty(t, op.Summary, "%s %s", m, rt.Path)
+			require.Contains(t, op.Responses, "200", "%s %s", m, rt.Path)
+		}
+	}
+
+	// Every documented route and method must be registered
+	for p, ops := range openAPIOperations {
+		methods, ok := registered[p]
+		require.True(t, ok, "route %s is in openAPIOperations but is not registered", p)
+		for m := range ops {
+			_, ok := methods[strings.ToLower(m)]
+			require.True(t, ok, "method %s of route %s is in openAPIOperations but is not registered", m, p)
+		}
+	}
+
+	// All schema references must resolve
+	var checkRefs func(s *openAPISchema)
+	checkRefs = func(s *openAPISchema) {
+		if s == nil {
+			return
+		}
+		if s.Ref != "" {
+			name := strings.TrimPrefix(s.Ref, "#/components/schemas/")
+			_, ok := doc.Components.Schemas[name]
+			require.True(t, ok, "unresolved schema reference %s", s.Ref)
+		}
+		checkRefs(s.Items)
+		checkRefs(s.AdditionalProperties)
+		for _, v := range s.Properties {
+			checkRefs(v)
+		}
+		for _, v := range s.OneOf {
+			checkRefs(v)
+		}
+	}
+
+	for _, s := range doc.Components.Schemas {
+		require.NotNil(t, s)
+		checkRefs(s)
+	}
+	for _, ops := range doc.Paths {
+		for _, op := range ops {
+			if op.RequestBody != nil {
+				for _, c := range op.RequestBody.Content {
+					checkRefs(c.Schema)
+				}
+			}
+			for _, r := range op.Responses {
+				for _, c := range r.Content {
+					checkRefs(c.Schema)
+				}
+			}
+		}
+	}
+}
+
+func TestOpenAPISchemaOf(t *testing.T) {
+	type embedded struct {
+		Hash string `json:"hash"`
+	}
+
+	type example struct {
+		embedded
+		Coins    uint64            `json:"coins"`
+		Label    string            `json:"label,omitempty"`
+		Addrs    []string          `json:"addrs"`
+		Counts   map[string]uint64 `json:"counts"`
+		Next     *example          `json:"next"`
+		Skipped  string            `json:"-"`
+		Response HTTPResponse      `json:"response"`
+		private  bool
+	}
+
+	schemas := make(map[string]*openAPISchema)
+	s := openAPISchemaOf(schemas, reflect.TypeOf(example{}))
+	require.Equal(t, &openAPISchema{Ref: "#/components/schemas/api.example"}, s)
+
+	require.Equal(t, &openAPISchema{
+		Type: "object",
+		Properties: map[string]*openAPISchema{
+			"hash":  {Type: "string"},
+			"coins": {Type: "integer", Format: "int64"},
+			"label": {Type: "string"},
+			"addrs": {
+				Type:  "array",
+				Items: &openAPISchema{Type: "string"},
+			},
+			"counts": {
+				Type:                 "object",
+				AdditionalProperties: &openAPISchema{Type: "integer", Format: "int64"},
+			},
+			"next":     {Ref: "#/components/schemas/api.example"},
+			"response": {Ref: "#/components/schemas/api.HTTPResponse"},
+		},
+	}, schemas["api.example"])
+
+	require.Contains(t, schemas, "api.HTTPResponse")
+	require.Contains(t, schemas, "api.HTTPError")
+}
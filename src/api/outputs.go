@@ -1,6 +1,7 @@
 package api
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/http"
 
@@ -75,3 +76,134 @@ func outputsHandler(gateway Gatewayer) http.HandlerFunc {
 		wh.SendJSONOr500(logger, w, rSummary)
 	}
 }
+
+const (
+	// maxBulkRequestBodySize is the maximum size of the body of POST /api/v2/outputs and POST /api/v2/balance
+	maxBulkRequestBodySize = 2 * 1024 * 1024
+	// maxBulkRequestItems is the maximum number of addresses or hashes in POST /api/v2/outputs and POST /api/v2/balance
+	maxBulkRequestItems = 10000
+)
+
+// OutputsRequestV2 is the request data for POST /api/v2/outputs
+type OutputsRequestV2 struct {
+	Addrs  []string `json:"addrs"`
+	Hashes []string `json:"hashes"`
+}
+
+// outputsHandlerV2 returns the unspent outputs of a large number of addresses, or a large number
+// of unspent outputs by hash. All outputs are read in one database transaction, and the response is streamed.
+// The response has the same format as /api/v1/outputs.
+// URI: /api/v2/outputs
+// Method: POST
+// Content-Type: application/json
+// Body: {"addrs": ["<address>", ...]} or {"hashes": ["<uxout hash>", ...]}
+func outputsHandlerV2(gateway Gatewayer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			resp := NewHTTPErrorResponse(http.StatusMethodNotAllowed, "")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		if r.Header.Get("Content-Type") != ContentTypeJSON {
+			resp := NewHTTPErrorResponse(http.StatusUnsupportedMediaType, "")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		r.Body = http.MaxBytesReader(w, r.Body, maxBulkRequestBodySize)
+
+		var req OutputsRequestV2
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, err.Error())
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		if len(req.Addrs) != 0 && len(req.Hashes) != 0 {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, "addrs and hashes cannot be specified together")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		if err := checkBulkRequestItems("addrs", len(req.Addrs)); err != nil {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, err.Error())
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		if err := checkBulkRequestItems("hashes", len(req.Hashes)); err != nil {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, err.Error())
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		var summary *visor.UnspentOutputsSummary
+		switch {
+		case len(req.Addrs) != 0:
+			addrs, err := parseAddressList(req.Addrs)
+			if err != nil {
+				resp := NewHTTPErrorResponse(http.StatusBadRequest, err.Error())
+				writeHTTPResponse(w, resp)
+				return
+			}
+
+			summary, err = gateway.GetUnspentOutputsSummaryOfAddrs(addrs)
+			if err != nil {
+				resp := NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())
+				writeHTTPResponse(w, resp)
+				return
+			}
+
+		case len(req.Hashes) != 0:
+			hashes, err := parseHashList(req.Hashes)
+			if err != nil {
+				resp := NewHTTPErrorResponse(http.StatusBadRequest, err.Error())
+				writeHTTPResponse(w, resp)
+				return
+			}
+
+			summary, err = gateway.GetUnspentOutputsSummaryOfHashes(hashes)
+			if err != nil {
+				resp := NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())
+				writeHTTPResponse(w, resp)
+				return
+			}
+
+		default:
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, "addrs or hashes is required")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		rSummary, err := readable.NewUnspentOutputsSummary(summary)
+		if err != nil {
+			resp := NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		// The fields of readable.UnspentOutputsSummary other than the outputs, which are streamed
+		fields := struct {
+			Head readable.BlockHeader `json:"head"`
+		}{
+			Head: rSummary.Head,
+		}
+
+		outputsField := func(name string, outputs readable.UnspentOutputs) jsonArrayField {
+			return jsonArrayField{
+				Name: name,
+				Len:  len(outputs),
+				Elem: func(i int) interface{} {
+					return outputs[i]
+				},
+			}
+		}
+
+		writeStreamedHTTPResponse(w, fields,
+			outputsField("head_outputs", rSummary.HeadOutputs),
+			outputsField("outgoing_outputs", rSummary.OutgoingOutputs),
+			outputsField("incoming_outputs", rSummary.IncomingOutputs),
+		)
+	}
+}
@@ -13,8 +13,10 @@ import (
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 
+	"github.com/skycoin/skycoin/src/cipher"
 	"github.com/skycoin/skycoin/src/coin"
 	"github.com/skycoin/skycoin/src/readable"
+	"github.com/skycoin/skycoin/src/testutil"
 	"github.com/skycoin/skycoin/src/visor"
 )
 
@@ -160,3 +162,218 @@ func TestGetOutputsHandler(t *testing.T) {
 		})
 	}
 }
+
+func TestOutputsHandlerV2(t *testing.T) {
+	addr := testutil.MakeAddress()
+	hash := testutil.RandSHA256(t)
+
+	head := coin.SignedBlock{
+		Block: coin.Block{
+			Head: coin.BlockHeader{
+				BkSeq: 10,
+				Time:  1500000000,
+			},
+		},
+	}
+
+	makeOutputs := func(n int) []visor.UnspentOutput {
+		outs := make([]visor.UnspentOutput, n)
+		for i := range outs {
+			outs[i] = visor.UnspentOutput{
+				UxOut: coin.UxOut{
+					Head: coin.UxHead{
+						Time:  uint64(1500000000 - i),
+						BkSeq: 9,
+					},
+					Body: coin.UxBody{
+						SrcTransaction: testutil.RandSHA256(t),
+						Address:        addr,
+						Coins:          uint64(i+1) * 1e6,
+						Hours:          uint64(i),
+					},
+				},
+				CalculatedHours: uint64(i * 2),
+			}
+		}
+		return outs
+	}
+
+	summary := &visor.UnspentOutputsSummary{
+		HeadBlock: &head,
+		Confirmed: makeOutputs(2500),
+		Outgoing:  makeOutputs(1),
+	}
+
+	rSummary, err := readable.NewUnspentOutputsSummary(summary)
+	require.NoError(t, err)
+
+	cases := []struct {
+		name                 string
+		method               string
+		contentType          string
+		body                 string
+		status               int
+		err                  string
+		getSummaryOfAddrsArg []cipher.Address
+		getSummaryOfHashArg  []cipher.SHA256
+		getSummaryErr        error
+		response             *readable.UnspentOutputsSummary
+	}{
+		{
+			name:   "405",
+			method: http.MethodGet,
+			status: http.StatusMethodNotAllowed,
+		},
+		{
+			name:        "415",
+			method:      http.MethodPost,
+			contentType: ContentTypeForm,
+			status:      http.StatusUnsupportedMediaType,
+		},
+		{
+			name:   "400 - EOF",
+			method: http.MethodPost,
+			status: http.StatusBadRequest,
+			err:    "EOF",
+		},
+		{
+			name:   "400 - missing addrs and hashes",
+			method: http.MethodPost,
+			body:   "{}",
+			status: http.StatusBadRequest,
+			err:    "addrs or hashes is required",
+		},
+		{
+			name:   "400 - addrs and hashes together",
+			method: http.MethodPost,
+			body: toJSON(t, OutputsRequestV2{
+				Addrs:  []string{addr.String()},
+				Hashes: []string{hash.Hex()},
+			}),
+			status: http.StatusBadRequest,
+			err:    "addrs and hashes cannot be specified together",
+		},
+		{
+			name:   "400 - body too large",
+			method: http.MethodPost,
+			body:   `{"addrs": [` + strings.Repeat(" ", maxBulkRequestBodySize) + `]}`,
+			status: http.StatusBadRequest,
+			err:    "http: request body too large",
+		},
+		{
+			name:   "400 - too many addrs",
+			method: http.MethodPost,
+			body: toJSON(t, OutputsRequestV2{
+				Addrs: repeatString(addr.String(), maxBulkRequestItems+1),
+			}),
+			status: http.StatusBadRequest,
+			err:    "too many addrs, the maximum is 10000",
+		},
+		{
+			name:   "400 - too many hashes",
+			method: http.MethodPost,
+			body: toJSON(t, OutputsRequestV2{
+				Hashes: repeatString(hash.Hex(), maxBulkRequestItems+1),
+			}),
+			status: http.StatusBadRequest,
+			err:    "too many hashes, the maximum is 10000",
+		},
+		{
+			name:   "400 - invalid address",
+			method: http.MethodPost,
+			body: toJSON(t, OutputsRequestV2{
+				Addrs: []string{"foo"},
+			}),
+			status: http.StatusBadRequest,
+			err:    "address \"foo\" is invalid: Invalid address length",
+		},
+		{
+			name:   "400 - invalid hash",
+			method: http.MethodPost,
+			body: toJSON(t, OutputsRequestV2{
+				Hashes: []string{"foo"},
+			}),
+			status: http.StatusBadRequest,
+			err:    "SHA256 hash \"foo\" is invalid: encoding/hex: invalid byte: U+006F 'o'",
+		},
+		{
+			name:   "500 - gateway error",
+			method: http.MethodPost,
+			body: toJSON(t, OutputsRequestV2{
+				Addrs: []string{addr.String()},
+			}),
+			status:               http.StatusInternalServerError,
+			err:                  "GetUnspentOutputsSummaryOfAddrs failed",
+			getSummaryOfAddrsArg: []cipher.Address{addr},
+			getSummaryErr:        errors.New("GetUnspentOutputsSummaryOfAddrs failed"),
+		},
+		{
+			name:   "200 - addrs",
+			method: http.MethodPost,
+			body: toJSON(t, OutputsRequestV2{
+				Addrs: []string{addr.String(), addr.String()},
+			}),
+			status:               http.StatusOK,
+			getSummaryOfAddrsArg: []cipher.Address{addr},
+			response:             rSummary,
+		},
+		{
+			name:   "200 - hashes",
+			method: http.MethodPost,
+			body: toJSON(t, OutputsRequestV2{
+				Hashes: []string{hash.Hex()},
+			}),
+			status:              http.StatusOK,
+			getSummaryOfHashArg: []cipher.SHA256{hash},
+			response:            rSummary,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			gateway := &MockGatewayer{}
+			gateway.On("GetUnspentOutputsSummaryOfAddrs", tc.getSummaryOfAddrsArg).Return(summary, tc.getSummaryErr)
+			gateway.On("GetUnspentOutputsSummaryOfHashes", tc.getSummaryOfHashArg).Return(summary, tc.getSummaryErr)
+
+			req, err := http.NewRequest(tc.method, "/api/v2/outputs", strings.NewReader(tc.body))
+			require.NoError(t, err)
+
+			contentType := tc.contentType
+			if contentType == "" {
+				contentType = ContentTypeJSON
+			}
+			req.Header.Set("Content-Type", contentType)
+			setCSRFParameters(t, tokenValid, req)
+
+			rr := httptest.NewRecorder()
+			handler := newServerMux(defaultMuxConfig(), gateway)
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.status, rr.Code, "got `%v` want `%v`", rr.Code, tc.status)
+
+			var rsp ReceivedHTTPResponse
+			err = json.NewDecoder(rr.Body).Decode(&rsp)
+			require.NoError(t, err)
+
+			if tc.status != http.StatusOK {
+				require.Equal(t, NewHTTPErrorResponse(tc.status, tc.err).Error, rsp.Error)
+				return
+			}
+
+			require.Nil(t, rsp.Error)
+
+			var outputs readable.UnspentOutputsSummary
+			err = json.Unmarshal(rsp.Data, &outputs)
+			require.NoError(t, err)
+			require.Equal(t, *tc.response, outputs)
+		})
+	}
+}
+
+func repeatString(s string, n int) []string {
+	ss := make([]string, n)
+	for i := range ss {
+		ss[i] = s
+	}
+	return ss
+}
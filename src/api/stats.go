@@ -0,0 +1,90 @@
+package api
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+
+	"github.com/skycoin/skycoin/src/readable"
+	"github.com/skycoin/skycoin/src/visor"
+)
+
+// statsSeriesHandler returns the time series of a chain statistic
+// Method: GET
+// URI: /api/v2/stats/series
+// Args:
+//	metric: coin_supply, coin_hour_supply, transactions, fees_burned or active_addresses [required]
+//	interval: block or day [optional, default day]. A block interval series has at most 10000 points.
+//	start_time: unix time of the start of the range, inclusive [optional]
+//	end_time: unix time of the end of the range, inclusive [optional]
+func statsSeriesHandler(gateway Gatewayer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			resp := NewHTTPErrorResponse(http.StatusMethodNotAllowed, "")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		metric := visor.StatsMetric(r.FormValue("metric"))
+		if metric == "" {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, "metric is required")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		if !visor.IsValidStatsMetric(metric) {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, fmt.Sprintf("Invalid metric %q, must be one of %v", metric, visor.StatsMetrics))
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		interval := visor.StatsIntervalDay
+		if v := r.FormValue("interval"); v != "" {
+			interval = visor.StatsInterval(v)
+			if !visor.IsValidStatsInterval(interval) {
+				resp := NewHTTPErrorResponse(http.StatusBadRequest, fmt.Sprintf("Invalid interval %q, must be one of %v", interval, visor.StatsIntervals))
+				writeHTTPResponse(w, resp)
+				return
+			}
+		}
+
+		start, end, ok, err := parseTimeRange(r)
+		if err != nil {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, err.Error())
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		if !ok {
+			start = 0
+			end = math.MaxUint64
+		}
+
+		points, err := gateway.GetStatsSeries(metric, interval, start, end)
+		if err != nil {
+			status := http.StatusInternalServerError
+			switch {
+			case visor.IsErrPruned(err):
+				status = http.StatusGone
+			case visor.IsErrBackfilling(err), err == visor.ErrBlockStatsNotReady:
+				status = http.StatusServiceUnavailable
+			case err == visor.ErrStatsTooManyBlocks:
+				status = http.StatusBadRequest
+			}
+			resp := NewHTTPErrorResponse(status, err.Error())
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		series, err := readable.NewStatsSeries(metric, interval, points)
+		if err != nil {
+			resp := NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		writeHTTPResponse(w, HTTPResponse{
+			Data: series,
+		})
+	}
+}
@@ -0,0 +1,282 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/readable"
+	"github.com/skycoin/skycoin/src/visor"
+)
+
+func TestGetStatsSeries(t *testing.T) {
+	type httpBody struct {
+		Metric    string
+		Interval  string
+		StartTime string
+		EndTime   string
+	}
+
+	points := []visor.StatsPoint{
+		{Time: 0, Seq: 1, Value: 2},
+		{Time: 86400, Seq: 3, Value: 4},
+	}
+
+	tt := []struct {
+		name                        string
+		method                      string
+		status                      int
+		body                        httpBody
+		metric                      visor.StatsMetric
+		interval                    visor.StatsInterval
+		start                       uint64
+		end                         uint64
+		gatewayGetStatsSeriesResult []visor.StatsPoint
+		gatewayGetStatsSeriesErr    error
+		httpResponse                HTTPResponse
+	}{
+		{
+			name:         "405",
+			method:       http.MethodPost,
+			status:       http.StatusMethodNotAllowed,
+			httpResponse: NewHTTPErrorResponse(http.StatusMethodNotAllowed, ""),
+		},
+		{
+			name:         "400 - missing metric",
+			method:       http.MethodGet,
+			status:       http.StatusBadRequest,
+			httpResponse: NewHTTPErrorResponse(http.StatusBadRequest, "metric is required"),
+		},
+		{
+			name:   "400 - invalid metric",
+			method: http.MethodGet,
+			status: http.StatusBadRequest,
+			body: httpBody{
+				Metric: "foo",
+			},
+			httpResponse: NewHTTPErrorResponse(http.StatusBadRequest, `Invalid metric "foo", must be one of [coin_supply coin_hour_supply transactions fees_burned active_addresses]`),
+		},
+		{
+			name:   "400 - invalid interval",
+			method: http.MethodGet,
+			status: http.StatusBadRequest,
+			body: httpBody{
+				Metric:   "transactions",
+				Interval: "week",
+			},
+			httpResponse: NewHTTPErrorResponse(http.StatusBadRequest, `Invalid interval "week", must be one of [block day]`),
+		},
+		{
+			name:   "400 - invalid start_time",
+			method: http.MethodGet,
+			status: http.StatusBadRequest,
+			body: httpBody{
+				Metric:    "transactions",
+				StartTime: "foo",
+			},
+			httpResponse: NewHTTPErrorResponse(http.StatusBadRequest, `Invalid start_time value "foo"`),
+		},
+		{
+			name:   "400 - start_time after end_time",
+			method: http.MethodGet,
+			status: http.StatusBadRequest,
+			body: httpBody{
+				Metric:    "transactions",
+				StartTime: "2",
+				EndTime:   "1",
+			},
+			httpResponse: NewHTTPErrorResponse(http.StatusBadRequest, "start_time must not be after end_time"),
+		},
+		{
+			name:   "500 - gatewayGetStatsSeriesErr",
+			method: http.MethodGet,
+			status: http.StatusInternalServerError,
+			body: httpBody{
+				Metric: "transactions",
+			},
+			metric:                   visor.StatsMetricTransactions,
+			interval:                 visor.StatsIntervalDay,
+			start:                    0,
+			end:                      math.MaxUint64,
+			gatewayGetStatsSeriesErr: errors.New("gatewayGetStatsSeriesErr"),
+			httpResponse:             NewHTTPErrorResponse(http.StatusInternalServerError, "gatewayGetStatsSeriesErr"),
+		},
+		{
+			name:   "410 - pruned",
+			method: http.MethodGet,
+			status: http.StatusGone,
+			body: httpBody{
+				Metric: "transactions",
+			},
+			metric:                   visor.StatsMetricTransactions,
+			interval:                 visor.StatsIntervalDay,
+			start:                    0,
+			end:                      math.MaxUint64,
+			gatewayGetStatsSeriesErr: visor.ErrHistoryPruned,
+			httpResponse:             NewHTTPErrorResponse(http.StatusGone, visor.ErrHistoryPruned.Error()),
+		},
+		{
+			name:   "503 - not backfilled",
+			method: http.MethodGet,
+			status: http.StatusServiceUnavailable,
+			body: httpBody{
+				Metric: "transactions",
+			},
+			metric:                   visor.StatsMetricTransactions,
+			interval:                 visor.StatsIntervalDay,
+			start:                    0,
+			end:                      math.MaxUint64,
+			gatewayGetStatsSeriesErr: visor.ErrBlockStatsNotReady,
+			httpResponse:             NewHTTPErrorResponse(http.StatusServiceUnavailable, visor.ErrBlockStatsNotReady.Error()),
+		},
+		{
+			name:   "400 - too many blocks",
+			method: http.MethodGet,
+			status: http.StatusBadRequest,
+			body: httpBody{
+				Metric:   "transactions",
+				Interval: "block",
+			},
+			metric:                   visor.StatsMetricTransactions,
+			interval:                 visor.StatsIntervalBlock,
+			start:                    0,
+			end:                      math.MaxUint64,
+			gatewayGetStatsSeriesErr: visor.ErrStatsTooManyBlocks,
+			httpResponse:             NewHTTPErrorResponse(http.StatusBadRequest, visor.ErrStatsTooManyBlocks.Error()),
+		},
+		{
+			name:   "200 - daily",
+			method: http.MethodGet,
+			status: http.StatusOK,
+			body: httpBody{
+				Metric: "transactions",
+			},
+			metric:                      visor.StatsMetricTransactions,
+			interval:                    visor.StatsIntervalDay,
+			start:                       0,
+			end:                         math.MaxUint64,
+			gatewayGetStatsSeriesResult: points,
+			httpResponse: HTTPResponse{
+				Data: &readable.StatsSeries{
+					Metric:   "transactions",
+					Interval: "day",
+					Points: []readable.StatsPoint{
+						{Time: 0, Seq: 1, Value: "2"},
+						{Time: 86400, Seq: 3, Value: "4"},
+					},
+				},
+			},
+		},
+		{
+			name:   "200 - blocks in time range",
+			method: http.MethodGet,
+			status: http.StatusOK,
+			body: httpBody{
+				Metric:    "coin_supply",
+				Interval:  "block",
+				StartTime: "10",
+				EndTime:   "20",
+			},
+			metric:                      visor.StatsMetricCoinSupply,
+			interval:                    visor.StatsIntervalBlock,
+			start:                       10,
+			end:                         20,
+			gatewayGetStatsSeriesResult: []visor.StatsPoint{},
+			httpResponse: HTTPResponse{
+				Data: &readable.StatsSeries{
+					Metric:   "coin_supply",
+					Interval: "block",
+					Points:   []readable.StatsPoint{},
+				},
+			},
+		},
+		{
+			name:   "200 - coin supply in coins",
+			method: http.MethodGet,
+			status: http.StatusOK,
+			body: httpBody{
+				Metric: "coin_supply",
+			},
+			metric:   visor.StatsMetricCoinSupply,
+			interval: visor.StatsIntervalDay,
+			start:    0,
+			end:      math.MaxUint64,
+			gatewayGetStatsSeriesResult: []visor.StatsPoint{
+				{Time: 0, Seq: 1, Value: 1500000},
+				{Time: 86400, Seq: 3, Value: 100e6},
+			},
+			httpResponse: HTTPResponse{
+				Data: &readable.StatsSeries{
+					Metric:   "coin_supply",
+					Interval: "day",
+					Points: []readable.StatsPoint{
+						{Time: 0, Seq: 1, Value: "1.500000"},
+						{Time: 86400, Seq: 3, Value: "100.000000"},
+					},
+				},
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			endpoint := "/api/v2/stats/series"
+			gateway := &MockGatewayer{}
+
+			gateway.On("GetStatsSeries", tc.metric, tc.interval, tc.start, tc.end).Return(tc.gatewayGetStatsSeriesResult, tc.gatewayGetStatsSeriesErr)
+
+			v := url.Values{}
+			if tc.body.Metric != "" {
+				v.Add("metric", tc.body.Metric)
+			}
+			if tc.body.Interval != "" {
+				v.Add("interval", tc.body.Interval)
+			}
+			if tc.body.StartTime != "" {
+				v.Add("start_time", tc.body.StartTime)
+			}
+			if tc.body.EndTime != "" {
+				v.Add("end_time", tc.body.EndTime)
+			}
+			if len(v) > 0 {
+				endpoint += "?" + v.Encode()
+			}
+
+			req, err := http.NewRequest(tc.method, endpoint, nil)
+			require.NoError(t, err)
+
+			setCSRFParameters(t, tokenValid, req)
+
+			rr := httptest.NewRecorder()
+			handler := newServerMux(defaultMuxConfig(), gateway)
+			handler.ServeHTTP(rr, req)
+
+			status := rr.Code
+			require.Equal(t, tc.status, status, "got `%v` want `%v`", status, tc.status)
+
+			var rsp ReceivedHTTPResponse
+			err = json.NewDecoder(rr.Body).Decode(&rsp)
+			require.NoError(t, err)
+
+			require.Equal(t, tc.httpResponse.Error, rsp.Error)
+
+			if rsp.Data == nil {
+				require.Nil(t, tc.httpResponse.Data)
+				return
+			}
+
+			require.NotNil(t, tc.httpResponse.Data)
+
+			var msg *readable.StatsSeries
+			err = json.Unmarshal(rsp.Data, &msg)
+			require.NoError(t, err)
+			require.Equal(t, tc.httpResponse.Data, msg)
+		})
+	}
+}
@@ -40,7 +40,7 @@ func pendingTxnsHandler(gateway Gatewayer) http.HandlerFunc {
 		if verbose {
 			txns, inputs, err := gateway.GetAllUnconfirmedTransactionsVerbose()
 			if err != nil {
-				wh.Error500(w, err.Error())
+				errorUnavailableOr500(w, err)
 				return
 			}
 
@@ -122,7 +122,7 @@ func transactionHandler(gateway Gatewayer) http.HandlerFunc {
 		if verbose {
 			txn, inputs, err := gateway.GetTransactionWithInputs(h)
 			if err != nil {
-				wh.Error500(w, err.Error())
+				errorUnavailableOr500(w, err)
 				return
 			}
 			if txn == nil {
@@ -142,7 +142,7 @@ func transactionHandler(gateway Gatewayer) http.HandlerFunc {
 
 		txn, err := gateway.GetTransaction(h)
 		if err != nil {
-			wh.Error500(w, err.Error())
+			errorUnavailableOr500(w, err)
 			return
 		}
 		if txn == nil {
@@ -255,7 +255,10 @@ func NewTransactionsWithStatusVerbose(txns []visor.Transaction, inputs [][]visor
 // Args:
 //     addrs: Comma separated addresses [optional, returns all transactions if no address provided]
 //     confirmed: Whether the transactions should be confirmed [optional, must be 0 or 1; if not provided, returns all]
+//     start_time: Unix timestamp, returns transactions at or after this time [optional]
+//     end_time: Unix timestamp, returns transactions at or before this time [optional]
 //	   verbose: [bool] include verbose transaction input data
+// The time of a confirmed transaction is its block time, the time of an unconfirmed transaction is the time it was received.
 func transactionsHandler(gateway Gatewayer) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodGet && r.Method != http.MethodPost {
@@ -291,10 +294,21 @@ func transactionsHandler(gateway Gatewayer) http.HandlerFunc {
 			flts = append(flts, visor.NewConfirmedTxFilter(confirmed))
 		}
 
+		// Gets the 'start_time' and 'end_time' parameter values
+		start, end, ok, err := parseTimeRange(r)
+		if err != nil {
+			wh.Error400(w, err.Error())
+			return
+		}
+
+		if ok {
+			flts = append(flts, visor.NewTimeRangeTxFilter(start, end))
+		}
+
 		if verbose {
 			txns, inputs, err := gateway.GetTransactionsWithInputs(flts)
 			if err != nil {
-				wh.Error500(w, err.Error())
+				errorUnavailableOr500(w, err)
 				return
 			}
 
@@ -310,7 +324,7 @@ func transactionsHandler(gateway Gatewayer) http.HandlerFunc {
 		} else {
 			txns, err := gateway.GetTransactions(flts)
 			if err != nil {
-				wh.Error500(w, err.Error())
+				errorUnavailableOr500(w, err)
 				return
 			}
 
@@ -448,7 +462,14 @@ func rawTxnHandler(gateway Gatewayer) http.HandlerFunc {
 
 		txn, err := gateway.GetTransaction(h)
 		if err != nil {
-			wh.Error400(w, err.Error())
+			switch {
+			case visor.IsErrPruned(err):
+				wh.Error410(w, err.Error())
+			case visor.IsErrBackfilling(err):
+				wh.Error503(w, err.Error())
+			default:
+				wh.Error400(w, err.Error())
+			}
 			return
 		}
 
@@ -534,7 +555,14 @@ func verifyTxnHandler(gateway Gatewayer) http.HandlerFunc {
 					Message: err.Error(),
 				}
 			default:
-				resp := NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())
+				status := http.StatusInternalServerError
+				switch {
+				case visor.IsErrPruned(err):
+					status = http.StatusGone
+				case visor.IsErrBackfilling(err):
+					status = http.StatusServiceUnavailable
+				}
+				resp := NewHTTPErrorResponse(status, err.Error())
 				writeHTTPResponse(w, resp)
 				return
 			}
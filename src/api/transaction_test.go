@@ -357,6 +357,30 @@ func TestGetTransactionByID(t *testing.T) {
 			getTransactionError: errors.New("getTransactionError"),
 		},
 
+		{
+			name:   "410 - history pruned",
+			method: http.MethodGet,
+			status: http.StatusGone,
+			err:    "410 Gone - transaction history is not available on a pruned node",
+			httpBody: &httpBody{
+				txid: validHash,
+			},
+			txid:                testutil.SHA256FromHex(t, validHash),
+			getTransactionError: visor.ErrHistoryPruned,
+		},
+
+		{
+			name:   "503 - history backfilling",
+			method: http.MethodGet,
+			status: http.StatusServiceUnavailable,
+			err:    "503 Service Unavailable - transaction history is not available until the blocks before the snapshot have been downloaded",
+			httpBody: &httpBody{
+				txid: validHash,
+			},
+			txid:                testutil.SHA256FromHex(t, validHash),
+			getTransactionError: visor.ErrHistoryBackfilling,
+		},
+
 		{
 			name:   "500 - getTransactionResultVerboseError",
 			method: http.MethodGet,
@@ -1018,6 +1042,8 @@ func TestGetTransactions(t *testing.T) {
 	type httpBody struct {
 		addrs     string
 		confirmed string
+		startTime string
+		endTime   string
 		verbose   string
 	}
 
@@ -1088,6 +1114,28 @@ func TestGetTransactions(t *testing.T) {
 			},
 		},
 
+		{
+			name:   "400 - invalid `start_time` param",
+			method: http.MethodGet,
+			status: http.StatusBadRequest,
+			err:    "400 Bad Request - Invalid start_time value \"foo\"",
+			httpBody: &httpBody{
+				addrs:     addrsStr,
+				startTime: "foo",
+			},
+		},
+
+		{
+			name:   "400 - `start_time` after `end_time`",
+			method: http.MethodGet,
+			status: http.StatusBadRequest,
+			err:    "400 Bad Request - start_time must not be after end_time",
+			httpBody: &httpBody{
+				startTime: "200",
+				endTime:   "100",
+			},
+		},
+
 		{
 			name:   "500 - getTransactionsError",
 			method: http.MethodGet,
@@ -1174,6 +1222,38 @@ func TestGetTransactions(t *testing.T) {
 			getTransactionsResponse: []visor.Transaction{},
 			httpResponse:            []readable.TransactionWithStatus{},
 		},
+
+		{
+			name:   "200 time range",
+			method: http.MethodGet,
+			status: http.StatusOK,
+			httpBody: &httpBody{
+				addrs:     addrsStr,
+				startTime: "100",
+				endTime:   "200",
+			},
+			getTransactionsArg: []visor.TxFilter{
+				visor.NewAddrsFilter(addrs),
+				visor.NewTimeRangeTxFilter(100, 200),
+			},
+			getTransactionsResponse: []visor.Transaction{},
+			httpResponse:            []readable.TransactionWithStatus{},
+		},
+
+		{
+			name:   "200 start time only",
+			method: http.MethodGet,
+			status: http.StatusOK,
+			httpBody: &httpBody{
+				startTime: "100",
+			},
+			getTransactionsArg: []visor.TxFilter{
+				visor.NewAddrsFilter(nil),
+				visor.NewTimeRangeTxFilter(100, math.MaxUint64),
+			},
+			getTransactionsResponse: []visor.Transaction{},
+			httpResponse:            []readable.TransactionWithStatus{},
+		},
 	}
 
 	for _, tc := range tt {
@@ -1224,6 +1304,11 @@ func TestGetTransactions(t *testing.T) {
 							return false
 						}
 
+					case visor.TimeRangeFilter:
+						if tc.getTransactionsArg[i] != f {
+							return false
+						}
+
 					default:
 						return false
 					}
@@ -1244,6 +1329,12 @@ func TestGetTransactions(t *testing.T) {
 				if tc.httpBody.confirmed != "" {
 					v.Add("confirmed", tc.httpBody.confirmed)
 				}
+				if tc.httpBody.startTime != "" {
+					v.Add("start_time", tc.httpBody.startTime)
+				}
+				if tc.httpBody.endTime != "" {
+					v.Add("end_time", tc.httpBody.endTime)
+				}
 				if tc.httpBody.verbose != "" {
 					v.Add("verbose", tc.httpBody.verbose)
 				}
@@ -6,6 +6,7 @@ import (
 	"github.com/skycoin/skycoin/src/cipher"
 	"github.com/skycoin/skycoin/src/readable"
 	wh "github.com/skycoin/skycoin/src/util/http"
+	"github.com/skycoin/skycoin/src/visor"
 )
 
 // URI: /api/v1/uxout
@@ -34,7 +35,14 @@ func uxOutHandler(gateway Gatewayer) http.HandlerFunc {
 
 		uxout, err := gateway.GetUxOutByID(id)
 		if err != nil {
-			wh.Error400(w, err.Error())
+			switch {
+			case visor.IsErrPruned(err):
+				wh.Error410(w, err.Error())
+			case visor.IsErrBackfilling(err):
+				wh.Error503(w, err.Error())
+			default:
+				wh.Error400(w, err.Error())
+			}
 			return
 		}
 
@@ -73,7 +81,14 @@ func addrUxOutsHandler(gateway Gatewayer) http.HandlerFunc {
 
 		uxs, err := gateway.GetSpentOutputsForAddresses([]cipher.Address{cipherAddr})
 		if err != nil {
-			wh.Error400(w, err.Error())
+			switch {
+			case visor.IsErrPruned(err):
+				wh.Error410(w, err.Error())
+			case visor.IsErrBackfilling(err):
+				wh.Error503(w, err.Error())
+			default:
+				wh.Error400(w, err.Error())
+			}
 			return
 		}
 
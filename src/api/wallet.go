@@ -176,6 +176,121 @@ func balanceHandler(gateway Gatewayer) http.HandlerFunc {
 	}
 }
 
+// BalanceRequestV2 is the request data for POST /api/v2/balance
+type BalanceRequestV2 struct {
+	Addrs []string `json:"addrs"`
+}
+
+// BalanceResponseV2 is returned by POST /api/v2/balance
+type BalanceResponseV2 struct {
+	// Head is the head block the balances were read at
+	Head readable.BlockHeader `json:"head"`
+	readable.BalancePair
+	// Addresses are in the order of the request, without duplicates
+	Addresses []AddressBalance `json:"addresses"`
+}
+
+// AddressBalance is the balance of an address in BalanceResponseV2
+type AddressBalance struct {
+	Address string `json:"address"`
+	readable.BalancePair
+}
+
+// balanceHandlerV2 returns the confirmed and predicted balances of a large number of addresses.
+// All balances are read in one database transaction, and the response is streamed.
+// URI: /api/v2/balance
+// Method: POST
+// Content-Type: application/json
+// Body: {"addrs": ["<address>", ...]}
+func balanceHandlerV2(gateway Gatewayer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			resp := NewHTTPErrorResponse(http.StatusMethodNotAllowed, "")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		if r.Header.Get("Content-Type") != ContentTypeJSON {
+			resp := NewHTTPErrorResponse(http.StatusUnsupportedMediaType, "")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		r.Body = http.MaxBytesReader(w, r.Body, maxBulkRequestBodySize)
+
+		var req BalanceRequestV2
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, err.Error())
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		if err := checkBulkRequestItems("addrs", len(req.Addrs)); err != nil {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, err.Error())
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		addrs, err := parseAddressList(req.Addrs)
+		if err != nil {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, err.Error())
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		if len(addrs) == 0 {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, "addrs is required")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		balances, err := gateway.GetAddressBalances(addrs)
+		if err != nil {
+			resp := NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		var balance wallet.BalancePair
+		for _, bal := range balances.Balances {
+			var err error
+			balance.Confirmed, err = balance.Confirmed.Add(bal.Confirmed)
+			if err != nil {
+				resp := NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())
+				writeHTTPResponse(w, resp)
+				return
+			}
+
+			balance.Predicted, err = balance.Predicted.Add(bal.Predicted)
+			if err != nil {
+				resp := NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())
+				writeHTTPResponse(w, resp)
+				return
+			}
+		}
+
+		// The fields of BalanceResponseV2 other than Addresses, which is streamed
+		fields := struct {
+			Head readable.BlockHeader `json:"head"`
+			readable.BalancePair
+		}{
+			Head:        readable.NewBlockHeader(balances.HeadBlock.Head),
+			BalancePair: readable.NewBalancePair(balance),
+		}
+
+		writeStreamedHTTPResponse(w, fields, jsonArrayField{
+			Name: "addresses",
+			Len:  len(addrs),
+			Elem: func(i int) interface{} {
+				return AddressBalance{
+					Address:     addrs[i].String(),
+					BalancePair: readable.NewBalancePair(balances.Balances[i]),
+				}
+			},
+		})
+	}
+}
+
 // Loads wallet from seed, will scan ahead N address and
 // load addresses till the last one that have coins.
 // URI: /api/v1/wallet/create
@@ -209,6 +209,179 @@ func TestGetBalanceHandler(t *testing.T) {
 	}
 }
 
+func TestBalanceHandlerV2(t *testing.T) {
+	// More addresses than streamFlushInterval, so that the response is flushed while it is written
+	addrs := make([]cipher.Address, 2500)
+	addrStrs := make([]string, len(addrs))
+	balances := make([]wallet.BalancePair, len(addrs))
+	for i := range addrs {
+		addrs[i] = cipher.Address{
+			Key: cipher.HashRipemd160(testutil.RandBytes(t, 32)),
+		}
+		addrStrs[i] = addrs[i].String()
+		balances[i] = wallet.BalancePair{
+			Confirmed: wallet.Balance{Coins: uint64(i) * 1e6, Hours: uint64(i)},
+			Predicted: wallet.Balance{Coins: uint64(i) * 2e6, Hours: uint64(i) * 2},
+		}
+	}
+
+	head := coin.SignedBlock{
+		Block: coin.Block{
+			Head: coin.BlockHeader{
+				BkSeq: 10,
+				Time:  1500000000,
+			},
+		},
+	}
+
+	var total wallet.BalancePair
+	expectedAddrs := make([]AddressBalance, len(addrs))
+	for i, bp := range balances {
+		var err error
+		total.Confirmed, err = total.Confirmed.Add(bp.Confirmed)
+		require.NoError(t, err)
+		total.Predicted, err = total.Predicted.Add(bp.Predicted)
+		require.NoError(t, err)
+
+		expectedAddrs[i] = AddressBalance{
+			Address:     addrStrs[i],
+			BalancePair: readable.NewBalancePair(bp),
+		}
+	}
+
+	cases := []struct {
+		name         string
+		method       string
+		contentType  string
+		body         string
+		status       int
+		err          string
+		gatewayAddrs []cipher.Address
+		gatewayResp  *visor.AddressBalances
+		gatewayErr   error
+		response     *BalanceResponseV2
+	}{
+		{
+			name:   "405",
+			method: http.MethodGet,
+			status: http.StatusMethodNotAllowed,
+		},
+		{
+			name:        "415",
+			method:      http.MethodPost,
+			contentType: ContentTypeForm,
+			status:      http.StatusUnsupportedMediaType,
+		},
+		{
+			name:   "400 - EOF",
+			method: http.MethodPost,
+			status: http.StatusBadRequest,
+			err:    "EOF",
+		},
+		{
+			name:   "400 - missing addrs",
+			method: http.MethodPost,
+			body:   "{}",
+			status: http.StatusBadRequest,
+			err:    "addrs is required",
+		},
+		{
+			name:   "400 - body too large",
+			method: http.MethodPost,
+			body:   `{"addrs": [` + strings.Repeat(" ", maxBulkRequestBodySize) + `]}`,
+			status: http.StatusBadRequest,
+			err:    "http: request body too large",
+		},
+		{
+			name:   "400 - too many addrs",
+			method: http.MethodPost,
+			body: toJSON(t, BalanceRequestV2{
+				Addrs: repeatString(addrStrs[0], maxBulkRequestItems+1),
+			}),
+			status: http.StatusBadRequest,
+			err:    "too many addrs, the maximum is 10000",
+		},
+		{
+			name:   "400 - invalid address",
+			method: http.MethodPost,
+			body: toJSON(t, BalanceRequestV2{
+				Addrs: []string{addrStrs[0], "foo"},
+			}),
+			status: http.StatusBadRequest,
+			err:    "address \"foo\" is invalid: Invalid address length",
+		},
+		{
+			name:   "500 - gateway error",
+			method: http.MethodPost,
+			body: toJSON(t, BalanceRequestV2{
+				Addrs: addrStrs[:1],
+			}),
+			status:       http.StatusInternalServerError,
+			err:          "GetAddressBalances failed",
+			gatewayAddrs: addrs[:1],
+			gatewayErr:   errors.New("GetAddressBalances failed"),
+		},
+		{
+			name:   "200",
+			method: http.MethodPost,
+			// Duplicate addresses are removed
+			body: toJSON(t, BalanceRequestV2{
+				Addrs: append(addrStrs, addrStrs[0]),
+			}),
+			status:       http.StatusOK,
+			gatewayAddrs: addrs,
+			gatewayResp: &visor.AddressBalances{
+				HeadBlock: &head,
+				Balances:  balances,
+			},
+			response: &BalanceResponseV2{
+				Head:        readable.NewBlockHeader(head.Head),
+				BalancePair: readable.NewBalancePair(total),
+				Addresses:   expectedAddrs,
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			gateway := &MockGatewayer{}
+			gateway.On("GetAddressBalances", tc.gatewayAddrs).Return(tc.gatewayResp, tc.gatewayErr)
+
+			req, err := http.NewRequest(tc.method, "/api/v2/balance", strings.NewReader(tc.body))
+			require.NoError(t, err)
+
+			contentType := tc.contentType
+			if contentType == "" {
+				contentType = ContentTypeJSON
+			}
+			req.Header.Set("Content-Type", contentType)
+			setCSRFParameters(t, tokenValid, req)
+
+			rr := httptest.NewRecorder()
+			handler := newServerMux(defaultMuxConfig(), gateway)
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.status, rr.Code, "got `%v` want `%v`", rr.Code, tc.status)
+
+			var rsp ReceivedHTTPResponse
+			err = json.NewDecoder(rr.Body).Decode(&rsp)
+			require.NoError(t, err)
+
+			if tc.status != http.StatusOK {
+				require.Equal(t, NewHTTPErrorResponse(tc.status, tc.err).Error, rsp.Error)
+				return
+			}
+
+			require.Nil(t, rsp.Error)
+
+			var balanceRsp BalanceResponseV2
+			err = json.Unmarshal(rsp.Data, &balanceRsp)
+			require.NoError(t, err)
+			require.Equal(t, *tc.response, balanceRsp)
+		})
+	}
+}
+
 func TestWalletGet(t *testing.T) {
 	entries, resEntries := makeEntries([]byte("seed"), 5)
 	type httpBody struct {
@@ -0,0 +1,41 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/skycoin/skycoin/src/cipher"
+)
+
+func addressHistoryCmd() *cobra.Command {
+	return &cobra.Command{
+		Short: "Show the transaction history of addresses with running balances",
+		Use:   "addressHistory [address list]",
+		Long: `Display the transaction history of specific addresses, separate multiple addresses with a space.
+    Each transaction is shown with its direction (in/out/self), net coin and hour change,
+    the address balance after it, its block seq and time and its number of confirmations.
+    Unconfirmed transactions are listed first, followed by confirmed transactions, most recent first.
+    Coin values are in droplets.
+    example: addressHistory addr1 addr2 addr3`,
+		Args:                  cobra.MinimumNArgs(1),
+		DisableFlagsInUseLine: true,
+		SilenceUsage:          true,
+		RunE:                  getAddressHistoryCmd,
+	}
+}
+
+func getAddressHistoryCmd(_ *cobra.Command, args []string) error {
+	for _, a := range args {
+		if _, err := cipher.DecodeBase58Address(a); err != nil {
+			return fmt.Errorf("invalid address: %v, err: %v", a, err)
+		}
+	}
+
+	histories, err := apiClient.AddressHistory(args)
+	if err != nil {
+		return err
+	}
+
+	return printJSON(histories)
+}
@@ -0,0 +1,42 @@
+package cli
+
+import (
+	"io"
+
+	"github.com/spf13/cobra"
+
+	"github.com/skycoin/skycoin/src/visor"
+)
+
+func backupDBCmd() *cobra.Command {
+	backupDBCmd := &cobra.Command{
+		Short: "Back up the database of a running node",
+		Use:   "backupDB [flags] [output file]",
+		Long: `Downloads a consistent copy of the node's database without stopping the node, using the DB_ADMIN API set.
+    The manifest of the backup, with the head block seq and hash, is saved next to it in [output file].manifest.json.
+    The backup is verified by opening it once it is downloaded.
+    An existing output file is not overwritten.`,
+		Args:                  cobra.ExactArgs(1),
+		DisableFlagsInUseLine: true,
+		SilenceUsage:          true,
+		RunE: func(c *cobra.Command, args []string) error {
+			compress, err := c.Flags().GetBool("compress")
+			if err != nil {
+				return err
+			}
+
+			m, err := visor.WriteBackupFile(args[0], func(w io.Writer) (*visor.BackupManifest, error) {
+				return apiClient.BackupDB(w, compress)
+			})
+			if err != nil {
+				return err
+			}
+
+			return printJSON(m)
+		},
+	}
+
+	backupDBCmd.Flags().BoolP("compress", "z", false, "Compress the backup with gzip")
+
+	return backupDBCmd
+}
@@ -0,0 +1,71 @@
+package cli
+
+import (
+	"github.com/spf13/cobra"
+)
+
+func listBansCmd() *cobra.Command {
+	return &cobra.Command{
+		Short:                 "List banned peer IPs",
+		Use:                   "listBans",
+		Args:                  cobra.NoArgs,
+		DisableFlagsInUseLine: true,
+		SilenceUsage:          true,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			bans, err := apiClient.Bans()
+			if err != nil {
+				return err
+			}
+
+			return printJSON(bans)
+		},
+	}
+}
+
+func banPeerCmd() *cobra.Command {
+	banPeerCmd := &cobra.Command{
+		Short: "Ban a peer IP",
+		Use:   "banPeer [flags] [ip or ip:port]",
+		Long: `Ban a peer IP and disconnect all connections from it.
+    If the duration is not specified, the node's default ban duration is used.`,
+		Args:                  cobra.ExactArgs(1),
+		DisableFlagsInUseLine: true,
+		SilenceUsage:          true,
+		RunE: func(c *cobra.Command, args []string) error {
+			duration, err := c.Flags().GetDuration("duration")
+			if err != nil {
+				return err
+			}
+
+			reason, err := c.Flags().GetString("reason")
+			if err != nil {
+				return err
+			}
+
+			ban, err := apiClient.BanPeer(args[0], duration, reason)
+			if err != nil {
+				return err
+			}
+
+			return printJSON(ban)
+		},
+	}
+
+	banPeerCmd.Flags().DurationP("duration", "d", 0, "Duration of the ban, e.g. 24h")
+	banPeerCmd.Flags().StringP("reason", "r", "", "Reason for the ban")
+
+	return banPeerCmd
+}
+
+func unbanPeerCmd() *cobra.Command {
+	return &cobra.Command{
+		Short:                 "Remove the ban of a peer IP",
+		Use:                   "unbanPeer [ip or ip:port]",
+		Args:                  cobra.ExactArgs(1),
+		DisableFlagsInUseLine: true,
+		SilenceUsage:          true,
+		RunE: func(_ *cobra.Command, args []string) error {
+			return apiClient.UnbanPeer(args[0])
+		},
+	}
+}
@@ -0,0 +1,184 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/spf13/cobra"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/util/apputil"
+	"github.com/skycoin/skycoin/src/visor"
+)
+
+func exportBlocksCmd() *cobra.Command {
+	exportBlocksCmd := &cobra.Command{
+		Short: "Export blocks to a block file",
+		Use:   "exportBlocks [flags] [start] [end]",
+		Long: `Writes the signed blocks from [start] to [end], inclusive, to a block file.
+    The block file can be imported by another node with importBlocks, or at startup with -import-blocks.
+    [start] defaults to the genesis block and [end] defaults to the head block.
+    If no db path is specificed, the default data.db in $HOME/.$COIN/ will be used.
+    The database is opened read-only, so blocks can be exported while the node is running.
+    Pruned blocks can not be exported.`,
+		Args:         cobra.MaximumNArgs(2),
+		SilenceUsage: true,
+		RunE:         exportBlocks,
+	}
+
+	exportBlocksCmd.Flags().StringP("output", "o", "", "Block file to write. Must not exist.")
+	exportBlocksCmd.Flags().String("db", "", "Database path")
+
+	return exportBlocksCmd
+}
+
+func exportBlocks(c *cobra.Command, args []string) error {
+	var start, end uint64
+	var err error
+	if len(args) > 0 {
+		start, err = strconv.ParseUint(args[0], 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid start block seq: %v, must be unsigned integer", args[0])
+		}
+	}
+
+	if len(args) > 1 {
+		end, err = strconv.ParseUint(args[1], 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid end block seq: %v, must be unsigned integer", args[1])
+		}
+
+		if end == 0 {
+			return errors.New("end block seq must be greater than 0")
+		}
+	}
+
+	output, err := c.Flags().GetString("output")
+	if err != nil {
+		return err
+	}
+
+	if output == "" {
+		return errors.New("missing --output block file")
+	}
+
+	dbPath, err := c.Flags().GetString("db")
+	if err != nil {
+		return err
+	}
+
+	dbPath, err = resolveDBPath(cliConfig, dbPath)
+	if err != nil {
+		return err
+	}
+
+	if _, err := os.Stat(dbPath); os.IsNotExist(err) {
+		return fmt.Errorf("db file: %v does not exist", dbPath)
+	}
+
+	db, err := bolt.Open(dbPath, 0600, &bolt.Options{
+		Timeout:  5 * time.Second,
+		ReadOnly: true,
+	})
+	if err != nil {
+		return fmt.Errorf("open db failed: %v", err)
+	}
+	defer db.Close()
+
+	f, err := os.OpenFile(output, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+
+	n, err := visor.ExportBlocks(wrapDB(db), f, start, end)
+	if err != nil {
+		f.Close()
+		os.Remove(output)
+		return fmt.Errorf("export blocks failed: %v", err)
+	}
+
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	fmt.Printf("exported %d blocks from block %d to block %d\n", n, start, start+n-1)
+	return nil
+}
+
+func importBlocksCmd() *cobra.Command {
+	return &cobra.Command{
+		Short: "Import a block file into the database",
+		Use:   "importBlocks [block file] [db path]",
+		Long: `Executes the blocks of a block file created with exportBlocks, verifying their signatures
+    against the blockchain pubkey. A new database is created if it does not exist.
+    The blocks that are already in the blockchain are skipped, so an interrupted import
+    is resumed by running the command again.
+    If no db path is specificed, the default data.db in $HOME/.$COIN/ will be used.
+    The node must be stopped. To import a block file when the node starts, use -import-blocks.`,
+		Args:                  cobra.RangeArgs(1, 2),
+		DisableFlagsInUseLine: true,
+		SilenceUsage:          true,
+		RunE:                  importBlocks,
+	}
+}
+
+func importBlocks(_ *cobra.Command, args []string) error {
+	dbPath := ""
+	if len(args) > 1 {
+		dbPath = args[1]
+	}
+	dbPath, err := resolveDBPath(cliConfig, dbPath)
+	if err != nil {
+		return err
+	}
+
+	pubkey, err := cipher.PubKeyFromHex(blockchainPubkey)
+	if err != nil {
+		return fmt.Errorf("decode blockchain pubkey failed: %v", err)
+	}
+
+	f, err := os.Open(args[0])
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	// The db is locked while the node is running
+	db, err := bolt.Open(dbPath, 0600, &bolt.Options{
+		Timeout: 5 * time.Second,
+	})
+	if err == bolt.ErrTimeout {
+		return fmt.Errorf("open db failed: %v, stop the node before importing blocks", err)
+	} else if err != nil {
+		return fmt.Errorf("open db failed: %v", err)
+	}
+	defer db.Close()
+
+	go func() {
+		apputil.CatchInterrupt(quitChan)
+	}()
+
+	printResult := func(r visor.ImportBlocksResult) {
+		fmt.Printf("imported %d blocks, skipped %d blocks, head block %d, %.1f blocks/s\n",
+			r.Imported, r.Skipped, r.HeadSeq, r.BlocksPerSecond())
+	}
+
+	result, err := visor.ImportBlocks(wrapDB(db), pubkey, f, quitChan, printResult)
+	if result != nil {
+		printResult(*result)
+	}
+
+	switch err {
+	case nil:
+		return nil
+	case visor.ErrImportBlocksStopped:
+		fmt.Println("import stopped, run the command again to resume")
+		return nil
+	default:
+		return fmt.Errorf("import blocks failed: %v", err)
+	}
+}
@@ -225,8 +225,10 @@ func NewCLI(cfg Config) (*cobra.Command, error) {
 		addPrivateKeyCmd(),
 		addressBalanceCmd(),
 		addressGenCmd(),
+		addressHistoryCmd(),
 		fiberAddressGenCmd(),
 		addressOutputsCmd(),
+		backupDBCmd(),
 		blocksCmd(),
 		broadcastTxCmd(),
 		checkDBCmd(),
@@ -235,12 +237,19 @@ func NewCLI(cfg Config) (*cobra.Command, error) {
 		decodeRawTxnCmd(),
 		decryptWalletCmd(),
 		encryptWalletCmd(),
+		exportBlocksCmd(),
+		exportSnapshotCmd(),
+		importBlocksCmd(),
+		importSnapshotCmd(),
 		lastBlocksCmd(),
 		listAddressesCmd(),
 		listWalletsCmd(),
+		migrateDBCmd(),
+		rollbackCmd(),
 		sendCmd(),
 		showConfigCmd(),
 		showSeedCmd(),
+		statsSeriesCmd(),
 		statusCmd(),
 		transactionCmd(),
 		verifyAddressCmd(),
@@ -253,6 +262,10 @@ func NewCLI(cfg Config) (*cobra.Command, error) {
 		walletOutputsCmd(),
 		richlistCmd(),
 		addressTransactionsCmd(),
+		listBansCmd(),
+		banPeerCmd(),
+		unbanPeerCmd(),
+		networkStatsCmd(),
 	}
 
 	skyCLI.Version = Version
@@ -0,0 +1,132 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/spf13/cobra"
+
+	"github.com/skycoin/skycoin/src/visor"
+)
+
+func migrateDBCmd() *cobra.Command {
+	migrateDBCmd := &cobra.Command{
+		Short: "Show and apply the pending database schema migrations",
+		Use:   "migrateDB [db path]",
+		Long: `Shows the schema version of the database of a stopped node and the migrations that have not been applied to it.
+    The migrations are applied with --apply, after copying the database file unless --backup=false is set.
+    With --dry-run, the migrations are applied and then rolled back, to check that they succeed.
+    If no db path is specificed, the default data.db in $HOME/.$COIN/ will be used.
+    The node applies the pending migrations when it starts.`,
+		Args:         cobra.MaximumNArgs(1),
+		SilenceUsage: true,
+		RunE:         migrateDB,
+	}
+
+	migrateDBCmd.Flags().Bool("apply", false, "Apply the pending migrations")
+	migrateDBCmd.Flags().Bool("dry-run", false, "Apply the pending migrations and roll them back")
+	migrateDBCmd.Flags().Bool("backup", true, "Copy the database file before applying the migrations")
+
+	return migrateDBCmd
+}
+
+func migrateDB(c *cobra.Command, args []string) error {
+	apply, err := c.Flags().GetBool("apply")
+	if err != nil {
+		return err
+	}
+
+	dryRun, err := c.Flags().GetBool("dry-run")
+	if err != nil {
+		return err
+	}
+
+	backup, err := c.Flags().GetBool("backup")
+	if err != nil {
+		return err
+	}
+
+	if apply && dryRun {
+		return errors.New("--apply and --dry-run can not be used together")
+	}
+
+	dbPath := ""
+	if len(args) > 0 {
+		dbPath = args[0]
+	}
+	dbPath, err = resolveDBPath(cliConfig, dbPath)
+	if err != nil {
+		return err
+	}
+
+	if _, err := os.Stat(dbPath); os.IsNotExist(err) {
+		return fmt.Errorf("db file: %v does not exist", dbPath)
+	}
+
+	readOnly := !apply && !dryRun
+
+	// The db is locked while the node is running
+	db, err := bolt.Open(dbPath, 0600, &bolt.Options{
+		Timeout:  5 * time.Second,
+		ReadOnly: readOnly,
+	})
+	if err == bolt.ErrTimeout {
+		return fmt.Errorf("open db failed: %v, stop the node before migrating", err)
+	} else if err != nil {
+		return fmt.Errorf("open db failed: %v", err)
+	}
+	defer db.Close()
+
+	wdb := wrapDB(db)
+
+	if readOnly {
+		version, ok, err := visor.GetSchemaVersion(wdb)
+		if err != nil {
+			return err
+		}
+
+		pending, err := visor.PendingMigrations(wdb)
+		if err != nil {
+			return err
+		}
+
+		if ok {
+			fmt.Printf("schema version: %d\n", version)
+		} else {
+			fmt.Println("schema version: not set")
+		}
+		fmt.Printf("latest schema version: %d\n", visor.LatestSchemaVersion())
+		fmt.Printf("%d pending migrations\n", len(pending))
+		for _, m := range pending {
+			fmt.Printf("%d %s: %s\n", m.Version, m.Name, m.Description)
+		}
+
+		return nil
+	}
+
+	result, err := visor.Migrate(wdb, visor.MigrateOptions{
+		DryRun: dryRun,
+		Backup: backup,
+		Progress: func(m visor.Migration, msg string) {
+			fmt.Printf("%d %s: %s\n", m.Version, m.Name, msg)
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("migrate db failed: %v", err)
+	}
+
+	if result.BackupPath != "" {
+		fmt.Printf("copied the database to %s\n", result.BackupPath)
+	}
+
+	if dryRun {
+		fmt.Printf("dry run: %d migrations would migrate the schema version from %d to %d\n", len(result.Applied), result.From, result.To)
+	} else {
+		fmt.Printf("applied %d migrations, schema version is %d\n", len(result.Applied), result.To)
+	}
+
+	return nil
+}
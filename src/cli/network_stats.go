@@ -0,0 +1,69 @@
+package cli
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/skycoin/skycoin/src/readable"
+)
+
+// NetworkStats is the output of the networkStats command
+type NetworkStats struct {
+	readable.NetworkStats
+	Connections []ConnectionStats `json:"connections,omitempty"`
+}
+
+// ConnectionStats is the traffic of a single connection
+type ConnectionStats struct {
+	Address  string                `json:"address"`
+	Outgoing bool                  `json:"outgoing"`
+	Stats    readable.NetworkStats `json:"stats"`
+}
+
+func networkStatsCmd() *cobra.Command {
+	networkStatsCmd := &cobra.Command{
+		Short: "Show network traffic statistics",
+		Use:   "networkStats [flags]",
+		Long: `Show the messages and bytes sent and received by the node since it started,
+    by message type, and the send and receive rates averaged over the last minute.`,
+		Args:                  cobra.NoArgs,
+		DisableFlagsInUseLine: true,
+		SilenceUsage:          true,
+		RunE: func(c *cobra.Command, _ []string) error {
+			showConns, err := c.Flags().GetBool("connections")
+			if err != nil {
+				return err
+			}
+
+			stats, err := apiClient.NetworkStats()
+			if err != nil {
+				return err
+			}
+
+			out := NetworkStats{
+				NetworkStats: *stats,
+			}
+
+			if showConns {
+				conns, err := apiClient.NetworkConnections(nil)
+				if err != nil {
+					return err
+				}
+
+				out.Connections = make([]ConnectionStats, len(conns.Connections))
+				for i, c := range conns.Connections {
+					out.Connections[i] = ConnectionStats{
+						Address:  c.Addr,
+						Outgoing: c.Outgoing,
+						Stats:    c.Stats,
+					}
+				}
+			}
+
+			return printJSON(out)
+		},
+	}
+
+	networkStatsCmd.Flags().BoolP("connections", "c", false, "Include the traffic of each connection")
+
+	return networkStatsCmd
+}
@@ -12,9 +12,9 @@ import (
 func richlistCmd() *cobra.Command {
 	return &cobra.Command{
 		Short:                 "Get skycoin richlist",
-		Long:                  "Returns top N address (default 20) balances (based on unspent outputs). Optionally include distribution addresses (exluded by default).",
-		Use:                   "richlist [top N addresses (20 default)] [include distribution addresses (false default)]",
-		Args:                  cobra.MaximumNArgs(2),
+		Long:                  "Returns top N address (default 20) balances (based on unspent outputs). Optionally include distribution addresses (exluded by default). Pages of N addresses after the top N are returned with the page argument.",
+		Use:                   "richlist [top N addresses (20 default)] [include distribution addresses (false default)] [page (1 default)]",
+		Args:                  cobra.MaximumNArgs(3),
 		DisableFlagsInUseLine: true,
 		SilenceUsage:          true,
 		RunE:                  getRichlist,
@@ -25,6 +25,7 @@ func getRichlist(_ *cobra.Command, args []string) error {
 	// default values
 	num := "20"
 	dist := "false"
+	pg := "1"
 
 	switch len(args) {
 	case 1:
@@ -32,6 +33,10 @@ func getRichlist(_ *cobra.Command, args []string) error {
 	case 2:
 		num = args[0]
 		dist = args[1]
+	case 3:
+		num = args[0]
+		dist = args[1]
+		pg = args[2]
 	}
 
 	n, err := strconv.Atoi(num)
@@ -44,8 +49,14 @@ func getRichlist(_ *cobra.Command, args []string) error {
 		return fmt.Errorf("invalid (bool) flag for include distribution addresses, %s", err)
 	}
 
+	page, err := strconv.Atoi(pg)
+	if err != nil || page < 1 {
+		return fmt.Errorf("invalid page, %s", pg)
+	}
+
 	params := &api.RichlistParams{
 		N:                   n,
+		Page:                page,
 		IncludeDistribution: d,
 	}
 
@@ -0,0 +1,116 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/spf13/cobra"
+
+	"github.com/skycoin/skycoin/src/params"
+	"github.com/skycoin/skycoin/src/visor"
+)
+
+func rollbackCmd() *cobra.Command {
+	rollbackCmd := &cobra.Command{
+		Short: "Roll the blockchain back to a block",
+		Use:   "rollback [block seq] [db path]",
+		Long: `Removes the blocks after a block from the blockchain database of a stopped node.
+    The unspent outputs and the transaction history are restored to their state at the block,
+    and the transactions of the removed blocks are returned to the unconfirmed pool if they are still valid.
+    If no db path is specificed, the default data.db in $HOME/.$COIN/ will be used.
+    At most the last 10000 blocks can be rolled back. Blocks executed before undo records were introduced,
+    and the blocks of pruned nodes, can not be rolled back.
+    The removed blocks can not be recovered, so the --confirm flag is required. Back up the database first.`,
+		Args:         cobra.RangeArgs(1, 2),
+		SilenceUsage: true,
+		RunE:         rollback,
+	}
+
+	rollbackCmd.Flags().Bool("confirm", false, "Confirm that the blocks after the block seq will be removed")
+
+	return rollbackCmd
+}
+
+func rollback(c *cobra.Command, args []string) error {
+	seq, err := strconv.ParseUint(args[0], 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid block seq: %v, must be unsigned integer", args[0])
+	}
+
+	confirm, err := c.Flags().GetBool("confirm")
+	if err != nil {
+		return err
+	}
+
+	if !confirm {
+		return errors.New("rolling back removes blocks from the database, use --confirm to proceed")
+	}
+
+	dbPath := ""
+	if len(args) > 1 {
+		dbPath = args[1]
+	}
+	dbPath, err = resolveDBPath(cliConfig, dbPath)
+	if err != nil {
+		return err
+	}
+
+	if _, err := os.Stat(dbPath); os.IsNotExist(err) {
+		return fmt.Errorf("db file: %v does not exist", dbPath)
+	}
+
+	// The db is locked while the node is running
+	db, err := bolt.Open(dbPath, 0600, &bolt.Options{
+		Timeout: 5 * time.Second,
+	})
+	if err == bolt.ErrTimeout {
+		return fmt.Errorf("open db failed: %v, stop the node before rolling back", err)
+	} else if err != nil {
+		return fmt.Errorf("open db failed: %v", err)
+	}
+	defer db.Close()
+
+	result, err := visor.Rollback(wrapDB(db), seq, params.UserVerifyTxn)
+	if err != nil {
+		return fmt.Errorf("rollback failed: %v", err)
+	}
+
+	return printJSON(newRollbackResult(seq, result))
+}
+
+// RollbackResult is the output of the rollback command
+type RollbackResult struct {
+	// HeadSeq is the seq of the head block after the rollback
+	HeadSeq uint64 `json:"head_seq"`
+	// RemovedBlocks are the seqs of the removed blocks, most recent first
+	RemovedBlocks []uint64 `json:"removed_blocks"`
+	// ReturnedTransactions are returned to the unconfirmed pool
+	ReturnedTransactions []string `json:"returned_transactions"`
+	// DroppedTransactions are no longer valid
+	DroppedTransactions []string `json:"dropped_transactions"`
+}
+
+func newRollbackResult(seq uint64, result *visor.RollbackResult) RollbackResult {
+	r := RollbackResult{
+		HeadSeq:              seq,
+		RemovedBlocks:        make([]uint64, len(result.Blocks)),
+		ReturnedTransactions: make([]string, len(result.Returned)),
+		DroppedTransactions:  make([]string, len(result.Dropped)),
+	}
+
+	for i, b := range result.Blocks {
+		r.RemovedBlocks[i] = b.Seq()
+	}
+	for i, h := range result.Returned {
+		r.ReturnedTransactions[i] = h.Hex()
+	}
+	for i, h := range result.Dropped {
+		r.DroppedTransactions[i] = h.Hex()
+	}
+
+	return r
+}
@@ -0,0 +1,144 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/spf13/cobra"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/visor"
+)
+
+func exportSnapshotCmd() *cobra.Command {
+	exportSnapshotCmd := &cobra.Command{
+		Short: "Export the unspent outputs of the blockchain to a snapshot file",
+		Use:   "exportSnapshot [snapshot file] [db path]",
+		Long: `Writes the unspent outputs of the blockchain at a block, with the signed block headers
+    that commit to them, to a snapshot file. A new node can be started from the snapshot with importSnapshot.
+    If no db path is specificed, the default data.db in $HOME/.$COIN/ will be used.
+    The database must not be pruned.`,
+		Args:         cobra.RangeArgs(1, 2),
+		SilenceUsage: true,
+		RunE:         exportSnapshot,
+	}
+
+	exportSnapshotCmd.Flags().Uint64("seq", 0, "Sequence of the snapshot block. Defaults to the head block.")
+
+	return exportSnapshotCmd
+}
+
+func exportSnapshot(c *cobra.Command, args []string) error {
+	seq, err := c.Flags().GetUint64("seq")
+	if err != nil {
+		return err
+	}
+
+	dbPath := ""
+	if len(args) > 1 {
+		dbPath = args[1]
+	}
+	dbPath, err = resolveDBPath(cliConfig, dbPath)
+	if err != nil {
+		return err
+	}
+
+	if _, err := os.Stat(dbPath); os.IsNotExist(err) {
+		return fmt.Errorf("db file: %v does not exist", dbPath)
+	}
+
+	db, err := bolt.Open(dbPath, 0600, &bolt.Options{
+		Timeout:  5 * time.Second,
+		ReadOnly: true,
+	})
+	if err != nil {
+		return fmt.Errorf("open db failed: %v", err)
+	}
+	defer db.Close()
+
+	s, err := visor.CreateSnapshot(wrapDB(db), seq)
+	if err != nil {
+		return fmt.Errorf("create snapshot failed: %v", err)
+	}
+
+	f, err := os.Create(args[0])
+	if err != nil {
+		return err
+	}
+
+	if err := visor.WriteSnapshot(f, s); err != nil {
+		f.Close()
+		return fmt.Errorf("write snapshot failed: %v", err)
+	}
+
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	fmt.Printf("exported snapshot at block %d with %d unspent outputs\n", s.Block.Seq(), len(s.Unspents))
+	return nil
+}
+
+func importSnapshotCmd() *cobra.Command {
+	return &cobra.Command{
+		Short: "Create a database from a snapshot file",
+		Use:   "importSnapshot [snapshot file] [db path]",
+		Long: `Verifies a snapshot file created with exportSnapshot against the blockchain pubkey
+    and creates a database from it. A node started with the database begins at the snapshot block,
+    and downloads the blocks before it from peers in the background.
+    If no db path is specificed, the default data.db in $HOME/.$COIN/ will be created.
+    The database must not exist.`,
+		Args:                  cobra.RangeArgs(1, 2),
+		DisableFlagsInUseLine: true,
+		SilenceUsage:          true,
+		RunE:                  importSnapshot,
+	}
+}
+
+func importSnapshot(_ *cobra.Command, args []string) error {
+	dbPath := ""
+	if len(args) > 1 {
+		dbPath = args[1]
+	}
+	dbPath, err := resolveDBPath(cliConfig, dbPath)
+	if err != nil {
+		return err
+	}
+
+	if _, err := os.Stat(dbPath); err == nil {
+		return fmt.Errorf("db file: %v already exists", dbPath)
+	}
+
+	pubkey, err := cipher.PubKeyFromHex(blockchainPubkey)
+	if err != nil {
+		return fmt.Errorf("decode blockchain pubkey failed: %v", err)
+	}
+
+	f, err := os.Open(args[0])
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	s, err := visor.ReadSnapshot(f)
+	if err != nil {
+		return fmt.Errorf("read snapshot failed: %v", err)
+	}
+
+	db, err := bolt.Open(dbPath, 0600, &bolt.Options{
+		Timeout: 5 * time.Second,
+	})
+	if err != nil {
+		return fmt.Errorf("open db failed: %v", err)
+	}
+	defer db.Close()
+
+	if err := visor.ImportSnapshot(wrapDB(db), pubkey, s); err != nil {
+		return fmt.Errorf("import snapshot failed: %v", err)
+	}
+
+	fmt.Printf("imported snapshot at block %d\n", s.Block.Seq())
+	return nil
+}
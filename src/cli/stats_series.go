@@ -0,0 +1,129 @@
+package cli
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/skycoin/skycoin/src/api"
+)
+
+func statsSeriesCmd() *cobra.Command {
+	statsSeriesCmd := &cobra.Command{
+		Short: "Show the time series of a chain statistic",
+		Use:   "statsSeries [flags]",
+		Long: `Show the time series of a chain statistic, per block or per UTC day.
+    The metric is one of coin_supply, coin_hour_supply, transactions, fees_burned or active_addresses.
+    The coin supply is in coins. A block interval series has at most 10000 points. The supplies of a day are the supplies after its last block,
+    the other metrics of a day are the totals of its blocks.`,
+		Args:                  cobra.NoArgs,
+		DisableFlagsInUseLine: true,
+		SilenceUsage:          true,
+		RunE:                  statsSeriesHandler,
+	}
+
+	statsSeriesCmd.Flags().StringP("metric", "m", "", "Metric of the series: coin_supply, coin_hour_supply, transactions, fees_burned or active_addresses")
+	statsSeriesCmd.Flags().StringP("interval", "i", "day", "Interval of the series points: block or day")
+	statsSeriesCmd.Flags().String("start-time", "", "Only show points at or after this time, as a unix timestamp or in RFC3339 format")
+	statsSeriesCmd.Flags().String("end-time", "", "Only show points at or before this time, as a unix timestamp or in RFC3339 format")
+	statsSeriesCmd.Flags().Bool("csv", false, "Print the points as CSV with the columns time, seq and value")
+
+	return statsSeriesCmd
+}
+
+func statsSeriesHandler(c *cobra.Command, _ []string) error {
+	metric, err := c.Flags().GetString("metric")
+	if err != nil {
+		return err
+	}
+
+	if metric == "" {
+		return errors.New("metric is required")
+	}
+
+	interval, err := c.Flags().GetString("interval")
+	if err != nil {
+		return err
+	}
+
+	asCSV, err := c.Flags().GetBool("csv")
+	if err != nil {
+		return err
+	}
+
+	params := api.StatsSeriesParams{
+		Metric:   strings.ToLower(metric),
+		Interval: strings.ToLower(interval),
+	}
+
+	startTime, err := parseHistoryTimeFlag(c, "start-time")
+	if err != nil {
+		return err
+	}
+
+	endTime, err := parseHistoryTimeFlag(c, "end-time")
+	if err != nil {
+		return err
+	}
+
+	if startTime != nil {
+		params.StartTime, err = statsSeriesUnixTime("start-time", *startTime)
+		if err != nil {
+			return err
+		}
+	}
+
+	if endTime != nil {
+		params.EndTime, err = statsSeriesUnixTime("end-time", *endTime)
+		if err != nil {
+			return err
+		}
+	}
+
+	if startTime != nil && endTime != nil && startTime.After(*endTime) {
+		return errors.New("start-time must not be after end-time")
+	}
+
+	series, err := apiClient.StatsSeries(params)
+	if err != nil {
+		return err
+	}
+
+	if !asCSV {
+		return printJSON(series)
+	}
+
+	w := csv.NewWriter(os.Stdout)
+	if err := w.Write([]string{"time", "seq", "value"}); err != nil {
+		return err
+	}
+
+	for _, p := range series.Points {
+		if err := w.Write([]string{
+			strconv.FormatUint(p.Time, 10),
+			strconv.FormatUint(p.Seq, 10),
+			p.Value,
+		}); err != nil {
+			return err
+		}
+	}
+
+	w.Flush()
+	return w.Error()
+}
+
+// statsSeriesUnixTime returns the unix time of t, which must not be before the unix epoch
+func statsSeriesUnixTime(name string, t time.Time) (uint64, error) {
+	n := t.Unix()
+	if n < 0 {
+		return 0, fmt.Errorf("invalid %s, must not be before 1970-01-01T00:00:00Z", name)
+	}
+
+	return uint64(n), nil
+}
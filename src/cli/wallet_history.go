@@ -3,6 +3,7 @@ package cli
 import (
 	"errors"
 	"fmt"
+	"strconv"
 
 	"time"
 
@@ -52,6 +53,8 @@ func walletHisCmd() *cobra.Command {
 	}
 
 	walletHisCmd.Flags().StringP("wallet-file", "f", "", "wallet file or path. If no path is specified your default wallet path will be used.")
+	walletHisCmd.Flags().String("start-time", "", "Only show history at or after this time, as a unix timestamp or in RFC3339 format")
+	walletHisCmd.Flags().String("end-time", "", "Only show history at or before this time, as a unix timestamp or in RFC3339 format")
 
 	return walletHisCmd
 }
@@ -62,6 +65,20 @@ func walletHistoryAction(c *cobra.Command, _ []string) error {
 		return err
 	}
 
+	startTime, err := parseHistoryTimeFlag(c, "start-time")
+	if err != nil {
+		return err
+	}
+
+	endTime, err := parseHistoryTimeFlag(c, "end-time")
+	if err != nil {
+		return err
+	}
+
+	if startTime != nil && endTime != nil && startTime.After(*endTime) {
+		return errors.New("start-time must not be after end-time")
+	}
+
 	w, err := resolveWalletPath(cliConfig, walletFile)
 	if err != nil {
 		return err
@@ -92,12 +109,53 @@ func walletHistoryAction(c *cobra.Command, _ []string) error {
 		totalAddrHis = append(totalAddrHis, addrHis...)
 	}
 
+	// Filter the history by time
+	if startTime != nil || endTime != nil {
+		filtered := []AddrHistory{}
+		for _, his := range totalAddrHis {
+			if startTime != nil && his.Timestamp.Before(*startTime) {
+				continue
+			}
+			if endTime != nil && his.Timestamp.After(*endTime) {
+				continue
+			}
+			filtered = append(filtered, his)
+		}
+		totalAddrHis = filtered
+	}
+
 	// Sort the uxouts by time ascending
 	sort.Sort(byTime(totalAddrHis))
 
 	return printJSON(totalAddrHis)
 }
 
+// parseHistoryTimeFlag parses a time flag given as a unix timestamp or in RFC3339 format.
+// Returns nil if the flag is not set.
+func parseHistoryTimeFlag(c *cobra.Command, name string) (*time.Time, error) {
+	v, err := c.Flags().GetString(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if v == "" {
+		return nil, nil
+	}
+
+	if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+		t := time.Unix(n, 0).UTC()
+		return &t, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s %q, must be a unix timestamp or in RFC3339 format", name, v)
+	}
+
+	t = t.UTC()
+	return &t, nil
+}
+
 func makeAddrHisArray(c *api.Client, addr string, uxOuts []readable.SpentOutput) ([]AddrHistory, error) {
 	if len(uxOuts) == 0 {
 		return nil, nil
@@ -0,0 +1,152 @@
+package daemon
+
+import (
+	"encoding/binary"
+	"errors"
+	"time"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/coin"
+)
+
+const (
+	// maxPendingCompactBlocks is the maximum number of compact blocks waiting for transactions
+	maxPendingCompactBlocks = 8
+)
+
+var (
+	// ErrCompactBlockNotPending block transactions were received for a compact block that is not waiting for them
+	ErrCompactBlockNotPending = errors.New("Block transactions do not answer a pending compact block")
+	// ErrCompactBlockTxnsMismatch the number of received block transactions does not match the number requested
+	ErrCompactBlockTxnsMismatch = errors.New("Received block transactions do not match the requested transactions")
+	// ErrCompactBlockBodyHashMismatch the transactions of a rebuilt compact block do not match the block header
+	ErrCompactBlockBodyHashMismatch = errors.New("Rebuilt compact block body hash does not match the block header")
+)
+
+// compactShortID returns the short ID of a transaction in a compact block.
+// The ID is salted with the block header hash, so that transactions with colliding short IDs
+// can't be created before the block is published.
+func compactShortID(blockHash, txnHash cipher.SHA256) uint64 {
+	h := cipher.AddSHA256(blockHash, txnHash)
+	return binary.LittleEndian.Uint64(h[:8])
+}
+
+// rebuildCompactBlock fills in the transactions of a compact block from the unconfirmed transactions.
+// Returns the block and the indexes of the transactions that were not found.
+// Unconfirmed transactions with colliding short IDs are treated as not found.
+func rebuildCompactBlock(m *CompactBlockMessage, unconfirmed []coin.Transaction) (coin.SignedBlock, []uint16) {
+	blockHash := m.Head.Hash()
+
+	// Index of the unconfirmed transaction with a short ID, or -1 if the short ID is ambiguous
+	byShortID := make(map[uint64]int, len(unconfirmed))
+	for i, txn := range unconfirmed {
+		id := compactShortID(blockHash, txn.Hash())
+		if _, ok := byShortID[id]; ok {
+			byShortID[id] = -1
+			continue
+		}
+		byShortID[id] = i
+	}
+
+	sb := coin.SignedBlock{
+		Block: coin.Block{
+			Head: m.Head,
+			Body: coin.BlockBody{
+				Transactions: make(coin.Transactions, len(m.ShortIDs)),
+			},
+		},
+		Sig: m.Sig,
+	}
+
+	var missing []uint16
+	for i, id := range m.ShortIDs {
+		j, ok := byShortID[id]
+		if !ok || j < 0 {
+			missing = append(missing, uint16(i))
+			continue
+		}
+		sb.Body.Transactions[i] = unconfirmed[j]
+	}
+
+	return sb, missing
+}
+
+// verifyCompactBlock checks that the transactions of a rebuilt compact block match its header
+func verifyCompactBlock(sb coin.SignedBlock) error {
+	if sb.Body.Hash() != sb.Head.BodyHash {
+		return ErrCompactBlockBodyHashMismatch
+	}
+	return nil
+}
+
+// pendingCompactBlock is a compact block waiting for the transactions requested from the peer that sent it
+type pendingCompactBlock struct {
+	Block       coin.SignedBlock
+	Missing     []uint16
+	Addr        string
+	GnetID      uint64
+	RequestedAt time.Time
+}
+
+// compactBlocks tracks the compact blocks waiting for transactions.
+// A block waits for transactions from one peer at a time. If the peer does not answer within the timeout,
+// the block can be requested from another peer; the block sync downloads it in full otherwise.
+// It is only accessed from the daemon run loop.
+type compactBlocks struct {
+	timeout time.Duration
+	pending map[cipher.SHA256]*pendingCompactBlock
+}
+
+func newCompactBlocks(timeout time.Duration) *compactBlocks {
+	return &compactBlocks{
+		timeout: timeout,
+		pending: make(map[cipher.SHA256]*pendingCompactBlock),
+	}
+}
+
+// add records a compact block waiting for transactions, and forgets the expired blocks and the blocks before it.
+// Returns false if the block is already waiting for transactions or too many blocks are waiting.
+func (cb *compactBlocks) add(p *pendingCompactBlock) bool {
+	for h, q := range cb.pending {
+		if q.Block.Seq() < p.Block.Seq() || p.RequestedAt.Sub(q.RequestedAt) >= cb.timeout {
+			delete(cb.pending, h)
+		}
+	}
+
+	h := p.Block.HashHeader()
+	if _, ok := cb.pending[h]; ok {
+		return false
+	}
+
+	if len(cb.pending) >= maxPendingCompactBlocks {
+		return false
+	}
+
+	cb.pending[h] = p
+	return true
+}
+
+// complete fills in the transactions received for a compact block and returns the block.
+// The block is no longer waiting for transactions, whether or not the transactions match.
+func (cb *compactBlocks) complete(addr string, gnetID uint64, blockHash cipher.SHA256, txns []coin.Transaction) (coin.SignedBlock, error) {
+	p, ok := cb.pending[blockHash]
+	if !ok || p.Addr != addr || p.GnetID != gnetID {
+		return coin.SignedBlock{}, ErrCompactBlockNotPending
+	}
+
+	delete(cb.pending, blockHash)
+
+	if len(txns) != len(p.Missing) {
+		return coin.SignedBlock{}, ErrCompactBlockTxnsMismatch
+	}
+
+	for i, j := range p.Missing {
+		p.Block.Body.Transactions[j] = txns[i]
+	}
+
+	if err := verifyCompactBlock(p.Block); err != nil {
+		return coin.SignedBlock{}, err
+	}
+
+	return p.Block, nil
+}
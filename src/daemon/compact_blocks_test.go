@@ -0,0 +1,279 @@
+package daemon
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/coin"
+)
+
+func makeCompactBlockTestTxns(n int) []coin.Transaction {
+	txns := make([]coin.Transaction, n)
+	for i := range txns {
+		txns[i] = coin.Transaction{
+			InnerHash: cipher.SumSHA256([]byte(fmt.Sprintf("inner %d", i))),
+			Sigs:      make([]cipher.Sig, 1),
+			In:        []cipher.SHA256{cipher.SumSHA256([]byte(fmt.Sprintf("in %d", i)))},
+			Out:       make([]coin.TransactionOutput, 2),
+		}
+	}
+	return txns
+}
+
+func makeCompactBlockTestBlock(seq uint64, txns []coin.Transaction) coin.SignedBlock {
+	body := coin.BlockBody{
+		Transactions: txns,
+	}
+	return coin.SignedBlock{
+		Block: coin.Block{
+			Head: coin.BlockHeader{
+				Version:  1,
+				Time:     1540000000 + seq,
+				BkSeq:    seq,
+				PrevHash: cipher.SumSHA256([]byte(fmt.Sprintf("prev %d", seq))),
+				BodyHash: body.Hash(),
+			},
+			Body: body,
+		},
+	}
+}
+
+// compactBlockRelaySize returns the bytes sent to relay a block as a CompactBlockMessage,
+// including the follow-up request for the transactions missing from unconfirmed
+func compactBlockRelaySize(sb coin.SignedBlock, unconfirmed []coin.Transaction, maxMsgLength uint64) uint64 {
+	m := NewCompactBlockMessage(sb)
+	size := encodeSizeCompactBlockMessage(m)
+
+	_, missing := rebuildCompactBlock(m, unconfirmed)
+	if len(missing) == 0 {
+		return size
+	}
+
+	missingTxns := make([]coin.Transaction, len(missing))
+	for i, k := range missing {
+		missingTxns[i] = sb.Body.Transactions[k]
+	}
+
+	blockHash := sb.HashHeader()
+	size += encodeSizeGetBlockTxnsMessage(NewGetBlockTxnsMessage(blockHash, missing))
+	size += encodeSizeGiveBlockTxnsMessage(NewGiveBlockTxnsMessage(blockHash, missingTxns, maxMsgLength))
+	return size
+}
+
+func TestNewCompactBlockMessage(t *testing.T) {
+	txns := makeCompactBlockTestTxns(3)
+	sb := makeCompactBlockTestBlock(10, txns)
+
+	m := NewCompactBlockMessage(sb)
+	require.Equal(t, sb.Head, m.Head)
+	require.Equal(t, sb.Sig, m.Sig)
+	require.Len(t, m.ShortIDs, 3)
+
+	blockHash := sb.HashHeader()
+	for i, txn := range txns {
+		require.Equal(t, compactShortID(blockHash, txn.Hash()), m.ShortIDs[i])
+	}
+
+	// Short IDs depend on the block
+	sb2 := makeCompactBlockTestBlock(11, txns)
+	m2 := NewCompactBlockMessage(sb2)
+	require.NotEqual(t, m.ShortIDs, m2.ShortIDs)
+}
+
+func TestRebuildCompactBlock(t *testing.T) {
+	txns := makeCompactBlockTestTxns(4)
+	sb := makeCompactBlockTestBlock(10, txns)
+	m := NewCompactBlockMessage(sb)
+
+	other := makeCompactBlockTestTxns(8)[4:]
+
+	cases := []struct {
+		name        string
+		unconfirmed []coin.Transaction
+		missing     []uint16
+	}{
+		{
+			name:        "all transactions known",
+			unconfirmed: append([]coin.Transaction{other[0], txns[3], txns[1]}, append(other[1:], txns[0], txns[2])...),
+		},
+		{
+			name:        "some transactions missing",
+			unconfirmed: []coin.Transaction{txns[2], other[0], txns[0]},
+			missing:     []uint16{1, 3},
+		},
+		{
+			name:    "empty pool",
+			missing: []uint16{0, 1, 2, 3},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			rebuilt, missing := rebuildCompactBlock(m, tc.unconfirmed)
+			require.Equal(t, tc.missing, missing)
+			require.Equal(t, sb.Head, rebuilt.Head)
+			require.Equal(t, sb.Sig, rebuilt.Sig)
+			require.Len(t, rebuilt.Body.Transactions, len(txns))
+
+			for i, txn := range rebuilt.Body.Transactions {
+				if len(missing) != 0 && missing[0] == uint16(i) {
+					missing = missing[1:]
+					require.Equal(t, coin.Transaction{}, txn)
+					continue
+				}
+				require.Equal(t, txns[i], txn)
+			}
+
+			if len(tc.missing) == 0 {
+				require.NoError(t, verifyCompactBlock(rebuilt))
+			}
+		})
+	}
+}
+
+func TestRebuildCompactBlockCollision(t *testing.T) {
+	txns := makeCompactBlockTestTxns(2)
+	sb := makeCompactBlockTestBlock(10, txns)
+	m := NewCompactBlockMessage(sb)
+
+	// Unconfirmed transactions with the same short ID are ambiguous
+	_, missing := rebuildCompactBlock(m, []coin.Transaction{txns[0], txns[1], txns[0]})
+	require.Equal(t, []uint16{0}, missing)
+
+	// A short ID that matches the wrong transaction is detected by the body hash
+	m.ShortIDs[1] = m.ShortIDs[0]
+	rebuilt, missing := rebuildCompactBlock(m, txns[:1])
+	require.Empty(t, missing)
+	require.Equal(t, ErrCompactBlockBodyHashMismatch, verifyCompactBlock(rebuilt))
+}
+
+func TestCompactBlocks(t *testing.T) {
+	txns := makeCompactBlockTestTxns(4)
+	sb := makeCompactBlockTestBlock(10, txns)
+	m := NewCompactBlockMessage(sb)
+	blockHash := sb.HashHeader()
+
+	now := time.Now().UTC()
+	cb := newCompactBlocks(time.Second * 20)
+
+	rebuilt, missing := rebuildCompactBlock(m, []coin.Transaction{txns[0], txns[2]})
+	require.Equal(t, []uint16{1, 3}, missing)
+
+	p := &pendingCompactBlock{
+		Block:       rebuilt,
+		Missing:     missing,
+		Addr:        "1.2.3.4:6000",
+		GnetID:      1,
+		RequestedAt: now,
+	}
+	require.True(t, cb.add(p))
+
+	// The block waits for transactions from one peer at a time
+	require.False(t, cb.add(&pendingCompactBlock{
+		Block:       rebuilt,
+		Missing:     missing,
+		Addr:        "5.6.7.8:6000",
+		GnetID:      2,
+		RequestedAt: now.Add(time.Second),
+	}))
+
+	// Transactions from another peer are ignored
+	_, err := cb.complete("5.6.7.8:6000", 2, blockHash, []coin.Transaction{txns[1], txns[3]})
+	require.Equal(t, ErrCompactBlockNotPending, err)
+
+	completed, err := cb.complete("1.2.3.4:6000", 1, blockHash, []coin.Transaction{txns[1], txns[3]})
+	require.NoError(t, err)
+	require.Equal(t, sb, completed)
+	require.Empty(t, cb.pending)
+
+	_, err = cb.complete("1.2.3.4:6000", 1, blockHash, []coin.Transaction{txns[1], txns[3]})
+	require.Equal(t, ErrCompactBlockNotPending, err)
+
+	// The wrong number of transactions
+	rebuilt, missing = rebuildCompactBlock(m, []coin.Transaction{txns[0], txns[2]})
+	p.Block = rebuilt
+	p.Missing = missing
+	require.True(t, cb.add(p))
+	_, err = cb.complete("1.2.3.4:6000", 1, blockHash, txns[1:2])
+	require.Equal(t, ErrCompactBlockTxnsMismatch, err)
+	require.Empty(t, cb.pending)
+
+	// The wrong transactions
+	rebuilt, missing = rebuildCompactBlock(m, []coin.Transaction{txns[0], txns[2]})
+	p.Block = rebuilt
+	p.Missing = missing
+	require.True(t, cb.add(p))
+	_, err = cb.complete("1.2.3.4:6000", 1, blockHash, []coin.Transaction{txns[3], txns[1]})
+	require.Equal(t, ErrCompactBlockBodyHashMismatch, err)
+	require.Empty(t, cb.pending)
+
+	// Expired blocks and blocks before the added block are forgotten
+	rebuilt, missing = rebuildCompactBlock(m, nil)
+	require.True(t, cb.add(&pendingCompactBlock{
+		Block:       rebuilt,
+		Missing:     missing,
+		Addr:        "1.2.3.4:6000",
+		GnetID:      1,
+		RequestedAt: now,
+	}))
+	require.True(t, cb.add(&pendingCompactBlock{
+		Block:       rebuilt,
+		Missing:     missing,
+		Addr:        "5.6.7.8:6000",
+		GnetID:      2,
+		RequestedAt: now.Add(time.Second * 20),
+	}))
+	require.Equal(t, "5.6.7.8:6000", cb.pending[blockHash].Addr)
+
+	sb2 := makeCompactBlockTestBlock(11, txns)
+	rebuilt2, missing2 := rebuildCompactBlock(NewCompactBlockMessage(sb2), nil)
+	require.True(t, cb.add(&pendingCompactBlock{
+		Block:       rebuilt2,
+		Missing:     missing2,
+		Addr:        "5.6.7.8:6000",
+		GnetID:      2,
+		RequestedAt: now.Add(time.Second * 21),
+	}))
+	require.Len(t, cb.pending, 1)
+	require.NotNil(t, cb.pending[sb2.HashHeader()])
+
+	// The number of pending blocks is limited
+	cb = newCompactBlocks(time.Second * 20)
+	for i := 0; i < maxPendingCompactBlocks; i++ {
+		sb := makeCompactBlockTestBlock(12, txns[i%len(txns):])
+		sb.Head.Time += uint64(i)
+		rebuilt, missing := rebuildCompactBlock(NewCompactBlockMessage(sb), nil)
+		require.True(t, cb.add(&pendingCompactBlock{
+			Block:       rebuilt,
+			Missing:     missing,
+			RequestedAt: now,
+		}))
+	}
+	sb3 := makeCompactBlockTestBlock(12, txns[:1])
+	rebuilt3, missing3 := rebuildCompactBlock(NewCompactBlockMessage(sb3), nil)
+	require.False(t, cb.add(&pendingCompactBlock{
+		Block:       rebuilt3,
+		Missing:     missing3,
+		RequestedAt: now,
+	}))
+}
+
+func TestCompactBlockRelaySize(t *testing.T) {
+	txns := makeCompactBlockTestTxns(200)
+	sb := makeCompactBlockTestBlock(10, txns)
+
+	maxMsgLength := NewDaemonConfig().MaxOutgoingMessageLength
+	fullSize := encodeSizeGiveBlocksMessage(NewGiveBlocksMessage([]coin.SignedBlock{sb}, maxMsgLength))
+
+	// The compact encoding is smaller than the full block while most transactions are known to the receiver
+	for _, known := range []int{100, 90, 50} {
+		t.Run(fmt.Sprintf("known-%d%%", known), func(t *testing.T) {
+			compactSize := compactBlockRelaySize(sb, txns[:len(txns)*known/100], maxMsgLength)
+			require.True(t, compactSize < fullSize, "compact size %d is not smaller than full size %d", compactSize, fullSize)
+		})
+	}
+}
@@ -2,7 +2,6 @@ package daemon
 
 import (
 	"errors"
-	"fmt"
 	"sync"
 	"time"
 
@@ -63,6 +62,10 @@ type ConnectionDetails struct {
 	Height               uint64
 	UserAgent            useragent.Data
 	UnconfirmedVerifyTxn params.VerifyTxn
+	Encrypted            bool
+	Capabilities         uint32
+	// Feeler is true for short-lived outgoing connections that check whether a peer is reachable
+	Feeler bool
 }
 
 // HasIntroduced returns true if the connection has introduced
@@ -75,6 +78,11 @@ func (c ConnectionDetails) HasIntroduced() bool {
 	}
 }
 
+// HasCapability returns true if the connection has introduced and announced the capability
+func (c ConnectionDetails) HasCapability(capability uint32) bool {
+	return c.HasIntroduced() && c.Capabilities&capability != 0
+}
+
 type connection struct {
 	Addr string
 	ConnectionDetails
@@ -93,7 +101,7 @@ func (c *connection) ListenAddr() string {
 		return ""
 	}
 
-	return fmt.Sprintf("%s:%d", ip, c.ListenPort)
+	return iputil.JoinAddr(ip, c.ListenPort)
 }
 
 // Connections manages a collection of Connection
@@ -119,6 +127,15 @@ func NewConnections() *Connections {
 
 // pending adds a new pending outgoing connection
 func (c *Connections) pending(addr string) (*connection, error) {
+	return c.addPending(addr, false)
+}
+
+// pendingFeeler adds a new pending outgoing feeler connection
+func (c *Connections) pendingFeeler(addr string) (*connection, error) {
+	return c.addPending(addr, true)
+}
+
+func (c *Connections) addPending(addr string, feeler bool) (*connection, error) {
 	c.Lock()
 	defer c.Unlock()
 
@@ -140,6 +157,7 @@ func (c *Connections) pending(addr string) (*connection, error) {
 			State:      ConnectionStatePending,
 			Outgoing:   true,
 			ListenPort: port,
+			Feeler:     feeler,
 		},
 	}
 
@@ -297,6 +315,8 @@ func (c *Connections) introduced(addr string, gnetID uint64, m *IntroductionMess
 	conn.ListenPort = listenPort
 	conn.UserAgent = m.userAgent
 	conn.UnconfirmedVerifyTxn = m.unconfirmedVerifyTxn
+	conn.Encrypted = m.encrypted
+	conn.Capabilities = m.capabilities
 
 	if !conn.Outgoing {
 		listenAddr := conn.ListenAddr()
@@ -441,13 +461,26 @@ func (c *Connections) Len() int {
 	return len(c.conns)
 }
 
-// OutgoingLen returns number of outgoing connections
+// OutgoingLen returns number of outgoing connections, not including feeler connections
 func (c *Connections) OutgoingLen() int {
 	c.Lock()
 	defer c.Unlock()
 	n := 0
 	for _, conn := range c.conns {
-		if conn.Outgoing {
+		if conn.Outgoing && !conn.Feeler {
+			n++
+		}
+	}
+	return n
+}
+
+// FeelerLen returns the number of feeler connections
+func (c *Connections) FeelerLen() int {
+	c.Lock()
+	defer c.Unlock()
+	n := 0
+	for _, conn := range c.conns {
+		if conn.Feeler {
 			n++
 		}
 	}
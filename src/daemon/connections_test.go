@@ -511,3 +511,28 @@ func TestConnectionsStateTransitionErrors(t *testing.T) {
 	_, err = conns.introduced(addr, 1, &IntroductionMessage{})
 	require.Equal(t, ErrConnectionAlreadyIntroduced, err)
 }
+
+func TestConnectionsFeeler(t *testing.T) {
+	conns := NewConnections()
+
+	c, err := conns.pendingFeeler("112.32.32.14:6060")
+	require.NoError(t, err)
+	require.True(t, c.Outgoing)
+	require.True(t, c.Feeler)
+
+	_, err = conns.pending("112.32.32.15:6060")
+	require.NoError(t, err)
+
+	// Feeler connections do not count as outgoing connections
+	require.Equal(t, 1, conns.OutgoingLen())
+	require.Equal(t, 1, conns.FeelerLen())
+	require.Equal(t, 2, conns.PendingLen())
+	require.Equal(t, 2, conns.Len())
+
+	_, err = conns.pendingFeeler("112.32.32.14:6060")
+	require.Equal(t, ErrConnectionExists, err)
+
+	require.NoError(t, conns.remove("112.32.32.14:6060", 0))
+	require.Equal(t, 0, conns.FeelerLen())
+	require.Equal(t, 1, conns.OutgoingLen())
+}
@@ -54,6 +54,9 @@ func IsBroadcastFailure(err error) bool {
 
 const (
 	daemonRunDurationThreshold = time.Millisecond * 200
+
+	// givePeersV2ProtocolVersion is the first protocol version that supports GivePeersV2Message
+	givePeersV2ProtocolVersion int32 = 3
 )
 
 // Config subsystem configurations
@@ -93,6 +96,12 @@ func (cfg *Config) preprocess() (Config, error) {
 	}
 	config.Pool.port = config.Daemon.Port
 	config.Pool.address = config.Daemon.Address
+	config.Pool.encryptConnections = config.Daemon.EncryptConnections
+	config.Pool.proxy = config.Daemon.Proxy
+	config.Pool.proxyIsolateAuth = config.Daemon.ProxyIsolateAuth
+	config.Pex.Proxy = config.Daemon.Proxy
+	config.Pex.ProxyIsolateAuth = config.Daemon.ProxyIsolateAuth
+	config.Pex.OnlyNetworks = config.Daemon.OnlyNetworks
 
 	if config.Daemon.DisableNetworking {
 		logger.Info("Networking is disabled")
@@ -118,6 +127,10 @@ func (cfg *Config) preprocess() (Config, error) {
 
 	config.Pool.MaxConnections = config.Daemon.MaxConnections
 	config.Pool.MaxOutgoingConnections = config.Daemon.MaxOutgoingConnections
+	// Reserve a pool slot for feeler connections, which are made when all outgoing connections are established
+	if config.Daemon.FeelerRate > 0 && config.Pool.MaxConnections > config.Pool.MaxOutgoingConnections+config.Pool.MaxDefaultPeerOutgoingConnections {
+		config.Pool.MaxOutgoingConnections++
+	}
 	config.Pool.MaxIncomingMessageLength = int(config.Daemon.MaxIncomingMessageLength)
 	config.Pool.MaxOutgoingMessageLength = int(config.Daemon.MaxOutgoingMessageLength)
 
@@ -185,6 +198,15 @@ type DaemonConfig struct { // nolint: golint
 	FlushAnnouncedTxnsRate time.Duration
 	// How many connections are allowed from the same base IP
 	IPCountsMax int
+	// Maximum number of outgoing connections to peers in the same IPv4 /16 or IPv6 /32 network.
+	// Connections to trusted and private peers are not limited. If zero, there is no limit
+	MaxOutgoingPerNetGroup int
+	// Maximum number of outgoing connections to peers in the same autonomous system, according to the pex ASMap.
+	// Connections to trusted and private peers are not limited. If zero, there is no limit
+	MaxOutgoingPerASN int
+	// How often to make a short-lived feeler connection to a peer that we have not connected to,
+	// to check that it is reachable before moving it to the tried table. If zero, no feeler connections are made
+	FeelerRate time.Duration
 	// Disable all networking activity
 	DisableNetworking bool
 	// Don't make outgoing connections
@@ -195,7 +217,7 @@ type DaemonConfig struct { // nolint: golint
 	LocalhostOnly bool
 	// Log ping and pong messages
 	LogPings bool
-	// How often to request blocks from peers
+	// How often to reassign timed out block requests and request more blocks from peers
 	BlocksRequestRate time.Duration
 	// How often to announce our blocks to peers
 	BlocksAnnounceRate time.Duration
@@ -203,8 +225,50 @@ type DaemonConfig struct { // nolint: golint
 	GetBlocksRequestCount uint64
 	// Maximum number of blocks to respond with to a GetBlocksMessage
 	MaxGetBlocksResponseCount uint64
+	// Maximum number of block requests that a peer can have in flight
+	SyncMaxRequestsPerPeer int
+	// Maximum number of blocks ahead of the head block to request and buffer
+	SyncMaxBufferedBlocks uint64
+	// How long to wait for a response to a block request before requesting the blocks from another peer
+	SyncRequestTimeout time.Duration
+	// Misbehaviour score at which a peer is banned
+	BanScoreThreshold int
+	// How long a misbehaving peer is banned for
+	BanDuration time.Duration
+	// How often a peer's misbehaviour score decreases by one point
+	BanScoreDecayInterval time.Duration
+	// Encrypt connections with peers that support connection encryption
+	EncryptConnections bool
+	// When EncryptConnections is set, still connect to peers that don't support connection encryption
+	AllowUnencryptedPeers bool
+	// Don't send or accept compact blocks, always relay full blocks
+	DisableCompactBlocks bool
+	// The node discards old block bodies, announce capabilityPruned to peers
+	Pruned bool
+	// Address of a SOCKS5 proxy to make outgoing peer connections and download the peers list through, host:port
+	Proxy string
+	// Authenticate each proxied connection with random credentials, so that a proxy
+	// which isolates streams by credentials, such as Tor, uses a separate circuit for each connection
+	ProxyIsolateAuth bool
+	// Only make outgoing connections to peers in these networks, "ipv4" or "ipv6". Leave empty to allow all networks
+	OnlyNetworks []string
+	// Broadcast transactions created by this node to all peers, instead of relaying them through a single outgoing peer
+	DisableTxnStemRelay bool
+	// How long the same outgoing peer is used to relay transactions created by this node
+	TxnStemEpoch time.Duration
+	// How long to wait for a relayed transaction created by this node to be announced by another peer,
+	// before announcing it to all peers
+	TxnStemEmbargo time.Duration
+	// Mean delay before announcing transactions received from peers, drawn independently for each peer.
+	// If zero, transactions are announced immediately
+	TxnAnnounceDelay time.Duration
+	// How often to send due transaction announcements and check for expired transaction embargoes
+	TxnRelayRate time.Duration
 	// Max announce txns hash number
 	MaxTxnAnnounceNum int
+	// Max number of transaction and block hashes remembered per connection, to avoid announcing
+	// inventory to peers that already have it. If zero, inventory is not tracked
+	MaxPeerInventory int
 	// How often new blocks are created by the signing node, in seconds
 	BlockCreationInterval uint64
 	// How often to check the unconfirmed pool for transactions that become valid
@@ -231,7 +295,7 @@ type DaemonConfig struct { // nolint: golint
 // NewDaemonConfig creates daemon config
 func NewDaemonConfig() DaemonConfig {
 	return DaemonConfig{
-		ProtocolVersion:              2,
+		ProtocolVersion:              3,
 		MinProtocolVersion:           2,
 		Address:                      "",
 		Port:                         6677,
@@ -245,16 +309,35 @@ func NewDaemonConfig() DaemonConfig {
 		CullInvalidRate:              time.Second * 3,
 		FlushAnnouncedTxnsRate:       time.Second * 3,
 		IPCountsMax:                  3,
+		MaxOutgoingPerNetGroup:       1,
+		MaxOutgoingPerASN:            2,
+		FeelerRate:                   time.Minute * 2,
 		DisableNetworking:            false,
 		DisableOutgoingConnections:   false,
 		DisableIncomingConnections:   false,
 		LocalhostOnly:                false,
 		LogPings:                     true,
-		BlocksRequestRate:            time.Second * 60,
+		BlocksRequestRate:            time.Second,
 		BlocksAnnounceRate:           time.Second * 60,
 		GetBlocksRequestCount:        20,
 		MaxGetBlocksResponseCount:    20,
+		SyncMaxRequestsPerPeer:       4,
+		SyncMaxBufferedBlocks:        1024,
+		SyncRequestTimeout:           time.Second * 20,
+		BanScoreThreshold:            100,
+		BanDuration:                  time.Hour * 24,
+		BanScoreDecayInterval:        time.Minute,
+		EncryptConnections:           false,
+		AllowUnencryptedPeers:        false,
+		DisableCompactBlocks:         false,
+		Pruned:                       false,
+		DisableTxnStemRelay:          false,
+		TxnStemEpoch:                 time.Minute * 10,
+		TxnStemEmbargo:               time.Second * 30,
+		TxnAnnounceDelay:             time.Second * 2,
+		TxnRelayRate:                 time.Millisecond * 200,
 		MaxTxnAnnounceNum:            16,
+		MaxPeerInventory:             5000,
 		BlockCreationInterval:        10,
 		UnconfirmedRefreshRate:       time.Minute,
 		UnconfirmedRemoveInvalidRate: time.Minute,
@@ -266,6 +349,16 @@ func NewDaemonConfig() DaemonConfig {
 	}
 }
 
+// syncConfig returns the syncManager configuration
+func (c DaemonConfig) syncConfig() syncConfig {
+	return syncConfig{
+		RangeSize:          c.GetBlocksRequestCount,
+		MaxRequestsPerPeer: c.SyncMaxRequestsPerPeer,
+		MaxBufferedBlocks:  c.SyncMaxBufferedBlocks,
+		RequestTimeout:     c.SyncRequestTimeout,
+	}
+}
+
 //go:generate mockery -name daemoner -case underscore -inpkg -testonly
 
 // daemoner Daemon interface
@@ -275,11 +368,13 @@ type daemoner interface {
 	sendMessage(addr string, msg gnet.Message) error
 	broadcastMessage(msg gnet.Message) ([]uint64, error)
 	disconnectNow(addr string, r gnet.DisconnectReason) error
-	addPeers(addrs []string) int
+	addPeers(addrs []string, source string) int
 	recordPeerHeight(addr string, gnetID, height uint64)
 	getSignedBlocksSince(seq, count uint64) ([]coin.SignedBlock, error)
 	headBkSeq() (uint64, bool, error)
-	executeSignedBlock(b coin.SignedBlock) error
+	receiveBlocks(addr string, gnetID uint64, blocks []coin.SignedBlock) (int, error)
+	receiveBackfillBlocks(addr string, gnetID uint64, blocks []coin.SignedBlock) (bool, error)
+	requestBlocks() error
 	filterKnownUnconfirmed(txns []cipher.SHA256) ([]cipher.SHA256, error)
 	getKnownUnconfirmed(txns []cipher.SHA256) (coin.Transactions, error)
 	requestBlocksFromAddr(addr string) error
@@ -289,6 +384,14 @@ type daemoner interface {
 	recordMessageEvent(m asyncMessage, c *gnet.MessageContext) error
 	connectionIntroduced(addr string, gnetID uint64, m *IntroductionMessage) (*connection, error)
 	sendRandomPeers(addr string) error
+	recordMisbehaviour(addr string, m misbehaviour)
+	relayHeadBlock() error
+	receiveCompactBlock(addr string, gnetID uint64, m *CompactBlockMessage) (*coin.SignedBlock, []uint16, error)
+	receiveBlockTxns(addr string, gnetID uint64, m *GiveBlockTxnsMessage) (*coin.SignedBlock, error)
+	getSignedBlockByHash(hash cipher.SHA256) (*coin.SignedBlock, error)
+	announceRelayedTxns(hashes []cipher.SHA256) error
+	endTxnStems(hashes []cipher.SHA256)
+	recordPeerInventory(addr string, gnetID uint64, hashes []cipher.SHA256)
 }
 
 // Daemon stateful properties of the daemon
@@ -306,6 +409,16 @@ type Daemon struct {
 	announcedTxns *announcedTxnsCache
 	// Cache of connection metadata
 	connections *Connections
+	// Parallel block download manager
+	blockSync *syncManager
+	// Misbehaviour scores of peer IPs
+	peerScores *peerScores
+	// Compact blocks waiting for transactions
+	compactBlocks *compactBlocks
+	// Stem relay and randomized announcements of transactions
+	txnRelay *txnRelay
+	// Transactions and blocks known by each connection
+	inventory *peerInventory
 	// connect, disconnect, message, error events channel
 	events chan interface{}
 	// quit channel
@@ -334,6 +447,11 @@ func New(config Config, v *visor.Visor) (*Daemon, error) {
 
 		announcedTxns: newAnnouncedTxnsCache(),
 		connections:   NewConnections(),
+		blockSync:     newSyncManager(config.Daemon.syncConfig()),
+		peerScores:    newPeerScores(config.Daemon.BanScoreDecayInterval),
+		compactBlocks: newCompactBlocks(config.Daemon.SyncRequestTimeout),
+		txnRelay:      newTxnRelay(config.Daemon.TxnAnnounceDelay, config.Daemon.TxnStemEmbargo, config.Daemon.TxnStemEpoch),
+		inventory:     newPeerInventory(config.Daemon.MaxPeerInventory),
 		events:        make(chan interface{}, config.Pool.EventChannelSize),
 		quit:          make(chan struct{}),
 		done:          make(chan struct{}),
@@ -476,6 +594,16 @@ func (dm *Daemon) Run() error {
 	flushAnnouncedTxnsTicker := time.NewTicker(dm.config.FlushAnnouncedTxnsRate)
 	defer flushAnnouncedTxnsTicker.Stop()
 
+	txnRelayTicker := time.NewTicker(dm.config.TxnRelayRate)
+	defer txnRelayTicker.Stop()
+
+	var feelerC <-chan time.Time
+	if dm.config.FeelerRate > 0 {
+		feelerTicker := time.NewTicker(dm.config.FeelerRate)
+		defer feelerTicker.Stop()
+		feelerC = feelerTicker.C
+	}
+
 	// Connect to all trusted peers on startup to try to ensure a connection establishes quickly.
 	// The number of connections to default peers is restricted;
 	// if multiple connections succeed, extra connections beyond the limit will be disconnected.
@@ -577,6 +705,11 @@ loop:
 			elapser.Register("outgoingConnectionsTicker")
 			dm.connectToRandomPeer()
 
+		case <-feelerC:
+			// Check that a peer we have not connected to is reachable
+			elapser.Register("feelerTicker")
+			dm.connectToFeeler()
+
 		case <-outgoingTrustedConnectionsTicker.C:
 			// Try to maintain at least one trusted connection
 			elapser.Register("outgoingTrustedConnectionsTicker")
@@ -619,6 +752,14 @@ loop:
 				logger.WithError(err).Error("Failed to set unconfirmed txn announce time")
 			}
 
+		case <-txnRelayTicker.C:
+			// Send randomly delayed transaction announcements and announce transactions whose stem embargo expired
+			elapser.Register("txnRelayTicker")
+			if !dm.config.DisableNetworking {
+				dm.sendTxnAnnouncements()
+				dm.announceExpiredTxnStems()
+			}
+
 		case <-blockCreationTicker.C:
 			// Create blocks, if block publisher
 			elapser.Register("blockCreationTicker.C")
@@ -668,6 +809,9 @@ loop:
 			if err := dm.requestBlocks(); err != nil {
 				logger.WithError(err).Warning("requestBlocks failed")
 			}
+			if err := dm.requestBackfillBlocks(); err != nil {
+				logger.WithError(err).Warning("requestBackfillBlocks failed")
+			}
 
 		case <-blocksAnnounceTicker.C:
 			elapser.Register("blocksAnnounceTicker")
@@ -694,6 +838,12 @@ loop:
 // made. If the connection attempt itself fails, the error is sent to
 // the connectionErrors channel.
 func (dm *Daemon) connectToPeer(p pex.Peer) error {
+	return dm.makeOutgoingConnection(p, false)
+}
+
+// makeOutgoingConnection connects to a given peer, like connectToPeer.
+// A feeler connection is disconnected once the peer has introduced itself.
+func (dm *Daemon) makeOutgoingConnection(p pex.Peer, feeler bool) error {
 	if dm.config.DisableOutgoingConnections {
 		return errors.New("Outgoing connections disabled")
 	}
@@ -708,6 +858,14 @@ func (dm *Daemon) connectToPeer(p pex.Peer) error {
 		return errors.New("Not localhost")
 	}
 
+	if dm.pex.IsBanned(a) {
+		return errors.New("Peer is banned")
+	}
+
+	if !dm.pex.AllowedNetwork(a) {
+		return errors.New("Peer is not in an allowed network")
+	}
+
 	if c := dm.connections.get(p.Addr); c != nil {
 		return errors.New("Already connected to this peer")
 	}
@@ -717,9 +875,23 @@ func (dm *Daemon) connectToPeer(p pex.Peer) error {
 		return errors.New("Already connected to a peer with this base IP")
 	}
 
-	logger.WithField("addr", p.Addr).Debug("Establishing outgoing connection")
+	if !dm.config.LocalhostOnly && !feeler && !p.Trusted && !p.Private {
+		if err := dm.checkOutgoingDiversity(a); err != nil {
+			return err
+		}
+	}
+
+	logger.WithFields(logrus.Fields{
+		"addr":   p.Addr,
+		"feeler": feeler,
+	}).Debug("Establishing outgoing connection")
+
+	pending := dm.connections.pending
+	if feeler {
+		pending = dm.connections.pendingFeeler
+	}
 
-	if _, err := dm.connections.pending(p.Addr); err != nil {
+	if _, err := pending(p.Addr); err != nil {
 		logger.Critical().WithError(err).WithField("addr", p.Addr).Error("dm.connections.pending failed")
 		return err
 	}
@@ -736,6 +908,47 @@ func (dm *Daemon) connectToPeer(p pex.Peer) error {
 	return nil
 }
 
+// checkOutgoingDiversity returns an error if there are already MaxOutgoingPerNetGroup outgoing connections
+// to peers in the network group of ip, or MaxOutgoingPerASN outgoing connections to peers in its autonomous system.
+// This prevents an attacker that controls a single network from occupying all of our outgoing connections.
+func (dm *Daemon) checkOutgoingDiversity(ip string) error {
+	group := iputil.NetGroup(ip)
+	asn := dm.pex.ASN(ip)
+	if group == "" && asn == "" {
+		return nil
+	}
+
+	groupCount := 0
+	asnCount := 0
+	for _, c := range dm.connections.all() {
+		if !c.Outgoing || c.Feeler {
+			continue
+		}
+
+		cip, _, err := iputil.SplitAddr(c.Addr)
+		if err != nil {
+			continue
+		}
+
+		if group != "" && iputil.NetGroup(cip) == group {
+			groupCount++
+		}
+		if asn != "" && dm.pex.ASN(cip) == asn {
+			asnCount++
+		}
+	}
+
+	if dm.config.MaxOutgoingPerNetGroup > 0 && groupCount >= dm.config.MaxOutgoingPerNetGroup {
+		return errors.New("Maximum outgoing connections to this network group reached")
+	}
+
+	if dm.config.MaxOutgoingPerASN > 0 && asnCount >= dm.config.MaxOutgoingPerASN {
+		return errors.New("Maximum outgoing connections to this autonomous system reached")
+	}
+
+	return nil
+}
+
 // Connects to all private peers
 func (dm *Daemon) makePrivateConnections() {
 	if dm.config.DisableOutgoingConnections {
@@ -829,6 +1042,37 @@ func (dm *Daemon) connectToRandomPeer() {
 	}
 }
 
+// connectToFeeler makes a feeler connection to a random peer that we have not connected to.
+// If the peer introduces itself, it is moved to the tried table and disconnected.
+// Feeler connections are only made once all outgoing connections are established,
+// since until then the outgoing connections themselves check which peers are reachable.
+func (dm *Daemon) connectToFeeler() {
+	if dm.config.DisableOutgoingConnections {
+		return
+	}
+	if dm.connections.OutgoingLen() < dm.config.MaxOutgoingConnections {
+		return
+	}
+	if dm.connections.FeelerLen() > 0 {
+		return
+	}
+	if dm.connections.PendingLen() >= dm.config.MaxPendingConnections {
+		return
+	}
+	if dm.connections.Len() >= dm.config.MaxConnections {
+		return
+	}
+
+	peers := dm.pex.RandomNew(1)
+	if len(peers) == 0 {
+		return
+	}
+
+	if err := dm.makeOutgoingConnection(peers[0], true); err != nil {
+		logger.WithError(err).WithField("addr", peers[0].Addr).Debug("Feeler connection failed")
+	}
+}
+
 // Removes connections who haven't sent a version after connecting
 func (dm *Daemon) cullInvalidConnections() {
 	now := time.Now().UTC()
@@ -949,6 +1193,14 @@ func (dm *Daemon) onConnectEvent(e ConnectEvent) {
 		logger.Critical().WithFields(fields).Warning("Connection.Outgoing does not match ConnectEvent.Solicited state")
 	}
 
+	if dm.pex.IsBanned(e.Addr) {
+		logger.WithFields(fields).Info("Peer is banned, disconnecting")
+		if err := dm.Disconnect(e.Addr, ErrDisconnectIsBlacklisted); err != nil {
+			logger.WithError(err).WithFields(fields).Error("Disconnect")
+		}
+		return
+	}
+
 	if dm.ipCountMaxed(e.Addr) {
 		logger.WithFields(fields).Info("Max connections for this IP address reached, disconnecting")
 		if err := dm.Disconnect(e.Addr, ErrDisconnectIPLimitReached); err != nil {
@@ -957,6 +1209,16 @@ func (dm *Daemon) onConnectEvent(e ConnectEvent) {
 		return
 	}
 
+	var encryptionPubKey cipher.PubKey
+	if dm.config.EncryptConnections {
+		gc, err := dm.pool.Pool.GetConnection(e.Addr)
+		if err != nil || gc == nil {
+			logger.WithError(err).WithFields(fields).Error("onConnectEvent: GetConnection failed")
+			return
+		}
+		encryptionPubKey, _ = gc.EncryptionPubKey()
+	}
+
 	logger.WithFields(fields).Debug("Sending introduction message")
 
 	if err := dm.sendMessage(e.Addr, NewIntroductionMessage(
@@ -966,6 +1228,8 @@ func (dm *Daemon) onConnectEvent(e ConnectEvent) {
 		dm.config.BlockchainPubkey,
 		dm.config.userAgent,
 		dm.config.UnconfirmedVerifyTxn,
+		dm.capabilities(),
+		encryptionPubKey,
 	)); err != nil {
 		logger.WithFields(fields).WithError(err).Error("Send IntroductionMessage failed")
 		return
@@ -985,7 +1249,20 @@ func (dm *Daemon) onDisconnectEvent(e DisconnectEvent) {
 		return
 	}
 
-	// TODO -- blacklist peer for certain reasons, not just remove
+	// Reassign the block requests sent to this peer
+	dm.blockSync.disconnected(e.Addr, e.GnetID)
+
+	// Forget the transaction announcements queued for this peer
+	dm.txnRelay.removePeer(e.Addr, e.GnetID)
+
+	// Forget the inventory known by this peer
+	dm.inventory.removePeer(e.Addr, e.GnetID)
+
+	// Peers that send data that can't be decoded are scored, and banned if they keep doing so
+	if m, ok := disconnectMisbehaviour(e.Reason); ok {
+		dm.recordMisbehaviour(e.Addr, m)
+	}
+
 	switch e.Reason {
 	case ErrDisconnectIntroductionTimeout,
 		ErrDisconnectBlockchainPubkeyNotMatched,
@@ -996,6 +1273,7 @@ func (dm *Daemon) onDisconnectEvent(e DisconnectEvent) {
 		}
 	case ErrDisconnectNoIntroduction,
 		ErrDisconnectVersionNotSupported,
+		ErrDisconnectEncryptionRequired,
 		ErrDisconnectSelf:
 		dm.pex.IncreaseRetryTimes(e.Addr)
 	default:
@@ -1086,6 +1364,13 @@ func (dm *Daemon) handleMessageSendResult(r gnet.SendResult) {
 		dm.announcedTxns.add(m.GetFiltered())
 	}
 
+	// The peer has the transactions and blocks that were sent to it
+	if hashes := inventoryOf(r.Message); len(hashes) != 0 {
+		if c := dm.connections.get(r.Addr); c != nil {
+			dm.inventory.add(c.Addr, c.gnetID, hashes)
+		}
+	}
+
 	if m, ok := r.Message.(*DisconnectMessage); ok {
 		if err := dm.disconnectNow(r.Addr, m.reason); err != nil {
 			logger.WithError(err).WithField("addr", r.Addr).Warning("disconnectNow")
@@ -1093,7 +1378,8 @@ func (dm *Daemon) handleMessageSendResult(r gnet.SendResult) {
 	}
 }
 
-// requestBlocks sends a GetBlocksMessage to all connections
+// requestBlocks reassigns timed out block requests and sends GetBlocksMessages for disjoint block ranges
+// to the introduced peers that are ahead of us
 func (dm *Daemon) requestBlocks() error {
 	if dm.config.DisableNetworking {
 		return ErrNetworkingDisabled
@@ -1107,16 +1393,72 @@ func (dm *Daemon) requestBlocks() error {
 		return errors.New("Cannot request blocks, there is no head block")
 	}
 
-	m := NewGetBlocksMessage(headSeq, dm.config.GetBlocksRequestCount)
+	dm.blockSync.setHead(headSeq)
 
-	if _, err := dm.broadcastMessage(m); err != nil {
-		logger.WithError(err).Debug("Broadcast GetBlocksMessage failed")
-		return err
+	now := time.Now().UTC()
+
+	for _, r := range dm.blockSync.timeout(now) {
+		logger.WithFields(logrus.Fields{
+			"addr":   r.Addr,
+			"gnetID": r.GnetID,
+			"start":  r.Start,
+			"end":    r.End,
+		}).Info("Block request timed out, requesting the blocks from another peer")
+	}
+
+	for _, r := range dm.blockSync.schedule(now, dm.syncPeers()) {
+		m := NewGetBlocksMessage(r.Start-1, r.Len())
+		if err := dm.sendMessage(r.Addr, m); err != nil {
+			logger.WithError(err).WithField("addr", r.Addr).Warning("Send GetBlocksMessage failed")
+			dm.blockSync.cancel(r)
+		}
 	}
 
 	return nil
 }
 
+// requestBackfillBlocks sends a GetBlocksMessage for the bodies of the blocks that are missing
+// after starting from a snapshot, if no such request is in flight
+func (dm *Daemon) requestBackfillBlocks() error {
+	if dm.config.DisableNetworking {
+		return ErrNetworkingDisabled
+	}
+
+	start, end, ok, err := dm.visor.BackfillRange()
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return nil
+	}
+
+	r, ok := dm.blockSync.scheduleBackfill(time.Now().UTC(), start, end, dm.syncPeers())
+	if !ok {
+		return nil
+	}
+
+	m := NewGetBlocksMessage(r.Start-1, r.Len())
+	return dm.sendMessage(r.Addr, m)
+}
+
+// syncPeers returns the introduced connections with their reported heights
+func (dm *Daemon) syncPeers() []syncPeer {
+	conns := dm.connections.all()
+	peers := make([]syncPeer, 0, len(conns))
+	for _, c := range conns {
+		if !c.HasIntroduced() {
+			continue
+		}
+		peers = append(peers, syncPeer{
+			Addr:   c.Addr,
+			GnetID: c.gnetID,
+			Height: c.Height,
+			Pruned: c.HasCapability(capabilityPruned),
+		})
+	}
+	return peers
+}
+
 // announceBlocks sends an AnnounceBlocksMessage to all connections
 func (dm *Daemon) announceBlocks() error {
 	if dm.config.DisableNetworking {
@@ -1164,6 +1506,7 @@ func (dm *Daemon) createAndPublishBlock() (*coin.SignedBlock, error) {
 }
 
 // ResendUnconfirmedTxns resends all unconfirmed transactions and returns the hashes that were successfully rebroadcast.
+// Transactions created by this node that are still embargoed are resent through the stem peer.
 // It does not return an error if broadcasting fails.
 func (dm *Daemon) ResendUnconfirmedTxns() ([]cipher.SHA256, error) {
 	if dm.config.DisableNetworking {
@@ -1175,11 +1518,30 @@ func (dm *Daemon) ResendUnconfirmedTxns() ([]cipher.SHA256, error) {
 		return nil, err
 	}
 
+	var head *coin.SignedBlock
 	var txids []cipher.SHA256
 	for i := range txns {
-		txnHash := txns[i].Transaction.Hash()
+		txn := txns[i].Transaction
+		txnHash := txn.Hash()
 		logger.WithField("txid", txnHash.Hex()).Debug("Rebroadcast transaction")
-		if _, err := dm.BroadcastTransaction(txns[i].Transaction); err == nil {
+
+		var inputs coin.UxArray
+		if dm.txnRelay.isEmbargoed(txnHash) {
+			if head == nil {
+				head, err = dm.headSignedBlock()
+				if err != nil {
+					return nil, err
+				}
+			}
+
+			inputs, err = dm.visor.GetUnspentOutputs(txn.In)
+			if err != nil {
+				logger.WithError(err).WithField("txid", txnHash.Hex()).Error("Rebroadcast transaction: GetUnspentOutputs failed")
+				continue
+			}
+		}
+
+		if err := dm.resendTransaction(txn, head, inputs); err == nil {
 			txids = append(txids, txnHash)
 		}
 	}
@@ -1187,6 +1549,40 @@ func (dm *Daemon) ResendUnconfirmedTxns() ([]cipher.SHA256, error) {
 	return txids, nil
 }
 
+// resendTransaction rebroadcasts an unconfirmed transaction.
+// A transaction created by this node that is still embargoed is sent to the stem peer again,
+// so that resending does not reveal it as created by this node. Other transactions are broadcast to all peers.
+// head and inputs are only used for embargoed transactions.
+func (dm *Daemon) resendTransaction(txn coin.Transaction, head *coin.SignedBlock, inputs coin.UxArray) error {
+	if dm.txnRelay.isEmbargoed(txn.Hash()) {
+		return dm.BroadcastUserTransaction(txn, head, inputs)
+	}
+
+	_, err := dm.BroadcastTransaction(txn)
+	return err
+}
+
+// headSignedBlock returns the head block of the blockchain
+func (dm *Daemon) headSignedBlock() (*coin.SignedBlock, error) {
+	headSeq, ok, err := dm.visor.HeadBkSeq()
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, errors.New("There is no head block")
+	}
+
+	sb, err := dm.visor.GetSignedBlockBySeq(headSeq)
+	if err != nil {
+		return nil, err
+	}
+	if sb == nil {
+		return nil, errors.New("Head block not found")
+	}
+
+	return sb, nil
+}
+
 // BroadcastTransaction broadcasts a single transaction to all peers.
 func (dm *Daemon) BroadcastTransaction(txn coin.Transaction) ([]uint64, error) {
 	if dm.config.DisableNetworking {
@@ -1209,9 +1605,19 @@ func (dm *Daemon) BroadcastTransaction(txn coin.Transaction) ([]uint64, error) {
 	return ids, nil
 }
 
-// BroadcastUserTransaction broadcasts a single transaction to all peers.
+// BroadcastUserTransaction broadcasts a single transaction created by this node.
+// Unless DisableTxnStemRelay is set, the transaction is relayed through a single outgoing peer,
+// and only broadcast to all peers if that fails.
 // Returns an error if no peers that would propagate the transaction could be reached.
 func (dm *Daemon) BroadcastUserTransaction(txn coin.Transaction, head *coin.SignedBlock, inputs coin.UxArray) error {
+	if !dm.config.DisableTxnStemRelay {
+		err := dm.stemUserTransaction(txn, head, inputs)
+		if err == nil {
+			return nil
+		}
+		logger.WithError(err).Info("Transaction stem relay failed, broadcasting the transaction to all peers")
+	}
+
 	ids, err := dm.BroadcastTransaction(txn)
 	if err != nil {
 		return err
@@ -1327,12 +1733,18 @@ func (dm *Daemon) Disconnect(addr string, r gnet.DisconnectReason) error {
 
 // Implements private daemoner interface methods:
 
-// requestBlocksFromAddr sends a GetBlocksMessage to one connected address
+// requestBlocksFromAddr sends a GetBlocksMessage to one connected address.
+// Pruned peers are not sent a request, since they may not have the blocks after our head block;
+// blocks are requested from them by the syncManager once their height is known.
 func (dm *Daemon) requestBlocksFromAddr(addr string) error {
 	if dm.config.DisableNetworking {
 		return ErrNetworkingDisabled
 	}
 
+	if c := dm.connections.get(addr); c != nil && c.HasCapability(capabilityPruned) {
+		return nil
+	}
+
 	headSeq, ok, err := dm.visor.HeadBkSeq()
 	if err != nil {
 		return err
@@ -1345,7 +1757,8 @@ func (dm *Daemon) requestBlocksFromAddr(addr string) error {
 	return dm.sendMessage(addr, m)
 }
 
-// broadcastBlock sends a signed block to all connections
+// broadcastBlock sends a signed block to all connections.
+// Connections that accept compact blocks are sent a CompactBlockMessage, the others are sent a GiveBlocksMessage.
 func (dm *Daemon) broadcastBlock(sb coin.SignedBlock) error {
 	if dm.config.DisableNetworking {
 		return ErrNetworkingDisabled
@@ -1356,10 +1769,173 @@ func (dm *Daemon) broadcastBlock(sb coin.SignedBlock) error {
 		logger.Critical().Error("NewGiveBlocksMessage truncated its only block")
 	}
 
-	_, err := dm.broadcastMessage(m)
+	return dm.broadcastCompactBlock(sb, m)
+}
+
+// relayHeadBlock sends the head block to all connections.
+// Connections that accept compact blocks are sent a CompactBlockMessage, the others are sent an AnnounceBlocksMessage.
+func (dm *Daemon) relayHeadBlock() error {
+	if dm.config.DisableNetworking {
+		return ErrNetworkingDisabled
+	}
+
+	headSeq, ok, err := dm.visor.HeadBkSeq()
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errors.New("Cannot relay head block, there is no head block")
+	}
+
+	sb, err := dm.visor.GetSignedBlockBySeq(headSeq)
+	if err != nil {
+		return err
+	}
+	if sb == nil {
+		return errors.New("Cannot relay head block, head block not found")
+	}
+
+	return dm.broadcastCompactBlock(*sb, NewAnnounceBlocksMessage(headSeq))
+}
+
+// broadcastCompactBlock sends a CompactBlockMessage of a block to the introduced connections that accept
+// compact blocks, and sends msg to the other introduced connections.
+// Connections that already have the block are skipped.
+func (dm *Daemon) broadcastCompactBlock(sb coin.SignedBlock, msg gnet.Message) error {
+	compactMsg := NewCompactBlockMessage(sb)
+	blockHash := sb.HashHeader()
+
+	var compactAddrs, addrs []string
+	suppressed := false
+	for _, c := range dm.connections.all() {
+		if !c.HasIntroduced() {
+			continue
+		}
+
+		compact := !dm.config.DisableCompactBlocks && c.HasCapability(capabilityCompactBlocks)
+
+		if dm.inventory.knows(c.Addr, c.gnetID, blockHash) {
+			suppressed = true
+			if compact {
+				dm.inventory.recordSuppressedBlock(gnet.MessageSize(compactMsg))
+			} else {
+				dm.inventory.recordSuppressedBlock(gnet.MessageSize(msg))
+			}
+			continue
+		}
+
+		if compact {
+			compactAddrs = append(compactAddrs, c.Addr)
+		} else {
+			addrs = append(addrs, c.Addr)
+		}
+	}
+
+	if suppressed && len(compactAddrs) == 0 && len(addrs) == 0 {
+		return nil
+	}
+
+	// Fails only if the block could not be queued for any connection
+	var err error
+	sent := false
+
+	if len(compactAddrs) != 0 {
+		if _, err = dm.pool.Pool.BroadcastMessage(compactMsg, compactAddrs); err == nil {
+			sent = true
+		}
+	}
+
+	if len(addrs) != 0 || len(compactAddrs) == 0 {
+		if _, fullErr := dm.pool.Pool.BroadcastMessage(msg, addrs); fullErr == nil {
+			sent = true
+		} else if err == nil {
+			err = fullErr
+		}
+	}
+
+	if sent {
+		return nil
+	}
 	return err
 }
 
+// receiveCompactBlock rebuilds a block received in a CompactBlockMessage from the unconfirmed transactions.
+// Returns the block if all of its transactions were found. Otherwise, returns the indexes of the missing
+// transactions, which must be requested from the peer, and the block waits for them.
+// Returns neither if the block is already waiting for transactions from a peer.
+func (dm *Daemon) receiveCompactBlock(addr string, gnetID uint64, m *CompactBlockMessage) (*coin.SignedBlock, []uint16, error) {
+	if dm.config.DisableCompactBlocks {
+		return nil, nil, errors.New("Compact blocks are disabled")
+	}
+
+	sb := coin.SignedBlock{
+		Block: coin.Block{
+			Head: m.Head,
+		},
+		Sig: m.Sig,
+	}
+	if err := sb.VerifySignature(dm.config.BlockchainPubkey); err != nil {
+		dm.recordMisbehaviour(addr, misbehaviourBadBlockSignature)
+		return nil, nil, err
+	}
+
+	unconfirmed, err := dm.visor.GetAllUnconfirmedTransactions()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	txns := make([]coin.Transaction, len(unconfirmed))
+	for i, u := range unconfirmed {
+		txns[i] = u.Transaction
+	}
+
+	sb, missing := rebuildCompactBlock(m, txns)
+	if len(missing) == 0 {
+		if err := verifyCompactBlock(sb); err != nil {
+			return nil, nil, err
+		}
+		return &sb, nil, nil
+	}
+
+	if !dm.compactBlocks.add(&pendingCompactBlock{
+		Block:       sb,
+		Missing:     missing,
+		Addr:        addr,
+		GnetID:      gnetID,
+		RequestedAt: time.Now().UTC(),
+	}) {
+		return nil, nil, nil
+	}
+
+	return nil, missing, nil
+}
+
+// receiveBlockTxns completes a compact block with the transactions received from the peer it was requested from
+func (dm *Daemon) receiveBlockTxns(addr string, gnetID uint64, m *GiveBlockTxnsMessage) (*coin.SignedBlock, error) {
+	sb, err := dm.compactBlocks.complete(addr, gnetID, m.BlockHash, m.Transactions)
+	if err != nil {
+		return nil, err
+	}
+	return &sb, nil
+}
+
+// getSignedBlockByHash returns the block with the given header hash, or nil if not found
+func (dm *Daemon) getSignedBlockByHash(hash cipher.SHA256) (*coin.SignedBlock, error) {
+	return dm.visor.GetSignedBlockByHash(hash)
+}
+
+// capabilities returns the capabilities announced in introduction messages
+func (dm *Daemon) capabilities() uint32 {
+	var c uint32
+	if !dm.config.DisableCompactBlocks {
+		c |= capabilityCompactBlocks
+	}
+	if dm.config.Pruned {
+		c |= capabilityPruned
+	}
+	return c
+}
+
 // DaemonConfig returns the daemon config
 func (dm *Daemon) DaemonConfig() DaemonConfig {
 	return dm.config
@@ -1390,6 +1966,12 @@ func (dm *Daemon) connectionIntroduced(addr string, gnetID uint64, m *Introducti
 			logger.Critical().WithError(err).WithFields(fields).Error("pex.SetHasIncomingPort failed")
 			return nil, err
 		}
+
+		// The peer is reachable, so it is moved to the tried table
+		if err := dm.pex.MarkTried(listenAddr); err != nil {
+			logger.Critical().WithError(err).WithFields(fields).Error("pex.MarkTried failed")
+			return nil, err
+		}
 	} else {
 		// For successful incoming connections, add the peer to the peer list, with their self-reported listen port
 		if err := dm.pex.AddPeer(listenAddr); err != nil {
@@ -1408,7 +1990,9 @@ func (dm *Daemon) connectionIntroduced(addr string, gnetID uint64, m *Introducti
 	return c, nil
 }
 
-// sendRandomPeers sends a random sample of peers to another peer
+// sendRandomPeers sends a random sample of peers to another peer.
+// Peers that introduced themselves with a protocol version that supports GivePeersV2Message
+// are sent IPv4 and IPv6 peers, other peers are only sent IPv4 peers with GivePeersMessage.
 func (dm *Daemon) sendRandomPeers(addr string) error {
 	peers := dm.pex.RandomExchangeable(dm.pex.Config.ReplyCount)
 	if len(peers) == 0 {
@@ -1416,7 +2000,12 @@ func (dm *Daemon) sendRandomPeers(addr string) error {
 		return errors.New("No peers available")
 	}
 
-	m := NewGivePeersMessage(peers, dm.config.MaxOutgoingMessageLength)
+	var m gnet.Message
+	if c := dm.connections.get(addr); c != nil && c.HasIntroduced() && c.ProtocolVersion >= givePeersV2ProtocolVersion {
+		m = NewGivePeersV2Message(peers, dm.config.MaxOutgoingMessageLength)
+	} else {
+		m = NewGivePeersMessage(peers, dm.config.MaxOutgoingMessageLength)
+	}
 
 	return dm.sendMessage(addr, m)
 }
@@ -1442,15 +2031,17 @@ func (dm *Daemon) announceTxnHashes(hashes []cipher.SHA256) error {
 		return ErrNetworkingDisabled
 	}
 
+	// Transactions relayed through a stem peer are not announced until their embargo ends
+	hashes = dm.txnRelay.filterEmbargoed(hashes)
+	if len(hashes) == 0 {
+		return nil
+	}
+
 	// Divide hashes into multiple sets of max size
 	hashesSet := divideHashes(hashes, dm.config.MaxTxnAnnounceNum)
 
 	for _, hs := range hashesSet {
-		m := NewAnnounceTxnsMessage(hs, dm.config.MaxOutgoingMessageLength)
-		if len(m.Transactions) != len(hs) {
-			logger.Critical().Error("NewAnnounceTxnsMessage truncated hashes that were already split up")
-		}
-		if _, err := dm.broadcastMessage(m); err != nil {
+		if err := dm.broadcastTxnAnnouncement(hs); err != nil {
 			logger.WithError(err).Debug("Broadcast AnnounceTxnsMessage failed")
 			return err
 		}
@@ -1459,6 +2050,219 @@ func (dm *Daemon) announceTxnHashes(hashes []cipher.SHA256) error {
 	return nil
 }
 
+// broadcastTxnAnnouncement announces transaction hashes to the introduced connections.
+// Each connection is only sent the hashes it is not known to have, and connections that have all of them are skipped.
+// The connections that need all of the hashes share a single broadcast message.
+func (dm *Daemon) broadcastTxnAnnouncement(hashes []cipher.SHA256) error {
+	m := NewAnnounceTxnsMessage(hashes, dm.config.MaxOutgoingMessageLength)
+	if len(m.Transactions) != len(hashes) {
+		logger.Critical().Error("NewAnnounceTxnsMessage truncated hashes that were already split up")
+	}
+
+	var addrs []string
+	filtered := false
+	for _, c := range dm.connections.all() {
+		if !c.HasIntroduced() {
+			continue
+		}
+
+		unknown := dm.inventory.filterKnown(c.Addr, c.gnetID, m.Transactions)
+		if len(unknown) == len(m.Transactions) {
+			addrs = append(addrs, c.Addr)
+			continue
+		}
+
+		filtered = true
+		dm.recordSuppressedTxnAnnouncement(m.Transactions, unknown)
+
+		if len(unknown) == 0 {
+			continue
+		}
+
+		if err := dm.sendMessage(c.Addr, NewAnnounceTxnsMessage(unknown, dm.config.MaxOutgoingMessageLength)); err != nil {
+			logger.WithError(err).WithField("addr", c.Addr).Debug("Send AnnounceTxnsMessage failed")
+		}
+	}
+
+	if filtered && len(addrs) == 0 {
+		return nil
+	}
+
+	_, err := dm.pool.Pool.BroadcastMessage(m, addrs)
+	return err
+}
+
+// announceRelayedTxns announces transactions received from peers.
+// Each introduced peer is sent the announcement after its own random delay, batched with the other
+// transactions queued for it. If TxnAnnounceDelay is zero, the transactions are announced immediately.
+func (dm *Daemon) announceRelayedTxns(hashes []cipher.SHA256) error {
+	if dm.config.DisableNetworking {
+		return ErrNetworkingDisabled
+	}
+
+	if dm.config.TxnAnnounceDelay == 0 {
+		return dm.announceTxnHashes(hashes)
+	}
+
+	conns := dm.connections.all()
+	introduced := conns[:0]
+	for _, c := range conns {
+		if c.HasIntroduced() {
+			introduced = append(introduced, c)
+		}
+	}
+
+	dm.txnRelay.queueAnnouncements(introduced, hashes, time.Now().UTC())
+
+	return nil
+}
+
+// sendTxnAnnouncements sends the transaction announcements that are due
+func (dm *Daemon) sendTxnAnnouncements() {
+	for _, a := range dm.txnRelay.dueAnnouncements(time.Now().UTC()) {
+		// Leave out the transactions that the peer received or announced while the announcement was queued
+		hashes := dm.inventory.filterKnown(a.Addr, a.GnetID, a.Hashes)
+		dm.recordSuppressedTxnAnnouncement(a.Hashes, hashes)
+
+		for _, hs := range divideHashes(hashes, dm.config.MaxTxnAnnounceNum) {
+			m := NewAnnounceTxnsMessage(hs, dm.config.MaxOutgoingMessageLength)
+			if len(m.Transactions) != len(hs) {
+				logger.Critical().Error("NewAnnounceTxnsMessage truncated hashes that were already split up")
+			}
+
+			if err := dm.sendMessage(a.Addr, m); err != nil {
+				logger.WithError(err).WithFields(logrus.Fields{
+					"addr":   a.Addr,
+					"gnetID": a.GnetID,
+				}).Debug("Send AnnounceTxnsMessage failed")
+				break
+			}
+		}
+	}
+}
+
+// recordSuppressedTxnAnnouncement records the traffic saved by reducing an announcement of hashes
+// to the unknown hashes. If no hashes are unknown, the announcement messages are not sent at all.
+func (dm *Daemon) recordSuppressedTxnAnnouncement(hashes, unknown []cipher.SHA256) {
+	known := len(hashes) - len(unknown)
+	if known == 0 {
+		return
+	}
+
+	if len(unknown) != 0 {
+		dm.inventory.recordSuppressedTxns(known, 0, uint64(known*len(cipher.SHA256{})))
+		return
+	}
+
+	var saved uint64
+	hashesSet := divideHashes(hashes, dm.config.MaxTxnAnnounceNum)
+	for _, hs := range hashesSet {
+		saved += gnet.MessageSize(NewAnnounceTxnsMessage(hs, dm.config.MaxOutgoingMessageLength))
+	}
+
+	dm.inventory.recordSuppressedTxns(known, len(hashesSet), saved)
+}
+
+// recordPeerInventory records transactions and blocks that a peer announced or sent to us
+func (dm *Daemon) recordPeerInventory(addr string, gnetID uint64, hashes []cipher.SHA256) {
+	dm.inventory.add(addr, gnetID, hashes)
+}
+
+// GetInventoryStats returns the relay traffic saved by not announcing inventory to peers that already have it
+func (dm *Daemon) GetInventoryStats() InventoryStats {
+	return dm.inventory.getStats()
+}
+
+// stemUserTransaction sends a transaction created by this node to the stem peer only, and embargoes it
+func (dm *Daemon) stemUserTransaction(txn coin.Transaction, head *coin.SignedBlock, inputs coin.UxArray) error {
+	if dm.config.DisableNetworking {
+		return ErrNetworkingDisabled
+	}
+
+	now := time.Now().UTC()
+	addr, ok := dm.selectStemPeer(txn, head, inputs, now)
+	if !ok {
+		return errNoStemPeer
+	}
+
+	m := NewGiveTxnsMessage(coin.Transactions{txn}, dm.config.MaxOutgoingMessageLength)
+	if len(m.Transactions) != 1 {
+		logger.Critical().Error("NewGiveTxnsMessage truncated its only transaction")
+	}
+
+	txnHash := txn.Hash()
+	dm.txnRelay.addEmbargo(txnHash, now)
+
+	if err := dm.sendMessage(addr, m); err != nil {
+		dm.txnRelay.removeEmbargo([]cipher.SHA256{txnHash})
+		dm.txnRelay.clearStemPeer()
+		return err
+	}
+
+	logger.WithFields(logrus.Fields{
+		"addr": addr,
+		"txid": txnHash.Hex(),
+	}).Debug("Relayed transaction to stem peer")
+
+	return nil
+}
+
+// selectStemPeer returns the address of the outgoing peer to relay a transaction created by this node through.
+// The stem peer of the current epoch is used if it is still connected and would propagate the transaction,
+// otherwise a new epoch is started with a random outgoing peer that would propagate the transaction.
+func (dm *Daemon) selectStemPeer(txn coin.Transaction, head *coin.SignedBlock, inputs coin.UxArray, now time.Time) (string, bool) {
+	accepts := func(c connection) bool {
+		if !c.Outgoing || !c.HasIntroduced() {
+			return false
+		}
+		_, err := checkBroadcastTxnRecipients(dm.connections, []uint64{c.gnetID}, txn, head, inputs)
+		return err == nil
+	}
+
+	if addr, gnetID, ok := dm.txnRelay.stemPeer(now); ok {
+		if c := dm.connections.get(addr); c != nil && c.gnetID == gnetID && accepts(*c) {
+			return addr, true
+		}
+	}
+
+	var candidates []connection
+	for _, c := range dm.connections.all() {
+		if accepts(c) {
+			candidates = append(candidates, c)
+		}
+	}
+
+	if len(candidates) == 0 {
+		dm.txnRelay.clearStemPeer()
+		return "", false
+	}
+
+	c := candidates[dm.txnRelay.intn(len(candidates))]
+	dm.txnRelay.setStemPeer(c.Addr, c.gnetID, now)
+
+	return c.Addr, true
+}
+
+// endTxnStems lifts the embargo of transactions announced by a peer, since they have reached the network
+func (dm *Daemon) endTxnStems(hashes []cipher.SHA256) {
+	dm.txnRelay.removeEmbargo(hashes)
+}
+
+// announceExpiredTxnStems announces the transactions that were relayed through the stem peer
+// but not announced back by the network before their embargo expired
+func (dm *Daemon) announceExpiredTxnStems() {
+	hashes := dm.txnRelay.expiredEmbargoes(time.Now().UTC())
+	if len(hashes) == 0 {
+		return
+	}
+
+	logger.Infof("Announcing %d transactions whose stem embargo expired", len(hashes))
+
+	if err := dm.announceTxnHashes(hashes); err != nil {
+		logger.WithError(err).Warning("announceTxnHashes failed")
+	}
+}
+
 func divideHashes(hashes []cipher.SHA256, n int) [][]cipher.SHA256 {
 	if len(hashes) == 0 {
 		return [][]cipher.SHA256{}
@@ -1520,9 +2324,9 @@ func (dm *Daemon) pexConfig() pex.Config {
 	return dm.pex.Config
 }
 
-// addPeers adds peers to the pex
-func (dm *Daemon) addPeers(addrs []string) int {
-	return dm.pex.AddPeers(addrs)
+// addPeers adds peers received from the peer at source to the pex
+func (dm *Daemon) addPeers(addrs []string, source string) int {
+	return dm.pex.AddPeersFrom(addrs, source)
 }
 
 // recordPeerHeight records the height of specific peer
@@ -1542,9 +2346,82 @@ func (dm *Daemon) headBkSeq() (uint64, bool, error) {
 	return dm.visor.HeadBkSeq()
 }
 
-// executeSignedBlock executes the signed block
-func (dm *Daemon) executeSignedBlock(b coin.SignedBlock) error {
-	return dm.visor.ExecuteSignedBlock(b)
+// receiveBlocks buffers blocks received from a peer and executes the buffered blocks that follow the head block.
+// Returns the number of executed blocks.
+func (dm *Daemon) receiveBlocks(addr string, gnetID uint64, blocks []coin.SignedBlock) (int, error) {
+	headSeq, ok, err := dm.visor.HeadBkSeq()
+	if err != nil {
+		return 0, err
+	}
+	if !ok {
+		return 0, errors.New("No HeadBkSeq found, cannot execute blocks")
+	}
+
+	dm.blockSync.setHead(headSeq)
+
+	if err := dm.blockSync.receive(addr, gnetID, blocks); err != nil {
+		logger.WithError(err).WithFields(logrus.Fields{
+			"addr":   addr,
+			"gnetID": gnetID,
+		}).Warning("Rejected received blocks")
+		dm.recordMisbehaviour(addr, misbehaviourInvalidBlocksResponse)
+	}
+
+	processed := 0
+	for {
+		b, ok := dm.blockSync.pop()
+		if !ok {
+			break
+		}
+
+		if err := b.Block.VerifySignature(dm.config.BlockchainPubkey); err != nil {
+			logger.WithError(err).WithFields(logrus.Fields{
+				"seq":  b.Block.Head.BkSeq,
+				"addr": b.Addr,
+			}).Warning("Received block has an invalid signature")
+			dm.blockSync.rejectBlock(time.Now().UTC(), b)
+			dm.recordMisbehaviour(b.Addr, misbehaviourBadBlockSignature)
+			break
+		}
+
+		if err := dm.visor.ExecuteSignedBlock(b.Block); err != nil {
+			logger.Critical().WithError(err).WithFields(logrus.Fields{
+				"seq":  b.Block.Head.BkSeq,
+				"addr": b.Addr,
+			}).Error("Failed to execute received block")
+			dm.blockSync.rejectBlock(time.Now().UTC(), b)
+			dm.recordMisbehaviour(b.Addr, misbehaviourInvalidBlock)
+			break
+		}
+
+		logger.Critical().WithField("seq", b.Block.Head.BkSeq).Info("Added new block")
+		processed++
+		dm.blockSync.setHead(b.Block.Seq())
+	}
+
+	return processed, nil
+}
+
+// receiveBackfillBlocks stores the bodies of blocks that are missing after starting from a snapshot,
+// if the blocks answer the backfill request sent to the peer. Returns false if the blocks do not answer the request.
+func (dm *Daemon) receiveBackfillBlocks(addr string, gnetID uint64, blocks []coin.SignedBlock) (bool, error) {
+	if !dm.blockSync.receiveBackfill(addr, gnetID, blocks) {
+		return false, nil
+	}
+
+	n, err := dm.visor.ExecuteBackfillBlocks(blocks)
+	if err != nil {
+		dm.recordMisbehaviour(addr, misbehaviourInvalidBlocksResponse)
+		return true, err
+	}
+
+	logger.WithFields(logrus.Fields{
+		"addr":  addr,
+		"count": n,
+	}).Debug("Backfilled blocks")
+
+	// Request the next blocks, since the request has been answered
+	return true, dm.requestBackfillBlocks()
 }
 
 // filterKnownUnconfirmed returns unconfirmed txn hashes with known ones removed
@@ -1581,6 +2458,7 @@ type GnetConnectionDetails struct {
 	ID           uint64
 	LastSent     time.Time
 	LastReceived time.Time
+	Stats        gnet.NetworkStats
 }
 
 func newConnection(dc *connection, gc *gnet.Connection, pp *pex.Peer) Connection {
@@ -1596,6 +2474,7 @@ func newConnection(dc *connection, gc *gnet.Connection, pp *pex.Peer) Connection
 			ID:           gc.ID,
 			LastSent:     gc.LastSent,
 			LastReceived: gc.LastReceived,
+			Stats:        gc.Stats,
 		}
 	}
 
@@ -1700,6 +2579,30 @@ func (dm *Daemon) GetExchgConnection() []string {
 	return dm.pex.RandomExchangeable(0).ToAddrs()
 }
 
+// GetNetworkStats returns the traffic of all connections since the node started
+func (dm *Daemon) GetNetworkStats() (gnet.NetworkStats, error) {
+	if dm.pool.Pool == nil {
+		return gnet.NetworkStats{}, nil
+	}
+
+	return dm.pool.Pool.GetStats()
+}
+
+// GetBans returns the banned IPs
+func (dm *Daemon) GetBans() []pex.Ban {
+	return dm.pex.Bans()
+}
+
+// BanPeer bans the IP of addr, which is of the form ip or ip:port, and disconnects all connections from it
+func (dm *Daemon) BanPeer(addr string, duration time.Duration, reason string) (pex.Ban, error) {
+	return dm.banIP(addr, duration, reason)
+}
+
+// UnbanPeer removes the ban of the IP of addr, which is of the form ip or ip:port
+func (dm *Daemon) UnbanPeer(addr string) error {
+	return dm.pex.Unban(addr)
+}
+
 /* Peer Blockchain Status API */
 
 // BlockchainProgress is the current blockchain syncing status
@@ -1710,6 +2613,8 @@ type BlockchainProgress struct {
 	Highest uint64
 	// Individual blockchain length reports from peers
 	Peers []PeerBlockchainHeight
+	// Block download status
+	Sync SyncProgress
 }
 
 // newBlockchainProgress creates BlockchainProgress from the local head blockchain sequence number
@@ -1757,7 +2662,9 @@ func EstimateBlockchainHeight(headSeq uint64, peers []PeerBlockchainHeight) uint
 // GetBlockchainProgress returns a *BlockchainProgress
 func (dm *Daemon) GetBlockchainProgress(headSeq uint64) *BlockchainProgress {
 	conns := dm.connections.all()
-	return newBlockchainProgress(headSeq, conns)
+	progress := newBlockchainProgress(headSeq, conns)
+	progress.Sync = dm.blockSync.progress()
+	return progress
 }
 
 // InjectBroadcastTransaction injects transaction to the unconfirmed pool and broadcasts it.
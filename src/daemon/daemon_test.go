@@ -1,6 +1,9 @@
 package daemon
 
 import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -8,6 +11,7 @@ import (
 
 	"github.com/skycoin/skycoin/src/cipher"
 	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/daemon/pex"
 	"github.com/skycoin/skycoin/src/params"
 	"github.com/skycoin/skycoin/src/util/fee"
 	"github.com/skycoin/skycoin/src/util/useragent"
@@ -340,3 +344,62 @@ func TestCheckBroadcastTxnRecipients(t *testing.T) {
 		})
 	}
 }
+
+func TestCheckOutgoingDiversity(t *testing.T) {
+	dir, err := ioutil.TempDir("", "daemon")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	asmapFile := filepath.Join(dir, "asmap.txt")
+	require.NoError(t, ioutil.WriteFile(asmapFile, []byte("45.0.0.0/8 AS100\n"), 0600))
+
+	pexCfg := pex.NewConfig()
+	pexCfg.DataDirectory = dir
+	pexCfg.ASMapFile = asmapFile
+	px, err := pex.New(pexCfg)
+	require.NoError(t, err)
+
+	cfg := NewDaemonConfig()
+	cfg.MaxOutgoingPerNetGroup = 1
+	cfg.MaxOutgoingPerASN = 2
+
+	d := &Daemon{
+		config:      cfg,
+		pex:         px,
+		connections: NewConnections(),
+	}
+
+	_, err = d.connections.pending("112.32.32.14:6000")
+	require.NoError(t, err)
+
+	// Only one outgoing connection is made to a network group
+	require.Error(t, d.checkOutgoingDiversity("112.32.1.1"))
+	require.NoError(t, d.checkOutgoingDiversity("112.33.1.1"))
+
+	// Only two outgoing connections are made to an autonomous system
+	_, err = d.connections.pending("45.1.1.1:6000")
+	require.NoError(t, err)
+	require.NoError(t, d.checkOutgoingDiversity("45.2.1.1"))
+	_, err = d.connections.pending("45.2.1.1:6000")
+	require.NoError(t, err)
+	require.Error(t, d.checkOutgoingDiversity("45.3.1.1"))
+
+	// Feeler and incoming connections are not counted
+	_, err = d.connections.pendingFeeler("46.1.1.1:6000")
+	require.NoError(t, err)
+	_, err = d.connections.connected("47.1.1.1:6000", 1)
+	require.NoError(t, err)
+	require.NoError(t, d.checkOutgoingDiversity("46.1.2.2"))
+	require.NoError(t, d.checkOutgoingDiversity("47.1.2.2"))
+
+	// Local addresses have no network group
+	_, err = d.connections.pending("127.0.0.1:6000")
+	require.NoError(t, err)
+	require.NoError(t, d.checkOutgoingDiversity("127.0.0.1"))
+
+	// The limits can be disabled
+	d.config.MaxOutgoingPerNetGroup = 0
+	d.config.MaxOutgoingPerASN = 0
+	require.NoError(t, d.checkOutgoingDiversity("112.32.1.1"))
+	require.NoError(t, d.checkOutgoingDiversity("45.3.1.1"))
+}
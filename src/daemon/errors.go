@@ -45,6 +45,10 @@ var (
 	ErrDisconnectInvalidMaxTransactionSize gnet.DisconnectReason = errors.New("Invalid max transaction size in introduction message")
 	// ErrDisconnectInvalidMaxDropletPrecision invalid max droplet precision in introduction message
 	ErrDisconnectInvalidMaxDropletPrecision gnet.DisconnectReason = errors.New("Invalid max droplet precision in introduction message")
+	// ErrDisconnectEncryptionRequired the peer does not support connection encryption, which is required
+	ErrDisconnectEncryptionRequired gnet.DisconnectReason = errors.New("Connection encryption is required")
+	// ErrDisconnectFeelerDone a feeler connection was closed after the peer introduced itself
+	ErrDisconnectFeelerDone gnet.DisconnectReason = errors.New("Feeler connection done")
 
 	// ErrDisconnectUnknownReason used when mapping an unknown reason code to an error. Is not sent over the network.
 	ErrDisconnectUnknownReason gnet.DisconnectReason = errors.New("Unknown DisconnectReason")
@@ -71,6 +75,8 @@ var (
 		ErrDisconnectInvalidBurnFactor:             17,
 		ErrDisconnectInvalidMaxTransactionSize:     18,
 		ErrDisconnectInvalidMaxDropletPrecision:    19,
+		ErrDisconnectEncryptionRequired:            20,
+		ErrDisconnectFeelerDone:                    21,
 
 		// gnet codes are registered here, but they are not sent in a DISC
 		// message by gnet. Only daemon sends a DISC packet.
@@ -82,6 +88,11 @@ var (
 		gnet.ErrDisconnectShutdown:               1005,
 		gnet.ErrDisconnectMessageDecodeUnderflow: 1006,
 		gnet.ErrDisconnectTruncatedMessageID:     1007,
+
+		gnet.ErrDisconnectDecryptionFailed:           1008,
+		gnet.ErrDisconnectUnexpectedEncryptedMessage: 1009,
+		gnet.ErrDisconnectUnexpectedPlaintextMessage: 1010,
+		gnet.ErrDisconnectInvalidEncryptionPubKey:    1011,
 	}
 
 	disconnectCodeReasons map[uint16]gnet.DisconnectReason
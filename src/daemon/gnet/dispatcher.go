@@ -34,16 +34,21 @@ func newSendResult(addr string, m Message, err error) SendResult {
 	}
 }
 
-// Serializes a Message over a net.Conn
-func sendMessage(conn net.Conn, msg Message, timeout time.Duration, maxMsgLength int) error {
+// Serializes a Message over a net.Conn, encrypting it if the connection's encryption is established.
+// Returns the number of bytes written.
+func sendMessage(conn net.Conn, msg Message, timeout time.Duration, maxMsgLength int, encryption *connectionEncryption) (int, error) {
 	m, err := EncodeMessage(msg)
 	if err != nil {
-		return err
+		return 0, err
 	}
 	if len(m) > maxMsgLength {
-		return ErrMsgExceedsMaxLen
+		return 0, ErrMsgExceedsMaxLen
+	}
+	b := encryption.seal(msg, m)
+	if err := sendByteMessage(conn, b, timeout); err != nil {
+		return 0, err
 	}
-	return sendByteMessage(conn, m, timeout)
+	return len(b), nil
 }
 
 // msgIDStringSafe formats msgID bytes to a string that is safe for logging (e.g. not impacted by ascii control chars)
@@ -192,10 +192,11 @@ func TestSendMessage(t *testing.T) {
 		require.True(t, bytes.Equal(msg, expect))
 		return nil
 	}
-	err := sendMessage(nil, m, 0, 1024)
+	n, err := sendMessage(nil, m, 0, 1024, nil)
 	require.NoError(t, err)
+	require.Equal(t, 9, n)
 
-	err = sendMessage(nil, m, 0, 1)
+	_, err = sendMessage(nil, m, 0, 1, nil)
 	testutil.RequireError(t, err, "Message exceeds max message length")
 }
 
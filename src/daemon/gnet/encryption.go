@@ -0,0 +1,243 @@
+package gnet
+
+import (
+	stdcipher "crypto/cipher"
+	"encoding/binary"
+	"errors"
+	"sync"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/cipher/chacha20poly1305"
+	"github.com/skycoin/skycoin/src/cipher/encoder"
+)
+
+/*
+Connection encryption
+
+Each side of a connection may announce an ephemeral secp256k1 public key in its handshake
+message, the first message that it sends (see EncryptionHandshake).
+When both sides have announced a key, they derive a shared secret with ECDH and encrypt
+the messages written after their own handshake message with chacha20poly1305.
+Each direction of the connection has its own key, derived from the shared secret and the
+sender's public key, and its own nonce, which counts the frames sent in that direction.
+
+An encrypted frame has the same length prefix as a plaintext frame, with the
+encryptedFrameFlag bit set. The length prefix is authenticated as additional data.
+Once an encrypted frame has been received, plaintext frames are rejected.
+
+The keys are ephemeral and are not tied to an identity. Encryption hides the messages from
+on-path observers and prevents tampering with the connection after the handshake,
+but it does not authenticate the peer against an active man-in-the-middle during the handshake.
+*/
+
+const (
+	// encryptedFrameFlag is set in the length prefix of encrypted frames
+	encryptedFrameFlag uint32 = 1 << 31
+	// encryptionOverhead is the number of bytes added to an encrypted frame's payload
+	encryptionOverhead = 16
+)
+
+var (
+	// ErrDisconnectDecryptionFailed an encrypted message could not be decrypted
+	ErrDisconnectDecryptionFailed DisconnectReason = errors.New("Message decryption failed")
+	// ErrDisconnectUnexpectedEncryptedMessage an encrypted message was received before encryption was negotiated
+	ErrDisconnectUnexpectedEncryptedMessage DisconnectReason = errors.New("Encrypted message received on an unencrypted connection")
+	// ErrDisconnectUnexpectedPlaintextMessage a plaintext message was received after an encrypted message
+	ErrDisconnectUnexpectedPlaintextMessage DisconnectReason = errors.New("Plaintext message received on an encrypted connection")
+	// ErrDisconnectInvalidEncryptionPubKey the peer's encryption public key is invalid
+	ErrDisconnectInvalidEncryptionPubKey DisconnectReason = errors.New("Invalid encryption public key")
+)
+
+// EncryptionHandshake is implemented by the handshake message, the first message sent by each side of a connection.
+// The message carries the sender's ephemeral encryption public key, if the sender encrypts connections.
+type EncryptionHandshake interface {
+	EncryptionPubKey() (cipher.PubKey, bool)
+}
+
+// frame is a message read from a connection, without its length prefix
+type frame struct {
+	data      []byte
+	encrypted bool
+}
+
+// size returns the number of bytes of the frame on the wire, including the length prefix
+func (f frame) size() int {
+	return messageLengthPrefixSize + len(f.data)
+}
+
+// frameCipher encrypts or decrypts the frames sent in one direction of a connection
+type frameCipher struct {
+	aead  stdcipher.AEAD
+	count uint64
+}
+
+func newFrameCipher(key []byte) (*frameCipher, error) {
+	aead, err := chacha20poly1305.New(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return &frameCipher{
+		aead: aead,
+	}, nil
+}
+
+// nextNonce returns the nonce for the next frame
+func (f *frameCipher) nextNonce() []byte {
+	nonce := make([]byte, chacha20poly1305.NonceSize)
+	binary.LittleEndian.PutUint64(nonce, f.count)
+	f.count++
+	return nonce
+}
+
+// seal encrypts a plaintext message of the form [length prefix][message ID][message]
+// to an encrypted frame of the form [flagged length prefix][ciphertext]
+func (f *frameCipher) seal(msg []byte) []byte {
+	payload := msg[messageLengthPrefixSize:]
+	lengthPrefix := encoder.SerializeUint32(uint32(len(payload)+encryptionOverhead) | encryptedFrameFlag)
+	return f.aead.Seal(lengthPrefix, f.nextNonce(), payload, lengthPrefix)
+}
+
+// open decrypts the data of an encrypted frame
+func (f *frameCipher) open(data []byte) ([]byte, error) {
+	lengthPrefix := encoder.SerializeUint32(uint32(len(data)) | encryptedFrameFlag)
+	return f.aead.Open(nil, f.nextNonce(), data, lengthPrefix)
+}
+
+// connectionEncryption is the encryption state of a connection
+type connectionEncryption struct {
+	sync.Mutex
+	pubKey cipher.PubKey
+	secKey cipher.SecKey
+	// read is only accessed by the connection's message receiving goroutine
+	read *frameCipher
+	// receivedEncrypted is only accessed by the connection's message receiving goroutine
+	receivedEncrypted bool
+	write             *frameCipher
+	handshakeSent     bool
+}
+
+func newConnectionEncryption() *connectionEncryption {
+	pubKey, secKey := cipher.GenerateKeyPair()
+	return &connectionEncryption{
+		pubKey: pubKey,
+		secKey: secKey,
+	}
+}
+
+// establish derives the read and write keys from the peer's public key
+func (e *connectionEncryption) establish(peerPubKey cipher.PubKey) error {
+	if peerPubKey == e.pubKey {
+		return ErrDisconnectInvalidEncryptionPubKey
+	}
+
+	secret, err := cipher.ECDH(peerPubKey, e.secKey)
+	if err != nil {
+		return ErrDisconnectInvalidEncryptionPubKey
+	}
+
+	deriveKey := func(pubKey cipher.PubKey) []byte {
+		b := make([]byte, 0, len(secret)+len(pubKey))
+		b = append(b, secret...)
+		b = append(b, pubKey[:]...)
+		key := cipher.SumSHA256(b)
+		return key[:]
+	}
+
+	read, err := newFrameCipher(deriveKey(peerPubKey))
+	if err != nil {
+		return err
+	}
+
+	write, err := newFrameCipher(deriveKey(e.pubKey))
+	if err != nil {
+		return err
+	}
+
+	e.Lock()
+	defer e.Unlock()
+
+	e.read = read
+	e.write = write
+
+	return nil
+}
+
+// receiveHandshake establishes the encryption if m is the peer's handshake message and announces a key.
+// Called from the connection's message receiving goroutine, before the next frame is opened.
+func (e *connectionEncryption) receiveHandshake(m Message) error {
+	if e == nil || e.read != nil {
+		return nil
+	}
+
+	h, ok := m.(EncryptionHandshake)
+	if !ok {
+		return nil
+	}
+
+	pubKey, ok := h.EncryptionPubKey()
+	if !ok {
+		return nil
+	}
+
+	return e.establish(pubKey)
+}
+
+// open returns the plaintext of a received frame
+func (e *connectionEncryption) open(f frame) ([]byte, error) {
+	if !f.encrypted {
+		if e != nil && e.receivedEncrypted {
+			return nil, ErrDisconnectUnexpectedPlaintextMessage
+		}
+		return f.data, nil
+	}
+
+	if e == nil || e.read == nil {
+		return nil, ErrDisconnectUnexpectedEncryptedMessage
+	}
+
+	data, err := e.read.open(f.data)
+	if err != nil {
+		return nil, ErrDisconnectDecryptionFailed
+	}
+
+	e.receivedEncrypted = true
+
+	return data, nil
+}
+
+// seal encrypts an encoded message, if the handshake message has been sent and the encryption is established.
+// The handshake message itself is never encrypted.
+func (e *connectionEncryption) seal(m Message, msg []byte) []byte {
+	if e == nil {
+		return msg
+	}
+
+	e.Lock()
+	defer e.Unlock()
+
+	if !e.handshakeSent {
+		if _, ok := m.(EncryptionHandshake); ok {
+			e.handshakeSent = true
+		}
+		return msg
+	}
+
+	if e.write == nil {
+		return msg
+	}
+
+	return e.write.seal(msg)
+}
+
+// encrypted returns true if messages written to the connection are encrypted
+func (e *connectionEncryption) encrypted() bool {
+	if e == nil {
+		return false
+	}
+
+	e.Lock()
+	defer e.Unlock()
+
+	return e.handshakeSent && e.write != nil
+}
@@ -0,0 +1,265 @@
+package gnet
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/cipher/encoder"
+)
+
+type HandshakeMessage struct {
+	PubKey cipher.PubKey
+}
+
+var HandshakePrefix = MessagePrefix{'H', 'S', 'H', 'K'}
+
+// EncodeSize implements gnet.Serializer
+func (hm *HandshakeMessage) EncodeSize() uint64 {
+	return uint64(encoder.Size(hm))
+}
+
+// Encode implements gnet.Serializer
+func (hm *HandshakeMessage) Encode(buf []byte) error {
+	buf2 := encoder.Serialize(hm)
+	if len(buf) < len(buf2) {
+		return errors.New("Not enough buffer data to encode")
+	}
+	copy(buf[:], buf2[:])
+	return nil
+}
+
+// Decode implements gnet.Serializer
+func (hm *HandshakeMessage) Decode(buf []byte) (uint64, error) {
+	return encoder.DeserializeRaw(buf, hm)
+}
+
+func (hm *HandshakeMessage) Handle(c *MessageContext, x interface{}) error {
+	return nil
+}
+
+// EncryptionPubKey implements gnet.EncryptionHandshake
+func (hm *HandshakeMessage) EncryptionPubKey() (cipher.PubKey, bool) {
+	return hm.PubKey, hm.PubKey != cipher.PubKey{}
+}
+
+func setupEncryptionMessages() {
+	EraseMessages()
+	RegisterMessage(BytePrefix, ByteMessage{})
+	RegisterMessage(HandshakePrefix, HandshakeMessage{})
+	VerifyMessages()
+}
+
+// sendEncrypted encodes a message as it would be written to a connection
+func sendEncrypted(t *testing.T, e *connectionEncryption, m Message) []byte {
+	b, err := EncodeMessage(m)
+	require.NoError(t, err)
+	return e.seal(m, b)
+}
+
+// receiveEncrypted decodes a message as it would be read from a connection
+func receiveEncrypted(t *testing.T, e *connectionEncryption, data []byte) (Message, error) {
+	frames, err := decodeData(bytes.NewBuffer(data), 1024)
+	require.NoError(t, err)
+	require.Len(t, frames, 1)
+
+	plaintext, err := e.open(frames[0])
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := convertToMessage(1, plaintext, false)
+	require.NoError(t, err)
+
+	if err := e.receiveHandshake(m); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+func TestConnectionEncryption(t *testing.T) {
+	setupEncryptionMessages()
+	defer EraseMessages()
+
+	a := newConnectionEncryption()
+	b := newConnectionEncryption()
+
+	// Messages sent before the handshake are not encrypted
+	data := sendEncrypted(t, a, NewByteMessage(1))
+	require.Zero(t, data[3]&0x80)
+	m, err := receiveEncrypted(t, b, data)
+	require.NoError(t, err)
+	require.Equal(t, NewByteMessage(1), m)
+
+	// The handshake messages are not encrypted
+	data = sendEncrypted(t, a, &HandshakeMessage{PubKey: a.pubKey})
+	require.Zero(t, data[3]&0x80)
+	_, err = receiveEncrypted(t, b, data)
+	require.NoError(t, err)
+	require.False(t, a.encrypted())
+
+	data = sendEncrypted(t, b, &HandshakeMessage{PubKey: b.pubKey})
+	require.Zero(t, data[3]&0x80)
+	require.True(t, b.encrypted())
+	_, err = receiveEncrypted(t, a, data)
+	require.NoError(t, err)
+	require.True(t, a.encrypted())
+
+	// Messages after the handshake are encrypted in both directions
+	for i := byte(0); i < 3; i++ {
+		data = sendEncrypted(t, a, NewByteMessage(i))
+		require.NotZero(t, data[3]&0x80)
+		m, err = receiveEncrypted(t, b, data)
+		require.NoError(t, err)
+		require.Equal(t, NewByteMessage(i), m)
+
+		data = sendEncrypted(t, b, NewByteMessage(i+10))
+		require.NotZero(t, data[3]&0x80)
+		m, err = receiveEncrypted(t, a, data)
+		require.NoError(t, err)
+		require.Equal(t, NewByteMessage(i+10), m)
+	}
+
+	// A plaintext message after an encrypted message is rejected
+	data, err = EncodeMessage(NewByteMessage(4))
+	require.NoError(t, err)
+	_, err = receiveEncrypted(t, b, data)
+	require.Equal(t, ErrDisconnectUnexpectedPlaintextMessage, err)
+
+	// A tampered message is rejected
+	data = sendEncrypted(t, a, NewByteMessage(5))
+	data[len(data)-1] ^= 0x01
+	_, err = receiveEncrypted(t, b, data)
+	require.Equal(t, ErrDisconnectDecryptionFailed, err)
+}
+
+func TestConnectionEncryptionReplay(t *testing.T) {
+	setupEncryptionMessages()
+	defer EraseMessages()
+
+	a := newConnectionEncryption()
+	b := newConnectionEncryption()
+
+	_, err := receiveEncrypted(t, b, sendEncrypted(t, a, &HandshakeMessage{PubKey: a.pubKey}))
+	require.NoError(t, err)
+	_, err = receiveEncrypted(t, a, sendEncrypted(t, b, &HandshakeMessage{PubKey: b.pubKey}))
+	require.NoError(t, err)
+
+	// A replayed frame does not decrypt, because the nonce has advanced
+	data := sendEncrypted(t, a, NewByteMessage(1))
+	_, err = receiveEncrypted(t, b, data)
+	require.NoError(t, err)
+	_, err = receiveEncrypted(t, b, data)
+	require.Equal(t, ErrDisconnectDecryptionFailed, err)
+}
+
+func TestConnectionEncryptionNotNegotiated(t *testing.T) {
+	setupEncryptionMessages()
+	defer EraseMessages()
+
+	a := newConnectionEncryption()
+	b := newConnectionEncryption()
+
+	// The peer does not announce a key
+	_, err := receiveEncrypted(t, b, sendEncrypted(t, a, &HandshakeMessage{}))
+	require.NoError(t, err)
+	_, err = receiveEncrypted(t, a, sendEncrypted(t, b, &HandshakeMessage{PubKey: b.pubKey}))
+	require.NoError(t, err)
+
+	require.True(t, a.encrypted())
+	require.False(t, b.encrypted())
+
+	// Encrypted messages are rejected by a connection that did not establish encryption
+	data := sendEncrypted(t, a, NewByteMessage(1))
+	_, err = receiveEncrypted(t, b, data)
+	require.Equal(t, ErrDisconnectUnexpectedEncryptedMessage, err)
+
+	// Connections without encryption only accept plaintext messages
+	var c *connectionEncryption
+	_, err = receiveEncrypted(t, c, data)
+	require.Equal(t, ErrDisconnectUnexpectedEncryptedMessage, err)
+
+	data = sendEncrypted(t, c, NewByteMessage(1))
+	require.Zero(t, data[3]&0x80)
+	m, err := receiveEncrypted(t, c, data)
+	require.NoError(t, err)
+	require.Equal(t, NewByteMessage(1), m)
+	require.False(t, c.encrypted())
+}
+
+func TestConnectionEncryptionInvalidPubKey(t *testing.T) {
+	a := newConnectionEncryption()
+
+	// The peer echoes our own key
+	err := a.receiveHandshake(&HandshakeMessage{PubKey: a.pubKey})
+	require.Equal(t, ErrDisconnectInvalidEncryptionPubKey, err)
+
+	var pk cipher.PubKey
+	pk[0] = 0x05
+	err = a.receiveHandshake(&HandshakeMessage{PubKey: pk})
+	require.Equal(t, ErrDisconnectInvalidEncryptionPubKey, err)
+}
+
+func TestDecodeDataEncrypted(t *testing.T) {
+	maxLen := 64
+
+	cases := []struct {
+		name      string
+		length    uint32
+		encrypted bool
+		err       error
+	}{
+		{
+			name:   "plaintext max length",
+			length: uint32(maxLen),
+		},
+		{
+			name:   "plaintext too long",
+			length: uint32(maxLen + 1),
+			err:    ErrDisconnectInvalidMessageLength,
+		},
+		{
+			name:      "encrypted max length",
+			length:    uint32(maxLen + encryptionOverhead),
+			encrypted: true,
+		},
+		{
+			name:      "encrypted too long",
+			length:    uint32(maxLen + encryptionOverhead + 1),
+			encrypted: true,
+			err:       ErrDisconnectInvalidMessageLength,
+		},
+		{
+			name:      "encrypted too short",
+			length:    uint32(messagePrefixLength + encryptionOverhead - 1),
+			encrypted: true,
+			err:       ErrDisconnectInvalidMessageLength,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			length := tc.length
+			if tc.encrypted {
+				length |= encryptedFrameFlag
+			}
+
+			buf := bytes.NewBuffer(encoder.SerializeUint32(length))
+			buf.Write(make([]byte, tc.length))
+
+			frames, err := decodeData(buf, maxLen)
+			require.Equal(t, tc.err, err)
+			if err != nil {
+				return
+			}
+
+			require.Len(t, frames, 1)
+			require.Equal(t, tc.encrypted, frames[0].encrypted)
+			require.Len(t, frames[0].data, int(tc.length))
+		})
+	}
+}
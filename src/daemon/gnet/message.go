@@ -58,6 +58,17 @@ func NewMessageContext(conn *Connection) *MessageContext {
 	return &MessageContext{ConnID: conn.ID}
 }
 
+// messagePrefixOf returns the prefix of a registered message
+func messagePrefixOf(msg Message) MessagePrefix {
+	return MessageIDMap[reflect.TypeOf(msg).Elem()]
+}
+
+// MessageSize returns the number of bytes an unencrypted message takes on the wire,
+// including the length prefix and the message prefix
+func MessageSize(msg Message) uint64 {
+	return messageLengthPrefixSize + messagePrefixLength + msg.EncodeSize()
+}
+
 // MessageIDMap maps message types to their ids
 var MessageIDMap = make(map[reflect.Type]MessagePrefix)
 
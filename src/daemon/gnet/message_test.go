@@ -129,6 +129,11 @@ func TestMessagePrefixFromString(t *testing.T) {
 	require.Equal(t, MessagePrefixFromString("a"), MessagePrefix{'a', 0x00, 0x00, 0x00})
 }
 
+func TestMessageSize(t *testing.T) {
+	// 4 byte length prefix, 4 byte message prefix and 1 byte body
+	require.Equal(t, uint64(9), MessageSize(NewByteMessage(7)))
+}
+
 /* Helpers */
 
 type Nothing struct{}
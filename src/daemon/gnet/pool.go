@@ -17,10 +17,13 @@ import (
 
 	"github.com/sirupsen/logrus"
 
+	"github.com/skycoin/skycoin/src/cipher"
 	"github.com/skycoin/skycoin/src/cipher/encoder"
 	"github.com/skycoin/skycoin/src/daemon/strand"
 	"github.com/skycoin/skycoin/src/util/elapse"
+	"github.com/skycoin/skycoin/src/util/iputil"
 	"github.com/skycoin/skycoin/src/util/logging"
+	"github.com/skycoin/skycoin/src/util/socks5"
 )
 
 // DisconnectReason is passed to ConnectionPool's DisconnectCallback
@@ -130,6 +133,14 @@ type Config struct {
 	ConnectFailureCallback ConnectFailureCallback
 	// Print debug logs
 	DebugPrint bool
+	// Announce an ephemeral encryption key in the handshake message of each connection,
+	// and encrypt the connection if the peer announces a key too
+	EncryptConnections bool
+	// Address of a SOCKS5 proxy to make outgoing connections through, host:port. Leave empty to connect directly
+	Proxy string
+	// Authenticate each proxied connection with random credentials, so that a proxy
+	// which isolates streams by credentials, such as Tor, uses a separate circuit for each peer
+	ProxyIsolateAuth bool
 	// Default "trusted" peers
 	DefaultConnections []string
 	// Default connections map
@@ -179,10 +190,21 @@ type Connection struct {
 	// Message send queue.
 	WriteQueue chan Message
 	Solicited  bool
+	// Traffic of the connection. Only set in the copies returned by GetConnection and GetConnections
+	Stats NetworkStats
+	// Encryption state, nil if the pool does not encrypt connections
+	encryption *connectionEncryption
+	// Traffic counters, only accessed from the strand
+	stats *trafficCounter
 }
 
 // NewConnection creates a new Connection tied to a ConnectionPool
 func NewConnection(pool *ConnectionPool, id uint64, conn net.Conn, writeQueueSize int, solicited bool) *Connection {
+	var encryption *connectionEncryption
+	if pool != nil && pool.Config.EncryptConnections {
+		encryption = newConnectionEncryption()
+	}
+
 	return &Connection{
 		ID:             id,
 		Conn:           conn,
@@ -192,7 +214,23 @@ func NewConnection(pool *ConnectionPool, id uint64, conn net.Conn, writeQueueSiz
 		LastSent:       Now(),
 		WriteQueue:     make(chan Message, writeQueueSize),
 		Solicited:      solicited,
+		encryption:     encryption,
+		stats:          newTrafficCounter(),
+	}
+}
+
+// EncryptionPubKey returns the ephemeral public key to announce in the connection's handshake message.
+// Returns false if the pool does not encrypt connections.
+func (conn *Connection) EncryptionPubKey() (cipher.PubKey, bool) {
+	if conn.encryption == nil {
+		return cipher.PubKey{}, false
 	}
+	return conn.encryption.pubKey, true
+}
+
+// Encrypted returns true if the messages written to the connection are encrypted
+func (conn *Connection) Encrypted() bool {
+	return conn.encryption.encrypted()
 }
 
 // Addr returns remote address
@@ -240,6 +278,8 @@ type ConnectionPool struct {
 	messageState interface{}
 	// Connection ID counter
 	connID uint64
+	// Traffic counters of all connections, only accessed from the strand
+	stats *trafficCounter
 	// Listening connection
 	listener     net.Listener
 	listenerLock sync.Mutex
@@ -272,6 +312,7 @@ func NewConnectionPool(c Config, state interface{}) (*ConnectionPool, error) {
 		outgoingConnections:        make(map[string]struct{}),
 		SendResults:                make(chan SendResult, c.SendResultsSize),
 		messageState:               state,
+		stats:                      newTrafficCounter(),
 		quit:                       make(chan struct{}),
 		done:                       make(chan struct{}),
 		strandDone:                 make(chan struct{}),
@@ -293,7 +334,7 @@ func (pool *ConnectionPool) Run() error {
 	}()
 
 	// start the connection accept loop
-	addr := fmt.Sprintf("%s:%v", pool.Config.Address, pool.Config.Port)
+	addr := iputil.JoinAddr(pool.Config.Address, pool.Config.Port)
 	logger.Infof("Listening for connections on %s...", addr)
 
 	ln, err := net.Listen("tcp", addr)
@@ -513,7 +554,7 @@ func (pool *ConnectionPool) handleConnection(conn net.Conn, solicited bool) erro
 		return err
 	}
 
-	msgC := make(chan []byte, 32)
+	msgC := make(chan frame, 32)
 
 	type methodErr struct {
 		method string
@@ -551,9 +592,17 @@ func (pool *ConnectionPool) handleConnection(conn net.Conn, solicited bool) erro
 		elapser := elapse.NewElapser(receiveMessageDurationThreshold, logger)
 		defer elapser.CheckForDone()
 
-		for msg := range msgC {
+		for f := range msgC {
 			elapser.Register(fmt.Sprintf("pool.receiveMessage address=%s", addr))
-			if err := pool.receiveMessage(c, msg); err != nil {
+			msg, err := c.encryption.open(f)
+			if err != nil {
+				errC <- methodErr{
+					method: "receiveMessage",
+					err:    err,
+				}
+				return
+			}
+			if err := pool.receiveMessage(c, msg, f.size()); err != nil {
 				errC <- methodErr{
 					method: "receiveMessage",
 					err:    err,
@@ -592,7 +641,7 @@ func (pool *ConnectionPool) handleConnection(conn net.Conn, solicited bool) erro
 	return err
 }
 
-func (pool *ConnectionPool) readLoop(conn *Connection, msgChan chan []byte, qc chan struct{}) error {
+func (pool *ConnectionPool) readLoop(conn *Connection, msgChan chan frame, qc chan struct{}) error {
 	defer close(msgChan)
 	// read data from connection
 	reader := bufio.NewReader(conn.Conn)
@@ -665,14 +714,14 @@ func (pool *ConnectionPool) sendLoop(conn *Connection, timeout time.Duration, ma
 				continue
 			}
 
-			err := sendMessage(conn.Conn, m, timeout, maxMsgLength)
+			n, err := sendMessage(conn.Conn, m, timeout, maxMsgLength, conn.encryption)
 
 			// Update last sent before writing to SendResult,
 			// this allows a write to SendResult to be used as a sync marker,
 			// since no further action in this block will happen after the write.
 			if err == nil {
-				if err := pool.updateLastSent(conn.Addr(), Now()); err != nil {
-					logger.WithField("addr", conn.Addr()).WithError(err).Warning("updateLastSent failed")
+				if err := pool.recordSent(conn.Addr(), messagePrefixOf(m), n, Now()); err != nil {
+					logger.WithField("addr", conn.Addr()).WithError(err).Warning("recordSent failed")
 				}
 			}
 
@@ -708,8 +757,8 @@ func readData(reader io.Reader, buf []byte) ([]byte, error) {
 }
 
 // decode data from buffer.
-func decodeData(buf *bytes.Buffer, maxMsgLength int) ([][]byte, error) {
-	dataArray := [][]byte{}
+func decodeData(buf *bytes.Buffer, maxMsgLength int) ([]frame, error) {
+	frames := []frame{}
 	for buf.Len() > messageLengthPrefixSize {
 		prefix := buf.Bytes()[:messageLengthPrefixSize]
 		// decode message length
@@ -721,39 +770,52 @@ func decodeData(buf *bytes.Buffer, maxMsgLength int) ([][]byte, error) {
 			logger.Panicf("encoder.DeserializeUint32 failed unexpectedly: %v", err)
 		}
 
-		length := int(tmpLength)
+		// The length of an encrypted frame includes the encryption overhead
+		encrypted := tmpLength&encryptedFrameFlag != 0
+		length := int(tmpLength &^ encryptedFrameFlag)
+		minLength := messagePrefixLength
+		maxLength := maxMsgLength
+		if encrypted {
+			minLength += encryptionOverhead
+			maxLength += encryptionOverhead
+		}
 
 		// Disconnect if we received an invalid length
-		if length < messagePrefixLength {
+		if length < minLength {
 			logger.WithFields(logrus.Fields{
 				"length":              length,
 				"messagePrefixLength": messagePrefixLength,
+				"encrypted":           encrypted,
 			}).Warningf("decodeData: length < messagePrefixLength")
-			return [][]byte{}, ErrDisconnectInvalidMessageLength
+			return []frame{}, ErrDisconnectInvalidMessageLength
 		}
 
-		if length > maxMsgLength {
+		if length > maxLength {
 			logger.WithFields(logrus.Fields{
 				"length":       length,
 				"maxMsgLength": maxMsgLength,
+				"encrypted":    encrypted,
 			}).Warning("decodeData: length > maxMsgLength")
-			return [][]byte{}, ErrDisconnectInvalidMessageLength
+			return []frame{}, ErrDisconnectInvalidMessageLength
 		}
 
 		if buf.Len()-messageLengthPrefixSize < length {
-			return [][]byte{}, nil
+			return []frame{}, nil
 		}
 
 		buf.Next(messageLengthPrefixSize) // strip the length prefix
 		data := make([]byte, length)
 		_, err = buf.Read(data)
 		if err != nil {
-			return [][]byte{}, err
+			return []frame{}, err
 		}
 
-		dataArray = append(dataArray, data)
+		frames = append(frames, frame{
+			data:      data,
+			encrypted: encrypted,
+		})
 	}
-	return dataArray, nil
+	return frames, nil
 }
 
 // isConnExist check if the connection of address does exist
@@ -774,31 +836,45 @@ func (pool *ConnectionPool) isMaxOutgoingDefaultConnectionsReached() bool {
 	return len(pool.defaultOutgoingConnections) >= pool.Config.MaxDefaultPeerOutgoingConnections
 }
 
-func (pool *ConnectionPool) updateLastSent(addr string, t time.Time) error {
-	return pool.strand("updateLastSent", func() error {
+// recordSent updates the last sent time and the traffic counters after a message of n bytes is sent
+func (pool *ConnectionPool) recordSent(addr string, prefix MessagePrefix, n int, t time.Time) error {
+	return pool.strand("recordSent", func() error {
+		pool.stats.recordSent(prefix, n, t)
 		if conn, ok := pool.addresses[addr]; ok {
 			conn.LastSent = t
+			conn.stats.recordSent(prefix, n, t)
 		}
 		return nil
 	})
 }
 
-func (pool *ConnectionPool) updateLastRecv(addr string, t time.Time) error {
-	return pool.strand("updateLastRecv", func() error {
+// recordReceived updates the last received time and the traffic counters after a message of n bytes is received
+func (pool *ConnectionPool) recordReceived(addr string, prefix MessagePrefix, n int, t time.Time) error {
+	return pool.strand("recordReceived", func() error {
+		pool.stats.recordReceived(prefix, n, t)
 		if conn, ok := pool.addresses[addr]; ok {
 			conn.LastReceived = t
+			conn.stats.recordReceived(prefix, n, t)
 		}
 		return nil
 	})
 }
 
+// copyConnection returns a copy of a connection with its traffic stats set
+func copyConnection(c *Connection, now time.Time) Connection {
+	cc := *c
+	if c.stats != nil {
+		cc.Stats = c.stats.stats(now)
+	}
+	return cc
+}
+
 // GetConnection returns a connection copy if exist
 func (pool *ConnectionPool) GetConnection(addr string) (*Connection, error) {
 	var conn *Connection
 	if err := pool.strand("GetConnection", func() error {
 		if c, ok := pool.addresses[addr]; ok {
-			// copy connection
-			cc := *c
+			cc := copyConnection(c, Now())
 			conn = &cc
 		}
 		return nil
@@ -809,6 +885,20 @@ func (pool *ConnectionPool) GetConnection(addr string) (*Connection, error) {
 	return conn, nil
 }
 
+// dial makes a TCP connection to an address, through the proxy if one is configured
+func (pool *ConnectionPool) dial(address string) (net.Conn, error) {
+	if pool.Config.Proxy == "" {
+		return net.DialTimeout("tcp", address, pool.Config.DialTimeout)
+	}
+
+	d := socks5.Dialer{
+		ProxyAddr:   pool.Config.Proxy,
+		IsolateAuth: pool.Config.ProxyIsolateAuth,
+		Timeout:     pool.Config.DialTimeout,
+	}
+	return d.Dial("tcp", address)
+}
+
 // Connect to an address
 func (pool *ConnectionPool) Connect(address string) error {
 	if err := pool.strand("canConnect", func() error {
@@ -818,7 +908,7 @@ func (pool *ConnectionPool) Connect(address string) error {
 	}
 
 	logger.WithField("addr", address).Debugf("Making TCP connection")
-	conn, err := net.DialTimeout("tcp", address, pool.Config.DialTimeout)
+	conn, err := pool.dial(address)
 	if err != nil {
 		return err
 	}
@@ -907,8 +997,9 @@ func (pool *ConnectionPool) disconnectAll() {
 func (pool *ConnectionPool) GetConnections() ([]Connection, error) {
 	conns := []Connection{}
 	if err := pool.strand("GetConnections", func() error {
+		now := Now()
 		for _, conn := range pool.pool {
-			conns = append(conns, *conn)
+			conns = append(conns, copyConnection(conn, now))
 		}
 		return nil
 	}); err != nil {
@@ -917,6 +1008,16 @@ func (pool *ConnectionPool) GetConnections() ([]Connection, error) {
 	return conns, nil
 }
 
+// GetStats returns the traffic of all connections since the pool was created
+func (pool *ConnectionPool) GetStats() (NetworkStats, error) {
+	var stats NetworkStats
+	err := pool.strand("GetStats", func() error {
+		stats = pool.stats.stats(Now())
+		return nil
+	})
+	return stats, err
+}
+
 // Size returns the pool size
 func (pool *ConnectionPool) Size() (l int, err error) {
 	err = pool.strand("Size", func() error {
@@ -1003,16 +1104,22 @@ func (pool *ConnectionPool) BroadcastMessage(msg Message, addrs []string) ([]uin
 	return queuedConns, nil
 }
 
-// Unpacks incoming bytes to a Message and calls the message handler.  If
-// the bytes cannot be converted to a Message, the error is returned as the
-// first return value.  Otherwise, error will be nil and DisconnectReason will
-// be the value returned from the message handler.
-func (pool *ConnectionPool) receiveMessage(c *Connection, msg []byte) error {
+// receiveMessage unpacks a decrypted message, which was read from a frame of n bytes,
+// and calls the message handler. If the bytes cannot be converted to a Message,
+// or the message handler fails, the error is returned.
+func (pool *ConnectionPool) receiveMessage(c *Connection, msg []byte, n int) error {
 	m, err := convertToMessage(c.ID, msg, pool.Config.DebugPrint)
 	if err != nil {
 		return err
 	}
-	if err := pool.updateLastRecv(c.Addr(), Now()); err != nil {
+	var prefix MessagePrefix
+	copy(prefix[:], msg)
+	if err := pool.recordReceived(c.Addr(), prefix, n, Now()); err != nil {
+		return err
+	}
+	// The peer's messages after its handshake message may be encrypted,
+	// so the encryption must be established before the next frame is opened
+	if err := c.encryption.receiveHandshake(m); err != nil {
 		return err
 	}
 	return m.Handle(NewMessageContext(c), pool.messageState)
@@ -12,6 +12,7 @@ import (
 
 	"github.com/stretchr/testify/require"
 
+	"github.com/skycoin/skycoin/src/testutil"
 	"github.com/skycoin/skycoin/src/util/logging"
 )
 
@@ -349,6 +350,48 @@ func TestConnect(t *testing.T) {
 	require.Error(t, connectErr)
 }
 
+func TestConnectProxy(t *testing.T) {
+	proxy := testutil.NewSOCKS5Server(t, false, "", "")
+	defer proxy.Close()
+
+	cfg := newTestConfig()
+	cfg.Proxy = proxy.Addr
+	cfg.ProxyIsolateAuth = true
+	p, err := NewConnectionPool(cfg, nil)
+	require.NoError(t, err)
+
+	q := make(chan struct{})
+	go func() {
+		defer close(q)
+		err := p.Run()
+		require.NoError(t, err)
+	}()
+	wait()
+
+	err = p.Connect(addr)
+	require.NoError(t, err)
+	wait()
+
+	requests := proxy.Requests()
+	require.Len(t, requests, 1)
+	require.Equal(t, addr, requests[0].Addr)
+	require.NotEmpty(t, requests[0].Username)
+	require.NotEmpty(t, requests[0].Password)
+
+	// The pool sees the incoming end of the proxied connection too
+	n, err := p.Size()
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+
+	p.Shutdown()
+	<-q
+
+	// Proxy is unreachable, connect should fail
+	proxy.Close()
+	_, err = p.dial(addr)
+	require.Error(t, err)
+}
+
 func TestConnectNoTimeout(t *testing.T) {
 	cfg := newTestConfig()
 	cfg.DialTimeout = 0
@@ -1170,19 +1213,19 @@ func TestPoolReceiveMessage(t *testing.T) {
 	b := make([]byte, 0)
 	b = append(b, BytePrefix[:]...)
 	b = append(b, byte(7))
-	err = p.receiveMessage(c, b)
+	err = p.receiveMessage(c, b, len(b)+4)
 	require.NoError(t, err)
 	require.False(t, c.LastReceived.IsZero())
 
 	// Invalid byte message received
 	b = []byte{1}
-	err = p.receiveMessage(c, b)
+	err = p.receiveMessage(c, b, len(b)+4)
 	require.Error(t, err)
 
 	// Valid message, but handler returns a DisconnectReason
 	b = make([]byte, 0)
 	b = append(b, ErrorPrefix[:]...)
-	err = p.receiveMessage(c, b)
+	err = p.receiveMessage(c, b, len(b)+4)
 	require.Equal(t, err, ErrErrorMessageHandler)
 
 	p.Shutdown()
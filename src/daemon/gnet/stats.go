@@ -0,0 +1,134 @@
+package gnet
+
+import (
+	"time"
+)
+
+const (
+	// rateWindowSeconds is the number of seconds that send and receive rates are averaged over
+	rateWindowSeconds = 60
+)
+
+// TrafficStats counts the messages and bytes sent and received.
+// Bytes are counted as written to and read from the wire, including the length prefix and any encryption overhead.
+type TrafficStats struct {
+	MessagesSent     uint64
+	BytesSent        uint64
+	MessagesReceived uint64
+	BytesReceived    uint64
+}
+
+// NetworkStats is the traffic of a connection or of the whole pool, with a breakdown by message type
+type NetworkStats struct {
+	TrafficStats
+	// Bytes per second sent, averaged over the last minute
+	SendRate float64
+	// Bytes per second received, averaged over the last minute
+	ReceiveRate float64
+	// Traffic by message prefix
+	Messages map[string]TrafficStats
+}
+
+// rateMeter sums a value in one-second buckets over a sliding window
+type rateMeter struct {
+	buckets [rateWindowSeconds]uint64
+	last    int64
+}
+
+// advance clears the buckets of the seconds elapsed since the last update
+func (m *rateMeter) advance(now time.Time) {
+	sec := now.Unix()
+	if sec <= m.last {
+		return
+	}
+
+	if sec-m.last >= rateWindowSeconds {
+		m.buckets = [rateWindowSeconds]uint64{}
+	} else {
+		for i := m.last + 1; i <= sec; i++ {
+			m.buckets[i%rateWindowSeconds] = 0
+		}
+	}
+
+	m.last = sec
+}
+
+func (m *rateMeter) add(n uint64, now time.Time) {
+	m.advance(now)
+	m.buckets[m.last%rateWindowSeconds] += n
+}
+
+// rate returns the average per second over the window
+func (m *rateMeter) rate(now time.Time) float64 {
+	m.advance(now)
+
+	var sum uint64
+	for _, n := range m.buckets {
+		sum += n
+	}
+
+	return float64(sum) / rateWindowSeconds
+}
+
+// trafficCounter records the traffic of a connection or of the whole pool.
+// It is not safe for concurrent use; the pool only accesses it from the strand.
+type trafficCounter struct {
+	total       TrafficStats
+	messages    map[MessagePrefix]*TrafficStats
+	sendRate    rateMeter
+	receiveRate rateMeter
+}
+
+func newTrafficCounter() *trafficCounter {
+	return &trafficCounter{
+		messages: make(map[MessagePrefix]*TrafficStats),
+	}
+}
+
+func (c *trafficCounter) message(prefix MessagePrefix) *TrafficStats {
+	s, ok := c.messages[prefix]
+	if !ok {
+		s = &TrafficStats{}
+		c.messages[prefix] = s
+	}
+	return s
+}
+
+// recordSent records a message of n bytes written to the wire
+func (c *trafficCounter) recordSent(prefix MessagePrefix, n int, now time.Time) {
+	c.total.MessagesSent++
+	c.total.BytesSent += uint64(n)
+
+	s := c.message(prefix)
+	s.MessagesSent++
+	s.BytesSent += uint64(n)
+
+	c.sendRate.add(uint64(n), now)
+}
+
+// recordReceived records a message of n bytes read from the wire
+func (c *trafficCounter) recordReceived(prefix MessagePrefix, n int, now time.Time) {
+	c.total.MessagesReceived++
+	c.total.BytesReceived += uint64(n)
+
+	s := c.message(prefix)
+	s.MessagesReceived++
+	s.BytesReceived += uint64(n)
+
+	c.receiveRate.add(uint64(n), now)
+}
+
+// stats returns a copy of the counters with the rates at now
+func (c *trafficCounter) stats(now time.Time) NetworkStats {
+	messages := make(map[string]TrafficStats, len(c.messages))
+	for prefix, s := range c.messages {
+		messages[msgIDStringSafe(prefix)] = *s
+	}
+
+	return NetworkStats{
+		TrafficStats: c.total,
+		SendRate:     c.sendRate.rate(now),
+		ReceiveRate:  c.receiveRate.rate(now),
+		Messages:     messages,
+	}
+}
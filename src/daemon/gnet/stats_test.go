@@ -0,0 +1,150 @@
+package gnet
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateMeter(t *testing.T) {
+	now := time.Unix(1000000, 0)
+
+	var m rateMeter
+	require.Equal(t, 0.0, m.rate(now))
+
+	m.add(60, now)
+	m.add(60, now.Add(time.Millisecond*500))
+	require.Equal(t, 2.0, m.rate(now))
+
+	// The bytes are still in the window
+	m.add(120, now.Add(time.Second*30))
+	require.Equal(t, 4.0, m.rate(now.Add(time.Second*30)))
+	require.Equal(t, 4.0, m.rate(now.Add(time.Second*59)))
+
+	// The first bucket leaves the window
+	require.Equal(t, 2.0, m.rate(now.Add(time.Second*60)))
+
+	// All buckets leave the window
+	require.Equal(t, 0.0, m.rate(now.Add(time.Hour)))
+
+	// A time before the last update is added to the current bucket
+	m.add(60, now)
+	require.Equal(t, 1.0, m.rate(now.Add(time.Hour)))
+}
+
+func TestTrafficCounter(t *testing.T) {
+	now := time.Unix(1000000, 0)
+	give := MessagePrefixFromString("GIVB")
+	ping := MessagePrefixFromString("PING")
+
+	c := newTrafficCounter()
+	require.Equal(t, NetworkStats{
+		Messages: map[string]TrafficStats{},
+	}, c.stats(now))
+
+	c.recordSent(give, 1000, now)
+	c.recordSent(give, 2000, now)
+	c.recordSent(ping, 60, now)
+	c.recordReceived(ping, 120, now)
+
+	require.Equal(t, NetworkStats{
+		TrafficStats: TrafficStats{
+			MessagesSent:     3,
+			BytesSent:        3060,
+			MessagesReceived: 1,
+			BytesReceived:    120,
+		},
+		SendRate:    51,
+		ReceiveRate: 2,
+		Messages: map[string]TrafficStats{
+			"GIVB": {
+				MessagesSent: 2,
+				BytesSent:    3000,
+			},
+			"PING": {
+				MessagesSent:     1,
+				BytesSent:        60,
+				MessagesReceived: 1,
+				BytesReceived:    120,
+			},
+		},
+	}, c.stats(now))
+
+	// The rates decay, the totals don't
+	s := c.stats(now.Add(time.Minute))
+	require.Equal(t, 0.0, s.SendRate)
+	require.Equal(t, 0.0, s.ReceiveRate)
+	require.Equal(t, uint64(3060), s.BytesSent)
+}
+
+func TestPoolStats(t *testing.T) {
+	resetHandler()
+	EraseMessages()
+	RegisterMessage(BytePrefix, ByteMessage{})
+	VerifyMessages()
+
+	cfg := newTestConfig()
+	p, err := NewConnectionPool(cfg, nil)
+	require.NoError(t, err)
+
+	q := make(chan struct{})
+	go func() {
+		defer close(q)
+		err := p.Run()
+		require.NoError(t, err)
+	}()
+	wait()
+
+	// Connect the pool to itself, so that it sends and receives the message
+	err = p.Connect(addr)
+	require.NoError(t, err)
+	wait()
+
+	err = p.SendMessage(addr, NewByteMessage(7))
+	require.NoError(t, err)
+	wait()
+
+	// 4 byte length prefix, 4 byte message prefix and 1 byte body
+	expectMessages := map[string]TrafficStats{
+		"BYTE": {
+			MessagesSent:     1,
+			BytesSent:        9,
+			MessagesReceived: 1,
+			BytesReceived:    9,
+		},
+	}
+
+	stats, err := p.GetStats()
+	require.NoError(t, err)
+	require.Equal(t, expectMessages["BYTE"], stats.TrafficStats)
+	require.Equal(t, expectMessages, stats.Messages)
+	require.Equal(t, 9.0/rateWindowSeconds, stats.SendRate)
+	require.Equal(t, 9.0/rateWindowSeconds, stats.ReceiveRate)
+
+	conns, err := p.GetConnections()
+	require.NoError(t, err)
+	require.Len(t, conns, 2)
+
+	for _, c := range conns {
+		if c.Solicited {
+			require.Equal(t, TrafficStats{
+				MessagesSent: 1,
+				BytesSent:    9,
+			}, c.Stats.TrafficStats)
+		} else {
+			require.Equal(t, TrafficStats{
+				MessagesReceived: 1,
+				BytesReceived:    9,
+			}, c.Stats.TrafficStats)
+		}
+	}
+
+	c, err := p.GetConnection(addr)
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), c.Stats.MessagesSent)
+	require.Equal(t, uint64(9), c.Stats.Messages["BYTE"].BytesSent)
+
+	p.Shutdown()
+	<-q
+}
@@ -0,0 +1,172 @@
+package daemon
+
+import (
+	"sync"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/daemon/gnet"
+)
+
+// InventoryStats counts the relay traffic saved by not sending peers the transactions and blocks they already know
+type InventoryStats struct {
+	// Transaction hashes not announced to a peer that already knew them
+	SuppressedTxnHashes uint64
+	// Blocks not relayed to a peer that already knew them
+	SuppressedBlocks uint64
+	// Messages not sent, because the peer already knew all of their inventory
+	SuppressedMessages uint64
+	// Estimated number of bytes not sent, including the message prefixes
+	SavedBytes uint64
+}
+
+// knownInventory is a bounded set of the transaction and block hashes known by a peer.
+// When it is full, the oldest hash is evicted.
+type knownInventory struct {
+	gnetID uint64
+	hashes map[cipher.SHA256]struct{}
+	order  []cipher.SHA256
+	next   int
+}
+
+func (k *knownInventory) add(h cipher.SHA256, maxSize int) {
+	if _, ok := k.hashes[h]; ok {
+		return
+	}
+
+	if len(k.order) < maxSize {
+		k.order = append(k.order, h)
+	} else {
+		delete(k.hashes, k.order[k.next])
+		k.order[k.next] = h
+		k.next = (k.next + 1) % maxSize
+	}
+
+	k.hashes[h] = struct{}{}
+}
+
+// peerInventory records the transactions and blocks that each connection is known to have, because
+// the peer announced or sent them to us, or we announced or sent them to the peer.
+// It is used to avoid announcing inventory back to peers that already have it.
+//
+// Inventory is recorded from message handlers and send results, so the state is protected by a mutex.
+type peerInventory struct {
+	sync.Mutex
+	maxSize int
+	peers   map[string]*knownInventory
+	stats   InventoryStats
+}
+
+// newPeerInventory creates a peerInventory that keeps up to maxSize hashes per connection.
+// If maxSize is zero, no inventory is kept and nothing is suppressed.
+func newPeerInventory(maxSize int) *peerInventory {
+	return &peerInventory{
+		maxSize: maxSize,
+		peers:   make(map[string]*knownInventory),
+	}
+}
+
+// add records hashes known by a connection.
+// A reconnected peer with a different gnet ID starts a new set.
+func (inv *peerInventory) add(addr string, gnetID uint64, hashes []cipher.SHA256) {
+	inv.Lock()
+	defer inv.Unlock()
+
+	if inv.maxSize <= 0 || len(hashes) == 0 {
+		return
+	}
+
+	k := inv.peers[addr]
+	if k == nil || k.gnetID != gnetID {
+		k = &knownInventory{
+			gnetID: gnetID,
+			hashes: make(map[cipher.SHA256]struct{}),
+		}
+		inv.peers[addr] = k
+	}
+
+	for _, h := range hashes {
+		k.add(h, inv.maxSize)
+	}
+}
+
+// filterKnown returns the hashes that a connection is not known to have
+func (inv *peerInventory) filterKnown(addr string, gnetID uint64, hashes []cipher.SHA256) []cipher.SHA256 {
+	inv.Lock()
+	defer inv.Unlock()
+
+	k := inv.peers[addr]
+	if k == nil || k.gnetID != gnetID {
+		return hashes
+	}
+
+	unknown := make([]cipher.SHA256, 0, len(hashes))
+	for _, h := range hashes {
+		if _, ok := k.hashes[h]; !ok {
+			unknown = append(unknown, h)
+		}
+	}
+
+	return unknown
+}
+
+// knows returns true if a connection is known to have the hash
+func (inv *peerInventory) knows(addr string, gnetID uint64, hash cipher.SHA256) bool {
+	return len(inv.filterKnown(addr, gnetID, []cipher.SHA256{hash})) == 0
+}
+
+// removePeer forgets the inventory of a disconnected peer
+func (inv *peerInventory) removePeer(addr string, gnetID uint64) {
+	inv.Lock()
+	defer inv.Unlock()
+
+	if k := inv.peers[addr]; k != nil && k.gnetID == gnetID {
+		delete(inv.peers, addr)
+	}
+}
+
+// recordSuppressedTxns records transaction hashes left out of announcements, and the number of
+// announcement messages that were not sent because all of their hashes were left out
+func (inv *peerInventory) recordSuppressedTxns(hashes, messages int, savedBytes uint64) {
+	inv.Lock()
+	defer inv.Unlock()
+
+	inv.stats.SuppressedTxnHashes += uint64(hashes)
+	inv.stats.SuppressedMessages += uint64(messages)
+	inv.stats.SavedBytes += savedBytes
+}
+
+// recordSuppressedBlock records a block message that was not sent
+func (inv *peerInventory) recordSuppressedBlock(savedBytes uint64) {
+	inv.Lock()
+	defer inv.Unlock()
+
+	inv.stats.SuppressedBlocks++
+	inv.stats.SuppressedMessages++
+	inv.stats.SavedBytes += savedBytes
+}
+
+// getStats returns the counts of suppressed inventory
+func (inv *peerInventory) getStats() InventoryStats {
+	inv.Lock()
+	defer inv.Unlock()
+
+	return inv.stats
+}
+
+// inventoryOf returns the transaction and block hashes carried by a message
+func inventoryOf(msg gnet.Message) []cipher.SHA256 {
+	switch m := msg.(type) {
+	case SendingTxnsMessage:
+		return m.GetFiltered()
+	case *GiveBlocksMessage:
+		hashes := make([]cipher.SHA256, len(m.Blocks))
+		for i, b := range m.Blocks {
+			hashes[i] = b.HashHeader()
+		}
+		return hashes
+	case *CompactBlockMessage:
+		return []cipher.SHA256{m.Head.Hash()}
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,187 @@
+package daemon
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/daemon/gnet"
+)
+
+func TestPeerInventory(t *testing.T) {
+	inv := newPeerInventory(3)
+	hashes := makeTxnRelayTestHashes(5)
+	addr := "1.1.1.1:6000"
+
+	// Nothing is known about a peer without inventory
+	require.Equal(t, hashes, inv.filterKnown(addr, 1, hashes))
+	require.False(t, inv.knows(addr, 1, hashes[0]))
+
+	inv.add(addr, 1, hashes[:2])
+	require.True(t, inv.knows(addr, 1, hashes[0]))
+	require.Equal(t, hashes[2:], inv.filterKnown(addr, 1, hashes))
+
+	// Another connection on the same address has its own inventory
+	require.Equal(t, hashes, inv.filterKnown(addr, 2, hashes))
+
+	// Adding a known hash does not evict anything
+	inv.add(addr, 1, hashes[:1])
+	require.Equal(t, hashes[2:], inv.filterKnown(addr, 1, hashes))
+
+	// When the set is full, the oldest hashes are evicted
+	inv.add(addr, 1, hashes[2:4])
+	require.Equal(t, []cipher.SHA256{hashes[0], hashes[4]}, inv.filterKnown(addr, 1, hashes))
+	inv.add(addr, 1, hashes[4:])
+	require.Equal(t, hashes[:2], inv.filterKnown(addr, 1, hashes))
+
+	// A reconnected peer with a different gnet ID starts a new set
+	inv.add(addr, 2, hashes[:1])
+	require.Equal(t, hashes[1:], inv.filterKnown(addr, 2, hashes))
+	require.Equal(t, hashes, inv.filterKnown(addr, 1, hashes))
+
+	// The inventory of a disconnected peer is forgotten
+	inv.removePeer(addr, 1)
+	require.True(t, inv.knows(addr, 2, hashes[0]))
+	inv.removePeer(addr, 2)
+	require.Empty(t, inv.peers)
+
+	// Without a size, nothing is recorded
+	inv = newPeerInventory(0)
+	inv.add(addr, 1, hashes)
+	require.Equal(t, hashes, inv.filterKnown(addr, 1, hashes))
+
+	inv.recordSuppressedTxns(3, 1, 100)
+	inv.recordSuppressedTxns(2, 0, 64)
+	inv.recordSuppressedBlock(50)
+	require.Equal(t, InventoryStats{
+		SuppressedTxnHashes: 5,
+		SuppressedBlocks:    1,
+		SuppressedMessages:  2,
+		SavedBytes:          214,
+	}, inv.getStats())
+}
+
+func TestInventoryOf(t *testing.T) {
+	txns := []coin.Transaction{{Length: 1}, {Length: 2}}
+	blocks := []coin.SignedBlock{
+		{
+			Block: coin.Block{
+				Head: coin.BlockHeader{
+					BkSeq: 1,
+				},
+			},
+		},
+		{
+			Block: coin.Block{
+				Head: coin.BlockHeader{
+					BkSeq: 2,
+				},
+			},
+		},
+	}
+
+	hashes := makeTxnRelayTestHashes(2)
+
+	require.Equal(t, hashes, inventoryOf(&AnnounceTxnsMessage{Transactions: hashes}))
+	require.Equal(t, []cipher.SHA256{txns[0].Hash(), txns[1].Hash()}, inventoryOf(&GiveTxnsMessage{Transactions: txns}))
+	require.Equal(t, []cipher.SHA256{blocks[0].HashHeader(), blocks[1].HashHeader()}, inventoryOf(&GiveBlocksMessage{Blocks: blocks}))
+	require.Equal(t, []cipher.SHA256{blocks[1].HashHeader()}, inventoryOf(NewCompactBlockMessage(blocks[1])))
+	require.Empty(t, inventoryOf(NewAnnounceBlocksMessage(2)))
+}
+
+func TestDaemonInventorySuppression(t *testing.T) {
+	d, peers, teardown := setupTxnRelayTest(t, 50877, 50878)
+	defer teardown()
+
+	hashes := makeTxnRelayTestHashes(3)
+	conn0 := d.connections.get(peers[0].addr)
+	conn1 := d.connections.get(peers[1].addr)
+
+	// The first peer announced a transaction to us, so it is not announced back to it
+	d.recordPeerInventory(conn0.Addr, conn0.gnetID, hashes[:1])
+	require.NoError(t, d.announceTxnHashes(hashes[:2]))
+
+	m := peers[0].receive(t)
+	require.IsType(t, &AnnounceTxnsMessage{}, m)
+	require.Equal(t, hashes[1:2], m.(*AnnounceTxnsMessage).Transactions)
+
+	m = peers[1].receive(t)
+	require.IsType(t, &AnnounceTxnsMessage{}, m)
+	require.Equal(t, hashes[:2], m.(*AnnounceTxnsMessage).Transactions)
+
+	require.Equal(t, InventoryStats{
+		SuppressedTxnHashes: 1,
+		SavedBytes:          32,
+	}, d.GetInventoryStats())
+
+	// Peers know the transactions that were sent to them
+	d.announcedTxns = newAnnouncedTxnsCache()
+	d.handleMessageSendResult(gnet.SendResult{
+		Addr:    conn0.Addr,
+		Message: m,
+	})
+	d.handleMessageSendResult(gnet.SendResult{
+		Addr:    conn1.Addr,
+		Message: m,
+	})
+
+	// No message is sent to a peer that knows all of the transactions
+	require.NoError(t, d.announceTxnHashes(hashes))
+	for _, p := range peers {
+		m := p.receive(t)
+		require.IsType(t, &AnnounceTxnsMessage{}, m)
+		require.Equal(t, hashes[2:], m.(*AnnounceTxnsMessage).Transactions)
+	}
+
+	require.NoError(t, d.announceTxnHashes(hashes[:2]))
+	for _, p := range peers {
+		p.requireNoMessage(t)
+	}
+
+	stats := d.GetInventoryStats()
+	require.Equal(t, uint64(9), stats.SuppressedTxnHashes)
+	require.Equal(t, uint64(2), stats.SuppressedMessages)
+	require.Equal(t, 32*5+2*gnet.MessageSize(NewAnnounceTxnsMessage(hashes[:2], d.config.MaxOutgoingMessageLength)), stats.SavedBytes)
+
+	// Queued announcements leave out the transactions the peer learned about while they were queued
+	require.NoError(t, d.announceRelayedTxns(hashes[2:]))
+	d.recordPeerInventory(conn1.Addr, conn1.gnetID, hashes[2:])
+	for _, q := range d.txnRelay.queues {
+		q.sendAt = time.Time{}
+	}
+	d.sendTxnAnnouncements()
+
+	m = peers[0].receive(t)
+	require.IsType(t, &AnnounceTxnsMessage{}, m)
+	require.Equal(t, hashes[2:], m.(*AnnounceTxnsMessage).Transactions)
+	peers[1].requireNoMessage(t)
+	require.Equal(t, uint64(3), d.GetInventoryStats().SuppressedMessages)
+
+	// Blocks are not relayed to peers that sent them to us
+	sb := coin.SignedBlock{
+		Block: coin.Block{
+			Head: coin.BlockHeader{
+				BkSeq: 1,
+			},
+		},
+	}
+	d.recordPeerInventory(conn1.Addr, conn1.gnetID, inventoryOf(&GiveBlocksMessage{Blocks: []coin.SignedBlock{sb}}))
+
+	require.NoError(t, d.broadcastCompactBlock(sb, NewAnnounceBlocksMessage(1)))
+	m = peers[0].receive(t)
+	require.IsType(t, &AnnounceBlocksMessage{}, m)
+	require.Equal(t, uint64(1), m.(*AnnounceBlocksMessage).MaxBkSeq)
+	peers[1].requireNoMessage(t)
+	require.Equal(t, uint64(1), d.GetInventoryStats().SuppressedBlocks)
+
+	// Nothing is sent if every peer has the block, which is not an error
+	d.recordPeerInventory(conn0.Addr, conn0.gnetID, []cipher.SHA256{sb.HashHeader()})
+	require.NoError(t, d.broadcastCompactBlock(sb, NewAnnounceBlocksMessage(1)))
+	for _, p := range peers {
+		p.requireNoMessage(t)
+	}
+	require.Equal(t, uint64(3), d.GetInventoryStats().SuppressedBlocks)
+}
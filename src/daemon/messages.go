@@ -17,6 +17,8 @@ import (
 	"github.com/skycoin/skycoin/src/params"
 	"github.com/skycoin/skycoin/src/util/iputil"
 	"github.com/skycoin/skycoin/src/util/useragent"
+	"github.com/skycoin/skycoin/src/visor"
+	"github.com/skycoin/skycoin/src/visor/blockdb"
 )
 
 // Message represent a packet to be serialized over the network by
@@ -46,14 +48,19 @@ func NewMessageConfig(prefix string, m interface{}) MessageConfig {
 
 //go:generate skyencoder -unexported -struct IntroductionMessage
 //go:generate skyencoder -unexported -struct GivePeersMessage
+//go:generate skyencoder -unexported -struct GivePeersV2Message
 //go:generate skyencoder -unexported -struct GetBlocksMessage
 //go:generate skyencoder -unexported -struct GiveBlocksMessage
 //go:generate skyencoder -unexported -struct AnnounceBlocksMessage
+//go:generate skyencoder -unexported -struct CompactBlockMessage
+//go:generate skyencoder -unexported -struct GetBlockTxnsMessage
+//go:generate skyencoder -unexported -struct GiveBlockTxnsMessage
 //go:generate skyencoder -unexported -struct GetTxnsMessage
 //go:generate skyencoder -unexported -struct GiveTxnsMessage
 //go:generate skyencoder -unexported -struct AnnounceTxnsMessage
 //go:generate skyencoder -unexported -struct DisconnectMessage
 //go:generate skyencoder -unexported -struct IPAddr
+//go:generate skyencoder -unexported -struct PeerAddr
 //go:generate skyencoder -unexported -output-path . -package daemon -struct SignedBlock github.com/skycoin/skycoin/src/coin
 //go:generate skyencoder -unexported -output-path . -package daemon -struct Transaction github.com/skycoin/skycoin/src/coin
 
@@ -72,6 +79,10 @@ func getMessageConfigs() []MessageConfig {
 		NewMessageConfig("GIVT", GiveTxnsMessage{}),
 		NewMessageConfig("ANNT", AnnounceTxnsMessage{}),
 		NewMessageConfig("DISC", DisconnectMessage{}),
+		NewMessageConfig("GVP2", GivePeersV2Message{}),
+		NewMessageConfig("CMPB", CompactBlockMessage{}),
+		NewMessageConfig("GTBT", GetBlockTxnsMessage{}),
+		NewMessageConfig("GVBT", GiveBlockTxnsMessage{}),
 	}
 }
 
@@ -108,7 +119,11 @@ func NewMessages(c MessagesConfig) *Messages {
 	}
 }
 
-// IPAddr compact representation of IP:Port
+// errIPv6Address is returned by NewIPAddr for IPv6 addresses
+var errIPv6Address = errors.New("Ignoring IPv6 address")
+
+// IPAddr compact representation of an IPv4 IP:Port.
+// IPv6 addresses can't be represented; PeerAddr is used for peers that support GivePeersV2Message.
 type IPAddr struct {
 	IP   uint32
 	Port uint16
@@ -121,10 +136,9 @@ func NewIPAddr(addr string) (ipaddr IPAddr, err error) {
 		return
 	}
 
-	// TODO -- support ipv6
 	ipb := net.ParseIP(ips).To4()
 	if ipb == nil {
-		err = errors.New("Ignoring IPv6 address")
+		err = errIPv6Address
 		return
 	}
 
@@ -141,6 +155,36 @@ func (ipa IPAddr) String() string {
 	return fmt.Sprintf("%s:%d", net.IP(ipb).String(), ipa.Port)
 }
 
+// PeerAddr compact representation of an IPv4 or IPv6 IP:Port.
+// IPv4 addresses are stored in their IPv4-mapped IPv6 form (::ffff:a.b.c.d).
+type PeerAddr struct {
+	IP   [16]byte
+	Port uint16
+}
+
+// NewPeerAddr returns a PeerAddr from an ip:port string.
+func NewPeerAddr(addr string) (PeerAddr, error) {
+	ips, port, err := iputil.SplitAddr(addr)
+	if err != nil {
+		return PeerAddr{}, err
+	}
+
+	ip := net.ParseIP(ips)
+	if ip == nil {
+		return PeerAddr{}, errors.New("Invalid IP address")
+	}
+
+	var peerAddr PeerAddr
+	copy(peerAddr.IP[:], ip.To16())
+	peerAddr.Port = port
+	return peerAddr, nil
+}
+
+// String returns PeerAddr as "ip:port" for IPv4 addresses and "[ip]:port" for IPv6 addresses
+func (pa PeerAddr) String() string {
+	return iputil.JoinAddr(net.IP(pa.IP[:]).String(), pa.Port)
+}
+
 // asyncMessage messages that perform an action when received must implement this interface.
 // process() is called after the message is pulled off of messageEvent channel.
 // Messages should place themselves on the messageEvent channel in their
@@ -207,7 +251,10 @@ func NewGivePeersMessage(peers []pex.Peer, maxMsgLength uint64) *GivePeersMessag
 	ipaddrs := make([]IPAddr, 0, len(peers))
 	for _, ps := range peers {
 		ipaddr, err := NewIPAddr(ps.Addr)
-		if err != nil {
+		if err == errIPv6Address {
+			// Peers that don't support GivePeersV2Message can't receive IPv6 addresses
+			continue
+		} else if err != nil {
 			logger.WithError(err).WithField("addr", ps.Addr).Warning("GivePeersMessage skipping invalid address")
 			continue
 		}
@@ -293,12 +340,15 @@ func (gpm *GivePeersMessage) Handle(mc *gnet.MessageContext, daemon interface{})
 
 // process Notifies the Pex instance that peers were received
 func (gpm *GivePeersMessage) process(d daemoner) {
+	addReceivedPeers(d, gpm.c, gpm.GetPeers())
+}
+
+// addReceivedPeers adds peers received via GivePeersMessage or GivePeersV2Message to the Pex
+func addReceivedPeers(d daemoner, c *gnet.MessageContext, peers []string) {
 	if d.pexConfig().Disabled {
 		return
 	}
 
-	peers := gpm.GetPeers()
-
 	if len(peers) == 0 {
 		return
 	}
@@ -314,13 +364,118 @@ func (gpm *GivePeersMessage) process(d daemoner) {
 	}
 
 	logger.WithFields(logrus.Fields{
-		"addr":   gpm.c.Addr,
-		"gnetID": gpm.c.ConnID,
+		"addr":   c.Addr,
+		"gnetID": c.ConnID,
 		"peers":  peersStr,
 		"count":  len(peers),
 	}).Debug("Received peers via PEX")
 
-	d.addPeers(peers)
+	d.addPeers(peers, c.Addr)
+}
+
+// GivePeersV2Message sent in response to GetPeersMessage to peers with a protocol version
+// of at least givePeersV2ProtocolVersion. Unlike GivePeersMessage, it can carry IPv6 addresses.
+type GivePeersV2Message struct {
+	Peers []PeerAddr           `enc:",maxlen=512"`
+	c     *gnet.MessageContext `enc:"-"`
+}
+
+// NewGivePeersV2Message []*pex.Peer is converted to []PeerAddr for binary transmission
+// If the size of the message would exceed maxMsgLength, the PeerAddr slice is truncated.
+func NewGivePeersV2Message(peers []pex.Peer, maxMsgLength uint64) *GivePeersV2Message {
+	if len(peers) > 512 {
+		peers = peers[:512]
+	}
+
+	peerAddrs := make([]PeerAddr, 0, len(peers))
+	for _, ps := range peers {
+		peerAddr, err := NewPeerAddr(ps.Addr)
+		if err != nil {
+			logger.WithError(err).WithField("addr", ps.Addr).Warning("GivePeersV2Message skipping invalid address")
+			continue
+		}
+		peerAddrs = append(peerAddrs, peerAddr)
+	}
+
+	m := &GivePeersV2Message{
+		Peers: peerAddrs,
+	}
+	truncateGivePeersV2Message(m, maxMsgLength)
+	return m
+}
+
+// truncateGivePeersV2Message truncates the peers in GivePeersV2Message to fit inside of MaxOutgoingMessageLength
+func truncateGivePeersV2Message(m *GivePeersV2Message, maxMsgLength uint64) {
+	// The message length will include a 4 byte message type prefix.
+	// Panic if the prefix can't fit, otherwise we can't adjust the uint64 safely
+	if maxMsgLength < 4 {
+		logger.Panic("maxMsgLength must be >= 4")
+	}
+
+	maxMsgLength -= 4
+
+	// Measure the current message size, if it fits, return
+	n := m.EncodeSize()
+	if n <= maxMsgLength {
+		return
+	}
+
+	// Measure the size of an empty message
+	var mm GivePeersV2Message
+	size := mm.EncodeSize()
+
+	// Measure the size of the peers, advancing the slice index until it reaches capacity
+	index := -1
+	for i, p := range m.Peers {
+		x := encodeSizePeerAddr(&p)
+		if size+x > maxMsgLength {
+			break
+		}
+		size += x
+		index = i
+	}
+
+	m.Peers = m.Peers[:index+1]
+
+	if len(m.Peers) == 0 {
+		logger.Critical().Error("truncateGivePeersV2Message truncated peers to an empty slice")
+	}
+}
+
+// EncodeSize implements gnet.Serializer
+func (gpm *GivePeersV2Message) EncodeSize() uint64 {
+	return encodeSizeGivePeersV2Message(gpm)
+}
+
+// Encode implements gnet.Serializer
+func (gpm *GivePeersV2Message) Encode(buf []byte) error {
+	return encodeGivePeersV2MessageToBuffer(buf, gpm)
+}
+
+// Decode implements gnet.Serializer
+func (gpm *GivePeersV2Message) Decode(buf []byte) (uint64, error) {
+	return decodeGivePeersV2Message(buf, gpm)
+}
+
+// GetPeers returns the peers contained in the message as an array of "ip:port"
+// or "[ip]:port" strings.
+func (gpm *GivePeersV2Message) GetPeers() []string {
+	peers := make([]string, len(gpm.Peers))
+	for i, peerAddr := range gpm.Peers {
+		peers[i] = peerAddr.String()
+	}
+	return peers
+}
+
+// Handle handle message
+func (gpm *GivePeersV2Message) Handle(mc *gnet.MessageContext, daemon interface{}) error {
+	gpm.c = mc
+	return daemon.(daemoner).recordMessageEvent(gpm, mc)
+}
+
+// process Notifies the Pex instance that peers were received
+func (gpm *GivePeersV2Message) process(d daemoner) {
+	addReceivedPeers(d, gpm.c, gpm.GetPeers())
 }
 
 // IntroductionMessage is sent on first connect by both parties
@@ -328,6 +483,8 @@ type IntroductionMessage struct {
 	c                    *gnet.MessageContext `enc:"-"`
 	userAgent            useragent.Data       `enc:"-"`
 	unconfirmedVerifyTxn params.VerifyTxn     `enc:"-"`
+	encrypted            bool                 `enc:"-"`
+	capabilities         uint32               `enc:"-"`
 
 	// Mirror is a random value generated on client startup that is used to identify self-connections
 	Mirror uint32
@@ -346,16 +503,36 @@ type IntroductionMessage struct {
 	// MaxTxnSize          uint32 // max txn size for announced txns
 	// MaxDropletPrecision uint8 // maximum number of decimal places for announced txns
 	// UserAgent           string `enc:",maxlen=256"`
+	// Capabilities        uint32 // optional, bitmask of the optional protocol features supported by the peer
+	// EncryptionPubkey    cipher.Pubkey // optional, ephemeral connection encryption pubkey. Requires Capabilities
 	Extra []byte `enc:",omitempty"`
 }
 
+const (
+	// capabilityCompactBlocks the peer accepts CompactBlockMessage
+	capabilityCompactBlocks uint32 = 1 << 0
+	// capabilityPruned the peer only has the bodies of the most recent PrunedPeerKeepBlocks blocks
+	capabilityPruned uint32 = 1 << 1
+)
+
+// PrunedPeerKeepBlocks is the minimum number of recent block bodies kept by a peer that announces capabilityPruned.
+// Older blocks are not requested from pruned peers.
+const PrunedPeerKeepBlocks uint64 = 288
+
 // NewIntroductionMessage creates introduction message
-func NewIntroductionMessage(mirror uint32, version int32, port uint16, pubkey cipher.PubKey, userAgent string, verifyParams params.VerifyTxn) *IntroductionMessage {
+// If encryptionPubKey is not empty, it is announced to the peer to encrypt the connection.
+func NewIntroductionMessage(mirror uint32, version int32, port uint16, pubkey cipher.PubKey, userAgent string, verifyParams params.VerifyTxn, capabilities uint32, encryptionPubKey cipher.PubKey) *IntroductionMessage {
+	extra := newIntroductionMessageExtra(pubkey, userAgent, verifyParams)
+	extra = append(extra, encoder.SerializeUint32(capabilities)...)
+	if encryptionPubKey != (cipher.PubKey{}) {
+		extra = append(extra, encryptionPubKey[:]...)
+	}
+
 	return &IntroductionMessage{
 		Mirror:          mirror,
 		ProtocolVersion: version,
 		ListenPort:      port,
-		Extra:           newIntroductionMessageExtra(pubkey, userAgent, verifyParams),
+		Extra:           extra,
 	}
 }
 
@@ -410,6 +587,47 @@ func (intro *IntroductionMessage) Handle(mc *gnet.MessageContext, daemon interfa
 	return daemon.(daemoner).recordMessageEvent(intro, mc)
 }
 
+// optionalExtra returns the optional fields that follow the user agent in the Extra field
+func (intro *IntroductionMessage) optionalExtra() []byte {
+	// The blockchain pubkey and the unconfirmed transaction verification params precede the user agent
+	i := len(cipher.PubKey{}) + 9
+	if len(intro.Extra) < i {
+		return nil
+	}
+
+	_, n, err := encoder.DeserializeString(intro.Extra[i:], useragent.MaxLen)
+	if err != nil {
+		return nil
+	}
+
+	return intro.Extra[i+int(n):]
+}
+
+// Capabilities returns the capabilities announced by the peer, if any.
+// They follow the user agent in the Extra field.
+func (intro *IntroductionMessage) Capabilities() (uint32, bool) {
+	extra := intro.optionalExtra()
+	if len(extra) < 4 {
+		return 0, false
+	}
+
+	return binary.LittleEndian.Uint32(extra[:4]), true
+}
+
+// EncryptionPubKey returns the ephemeral connection encryption pubkey announced by the peer, if any.
+// It follows the capabilities in the Extra field. Implements gnet.EncryptionHandshake.
+func (intro *IntroductionMessage) EncryptionPubKey() (cipher.PubKey, bool) {
+	var pubKey cipher.PubKey
+
+	extra := intro.optionalExtra()
+	if len(extra) < 4+len(pubKey) {
+		return cipher.PubKey{}, false
+	}
+
+	copy(pubKey[:], extra[4:4+len(pubKey)])
+	return pubKey, true
+}
+
 // process an event queued by Handle()
 func (intro *IntroductionMessage) process(d daemoner) {
 	addr := intro.c.Addr
@@ -429,7 +647,8 @@ func (intro *IntroductionMessage) process(d daemoner) {
 		return
 	}
 
-	if _, err := d.connectionIntroduced(addr, intro.c.ConnID, intro); err != nil {
+	c, err := d.connectionIntroduced(addr, intro.c.ConnID, intro)
+	if err != nil {
 		logger.WithError(err).WithFields(fields).Warning("connectionIntroduced failed")
 		var reason gnet.DisconnectReason
 		switch err {
@@ -463,6 +682,15 @@ func (intro *IntroductionMessage) process(d daemoner) {
 		return
 	}
 
+	// A feeler connection has served its purpose once the peer has introduced itself
+	if c.Feeler {
+		logger.WithFields(fields).Debug("Disconnecting feeler connection")
+		if err := d.Disconnect(addr, ErrDisconnectFeelerDone); err != nil {
+			logger.WithError(err).WithFields(fields).Warning("Disconnect")
+		}
+		return
+	}
+
 	// Request blocks immediately after they're confirmed
 	if err := d.requestBlocksFromAddr(addr); err != nil {
 		logger.WithError(err).WithFields(fields).Warning("requestBlocksFromAddr")
@@ -564,6 +792,18 @@ func (intro *IntroductionMessage) verify(d daemoner) error {
 			logger.WithError(err).WithFields(fields).WithField("userAgent", userAgent).Warning("User agent is invalid")
 			return ErrDisconnectInvalidUserAgent
 		}
+
+		intro.capabilities, _ = intro.Capabilities()
+	}
+
+	// The connection is encrypted if both peers announced an encryption pubkey
+	if dc.EncryptConnections {
+		if _, ok := intro.EncryptionPubKey(); ok {
+			intro.encrypted = true
+		} else if !dc.AllowUnencryptedPeers {
+			logger.WithFields(fields).Info("Peer does not support connection encryption")
+			return ErrDisconnectEncryptionRequired
+		}
 	}
 
 	return nil
@@ -762,6 +1002,15 @@ func (gbm *GetBlocksMessage) process(d daemoner) {
 	// Fetch and return signed blocks since LastBlock
 	blocks, err := d.getSignedBlocksSince(gbm.LastBlock, requestedBlocks)
 	if err != nil {
+		if visor.IsErrPruned(err) || visor.IsErrBackfilling(err) {
+			// The peer can only execute the blocks that follow its last block, so the blocks
+			// after the unavailable first block would be of no use to it
+			logger.WithFields(fields).WithError(err).WithFields(logrus.Fields{
+				"lastBlock":       gbm.LastBlock,
+				"requestedBlocks": requestedBlocks,
+			}).Info("GetBlocksMessage: not replying, the requested blocks are not available on this node")
+			return
+		}
 		logger.WithFields(fields).WithError(err).Error("getSignedBlocksSince failed")
 		return
 	}
@@ -867,10 +1116,33 @@ func (m *GiveBlocksMessage) process(d daemoner) {
 		return
 	}
 
-	// These DB queries are not performed in a transaction for performance reasons.
-	// It is not necessary that the blocks be executed together in a single transaction.
+	// The peer has these blocks, don't relay them back to it
+	d.recordPeerInventory(m.c.Addr, m.c.ConnID, inventoryOf(m))
+
+	// The blocks may answer the request for the blocks that are missing after starting from a snapshot
+	if ok, err := d.receiveBackfillBlocks(m.c.Addr, m.c.ConnID, m.Blocks); ok {
+		if err != nil {
+			logger.WithError(err).WithFields(logrus.Fields{
+				"addr":   m.c.Addr,
+				"gnetID": m.c.ConnID,
+			}).Warning("d.receiveBackfillBlocks failed")
+		}
+		return
+	}
+
+	// The blocks may answer any of the block requests sent to this peer, and may arrive out of order.
+	// They are buffered until the blocks before them are received, then executed in order.
+	executeReceivedBlocks(d, m.c, m.Blocks)
+}
+
+// executeReceivedBlocks executes the blocks received from a peer, requests more blocks
+// and relays the new head block to peers
+func executeReceivedBlocks(d daemoner, c *gnet.MessageContext, blocks []coin.SignedBlock) {
+	fields := logrus.Fields{
+		"addr":   c.Addr,
+		"gnetID": c.ConnID,
+	}
 
-	processed := 0
 	maxSeq, ok, err := d.headBkSeq()
 	if err != nil {
 		logger.WithError(err).Error("d.headBkSeq failed")
@@ -881,28 +1153,17 @@ func (m *GiveBlocksMessage) process(d daemoner) {
 		return
 	}
 
-	for _, b := range m.Blocks {
-		// To minimize waste when receiving multiple responses from peers
-		// we only break out of the loop if the block itself is invalid.
-		// E.g. if we request 20 blocks since 0 from 2 peers, and one peer
-		// replies with 15 and the other 20, if we did not do this check and
-		// the reply with 15 was received first, we would toss the one with 20
-		// even though we could process it at the time.
-		if b.Seq() <= maxSeq {
-			continue
-		}
+	processed, err := d.receiveBlocks(c.Addr, c.ConnID, blocks)
+	if err != nil {
+		logger.WithError(err).WithFields(fields).Error("d.receiveBlocks failed")
+		return
+	}
 
-		err := d.executeSignedBlock(b)
-		if err == nil {
-			logger.Critical().WithField("seq", b.Block.Head.BkSeq).Info("Added new block")
-			processed++
-		} else {
-			logger.Critical().WithError(err).WithField("seq", b.Block.Head.BkSeq).Error("Failed to execute received block")
-			// Blocks must be received in order, so if one fails its assumed
-			// the rest are failing
-			break
-		}
+	// Request more blocks, since the peer has free request slots now
+	if err := d.requestBlocks(); err != nil {
+		logger.WithError(err).Warning("requestBlocks failed")
 	}
+
 	if processed == 0 {
 		return
 	}
@@ -924,15 +1185,8 @@ func (m *GiveBlocksMessage) process(d daemoner) {
 	}
 
 	// Announce our new blocks to peers
-	abm := NewAnnounceBlocksMessage(headBkSeq)
-	if _, err := d.broadcastMessage(abm); err != nil {
-		logger.WithError(err).Warning("Broadcast AnnounceBlocksMessage failed")
-	}
-
-	// Request more blocks
-	gbm := NewGetBlocksMessage(headBkSeq, d.DaemonConfig().GetBlocksRequestCount)
-	if _, err := d.broadcastMessage(gbm); err != nil {
-		logger.WithError(err).Warning("Broadcast GetBlocksMessage failed")
+	if err := d.relayHeadBlock(); err != nil {
+		logger.WithError(err).Warning("relayHeadBlock failed")
 	}
 }
 
@@ -992,18 +1246,322 @@ func (abm *AnnounceBlocksMessage) process(d daemoner) {
 		return
 	}
 
+	// Record this as this peer's highest block
+	d.recordPeerHeight(abm.c.Addr, abm.c.ConnID, abm.MaxBkSeq)
+
 	if headBkSeq >= abm.MaxBkSeq {
 		return
 	}
 
-	// TODO: Should this be block get request for current sequence?
-	// If client is not caught up, won't attempt to get block
-	m := NewGetBlocksMessage(headBkSeq, d.DaemonConfig().GetBlocksRequestCount)
-	if err := d.sendMessage(abm.c.Addr, m); err != nil {
-		logger.WithError(err).WithFields(fields).Error("Send GetBlocksMessage")
+	if err := d.requestBlocks(); err != nil {
+		logger.WithError(err).WithFields(fields).Warning("requestBlocks failed")
+	}
+}
+
+// CompactBlockMessage sends a new block to a peer that announced capabilityCompactBlocks.
+// It carries the block header, the block signature and a short ID of each transaction in the block.
+// The peer rebuilds the block from its unconfirmed transactions and requests the transactions
+// that it doesn't have with GetBlockTxnsMessage. Like AnnounceBlocksMessage, it also tells the peer our highest known BkSeq.
+type CompactBlockMessage struct {
+	Head     coin.BlockHeader
+	Sig      cipher.Sig
+	ShortIDs []uint64             `enc:",maxlen=65535"`
+	c        *gnet.MessageContext `enc:"-"`
+}
+
+// NewCompactBlockMessage creates CompactBlockMessage
+func NewCompactBlockMessage(sb coin.SignedBlock) *CompactBlockMessage {
+	blockHash := sb.HashHeader()
+	shortIDs := make([]uint64, len(sb.Body.Transactions))
+	for i, txn := range sb.Body.Transactions {
+		shortIDs[i] = compactShortID(blockHash, txn.Hash())
+	}
+
+	return &CompactBlockMessage{
+		Head:     sb.Head,
+		Sig:      sb.Sig,
+		ShortIDs: shortIDs,
+	}
+}
+
+// EncodeSize implements gnet.Serializer
+func (cbm *CompactBlockMessage) EncodeSize() uint64 {
+	return encodeSizeCompactBlockMessage(cbm)
+}
+
+// Encode implements gnet.Serializer
+func (cbm *CompactBlockMessage) Encode(buf []byte) error {
+	return encodeCompactBlockMessageToBuffer(buf, cbm)
+}
+
+// Decode implements gnet.Serializer
+func (cbm *CompactBlockMessage) Decode(buf []byte) (uint64, error) {
+	return decodeCompactBlockMessage(buf, cbm)
+}
+
+// Handle handles message
+func (cbm *CompactBlockMessage) Handle(mc *gnet.MessageContext, daemon interface{}) error {
+	cbm.c = mc
+	return daemon.(daemoner).recordMessageEvent(cbm, mc)
+}
+
+// process process message
+func (cbm *CompactBlockMessage) process(d daemoner) {
+	dc := d.DaemonConfig()
+	if dc.DisableNetworking {
+		return
+	}
+
+	fields := logrus.Fields{
+		"addr":   cbm.c.Addr,
+		"gnetID": cbm.c.ConnID,
+		"seq":    cbm.Head.BkSeq,
+	}
+
+	headBkSeq, ok, err := d.headBkSeq()
+	if err != nil {
+		logger.WithError(err).Error("CompactBlockMessage d.headBkSeq failed")
+		return
+	}
+	if !ok {
+		logger.Error("CompactBlockMessage no head block, cannot process CompactBlockMessage")
+		return
+	}
+
+	// Record this as this peer's highest block
+	d.recordPeerHeight(cbm.c.Addr, cbm.c.ConnID, cbm.Head.BkSeq)
+
+	// The peer has this block, don't relay it back to it
+	d.recordPeerInventory(cbm.c.Addr, cbm.c.ConnID, inventoryOf(cbm))
+
+	if headBkSeq >= cbm.Head.BkSeq {
+		return
+	}
+
+	// Only the block after our head block can be rebuilt, the blocks before it are downloaded normally
+	if cbm.Head.BkSeq != headBkSeq+1 {
+		if err := d.requestBlocks(); err != nil {
+			logger.WithError(err).WithFields(fields).Warning("requestBlocks failed")
+		}
+		return
+	}
+
+	sb, missing, err := d.receiveCompactBlock(cbm.c.Addr, cbm.c.ConnID, cbm)
+	if err != nil {
+		logger.WithError(err).WithFields(fields).Warning("Compact block could not be rebuilt, downloading the full block")
+		if err := d.requestBlocks(); err != nil {
+			logger.WithError(err).WithFields(fields).Warning("requestBlocks failed")
+		}
+		return
+	}
+
+	if sb != nil {
+		logger.WithFields(fields).Debug("Rebuilt compact block from unconfirmed transactions")
+		executeReceivedBlocks(d, cbm.c, []coin.SignedBlock{*sb})
+		return
+	}
+
+	if len(missing) == 0 {
+		// The block is already waiting for transactions from a peer
+		return
+	}
+
+	logger.WithFields(fields).Debugf("Requesting %d of %d transactions of compact block", len(missing), len(cbm.ShortIDs))
+
+	m := NewGetBlockTxnsMessage(cbm.Head.Hash(), missing)
+	if err := d.sendMessage(cbm.c.Addr, m); err != nil {
+		logger.WithError(err).WithFields(fields).Error("Send GetBlockTxnsMessage failed")
 	}
 }
 
+// GetBlockTxnsMessage requests the transactions of a block that were missing when rebuilding a CompactBlockMessage.
+// The transactions are identified by their index in the block.
+type GetBlockTxnsMessage struct {
+	BlockHash cipher.SHA256
+	Indexes   []uint16             `enc:",maxlen=65535"`
+	c         *gnet.MessageContext `enc:"-"`
+}
+
+// NewGetBlockTxnsMessage creates GetBlockTxnsMessage
+func NewGetBlockTxnsMessage(blockHash cipher.SHA256, indexes []uint16) *GetBlockTxnsMessage {
+	return &GetBlockTxnsMessage{
+		BlockHash: blockHash,
+		Indexes:   indexes,
+	}
+}
+
+// EncodeSize implements gnet.Serializer
+func (gbt *GetBlockTxnsMessage) EncodeSize() uint64 {
+	return encodeSizeGetBlockTxnsMessage(gbt)
+}
+
+// Encode implements gnet.Serializer
+func (gbt *GetBlockTxnsMessage) Encode(buf []byte) error {
+	return encodeGetBlockTxnsMessageToBuffer(buf, gbt)
+}
+
+// Decode implements gnet.Serializer
+func (gbt *GetBlockTxnsMessage) Decode(buf []byte) (uint64, error) {
+	return decodeGetBlockTxnsMessage(buf, gbt)
+}
+
+// Handle handles message
+func (gbt *GetBlockTxnsMessage) Handle(mc *gnet.MessageContext, daemon interface{}) error {
+	gbt.c = mc
+	return daemon.(daemoner).recordMessageEvent(gbt, mc)
+}
+
+// process process message
+func (gbt *GetBlockTxnsMessage) process(d daemoner) {
+	dc := d.DaemonConfig()
+	if dc.DisableNetworking {
+		return
+	}
+
+	fields := logrus.Fields{
+		"addr":      gbt.c.Addr,
+		"gnetID":    gbt.c.ConnID,
+		"blockHash": gbt.BlockHash.Hex(),
+	}
+
+	sb, err := d.getSignedBlockByHash(gbt.BlockHash)
+	if err != nil {
+		logger.WithError(err).WithFields(fields).Error("GetBlockTxnsMessage d.getSignedBlockByHash failed")
+		return
+	}
+	if sb == nil {
+		logger.WithFields(fields).Debug("GetBlockTxnsMessage requested an unknown block")
+		return
+	}
+
+	txns := make([]coin.Transaction, 0, len(gbt.Indexes))
+	for _, i := range gbt.Indexes {
+		if int(i) >= len(sb.Body.Transactions) {
+			logger.WithFields(fields).WithField("index", i).Warning("GetBlockTxnsMessage requested an invalid transaction index")
+			return
+		}
+		txns = append(txns, sb.Body.Transactions[i])
+	}
+
+	m := NewGiveBlockTxnsMessage(gbt.BlockHash, txns, dc.MaxOutgoingMessageLength)
+	if len(m.Transactions) != len(txns) {
+		logger.WithFields(fields).Warningf("NewGiveBlockTxnsMessage truncated %d txns to %d txns", len(txns), len(m.Transactions))
+	}
+
+	if err := d.sendMessage(gbt.c.Addr, m); err != nil {
+		logger.WithError(err).WithFields(fields).Error("Send GiveBlockTxnsMessage failed")
+	}
+}
+
+// GiveBlockTxnsMessage sent in response to GetBlockTxnsMessage, with the requested transactions in the requested order
+type GiveBlockTxnsMessage struct {
+	BlockHash    cipher.SHA256
+	Transactions []coin.Transaction   `enc:",maxlen=65535"`
+	c            *gnet.MessageContext `enc:"-"`
+}
+
+// NewGiveBlockTxnsMessage creates GiveBlockTxnsMessage.
+// If the size of the message would exceed maxMsgLength, the transactions slice is truncated.
+func NewGiveBlockTxnsMessage(blockHash cipher.SHA256, txns []coin.Transaction, maxMsgLength uint64) *GiveBlockTxnsMessage {
+	if len(txns) > 65535 {
+		txns = txns[:65535]
+	}
+	m := &GiveBlockTxnsMessage{
+		BlockHash:    blockHash,
+		Transactions: txns,
+	}
+	truncateGiveBlockTxnsMessage(m, maxMsgLength)
+	return m
+}
+
+// truncateGiveBlockTxnsMessage truncates the transactions in GiveBlockTxnsMessage to fit inside of MaxOutgoingMessageLength
+func truncateGiveBlockTxnsMessage(m *GiveBlockTxnsMessage, maxMsgLength uint64) {
+	// The message length will include a 4 byte message type prefix.
+	// Panic if the prefix can't fit, otherwise we can't adjust the uint64 safely
+	if maxMsgLength < 4 {
+		logger.Panic("maxMsgLength must be >= 4")
+	}
+
+	maxMsgLength -= 4
+
+	// Measure the current message size, if it fits, return
+	n := m.EncodeSize()
+	if n <= maxMsgLength {
+		return
+	}
+
+	// Measure the size of an empty message
+	var mm GiveBlockTxnsMessage
+	size := mm.EncodeSize()
+
+	// Measure the size of the txns, advancing the slice index until it reaches capacity
+	index := -1
+	for i, txn := range m.Transactions {
+		x := encodeSizeTransaction(&txn)
+		if size+x > maxMsgLength {
+			break
+		}
+		size += x
+		index = i
+	}
+
+	m.Transactions = m.Transactions[:index+1]
+
+	if len(m.Transactions) == 0 {
+		logger.Critical().Error("truncateGiveBlockTxnsMessage truncated txns to an empty slice")
+	}
+}
+
+// EncodeSize implements gnet.Serializer
+func (gbt *GiveBlockTxnsMessage) EncodeSize() uint64 {
+	return encodeSizeGiveBlockTxnsMessage(gbt)
+}
+
+// Encode implements gnet.Serializer
+func (gbt *GiveBlockTxnsMessage) Encode(buf []byte) error {
+	return encodeGiveBlockTxnsMessageToBuffer(buf, gbt)
+}
+
+// Decode implements gnet.Serializer
+func (gbt *GiveBlockTxnsMessage) Decode(buf []byte) (uint64, error) {
+	return decodeGiveBlockTxnsMessage(buf, gbt)
+}
+
+// Handle handles message
+func (gbt *GiveBlockTxnsMessage) Handle(mc *gnet.MessageContext, daemon interface{}) error {
+	gbt.c = mc
+	return daemon.(daemoner).recordMessageEvent(gbt, mc)
+}
+
+// process process message
+func (gbt *GiveBlockTxnsMessage) process(d daemoner) {
+	if d.DaemonConfig().DisableNetworking {
+		return
+	}
+
+	fields := logrus.Fields{
+		"addr":      gbt.c.Addr,
+		"gnetID":    gbt.c.ConnID,
+		"blockHash": gbt.BlockHash.Hex(),
+	}
+
+	sb, err := d.receiveBlockTxns(gbt.c.Addr, gbt.c.ConnID, gbt)
+	switch err {
+	case nil:
+	case ErrCompactBlockNotPending:
+		logger.WithFields(fields).Debug("GiveBlockTxnsMessage does not answer a pending compact block")
+		return
+	default:
+		logger.WithError(err).WithFields(fields).Warning("Compact block could not be rebuilt, downloading the full block")
+		if err := d.requestBlocks(); err != nil {
+			logger.WithError(err).WithFields(fields).Warning("requestBlocks failed")
+		}
+		return
+	}
+
+	executeReceivedBlocks(d, gbt.c, []coin.SignedBlock{*sb})
+}
+
 // SendingTxnsMessage send transaction message interface
 type SendingTxnsMessage interface {
 	GetFiltered() []cipher.SHA256
@@ -1118,6 +1676,12 @@ func (atm *AnnounceTxnsMessage) process(d daemoner) {
 		"gnetID": atm.c.ConnID,
 	}
 
+	// Transactions announced by a peer have left the stem phase
+	d.endTxnStems(atm.Transactions)
+
+	// The peer has these transactions, don't announce them back to it
+	d.recordPeerInventory(atm.c.Addr, atm.c.ConnID, inventoryOf(atm))
+
 	unknown, err := d.filterKnownUnconfirmed(atm.Transactions)
 	if err != nil {
 		logger.WithError(err).Error("AnnounceTxnsMessage d.filterKnownUnconfirmed failed")
@@ -1337,6 +1901,9 @@ func (gtm *GiveTxnsMessage) process(d daemoner) {
 		return
 	}
 
+	// The peer has these transactions, don't announce them back to it
+	d.recordPeerInventory(gtm.c.Addr, gtm.c.ConnID, inventoryOf(gtm))
+
 	hashes := make([]cipher.SHA256, 0, len(gtm.Transactions))
 	// Update unconfirmed pool with these transactions
 	for _, txn := range gtm.Transactions {
@@ -1346,6 +1913,13 @@ func (gtm *GiveTxnsMessage) process(d daemoner) {
 		known, softErr, err := d.injectTransaction(txn)
 		if err != nil {
 			logger.WithError(err).WithField("txid", txn.Hash().Hex()).Warning("Failed to record transaction")
+			if e, ok := err.(visor.ErrTxnViolatesHardConstraint); ok {
+				// Unknown inputs are not misbehaviour, the peer may be ahead of us
+				// or may have seen a conflicting transaction first
+				if _, ok := e.Err.(blockdb.ErrUnspentNotExist); !ok {
+					d.recordMisbehaviour(gtm.c.Addr, misbehaviourInvalidTransaction)
+				}
+			}
 			continue
 		} else if softErr != nil {
 			logger.WithError(err).WithField("txid", txn.Hash().Hex()).Warning("Transaction soft violation")
@@ -1363,14 +1937,7 @@ func (gtm *GiveTxnsMessage) process(d daemoner) {
 	}
 
 	// Announce these transactions to peers
-	m := NewAnnounceTxnsMessage(hashes, dc.MaxOutgoingMessageLength)
-	if len(m.Transactions) != len(hashes) {
-		logger.Warningf("NewAnnounceTxnsMessage truncated %d hashes to %d hashes", len(hashes), len(m.Transactions))
-	}
-
-	if ids, err := d.broadcastMessage(m); err != nil {
-		logger.WithError(err).Warning("Broadcast AnnounceTxnsMessage failed")
-	} else {
-		logger.Debugf("Announced %d transactions to %d peers", len(hashes), len(ids))
+	if err := d.announceRelayedTxns(hashes); err != nil {
+		logger.WithError(err).Warning("announceRelayedTxns failed")
 	}
 }
@@ -1,6 +1,7 @@
 package daemon
 
 import (
+	"fmt"
 	"testing"
 
 	"github.com/skycoin/skycoin/src/cipher"
@@ -235,3 +236,28 @@ func BenchmarkSerializeGiveTxnsMessage(b *testing.B) {
 		encoder.Serialize(&giveTxnsMessageObj)
 	}
 }
+
+// BenchmarkCompactBlockBandwidth compares the bytes sent to relay a block as a GiveBlocksMessage
+// with the bytes sent to relay it as a CompactBlockMessage, including the follow-up request
+// for the transactions missing from the receiver's unconfirmed pool
+func BenchmarkCompactBlockBandwidth(b *testing.B) {
+	txns := makeCompactBlockTestTxns(200)
+	sb := makeCompactBlockTestBlock(10, txns)
+
+	maxMsgLength := NewDaemonConfig().MaxOutgoingMessageLength
+	fullSize := encodeSizeGiveBlocksMessage(NewGiveBlocksMessage([]coin.SignedBlock{sb}, maxMsgLength))
+
+	for _, known := range []int{100, 90, 50, 0} {
+		b.Run(fmt.Sprintf("known-%d%%", known), func(b *testing.B) {
+			unconfirmed := txns[:len(txns)*known/100]
+
+			var compactSize uint64
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				compactSize = compactBlockRelaySize(sb, unconfirmed, maxMsgLength)
+			}
+
+			b.Logf("full: %d bytes, compact: %d bytes, saved: %.1f%%", fullSize, compactSize, 100*(1-float64(compactSize)/float64(fullSize)))
+		})
+	}
+}
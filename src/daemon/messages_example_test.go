@@ -473,7 +473,7 @@ func ExampleIntroductionMessage() {
 		BurnFactor:          2,
 		MaxTransactionSize:  32768,
 		MaxDropletPrecision: 3,
-	})
+	}, capabilityCompactBlocks, cipher.PubKey{})
 	fmt.Println("IntroductionMessage:")
 	var mai = NewMessagesAnnotationsIterator(message)
 	w := bufio.NewWriter(os.Stdout)
@@ -487,12 +487,12 @@ func ExampleIntroductionMessage() {
 	}
 	// Output:
 	// IntroductionMessage:
-	// 0x0000 | 4e 00 00 00 ....................................... Length
+	// 0x0000 | 52 00 00 00 ....................................... Length
 	// 0x0004 | 49 4e 54 52 ....................................... Prefix
 	// 0x0008 | d2 04 00 00 ....................................... Mirror
 	// 0x000c | d2 1e ............................................. ListenPort
 	// 0x000e | 05 00 00 00 ....................................... ProtocolVersion
-	// 0x0012 | 3c 00 00 00 ....................................... Extra length
+	// 0x0012 | 40 00 00 00 ....................................... Extra length
 	// 0x0016 | 03 ................................................ Extra[0]
 	// 0x0017 | 28 ................................................ Extra[1]
 	// 0x0018 | c5 ................................................ Extra[2]
@@ -553,7 +553,11 @@ func ExampleIntroductionMessage() {
 	// 0x004f | 34 ................................................ Extra[57]
 	// 0x0050 | 2e ................................................ Extra[58]
 	// 0x0051 | 31 ................................................ Extra[59]
-	// 0x0052 |
+	// 0x0052 | 01 ................................................ Extra[60]
+	// 0x0053 | 00 ................................................ Extra[61]
+	// 0x0054 | 00 ................................................ Extra[62]
+	// 0x0055 | 00 ................................................ Extra[63]
+	// 0x0056 |
 }
 
 func ExampleGetPeersMessage() {
@@ -609,6 +613,37 @@ func ExampleGivePeersMessage() {
 	// 0x001e |
 }
 
+func ExampleGivePeersV2Message() {
+	defer gnet.EraseMessages()
+	setupMsgEncoding()
+	var peers = make([]pex.Peer, 0, 2)
+	var peer0 = *pex.NewPeer("118.178.135.93:6000")
+	var peer1 = *pex.NewPeer("[2001:db8::68]:6000")
+	peers = append(peers, peer0, peer1)
+	var message = NewGivePeersV2Message(peers, 1024*1024)
+	fmt.Println("GivePeersV2Message:")
+	var mai = NewMessagesAnnotationsIterator(message)
+	w := bufio.NewWriter(os.Stdout)
+	msg, err := gnet.EncodeMessage(message)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	if err := NewFromIterator(msg, &mai, w); err != nil {
+		fmt.Println(err)
+	}
+	// Output:
+	// GivePeersV2Message:
+	// 0x0000 | 2c 00 00 00 ....................................... Length
+	// 0x0004 | 47 56 50 32 ....................................... Prefix
+	// 0x0008 | 02 00 00 00 ....................................... Peers length
+	// 0x000c | 00 00 00 00 00 00 00 00 00 00 ff ff 76 b2 87 5d
+	// 0x001c | 70 17 ............................................. Peers[0]
+	// 0x001e | 20 01 0d b8 00 00 00 00 00 00 00 00 00 00 00 68
+	// 0x002e | 70 17 ............................................. Peers[1]
+	// 0x0030 |
+}
+
 func ExampleGetBlocksMessage() {
 	defer gnet.EraseMessages()
 	setupMsgEncoding()
@@ -744,6 +779,133 @@ func ExampleAnnounceBlocksMessage() {
 	// 0x0010 |
 }
 
+func ExampleCompactBlockMessage() {
+	defer gnet.EraseMessages()
+	setupMsgEncoding()
+	var sig, _ = cipher.SigFromHex(sig1hex)
+	var message = &CompactBlockMessage{
+		Head: coin.BlockHeader{
+			Version:  0x02,
+			Time:     100,
+			BkSeq:    3,
+			Fee:      10,
+			PrevHash: hashes[0],
+			BodyHash: hashes[1],
+			UxHash:   hashes[2],
+		},
+		Sig:      sig,
+		ShortIDs: []uint64{0x0102030405060708, 0x1112131415161718},
+	}
+	fmt.Println("CompactBlockMessage:")
+	var mai = NewMessagesAnnotationsIterator(message)
+	w := bufio.NewWriter(os.Stdout)
+	msg, err := gnet.EncodeMessage(message)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	if err := NewFromIterator(msg, &mai, w); err != nil {
+		fmt.Println(err)
+	}
+	// Output:
+	// CompactBlockMessage:
+	// 0x0000 | d5 00 00 00 ....................................... Length
+	// 0x0004 | 43 4d 50 42 ....................................... Prefix
+	// 0x0008 | 02 00 00 00 64 00 00 00 00 00 00 00 03 00 00 00
+	// 0x0018 | 00 00 00 00 0a 00 00 00 00 00 00 00 40 af f2 e9
+	// 0x0028 | d2 d8 92 2e 47 af d4 64 8e 69 67 49 71 58 78 5f
+	// 0x0038 | bd 1d a8 70 e7 11 02 66 bf 94 48 80 7b b4 62 c3
+	// 0x0048 | bd 37 1d d8 1c 06 ad 1d 2b 63 59 71 cb 56 eb 22
+	// 0x0058 | 23 3d fc 9f eb e8 3e 44 c8 40 b8 d7 e7 5a c8 01
+	// 0x0068 | c1 3f 3d a9 c7 a1 24 ca 31 3b e2 a3 73 f6 4a d9
+	// 0x0078 | 7c 58 a1 b6 fe bc 0e 0c a5 c5 c8 73 ............... Head
+	// 0x0084 | 03 21 3f dd 6d df 86 0e 40 53 e1 a9 7e 42 76 d6
+	// 0x0094 | 34 54 f5 19 5a 83 21 35 70 04 d5 2c db bf d3 88
+	// 0x00a4 | 6f c7 ad 3f 3f 63 b6 5d 4a 87 9c e3 08 6d ae b3
+	// 0x00b4 | e5 4a 93 d3 c2 f9 6a 50 61 f9 bc 49 36 83 ca 8e
+	// 0x00c4 | 01 ................................................ Sig
+	// 0x00c5 | 02 00 00 00 ....................................... ShortIDs length
+	// 0x00c9 | 08 07 06 05 04 03 02 01 ........................... ShortIDs[0]
+	// 0x00d1 | 18 17 16 15 14 13 12 11 ........................... ShortIDs[1]
+	// 0x00d9 |
+}
+
+func ExampleGetBlockTxnsMessage() {
+	defer gnet.EraseMessages()
+	setupMsgEncoding()
+	var message = NewGetBlockTxnsMessage(hashes[0], []uint16{1, 3})
+	fmt.Println("GetBlockTxnsMessage:")
+	var mai = NewMessagesAnnotationsIterator(message)
+	w := bufio.NewWriter(os.Stdout)
+	msg, err := gnet.EncodeMessage(message)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	if err := NewFromIterator(msg, &mai, w); err != nil {
+		fmt.Println(err)
+	}
+	// Output:
+	// GetBlockTxnsMessage:
+	// 0x0000 | 2c 00 00 00 ....................................... Length
+	// 0x0004 | 47 54 42 54 ....................................... Prefix
+	// 0x0008 | 40 af f2 e9 d2 d8 92 2e 47 af d4 64 8e 69 67 49
+	// 0x0018 | 71 58 78 5f bd 1d a8 70 e7 11 02 66 bf 94 48 80 ... BlockHash
+	// 0x0028 | 02 00 00 00 ....................................... Indexes length
+	// 0x002c | 01 00 ............................................. Indexes[0]
+	// 0x002e | 03 00 ............................................. Indexes[1]
+	// 0x0030 |
+}
+
+func ExampleGiveBlockTxnsMessage() {
+	defer gnet.EraseMessages()
+	setupMsgEncoding()
+	var transactions = coin.Transactions{
+		{
+			Length:    43,
+			Type:      0,
+			InnerHash: hashes[1],
+			Sigs:      []cipher.Sig{},
+			In:        []cipher.SHA256{hashes[2]},
+			Out: []coin.TransactionOutput{
+				{
+					Address: addresses[0],
+					Coins:   12,
+					Hours:   34,
+				},
+			},
+		},
+	}
+	var message = NewGiveBlockTxnsMessage(hashes[0], transactions, 1024*1024)
+	fmt.Println("GiveBlockTxnsMessage:")
+	var mai = NewMessagesAnnotationsIterator(message)
+	w := bufio.NewWriter(os.Stdout)
+	msg, err := gnet.EncodeMessage(message)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	if err := NewFromIterator(msg, &mai, w); err != nil {
+		fmt.Println(err)
+	}
+	// Output:
+	// GiveBlockTxnsMessage:
+	// 0x0000 | 9e 00 00 00 ....................................... Length
+	// 0x0004 | 47 56 42 54 ....................................... Prefix
+	// 0x0008 | 40 af f2 e9 d2 d8 92 2e 47 af d4 64 8e 69 67 49
+	// 0x0018 | 71 58 78 5f bd 1d a8 70 e7 11 02 66 bf 94 48 80 ... BlockHash
+	// 0x0028 | 01 00 00 00 ....................................... Transactions length
+	// 0x002c | 2b 00 00 00 00 7b b4 62 c3 bd 37 1d d8 1c 06 ad
+	// 0x003c | 1d 2b 63 59 71 cb 56 eb 22 23 3d fc 9f eb e8 3e
+	// 0x004c | 44 c8 40 b8 d7 00 00 00 00 01 00 00 00 e7 5a c8
+	// 0x005c | 01 c1 3f 3d a9 c7 a1 24 ca 31 3b e2 a3 73 f6 4a
+	// 0x006c | d9 7c 58 a1 b6 fe bc 0e 0c a5 c5 c8 73 01 00 00
+	// 0x007c | 00 00 ad dc d4 a7 19 6a 8c a8 6b 9b 3d 74 16 95
+	// 0x008c | f3 69 ef 1b 3d ba 0c 00 00 00 00 00 00 00 22 00
+	// 0x009c | 00 00 00 00 00 00 ................................. Transactions[0]
+	// 0x00a2 |
+}
+
 func ExampleGetTxnsMessage() {
 	defer gnet.EraseMessages()
 	setupMsgEncoding()
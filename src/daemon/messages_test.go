@@ -1,6 +1,8 @@
 package daemon
 
 import (
+	"encoding/binary"
+	"errors"
 	"io/ioutil"
 	"os"
 	"path/filepath"
@@ -16,6 +18,8 @@ import (
 	"github.com/skycoin/skycoin/src/daemon/pex"
 	"github.com/skycoin/skycoin/src/params"
 	"github.com/skycoin/skycoin/src/util/useragent"
+	"github.com/skycoin/skycoin/src/visor"
+	"github.com/skycoin/skycoin/src/visor/blockdb"
 )
 
 func TestIntroductionMessage(t *testing.T) {
@@ -24,6 +28,7 @@ func TestIntroductionMessage(t *testing.T) {
 
 	pubkey, _ := cipher.GenerateKeyPair()
 	pubkey2, _ := cipher.GenerateKeyPair()
+	encryptionPubKey, _ := cipher.GenerateKeyPair()
 
 	type daemonMockValue struct {
 		protocolVersion          uint32
@@ -38,6 +43,8 @@ func TestIntroductionMessage(t *testing.T) {
 		requestBlocksFromAddrErr error
 		announceAllTxnsErr       error
 		sendRandomPeersErr       error
+		encryptConnections       bool
+		allowUnencryptedPeers    bool
 	}
 
 	tt := []struct {
@@ -47,6 +54,8 @@ func TestIntroductionMessage(t *testing.T) {
 		mockValue            daemonMockValue
 		userAgent            useragent.Data
 		unconfirmedVerifyTxn params.VerifyTxn
+		encrypted            bool
+		capabilities         uint32
 		intro                *IntroductionMessage
 	}{
 		{
@@ -176,6 +185,8 @@ func TestIntroductionMessage(t *testing.T) {
 				MaxTransactionSize:  32768,
 				MaxDropletPrecision: 3,
 			},
+			// The first bytes of the additional data are read as capabilities
+			capabilities: binary.LittleEndian.Uint32([]byte("additional data")),
 			intro: &IntroductionMessage{
 				Mirror:          10001,
 				ListenPort:      6000,
@@ -385,6 +396,213 @@ func TestIntroductionMessage(t *testing.T) {
 				ListenPort:      6000,
 			},
 		},
+		{
+			name: "INTR message with capabilities",
+			addr: "121.121.121.121:6000",
+			mockValue: daemonMockValue{
+				mirror:          10000,
+				protocolVersion: 1,
+				pubkey:          pubkey,
+				connectionIntroduced: &connection{
+					Addr: "121.121.121.121:6000",
+					ConnectionDetails: ConnectionDetails{
+						ListenPort:   6000,
+						Capabilities: capabilityCompactBlocks,
+					},
+				},
+			},
+			userAgent: useragent.Data{
+				Coin:    "skycoin",
+				Version: "0.24.1",
+			},
+			unconfirmedVerifyTxn: params.VerifyTxn{
+				BurnFactor:          4,
+				MaxTransactionSize:  32768,
+				MaxDropletPrecision: 3,
+			},
+			capabilities: capabilityCompactBlocks,
+			intro: &IntroductionMessage{
+				Mirror:          10001,
+				ListenPort:      6000,
+				ProtocolVersion: 1,
+				Extra: append(newIntroductionMessageExtra(pubkey, "skycoin:0.24.1", params.VerifyTxn{
+					BurnFactor:          4,
+					MaxTransactionSize:  32768,
+					MaxDropletPrecision: 3,
+				}), encoder.SerializeUint32(capabilityCompactBlocks)...),
+			},
+		},
+		{
+			name: "encrypted connection",
+			addr: "121.121.121.121:6000",
+			mockValue: daemonMockValue{
+				mirror:                10000,
+				protocolVersion:       1,
+				pubkey:                pubkey,
+				encryptConnections:    true,
+				allowUnencryptedPeers: false,
+				connectionIntroduced: &connection{
+					Addr: "121.121.121.121:6000",
+					ConnectionDetails: ConnectionDetails{
+						ListenPort: 6000,
+						Encrypted:  true,
+					},
+				},
+			},
+			userAgent: useragent.Data{
+				Coin:    "skycoin",
+				Version: "0.24.1",
+			},
+			unconfirmedVerifyTxn: params.VerifyTxn{
+				BurnFactor:          4,
+				MaxTransactionSize:  32768,
+				MaxDropletPrecision: 3,
+			},
+			encrypted: true,
+			intro: &IntroductionMessage{
+				Mirror:          10001,
+				ListenPort:      6000,
+				ProtocolVersion: 1,
+				Extra: append(newIntroductionMessageExtra(pubkey, "skycoin:0.24.1", params.VerifyTxn{
+					BurnFactor:          4,
+					MaxTransactionSize:  32768,
+					MaxDropletPrecision: 3,
+				}), append(encoder.SerializeUint32(0), encryptionPubKey[:]...)...),
+			},
+		},
+		{
+			name: "peer does not support required encryption",
+			addr: "121.121.121.121:6000",
+			mockValue: daemonMockValue{
+				mirror:                10000,
+				protocolVersion:       1,
+				pubkey:                pubkey,
+				encryptConnections:    true,
+				allowUnencryptedPeers: false,
+				disconnectReason:      ErrDisconnectEncryptionRequired,
+			},
+			intro: &IntroductionMessage{
+				Mirror:          10001,
+				ListenPort:      6000,
+				ProtocolVersion: 1,
+				Extra: newIntroductionMessageExtra(pubkey, "skycoin:0.24.1", params.VerifyTxn{
+					BurnFactor:          4,
+					MaxTransactionSize:  32768,
+					MaxDropletPrecision: 3,
+				}),
+			},
+		},
+		{
+			name: "peer does not support encryption, unencrypted peers allowed",
+			addr: "121.121.121.121:6000",
+			mockValue: daemonMockValue{
+				mirror:                10000,
+				protocolVersion:       1,
+				pubkey:                pubkey,
+				encryptConnections:    true,
+				allowUnencryptedPeers: true,
+				connectionIntroduced: &connection{
+					Addr: "121.121.121.121:6000",
+					ConnectionDetails: ConnectionDetails{
+						ListenPort: 6000,
+						Encrypted:  false,
+					},
+				},
+			},
+			userAgent: useragent.Data{
+				Coin:    "skycoin",
+				Version: "0.24.1",
+			},
+			unconfirmedVerifyTxn: params.VerifyTxn{
+				BurnFactor:          4,
+				MaxTransactionSize:  32768,
+				MaxDropletPrecision: 3,
+			},
+			intro: &IntroductionMessage{
+				Mirror:          10001,
+				ListenPort:      6000,
+				ProtocolVersion: 1,
+				Extra: newIntroductionMessageExtra(pubkey, "skycoin:0.24.1", params.VerifyTxn{
+					BurnFactor:          4,
+					MaxTransactionSize:  32768,
+					MaxDropletPrecision: 3,
+				}),
+			},
+		},
+		{
+			name: "peer announces encryption pubkey, encryption disabled",
+			addr: "121.121.121.121:6000",
+			mockValue: daemonMockValue{
+				mirror:                10000,
+				protocolVersion:       1,
+				pubkey:                pubkey,
+				encryptConnections:    false,
+				allowUnencryptedPeers: false,
+				connectionIntroduced: &connection{
+					Addr: "121.121.121.121:6000",
+					ConnectionDetails: ConnectionDetails{
+						ListenPort: 6000,
+						Encrypted:  false,
+					},
+				},
+			},
+			userAgent: useragent.Data{
+				Coin:    "skycoin",
+				Version: "0.24.1",
+			},
+			unconfirmedVerifyTxn: params.VerifyTxn{
+				BurnFactor:          4,
+				MaxTransactionSize:  32768,
+				MaxDropletPrecision: 3,
+			},
+			intro: &IntroductionMessage{
+				Mirror:          10001,
+				ListenPort:      6000,
+				ProtocolVersion: 1,
+				Extra: append(newIntroductionMessageExtra(pubkey, "skycoin:0.24.1", params.VerifyTxn{
+					BurnFactor:          4,
+					MaxTransactionSize:  32768,
+					MaxDropletPrecision: 3,
+				}), append(encoder.SerializeUint32(0), encryptionPubKey[:]...)...),
+			},
+		},
+		{
+			name: "feeler connection",
+			addr: "121.121.121.121:6000",
+			mockValue: daemonMockValue{
+				mirror:           10000,
+				protocolVersion:  1,
+				pubkey:           pubkey,
+				disconnectReason: ErrDisconnectFeelerDone,
+				connectionIntroduced: &connection{
+					Addr: "121.121.121.121:6000",
+					ConnectionDetails: ConnectionDetails{
+						ListenPort: 6000,
+						Outgoing:   true,
+						Feeler:     true,
+					},
+				},
+			},
+			userAgent: useragent.Data{
+				Coin:    "skycoin",
+				Version: "0.24.1",
+			},
+			unconfirmedVerifyTxn: params.VerifyTxn{
+				BurnFactor:          4,
+				MaxTransactionSize:  32768,
+				MaxDropletPrecision: 3,
+			},
+			intro: &IntroductionMessage{
+				Mirror:          10001,
+				ListenPort:      6000,
+				ProtocolVersion: 1,
+				Extra: newIntroductionMessageExtra(pubkey, "skycoin:0.24.1", params.VerifyTxn{
+					BurnFactor:          4,
+					MaxTransactionSize:  32768,
+					MaxDropletPrecision: 3,
+				}),
+			},
+		},
 		{
 			name: "peer list full",
 			addr: "121.121.121.121:12345",
@@ -420,8 +638,10 @@ func TestIntroductionMessage(t *testing.T) {
 					Coin:    "skycoin",
 					Version: "0.24.1",
 				},
-				Mirror:           tc.mockValue.mirror,
-				BlockchainPubkey: tc.mockValue.pubkey,
+				Mirror:                tc.mockValue.mirror,
+				BlockchainPubkey:      tc.mockValue.pubkey,
+				EncryptConnections:    tc.mockValue.encryptConnections,
+				AllowUnencryptedPeers: tc.mockValue.allowUnencryptedPeers,
 			})
 			d.On("recordMessageEvent", tc.intro, mc).Return(tc.mockValue.recordMessageEventErr)
 			d.On("Disconnect", tc.addr, tc.mockValue.disconnectReason).Return(tc.mockValue.disconnectErr)
@@ -437,6 +657,12 @@ func TestIntroductionMessage(t *testing.T) {
 				if tc.unconfirmedVerifyTxn != m.unconfirmedVerifyTxn {
 					return false
 				}
+				if tc.encrypted != m.encrypted {
+					return false
+				}
+				if tc.capabilities != m.capabilities {
+					return false
+				}
 
 				return true
 			})).Return(tc.mockValue.connectionIntroduced, tc.mockValue.connectionIntroducedErr)
@@ -458,6 +684,49 @@ func TestIntroductionMessage(t *testing.T) {
 	}
 }
 
+func TestIntroductionMessageOptionalExtra(t *testing.T) {
+	pubkey, _ := cipher.GenerateKeyPair()
+	encryptionPubKey, _ := cipher.GenerateKeyPair()
+	verifyParams := params.VerifyTxn{
+		BurnFactor:          2,
+		MaxTransactionSize:  32768,
+		MaxDropletPrecision: 3,
+	}
+
+	intro := NewIntroductionMessage(10001, 3, 6000, pubkey, "skycoin:0.26.0", verifyParams, capabilityCompactBlocks, encryptionPubKey)
+	capabilities, ok := intro.Capabilities()
+	require.True(t, ok)
+	require.Equal(t, capabilityCompactBlocks, capabilities)
+	pk, ok := intro.EncryptionPubKey()
+	require.True(t, ok)
+	require.Equal(t, encryptionPubKey, pk)
+
+	intro = NewIntroductionMessage(10001, 3, 6000, pubkey, "skycoin:0.26.0", verifyParams, 0, cipher.PubKey{})
+	capabilities, ok = intro.Capabilities()
+	require.True(t, ok)
+	require.Equal(t, uint32(0), capabilities)
+	_, ok = intro.EncryptionPubKey()
+	require.False(t, ok)
+
+	// Additional data shorter than a pubkey is not an encryption pubkey
+	intro.Extra = append(intro.Extra, []byte("additional data")...)
+	_, ok = intro.EncryptionPubKey()
+	require.False(t, ok)
+
+	// Older peers don't send capabilities
+	intro.Extra = newIntroductionMessageExtra(pubkey, "skycoin:0.26.0", verifyParams)
+	_, ok = intro.Capabilities()
+	require.False(t, ok)
+	_, ok = intro.EncryptionPubKey()
+	require.False(t, ok)
+
+	intro.Extra = nil
+	_, ok = intro.Capabilities()
+	require.False(t, ok)
+	_, ok = intro.EncryptionPubKey()
+	require.False(t, ok)
+}
+
 func TestMessageEncodeDecode(t *testing.T) {
 	update := false
 
@@ -512,6 +781,22 @@ func TestMessageEncodeDecode(t *testing.T) {
 				},
 			},
 		},
+		{
+			goldenFile: "give-peers-v2-msg.golden",
+			obj:        &GivePeersV2Message{},
+			msg: &GivePeersV2Message{
+				Peers: []PeerAddr{
+					{
+						IP:   [16]byte{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0xff, 0xff, 118, 178, 135, 93},
+						Port: 6000,
+					},
+					{
+						IP:   [16]byte{0x20, 0x01, 0x0d, 0xb8, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0x68},
+						Port: 7200,
+					},
+				},
+			},
+		},
 		{
 			goldenFile: "ping-msg.golden",
 			obj:        &PingMessage{},
@@ -681,6 +966,54 @@ func TestMessageEncodeDecode(t *testing.T) {
 				MaxBkSeq: 50000,
 			},
 		},
+		{
+			goldenFile: "compact-block-msg.golden",
+			obj:        &CompactBlockMessage{},
+			msg: &CompactBlockMessage{
+				Head: coin.BlockHeader{
+					Version:  1,
+					Time:     1540000000,
+					BkSeq:    50001,
+					Fee:      2000,
+					PrevHash: cipher.SumSHA256([]byte("prev")),
+					BodyHash: cipher.SumSHA256([]byte("body")),
+					UxHash:   cipher.SumSHA256([]byte("ux")),
+				},
+				Sig:      cipher.MustSigFromHex("8cf145e9ef4a4a5254bc57798a7a61dfed238768f94edc5635175c6b91bccd8ec1555da603c5e31b018e135b82b1525be8a92973c468a74b5b40b8da189cb465eb"),
+				ShortIDs: []uint64{0x0102030405060708, 0x1112131415161718, 0xf1f2f3f4f5f6f7f8},
+			},
+		},
+		{
+			goldenFile: "get-block-txns-msg.golden",
+			obj:        &GetBlockTxnsMessage{},
+			msg: &GetBlockTxnsMessage{
+				BlockHash: cipher.SumSHA256([]byte("block")),
+				Indexes:   []uint16{0, 2, 65534},
+			},
+		},
+		{
+			goldenFile: "give-block-txns-msg.golden",
+			obj:        &GiveBlockTxnsMessage{},
+			msg: &GiveBlockTxnsMessage{
+				BlockHash: cipher.SumSHA256([]byte("block")),
+				Transactions: []coin.Transaction{
+					{
+						Length:    43,
+						Type:      0,
+						InnerHash: cipher.SumSHA256([]byte("inner")),
+						Sigs:      []cipher.Sig{cipher.MustSigFromHex("8cf145e9ef4a4a5254bc57798a7a61dfed238768f94edc5635175c6b91bccd8ec1555da603c5e31b018e135b82b1525be8a92973c468a74b5b40b8da189cb465eb")},
+						In:        []cipher.SHA256{cipher.SumSHA256([]byte("in"))},
+						Out: []coin.TransactionOutput{
+							{
+								Address: cipher.MustDecodeBase58Address("23FF4fshzD8tZk2d88P22WATfzUpNQF1x85"),
+								Coins:   1000000,
+								Hours:   7,
+							},
+						},
+					},
+				},
+			},
+		},
 		{
 			goldenFile: "announce-txns-msg.golden",
 			obj:        &AnnounceTxnsMessage{},
@@ -832,6 +1165,80 @@ func TestTruncateGivePeersMessage(t *testing.T) {
 	require.True(t, n <= maxLen)
 }
 
+func TestTruncateGivePeersV2Message(t *testing.T) {
+	maxLen := uint64(1024)
+	m := &GivePeersV2Message{}
+
+	// Empty message, no truncation
+	prevLen := len(m.Peers)
+	truncateGivePeersV2Message(m, maxLen)
+	require.Equal(t, prevLen, len(m.Peers))
+
+	n := encodeSizeGivePeersV2Message(m)
+	require.True(t, n <= maxLen)
+
+	// One peer, no truncation
+	m.Peers = append(m.Peers, PeerAddr{})
+	prevLen = len(m.Peers)
+	truncateGivePeersV2Message(m, maxLen)
+	require.Equal(t, prevLen, len(m.Peers))
+
+	n = encodeSizeGivePeersV2Message(m)
+	require.True(t, n <= maxLen)
+
+	// Too many peers, truncated
+	n = encodeSizePeerAddr(&PeerAddr{})
+	m.Peers = make([]PeerAddr, (maxLen/n)*2)
+	prevLen = len(m.Peers)
+	truncateGivePeersV2Message(m, maxLen)
+	require.True(t, len(m.Peers) < prevLen)
+	require.NotEmpty(t, m.Peers)
+
+	n = encodeSizeGivePeersV2Message(m)
+	require.True(t, n <= maxLen)
+}
+
+func TestPeerAddr(t *testing.T) {
+	cases := []struct {
+		addr string
+		err  bool
+	}{
+		{addr: "118.178.135.93:6000"},
+		{addr: "[2001:db8::68]:7200"},
+		{addr: "[::1]:6000"},
+		{addr: "118.178.135.93", err: true},
+		{addr: "foo:6000", err: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.addr, func(t *testing.T) {
+			peerAddr, err := NewPeerAddr(tc.addr)
+			if tc.err {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tc.addr, peerAddr.String())
+		})
+	}
+}
+
+func TestNewGivePeersMessages(t *testing.T) {
+	peers := []pex.Peer{
+		*pex.NewPeer("118.178.135.93:6000"),
+		*pex.NewPeer("[2001:db8::68]:7200"),
+		*pex.NewPeer("47.88.33.156:6000"),
+	}
+
+	// GivePeersMessage can only carry IPv4 addresses
+	m := NewGivePeersMessage(peers, 1024)
+	require.Equal(t, []string{"118.178.135.93:6000", "47.88.33.156:6000"}, m.GetPeers())
+
+	m2 := NewGivePeersV2Message(peers, 1024)
+	require.Equal(t, []string{"118.178.135.93:6000", "[2001:db8::68]:7200", "47.88.33.156:6000"}, m2.GetPeers())
+}
+
 func TestTruncateGiveBlocksMessage(t *testing.T) {
 	maxLen := uint64(1024)
 	m := &GiveBlocksMessage{}
@@ -1005,3 +1412,38 @@ func TestGetBlocksMessageProcess(t *testing.T) {
 
 	d.AssertExpectations(t)
 }
+
+func TestGiveTxnsMessageProcessInvalidTransaction(t *testing.T) {
+	d := &mockDaemoner{}
+
+	invalidTxn := coin.Transaction{Length: 1}
+	validTxn := coin.Transaction{Length: 2}
+	softTxn := coin.Transaction{Length: 3}
+	unknownInputTxn := coin.Transaction{Length: 4}
+
+	m := &GiveTxnsMessage{
+		Transactions: []coin.Transaction{invalidTxn, validTxn, softTxn, unknownInputTxn},
+		c: &gnet.MessageContext{
+			ConnID: 10,
+			Addr:   "1.2.3.4:6000",
+		},
+	}
+
+	config := DaemonConfig{
+		MaxOutgoingMessageLength: 1024,
+	}
+
+	d.On("DaemonConfig").Return(config)
+	d.On("injectTransaction", invalidTxn).Return(false, nil, visor.NewErrTxnViolatesHardConstraint(errors.New("bad")))
+	d.On("injectTransaction", validTxn).Return(false, nil, nil)
+	d.On("injectTransaction", softTxn).Return(false, &visor.ErrTxnViolatesSoftConstraint{Err: errors.New("soft")}, nil)
+	d.On("injectTransaction", unknownInputTxn).Return(false, nil, visor.NewErrTxnViolatesHardConstraint(blockdb.NewErrUnspentNotExist("foo")))
+	d.On("recordMisbehaviour", "1.2.3.4:6000", misbehaviourInvalidTransaction).Return()
+	d.On("recordPeerInventory", "1.2.3.4:6000", uint64(10), []cipher.SHA256{invalidTxn.Hash(), validTxn.Hash(), softTxn.Hash(), unknownInputTxn.Hash()}).Return()
+	d.On("announceRelayedTxns", []cipher.SHA256{validTxn.Hash(), softTxn.Hash()}).Return(nil)
+
+	m.process(d)
+
+	d.AssertExpectations(t)
+	d.AssertNumberOfCalls(t, "recordMisbehaviour", 1)
+}
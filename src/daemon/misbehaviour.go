@@ -0,0 +1,183 @@
+package daemon
+
+import (
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/skycoin/skycoin/src/daemon/gnet"
+	"github.com/skycoin/skycoin/src/daemon/pex"
+	"github.com/skycoin/skycoin/src/util/iputil"
+)
+
+// misbehaviour is a protocol violation by a peer, which adds Score to the peer's ban score
+type misbehaviour struct {
+	Name  string
+	Score int
+}
+
+var (
+	// misbehaviourInvalidBlock a block that could not be executed
+	misbehaviourInvalidBlock = misbehaviour{"invalid block", 50}
+	// misbehaviourBadBlockSignature a block that was not signed by the blockchain pubkey
+	misbehaviourBadBlockSignature = misbehaviour{"bad block signature", 100}
+	// misbehaviourInvalidBlocksResponse a GiveBlocksMessage that does not answer a block request
+	misbehaviourInvalidBlocksResponse = misbehaviour{"invalid blocks response", 20}
+	// misbehaviourOversizedMessage a message longer than the maximum message length
+	misbehaviourOversizedMessage = misbehaviour{"oversized message", 50}
+	// misbehaviourDecodeError a message that could not be decoded
+	misbehaviourDecodeError = misbehaviour{"message decode error", 50}
+	// misbehaviourInvalidTransaction an announced transaction that violates hard constraints
+	misbehaviourInvalidTransaction = misbehaviour{"invalid transaction", 10}
+)
+
+// disconnectMisbehaviour returns the misbehaviour that caused a disconnect, if any.
+// An unknown message disconnects the peer without a score, since it may come from a newer protocol version.
+func disconnectMisbehaviour(r gnet.DisconnectReason) (misbehaviour, bool) {
+	switch r {
+	case gnet.ErrDisconnectInvalidMessageLength:
+		return misbehaviourOversizedMessage, true
+	case gnet.ErrDisconnectMalformedMessage,
+		gnet.ErrDisconnectMessageDecodeUnderflow,
+		gnet.ErrDisconnectTruncatedMessageID:
+		return misbehaviourDecodeError, true
+	default:
+		return misbehaviour{}, false
+	}
+}
+
+// peerScore is the ban score of an IP
+type peerScore struct {
+	Score   int
+	Updated time.Time
+}
+
+// peerScores tracks the ban scores of IPs.
+// A score decreases by one point every decayInterval, so that occasional misbehaviour is forgiven.
+// It is only accessed from the daemon run loop.
+type peerScores struct {
+	decayInterval time.Duration
+	scores        map[string]*peerScore
+}
+
+func newPeerScores(decayInterval time.Duration) *peerScores {
+	return &peerScores{
+		decayInterval: decayInterval,
+		scores:        make(map[string]*peerScore),
+	}
+}
+
+// decay applies the decay to the score at time now
+func (s *peerScores) decay(p *peerScore, now time.Time) {
+	if s.decayInterval <= 0 {
+		return
+	}
+
+	n := int(now.Sub(p.Updated) / s.decayInterval)
+	if n <= 0 {
+		return
+	}
+
+	p.Score -= n
+	if p.Score < 0 {
+		p.Score = 0
+	}
+	p.Updated = p.Updated.Add(time.Duration(n) * s.decayInterval)
+}
+
+// add adds score to the score of an IP and returns the new score.
+// Scores that have decayed to zero are removed.
+func (s *peerScores) add(ip string, score int, now time.Time) int {
+	for k, p := range s.scores {
+		s.decay(p, now)
+		if p.Score == 0 && k != ip {
+			delete(s.scores, k)
+		}
+	}
+
+	p, ok := s.scores[ip]
+	if !ok {
+		p = &peerScore{
+			Updated: now,
+		}
+		s.scores[ip] = p
+	}
+
+	p.Score += score
+	return p.Score
+}
+
+// get returns the score of an IP at time now
+func (s *peerScores) get(ip string, now time.Time) int {
+	p, ok := s.scores[ip]
+	if !ok {
+		return 0
+	}
+	s.decay(p, now)
+	return p.Score
+}
+
+// reset removes the score of an IP
+func (s *peerScores) reset(ip string) {
+	delete(s.scores, ip)
+}
+
+// recordMisbehaviour adds the score of a misbehaviour to the peer's ban score.
+// When the score reaches BanScoreThreshold, the peer's IP is banned for BanDuration
+// and all connections from the IP are disconnected.
+// Trusted and localhost peers are never banned.
+func (dm *Daemon) recordMisbehaviour(addr string, m misbehaviour) {
+	ip, _, err := iputil.SplitAddr(addr)
+	if err != nil {
+		logger.Critical().WithError(err).WithField("addr", addr).Error("recordMisbehaviour: invalid address")
+		return
+	}
+
+	score := dm.peerScores.add(ip, m.Score, time.Now().UTC())
+
+	fields := logrus.Fields{
+		"addr":         addr,
+		"misbehaviour": m.Name,
+		"score":        score,
+	}
+	logger.WithFields(fields).Info("Peer misbehaved")
+
+	if score < dm.config.BanScoreThreshold {
+		return
+	}
+
+	if dm.isTrustedPeer(addr) || iputil.IsLocalhost(ip) {
+		logger.WithFields(fields).Info("Ban score threshold reached, but not banning trusted or localhost peer")
+		return
+	}
+
+	dm.peerScores.reset(ip)
+
+	if _, err := dm.banIP(ip, dm.config.BanDuration, m.Name); err != nil {
+		logger.WithError(err).WithFields(fields).Error("Ban peer failed")
+		return
+	}
+
+	logger.WithFields(fields).WithField("duration", dm.config.BanDuration).Warning("Banned peer")
+}
+
+// banIP bans an IP and disconnects all connections from it
+func (dm *Daemon) banIP(addr string, duration time.Duration, reason string) (pex.Ban, error) {
+	b, err := dm.pex.Ban(addr, duration, reason)
+
+	// The ban is in effect even if it could not be saved to disk
+	if b.IP != "" {
+		for _, c := range dm.connections.all() {
+			ip, _, splitErr := iputil.SplitAddr(c.Addr)
+			if splitErr != nil || ip != b.IP {
+				continue
+			}
+
+			if err := dm.Disconnect(c.Addr, ErrDisconnectIsBlacklisted); err != nil {
+				logger.WithError(err).WithField("addr", c.Addr).Error("Disconnect")
+			}
+		}
+	}
+
+	return b, err
+}
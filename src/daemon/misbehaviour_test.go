@@ -0,0 +1,68 @@
+package daemon
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/daemon/gnet"
+)
+
+func TestPeerScores(t *testing.T) {
+	now := time.Now()
+	s := newPeerScores(time.Minute)
+
+	require.Equal(t, 0, s.get("1.1.1.1", now))
+	require.Equal(t, 50, s.add("1.1.1.1", 50, now))
+	require.Equal(t, 10, s.add("2.2.2.2", 10, now))
+	require.Equal(t, 60, s.add("1.1.1.1", 10, now))
+
+	// Scores decay by one point per interval
+	now = now.Add(time.Minute*5 + time.Second*30)
+	require.Equal(t, 55, s.get("1.1.1.1", now))
+	require.Equal(t, 5, s.get("2.2.2.2", now))
+
+	// The partial interval is not lost
+	now = now.Add(time.Second * 30)
+	require.Equal(t, 54, s.get("1.1.1.1", now))
+
+	// Scores that decayed to zero are removed
+	now = now.Add(time.Minute * 10)
+	require.Equal(t, 54, s.add("1.1.1.1", 10, now))
+	require.Len(t, s.scores, 1)
+
+	s.reset("1.1.1.1")
+	require.Equal(t, 0, s.get("1.1.1.1", now))
+	require.Empty(t, s.scores)
+
+	// Scores don't decay without an interval
+	s = newPeerScores(0)
+	s.add("1.1.1.1", 10, now)
+	require.Equal(t, 10, s.get("1.1.1.1", now.Add(time.Hour)))
+}
+
+func TestDisconnectMisbehaviour(t *testing.T) {
+	cases := []struct {
+		reason gnet.DisconnectReason
+		m      misbehaviour
+		ok     bool
+	}{
+		{gnet.ErrDisconnectInvalidMessageLength, misbehaviourOversizedMessage, true},
+		{gnet.ErrDisconnectMalformedMessage, misbehaviourDecodeError, true},
+		{gnet.ErrDisconnectUnknownMessage, misbehaviour{}, false},
+		{gnet.ErrDisconnectMessageDecodeUnderflow, misbehaviourDecodeError, true},
+		{gnet.ErrDisconnectTruncatedMessageID, misbehaviourDecodeError, true},
+		{ErrDisconnectIdle, misbehaviour{}, false},
+		{errors.New("read failed: EOF"), misbehaviour{}, false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.reason.Error(), func(t *testing.T) {
+			m, ok := disconnectMisbehaviour(tc.reason)
+			require.Equal(t, tc.ok, ok)
+			require.Equal(t, tc.m, m)
+		})
+	}
+}
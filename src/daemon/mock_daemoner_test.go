@@ -42,13 +42,13 @@ func (_m *mockDaemoner) Disconnect(addr string, r gnet.DisconnectReason) error {
 	return r0
 }
 
-// addPeers provides a mock function with given fields: addrs
-func (_m *mockDaemoner) addPeers(addrs []string) int {
-	ret := _m.Called(addrs)
+// addPeers provides a mock function with given fields: addrs, source
+func (_m *mockDaemoner) addPeers(addrs []string, source string) int {
+	ret := _m.Called(addrs, source)
 
 	var r0 int
-	if rf, ok := ret.Get(0).(func([]string) int); ok {
-		r0 = rf(addrs)
+	if rf, ok := ret.Get(0).(func([]string, string) int); ok {
+		r0 = rf(addrs, source)
 	} else {
 		r0 = ret.Get(0).(int)
 	}
@@ -70,6 +70,20 @@ func (_m *mockDaemoner) announceAllValidTxns() error {
 	return r0
 }
 
+// announceRelayedTxns provides a mock function with given fields: hashes
+func (_m *mockDaemoner) announceRelayedTxns(hashes []cipher.SHA256) error {
+	ret := _m.Called(hashes)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func([]cipher.SHA256) error); ok {
+		r0 = rf(hashes)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // broadcastMessage provides a mock function with given fields: msg
 func (_m *mockDaemoner) broadcastMessage(msg gnet.Message) ([]uint64, error) {
 	ret := _m.Called(msg)
@@ -130,18 +144,9 @@ func (_m *mockDaemoner) disconnectNow(addr string, r gnet.DisconnectReason) erro
 	return r0
 }
 
-// executeSignedBlock provides a mock function with given fields: b
-func (_m *mockDaemoner) executeSignedBlock(b coin.SignedBlock) error {
-	ret := _m.Called(b)
-
-	var r0 error
-	if rf, ok := ret.Get(0).(func(coin.SignedBlock) error); ok {
-		r0 = rf(b)
-	} else {
-		r0 = ret.Error(0)
-	}
-
-	return r0
+// endTxnStems provides a mock function with given fields: hashes
+func (_m *mockDaemoner) endTxnStems(hashes []cipher.SHA256) {
+	_m.Called(hashes)
 }
 
 // filterKnownUnconfirmed provides a mock function with given fields: txns
@@ -190,6 +195,29 @@ func (_m *mockDaemoner) getKnownUnconfirmed(txns []cipher.SHA256) (coin.Transact
 	return r0, r1
 }
 
+// getSignedBlockByHash provides a mock function with given fields: hash
+func (_m *mockDaemoner) getSignedBlockByHash(hash cipher.SHA256) (*coin.SignedBlock, error) {
+	ret := _m.Called(hash)
+
+	var r0 *coin.SignedBlock
+	if rf, ok := ret.Get(0).(func(cipher.SHA256) *coin.SignedBlock); ok {
+		r0 = rf(hash)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*coin.SignedBlock)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(cipher.SHA256) error); ok {
+		r1 = rf(hash)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // getSignedBlocksSince provides a mock function with given fields: seq, count
 func (_m *mockDaemoner) getSignedBlocksSince(seq uint64, count uint64) ([]coin.SignedBlock, error) {
 	ret := _m.Called(seq, count)
@@ -285,6 +313,103 @@ func (_m *mockDaemoner) pexConfig() pex.Config {
 	return r0
 }
 
+// receiveBackfillBlocks provides a mock function with given fields: addr, gnetID, blocks
+func (_m *mockDaemoner) receiveBackfillBlocks(addr string, gnetID uint64, blocks []coin.SignedBlock) (bool, error) {
+	ret := _m.Called(addr, gnetID, blocks)
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func(string, uint64, []coin.SignedBlock) bool); ok {
+		r0 = rf(addr, gnetID, blocks)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, uint64, []coin.SignedBlock) error); ok {
+		r1 = rf(addr, gnetID, blocks)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// receiveBlockTxns provides a mock function with given fields: addr, gnetID, m
+func (_m *mockDaemoner) receiveBlockTxns(addr string, gnetID uint64, m *GiveBlockTxnsMessage) (*coin.SignedBlock, error) {
+	ret := _m.Called(addr, gnetID, m)
+
+	var r0 *coin.SignedBlock
+	if rf, ok := ret.Get(0).(func(string, uint64, *GiveBlockTxnsMessage) *coin.SignedBlock); ok {
+		r0 = rf(addr, gnetID, m)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*coin.SignedBlock)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, uint64, *GiveBlockTxnsMessage) error); ok {
+		r1 = rf(addr, gnetID, m)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// receiveBlocks provides a mock function with given fields: addr, gnetID, blocks
+func (_m *mockDaemoner) receiveBlocks(addr string, gnetID uint64, blocks []coin.SignedBlock) (int, error) {
+	ret := _m.Called(addr, gnetID, blocks)
+
+	var r0 int
+	if rf, ok := ret.Get(0).(func(string, uint64, []coin.SignedBlock) int); ok {
+		r0 = rf(addr, gnetID, blocks)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, uint64, []coin.SignedBlock) error); ok {
+		r1 = rf(addr, gnetID, blocks)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// receiveCompactBlock provides a mock function with given fields: addr, gnetID, m
+func (_m *mockDaemoner) receiveCompactBlock(addr string, gnetID uint64, m *CompactBlockMessage) (*coin.SignedBlock, []uint16, error) {
+	ret := _m.Called(addr, gnetID, m)
+
+	var r0 *coin.SignedBlock
+	if rf, ok := ret.Get(0).(func(string, uint64, *CompactBlockMessage) *coin.SignedBlock); ok {
+		r0 = rf(addr, gnetID, m)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*coin.SignedBlock)
+		}
+	}
+
+	var r1 []uint16
+	if rf, ok := ret.Get(1).(func(string, uint64, *CompactBlockMessage) []uint16); ok {
+		r1 = rf(addr, gnetID, m)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).([]uint16)
+		}
+	}
+
+	var r2 error
+	if rf, ok := ret.Get(2).(func(string, uint64, *CompactBlockMessage) error); ok {
+		r2 = rf(addr, gnetID, m)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
 // recordMessageEvent provides a mock function with given fields: m, c
 func (_m *mockDaemoner) recordMessageEvent(m asyncMessage, c *gnet.MessageContext) error {
 	ret := _m.Called(m, c)
@@ -299,11 +424,49 @@ func (_m *mockDaemoner) recordMessageEvent(m asyncMessage, c *gnet.MessageContex
 	return r0
 }
 
+// recordMisbehaviour provides a mock function with given fields: addr, m
+func (_m *mockDaemoner) recordMisbehaviour(addr string, m misbehaviour) {
+	_m.Called(addr, m)
+}
+
 // recordPeerHeight provides a mock function with given fields: addr, gnetID, height
 func (_m *mockDaemoner) recordPeerHeight(addr string, gnetID uint64, height uint64) {
 	_m.Called(addr, gnetID, height)
 }
 
+// recordPeerInventory provides a mock function with given fields: addr, gnetID, hashes
+func (_m *mockDaemoner) recordPeerInventory(addr string, gnetID uint64, hashes []cipher.SHA256) {
+	_m.Called(addr, gnetID, hashes)
+}
+
+// relayHeadBlock provides a mock function with given fields:
+func (_m *mockDaemoner) relayHeadBlock() error {
+	ret := _m.Called()
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func() error); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// requestBlocks provides a mock function with given fields:
+func (_m *mockDaemoner) requestBlocks() error {
+	ret := _m.Called()
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func() error); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // requestBlocksFromAddr provides a mock function with given fields: addr
 func (_m *mockDaemoner) requestBlocksFromAddr(addr string) error {
 	ret := _m.Called(addr)
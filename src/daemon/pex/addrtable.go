@@ -0,0 +1,134 @@
+package pex
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"strconv"
+)
+
+const (
+	// newBucketCount is the number of buckets in the new table, which holds addresses that we have not connected to
+	newBucketCount = 256
+	// triedBucketCount is the number of buckets in the tried table, which holds addresses that we have connected to
+	triedBucketCount = 64
+	// bucketSize is the number of addresses that fit in a bucket
+	bucketSize = 64
+	// newBucketsPerSourceGroup is the number of new buckets that the addresses received from peers
+	// in one network group can be placed in
+	newBucketsPerSourceGroup = 16
+	// triedBucketsPerGroup is the number of tried buckets that the addresses in one network group can be placed in
+	triedBucketsPerGroup = 4
+)
+
+// bucketID identifies a bucket in the new or tried table
+type bucketID struct {
+	tried bool
+	index int
+}
+
+// addrTable places the peers of the peerlist in buckets, modelled on Bitcoin's addrman.
+//
+// Peers that we have not connected to are placed in the new table. Their bucket is determined by the network group
+// of the peer and the network group of the peer that sent it to us, so that the peers sent by a single
+// network group can only fill newBucketsPerSourceGroup buckets, and the peers in one network group
+// sent by a single network group share one bucket.
+// Peers that we have connected to are placed in the tried table. Their bucket is determined by the address
+// and the network group of the peer, so that a single network group can only fill triedBucketsPerGroup buckets.
+//
+// Bucket positions are derived from a random key, so that an attacker cannot predict which addresses
+// collide with each other. The key is not saved, so the peers are placed in different buckets after a restart.
+type addrTable struct {
+	key       [32]byte
+	buckets   map[bucketID]map[string]struct{}
+	positions map[string]bucketID
+}
+
+func newAddrTable() *addrTable {
+	t := &addrTable{
+		buckets:   make(map[bucketID]map[string]struct{}),
+		positions: make(map[string]bucketID),
+	}
+
+	if _, err := rand.Read(t.key[:]); err != nil {
+		logger.WithError(err).Panic("Failed to generate the address table key")
+	}
+
+	return t
+}
+
+// hash returns a keyed hash of the values
+func (t *addrTable) hash(values ...string) uint64 {
+	h := sha256.New()
+	h.Write(t.key[:]) // nolint: errcheck
+	for _, v := range values {
+		h.Write([]byte(v)) // nolint: errcheck
+		h.Write([]byte{0}) // nolint: errcheck
+	}
+	return binary.LittleEndian.Uint64(h.Sum(nil))
+}
+
+// newBucket returns the new table bucket of a peer in a network group, received from a peer in sourceGroup
+func (t *addrTable) newBucket(group, sourceGroup string) bucketID {
+	i := t.hash(group, sourceGroup) % newBucketsPerSourceGroup
+	return bucketID{
+		index: int(t.hash(sourceGroup, strconv.FormatUint(i, 10)) % newBucketCount),
+	}
+}
+
+// triedBucket returns the tried table bucket of a peer address in a network group
+func (t *addrTable) triedBucket(addr, group string) bucketID {
+	i := t.hash(addr) % triedBucketsPerGroup
+	return bucketID{
+		tried: true,
+		index: int(t.hash(group, strconv.FormatUint(i, 10)) % triedBucketCount),
+	}
+}
+
+// place puts an address in a bucket, removing it from its previous bucket
+func (t *addrTable) place(addr string, id bucketID) {
+	t.remove(addr)
+
+	b := t.buckets[id]
+	if b == nil {
+		b = make(map[string]struct{})
+		t.buckets[id] = b
+	}
+
+	b[addr] = struct{}{}
+	t.positions[addr] = id
+}
+
+// remove removes an address from its bucket
+func (t *addrTable) remove(addr string) {
+	id, ok := t.positions[addr]
+	if !ok {
+		return
+	}
+
+	delete(t.positions, addr)
+	delete(t.buckets[id], addr)
+	if len(t.buckets[id]) == 0 {
+		delete(t.buckets, id)
+	}
+}
+
+// position returns the bucket of an address
+func (t *addrTable) position(addr string) (bucketID, bool) {
+	id, ok := t.positions[addr]
+	return id, ok
+}
+
+// members returns the addresses in a bucket
+func (t *addrTable) members(id bucketID) []string {
+	addrs := make([]string, 0, len(t.buckets[id]))
+	for a := range t.buckets[id] {
+		addrs = append(addrs, a)
+	}
+	return addrs
+}
+
+// size returns the number of addresses in a bucket
+func (t *addrTable) size(id bucketID) int {
+	return len(t.buckets[id])
+}
@@ -0,0 +1,159 @@
+package pex
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// groupAddrs returns n addresses in the 112.32.0.0/16 network group
+func groupAddrs(n int) []string {
+	addrs := make([]string, n)
+	for i := range addrs {
+		addrs[i] = fmt.Sprintf("112.32.%d.%d:7200", i/250, i%250+1)
+	}
+	return addrs
+}
+
+func countBuckets(pl peerlist, tried bool) int {
+	n := 0
+	for id := range pl.table.buckets {
+		if id.tried == tried {
+			n++
+		}
+	}
+	return n
+}
+
+func TestPeerlistNewBuckets(t *testing.T) {
+	pl := newPeerlist()
+
+	// Peers in one network group sent by one source share a bucket,
+	// and the least recently seen peers are removed when it is full
+	addrs := groupAddrs(1000)
+	pl.addPeersFrom(addrs, "45.1.1.1:6000")
+	require.Equal(t, 1, countBuckets(pl, false))
+	require.Equal(t, bucketSize, pl.len())
+	require.Equal(t, pl.len(), len(pl.table.positions))
+
+	for _, p := range pl.peers {
+		require.Equal(t, "45.1.1.1:6000", p.Source)
+		require.False(t, p.Tried)
+	}
+
+	// Peers in many network groups sent by one source can only occupy a few buckets
+	for i := 0; i < 200; i++ {
+		pl.addPeerFrom(fmt.Sprintf("%d.%d.1.1:6000", 50+i/100, i%100), "45.1.1.1:6000")
+	}
+	require.True(t, countBuckets(pl, false) <= newBucketsPerSourceGroup)
+
+	// Trusted and private peers are never removed
+	pl = newPeerlist()
+	pl.addPeersFrom(addrs[:bucketSize], "45.1.1.1:6000")
+	for _, p := range pl.peers {
+		p.Trusted = true
+	}
+	pl.addPeersFrom(addrs[bucketSize:], "45.1.1.1:6000")
+	for _, a := range addrs[:bucketSize] {
+		require.True(t, pl.hasPeer(a))
+	}
+
+	// Removed peers are removed from their bucket
+	for a := range pl.peers {
+		pl.removePeer(a)
+	}
+	require.Empty(t, pl.table.positions)
+	require.Empty(t, pl.table.buckets)
+}
+
+func TestPeerlistMarkTried(t *testing.T) {
+	pl := newPeerlist()
+	// Add the peers from different sources, so that they fit in the new table
+	addrs := groupAddrs(400)
+	for i, a := range addrs {
+		pl.addPeerFrom(a, fmt.Sprintf("%d.%d.1.1:6000", 50+i/100, i%100))
+	}
+	require.Equal(t, 400, pl.len())
+
+	require.Error(t, pl.markTried("45.1.1.1:6000"))
+
+	for _, a := range addrs {
+		require.NoError(t, pl.markTried(a))
+	}
+
+	// A network group can only occupy a few tried buckets. When they are full,
+	// the least recently seen peers are moved back to the new table
+	require.True(t, countBuckets(pl, true) <= triedBucketsPerGroup)
+	require.Equal(t, 400, pl.len())
+
+	tried := 0
+	for a, p := range pl.peers {
+		id, ok := pl.table.position(a)
+		require.True(t, ok)
+		require.Equal(t, p.Tried, id.tried)
+		if p.Tried {
+			tried++
+		}
+	}
+	require.True(t, tried > 0)
+	require.True(t, tried <= triedBucketsPerGroup*bucketSize)
+}
+
+func TestPeerlistRandomDiversity(t *testing.T) {
+	pl := newPeerlist()
+
+	// An attacker announces many addresses in a single network group
+	attacker := groupAddrs(1000)
+	pl.addPeersFrom(attacker, "112.32.0.1:7200")
+
+	var honest []string
+	for i := 0; i < 20; i++ {
+		honest = append(honest, fmt.Sprintf("%d.10.10.10:6000", 50+i))
+	}
+	pl.addPeers(honest)
+
+	attackerAddrs := make(map[string]struct{}, len(attacker))
+	for _, a := range attacker {
+		attackerAddrs[a] = struct{}{}
+	}
+
+	for i := 0; i < 20; i++ {
+		ps := pl.random(8, nil)
+		require.Len(t, ps, 8)
+
+		n := 0
+		for _, p := range ps {
+			if _, ok := attackerAddrs[p.Addr]; ok {
+				n++
+			}
+		}
+		require.True(t, n <= 1)
+	}
+
+	// Peers in a repeated network group are returned if there are not enough other peers
+	ps := pl.random(0, nil)
+	require.Len(t, ps, pl.len())
+
+	seen := make(map[string]struct{}, len(ps))
+	for _, p := range ps {
+		seen[p.Addr] = struct{}{}
+	}
+	require.Len(t, seen, pl.len())
+}
+
+func TestPeerlistSaveTried(t *testing.T) {
+	pl := newPeerlist()
+	pl.addPeersFrom(testPeers[:2], "45.1.1.1:6000")
+	require.NoError(t, pl.markTried(testPeers[0]))
+
+	f, removeFile := preparePeerlistFile(t)
+	defer removeFile()
+	require.NoError(t, pl.save(f))
+
+	psMap, err := loadCachedPeersFile(f)
+	require.NoError(t, err)
+	require.True(t, psMap[testPeers[0]].Tried)
+	require.False(t, psMap[testPeers[1]].Tried)
+	require.Equal(t, "45.1.1.1:6000", psMap[testPeers[1]].Source)
+}
@@ -0,0 +1,109 @@
+package pex
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"sort"
+	"strings"
+)
+
+// ASMap maps IP address ranges to autonomous systems, or any other named groups of networks
+// that are likely to be operated by the same party.
+// Peers in the same group are treated as a single network group when selecting peers to connect to.
+type ASMap struct {
+	// networks maps each prefix length to the masked networks of that length and their names.
+	// IPv4 networks are stored as IPv4-mapped IPv6 networks
+	networks map[int]map[string]string
+	// lengths are the prefix lengths in networks, longest first
+	lengths []int
+}
+
+// LoadASMap loads an ASMap from a file
+func LoadASMap(fn string) (*ASMap, error) {
+	data, err := ioutil.ReadFile(fn)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseASMap(string(data))
+}
+
+// parseASMap parses an ASMap file.
+// The format is a newline separated list of "cidr name" entries, e.g. "85.56.0.0/14 AS1234".
+// Empty lines and lines that begin with # are treated as comment lines.
+// When networks overlap, the entry with the longest prefix is used.
+func parseASMap(body string) (*ASMap, error) {
+	m := &ASMap{
+		networks: make(map[int]map[string]string),
+	}
+
+	for i, line := range strings.Split(body, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("ASMap line %d: expected \"cidr name\", got %q", i+1, line)
+		}
+
+		_, n, err := net.ParseCIDR(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("ASMap line %d: %v", i+1, err)
+		}
+
+		ones, bits := n.Mask.Size()
+		if bits == 8*net.IPv4len {
+			ones += 8 * (net.IPv6len - net.IPv4len)
+		}
+
+		networks := m.networks[ones]
+		if networks == nil {
+			networks = make(map[string]string)
+			m.networks[ones] = networks
+			m.lengths = append(m.lengths, ones)
+		}
+
+		networks[string(n.IP.To16().Mask(net.CIDRMask(ones, 8*net.IPv6len)))] = fields[1]
+	}
+
+	sort.Sort(sort.Reverse(sort.IntSlice(m.lengths)))
+
+	return m, nil
+}
+
+// Lookup returns the name of the group that contains ip.
+// Returns an empty string if ip is not an IP address or is not in any group.
+func (m *ASMap) Lookup(ip string) string {
+	if m == nil {
+		return ""
+	}
+
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return ""
+	}
+
+	for _, ones := range m.lengths {
+		if name, ok := m.networks[ones][string(parsed.Mask(net.CIDRMask(ones, 8*net.IPv6len)))]; ok {
+			return name
+		}
+	}
+
+	return ""
+}
+
+// Len returns the number of networks in the map
+func (m *ASMap) Len() int {
+	if m == nil {
+		return 0
+	}
+
+	n := 0
+	for _, networks := range m.networks {
+		n += len(networks)
+	}
+	return n
+}
@@ -0,0 +1,80 @@
+package pex
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseASMap(t *testing.T) {
+	m, err := parseASMap(`
+# comment
+85.56.0.0/14 AS100
+85.57.1.0/24 AS200
+
+2001:db8::/32 AS300
+`)
+	require.NoError(t, err)
+	require.Equal(t, 3, m.Len())
+
+	testData := []struct {
+		ip       string
+		expected string
+	}{
+		{"85.56.1.1", "AS100"},
+		{"85.59.255.255", "AS100"},
+		{"85.57.1.2", "AS200"},
+		{"::ffff:85.57.1.2", "AS200"},
+		{"85.60.0.1", ""},
+		{"2001:db8:1::1", "AS300"},
+		{"2001:db9::1", ""},
+		{"localhost", ""},
+	}
+
+	for _, tc := range testData {
+		t.Run(tc.ip, func(t *testing.T) {
+			require.Equal(t, tc.expected, m.Lookup(tc.ip))
+		})
+	}
+
+	// A nil ASMap has no entries
+	var nilMap *ASMap
+	require.Equal(t, "", nilMap.Lookup("85.56.1.1"))
+	require.Equal(t, 0, nilMap.Len())
+
+	_, err = parseASMap("85.56.0.0/14")
+	require.Error(t, err)
+
+	_, err = parseASMap("85.56.0.0/14 AS100\n85.56.0.0 AS100")
+	require.Error(t, err)
+}
+
+func TestNewPexASMap(t *testing.T) {
+	dir, err := ioutil.TempDir("", "asmap")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	fn := filepath.Join(dir, "asmap.txt")
+	require.NoError(t, ioutil.WriteFile(fn, []byte("112.32.0.0/15 AS100\n"), 0600))
+
+	cfg := NewConfig()
+	cfg.DataDirectory = dir
+	cfg.ASMapFile = fn
+
+	px, err := New(cfg)
+	require.NoError(t, err)
+	require.Equal(t, "AS100", px.ASN("112.33.1.1:6000"))
+	require.Equal(t, "AS100", px.ASN("112.32.1.1"))
+	require.Equal(t, "", px.ASN("112.34.1.1:6000"))
+
+	// Peers in the same autonomous system are in the same network group
+	require.Equal(t, px.peerlist.group("112.32.1.1:6000"), px.peerlist.group("112.33.1.1:6000"))
+	require.NotEqual(t, px.peerlist.group("112.34.1.1:6000"), px.peerlist.group("112.35.1.1:6000"))
+
+	cfg.ASMapFile = filepath.Join(dir, "missing.txt")
+	_, err = New(cfg)
+	require.Error(t, err)
+}
@@ -0,0 +1,145 @@
+package pex
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/skycoin/skycoin/src/util/file"
+)
+
+// BlacklistFilename filename for disk-persisted peer bans
+const BlacklistFilename = "blacklist.json"
+
+var (
+	// ErrNotBanned is returned when removing a ban for an IP that is not banned
+	ErrNotBanned = errors.New("IP is not banned")
+	// ErrInvalidBanDuration is returned when a ban duration is not positive
+	ErrInvalidBanDuration = errors.New("Ban duration must be positive")
+)
+
+// Ban is a timed ban of all connections from an IP address
+type Ban struct {
+	IP      string    `json:"ip"`
+	Reason  string    `json:"reason"`
+	Created time.Time `json:"created"`
+	Expires time.Time `json:"expires"`
+}
+
+// Expired returns true if the ban is no longer in effect at time t
+func (b Ban) Expired(t time.Time) bool {
+	return !t.Before(b.Expires)
+}
+
+// banIP returns the normalized IP of an address of the form ip or ip:port
+func banIP(addr string) (string, error) {
+	addr = whitespaceFilter.ReplaceAllString(addr, "")
+	host := addr
+	if h, _, err := net.SplitHostPort(addr); err == nil {
+		host = h
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return "", ErrInvalidAddress
+	}
+
+	return ip.String(), nil
+}
+
+// blacklist holds the banned IPs
+type blacklist struct {
+	bans map[string]Ban
+}
+
+func newBlacklist() blacklist {
+	return blacklist{
+		bans: make(map[string]Ban),
+	}
+}
+
+// loadBlacklistFile loads the bans saved in a file. Returns nil if the file does not exist
+func loadBlacklistFile(path string) (map[string]Ban, error) {
+	var bans []Ban
+	err := file.LoadJSON(path, &bans)
+
+	if os.IsNotExist(err) {
+		logger.WithField("path", path).Info("File does not exist")
+		return nil, nil
+	} else if err == io.EOF {
+		logger.WithField("path", path).Error("Corrupt or empty file")
+		return nil, nil
+	}
+
+	if err != nil {
+		logger.WithField("path", path).WithError(err).Error("Failed to load blacklist file")
+		return nil, err
+	}
+
+	m := make(map[string]Ban, len(bans))
+	for _, b := range bans {
+		ip, err := banIP(b.IP)
+		if err != nil {
+			logger.WithError(err).WithField("ip", b.IP).Error("Invalid IP in blacklist file")
+			continue
+		}
+		b.IP = ip
+		m[ip] = b
+	}
+
+	return m, nil
+}
+
+func (bl *blacklist) save(fn string) error {
+	if err := file.SaveJSON(fn, bl.all(), 0600); err != nil {
+		return fmt.Errorf("save blacklist failed: %s", err)
+	}
+	return nil
+}
+
+func (bl *blacklist) add(b Ban) {
+	bl.bans[b.IP] = b
+}
+
+func (bl *blacklist) remove(ip string) bool {
+	if _, ok := bl.bans[ip]; !ok {
+		return false
+	}
+	delete(bl.bans, ip)
+	return true
+}
+
+func (bl *blacklist) isBanned(ip string, now time.Time) bool {
+	b, ok := bl.bans[ip]
+	return ok && !b.Expired(now)
+}
+
+// clearExpired removes expired bans and returns the number of bans removed
+func (bl *blacklist) clearExpired(now time.Time) int {
+	n := 0
+	for ip, b := range bl.bans {
+		if b.Expired(now) {
+			delete(bl.bans, ip)
+			n++
+		}
+	}
+	return n
+}
+
+// all returns the bans sorted by IP
+func (bl *blacklist) all() []Ban {
+	bans := make([]Ban, 0, len(bl.bans))
+	for _, b := range bl.bans {
+		bans = append(bans, b)
+	}
+
+	sort.Slice(bans, func(i, j int) bool {
+		return bans[i].IP < bans[j].IP
+	})
+
+	return bans
+}
@@ -0,0 +1,138 @@
+package pex
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBanIP(t *testing.T) {
+	cases := []struct {
+		addr string
+		ip   string
+		err  error
+	}{
+		{"112.32.32.14", "112.32.32.14", nil},
+		{"112.32.32.14:7200", "112.32.32.14", nil},
+		{" 112.32.32.14:7200 ", "112.32.32.14", nil},
+		{"[::1]:7200", "::1", nil},
+		{"112.32.32", "", ErrInvalidAddress},
+		{"foo:7200", "", ErrInvalidAddress},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.addr, func(t *testing.T) {
+			ip, err := banIP(tc.addr)
+			require.Equal(t, tc.err, err)
+			require.Equal(t, tc.ip, ip)
+		})
+	}
+}
+
+func TestPexBan(t *testing.T) {
+	dir, err := ioutil.TempDir("", "blacklist")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	cfg := NewConfig()
+	cfg.DataDirectory = dir
+	cfg.DefaultConnections = testPeers[:1]
+
+	px, err := New(cfg)
+	require.NoError(t, err)
+
+	otherPort := "112.32.32.15:7201"
+	require.NoError(t, px.AddPeer(testPeers[1]))
+	require.NoError(t, px.AddPeer(otherPort))
+	require.NoError(t, px.AddPeer(testPeers[2]))
+
+	_, err = px.Ban(testPeers[1], 0, "test")
+	require.Equal(t, ErrInvalidBanDuration, err)
+	_, err = px.Ban("foo", time.Hour, "test")
+	require.Equal(t, ErrInvalidAddress, err)
+
+	// Banning removes all untrusted peers with the IP
+	b, err := px.Ban(testPeers[1], time.Hour, "test")
+	require.NoError(t, err)
+	require.Equal(t, "112.32.32.15", b.IP)
+	require.Equal(t, "test", b.Reason)
+	require.Equal(t, time.Hour, b.Expires.Sub(b.Created))
+
+	_, ok := px.GetPeer(testPeers[1])
+	require.False(t, ok)
+	_, ok = px.GetPeer(otherPort)
+	require.False(t, ok)
+	_, ok = px.GetPeer(testPeers[2])
+	require.True(t, ok)
+
+	require.True(t, px.IsBanned(testPeers[1]))
+	require.True(t, px.IsBanned("112.32.32.15"))
+	require.False(t, px.IsBanned(testPeers[2]))
+
+	// Banned peers can't be added
+	require.Equal(t, ErrBlacklistedAddress, px.AddPeer(testPeers[1]))
+	require.Equal(t, 1, px.AddPeers([]string{otherPort, testPeers[3]}))
+
+	// Trusted peers are banned but not removed
+	_, err = px.Ban(testPeers[0], time.Hour, "test")
+	require.NoError(t, err)
+	_, ok = px.GetPeer(testPeers[0])
+	require.True(t, ok)
+
+	require.Equal(t, []Ban{
+		{
+			IP:      "112.32.32.14",
+			Reason:  "test",
+			Created: px.Bans()[0].Created,
+			Expires: px.Bans()[0].Expires,
+		},
+		b,
+	}, px.Bans())
+
+	// The bans are saved to disk
+	bans, err := loadBlacklistFile(filepath.Join(dir, BlacklistFilename))
+	require.NoError(t, err)
+	require.Len(t, bans, 2)
+	require.Equal(t, b.IP, bans[b.IP].IP)
+	require.True(t, b.Expires.Equal(bans[b.IP].Expires))
+
+	require.Equal(t, ErrNotBanned, px.Unban(testPeers[2]))
+	require.NoError(t, px.Unban(testPeers[0]))
+	require.False(t, px.IsBanned(testPeers[0]))
+
+	bans, err = loadBlacklistFile(filepath.Join(dir, BlacklistFilename))
+	require.NoError(t, err)
+	require.Len(t, bans, 1)
+
+	// Bans are loaded when the pex is created
+	px, err = New(cfg)
+	require.NoError(t, err)
+	require.True(t, px.IsBanned(testPeers[1]))
+	require.False(t, px.IsBanned(testPeers[0]))
+}
+
+func TestBlacklistClearExpired(t *testing.T) {
+	now := time.Now().UTC()
+	bl := newBlacklist()
+	bl.add(Ban{
+		IP:      "112.32.32.14",
+		Created: now.Add(-time.Hour),
+		Expires: now,
+	})
+	bl.add(Ban{
+		IP:      "112.32.32.15",
+		Created: now.Add(-time.Hour),
+		Expires: now.Add(time.Second),
+	})
+
+	require.False(t, bl.isBanned("112.32.32.14", now))
+	require.True(t, bl.isBanned("112.32.32.15", now))
+
+	require.Equal(t, 1, bl.clearExpired(now))
+	require.Len(t, bl.all(), 1)
+	require.Equal(t, "112.32.32.15", bl.all()[0].IP)
+}
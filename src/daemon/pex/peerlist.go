@@ -11,6 +11,7 @@ import (
 	"github.com/sirupsen/logrus"
 
 	"github.com/skycoin/skycoin/src/util/file"
+	"github.com/skycoin/skycoin/src/util/iputil"
 	"github.com/skycoin/skycoin/src/util/useragent"
 )
 
@@ -29,11 +30,16 @@ func (ps Peers) ToAddrs() []string {
 // peerlist is a map of addresses to *PeerStates
 type peerlist struct {
 	peers map[string]*Peer
+	// Buckets of the peers in the new and tried tables
+	table *addrTable
+	// Optional map of IP ranges to autonomous systems, used for network groups
+	asmap *ASMap
 }
 
 func newPeerlist() peerlist {
 	return peerlist{
 		peers: make(map[string]*Peer),
+		table: newAddrTable(),
 	}
 }
 
@@ -94,28 +100,133 @@ func (pl *peerlist) setPeers(peers []Peer) {
 	for _, p := range peers {
 		np := p
 		pl.peers[p.Addr] = &np
+		pl.table.place(p.Addr, pl.bucket(np))
 	}
 }
 
+// group returns the network group of a peer address, which is its autonomous system if it is in the ASMap,
+// otherwise its IPv4 /16 or IPv6 /32 network. Localhost and private addresses have no network group.
+func (pl *peerlist) group(addr string) string {
+	ip := addr
+	if host, _, err := iputil.SplitAddr(addr); err == nil {
+		ip = host
+	}
+
+	if name := pl.asmap.Lookup(ip); name != "" {
+		return name
+	}
+
+	return iputil.NetGroup(ip)
+}
+
+// bucket returns the bucket that a peer belongs in
+func (pl *peerlist) bucket(p Peer) bucketID {
+	if p.Tried {
+		return pl.table.triedBucket(p.Addr, pl.group(p.Addr))
+	}
+
+	sourceGroup := ""
+	if p.Source != "" {
+		sourceGroup = pl.group(p.Source)
+	}
+	return pl.table.newBucket(pl.group(p.Addr), sourceGroup)
+}
+
+// makeRoom evicts the peer that was seen least recently from a full bucket.
+// Trusted and private peers are never evicted.
+func (pl *peerlist) makeRoom(id bucketID) {
+	if pl.table.size(id) < bucketSize {
+		return
+	}
+
+	var oldest *Peer
+	for _, a := range pl.table.members(id) {
+		p := pl.peers[a]
+		if p.Trusted || p.Private {
+			continue
+		}
+		if oldest == nil || p.LastSeen < oldest.LastSeen {
+			oldest = p
+		}
+	}
+
+	if oldest == nil {
+		return
+	}
+
+	if !id.tried {
+		logger.WithField("addr", oldest.Addr).Debug("New table bucket is full, removing the oldest peer")
+		pl.removePeer(oldest.Addr)
+		return
+	}
+
+	// Peers evicted from the tried table are moved back to the new table
+	logger.WithField("addr", oldest.Addr).Debug("Tried table bucket is full, moving the oldest peer to the new table")
+	oldest.Tried = false
+	newID := pl.bucket(*oldest)
+	pl.table.remove(oldest.Addr)
+	pl.makeRoom(newID)
+	pl.table.place(oldest.Addr, newID)
+}
+
 func (pl *peerlist) hasPeer(addr string) bool {
 	p, ok := pl.peers[addr]
 	return ok && p != nil
 }
 
 func (pl *peerlist) addPeer(addr string) {
+	pl.addPeerFrom(addr, "")
+}
+
+// addPeerFrom adds a peer that was received from the peer at source, which is empty if the peer was not received from a peer.
+// If the peer's bucket is full, the peer in that bucket that was seen least recently is removed
+func (pl *peerlist) addPeerFrom(addr, source string) {
 	if p, ok := pl.peers[addr]; ok && p != nil {
 		p.Seen()
 		return
 	}
 
 	peer := NewPeer(addr)
+	peer.Source = source
+
+	id := pl.bucket(*peer)
+	pl.makeRoom(id)
+
 	pl.peers[addr] = peer
+	pl.table.place(addr, id)
 }
 
 func (pl *peerlist) addPeers(addrs []string) {
+	pl.addPeersFrom(addrs, "")
+}
+
+func (pl *peerlist) addPeersFrom(addrs []string, source string) {
 	for _, addr := range addrs {
-		pl.addPeer(addr)
+		pl.addPeerFrom(addr, source)
+	}
+}
+
+// markTried moves a peer that we have connected to into the tried table.
+// If the peer's tried bucket is full, the peer in that bucket that was seen least recently is moved back to the new table
+func (pl *peerlist) markTried(addr string) error {
+	p, ok := pl.peers[addr]
+	if !ok {
+		return fmt.Errorf("mark peer tried failed: %v does not exist in peer list", addr)
+	}
+
+	p.Seen()
+
+	if p.Tried {
+		return nil
 	}
+
+	p.Tried = true
+	id := pl.bucket(*p)
+	pl.table.remove(addr)
+	pl.makeRoom(id)
+	pl.table.place(addr, id)
+
+	return nil
 }
 
 func (pl *peerlist) seen(addr string) {
@@ -187,6 +298,19 @@ var isExchangeable = []Filter{hasIncomingPort, isPublic}
 // removePeer removes peer
 func (pl *peerlist) removePeer(addr string) {
 	delete(pl.peers, addr)
+	pl.table.remove(addr)
+}
+
+// removeUntrustedByIP removes the untrusted peers with the given IP
+func (pl *peerlist) removeUntrustedByIP(ip string) {
+	for addr, p := range pl.peers {
+		if p.Trusted {
+			continue
+		}
+		if a, err := banIP(addr); err == nil && a == ip {
+			pl.removePeer(addr)
+		}
+	}
 }
 
 // SetPrivate sets specific peer as private
@@ -258,29 +382,90 @@ func (pl *peerlist) clearOld(timeAgo time.Duration) {
 	for addr, peer := range pl.peers {
 		lastSeen := time.Unix(peer.LastSeen, 0)
 		if !peer.Private && !peer.Trusted && t.Sub(lastSeen) > timeAgo {
-			delete(pl.peers, addr)
+			pl.removePeer(addr)
 		}
 	}
 }
 
 // Returns n random peers, or all of the peers, whichever is lower.
 // If count is 0, all of the peers are returned, shuffled.
+//
+// Each peer is selected by choosing the new or the tried table with equal probability, then a random bucket
+// of that table, then a random peer in that bucket. The chance of selecting a peer is therefore bounded
+// by the number of buckets its network group can occupy, not by the number of addresses in the network group.
+// Peers in network groups that have not been selected yet are preferred.
 func (pl *peerlist) random(count int, flts []Filter) Peers {
-	keys := pl.getCanTryPeers(flts).ToAddrs()
-	if len(keys) == 0 {
+	peers := pl.getCanTryPeers(flts)
+	if len(peers) == 0 {
 		return Peers{}
 	}
 
 	max := count
-	if max == 0 || max > len(keys) {
-		max = len(keys)
+	if max == 0 || max > len(peers) {
+		max = len(peers)
 	}
 
-	ps := make(Peers, max)
-	perm := rand.Perm(len(keys))
-	for i, j := range perm[:max] {
-		ps[i] = *pl.peers[keys[j]]
+	// Gather the peers by bucket, with the new table at index 0 and the tried table at index 1
+	var tables [2][]Peers
+	indexes := make(map[bucketID]int)
+	for _, p := range peers {
+		id, ok := pl.table.position(p.Addr)
+		if !ok {
+			id = pl.bucket(p)
+		}
+
+		t := 0
+		if id.tried {
+			t = 1
+		}
+
+		i, ok := indexes[id]
+		if !ok {
+			i = len(tables[t])
+			indexes[id] = i
+			tables[t] = append(tables[t], Peers{})
+		}
+		tables[t][i] = append(tables[t][i], p)
+	}
+
+	ps := make(Peers, 0, max)
+	var repeatedGroups Peers
+	groups := make(map[string]struct{})
+	for len(ps) < max && len(tables[0])+len(tables[1]) > 0 {
+		t := rand.Intn(2)
+		if len(tables[t]) == 0 {
+			t = 1 - t
+		}
+
+		// Take a random peer out of a random bucket
+		i := rand.Intn(len(tables[t]))
+		b := tables[t][i]
+		j := rand.Intn(len(b))
+		p := b[j]
+
+		b[j] = b[len(b)-1]
+		b = b[:len(b)-1]
+		if len(b) == 0 {
+			tables[t][i] = tables[t][len(tables[t])-1]
+			tables[t] = tables[t][:len(tables[t])-1]
+		} else {
+			tables[t][i] = b
+		}
+
+		group := pl.group(p.Addr)
+		if _, ok := groups[group]; ok && group != "" {
+			repeatedGroups = append(repeatedGroups, p)
+			continue
+		}
+
+		groups[group] = struct{}{}
+		ps = append(ps, p)
+	}
+
+	if n := max - len(ps); n > 0 {
+		ps = append(ps, repeatedGroups[:n]...)
 	}
+
 	return ps
 }
 
@@ -358,6 +543,8 @@ type PeerJSON struct {
 	HasIncomePort   *bool `json:"HasIncomePort,omitempty"` // Whether this peer has incoming port [DEPRECATED]
 	HasIncomingPort *bool // Whether this peer has incoming port
 	UserAgent       useragent.Data
+	Tried           bool   `json:",omitempty"` // Whether we have connected to this peer
+	Source          string `json:",omitempty"` // Address of the peer that sent us this peer
 }
 
 // newPeerJSON returns a PeerJSON from a Peer
@@ -369,6 +556,8 @@ func newPeerJSON(p Peer) PeerJSON {
 		Trusted:         p.Trusted,
 		HasIncomingPort: &p.HasIncomingPort,
 		UserAgent:       p.UserAgent,
+		Tried:           p.Tried,
+		Source:          p.Source,
 	}
 }
 
@@ -413,5 +602,7 @@ func newPeerFromJSON(p PeerJSON) (*Peer, error) {
 		Trusted:         p.Trusted,
 		HasIncomingPort: hasIncomingPort,
 		UserAgent:       p.UserAgent,
+		Tried:           p.Tried,
+		Source:          p.Source,
 	}, nil
 }
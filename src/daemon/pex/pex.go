@@ -20,7 +20,9 @@ import (
 	"github.com/cenkalti/backoff"
 	"github.com/sirupsen/logrus"
 
+	"github.com/skycoin/skycoin/src/util/iputil"
 	"github.com/skycoin/skycoin/src/util/logging"
+	"github.com/skycoin/skycoin/src/util/socks5"
 	"github.com/skycoin/skycoin/src/util/useragent"
 )
 
@@ -40,6 +42,8 @@ const (
 	oldPeerCacheFilename = "peers.txt"
 	// MaxPeerRetryTimes is the maximum number of times to retry a peer
 	MaxPeerRetryTimes = 10
+	// proxyDialTimeout is the timeout for connecting to a destination through the proxy when downloading the peers list
+	proxyDialTimeout = time.Second * 30
 )
 
 var (
@@ -68,12 +72,12 @@ var (
 // validateAddress returns a sanitized address if valid, otherwise an error
 func validateAddress(ipPort string, allowLocalhost bool) (string, error) {
 	ipPort = whitespaceFilter.ReplaceAllString(ipPort, "")
-	pts := strings.Split(ipPort, ":")
-	if len(pts) != 2 {
+	host, portStr, err := net.SplitHostPort(ipPort)
+	if err != nil {
 		return "", ErrInvalidAddress
 	}
 
-	ip := net.ParseIP(pts[0])
+	ip := net.ParseIP(host)
 	if ip == nil {
 		return "", ErrInvalidAddress
 	} else if ip.IsLoopback() {
@@ -84,7 +88,7 @@ func validateAddress(ipPort string, allowLocalhost bool) (string, error) {
 		return "", ErrNotExternalIP
 	}
 
-	port, err := strconv.ParseUint(pts[1], 10, 16)
+	port, err := strconv.ParseUint(portStr, 10, 16)
 	if err != nil {
 		return "", ErrInvalidAddress
 	}
@@ -93,7 +97,8 @@ func validateAddress(ipPort string, allowLocalhost bool) (string, error) {
 		return "", ErrPortTooLow
 	}
 
-	return ipPort, nil
+	// Normalize the address, so that the same IPv6 endpoint always has the same string form
+	return iputil.JoinAddr(ip.String(), uint16(port)), nil
 }
 
 // Peer represents a known peer
@@ -104,6 +109,8 @@ type Peer struct {
 	Trusted         bool           // Whether this peer is trusted
 	HasIncomingPort bool           // Whether this peer has accessible public port
 	UserAgent       useragent.Data // Peer's last reported user agent
+	Tried           bool           // Whether we have connected to this peer
+	Source          string         // Address of the peer that sent us this peer, empty if it was not received from a peer
 	RetryTimes      int            `json:"-"` // records the retry times
 }
 
@@ -190,6 +197,15 @@ type Config struct {
 	CustomPeersFile string
 	// Default "trusted" connections
 	DefaultConnections []string
+	// Address of a SOCKS5 proxy to download the peers list through, host:port. Leave empty to connect directly
+	Proxy string
+	// Authenticate each proxied connection with random credentials, for stream isolation
+	ProxyIsolateAuth bool
+	// Only connect to peers in these networks, iputil.NetworkIPv4 or iputil.NetworkIPv6. Leave empty to allow all networks
+	OnlyNetworks []string
+	// Load a map of IP ranges to autonomous systems from this file, to group peers by autonomous system.
+	// See LoadASMap for the format. Leave empty to group peers by IPv4 /16 and IPv6 /32 network only
+	ASMapFile string
 }
 
 // NewConfig creates default pex config.
@@ -218,18 +234,44 @@ type Pex struct {
 	sync.RWMutex
 	// All known peers
 	peerlist peerlist
-	Config   Config
-	quit     chan struct{}
-	done     chan struct{}
+	// Banned IPs
+	blacklist blacklist
+	Config    Config
+	quit      chan struct{}
+	done      chan struct{}
 }
 
 // New creates pex
 func New(cfg Config) (*Pex, error) {
+	for _, n := range cfg.OnlyNetworks {
+		if err := iputil.ValidateNetwork(n); err != nil {
+			return nil, fmt.Errorf("Invalid OnlyNetworks entry %q: %v", n, err)
+		}
+	}
+
 	pex := &Pex{
-		Config:   cfg,
-		peerlist: newPeerlist(),
-		quit:     make(chan struct{}),
-		done:     make(chan struct{}),
+		Config:    cfg,
+		peerlist:  newPeerlist(),
+		blacklist: newBlacklist(),
+		quit:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+
+	// Load the ASMap before the peers, since it determines their buckets
+	if cfg.ASMapFile != "" {
+		asmap, err := LoadASMap(cfg.ASMapFile)
+		if err != nil {
+			logger.Critical().WithError(err).WithField("file", cfg.ASMapFile).Error("Failed to load ASMap file")
+			return nil, err
+		}
+		logger.Infof("Loaded %d networks from ASMap file %s", asmap.Len(), cfg.ASMapFile)
+		pex.peerlist.asmap = asmap
+	}
+
+	// Load bans from disk
+	if err := pex.loadBlacklist(); err != nil {
+		logger.Critical().WithError(err).Error("pex.loadBlacklist failed")
+		return nil, err
 	}
 
 	// Load peers from disk
@@ -293,9 +335,16 @@ func (px *Pex) Run() error {
 		if err := px.save(); err != nil {
 			logger.WithError(err).Error("Save peerlist failed")
 		}
+
+		// Save the blacklist
+		logger.Info("Save blacklist")
+		if err := px.saveBlacklist(); err != nil {
+			logger.WithError(err).Error("Save blacklist failed")
+		}
 	}()
 
 	clearOldTicker := time.NewTicker(px.Config.ClearOldRate)
+	updateBlacklistTicker := time.NewTicker(px.Config.UpdateBlacklistRate)
 
 	for {
 		select {
@@ -308,6 +357,15 @@ func (px *Pex) Run() error {
 					px.peerlist.clearOld(px.Config.Expiration)
 				}()
 			}
+		case <-updateBlacklistTicker.C:
+			// Remove expired bans
+			func() {
+				px.Lock()
+				defer px.Unlock()
+				if n := px.blacklist.clearExpired(time.Now().UTC()); n > 0 {
+					logger.Infof("Removed %d expired bans", n)
+				}
+			}()
 		case <-px.quit:
 			return nil
 		}
@@ -323,7 +381,7 @@ func (px *Pex) Shutdown() {
 }
 
 func (px *Pex) downloadPeers() error {
-	body, err := backoffDownloadText(px.Config.PeerListURL)
+	body, err := backoffDownloadText(px.httpClient(), px.Config.PeerListURL)
 	if err != nil {
 		logger.WithError(err).WithField("url", px.Config.PeerListURL).Error("Failed to download peers")
 		return err
@@ -419,6 +477,39 @@ func (px *Pex) save() error {
 	return px.peerlist.save(fn)
 }
 
+func (px *Pex) loadBlacklist() error {
+	px.Lock()
+	defer px.Unlock()
+
+	fp := filepath.Join(px.Config.DataDirectory, BlacklistFilename)
+	bans, err := loadBlacklistFile(fp)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	for _, b := range bans {
+		if !b.Expired(now) {
+			px.blacklist.add(b)
+		}
+	}
+
+	return nil
+}
+
+// saveBlacklist persists the blacklist
+func (px *Pex) saveBlacklist() error {
+	px.Lock()
+	defer px.Unlock()
+
+	return px.saveBlacklistLocked()
+}
+
+func (px *Pex) saveBlacklistLocked() error {
+	fn := filepath.Join(px.Config.DataDirectory, BlacklistFilename)
+	return px.blacklist.save(fn)
+}
+
 // AddPeer adds a peer to the peer list, given an address. If the peer list is
 // full, it will try to remove an old peer to make room.
 // If no room can be made, ErrPeerlistFull is returned
@@ -432,6 +523,10 @@ func (px *Pex) AddPeer(addr string) error {
 		return ErrInvalidAddress
 	}
 
+	if px.isBanned(cleanAddr) {
+		return ErrBlacklistedAddress
+	}
+
 	if px.peerlist.hasPeer(cleanAddr) {
 		px.peerlist.seen(cleanAddr)
 		return nil
@@ -462,6 +557,12 @@ func (px *Pex) AddPeer(addr string) error {
 // Returns the number of peers that were added without error. Note that
 // adding a duplicate peer will not cause an error.
 func (px *Pex) AddPeers(addrs []string) int {
+	return px.AddPeersFrom(addrs, "")
+}
+
+// AddPeersFrom adds multiple peers that were received from the peer at source, like AddPeers.
+// The network group of the source limits the part of the address table that these peers can occupy.
+func (px *Pex) AddPeersFrom(addrs []string, source string) int {
 	px.Lock()
 	defer px.Unlock()
 
@@ -478,6 +579,10 @@ func (px *Pex) AddPeers(addrs []string) int {
 			logger.WithField("addr", addr).WithError(err).Info("Add peers sees an invalid address")
 			continue
 		}
+		if px.isBanned(a) {
+			logger.WithField("addr", addr).Debug("Add peers sees a banned address")
+			continue
+		}
 		validAddrs = append(validAddrs, a)
 	}
 	addrs = validAddrs
@@ -494,7 +599,7 @@ func (px *Pex) AddPeers(addrs []string) int {
 		}
 	}
 
-	px.peerlist.addPeers(addrs)
+	px.peerlist.addPeersFrom(addrs, source)
 	return len(addrs)
 }
 
@@ -548,6 +653,20 @@ func (px *Pex) SetHasIncomingPort(addr string, hasPublicPort bool) error {
 	return px.peerlist.setHasIncomingPort(cleanAddr, hasPublicPort)
 }
 
+// MarkTried moves a peer that we have connected to from the new table to the tried table
+func (px *Pex) MarkTried(addr string) error {
+	px.Lock()
+	defer px.Unlock()
+
+	cleanAddr, err := validateAddress(addr, px.Config.AllowLocalhost)
+	if err != nil {
+		logger.WithError(err).WithField("addr", addr).Error("Invalid address")
+		return ErrInvalidAddress
+	}
+
+	return px.peerlist.markTried(cleanAddr)
+}
+
 // SetUserAgent sets the peer's user agent
 func (px *Pex) SetUserAgent(addr string, userAgent useragent.Data) error {
 	px.Lock()
@@ -593,14 +712,14 @@ func (px *Pex) Trusted() Peers {
 func (px *Pex) Private() Peers {
 	px.RLock()
 	defer px.RUnlock()
-	return px.peerlist.getCanTryPeers([]Filter{isPrivate})
+	return px.peerlist.getCanTryPeers([]Filter{isPrivate, px.isAllowedNetwork})
 }
 
 // TrustedPublic returns trusted public peers
 func (px *Pex) TrustedPublic() Peers {
 	px.RLock()
 	defer px.RUnlock()
-	return px.peerlist.getCanTryPeers([]Filter{isPublic, isTrusted})
+	return px.peerlist.getCanTryPeers([]Filter{isPublic, isTrusted, px.isAllowedNetwork})
 }
 
 // RandomPublic returns N random public untrusted peers
@@ -609,7 +728,16 @@ func (px *Pex) RandomPublic(n int) Peers {
 	defer px.RUnlock()
 	return px.peerlist.random(n, []Filter{func(p Peer) bool {
 		return !p.Private
-	}})
+	}, px.isAllowedNetwork})
+}
+
+// RandomNew returns N random public untrusted peers that we have not connected to
+func (px *Pex) RandomNew(n int) Peers {
+	px.RLock()
+	defer px.RUnlock()
+	return px.peerlist.random(n, []Filter{func(p Peer) bool {
+		return !p.Private && !p.Trusted && !p.Tried
+	}, px.isAllowedNetwork})
 }
 
 // RandomExchangeable returns N random exchangeable peers
@@ -619,6 +747,42 @@ func (px *Pex) RandomExchangeable(n int) Peers {
 	return px.peerlist.random(n, isExchangeable)
 }
 
+// AllowedNetwork returns true if the IP of addr, which is of the form ip or ip:port,
+// is in one of the networks in Config.OnlyNetworks, or if all networks are allowed
+func (px *Pex) AllowedNetwork(addr string) bool {
+	if len(px.Config.OnlyNetworks) == 0 {
+		return true
+	}
+
+	ip := addr
+	if host, _, err := iputil.SplitAddr(addr); err == nil {
+		ip = host
+	}
+
+	network := iputil.IPNetwork(ip)
+	for _, n := range px.Config.OnlyNetworks {
+		if n == network {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (px *Pex) isAllowedNetwork(p Peer) bool {
+	return px.AllowedNetwork(p.Addr)
+}
+
+// ASN returns the name of the autonomous system of the IP of addr, which is of the form ip or ip:port.
+// Returns an empty string if no ASMap is loaded or if the IP is not in the ASMap.
+func (px *Pex) ASN(addr string) string {
+	ip := addr
+	if host, _, err := iputil.SplitAddr(addr); err == nil {
+		ip = host
+	}
+	return px.peerlist.asmap.Lookup(ip)
+}
+
 // IncreaseRetryTimes increases retry times
 func (px *Pex) IncreaseRetryTimes(addr string) {
 	px.Lock()
@@ -640,6 +804,84 @@ func (px *Pex) ResetAllRetryTimes() {
 	px.peerlist.resetAllRetryTimes()
 }
 
+// Ban bans all connections from the IP of addr, which is of the form ip or ip:port, for the given duration.
+// Untrusted peers with this IP are removed from the peer list.
+// The blacklist is saved to disk.
+func (px *Pex) Ban(addr string, duration time.Duration, reason string) (Ban, error) {
+	if duration <= 0 {
+		return Ban{}, ErrInvalidBanDuration
+	}
+
+	ip, err := banIP(addr)
+	if err != nil {
+		return Ban{}, err
+	}
+
+	px.Lock()
+	defer px.Unlock()
+
+	now := time.Now().UTC()
+	b := Ban{
+		IP:      ip,
+		Reason:  reason,
+		Created: now,
+		Expires: now.Add(duration),
+	}
+	px.blacklist.add(b)
+	px.peerlist.removeUntrustedByIP(ip)
+
+	return b, px.saveBlacklistLocked()
+}
+
+// Unban removes the ban of an IP, given as ip or ip:port.
+// Returns ErrNotBanned if the IP is not banned.
+// The blacklist is saved to disk.
+func (px *Pex) Unban(addr string) error {
+	ip, err := banIP(addr)
+	if err != nil {
+		return err
+	}
+
+	px.Lock()
+	defer px.Unlock()
+
+	if !px.blacklist.remove(ip) {
+		return ErrNotBanned
+	}
+
+	return px.saveBlacklistLocked()
+}
+
+// IsBanned returns true if the IP of addr, which is of the form ip or ip:port, is banned
+func (px *Pex) IsBanned(addr string) bool {
+	px.RLock()
+	defer px.RUnlock()
+	return px.isBanned(addr)
+}
+
+func (px *Pex) isBanned(addr string) bool {
+	ip, err := banIP(addr)
+	if err != nil {
+		return false
+	}
+	return px.blacklist.isBanned(ip, time.Now().UTC())
+}
+
+// Bans returns the bans that have not expired, sorted by IP
+func (px *Pex) Bans() []Ban {
+	px.RLock()
+	defer px.RUnlock()
+
+	now := time.Now().UTC()
+	var bans []Ban
+	for _, b := range px.blacklist.all() {
+		if !b.Expired(now) {
+			bans = append(bans, b)
+		}
+	}
+	return bans
+}
+
 // IsFull returns whether the peer list is full
 func (px *Pex) IsFull() bool {
 	px.RLock()
@@ -651,11 +893,30 @@ func (px *Pex) isFull() bool {
 	return px.Config.Max > 0 && px.peerlist.len() >= px.Config.Max
 }
 
+// httpClient returns the client for downloading the peers list, which connects through the proxy if one is configured
+func (px *Pex) httpClient() *http.Client {
+	if px.Config.Proxy == "" {
+		return http.DefaultClient
+	}
+
+	d := &socks5.Dialer{
+		ProxyAddr:   px.Config.Proxy,
+		IsolateAuth: px.Config.ProxyIsolateAuth,
+		Timeout:     proxyDialTimeout,
+	}
+
+	return &http.Client{
+		Transport: &http.Transport{
+			DialContext: d.DialContext,
+		},
+	}
+}
+
 // downloadText downloads a text format file from url.
 // Returns the raw response body as a string.
 // TODO -- move to util, add backoff options
-func downloadText(url string) (string, error) {
-	resp, err := http.Get(url)
+func downloadText(client *http.Client, url string) (string, error) {
+	resp, err := client.Get(url)
 	if err != nil {
 		return "", err
 	}
@@ -669,7 +930,7 @@ func downloadText(url string) (string, error) {
 	return string(body), nil
 }
 
-func backoffDownloadText(url string) (string, error) {
+func backoffDownloadText(client *http.Client, url string) (string, error) {
 	var body string
 
 	b := backoff.NewExponentialBackOff()
@@ -681,7 +942,7 @@ func backoffDownloadText(url string) (string, error) {
 	operation := func() error {
 		logger.WithField("url", url).Info("Trying to download peers list")
 		var err error
-		body, err = downloadText(url)
+		body, err = downloadText(client, url)
 		return err
 	}
 
@@ -3,14 +3,19 @@ package pex
 import (
 	"fmt"
 	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"sort"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/require"
 
+	"github.com/skycoin/skycoin/src/testutil"
 	"github.com/skycoin/skycoin/src/util/file"
+	"github.com/skycoin/skycoin/src/util/iputil"
 )
 
 func TestValidateAddress(t *testing.T) {
@@ -140,6 +145,44 @@ func TestValidateAddress(t *testing.T) {
 			allowLocalhost: false,
 			cleanAddr:      "11.22.33.44:8080",
 		},
+		{
+			addr:           "[2001:db8::68]:8080",
+			allowLocalhost: false,
+		},
+		{
+			addr:           "[2001:0db8:0000:0000:0000:0000:0000:0068]:8080",
+			allowLocalhost: false,
+			cleanAddr:      "[2001:db8::68]:8080",
+		},
+		{
+			addr:           "2001:db8::68:8080",
+			allowLocalhost: false,
+			err:            ErrInvalidAddress,
+		},
+		{
+			addr:           "[2001:db8::68]:1000",
+			allowLocalhost: false,
+			err:            ErrPortTooLow,
+		},
+		{
+			addr:           "[::1]:8888",
+			allowLocalhost: true,
+		},
+		{
+			addr:           "[::1]:8888",
+			allowLocalhost: false,
+			err:            ErrNoLocalhost,
+		},
+		{
+			addr:           "[::]:8888",
+			allowLocalhost: false,
+			err:            ErrNotExternalIP,
+		},
+		{
+			addr:           "[fe80::1]:8888",
+			allowLocalhost: false,
+			err:            ErrNotExternalIP,
+		},
 	}
 
 	for _, tc := range cases {
@@ -1477,6 +1520,124 @@ func TestPexIsFull(t *testing.T) {
 	require.True(t, pex.IsFull())
 }
 
+func TestNewPexInvalidOnlyNetworks(t *testing.T) {
+	config := NewConfig()
+	config.OnlyNetworks = []string{iputil.NetworkIPv4, "onion"}
+
+	_, err := New(config)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), iputil.ErrInvalidNetwork.Error())
+}
+
+func TestPexOnlyNetworks(t *testing.T) {
+	ipv4 := "112.32.32.14:7200"
+	ipv6 := "[2001:db8::1]:7200"
+
+	cases := []struct {
+		name         string
+		onlyNetworks []string
+		expect       []string
+	}{
+		{
+			name:   "all networks",
+			expect: []string{ipv4, ipv6},
+		},
+		{
+			name:         "ipv4",
+			onlyNetworks: []string{iputil.NetworkIPv4},
+			expect:       []string{ipv4},
+		},
+		{
+			name:         "ipv6",
+			onlyNetworks: []string{iputil.NetworkIPv6},
+			expect:       []string{ipv6},
+		},
+		{
+			name:         "ipv4 and ipv6",
+			onlyNetworks: []string{iputil.NetworkIPv6, iputil.NetworkIPv4},
+			expect:       []string{ipv4, ipv6},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			pex := &Pex{
+				peerlist: newPeerlist(),
+				Config: Config{
+					OnlyNetworks: tc.onlyNetworks,
+				},
+			}
+
+			pex.peerlist.setPeers([]Peer{
+				{Addr: ipv4},
+				{Addr: ipv6},
+			})
+			requireAddrsMatch(t, tc.expect, pex.RandomPublic(0))
+
+			pex.peerlist.setPeers([]Peer{
+				{Addr: ipv4, Trusted: true},
+				{Addr: ipv6, Trusted: true},
+			})
+			requireAddrsMatch(t, tc.expect, pex.TrustedPublic())
+
+			pex.peerlist.setPeers([]Peer{
+				{Addr: ipv4, Private: true},
+				{Addr: ipv6, Private: true},
+			})
+			requireAddrsMatch(t, tc.expect, pex.Private())
+
+			for _, addr := range []string{ipv4, ipv6} {
+				allowed := false
+				for _, a := range tc.expect {
+					if a == addr {
+						allowed = true
+					}
+				}
+				require.Equal(t, allowed, pex.AllowedNetwork(addr))
+			}
+		})
+	}
+}
+
+func requireAddrsMatch(t *testing.T, expect []string, peers Peers) {
+	addrs := peers.ToAddrs()
+	sort.Strings(addrs)
+	expect = append([]string{}, expect...)
+	sort.Strings(expect)
+	require.Equal(t, expect, addrs)
+}
+
+func TestDownloadTextProxy(t *testing.T) {
+	body := "11.22.33.44:5555\n66.55.44.33:2020\n"
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, body)
+	}))
+	defer s.Close()
+
+	proxy := testutil.NewSOCKS5Server(t, false, "", "")
+	defer proxy.Close()
+
+	pex := &Pex{
+		Config: Config{
+			Proxy:            proxy.Addr,
+			ProxyIsolateAuth: true,
+		},
+	}
+
+	text, err := downloadText(pex.httpClient(), s.URL)
+	require.NoError(t, err)
+	require.Equal(t, body, text)
+
+	requests := proxy.Requests()
+	require.Len(t, requests, 1)
+	require.Equal(t, s.Listener.Addr().String(), requests[0].Addr)
+	require.NotEmpty(t, requests[0].Username)
+
+	// Without a proxy the default client is used
+	pex.Config.Proxy = ""
+	require.Equal(t, http.DefaultClient, pex.httpClient())
+}
+
 func TestParseRemotePeerList(t *testing.T) {
 	body := `11.22.33.44:5555
 66.55.44.33:2020
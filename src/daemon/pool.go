@@ -35,8 +35,11 @@ type PoolConfig struct {
 	// Maximum length of outgoing messages in bytes
 	MaxOutgoingMessageLength int
 	// These should be assigned by the controlling daemon
-	address string
-	port    int
+	address            string
+	port               int
+	encryptConnections bool
+	proxy              string
+	proxyIsolateAuth   bool
 }
 
 // NewPoolConfig creates pool config
@@ -80,6 +83,9 @@ func NewPool(cfg PoolConfig, d *Daemon) (*Pool, error) {
 	gnetCfg.DefaultConnections = cfg.DefaultConnections
 	gnetCfg.MaxIncomingMessageLength = cfg.MaxIncomingMessageLength
 	gnetCfg.MaxOutgoingMessageLength = cfg.MaxOutgoingMessageLength
+	gnetCfg.EncryptConnections = cfg.encryptConnections
+	gnetCfg.Proxy = cfg.proxy
+	gnetCfg.ProxyIsolateAuth = cfg.proxyIsolateAuth
 
 	pool, err := gnet.NewConnectionPool(gnetCfg, d)
 	if err != nil {
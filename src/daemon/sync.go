@@ -0,0 +1,670 @@
+package daemon
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/skycoin/skycoin/src/coin"
+)
+
+var (
+	// ErrSyncBlocksNotSequential blocks in a GiveBlocksMessage are not sequential
+	ErrSyncBlocksNotSequential = errors.New("Received blocks are not sequential")
+	// ErrSyncBlocksNotChained blocks in a GiveBlocksMessage do not reference the previous block's hash
+	ErrSyncBlocksNotChained = errors.New("Received blocks are not chained by their previous block hash")
+	// ErrSyncBlocksOutOfRange blocks in a GiveBlocksMessage go beyond the end of the requested range
+	ErrSyncBlocksOutOfRange = errors.New("Received blocks beyond the requested range")
+)
+
+// syncConfig configures the syncManager
+type syncConfig struct {
+	// Number of blocks to request in one GetBlocksMessage
+	RangeSize uint64
+	// Maximum number of requests that a peer can have in flight
+	MaxRequestsPerPeer int
+	// Maximum number of blocks ahead of the head block that are requested or buffered
+	MaxBufferedBlocks uint64
+	// How long to wait for a response before requesting the range from another peer.
+	// A peer that times out is not sent requests for the same duration.
+	RequestTimeout time.Duration
+}
+
+// syncRange is an inclusive range of block sequences
+type syncRange struct {
+	Start uint64
+	End   uint64
+}
+
+// Len returns the number of blocks in the range
+func (r syncRange) Len() uint64 {
+	return r.End - r.Start + 1
+}
+
+// syncRequest is a block range requested from a peer
+type syncRequest struct {
+	syncRange
+	Addr   string
+	GnetID uint64
+	SentAt time.Time
+}
+
+// syncPeer is an introduced peer that blocks can be requested from
+type syncPeer struct {
+	Addr   string
+	GnetID uint64
+	Height uint64
+	// The peer only has the most recent PrunedPeerKeepBlocks blocks
+	Pruned bool
+}
+
+// has returns true if the peer has the blocks from start to end
+func (p syncPeer) has(start, end uint64) bool {
+	if p.Height < end {
+		return false
+	}
+
+	return !p.Pruned || p.Height-start < PrunedPeerKeepBlocks
+}
+
+// syncBlock is a received block waiting for the blocks before it
+type syncBlock struct {
+	Block  coin.SignedBlock
+	Addr   string
+	GnetID uint64
+}
+
+// peerSyncStats are the block download statistics of a peer
+type peerSyncStats struct {
+	received uint64
+	timeouts uint64
+}
+
+// SyncProgress is the status of the block download
+type SyncProgress struct {
+	// Number of block requests waiting for a response
+	RequestsInFlight int
+	// Number of blocks in requests waiting for a response
+	BlocksInFlight uint64
+	// Number of received blocks waiting for earlier blocks to be received
+	BlocksBuffered int
+	// Number of block requests that timed out
+	Timeouts uint64
+	// Block download status of each peer
+	Peers []PeerSyncProgress
+}
+
+// PeerSyncProgress is the block download status of a peer
+type PeerSyncProgress struct {
+	Address          string
+	RequestsInFlight int
+	BlocksReceived   uint64
+	Timeouts         uint64
+}
+
+// syncManager downloads blocks from multiple peers in parallel.
+// The blocks after the head block are split into disjoint ranges which are assigned to peers that
+// have reported a height that covers them. Pruned peers are only assigned the recent blocks that they keep.
+// Each peer can have several requests in flight.
+// Responses can arrive out of order; they are buffered until the blocks before them have been received.
+// Requests that are not answered in time, or only partially answered, are reassigned.
+type syncManager struct {
+	sync.Mutex
+	config syncConfig
+	// head block sequence
+	head uint64
+	// next block sequence that has not been requested
+	next uint64
+	// ranges that must be requested again, sorted by start
+	queue []syncRange
+	// requests waiting for a response
+	requests []*syncRequest
+	// received blocks after the head block
+	blocks map[uint64]syncBlock
+	// peers that are not sent requests until the given time
+	backoff map[string]time.Time
+	// statistics of connected peers
+	stats    map[string]*peerSyncStats
+	timeouts uint64
+	// request for the bodies of blocks below the head block that are missing after starting from a snapshot
+	backfill *syncRequest
+}
+
+// newSyncManager creates a syncManager
+func newSyncManager(c syncConfig) *syncManager {
+	return &syncManager{
+		config:  c,
+		next:    1,
+		blocks:  make(map[uint64]syncBlock),
+		backoff: make(map[string]time.Time),
+		stats:   make(map[string]*peerSyncStats),
+	}
+}
+
+// setHead updates the head block sequence and forgets the blocks and requests at or below it
+func (s *syncManager) setHead(head uint64) {
+	s.Lock()
+	defer s.Unlock()
+	s.setHeadLocked(head)
+}
+
+func (s *syncManager) setHeadLocked(head uint64) {
+	if head == s.head {
+		return
+	}
+
+	if head < s.head {
+		// The blockchain was truncated, start over
+		s.queue = nil
+		s.next = head + 1
+	}
+
+	s.head = head
+
+	for seq := range s.blocks {
+		if seq <= head {
+			delete(s.blocks, seq)
+		}
+	}
+
+	if s.next <= head {
+		s.next = head + 1
+	}
+
+	queue := s.queue[:0]
+	for _, r := range s.queue {
+		if r.End <= head {
+			continue
+		}
+		if r.Start <= head {
+			r.Start = head + 1
+		}
+		queue = append(queue, r)
+	}
+	s.queue = queue
+
+	requests := s.requests[:0]
+	for _, r := range s.requests {
+		if r.End > head {
+			requests = append(requests, r)
+		}
+	}
+	s.requests = requests
+}
+
+// schedule assigns block ranges to peers, up to the highest peer height and at most
+// MaxBufferedBlocks ahead of the head block. The lowest ranges are assigned first, each to the eligible peer
+// with the fewest requests in flight. If no eligible peer has the whole range, the start of the range is
+// assigned to a peer that has it. Returns the new requests, which the caller must send.
+func (s *syncManager) schedule(now time.Time, peers []syncPeer) []syncRequest {
+	s.Lock()
+	defer s.Unlock()
+
+	for addr, t := range s.backoff {
+		if !now.Before(t) {
+			delete(s.backoff, addr)
+		}
+	}
+
+	var target uint64
+	for _, p := range peers {
+		if p.Height > target {
+			target = p.Height
+		}
+	}
+
+	if target <= s.head {
+		return nil
+	}
+
+	maxEnd := s.head + s.config.MaxBufferedBlocks
+	if target < maxEnd {
+		maxEnd = target
+	}
+
+	var reqs []syncRequest
+	for {
+		r, ok := s.nextRange(maxEnd)
+		if !ok {
+			break
+		}
+
+		p, ok := s.pickPeer(peers, r.Start, r.End)
+		if !ok {
+			// Request the start of the range from a peer that does not have all of it
+			p, ok = s.pickPeer(peers, r.Start, r.Start)
+			if !ok {
+				s.requeue(r)
+				break
+			}
+
+			s.requeue(syncRange{
+				Start: p.Height + 1,
+				End:   r.End,
+			})
+			r.End = p.Height
+		}
+
+		req := &syncRequest{
+			syncRange: r,
+			Addr:      p.Addr,
+			GnetID:    p.GnetID,
+			SentAt:    now,
+		}
+		s.requests = append(s.requests, req)
+		reqs = append(reqs, *req)
+	}
+
+	return reqs
+}
+
+// nextRange returns the lowest range that needs to be requested and ends at or before maxEnd
+func (s *syncManager) nextRange(maxEnd uint64) (syncRange, bool) {
+	for {
+		var r syncRange
+		if len(s.queue) > 0 {
+			r = s.queue[0]
+			if r.Start > maxEnd {
+				return syncRange{}, false
+			}
+			if r.End > maxEnd {
+				s.queue[0].Start = maxEnd + 1
+				r.End = maxEnd
+			} else {
+				s.queue = s.queue[1:]
+			}
+		} else {
+			if s.next > maxEnd {
+				return syncRange{}, false
+			}
+			r = syncRange{
+				Start: s.next,
+				End:   s.next + s.config.RangeSize - 1,
+			}
+			if r.End > maxEnd {
+				r.End = maxEnd
+			}
+			s.next = r.End + 1
+		}
+
+		// Don't request blocks that were already received
+		for r.Start <= r.End {
+			if _, ok := s.blocks[r.Start]; !ok {
+				break
+			}
+			r.Start++
+		}
+
+		if r.Start <= r.End {
+			return r, true
+		}
+	}
+}
+
+// pickPeer returns the peer with the fewest requests in flight that has the blocks from start to end
+func (s *syncManager) pickPeer(peers []syncPeer, start, end uint64) (syncPeer, bool) {
+	var best syncPeer
+	bestInFlight := -1
+	for _, p := range peers {
+		if !p.has(start, end) {
+			continue
+		}
+		if _, ok := s.backoff[p.Addr]; ok {
+			continue
+		}
+
+		n := s.inFlight(p.Addr, p.GnetID)
+		if n >= s.config.MaxRequestsPerPeer {
+			continue
+		}
+
+		if bestInFlight == -1 || n < bestInFlight {
+			best = p
+			bestInFlight = n
+		}
+	}
+
+	return best, bestInFlight != -1
+}
+
+func (s *syncManager) inFlight(addr string, gnetID uint64) int {
+	n := 0
+	for _, r := range s.requests {
+		if r.Addr == addr && r.GnetID == gnetID {
+			n++
+		}
+	}
+	return n
+}
+
+// requeue adds a range to the queue of ranges to request, keeping the queue sorted
+func (s *syncManager) requeue(r syncRange) {
+	if r.End <= s.head {
+		return
+	}
+	if r.Start <= s.head {
+		r.Start = s.head + 1
+	}
+
+	i := sort.Search(len(s.queue), func(i int) bool {
+		return s.queue[i].Start >= r.Start
+	})
+	s.queue = append(s.queue, syncRange{})
+	copy(s.queue[i+1:], s.queue[i:])
+	s.queue[i] = r
+}
+
+// removeRequest removes the i-th request
+func (s *syncManager) removeRequest(i int) *syncRequest {
+	r := s.requests[i]
+	s.requests = append(s.requests[:i], s.requests[i+1:]...)
+	return r
+}
+
+// receive buffers blocks received from a peer and completes the request they answer.
+// If the request was only partially answered, the rest of its range is requested again.
+// Blocks that are not sequential and chained, or that go beyond the request they answer, are rejected,
+// and the peer's request is reassigned.
+func (s *syncManager) receive(addr string, gnetID uint64, blocks []coin.SignedBlock) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if len(blocks) == 0 {
+		return nil
+	}
+
+	first := blocks[0].Seq()
+	last := blocks[len(blocks)-1].Seq()
+
+	var req *syncRequest
+	for i, r := range s.requests {
+		if r.Addr == addr && r.GnetID == gnetID && r.Start == first {
+			req = s.removeRequest(i)
+			break
+		}
+	}
+
+	err := verifySyncBlocks(blocks)
+	if err == nil && req != nil && last > req.End {
+		err = ErrSyncBlocksOutOfRange
+	}
+
+	if err != nil {
+		if req != nil {
+			s.requeue(req.syncRange)
+		}
+		return err
+	}
+
+	if req != nil && last < req.End {
+		s.requeue(syncRange{
+			Start: last + 1,
+			End:   req.End,
+		})
+	}
+
+	stats := s.peerStats(addr)
+	for _, b := range blocks {
+		seq := b.Seq()
+		if seq <= s.head || seq > s.head+s.config.MaxBufferedBlocks {
+			continue
+		}
+		if _, ok := s.blocks[seq]; ok {
+			continue
+		}
+
+		s.blocks[seq] = syncBlock{
+			Block:  b,
+			Addr:   addr,
+			GnetID: gnetID,
+		}
+		stats.received++
+	}
+
+	return nil
+}
+
+// verifySyncBlocks checks that blocks are sequential and that each block references the hash of the block before it
+func verifySyncBlocks(blocks []coin.SignedBlock) error {
+	for i := 1; i < len(blocks); i++ {
+		if blocks[i].Seq() != blocks[i-1].Seq()+1 {
+			return ErrSyncBlocksNotSequential
+		}
+		if blocks[i].Head.PrevHash != blocks[i-1].HashHeader() {
+			return ErrSyncBlocksNotChained
+		}
+	}
+	return nil
+}
+
+// pop removes and returns the block after the head block, if it has been received
+func (s *syncManager) pop() (syncBlock, bool) {
+	s.Lock()
+	defer s.Unlock()
+
+	b, ok := s.blocks[s.head+1]
+	if ok {
+		delete(s.blocks, s.head+1)
+	}
+	return b, ok
+}
+
+// rejectBlock is called when a received block fails to execute. The other blocks received from the same peer
+// are discarded, all the discarded blocks are requested again and the peer is not sent requests for a while.
+func (s *syncManager) rejectBlock(now time.Time, b syncBlock) {
+	s.Lock()
+	defer s.Unlock()
+
+	seqs := []uint64{b.Block.Seq()}
+	for seq, sb := range s.blocks {
+		if sb.Addr == b.Addr && sb.GnetID == b.GnetID {
+			delete(s.blocks, seq)
+			seqs = append(seqs, seq)
+		}
+	}
+
+	sort.Slice(seqs, func(i, j int) bool {
+		return seqs[i] < seqs[j]
+	})
+
+	// Requeue the discarded blocks as ranges of consecutive blocks
+	r := syncRange{
+		Start: seqs[0],
+		End:   seqs[0],
+	}
+	for _, seq := range seqs[1:] {
+		if seq == r.End+1 && r.Len() < s.config.RangeSize {
+			r.End = seq
+			continue
+		}
+		s.requeue(r)
+		r = syncRange{
+			Start: seq,
+			End:   seq,
+		}
+	}
+	s.requeue(r)
+
+	s.backoff[b.Addr] = now.Add(s.config.RequestTimeout)
+}
+
+// timeout reassigns requests that have been waiting for a response for longer than RequestTimeout.
+// Peers with timed out requests are not sent requests for a while. Returns the timed out requests.
+func (s *syncManager) timeout(now time.Time) []syncRequest {
+	s.Lock()
+	defer s.Unlock()
+
+	var timedOut []syncRequest
+	requests := s.requests[:0]
+	for _, r := range s.requests {
+		if now.Sub(r.SentAt) < s.config.RequestTimeout {
+			requests = append(requests, r)
+			continue
+		}
+
+		timedOut = append(timedOut, *r)
+		s.backoff[r.Addr] = now.Add(s.config.RequestTimeout)
+		s.peerStats(r.Addr).timeouts++
+		s.timeouts++
+	}
+	s.requests = requests
+
+	for _, r := range timedOut {
+		s.requeue(r.syncRange)
+	}
+
+	return timedOut
+}
+
+// cancel reassigns a request that could not be sent
+func (s *syncManager) cancel(req syncRequest) {
+	s.Lock()
+	defer s.Unlock()
+
+	for i, r := range s.requests {
+		if r.Addr == req.Addr && r.GnetID == req.GnetID && r.Start == req.Start {
+			s.removeRequest(i)
+			s.requeue(r.syncRange)
+			return
+		}
+	}
+}
+
+// disconnected reassigns the requests of a disconnected peer and forgets its statistics
+func (s *syncManager) disconnected(addr string, gnetID uint64) {
+	s.Lock()
+	defer s.Unlock()
+
+	var removed []syncRange
+	requests := s.requests[:0]
+	for _, r := range s.requests {
+		if r.Addr == addr && r.GnetID == gnetID {
+			removed = append(removed, r.syncRange)
+		} else {
+			requests = append(requests, r)
+		}
+	}
+	s.requests = requests
+
+	for _, r := range removed {
+		s.requeue(r)
+	}
+
+	if s.backfill != nil && s.backfill.Addr == addr && s.backfill.GnetID == gnetID {
+		s.backfill = nil
+	}
+
+	delete(s.stats, addr)
+}
+
+// scheduleBackfill assigns the first blocks of the backfill range, from start to end, to a peer.
+// Only one backfill request is in flight at a time, a request that times out is assigned to another peer.
+func (s *syncManager) scheduleBackfill(now time.Time, start, end uint64, peers []syncPeer) (syncRequest, bool) {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.backfill != nil {
+		if now.Sub(s.backfill.SentAt) < s.config.RequestTimeout {
+			return syncRequest{}, false
+		}
+
+		s.backoff[s.backfill.Addr] = now.Add(s.config.RequestTimeout)
+		s.peerStats(s.backfill.Addr).timeouts++
+		s.timeouts++
+		s.backfill = nil
+	}
+
+	if end-start+1 > s.config.RangeSize {
+		end = start + s.config.RangeSize - 1
+	}
+
+	p, ok := s.pickPeer(peers, start, end)
+	if !ok {
+		return syncRequest{}, false
+	}
+
+	s.backfill = &syncRequest{
+		syncRange: syncRange{
+			Start: start,
+			End:   end,
+		},
+		Addr:   p.Addr,
+		GnetID: p.GnetID,
+		SentAt: now,
+	}
+
+	return *s.backfill, true
+}
+
+// receiveBackfill returns true if the blocks answer the backfill request sent to the peer, and completes the request
+func (s *syncManager) receiveBackfill(addr string, gnetID uint64, blocks []coin.SignedBlock) bool {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.backfill == nil || len(blocks) == 0 {
+		return false
+	}
+
+	if s.backfill.Addr != addr || s.backfill.GnetID != gnetID || s.backfill.Start != blocks[0].Seq() {
+		return false
+	}
+
+	s.backfill = nil
+	return true
+}
+
+func (s *syncManager) peerStats(addr string) *peerSyncStats {
+	stats, ok := s.stats[addr]
+	if !ok {
+		stats = &peerSyncStats{}
+		s.stats[addr] = stats
+	}
+	return stats
+}
+
+// progress returns the status of the block download
+func (s *syncManager) progress() SyncProgress {
+	s.Lock()
+	defer s.Unlock()
+
+	p := SyncProgress{
+		RequestsInFlight: len(s.requests),
+		BlocksBuffered:   len(s.blocks),
+		Timeouts:         s.timeouts,
+	}
+
+	peers := make(map[string]*PeerSyncProgress, len(s.stats))
+	peer := func(addr string) *PeerSyncProgress {
+		pp, ok := peers[addr]
+		if !ok {
+			pp = &PeerSyncProgress{
+				Address: addr,
+			}
+			peers[addr] = pp
+		}
+		return pp
+	}
+
+	for _, r := range s.requests {
+		p.BlocksInFlight += r.Len()
+		peer(r.Addr).RequestsInFlight++
+	}
+
+	for addr, stats := range s.stats {
+		pp := peer(addr)
+		pp.BlocksReceived = stats.received
+		pp.Timeouts = stats.timeouts
+	}
+
+	p.Peers = make([]PeerSyncProgress, 0, len(peers))
+	for _, pp := range peers {
+		p.Peers = append(p.Peers, *pp)
+	}
+
+	sort.Slice(p.Peers, func(i, j int) bool {
+		return p.Peers[i].Address < p.Peers[j].Address
+	})
+
+	return p
+}
@@ -0,0 +1,414 @@
+package daemon
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/coin"
+)
+
+// makeSyncChain creates n chained blocks after the genesis block, indexed by seq
+func makeSyncChain(n int) []coin.SignedBlock {
+	blocks := []coin.SignedBlock{
+		{
+			Block: coin.Block{
+				Head: coin.BlockHeader{
+					Time: 1500000000,
+				},
+			},
+		},
+	}
+
+	for i := 1; i <= n; i++ {
+		prev := blocks[i-1]
+		blocks = append(blocks, coin.SignedBlock{
+			Block: coin.Block{
+				Head: coin.BlockHeader{
+					BkSeq:    uint64(i),
+					Time:     prev.Head.Time + 10,
+					PrevHash: prev.HashHeader(),
+				},
+			},
+		})
+	}
+
+	return blocks
+}
+
+func newTestSyncManager() *syncManager {
+	return newSyncManager(syncConfig{
+		RangeSize:          10,
+		MaxRequestsPerPeer: 2,
+		MaxBufferedBlocks:  1000,
+		RequestTimeout:     time.Second * 20,
+	})
+}
+
+func requireSyncRanges(t *testing.T, expected []syncRequest, reqs []syncRequest) {
+	t.Helper()
+	require.Equal(t, len(expected), len(reqs))
+	for i, r := range reqs {
+		require.Equal(t, expected[i].syncRange, r.syncRange, "request %d", i)
+		require.Equal(t, expected[i].Addr, r.Addr, "request %d", i)
+	}
+}
+
+func syncReq(addr string, start, end uint64) syncRequest {
+	return syncRequest{
+		syncRange: syncRange{
+			Start: start,
+			End:   end,
+		},
+		Addr: addr,
+	}
+}
+
+var (
+	syncPeerA = syncPeer{Addr: "1.1.1.1:6000", GnetID: 1, Height: 100}
+	syncPeerB = syncPeer{Addr: "2.2.2.2:6000", GnetID: 2, Height: 45}
+	syncPeerC = syncPeer{Addr: "3.3.3.3:6000", GnetID: 3, Height: 0}
+)
+
+func TestSyncManagerSchedule(t *testing.T) {
+	now := time.Now()
+	s := newTestSyncManager()
+
+	// Nothing to request when no peer is ahead
+	require.Empty(t, s.schedule(now, []syncPeer{syncPeerC}))
+
+	// Disjoint ranges are spread over the peers that have the blocks, up to MaxRequestsPerPeer each.
+	// The fifth range is not requested because both peers are busy
+	peers := []syncPeer{syncPeerA, syncPeerB, syncPeerC}
+	reqs := s.schedule(now, peers)
+	requireSyncRanges(t, []syncRequest{
+		syncReq(syncPeerA.Addr, 1, 10),
+		syncReq(syncPeerB.Addr, 11, 20),
+		syncReq(syncPeerA.Addr, 21, 30),
+		syncReq(syncPeerB.Addr, 31, 40),
+	}, reqs)
+	require.Empty(t, s.schedule(now, peers))
+
+	// Ranges beyond a peer's height are only assigned to peers that have them
+	require.NoError(t, s.receive(syncPeerB.Addr, syncPeerB.GnetID, makeSyncChain(20)[11:21]))
+	reqs = s.schedule(now, peers)
+	requireSyncRanges(t, []syncRequest{
+		syncReq(syncPeerB.Addr, 41, 45),
+	}, reqs)
+
+	progress := s.progress()
+	require.Equal(t, 4, progress.RequestsInFlight)
+	require.Equal(t, uint64(35), progress.BlocksInFlight)
+	require.Equal(t, 10, progress.BlocksBuffered)
+	require.Equal(t, []PeerSyncProgress{
+		{
+			Address:          syncPeerA.Addr,
+			RequestsInFlight: 2,
+		},
+		{
+			Address:          syncPeerB.Addr,
+			RequestsInFlight: 2,
+			BlocksReceived:   10,
+		},
+	}, progress.Peers)
+}
+
+func TestSyncManagerPrunedPeer(t *testing.T) {
+	now := time.Now()
+	s := newTestSyncManager()
+
+	// The pruned peer only has the blocks after 50
+	pruned := syncPeer{Addr: "4.4.4.4:6000", GnetID: 4, Height: PrunedPeerKeepBlocks + 50, Pruned: true}
+	require.Empty(t, s.schedule(now, []syncPeer{pruned}))
+
+	// The old blocks are requested from a peer that is not pruned
+	reqs := s.schedule(now, []syncPeer{pruned, syncPeerB})
+	requireSyncRanges(t, []syncRequest{
+		syncReq(syncPeerB.Addr, 1, 10),
+		syncReq(syncPeerB.Addr, 11, 20),
+	}, reqs)
+
+	// The recent blocks are requested from the pruned peer
+	s.setHead(50)
+	reqs = s.schedule(now, []syncPeer{pruned})
+	requireSyncRanges(t, []syncRequest{
+		syncReq(pruned.Addr, 51, 60),
+		syncReq(pruned.Addr, 61, 70),
+	}, reqs)
+}
+
+func TestSyncManagerMaxBufferedBlocks(t *testing.T) {
+	now := time.Now()
+	s := newTestSyncManager()
+	s.config.MaxBufferedBlocks = 25
+	s.config.MaxRequestsPerPeer = 10
+
+	reqs := s.schedule(now, []syncPeer{syncPeerA})
+	requireSyncRanges(t, []syncRequest{
+		syncReq(syncPeerA.Addr, 1, 10),
+		syncReq(syncPeerA.Addr, 11, 20),
+		syncReq(syncPeerA.Addr, 21, 25),
+	}, reqs)
+
+	// Advancing the head allows more blocks to be requested
+	s.setHead(10)
+	reqs = s.schedule(now, []syncPeer{syncPeerA})
+	requireSyncRanges(t, []syncRequest{
+		syncReq(syncPeerA.Addr, 26, 35),
+	}, reqs)
+	require.Equal(t, 3, s.progress().RequestsInFlight)
+}
+
+func TestSyncManagerReceiveOutOfOrder(t *testing.T) {
+	now := time.Now()
+	s := newTestSyncManager()
+	chain := makeSyncChain(30)
+
+	peers := []syncPeer{syncPeerA, syncPeerB}
+	s.schedule(now, peers)
+
+	// Blocks after a missing block are buffered
+	require.NoError(t, s.receive(syncPeerB.Addr, syncPeerB.GnetID, chain[11:21]))
+	_, ok := s.pop()
+	require.False(t, ok)
+
+	require.NoError(t, s.receive(syncPeerA.Addr, syncPeerA.GnetID, chain[1:11]))
+	for i := uint64(1); i <= 20; i++ {
+		b, ok := s.pop()
+		require.True(t, ok)
+		require.Equal(t, chain[i], b.Block)
+		s.setHead(i)
+	}
+	_, ok = s.pop()
+	require.False(t, ok)
+
+	// Blocks at or below the head are ignored
+	require.NoError(t, s.receive(syncPeerA.Addr, syncPeerA.GnetID, chain[5:15]))
+	require.Equal(t, 0, s.progress().BlocksBuffered)
+}
+
+func TestSyncManagerPartialResponse(t *testing.T) {
+	now := time.Now()
+	s := newTestSyncManager()
+	chain := makeSyncChain(30)
+
+	peers := []syncPeer{syncPeerA}
+	s.schedule(now, peers)
+
+	// The rest of a partially answered range is requested before new ranges
+	require.NoError(t, s.receive(syncPeerA.Addr, syncPeerA.GnetID, chain[1:6]))
+	reqs := s.schedule(now, peers)
+	requireSyncRanges(t, []syncRequest{
+		syncReq(syncPeerA.Addr, 6, 10),
+	}, reqs)
+}
+
+func TestSyncManagerInvalidResponse(t *testing.T) {
+	chain := makeSyncChain(30)
+
+	notSequential := append([]coin.SignedBlock{}, chain[1:5]...)
+	notSequential = append(notSequential, chain[6:11]...)
+
+	notChained := append([]coin.SignedBlock{}, chain[1:11]...)
+	notChained[5].Head.PrevHash = chain[1].HashHeader()
+
+	cases := []struct {
+		name   string
+		blocks []coin.SignedBlock
+		err    error
+	}{
+		{
+			name:   "not sequential",
+			blocks: notSequential,
+			err:    ErrSyncBlocksNotSequential,
+		},
+		{
+			name:   "not chained",
+			blocks: notChained,
+			err:    ErrSyncBlocksNotChained,
+		},
+		{
+			name:   "out of range",
+			blocks: chain[1:12],
+			err:    ErrSyncBlocksOutOfRange,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			now := time.Now()
+			s := newTestSyncManager()
+			s.schedule(now, []syncPeer{syncPeerA})
+
+			err := s.receive(syncPeerA.Addr, syncPeerA.GnetID, tc.blocks)
+			require.Equal(t, tc.err, err)
+			require.Equal(t, 0, s.progress().BlocksBuffered)
+
+			// The request is assigned again
+			reqs := s.schedule(now, []syncPeer{syncPeerA})
+			requireSyncRanges(t, []syncRequest{
+				syncReq(syncPeerA.Addr, 1, 10),
+			}, reqs)
+		})
+	}
+}
+
+func TestSyncManagerTimeout(t *testing.T) {
+	now := time.Now()
+	s := newTestSyncManager()
+
+	peerB := syncPeerB
+	peerB.Height = 100
+	s.schedule(now, []syncPeer{syncPeerA})
+
+	require.Empty(t, s.timeout(now.Add(time.Second)))
+
+	// Timed out requests are reassigned to other peers
+	now = now.Add(s.config.RequestTimeout)
+	timedOut := s.timeout(now)
+	requireSyncRanges(t, []syncRequest{
+		syncReq(syncPeerA.Addr, 1, 10),
+		syncReq(syncPeerA.Addr, 11, 20),
+	}, timedOut)
+
+	reqs := s.schedule(now, []syncPeer{syncPeerA, peerB})
+	requireSyncRanges(t, []syncRequest{
+		syncReq(peerB.Addr, 1, 10),
+		syncReq(peerB.Addr, 11, 20),
+	}, reqs)
+
+	progress := s.progress()
+	require.Equal(t, uint64(2), progress.Timeouts)
+	require.Equal(t, uint64(2), progress.Peers[0].Timeouts)
+
+	// The slow peer is sent requests again after the backoff
+	now = now.Add(s.config.RequestTimeout)
+	reqs = s.schedule(now, []syncPeer{syncPeerA, peerB})
+	requireSyncRanges(t, []syncRequest{
+		syncReq(syncPeerA.Addr, 21, 30),
+		syncReq(syncPeerA.Addr, 31, 40),
+	}, reqs)
+}
+
+func TestSyncManagerRejectBlock(t *testing.T) {
+	now := time.Now()
+	s := newTestSyncManager()
+	chain := makeSyncChain(30)
+
+	peerB := syncPeerB
+	peerB.Height = 100
+	peers := []syncPeer{syncPeerA, peerB}
+	s.schedule(now, peers)
+
+	require.NoError(t, s.receive(syncPeerA.Addr, syncPeerA.GnetID, chain[1:11]))
+	require.NoError(t, s.receive(peerB.Addr, peerB.GnetID, chain[11:21]))
+
+	b, ok := s.pop()
+	require.True(t, ok)
+	require.Equal(t, chain[1], b.Block)
+
+	// The failed block and the other blocks from the same peer are requested again from another peer
+	s.rejectBlock(now, b)
+	require.Equal(t, 10, s.progress().BlocksBuffered)
+
+	reqs := s.schedule(now, peers)
+	requireSyncRanges(t, []syncRequest{
+		syncReq(peerB.Addr, 1, 10),
+	}, reqs)
+}
+
+func TestSyncManagerDisconnected(t *testing.T) {
+	now := time.Now()
+	s := newTestSyncManager()
+
+	peerB := syncPeerB
+	peerB.Height = 100
+	s.schedule(now, []syncPeer{syncPeerA, peerB})
+	require.Equal(t, 4, s.progress().RequestsInFlight)
+
+	s.disconnected(syncPeerA.Addr, syncPeerA.GnetID)
+	require.Equal(t, 2, s.progress().RequestsInFlight)
+
+	s.disconnected(peerB.Addr, peerB.GnetID)
+	reqs := s.schedule(now, []syncPeer{syncPeerC, syncPeerA})
+	requireSyncRanges(t, []syncRequest{
+		syncReq(syncPeerA.Addr, 1, 10),
+		syncReq(syncPeerA.Addr, 11, 20),
+	}, reqs)
+
+	// Cancelled requests are assigned again
+	s.cancel(reqs[1])
+	reqs = s.schedule(now, []syncPeer{syncPeerA})
+	requireSyncRanges(t, []syncRequest{
+		syncReq(syncPeerA.Addr, 11, 20),
+	}, reqs)
+}
+
+func TestSyncManagerSetHead(t *testing.T) {
+	now := time.Now()
+	s := newTestSyncManager()
+	s.config.MaxRequestsPerPeer = 10
+
+	s.schedule(now, []syncPeer{syncPeerA})
+	require.Equal(t, 10, s.progress().RequestsInFlight)
+
+	// Requests below the head are forgotten, and the next range starts after the head
+	s.setHead(95)
+	require.Equal(t, 1, s.progress().RequestsInFlight)
+
+	peerB := syncPeerB
+	peerB.Height = 120
+	reqs := s.schedule(now, []syncPeer{peerB})
+	requireSyncRanges(t, []syncRequest{
+		syncReq(peerB.Addr, 101, 110),
+		syncReq(peerB.Addr, 111, 120),
+	}, reqs)
+}
+
+func TestSyncManagerBackfill(t *testing.T) {
+	now := time.Now()
+	s := newTestSyncManager()
+	s.setHead(50)
+	chain := makeSyncChain(50)
+
+	// The pruned peer does not have the old blocks
+	pruned := syncPeer{Addr: "4.4.4.4:6000", GnetID: 4, Height: PrunedPeerKeepBlocks + 50, Pruned: true}
+	_, ok := s.scheduleBackfill(now, 1, 49, []syncPeer{pruned})
+	require.False(t, ok)
+
+	r, ok := s.scheduleBackfill(now, 1, 49, []syncPeer{pruned, syncPeerA})
+	require.True(t, ok)
+	requireSyncRanges(t, []syncRequest{syncReq(syncPeerA.Addr, 1, 10)}, []syncRequest{r})
+
+	// Only one backfill request is in flight at a time
+	_, ok = s.scheduleBackfill(now, 1, 49, []syncPeer{syncPeerA})
+	require.False(t, ok)
+
+	// Blocks that do not answer the request are not backfilled
+	require.False(t, s.receiveBackfill(syncPeerB.Addr, syncPeerB.GnetID, chain[1:11]))
+	require.False(t, s.receiveBackfill(syncPeerA.Addr, syncPeerA.GnetID, chain[2:11]))
+
+	require.True(t, s.receiveBackfill(syncPeerA.Addr, syncPeerA.GnetID, chain[1:11]))
+	require.False(t, s.receiveBackfill(syncPeerA.Addr, syncPeerA.GnetID, chain[1:11]))
+
+	// The end of the backfill range is not exceeded
+	r, ok = s.scheduleBackfill(now, 45, 49, []syncPeer{syncPeerA})
+	require.True(t, ok)
+	requireSyncRanges(t, []syncRequest{syncReq(syncPeerA.Addr, 45, 49)}, []syncRequest{r})
+
+	// A request that times out is assigned to another peer
+	peerB := syncPeerB
+	peerB.Height = 100
+	now = now.Add(s.config.RequestTimeout)
+	r, ok = s.scheduleBackfill(now, 45, 49, []syncPeer{syncPeerA, peerB})
+	require.True(t, ok)
+	requireSyncRanges(t, []syncRequest{syncReq(peerB.Addr, 45, 49)}, []syncRequest{r})
+	require.Equal(t, uint64(1), s.progress().Timeouts)
+
+	// The request of a disconnected peer is forgotten, so the range can be requested again
+	s.disconnected(peerB.Addr, peerB.GnetID)
+	_, ok = s.scheduleBackfill(now, 45, 49, []syncPeer{syncPeerC})
+	require.False(t, ok)
+	require.Nil(t, s.backfill)
+}
@@ -0,0 +1,256 @@
+package daemon
+
+import (
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/skycoin/skycoin/src/cipher"
+)
+
+var (
+	// errNoStemPeer no outgoing peer is available to relay a transaction through
+	errNoStemPeer = errors.New("No outgoing peer is available to relay the transaction through")
+)
+
+// txnAnnouncement is a batch of transaction hashes due to be announced to a peer
+type txnAnnouncement struct {
+	Addr   string
+	GnetID uint64
+	Hashes []cipher.SHA256
+}
+
+// txnAnnounceQueue holds the transaction hashes waiting to be announced to a peer
+type txnAnnounceQueue struct {
+	gnetID uint64
+	hashes []cipher.SHA256
+	known  map[cipher.SHA256]struct{}
+	sendAt time.Time
+}
+
+// txnRelay hides which node created a transaction, in the style of Dandelion.
+//
+// Transactions created by this node are sent to a single outgoing peer, the stem peer, which
+// announces them to the network (the stem phase). Until another peer announces the transaction
+// back to us, it is embargoed: it is not announced by this node. If the embargo expires,
+// the transaction is announced to all peers, in case the stem peer dropped it.
+// The stem peer is kept for an epoch, so that repeated transactions don't reveal more peers.
+//
+// Transactions received from peers are announced to each peer after an independent, exponentially
+// distributed delay (the fluff phase), so that the first announcement seen by an observer
+// does not point to the node that relayed it first. Announcements are batched per peer.
+//
+// Transactions are stemmed from API goroutines, so the state is protected by a mutex.
+type txnRelay struct {
+	sync.Mutex
+	announceDelay time.Duration
+	embargo       time.Duration
+	stemEpoch     time.Duration
+	rand          *rand.Rand
+
+	queues       map[string]*txnAnnounceQueue
+	embargoed    map[cipher.SHA256]time.Time
+	stemAddr     string
+	stemGnetID   uint64
+	stemChosenAt time.Time
+}
+
+func newTxnRelay(announceDelay, embargo, stemEpoch time.Duration) *txnRelay {
+	return &txnRelay{
+		announceDelay: announceDelay,
+		embargo:       embargo,
+		stemEpoch:     stemEpoch,
+		rand:          rand.New(rand.NewSource(time.Now().UTC().UnixNano())),
+		queues:        make(map[string]*txnAnnounceQueue),
+		embargoed:     make(map[cipher.SHA256]time.Time),
+	}
+}
+
+// randomDelay returns an exponentially distributed delay with a mean of announceDelay,
+// so that announcements to a peer form a Poisson process
+func (r *txnRelay) randomDelay() time.Duration {
+	return time.Duration(r.rand.ExpFloat64() * float64(r.announceDelay))
+}
+
+// queueAnnouncements queues transaction hashes to be announced to connections.
+// A connection without queued hashes is given a new random announcement time.
+func (r *txnRelay) queueAnnouncements(conns []connection, hashes []cipher.SHA256, now time.Time) {
+	r.Lock()
+	defer r.Unlock()
+
+	for _, c := range conns {
+		q := r.queues[c.Addr]
+		if q == nil || q.gnetID != c.gnetID {
+			q = &txnAnnounceQueue{
+				gnetID: c.gnetID,
+				known:  make(map[cipher.SHA256]struct{}),
+				sendAt: now.Add(r.randomDelay()),
+			}
+			r.queues[c.Addr] = q
+		}
+
+		for _, h := range hashes {
+			if _, ok := q.known[h]; ok {
+				continue
+			}
+			q.known[h] = struct{}{}
+			q.hashes = append(q.hashes, h)
+		}
+	}
+}
+
+// dueAnnouncements removes and returns the queued announcements that are due at now
+func (r *txnRelay) dueAnnouncements(now time.Time) []txnAnnouncement {
+	r.Lock()
+	defer r.Unlock()
+
+	var due []txnAnnouncement
+	for addr, q := range r.queues {
+		if now.Before(q.sendAt) {
+			continue
+		}
+
+		delete(r.queues, addr)
+
+		if len(q.hashes) == 0 {
+			continue
+		}
+
+		due = append(due, txnAnnouncement{
+			Addr:   addr,
+			GnetID: q.gnetID,
+			Hashes: q.hashes,
+		})
+	}
+
+	return due
+}
+
+// removePeer forgets the queued announcements of a disconnected peer, and the stem peer if it disconnected
+func (r *txnRelay) removePeer(addr string, gnetID uint64) {
+	r.Lock()
+	defer r.Unlock()
+
+	if q := r.queues[addr]; q != nil && q.gnetID == gnetID {
+		delete(r.queues, addr)
+	}
+
+	if r.stemAddr == addr && r.stemGnetID == gnetID {
+		r.clearStemPeerLocked()
+	}
+}
+
+// stemPeer returns the stem peer, if one was chosen in the current epoch
+func (r *txnRelay) stemPeer(now time.Time) (string, uint64, bool) {
+	r.Lock()
+	defer r.Unlock()
+
+	if r.stemAddr == "" || now.Sub(r.stemChosenAt) >= r.stemEpoch {
+		return "", 0, false
+	}
+
+	return r.stemAddr, r.stemGnetID, true
+}
+
+// setStemPeer starts a new epoch with a stem peer
+func (r *txnRelay) setStemPeer(addr string, gnetID uint64, now time.Time) {
+	r.Lock()
+	defer r.Unlock()
+
+	r.stemAddr = addr
+	r.stemGnetID = gnetID
+	r.stemChosenAt = now
+}
+
+// clearStemPeer forgets the stem peer, so that a new one is chosen for the next transaction
+func (r *txnRelay) clearStemPeer() {
+	r.Lock()
+	defer r.Unlock()
+
+	r.clearStemPeerLocked()
+}
+
+func (r *txnRelay) clearStemPeerLocked() {
+	r.stemAddr = ""
+	r.stemGnetID = 0
+	r.stemChosenAt = time.Time{}
+}
+
+// intn returns a random number in [0, n)
+func (r *txnRelay) intn(n int) int {
+	r.Lock()
+	defer r.Unlock()
+
+	return r.rand.Intn(n)
+}
+
+// addEmbargo embargoes a transaction sent to the stem peer.
+// The embargo of a transaction that is sent to the stem peer again is not extended.
+func (r *txnRelay) addEmbargo(hash cipher.SHA256, now time.Time) {
+	r.Lock()
+	defer r.Unlock()
+
+	if _, ok := r.embargoed[hash]; ok {
+		return
+	}
+
+	r.embargoed[hash] = now.Add(r.embargo)
+}
+
+// isEmbargoed returns true if a transaction is embargoed
+func (r *txnRelay) isEmbargoed(hash cipher.SHA256) bool {
+	r.Lock()
+	defer r.Unlock()
+
+	_, ok := r.embargoed[hash]
+	return ok
+}
+
+// removeEmbargo lifts the embargo of transactions, because they were announced by the network
+// or could not be sent to the stem peer
+func (r *txnRelay) removeEmbargo(hashes []cipher.SHA256) {
+	r.Lock()
+	defer r.Unlock()
+
+	for _, h := range hashes {
+		delete(r.embargoed, h)
+	}
+}
+
+// expiredEmbargoes removes and returns the transactions whose embargo expired at now
+func (r *txnRelay) expiredEmbargoes(now time.Time) []cipher.SHA256 {
+	r.Lock()
+	defer r.Unlock()
+
+	var expired []cipher.SHA256
+	for h, t := range r.embargoed {
+		if now.Before(t) {
+			continue
+		}
+
+		delete(r.embargoed, h)
+		expired = append(expired, h)
+	}
+
+	return expired
+}
+
+// filterEmbargoed returns the hashes of transactions that are not embargoed
+func (r *txnRelay) filterEmbargoed(hashes []cipher.SHA256) []cipher.SHA256 {
+	r.Lock()
+	defer r.Unlock()
+
+	if len(r.embargoed) == 0 {
+		return hashes
+	}
+
+	filtered := make([]cipher.SHA256, 0, len(hashes))
+	for _, h := range hashes {
+		if _, ok := r.embargoed[h]; !ok {
+			filtered = append(filtered, h)
+		}
+	}
+
+	return filtered
+}
@@ -0,0 +1,494 @@
+package daemon
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/daemon/gnet"
+	"github.com/skycoin/skycoin/src/params"
+	"github.com/skycoin/skycoin/src/util/useragent"
+)
+
+func makeTxnRelayTestHashes(n int) []cipher.SHA256 {
+	hashes := make([]cipher.SHA256, n)
+	for i := range hashes {
+		hashes[i] = cipher.SumSHA256([]byte(fmt.Sprintf("txn %d", i)))
+	}
+	return hashes
+}
+
+func TestTxnRelayRandomDelay(t *testing.T) {
+	r := newTxnRelay(time.Second*2, time.Second*30, time.Minute*10)
+	r.rand = rand.New(rand.NewSource(1))
+
+	n := 10000
+	var total time.Duration
+	for i := 0; i < n; i++ {
+		d := r.randomDelay()
+		require.True(t, d >= 0)
+		total += d
+	}
+
+	mean := total / time.Duration(n)
+	require.InDelta(t, float64(time.Second*2), float64(mean), float64(time.Millisecond*100))
+}
+
+func TestTxnRelayAnnouncements(t *testing.T) {
+	r := newTxnRelay(time.Second*2, time.Second*30, time.Minute*10)
+	r.rand = rand.New(rand.NewSource(1))
+
+	hashes := makeTxnRelayTestHashes(4)
+	conns := []connection{
+		{
+			Addr:   "1.1.1.1:6000",
+			gnetID: 1,
+		},
+		{
+			Addr:   "2.2.2.2:6000",
+			gnetID: 2,
+		},
+	}
+
+	now := time.Now().UTC()
+	r.queueAnnouncements(conns, hashes[:2], now)
+
+	// Hashes already queued are not queued again, and the announcement time doesn't change
+	sendAt := map[string]time.Time{}
+	for addr, q := range r.queues {
+		sendAt[addr] = q.sendAt
+	}
+	r.queueAnnouncements(conns, hashes[1:3], now.Add(time.Second))
+	for addr, q := range r.queues {
+		require.Equal(t, sendAt[addr], q.sendAt)
+		require.Equal(t, hashes[:3], q.hashes)
+	}
+
+	// Each peer has its own announcement time
+	require.NotEqual(t, sendAt["1.1.1.1:6000"], sendAt["2.2.2.2:6000"])
+	first, last := "1.1.1.1:6000", "2.2.2.2:6000"
+	if sendAt[last].Before(sendAt[first]) {
+		first, last = last, first
+	}
+
+	require.Empty(t, r.dueAnnouncements(sendAt[first].Add(-time.Nanosecond)))
+
+	due := r.dueAnnouncements(sendAt[first])
+	require.Len(t, due, 1)
+	require.Equal(t, first, due[0].Addr)
+	require.Equal(t, hashes[:3], due[0].Hashes)
+
+	// A peer with announcements sent starts a new queue
+	r.queueAnnouncements(conns, hashes[3:], sendAt[first])
+	require.Equal(t, hashes[3:], r.queues[first].hashes)
+	require.Equal(t, hashes, r.queues[last].hashes)
+
+	// A disconnected peer's announcements are forgotten.
+	// A reconnected peer with a different gnet ID starts a new queue
+	r.removePeer(first, 99)
+	require.NotNil(t, r.queues[first])
+	r.removePeer(first, r.queues[first].gnetID)
+	require.Nil(t, r.queues[first])
+
+	r.queueAnnouncements([]connection{
+		{
+			Addr:   last,
+			gnetID: 3,
+		},
+	}, hashes[:1], sendAt[last])
+	require.Equal(t, uint64(3), r.queues[last].gnetID)
+	require.Equal(t, hashes[:1], r.queues[last].hashes)
+
+	due = r.dueAnnouncements(sendAt[last].Add(time.Hour))
+	require.Len(t, due, 1)
+	require.Equal(t, last, due[0].Addr)
+	require.Equal(t, uint64(3), due[0].GnetID)
+	require.Empty(t, r.queues)
+}
+
+func TestTxnRelayEmbargo(t *testing.T) {
+	r := newTxnRelay(time.Second*2, time.Second*30, time.Minute*10)
+	hashes := makeTxnRelayTestHashes(3)
+
+	now := time.Now().UTC()
+	require.Equal(t, hashes, r.filterEmbargoed(hashes))
+
+	r.addEmbargo(hashes[0], now)
+	r.addEmbargo(hashes[1], now.Add(time.Second))
+	require.Equal(t, hashes[2:], r.filterEmbargoed(hashes))
+	require.True(t, r.isEmbargoed(hashes[0]))
+	require.False(t, r.isEmbargoed(hashes[2]))
+
+	// The embargo is not extended when the transaction is embargoed again
+	r.addEmbargo(hashes[0], now.Add(time.Second*10))
+
+	require.Empty(t, r.expiredEmbargoes(now.Add(time.Second*29)))
+	require.Equal(t, hashes[:1], r.expiredEmbargoes(now.Add(time.Second*30)))
+	require.Equal(t, hashes[2:], r.filterEmbargoed(hashes[1:]))
+
+	r.removeEmbargo(hashes[1:2])
+	require.Equal(t, hashes, r.filterEmbargoed(hashes))
+	require.Empty(t, r.expiredEmbargoes(now.Add(time.Hour)))
+}
+
+func TestTxnRelayStemPeer(t *testing.T) {
+	r := newTxnRelay(time.Second*2, time.Second*30, time.Minute*10)
+
+	now := time.Now().UTC()
+	_, _, ok := r.stemPeer(now)
+	require.False(t, ok)
+
+	r.setStemPeer("1.1.1.1:6000", 1, now)
+	addr, gnetID, ok := r.stemPeer(now.Add(time.Minute * 9))
+	require.True(t, ok)
+	require.Equal(t, "1.1.1.1:6000", addr)
+	require.Equal(t, uint64(1), gnetID)
+
+	// The epoch ends
+	_, _, ok = r.stemPeer(now.Add(time.Minute * 10))
+	require.False(t, ok)
+
+	// The stem peer disconnects
+	r.setStemPeer("1.1.1.1:6000", 1, now)
+	r.removePeer("1.1.1.1:6000", 2)
+	_, _, ok = r.stemPeer(now)
+	require.True(t, ok)
+	r.removePeer("1.1.1.1:6000", 1)
+	_, _, ok = r.stemPeer(now)
+	require.False(t, ok)
+}
+
+// txnRelayTestPeer is a gnet pool that records the messages it receives
+type txnRelayTestPeer struct {
+	addr     string
+	pool     *gnet.ConnectionPool
+	messages chan gnet.Message
+	done     chan struct{}
+}
+
+func newTxnRelayTestPeer(t *testing.T, port uint16) *txnRelayTestPeer {
+	messages := make(chan gnet.Message, 16)
+
+	d := &mockDaemoner{}
+	d.On("recordMessageEvent", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		messages <- args.Get(0).(gnet.Message)
+	}).Return(nil)
+
+	cfg := gnet.NewConfig()
+	cfg.Address = "127.0.0.1"
+	cfg.Port = port
+	p, err := gnet.NewConnectionPool(cfg, d)
+	require.NoError(t, err)
+
+	peer := &txnRelayTestPeer{
+		addr:     fmt.Sprintf("127.0.0.1:%d", port),
+		pool:     p,
+		messages: messages,
+		done:     make(chan struct{}),
+	}
+
+	go func() {
+		defer close(peer.done)
+		err := p.Run()
+		require.NoError(t, err)
+	}()
+
+	return peer
+}
+
+func (p *txnRelayTestPeer) shutdown() {
+	p.pool.Shutdown()
+	<-p.done
+}
+
+// receive waits for a message received by the peer
+func (p *txnRelayTestPeer) receive(t *testing.T) gnet.Message {
+	select {
+	case m := <-p.messages:
+		return m
+	case <-time.After(time.Second * 5):
+		t.Fatalf("peer %s did not receive a message", p.addr)
+		return nil
+	}
+}
+
+// requireNoMessage checks that the peer has not received a message
+func (p *txnRelayTestPeer) requireNoMessage(t *testing.T) {
+	select {
+	case m := <-p.messages:
+		t.Fatalf("peer %s received unexpected message %T", p.addr, m)
+	case <-time.After(time.Millisecond * 200):
+	}
+}
+
+// setupTxnRelayTestDaemon creates a Daemon with a gnet pool connected to the peers.
+// The connections to the peers are introduced; only the first peer is an outgoing connection.
+func setupTxnRelayTestDaemon(t *testing.T, peers []*txnRelayTestPeer) *Daemon {
+	cfg := NewConfig()
+
+	connected := make(chan uint64, len(peers))
+	gnetCfg := gnet.NewConfig()
+	gnetCfg.Address = "127.0.0.1"
+	gnetCfg.MaxConnections = 16
+	gnetCfg.MaxOutgoingConnections = 8
+	gnetCfg.ConnectCallback = func(addr string, gnetID uint64, solicited bool) {
+		connected <- gnetID
+	}
+	p, err := gnet.NewConnectionPool(gnetCfg, nil)
+	require.NoError(t, err)
+
+	go func() {
+		err := p.RunOffline()
+		require.NoError(t, err)
+	}()
+
+	d := &Daemon{
+		config:      cfg.Daemon,
+		pool:        &Pool{Config: cfg.Pool, Pool: p},
+		connections: NewConnections(),
+		txnRelay:    newTxnRelay(cfg.Daemon.TxnAnnounceDelay, cfg.Daemon.TxnStemEmbargo, cfg.Daemon.TxnStemEpoch),
+		inventory:   newPeerInventory(cfg.Daemon.MaxPeerInventory),
+	}
+
+	for i, peer := range peers {
+		if i == 0 {
+			_, err := d.connections.pending(peer.addr)
+			require.NoError(t, err)
+		}
+
+		require.NoError(t, p.Connect(peer.addr))
+
+		var gnetID uint64
+		select {
+		case gnetID = <-connected:
+		case <-time.After(time.Second * 5):
+			t.Fatalf("connection to %s failed", peer.addr)
+		}
+
+		_, err := d.connections.connected(peer.addr, gnetID)
+		require.NoError(t, err)
+		_, err = d.connections.introduced(peer.addr, gnetID, &IntroductionMessage{
+			Mirror:               uint32(i + 1),
+			ListenPort:           6000,
+			ProtocolVersion:      2,
+			userAgent:            useragent.MustParse("skycoin:0.25.1"),
+			unconfirmedVerifyTxn: params.UserVerifyTxn,
+		})
+		require.NoError(t, err)
+	}
+
+	return d
+}
+
+func setupTxnRelayTest(t *testing.T, ports ...uint16) (*Daemon, []*txnRelayTestPeer, func()) {
+	setupMsgEncoding()
+
+	peers := make([]*txnRelayTestPeer, len(ports))
+	for i, port := range ports {
+		peers[i] = newTxnRelayTestPeer(t, port)
+	}
+
+	d := setupTxnRelayTestDaemon(t, peers)
+
+	return d, peers, func() {
+		d.pool.Shutdown()
+		for _, p := range peers {
+			p.shutdown()
+		}
+		gnet.EraseMessages()
+	}
+}
+
+func makeTxnRelayTestUserTxn() (coin.Transaction, *coin.SignedBlock, coin.UxArray) {
+	txn := coin.Transaction{
+		InnerHash: cipher.SumSHA256([]byte("user txn")),
+		Out: []coin.TransactionOutput{
+			{
+				Coins: 1e6,
+			},
+		},
+	}
+
+	inputs := coin.UxArray{
+		{
+			Body: coin.UxBody{
+				Coins: 1e6,
+				Hours: 100,
+			},
+		},
+	}
+
+	return txn, &coin.SignedBlock{}, inputs
+}
+
+func TestDaemonAnnounceRelayedTxns(t *testing.T) {
+	d, peers, teardown := setupTxnRelayTest(t, 50871, 50872)
+	defer teardown()
+
+	hashes := makeTxnRelayTestHashes(3)
+
+	// Announcements are queued for each peer instead of broadcast
+	require.NoError(t, d.announceRelayedTxns(hashes[:2]))
+	require.NoError(t, d.announceRelayedTxns(hashes[1:]))
+	require.Len(t, d.txnRelay.queues, 2)
+
+	// Nothing is sent before the announcements are due
+	for _, q := range d.txnRelay.queues {
+		q.sendAt = time.Now().Add(time.Hour)
+	}
+	d.sendTxnAnnouncements()
+	for _, p := range peers {
+		p.requireNoMessage(t)
+	}
+
+	// A due announcement is sent only to its peer, with all of the queued hashes
+	d.txnRelay.queues[peers[1].addr].sendAt = time.Time{}
+	d.sendTxnAnnouncements()
+
+	m := peers[1].receive(t)
+	require.IsType(t, &AnnounceTxnsMessage{}, m)
+	require.Equal(t, hashes, m.(*AnnounceTxnsMessage).Transactions)
+	peers[0].requireNoMessage(t)
+
+	d.txnRelay.queues[peers[0].addr].sendAt = time.Time{}
+	d.sendTxnAnnouncements()
+
+	m = peers[0].receive(t)
+	require.IsType(t, &AnnounceTxnsMessage{}, m)
+	require.Equal(t, hashes, m.(*AnnounceTxnsMessage).Transactions)
+	require.Empty(t, d.txnRelay.queues)
+
+	// Without a delay, the transactions are announced to all peers immediately
+	d.config.TxnAnnounceDelay = 0
+	require.NoError(t, d.announceRelayedTxns(hashes[:1]))
+	for _, p := range peers {
+		m := p.receive(t)
+		require.IsType(t, &AnnounceTxnsMessage{}, m)
+		require.Equal(t, hashes[:1], m.(*AnnounceTxnsMessage).Transactions)
+	}
+	require.Empty(t, d.txnRelay.queues)
+}
+
+func TestDaemonBroadcastUserTransactionStem(t *testing.T) {
+	d, peers, teardown := setupTxnRelayTest(t, 50873, 50874)
+	defer teardown()
+
+	txn, head, inputs := makeTxnRelayTestUserTxn()
+	txnHash := txn.Hash()
+	other := makeTxnRelayTestHashes(1)[0]
+
+	// The transaction is only sent to the outgoing peer
+	require.NoError(t, d.BroadcastUserTransaction(txn, head, inputs))
+
+	m := peers[0].receive(t)
+	require.IsType(t, &GiveTxnsMessage{}, m)
+	require.Equal(t, []coin.Transaction{txn}, m.(*GiveTxnsMessage).Transactions)
+	peers[1].requireNoMessage(t)
+
+	addr, _, ok := d.txnRelay.stemPeer(time.Now().UTC())
+	require.True(t, ok)
+	require.Equal(t, peers[0].addr, addr)
+
+	// The transaction is not announced while it is embargoed
+	require.NoError(t, d.announceTxnHashes([]cipher.SHA256{txnHash, other}))
+	for _, p := range peers {
+		m := p.receive(t)
+		require.IsType(t, &AnnounceTxnsMessage{}, m)
+		require.Equal(t, []cipher.SHA256{other}, m.(*AnnounceTxnsMessage).Transactions)
+	}
+
+	// The transaction is announced to all peers when the embargo expires
+	d.txnRelay.embargoed[txnHash] = time.Time{}
+	d.announceExpiredTxnStems()
+	for _, p := range peers {
+		m := p.receive(t)
+		require.IsType(t, &AnnounceTxnsMessage{}, m)
+		require.Equal(t, []cipher.SHA256{txnHash}, m.(*AnnounceTxnsMessage).Transactions)
+	}
+	require.Empty(t, d.txnRelay.embargoed)
+
+	// The embargo ends when a peer announces the transaction
+	require.NoError(t, d.BroadcastUserTransaction(txn, head, inputs))
+	m = peers[0].receive(t)
+	require.IsType(t, &GiveTxnsMessage{}, m)
+	require.Len(t, d.txnRelay.embargoed, 1)
+
+	d.endTxnStems([]cipher.SHA256{txnHash})
+	require.Empty(t, d.txnRelay.embargoed)
+	d.announceExpiredTxnStems()
+	for _, p := range peers {
+		p.requireNoMessage(t)
+	}
+}
+
+func TestDaemonResendTransaction(t *testing.T) {
+	d, peers, teardown := setupTxnRelayTest(t, 50879, 50880)
+	defer teardown()
+
+	txn, head, inputs := makeTxnRelayTestUserTxn()
+	txnHash := txn.Hash()
+
+	require.NoError(t, d.BroadcastUserTransaction(txn, head, inputs))
+	m := peers[0].receive(t)
+	require.IsType(t, &GiveTxnsMessage{}, m)
+	expires := d.txnRelay.embargoed[txnHash]
+
+	// An embargoed transaction is only resent to the stem peer, and its embargo is not extended
+	require.NoError(t, d.resendTransaction(txn, head, inputs))
+	m = peers[0].receive(t)
+	require.IsType(t, &GiveTxnsMessage{}, m)
+	require.Equal(t, []coin.Transaction{txn}, m.(*GiveTxnsMessage).Transactions)
+	peers[1].requireNoMessage(t)
+	require.Equal(t, expires, d.txnRelay.embargoed[txnHash])
+
+	// Once the embargo has ended, the transaction is resent to all peers
+	d.endTxnStems([]cipher.SHA256{txnHash})
+	require.NoError(t, d.resendTransaction(txn, nil, nil))
+	for _, p := range peers {
+		m := p.receive(t)
+		require.IsType(t, &GiveTxnsMessage{}, m)
+		require.Equal(t, []coin.Transaction{txn}, m.(*GiveTxnsMessage).Transactions)
+	}
+	require.Empty(t, d.txnRelay.embargoed)
+}
+
+func TestDaemonBroadcastUserTransactionFluff(t *testing.T) {
+	d, peers, teardown := setupTxnRelayTest(t, 50875, 50876)
+	defer teardown()
+
+	txn, head, inputs := makeTxnRelayTestUserTxn()
+
+	// The transaction is broadcast to all peers if there is no outgoing peer
+	err := d.connections.modify(peers[0].addr, d.connections.get(peers[0].addr).gnetID, func(c *ConnectionDetails) {
+		c.Outgoing = false
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, d.BroadcastUserTransaction(txn, head, inputs))
+	for _, p := range peers {
+		m := p.receive(t)
+		require.IsType(t, &GiveTxnsMessage{}, m)
+		require.Equal(t, []coin.Transaction{txn}, m.(*GiveTxnsMessage).Transactions)
+	}
+	require.Empty(t, d.txnRelay.embargoed)
+
+	// The transaction is broadcast to all peers if stem relay is disabled
+	err = d.connections.modify(peers[0].addr, d.connections.get(peers[0].addr).gnetID, func(c *ConnectionDetails) {
+		c.Outgoing = true
+	})
+	require.NoError(t, err)
+	d.config.DisableTxnStemRelay = true
+
+	require.NoError(t, d.BroadcastUserTransaction(txn, head, inputs))
+	for _, p := range peers {
+		m := p.receive(t)
+		require.IsType(t, &GiveTxnsMessage{}, m)
+	}
+	require.Empty(t, d.txnRelay.embargoed)
+}
@@ -0,0 +1,206 @@
+package grpc
+
+import (
+	"fmt"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/visor"
+)
+
+func newTransactionOutput(o coin.TransactionOutput) *TransactionOutput {
+	return &TransactionOutput{
+		Address: o.Address.String(),
+		Coins:   o.Coins,
+		Hours:   o.Hours,
+	}
+}
+
+// newTransaction converts a coin.Transaction to a Transaction
+func newTransaction(txn coin.Transaction) *Transaction {
+	t := &Transaction{
+		Length:    txn.Length,
+		Type:      uint32(txn.Type),
+		InnerHash: hashBytes(txn.InnerHash),
+		Sigs:      make([][]byte, len(txn.Sigs)),
+		In:        make([][]byte, len(txn.In)),
+		Out:       make([]*TransactionOutput, len(txn.Out)),
+		Hash:      hashBytes(txn.Hash()),
+	}
+
+	for i, s := range txn.Sigs {
+		t.Sigs[i] = append([]byte{}, s[:]...)
+	}
+	for i, h := range txn.In {
+		t.In[i] = hashBytes(h)
+	}
+	for i, o := range txn.Out {
+		t.Out[i] = newTransactionOutput(o)
+	}
+
+	return t
+}
+
+// toCoinTransaction converts a Transaction to a coin.Transaction
+func toCoinTransaction(t *Transaction) (*coin.Transaction, error) {
+	if t == nil {
+		return nil, newStatusError(codeInvalidArgument, "transaction is required")
+	}
+
+	if t.Type > 255 {
+		return nil, newStatusError(codeInvalidArgument, "transaction type is invalid")
+	}
+
+	innerHash, err := cipher.SHA256FromBytes(t.InnerHash)
+	if err != nil {
+		return nil, newStatusError(codeInvalidArgument, fmt.Sprintf("inner_hash is invalid: %v", err))
+	}
+
+	txn := &coin.Transaction{
+		Length:    t.Length,
+		Type:      uint8(t.Type),
+		InnerHash: innerHash,
+		Sigs:      make([]cipher.Sig, len(t.Sigs)),
+		In:        make([]cipher.SHA256, len(t.In)),
+		Out:       make([]coin.TransactionOutput, len(t.Out)),
+	}
+
+	for i, b := range t.Sigs {
+		s, err := cipher.NewSig(b)
+		if err != nil {
+			return nil, newStatusError(codeInvalidArgument, fmt.Sprintf("sigs[%d] is invalid: %v", i, err))
+		}
+		txn.Sigs[i] = s
+	}
+
+	for i, b := range t.In {
+		h, err := cipher.SHA256FromBytes(b)
+		if err != nil {
+			return nil, newStatusError(codeInvalidArgument, fmt.Sprintf("in[%d] is invalid: %v", i, err))
+		}
+		txn.In[i] = h
+	}
+
+	for i, o := range t.Out {
+		if o == nil {
+			return nil, newStatusError(codeInvalidArgument, fmt.Sprintf("out[%d] is empty", i))
+		}
+
+		addr, err := cipher.DecodeBase58Address(o.Address)
+		if err != nil {
+			return nil, newStatusError(codeInvalidArgument, fmt.Sprintf("out[%d].address is invalid: %v", i, err))
+		}
+
+		txn.Out[i] = coin.TransactionOutput{
+			Address: addr,
+			Coins:   o.Coins,
+			Hours:   o.Hours,
+		}
+	}
+
+	return txn, nil
+}
+
+func newBlockHeader(h coin.BlockHeader) *BlockHeader {
+	return &BlockHeader{
+		Version:  h.Version,
+		Time:     h.Time,
+		Seq:      h.BkSeq,
+		Fee:      h.Fee,
+		PrevHash: hashBytes(h.PrevHash),
+		BodyHash: hashBytes(h.BodyHash),
+		UxHash:   hashBytes(h.UxHash),
+	}
+}
+
+func newSignedBlock(b coin.SignedBlock) *SignedBlock {
+	txns := make([]*Transaction, len(b.Body.Transactions))
+	for i, txn := range b.Body.Transactions {
+		txns[i] = newTransaction(txn)
+	}
+
+	return &SignedBlock{
+		Head:         newBlockHeader(b.Head),
+		Transactions: txns,
+		Sig:          append([]byte{}, b.Sig[:]...),
+		Hash:         hashBytes(b.HashHeader()),
+	}
+}
+
+// newBlock converts a coin.SignedBlock to a Block. inputs can be nil if the block is not verbose
+func newBlock(b coin.SignedBlock, inputs [][]visor.TransactionInput) (*Block, error) {
+	blk := &Block{
+		Block: newSignedBlock(b),
+	}
+
+	if inputs == nil {
+		return blk, nil
+	}
+
+	if len(inputs) != len(b.Body.Transactions) {
+		return nil, fmt.Errorf("block seq=%d has %d transactions but %d input sets", b.Seq(), len(b.Body.Transactions), len(inputs))
+	}
+
+	blk.Inputs = make([]*TransactionInputs, len(inputs))
+	for i, in := range inputs {
+		blk.Inputs[i] = &TransactionInputs{
+			Inputs: newTransactionInputs(in),
+		}
+	}
+
+	return blk, nil
+}
+
+func newUxOut(ux coin.UxOut) *UxOut {
+	return &UxOut{
+		Time:           ux.Head.Time,
+		BlockSeq:       ux.Head.BkSeq,
+		SrcTransaction: hashBytes(ux.Body.SrcTransaction),
+		Address:        ux.Body.Address.String(),
+		Coins:          ux.Body.Coins,
+		Hours:          ux.Body.Hours,
+		Hash:           hashBytes(ux.Hash()),
+	}
+}
+
+func newTransactionInputs(inputs []visor.TransactionInput) []*TransactionInput {
+	in := make([]*TransactionInput, len(inputs))
+	for i, x := range inputs {
+		in[i] = &TransactionInput{
+			UxOut:           newUxOut(x.UxOut),
+			CalculatedHours: x.CalculatedHours,
+		}
+	}
+	return in
+}
+
+func newUnspentOutputs(outs []visor.UnspentOutput) []*TransactionInput {
+	in := make([]*TransactionInput, len(outs))
+	for i, x := range outs {
+		in[i] = &TransactionInput{
+			UxOut:           newUxOut(x.UxOut),
+			CalculatedHours: x.CalculatedHours,
+		}
+	}
+	return in
+}
+
+func newTransactionStatus(s visor.TransactionStatus) *TransactionStatus {
+	return &TransactionStatus{
+		Confirmed: s.Confirmed,
+		Height:    s.Height,
+		BlockSeq:  s.BlockSeq,
+	}
+}
+
+func newUnconfirmedTransaction(txn visor.UnconfirmedTransaction) *UnconfirmedTransaction {
+	return &UnconfirmedTransaction{
+		Transaction: newTransaction(txn.Transaction),
+		Received:    txn.Received,
+		IsValid:     txn.IsValid == 1,
+	}
+}
+
+func hashBytes(h cipher.SHA256) []byte {
+	return append([]byte{}, h[:]...)
+}
@@ -0,0 +1,29 @@
+package grpc
+
+import (
+	"github.com/skycoin/skycoin/src/api"
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/visor"
+)
+
+//go:generate mockery -name Gatewayer -case underscore -inpkg -testonly
+
+// Gatewayer is the subset of api.Gatewayer used by the gRPC server.
+// The REST API and the gRPC server are backed by the same gateway, so both transports behave the same.
+type Gatewayer interface {
+	HeadBkSeq() (uint64, bool, error)
+	GetSignedBlockByHash(hash cipher.SHA256) (*coin.SignedBlock, error)
+	GetSignedBlockByHashVerbose(hash cipher.SHA256) (*coin.SignedBlock, [][]visor.TransactionInput, error)
+	GetSignedBlockBySeq(seq uint64) (*coin.SignedBlock, error)
+	GetSignedBlockBySeqVerbose(seq uint64) (*coin.SignedBlock, [][]visor.TransactionInput, error)
+	GetBlocksInRange(start, end uint64) ([]coin.SignedBlock, error)
+	GetBlocksInRangeVerbose(start, end uint64) ([]coin.SignedBlock, [][][]visor.TransactionInput, error)
+	GetTransactionWithInputs(txid cipher.SHA256) (*visor.Transaction, []visor.TransactionInput, error)
+	GetUnspentOutputsSummary(filters []visor.OutputsFilter) (*visor.UnspentOutputsSummary, error)
+	GetAllUnconfirmedTransactions() ([]visor.UnconfirmedTransaction, error)
+	InjectBroadcastTransaction(txn coin.Transaction) error
+}
+
+// Any api.Gatewayer can back the gRPC server
+var _ Gatewayer = api.Gatewayer(nil)
@@ -0,0 +1,292 @@
+package grpc
+
+import (
+	"github.com/golang/protobuf/proto"
+)
+
+// The message types in this file mirror skycoin.proto.
+// They are written by hand, since protoc is not part of the build toolchain,
+// and are encoded by the proto package using their struct tags.
+
+// PoolEventType is the type of a PoolEvent
+type PoolEventType int32
+
+const (
+	// PoolEventAdded the transaction was added to the unconfirmed transaction pool
+	PoolEventAdded PoolEventType = 0
+	// PoolEventRemoved the transaction was confirmed in a block or removed from the unconfirmed transaction pool
+	PoolEventRemoved PoolEventType = 1
+)
+
+// String returns the name of the PoolEventType, as used in skycoin.proto
+func (t PoolEventType) String() string {
+	switch t {
+	case PoolEventAdded:
+		return "ADDED"
+	case PoolEventRemoved:
+		return "REMOVED"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// TransactionOutput mirrors coin.TransactionOutput
+type TransactionOutput struct {
+	Address string `protobuf:"bytes,1,opt,name=address,proto3" json:"address,omitempty"`
+	Coins   uint64 `protobuf:"varint,2,opt,name=coins,proto3" json:"coins,omitempty"`
+	Hours   uint64 `protobuf:"varint,3,opt,name=hours,proto3" json:"hours,omitempty"`
+}
+
+func (m *TransactionOutput) Reset()         { *m = TransactionOutput{} }
+func (m *TransactionOutput) String() string { return proto.CompactTextString(m) }
+func (*TransactionOutput) ProtoMessage()    {}
+
+// Transaction mirrors coin.Transaction. Hash is the transaction id and is ignored by InjectTransaction
+type Transaction struct {
+	Length    uint32               `protobuf:"varint,1,opt,name=length,proto3" json:"length,omitempty"`
+	Type      uint32               `protobuf:"varint,2,opt,name=type,proto3" json:"type,omitempty"`
+	InnerHash []byte               `protobuf:"bytes,3,opt,name=inner_hash,proto3" json:"inner_hash,omitempty"`
+	Sigs      [][]byte             `protobuf:"bytes,4,rep,name=sigs,proto3" json:"sigs,omitempty"`
+	In        [][]byte             `protobuf:"bytes,5,rep,name=in,proto3" json:"in,omitempty"`
+	Out       []*TransactionOutput `protobuf:"bytes,6,rep,name=out,proto3" json:"out,omitempty"`
+	Hash      []byte               `protobuf:"bytes,7,opt,name=hash,proto3" json:"hash,omitempty"`
+}
+
+func (m *Transaction) Reset()         { *m = Transaction{} }
+func (m *Transaction) String() string { return proto.CompactTextString(m) }
+func (*Transaction) ProtoMessage()    {}
+
+// BlockHeader mirrors coin.BlockHeader
+type BlockHeader struct {
+	Version  uint32 `protobuf:"varint,1,opt,name=version,proto3" json:"version,omitempty"`
+	Time     uint64 `protobuf:"varint,2,opt,name=time,proto3" json:"time,omitempty"`
+	Seq      uint64 `protobuf:"varint,3,opt,name=seq,proto3" json:"seq,omitempty"`
+	Fee      uint64 `protobuf:"varint,4,opt,name=fee,proto3" json:"fee,omitempty"`
+	PrevHash []byte `protobuf:"bytes,5,opt,name=prev_hash,proto3" json:"prev_hash,omitempty"`
+	BodyHash []byte `protobuf:"bytes,6,opt,name=body_hash,proto3" json:"body_hash,omitempty"`
+	UxHash   []byte `protobuf:"bytes,7,opt,name=ux_hash,proto3" json:"ux_hash,omitempty"`
+}
+
+func (m *BlockHeader) Reset()         { *m = BlockHeader{} }
+func (m *BlockHeader) String() string { return proto.CompactTextString(m) }
+func (*BlockHeader) ProtoMessage()    {}
+
+// SignedBlock mirrors coin.SignedBlock
+type SignedBlock struct {
+	Head         *BlockHeader   `protobuf:"bytes,1,opt,name=head,proto3" json:"head,omitempty"`
+	Transactions []*Transaction `protobuf:"bytes,2,rep,name=transactions,proto3" json:"transactions,omitempty"`
+	Sig          []byte         `protobuf:"bytes,3,opt,name=sig,proto3" json:"sig,omitempty"`
+	Hash         []byte         `protobuf:"bytes,4,opt,name=hash,proto3" json:"hash,omitempty"`
+}
+
+func (m *SignedBlock) Reset()         { *m = SignedBlock{} }
+func (m *SignedBlock) String() string { return proto.CompactTextString(m) }
+func (*SignedBlock) ProtoMessage()    {}
+
+// UxOut mirrors coin.UxOut
+type UxOut struct {
+	Time           uint64 `protobuf:"varint,1,opt,name=time,proto3" json:"time,omitempty"`
+	BlockSeq       uint64 `protobuf:"varint,2,opt,name=block_seq,proto3" json:"block_seq,omitempty"`
+	SrcTransaction []byte `protobuf:"bytes,3,opt,name=src_transaction,proto3" json:"src_transaction,omitempty"`
+	Address        string `protobuf:"bytes,4,opt,name=address,proto3" json:"address,omitempty"`
+	Coins          uint64 `protobuf:"varint,5,opt,name=coins,proto3" json:"coins,omitempty"`
+	Hours          uint64 `protobuf:"varint,6,opt,name=hours,proto3" json:"hours,omitempty"`
+	Hash           []byte `protobuf:"bytes,7,opt,name=hash,proto3" json:"hash,omitempty"`
+}
+
+func (m *UxOut) Reset()         { *m = UxOut{} }
+func (m *UxOut) String() string { return proto.CompactTextString(m) }
+func (*UxOut) ProtoMessage()    {}
+
+// TransactionInput mirrors visor.TransactionInput, and is also used for visor.UnspentOutput
+type TransactionInput struct {
+	UxOut           *UxOut `protobuf:"bytes,1,opt,name=ux_out,proto3" json:"ux_out,omitempty"`
+	CalculatedHours uint64 `protobuf:"varint,2,opt,name=calculated_hours,proto3" json:"calculated_hours,omitempty"`
+}
+
+func (m *TransactionInput) Reset()         { *m = TransactionInput{} }
+func (m *TransactionInput) String() string { return proto.CompactTextString(m) }
+func (*TransactionInput) ProtoMessage()    {}
+
+// TransactionInputs are the inputs of one transaction
+type TransactionInputs struct {
+	Inputs []*TransactionInput `protobuf:"bytes,1,rep,name=inputs,proto3" json:"inputs,omitempty"`
+}
+
+func (m *TransactionInputs) Reset()         { *m = TransactionInputs{} }
+func (m *TransactionInputs) String() string { return proto.CompactTextString(m) }
+func (*TransactionInputs) ProtoMessage()    {}
+
+// Block is a signed block, with the inputs of each of its transactions if requested.
+// Inputs[i] are the inputs of Block.Transactions[i]
+type Block struct {
+	Block  *SignedBlock         `protobuf:"bytes,1,opt,name=block,proto3" json:"block,omitempty"`
+	Inputs []*TransactionInputs `protobuf:"bytes,2,rep,name=inputs,proto3" json:"inputs,omitempty"`
+}
+
+func (m *Block) Reset()         { *m = Block{} }
+func (m *Block) String() string { return proto.CompactTextString(m) }
+func (*Block) ProtoMessage()    {}
+
+// TransactionStatus mirrors visor.TransactionStatus
+type TransactionStatus struct {
+	Confirmed bool   `protobuf:"varint,1,opt,name=confirmed,proto3" json:"confirmed,omitempty"`
+	Height    uint64 `protobuf:"varint,2,opt,name=height,proto3" json:"height,omitempty"`
+	BlockSeq  uint64 `protobuf:"varint,3,opt,name=block_seq,proto3" json:"block_seq,omitempty"`
+}
+
+func (m *TransactionStatus) Reset()         { *m = TransactionStatus{} }
+func (m *TransactionStatus) String() string { return proto.CompactTextString(m) }
+func (*TransactionStatus) ProtoMessage()    {}
+
+// TransactionWithStatus is a transaction with its status and inputs
+type TransactionWithStatus struct {
+	Transaction *Transaction        `protobuf:"bytes,1,opt,name=transaction,proto3" json:"transaction,omitempty"`
+	Status      *TransactionStatus  `protobuf:"bytes,2,opt,name=status,proto3" json:"status,omitempty"`
+	Time        uint64              `protobuf:"varint,3,opt,name=time,proto3" json:"time,omitempty"`
+	Inputs      []*TransactionInput `protobuf:"bytes,4,rep,name=inputs,proto3" json:"inputs,omitempty"`
+}
+
+func (m *TransactionWithStatus) Reset()         { *m = TransactionWithStatus{} }
+func (m *TransactionWithStatus) String() string { return proto.CompactTextString(m) }
+func (*TransactionWithStatus) ProtoMessage()    {}
+
+// UnconfirmedTransaction is a transaction in the unconfirmed transaction pool.
+// Received is the unix time in nanoseconds when the transaction was last received
+type UnconfirmedTransaction struct {
+	Transaction *Transaction `protobuf:"bytes,1,opt,name=transaction,proto3" json:"transaction,omitempty"`
+	Received    int64        `protobuf:"varint,2,opt,name=received,proto3" json:"received,omitempty"`
+	IsValid     bool         `protobuf:"varint,3,opt,name=is_valid,proto3" json:"is_valid,omitempty"`
+}
+
+func (m *UnconfirmedTransaction) Reset()         { *m = UnconfirmedTransaction{} }
+func (m *UnconfirmedTransaction) String() string { return proto.CompactTextString(m) }
+func (*UnconfirmedTransaction) ProtoMessage()    {}
+
+// GetBlockRequest is the request message of GetBlock
+type GetBlockRequest struct {
+	Seq     uint64 `protobuf:"varint,1,opt,name=seq,proto3" json:"seq,omitempty"`
+	Hash    []byte `protobuf:"bytes,2,opt,name=hash,proto3" json:"hash,omitempty"`
+	Verbose bool   `protobuf:"varint,3,opt,name=verbose,proto3" json:"verbose,omitempty"`
+}
+
+func (m *GetBlockRequest) Reset()         { *m = GetBlockRequest{} }
+func (m *GetBlockRequest) String() string { return proto.CompactTextString(m) }
+func (*GetBlockRequest) ProtoMessage()    {}
+
+// GetBlocksRequest is the request message of GetBlocks
+type GetBlocksRequest struct {
+	Start   uint64 `protobuf:"varint,1,opt,name=start,proto3" json:"start,omitempty"`
+	End     uint64 `protobuf:"varint,2,opt,name=end,proto3" json:"end,omitempty"`
+	Verbose bool   `protobuf:"varint,3,opt,name=verbose,proto3" json:"verbose,omitempty"`
+}
+
+func (m *GetBlocksRequest) Reset()         { *m = GetBlocksRequest{} }
+func (m *GetBlocksRequest) String() string { return proto.CompactTextString(m) }
+func (*GetBlocksRequest) ProtoMessage()    {}
+
+// GetBlocksResponse is the response message of GetBlocks
+type GetBlocksResponse struct {
+	Blocks []*Block `protobuf:"bytes,1,rep,name=blocks,proto3" json:"blocks,omitempty"`
+}
+
+func (m *GetBlocksResponse) Reset()         { *m = GetBlocksResponse{} }
+func (m *GetBlocksResponse) String() string { return proto.CompactTextString(m) }
+func (*GetBlocksResponse) ProtoMessage()    {}
+
+// GetTransactionRequest is the request message of GetTransaction
+type GetTransactionRequest struct {
+	Txid []byte `protobuf:"bytes,1,opt,name=txid,proto3" json:"txid,omitempty"`
+}
+
+func (m *GetTransactionRequest) Reset()         { *m = GetTransactionRequest{} }
+func (m *GetTransactionRequest) String() string { return proto.CompactTextString(m) }
+func (*GetTransactionRequest) ProtoMessage()    {}
+
+// GetUnspentOutputsRequest is the request message of GetUnspentOutputs
+type GetUnspentOutputsRequest struct {
+	Addresses []string `protobuf:"bytes,1,rep,name=addresses,proto3" json:"addresses,omitempty"`
+	Hashes    [][]byte `protobuf:"bytes,2,rep,name=hashes,proto3" json:"hashes,omitempty"`
+}
+
+func (m *GetUnspentOutputsRequest) Reset()         { *m = GetUnspentOutputsRequest{} }
+func (m *GetUnspentOutputsRequest) String() string { return proto.CompactTextString(m) }
+func (*GetUnspentOutputsRequest) ProtoMessage()    {}
+
+// GetUnspentOutputsResponse is the response message of GetUnspentOutputs
+type GetUnspentOutputsResponse struct {
+	Head      *BlockHeader        `protobuf:"bytes,1,opt,name=head,proto3" json:"head,omitempty"`
+	Confirmed []*TransactionInput `protobuf:"bytes,2,rep,name=confirmed,proto3" json:"confirmed,omitempty"`
+	Outgoing  []*TransactionInput `protobuf:"bytes,3,rep,name=outgoing,proto3" json:"outgoing,omitempty"`
+	Incoming  []*TransactionInput `protobuf:"bytes,4,rep,name=incoming,proto3" json:"incoming,omitempty"`
+}
+
+func (m *GetUnspentOutputsResponse) Reset()         { *m = GetUnspentOutputsResponse{} }
+func (m *GetUnspentOutputsResponse) String() string { return proto.CompactTextString(m) }
+func (*GetUnspentOutputsResponse) ProtoMessage()    {}
+
+// GetUnconfirmedTransactionsRequest is the request message of GetUnconfirmedTransactions
+type GetUnconfirmedTransactionsRequest struct {
+}
+
+func (m *GetUnconfirmedTransactionsRequest) Reset()         { *m = GetUnconfirmedTransactionsRequest{} }
+func (m *GetUnconfirmedTransactionsRequest) String() string { return proto.CompactTextString(m) }
+func (*GetUnconfirmedTransactionsRequest) ProtoMessage()    {}
+
+// GetUnconfirmedTransactionsResponse is the response message of GetUnconfirmedTransactions
+type GetUnconfirmedTransactionsResponse struct {
+	Transactions []*UnconfirmedTransaction `protobuf:"bytes,1,rep,name=transactions,proto3" json:"transactions,omitempty"`
+}
+
+func (m *GetUnconfirmedTransactionsResponse) Reset()         { *m = GetUnconfirmedTransactionsResponse{} }
+func (m *GetUnconfirmedTransactionsResponse) String() string { return proto.CompactTextString(m) }
+func (*GetUnconfirmedTransactionsResponse) ProtoMessage()    {}
+
+// InjectTransactionRequest is the request message of InjectTransaction
+type InjectTransactionRequest struct {
+	Transaction *Transaction `protobuf:"bytes,1,opt,name=transaction,proto3" json:"transaction,omitempty"`
+}
+
+func (m *InjectTransactionRequest) Reset()         { *m = InjectTransactionRequest{} }
+func (m *InjectTransactionRequest) String() string { return proto.CompactTextString(m) }
+func (*InjectTransactionRequest) ProtoMessage()    {}
+
+// InjectTransactionResponse is the response message of InjectTransaction
+type InjectTransactionResponse struct {
+	Txid []byte `protobuf:"bytes,1,opt,name=txid,proto3" json:"txid,omitempty"`
+}
+
+func (m *InjectTransactionResponse) Reset()         { *m = InjectTransactionResponse{} }
+func (m *InjectTransactionResponse) String() string { return proto.CompactTextString(m) }
+func (*InjectTransactionResponse) ProtoMessage()    {}
+
+// SubscribeBlocksRequest is the request message of SubscribeBlocks
+type SubscribeBlocksRequest struct {
+	StartSeq uint64 `protobuf:"varint,1,opt,name=start_seq,proto3" json:"start_seq,omitempty"`
+	Latest   bool   `protobuf:"varint,2,opt,name=latest,proto3" json:"latest,omitempty"`
+	Verbose  bool   `protobuf:"varint,3,opt,name=verbose,proto3" json:"verbose,omitempty"`
+}
+
+func (m *SubscribeBlocksRequest) Reset()         { *m = SubscribeBlocksRequest{} }
+func (m *SubscribeBlocksRequest) String() string { return proto.CompactTextString(m) }
+func (*SubscribeBlocksRequest) ProtoMessage()    {}
+
+// SubscribeUnconfirmedTransactionsRequest is the request message of SubscribeUnconfirmedTransactions
+type SubscribeUnconfirmedTransactionsRequest struct {
+}
+
+func (m *SubscribeUnconfirmedTransactionsRequest) Reset() {
+	*m = SubscribeUnconfirmedTransactionsRequest{}
+}
+func (m *SubscribeUnconfirmedTransactionsRequest) String() string { return proto.CompactTextString(m) }
+func (*SubscribeUnconfirmedTransactionsRequest) ProtoMessage()    {}
+
+// PoolEvent is a change to the unconfirmed transaction pool
+type PoolEvent struct {
+	Type        PoolEventType           `protobuf:"varint,1,opt,name=type,proto3" json:"type,omitempty"`
+	Transaction *UnconfirmedTransaction `protobuf:"bytes,2,opt,name=transaction,proto3" json:"transaction,omitempty"`
+}
+
+func (m *PoolEvent) Reset()         { *m = PoolEvent{} }
+func (m *PoolEvent) String() string { return proto.CompactTextString(m) }
+func (*PoolEvent) ProtoMessage()    {}
@@ -0,0 +1,322 @@
+/*
+Package grpc implements a gRPC server for backend integrations.
+
+The server speaks the gRPC protocol over the HTTP/2 support of net/http, which requires TLS.
+Messages are protocol buffers defined in skycoin.proto, so clients can be generated for any
+language supported by protoc. Message compression is not supported.
+*/
+package grpc
+
+import (
+	"crypto/tls"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+
+	"github.com/skycoin/skycoin/src/util/logging"
+)
+
+const (
+	serviceName = "skycoin.Skycoin"
+
+	// grpcContentType is the content type of gRPC requests and responses
+	grpcContentType = "application/grpc"
+
+	// messagePrefixLength is the length of the prefix of each message, a compressed flag byte and a 4 byte big endian length
+	messagePrefixLength = 5
+
+	defaultMaxMessageSize = 4 * 1024 * 1024
+	defaultPollInterval   = time.Second
+	defaultReadTimeout    = time.Second * 10
+	defaultIdleTimeout    = time.Second * 120
+)
+
+var (
+	logger = logging.MustGetLogger("grpc")
+)
+
+// Config configures Server
+type Config struct {
+	// MaxMessageSize is the maximum size of a request message
+	MaxMessageSize int
+	// PollInterval is how often streams check the gateway for new blocks and unconfirmed transactions
+	PollInterval time.Duration
+	// ReadTimeout is the maximum duration for reading a request.
+	// There is no write timeout, since streams are long-lived
+	ReadTimeout time.Duration
+	// IdleTimeout is how long an idle HTTP/2 connection is kept open
+	IdleTimeout time.Duration
+}
+
+// Server exposes a Gatewayer over gRPC
+type Server struct {
+	server   *http.Server
+	listener net.Listener
+	config   Config
+	gateway  Gatewayer
+	methods  map[string]rpcMethod
+	quit     chan struct{}
+	done     chan struct{}
+}
+
+// sendFunc sends a message on a server stream
+type sendFunc func(proto.Message) error
+
+// rpcMethod is a unary or server-streaming RPC of the service
+type rpcMethod struct {
+	newRequest func() proto.Message
+	// unary handles a unary RPC, returning the response message
+	unary func(req proto.Message) (proto.Message, error)
+	// stream handles a server-streaming RPC, sending messages until the stream ends.
+	// done is closed when the client cancels the stream or the server is shutting down
+	stream func(req proto.Message, send sendFunc, done <-chan struct{}) error
+}
+
+// newServer creates a Server without a listener
+func newServer(c Config, gateway Gatewayer) *Server {
+	if c.MaxMessageSize == 0 {
+		c.MaxMessageSize = defaultMaxMessageSize
+	}
+	if c.PollInterval == 0 {
+		c.PollInterval = defaultPollInterval
+	}
+	if c.ReadTimeout == 0 {
+		c.ReadTimeout = defaultReadTimeout
+	}
+	if c.IdleTimeout == 0 {
+		c.IdleTimeout = defaultIdleTimeout
+	}
+
+	s := &Server{
+		config:  c,
+		gateway: gateway,
+		quit:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+
+	s.methods = s.newMethods()
+
+	s.server = &http.Server{
+		Handler:     s,
+		ReadTimeout: c.ReadTimeout,
+		IdleTimeout: c.IdleTimeout,
+	}
+
+	return s
+}
+
+// Create creates a new Server instance that listens on host with TLS.
+// gRPC requires HTTP/2, which net/http only supports over TLS
+func Create(host string, c Config, gateway Gatewayer, certFile, keyFile string) (*Server, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Infof("Using %s for the certificate", certFile)
+	logger.Infof("Using %s for the key", keyFile)
+
+	listener, err := net.Listen("tcp", host)
+	if err != nil {
+		return nil, err
+	}
+
+	s := newServer(c, gateway)
+	s.listener = listener
+	s.server.TLSConfig = &tls.Config{
+		Certificates: []tls.Certificate{cert},
+	}
+
+	return s, nil
+}
+
+// Addr returns the listening address of the Server
+func (s *Server) Addr() string {
+	if s == nil || s.listener == nil {
+		return ""
+	}
+	return s.listener.Addr().String()
+}
+
+// Serve serves gRPC on the configured host
+func (s *Server) Serve() error {
+	logger.Infof("Starting gRPC server on %s", s.listener.Addr())
+	defer logger.Info("gRPC server closed")
+	defer close(s.done)
+
+	// The certificate is already in TLSConfig. ServeTLS enables HTTP/2 with ALPN
+	if err := s.server.ServeTLS(s.listener, "", ""); err != nil {
+		if err != http.ErrServerClosed {
+			return err
+		}
+	}
+	return nil
+}
+
+// Shutdown ends all streams and closes the gRPC server. This can only be called after Serve has been called.
+func (s *Server) Shutdown() {
+	if s == nil {
+		return
+	}
+
+	logger.Info("Shutting down gRPC server")
+	defer logger.Info("gRPC server shut down")
+
+	close(s.quit)
+
+	if err := s.server.Close(); err != nil {
+		logger.WithError(err).Warning("s.server.Close() error")
+	}
+	<-s.done
+}
+
+// ServeHTTP handles a gRPC call
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !strings.HasPrefix(r.Header.Get("Content-Type"), grpcContentType) {
+		http.Error(w, http.StatusText(http.StatusUnsupportedMediaType), http.StatusUnsupportedMediaType)
+		return
+	}
+
+	w.Header().Set("Content-Type", grpcContentType)
+
+	var started bool
+	err := s.call(w, r, &started)
+	if err != nil {
+		logger.WithError(err).Debugf("gRPC call %s failed", r.URL.Path)
+	}
+
+	// The status is sent in the trailers once the response has started,
+	// otherwise it is sent in the headers of a trailers-only response
+	prefix := ""
+	if started {
+		prefix = http.TrailerPrefix
+	}
+
+	st := toStatusError(err)
+	w.Header().Set(prefix+"Grpc-Status", strconv.Itoa(int(st.code)))
+	if st.message != "" {
+		w.Header().Set(prefix+"Grpc-Message", encodeGRPCMessage(st.message))
+	}
+}
+
+// call dispatches a gRPC call to its method and writes the response messages.
+// started is set once the response headers have been written
+func (s *Server) call(w http.ResponseWriter, r *http.Request, started *bool) error {
+	m, ok := s.methods[r.URL.Path]
+	if !ok {
+		return newStatusError(codeUnimplemented, fmt.Sprintf("unknown method %s", r.URL.Path))
+	}
+
+	b, err := readMessage(r.Body, s.config.MaxMessageSize)
+	if err != nil {
+		return err
+	}
+
+	req := m.newRequest()
+	if err := proto.Unmarshal(b, req); err != nil {
+		return newStatusError(codeInvalidArgument, fmt.Sprintf("invalid request message: %v", err))
+	}
+
+	send := func(msg proto.Message) error {
+		*started = true
+		return writeMessage(w, msg)
+	}
+
+	if m.unary != nil {
+		resp, err := m.unary(req)
+		if err != nil {
+			return err
+		}
+		return send(resp)
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+
+	streamDone := make(chan struct{})
+	go func() {
+		defer close(streamDone)
+		select {
+		case <-r.Context().Done():
+		case <-s.quit:
+		case <-done:
+		}
+	}()
+
+	// Send the response headers immediately, so that the client knows the stream has started
+	*started = true
+	w.WriteHeader(http.StatusOK)
+	flush(w)
+
+	err = m.stream(req, send, streamDone)
+
+	select {
+	case <-s.quit:
+		return newStatusError(codeUnavailable, "server is shutting down")
+	default:
+	}
+
+	return err
+}
+
+// readMessage reads a length-prefixed gRPC message
+func readMessage(r io.Reader, maxSize int) ([]byte, error) {
+	var prefix [messagePrefixLength]byte
+	if _, err := io.ReadFull(r, prefix[:]); err != nil {
+		return nil, newStatusError(codeInvalidArgument, fmt.Sprintf("failed to read message prefix: %v", err))
+	}
+
+	if prefix[0] != 0 {
+		return nil, newStatusError(codeUnimplemented, "compressed messages are not supported")
+	}
+
+	n := binary.BigEndian.Uint32(prefix[1:])
+	if uint64(n) > uint64(maxSize) {
+		return nil, newStatusError(codeInvalidArgument, fmt.Sprintf("message size %d exceeds the maximum of %d", n, maxSize))
+	}
+
+	b := make([]byte, n)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return nil, newStatusError(codeInvalidArgument, fmt.Sprintf("failed to read message: %v", err))
+	}
+
+	return b, nil
+}
+
+// writeMessage writes a length-prefixed gRPC message and flushes it to the client
+func writeMessage(w io.Writer, msg proto.Message) error {
+	b, err := proto.Marshal(msg)
+	if err != nil {
+		return newStatusError(codeInternal, fmt.Sprintf("failed to marshal response message: %v", err))
+	}
+
+	buf := make([]byte, messagePrefixLength+len(b))
+	binary.BigEndian.PutUint32(buf[1:messagePrefixLength], uint32(len(b)))
+	copy(buf[messagePrefixLength:], b)
+
+	if _, err := w.Write(buf); err != nil {
+		return newStatusError(codeUnavailable, fmt.Sprintf("failed to write response message: %v", err))
+	}
+
+	flush(w)
+
+	return nil
+}
+
+func flush(w io.Writer) {
+	if f, ok := w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
@@ -0,0 +1,507 @@
+package grpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/daemon"
+	"github.com/skycoin/skycoin/src/testutil"
+	"github.com/skycoin/skycoin/src/visor"
+)
+
+// grpcCall is the result of a gRPC call made with callGRPC
+type grpcCall struct {
+	resp    *http.Response
+	cancel  context.CancelFunc
+	status  statusCode
+	message string
+}
+
+// callGRPC makes a gRPC call to the test server. The response body is left open for reading messages
+func callGRPC(t *testing.T, ts *httptest.Server, method string, req proto.Message) *grpcCall {
+	b, err := proto.Marshal(req)
+	require.NoError(t, err)
+
+	body := make([]byte, messagePrefixLength+len(b))
+	binary.BigEndian.PutUint32(body[1:messagePrefixLength], uint32(len(b)))
+	copy(body[messagePrefixLength:], b)
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	r, err := http.NewRequest(http.MethodPost, ts.URL+"/"+serviceName+"/"+method, bytes.NewReader(body))
+	require.NoError(t, err)
+	r = r.WithContext(ctx)
+	r.Header.Set("Content-Type", grpcContentType)
+
+	resp, err := ts.Client().Do(r)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, grpcContentType, resp.Header.Get("Content-Type"))
+
+	return &grpcCall{
+		resp:   resp,
+		cancel: cancel,
+	}
+}
+
+// recv reads the next message of the response, returning io.EOF and setting the status once the response ends
+func (c *grpcCall) recv(t *testing.T, msg proto.Message) error {
+	var prefix [messagePrefixLength]byte
+	if _, err := io.ReadFull(c.resp.Body, prefix[:]); err != nil {
+		require.Equal(t, io.EOF, err)
+
+		// A trailers-only response has the status in the headers
+		st := c.resp.Header.Get("Grpc-Status")
+		msg := c.resp.Header.Get("Grpc-Message")
+		if st == "" {
+			st = c.resp.Trailer.Get("Grpc-Status")
+			msg = c.resp.Trailer.Get("Grpc-Message")
+		}
+
+		code, err := strconv.Atoi(st)
+		require.NoError(t, err)
+		c.status = statusCode(code)
+		c.message = msg
+
+		return io.EOF
+	}
+
+	b := make([]byte, binary.BigEndian.Uint32(prefix[1:]))
+	_, err := io.ReadFull(c.resp.Body, b)
+	require.NoError(t, err)
+
+	return proto.Unmarshal(b, msg)
+}
+
+func (c *grpcCall) close() {
+	c.cancel()
+	c.resp.Body.Close()
+}
+
+// unaryGRPC makes a unary gRPC call, returning the status
+func unaryGRPC(t *testing.T, ts *httptest.Server, method string, req, resp proto.Message) (statusCode, string) {
+	c := callGRPC(t, ts, method, req)
+	defer c.close()
+
+	err := c.recv(t, resp)
+	if err == nil {
+		require.Equal(t, io.EOF, c.recv(t, resp))
+	} else {
+		require.Equal(t, io.EOF, err)
+	}
+
+	return c.status, c.message
+}
+
+func newTestServer(gateway Gatewayer) (*Server, *httptest.Server) {
+	s := newServer(Config{
+		PollInterval: time.Millisecond * 10,
+	}, gateway)
+	return s, httptest.NewServer(s)
+}
+
+func makeBlock(t *testing.T, seq uint64) coin.SignedBlock {
+	txn := coin.Transaction{}
+	err := txn.PushOutput(testutil.MakeAddress(), 1e6, 100)
+	require.NoError(t, err)
+	err = txn.PushInput(testutil.RandSHA256(t))
+	require.NoError(t, err)
+	_, sk := cipher.GenerateKeyPair()
+	txn.SignInputs([]cipher.SecKey{sk})
+	err = txn.UpdateHeader()
+	require.NoError(t, err)
+
+	return coin.SignedBlock{
+		Block: coin.Block{
+			Head: coin.BlockHeader{
+				BkSeq: seq,
+				Time:  1500000000 + seq*10,
+			},
+			Body: coin.BlockBody{
+				Transactions: coin.Transactions{txn},
+			},
+		},
+	}
+}
+
+func TestRequestErrors(t *testing.T) {
+	_, ts := newTestServer(&MockGatewayer{})
+	defer ts.Close()
+
+	// Only POST is allowed
+	resp, err := ts.Client().Get(ts.URL + "/" + serviceName + "/GetBlock")
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+
+	// The content type must be application/grpc
+	resp, err = ts.Client().Post(ts.URL+"/"+serviceName+"/GetBlock", "application/json", nil)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusUnsupportedMediaType, resp.StatusCode)
+
+	// Unknown method
+	code, msg := unaryGRPC(t, ts, "Foo", &GetBlockRequest{}, &Block{})
+	require.Equal(t, codeUnimplemented, code)
+	require.Equal(t, "unknown method /skycoin.Skycoin/Foo", msg)
+
+	// Empty body
+	r, err := http.NewRequest(http.MethodPost, ts.URL+"/"+serviceName+"/GetBlock", nil)
+	require.NoError(t, err)
+	r.Header.Set("Content-Type", grpcContentType)
+	resp, err = ts.Client().Do(r)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, strconv.Itoa(int(codeInvalidArgument)), resp.Header.Get("Grpc-Status"))
+
+	// Compressed message
+	r, err = http.NewRequest(http.MethodPost, ts.URL+"/"+serviceName+"/GetBlock", bytes.NewReader([]byte{1, 0, 0, 0, 0}))
+	require.NoError(t, err)
+	r.Header.Set("Content-Type", grpcContentType)
+	resp, err = ts.Client().Do(r)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, strconv.Itoa(int(codeUnimplemented)), resp.Header.Get("Grpc-Status"))
+}
+
+func TestGetBlock(t *testing.T) {
+	b := makeBlock(t, 3)
+	inputs := [][]visor.TransactionInput{
+		{
+			{
+				UxOut: coin.UxOut{
+					Body: coin.UxBody{
+						Address: testutil.MakeAddress(),
+						Coins:   2e6,
+						Hours:   20,
+					},
+				},
+				CalculatedHours: 25,
+			},
+		},
+	}
+
+	expected, err := newBlock(b, nil)
+	require.NoError(t, err)
+	expectedVerbose, err := newBlock(b, inputs)
+	require.NoError(t, err)
+
+	hash := b.HashHeader()
+
+	cases := []struct {
+		name     string
+		req      *GetBlockRequest
+		setup    func(gw *MockGatewayer)
+		code     statusCode
+		message  string
+		expected *Block
+	}{
+		{
+			name: "by seq",
+			req:  &GetBlockRequest{Seq: 3},
+			setup: func(gw *MockGatewayer) {
+				gw.On("GetSignedBlockBySeq", uint64(3)).Return(&b, nil)
+			},
+			expected: expected,
+		},
+		{
+			name: "by seq verbose",
+			req:  &GetBlockRequest{Seq: 3, Verbose: true},
+			setup: func(gw *MockGatewayer) {
+				gw.On("GetSignedBlockBySeqVerbose", uint64(3)).Return(&b, inputs, nil)
+			},
+			expected: expectedVerbose,
+		},
+		{
+			name: "by hash",
+			req:  &GetBlockRequest{Hash: hash[:]},
+			setup: func(gw *MockGatewayer) {
+				gw.On("GetSignedBlockByHash", hash).Return(&b, nil)
+			},
+			expected: expected,
+		},
+		{
+			name: "by hash verbose",
+			req:  &GetBlockRequest{Hash: hash[:], Verbose: true},
+			setup: func(gw *MockGatewayer) {
+				gw.On("GetSignedBlockByHashVerbose", hash).Return(&b, inputs, nil)
+			},
+			expected: expectedVerbose,
+		},
+		{
+			name:    "invalid hash",
+			req:     &GetBlockRequest{Hash: []byte{1, 2, 3}},
+			code:    codeInvalidArgument,
+			message: "hash is invalid: Invalid sha256 length",
+		},
+		{
+			name: "not found",
+			req:  &GetBlockRequest{Seq: 4},
+			setup: func(gw *MockGatewayer) {
+				gw.On("GetSignedBlockBySeq", uint64(4)).Return(nil, nil)
+			},
+			code:    codeNotFound,
+			message: "block not found",
+		},
+		{
+			name: "gateway error",
+			req:  &GetBlockRequest{Seq: 4},
+			setup: func(gw *MockGatewayer) {
+				gw.On("GetSignedBlockBySeq", uint64(4)).Return(nil, errors.New("GetSignedBlockBySeq failed"))
+			},
+			code:    codeInternal,
+			message: "GetSignedBlockBySeq failed",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			gw := &MockGatewayer{}
+			if tc.setup != nil {
+				tc.setup(gw)
+			}
+
+			_, ts := newTestServer(gw)
+			defer ts.Close()
+
+			var blk Block
+			code, msg := unaryGRPC(t, ts, "GetBlock", tc.req, &blk)
+			require.Equal(t, tc.code, code)
+			require.Equal(t, tc.message, msg)
+
+			if tc.expected != nil {
+				require.True(t, proto.Equal(tc.expected, &blk), "expected %v, got %v", tc.expected, &blk)
+			}
+
+			gw.AssertExpectations(t)
+		})
+	}
+}
+
+func TestInjectTransaction(t *testing.T) {
+	txn := makeBlock(t, 0).Body.Transactions[0]
+
+	cases := []struct {
+		name    string
+		txn     *Transaction
+		err     error
+		code    statusCode
+		message string
+	}{
+		{
+			name: "ok",
+			txn:  newTransaction(txn),
+		},
+		{
+			name:    "missing transaction",
+			code:    codeInvalidArgument,
+			message: "transaction is required",
+		},
+		{
+			name: "invalid address",
+			txn: func() *Transaction {
+				t := newTransaction(txn)
+				t.Out[0].Address = "foo"
+				return t
+			}(),
+			code:    codeInvalidArgument,
+			message: "out[0].address is invalid: Invalid address length",
+		},
+		{
+			name:    "broadcast failure",
+			txn:     newTransaction(txn),
+			err:     daemon.ErrNetworkingDisabled,
+			code:    codeUnavailable,
+			message: daemon.ErrNetworkingDisabled.Error(),
+		},
+		{
+			name:    "constraint violation",
+			txn:     newTransaction(txn),
+			err:     visor.NewErrTxnViolatesHardConstraint(errors.New("bad txn")),
+			code:    codeFailedPrecondition,
+			message: "Transaction violates hard constraint: bad txn",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			gw := &MockGatewayer{}
+			gw.On("InjectBroadcastTransaction", txn).Return(tc.err)
+
+			_, ts := newTestServer(gw)
+			defer ts.Close()
+
+			var resp InjectTransactionResponse
+			code, msg := unaryGRPC(t, ts, "InjectTransaction", &InjectTransactionRequest{
+				Transaction: tc.txn,
+			}, &resp)
+			require.Equal(t, tc.code, code)
+			require.Equal(t, tc.message, msg)
+
+			if tc.code == codeOK {
+				h := txn.Hash()
+				require.Equal(t, h[:], resp.Txid)
+			}
+		})
+	}
+}
+
+func TestGetUnspentOutputs(t *testing.T) {
+	gw := &MockGatewayer{}
+	_, ts := newTestServer(gw)
+	defer ts.Close()
+
+	var resp GetUnspentOutputsResponse
+	h := testutil.RandSHA256(t)
+	code, msg := unaryGRPC(t, ts, "GetUnspentOutputs", &GetUnspentOutputsRequest{
+		Addresses: []string{testutil.MakeAddress().String()},
+		Hashes:    [][]byte{h[:]},
+	}, &resp)
+	require.Equal(t, codeInvalidArgument, code)
+	require.Equal(t, "addresses and hashes cannot be specified together", msg)
+
+	head := makeBlock(t, 5)
+	ux := coin.UxOut{
+		Head: coin.UxHead{
+			BkSeq: 2,
+		},
+		Body: coin.UxBody{
+			Address: testutil.MakeAddress(),
+			Coins:   1e6,
+			Hours:   3,
+		},
+	}
+
+	gw.On("GetUnspentOutputsSummary", []visor.OutputsFilter(nil)).Return(&visor.UnspentOutputsSummary{
+		HeadBlock: &head,
+		Confirmed: []visor.UnspentOutput{
+			{
+				UxOut:           ux,
+				CalculatedHours: 10,
+			},
+		},
+	}, nil)
+
+	code, msg = unaryGRPC(t, ts, "GetUnspentOutputs", &GetUnspentOutputsRequest{}, &resp)
+	require.Equal(t, codeOK, code)
+	require.Empty(t, msg)
+	require.Equal(t, uint64(5), resp.Head.Seq)
+	require.Len(t, resp.Confirmed, 1)
+	require.Equal(t, uint64(10), resp.Confirmed[0].CalculatedHours)
+	require.Equal(t, ux.Body.Address.String(), resp.Confirmed[0].UxOut.Address)
+	require.Empty(t, resp.Outgoing)
+	require.Empty(t, resp.Incoming)
+}
+
+func TestSubscribeBlocks(t *testing.T) {
+	b0 := makeBlock(t, 0)
+	b1 := makeBlock(t, 1)
+	b2 := makeBlock(t, 2)
+
+	gw := &MockGatewayer{}
+	gw.On("HeadBkSeq").Return(uint64(1), true, nil).Once()
+	gw.On("HeadBkSeq").Return(uint64(2), true, nil)
+	gw.On("GetBlocksInRange", uint64(0), uint64(1)).Return([]coin.SignedBlock{b0, b1}, nil)
+	gw.On("GetBlocksInRange", uint64(2), uint64(2)).Return([]coin.SignedBlock{b2}, nil)
+
+	_, ts := newTestServer(gw)
+	defer ts.Close()
+
+	c := callGRPC(t, ts, "SubscribeBlocks", &SubscribeBlocksRequest{})
+	defer c.close()
+
+	for _, b := range []coin.SignedBlock{b0, b1, b2} {
+		var blk Block
+		err := c.recv(t, &blk)
+		require.NoError(t, err)
+
+		expected, err := newBlock(b, nil)
+		require.NoError(t, err)
+		require.True(t, proto.Equal(expected, &blk), "expected %v, got %v", expected, &blk)
+	}
+}
+
+func TestSubscribeUnconfirmedTransactions(t *testing.T) {
+	makeUnconfirmed := func(received int64) visor.UnconfirmedTransaction {
+		return visor.UnconfirmedTransaction{
+			Transaction: makeBlock(t, 0).Body.Transactions[0],
+			Received:    received,
+		}
+	}
+
+	txn1 := makeUnconfirmed(1)
+	txn2 := makeUnconfirmed(2)
+	txn3 := makeUnconfirmed(3)
+
+	gw := &MockGatewayer{}
+	gw.On("GetAllUnconfirmedTransactions").Return([]visor.UnconfirmedTransaction{txn2, txn1}, nil).Once()
+	gw.On("GetAllUnconfirmedTransactions").Return([]visor.UnconfirmedTransaction{txn3, txn2}, nil)
+
+	_, ts := newTestServer(gw)
+	defer ts.Close()
+
+	c := callGRPC(t, ts, "SubscribeUnconfirmedTransactions", &SubscribeUnconfirmedTransactionsRequest{})
+	defer c.close()
+
+	expected := []*PoolEvent{
+		{Type: PoolEventAdded, Transaction: newUnconfirmedTransaction(txn1)},
+		{Type: PoolEventAdded, Transaction: newUnconfirmedTransaction(txn2)},
+		{Type: PoolEventAdded, Transaction: newUnconfirmedTransaction(txn3)},
+		{Type: PoolEventRemoved, Transaction: newUnconfirmedTransaction(txn1)},
+	}
+
+	for _, e := range expected {
+		var ev PoolEvent
+		err := c.recv(t, &ev)
+		require.NoError(t, err)
+		require.True(t, proto.Equal(e, &ev), "expected %v, got %v", e, &ev)
+	}
+}
+
+func TestShutdownEndsStreams(t *testing.T) {
+	gw := &MockGatewayer{}
+	gw.On("HeadBkSeq").Return(uint64(0), false, nil)
+
+	s, ts := newTestServer(gw)
+	defer ts.Close()
+
+	c := callGRPC(t, ts, "SubscribeBlocks", &SubscribeBlocksRequest{Latest: true})
+	defer c.close()
+
+	close(s.quit)
+
+	var blk Block
+	err := c.recv(t, &blk)
+	require.Equal(t, io.EOF, err)
+	require.Equal(t, codeUnavailable, c.status)
+	require.Equal(t, "server is shutting down", c.message)
+}
+
+func TestTransactionConversion(t *testing.T) {
+	txn := makeBlock(t, 0).Body.Transactions[0]
+
+	pt := newTransaction(txn)
+	b, err := proto.Marshal(pt)
+	require.NoError(t, err)
+
+	var decoded Transaction
+	err = proto.Unmarshal(b, &decoded)
+	require.NoError(t, err)
+
+	txn2, err := toCoinTransaction(&decoded)
+	require.NoError(t, err)
+	require.Equal(t, txn, *txn2)
+}
@@ -0,0 +1,416 @@
+package grpc
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/daemon"
+	"github.com/skycoin/skycoin/src/visor"
+)
+
+// maxBlocksPerPoll is the maximum number of blocks a SubscribeBlocks stream loads from the gateway at once
+const maxBlocksPerPoll = 100
+
+// newMethods returns the RPCs of the service, by their HTTP/2 path
+func (s *Server) newMethods() map[string]rpcMethod {
+	methods := map[string]rpcMethod{
+		"GetBlock": {
+			newRequest: func() proto.Message { return &GetBlockRequest{} },
+			unary: func(req proto.Message) (proto.Message, error) {
+				return s.getBlock(req.(*GetBlockRequest))
+			},
+		},
+		"GetBlocks": {
+			newRequest: func() proto.Message { return &GetBlocksRequest{} },
+			unary: func(req proto.Message) (proto.Message, error) {
+				return s.getBlocks(req.(*GetBlocksRequest))
+			},
+		},
+		"GetTransaction": {
+			newRequest: func() proto.Message { return &GetTransactionRequest{} },
+			unary: func(req proto.Message) (proto.Message, error) {
+				return s.getTransaction(req.(*GetTransactionRequest))
+			},
+		},
+		"GetUnspentOutputs": {
+			newRequest: func() proto.Message { return &GetUnspentOutputsRequest{} },
+			unary: func(req proto.Message) (proto.Message, error) {
+				return s.getUnspentOutputs(req.(*GetUnspentOutputsRequest))
+			},
+		},
+		"GetUnconfirmedTransactions": {
+			newRequest: func() proto.Message { return &GetUnconfirmedTransactionsRequest{} },
+			unary: func(req proto.Message) (proto.Message, error) {
+				return s.getUnconfirmedTransactions(req.(*GetUnconfirmedTransactionsRequest))
+			},
+		},
+		"InjectTransaction": {
+			newRequest: func() proto.Message { return &InjectTransactionRequest{} },
+			unary: func(req proto.Message) (proto.Message, error) {
+				return s.injectTransaction(req.(*InjectTransactionRequest))
+			},
+		},
+		"SubscribeBlocks": {
+			newRequest: func() proto.Message { return &SubscribeBlocksRequest{} },
+			stream: func(req proto.Message, send sendFunc, done <-chan struct{}) error {
+				return s.subscribeBlocks(req.(*SubscribeBlocksRequest), send, done)
+			},
+		},
+		"SubscribeUnconfirmedTransactions": {
+			newRequest: func() proto.Message { return &SubscribeUnconfirmedTransactionsRequest{} },
+			stream: func(req proto.Message, send sendFunc, done <-chan struct{}) error {
+				return s.subscribeUnconfirmedTransactions(req.(*SubscribeUnconfirmedTransactionsRequest), send, done)
+			},
+		},
+	}
+
+	paths := make(map[string]rpcMethod, len(methods))
+	for name, m := range methods {
+		paths[fmt.Sprintf("/%s/%s", serviceName, name)] = m
+	}
+
+	return paths
+}
+
+func (s *Server) getBlock(req *GetBlockRequest) (*Block, error) {
+	var b *coin.SignedBlock
+	var inputs [][]visor.TransactionInput
+	var err error
+
+	if len(req.Hash) != 0 {
+		h, err := cipher.SHA256FromBytes(req.Hash)
+		if err != nil {
+			return nil, newStatusError(codeInvalidArgument, fmt.Sprintf("hash is invalid: %v", err))
+		}
+
+		if req.Verbose {
+			b, inputs, err = s.gateway.GetSignedBlockByHashVerbose(h)
+		} else {
+			b, err = s.gateway.GetSignedBlockByHash(h)
+		}
+	} else {
+		if req.Verbose {
+			b, inputs, err = s.gateway.GetSignedBlockBySeqVerbose(req.Seq)
+		} else {
+			b, err = s.gateway.GetSignedBlockBySeq(req.Seq)
+		}
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if b == nil {
+		return nil, newStatusError(codeNotFound, "block not found")
+	}
+
+	return newBlock(*b, inputs)
+}
+
+func (s *Server) getBlocks(req *GetBlocksRequest) (*GetBlocksResponse, error) {
+	if req.Start > req.End {
+		return nil, newStatusError(codeInvalidArgument, "start must not be greater than end")
+	}
+
+	blocks, inputs, err := s.getBlocksInRange(req.Start, req.End, req.Verbose)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &GetBlocksResponse{
+		Blocks: make([]*Block, len(blocks)),
+	}
+
+	for i, b := range blocks {
+		var in [][]visor.TransactionInput
+		if inputs != nil {
+			in = inputs[i]
+		}
+
+		resp.Blocks[i], err = newBlock(b, in)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return resp, nil
+}
+
+func (s *Server) getBlocksInRange(start, end uint64, verbose bool) ([]coin.SignedBlock, [][][]visor.TransactionInput, error) {
+	var blocks []coin.SignedBlock
+	var inputs [][][]visor.TransactionInput
+	var err error
+
+	if verbose {
+		blocks, inputs, err = s.gateway.GetBlocksInRangeVerbose(start, end)
+	} else {
+		blocks, err = s.gateway.GetBlocksInRange(start, end)
+	}
+
+	if err != nil {
+		switch err.(type) {
+		case visor.ErrBlockNotExist:
+			return nil, nil, newStatusError(codeNotFound, err.Error())
+		default:
+			return nil, nil, err
+		}
+	}
+
+	return blocks, inputs, nil
+}
+
+func (s *Server) getTransaction(req *GetTransactionRequest) (*TransactionWithStatus, error) {
+	txid, err := cipher.SHA256FromBytes(req.Txid)
+	if err != nil {
+		return nil, newStatusError(codeInvalidArgument, fmt.Sprintf("txid is invalid: %v", err))
+	}
+
+	txn, inputs, err := s.gateway.GetTransactionWithInputs(txid)
+	if err != nil {
+		return nil, err
+	}
+
+	if txn == nil {
+		return nil, newStatusError(codeNotFound, "transaction not found")
+	}
+
+	return &TransactionWithStatus{
+		Transaction: newTransaction(txn.Transaction),
+		Status:      newTransactionStatus(txn.Status),
+		Time:        txn.Time,
+		Inputs:      newTransactionInputs(inputs),
+	}, nil
+}
+
+func (s *Server) getUnspentOutputs(req *GetUnspentOutputsRequest) (*GetUnspentOutputsResponse, error) {
+	if len(req.Addresses) != 0 && len(req.Hashes) != 0 {
+		return nil, newStatusError(codeInvalidArgument, "addresses and hashes cannot be specified together")
+	}
+
+	var filters []visor.OutputsFilter
+
+	if len(req.Addresses) != 0 {
+		addrs := make([]cipher.Address, len(req.Addresses))
+		for i, a := range req.Addresses {
+			addr, err := cipher.DecodeBase58Address(a)
+			if err != nil {
+				return nil, newStatusError(codeInvalidArgument, fmt.Sprintf("address %q is invalid: %v", a, err))
+			}
+			addrs[i] = addr
+		}
+
+		filters = append(filters, visor.FbyAddresses(addrs))
+	}
+
+	if len(req.Hashes) != 0 {
+		hashes := make([]cipher.SHA256, len(req.Hashes))
+		for i, b := range req.Hashes {
+			h, err := cipher.SHA256FromBytes(b)
+			if err != nil {
+				return nil, newStatusError(codeInvalidArgument, fmt.Sprintf("hashes[%d] is invalid: %v", i, err))
+			}
+			hashes[i] = h
+		}
+
+		filters = append(filters, visor.FbyHashes(hashes))
+	}
+
+	summary, err := s.gateway.GetUnspentOutputsSummary(filters)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &GetUnspentOutputsResponse{
+		Confirmed: newUnspentOutputs(summary.Confirmed),
+		Outgoing:  newUnspentOutputs(summary.Outgoing),
+		Incoming:  newUnspentOutputs(summary.Incoming),
+	}
+
+	if summary.HeadBlock != nil {
+		resp.Head = newBlockHeader(summary.HeadBlock.Head)
+	}
+
+	return resp, nil
+}
+
+func (s *Server) getUnconfirmedTransactions(req *GetUnconfirmedTransactionsRequest) (*GetUnconfirmedTransactionsResponse, error) {
+	txns, err := s.gateway.GetAllUnconfirmedTransactions()
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &GetUnconfirmedTransactionsResponse{
+		Transactions: make([]*UnconfirmedTransaction, len(txns)),
+	}
+
+	for i, txn := range txns {
+		resp.Transactions[i] = newUnconfirmedTransaction(txn)
+	}
+
+	return resp, nil
+}
+
+func (s *Server) injectTransaction(req *InjectTransactionRequest) (*InjectTransactionResponse, error) {
+	txn, err := toCoinTransaction(req.Transaction)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.gateway.InjectBroadcastTransaction(*txn); err != nil {
+		switch err.(type) {
+		case visor.ErrTxnViolatesHardConstraint,
+			visor.ErrTxnViolatesSoftConstraint,
+			visor.ErrTxnViolatesUserConstraint:
+			return nil, newStatusError(codeFailedPrecondition, err.Error())
+		}
+
+		if daemon.IsBroadcastFailure(err) {
+			return nil, newStatusError(codeUnavailable, err.Error())
+		}
+
+		return nil, err
+	}
+
+	return &InjectTransactionResponse{
+		Txid: hashBytes(txn.Hash()),
+	}, nil
+}
+
+// subscribeBlocks streams blocks from the requested seq, then polls the gateway for new blocks
+func (s *Server) subscribeBlocks(req *SubscribeBlocksRequest, send sendFunc, done <-chan struct{}) error {
+	next := req.StartSeq
+	if req.Latest {
+		head, ok, err := s.gateway.HeadBkSeq()
+		if err != nil {
+			return err
+		}
+
+		next = 0
+		if ok {
+			next = head + 1
+		}
+	}
+
+	for {
+		head, ok, err := s.gateway.HeadBkSeq()
+		if err != nil {
+			return err
+		}
+
+		if ok && head >= next {
+			end := head
+			if end-next >= maxBlocksPerPoll {
+				end = next + maxBlocksPerPoll - 1
+			}
+
+			blocks, inputs, err := s.getBlocksInRange(next, end, req.Verbose)
+			if err != nil {
+				return err
+			}
+
+			for i, b := range blocks {
+				var in [][]visor.TransactionInput
+				if inputs != nil {
+					in = inputs[i]
+				}
+
+				blk, err := newBlock(b, in)
+				if err != nil {
+					return err
+				}
+
+				if err := send(blk); err != nil {
+					return err
+				}
+
+				next = b.Seq() + 1
+			}
+
+			// Keep sending without waiting until the stream has caught up with the head block
+			if next <= head && len(blocks) != 0 {
+				select {
+				case <-done:
+					return nil
+				default:
+					continue
+				}
+			}
+		}
+
+		select {
+		case <-done:
+			return nil
+		case <-time.After(s.config.PollInterval):
+		}
+	}
+}
+
+// subscribeUnconfirmedTransactions sends the transactions in the unconfirmed transaction pool,
+// then polls the gateway for transactions added to or removed from the pool
+func (s *Server) subscribeUnconfirmedTransactions(req *SubscribeUnconfirmedTransactionsRequest, send sendFunc, done <-chan struct{}) error {
+	known := make(map[cipher.SHA256]visor.UnconfirmedTransaction)
+
+	for {
+		txns, err := s.gateway.GetAllUnconfirmedTransactions()
+		if err != nil {
+			return err
+		}
+
+		// Send added transactions in the order they were received
+		sort.SliceStable(txns, func(i, j int) bool {
+			return txns[i].Received < txns[j].Received
+		})
+
+		current := make(map[cipher.SHA256]struct{}, len(txns))
+		for _, txn := range txns {
+			h := txn.Transaction.Hash()
+			current[h] = struct{}{}
+
+			if _, ok := known[h]; ok {
+				continue
+			}
+
+			if err := send(&PoolEvent{
+				Type:        PoolEventAdded,
+				Transaction: newUnconfirmedTransaction(txn),
+			}); err != nil {
+				return err
+			}
+
+			known[h] = txn
+		}
+
+		var removed []cipher.SHA256
+		for h := range known {
+			if _, ok := current[h]; !ok {
+				removed = append(removed, h)
+			}
+		}
+
+		sort.Slice(removed, func(i, j int) bool {
+			return known[removed[i]].Received < known[removed[j]].Received
+		})
+
+		for _, h := range removed {
+			if err := send(&PoolEvent{
+				Type:        PoolEventRemoved,
+				Transaction: newUnconfirmedTransaction(known[h]),
+			}); err != nil {
+				return err
+			}
+
+			delete(known, h)
+		}
+
+		select {
+		case <-done:
+			return nil
+		case <-time.After(s.config.PollInterval):
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package grpc
+
+import (
+	"fmt"
+	"strings"
+)
+
+// statusCode is a gRPC status code, see https://github.com/grpc/grpc/blob/master/doc/statuscodes.md
+type statusCode int
+
+const (
+	codeOK                 statusCode = 0
+	codeCanceled           statusCode = 1
+	codeUnknown            statusCode = 2
+	codeInvalidArgument    statusCode = 3
+	codeNotFound           statusCode = 5
+	codeFailedPrecondition statusCode = 9
+	codeUnimplemented      statusCode = 12
+	codeInternal           statusCode = 13
+	codeUnavailable        statusCode = 14
+)
+
+// statusError is an error with a gRPC status code, sent to the client in the grpc-status and grpc-message trailers
+type statusError struct {
+	code    statusCode
+	message string
+}
+
+func newStatusError(code statusCode, message string) statusError {
+	return statusError{
+		code:    code,
+		message: message,
+	}
+}
+
+func (e statusError) Error() string {
+	return e.message
+}
+
+// toStatusError converts an error to a statusError, errors without a status code are internal errors
+func toStatusError(err error) statusError {
+	if err == nil {
+		return newStatusError(codeOK, "")
+	}
+
+	if e, ok := err.(statusError); ok {
+		return e
+	}
+
+	return newStatusError(codeInternal, err.Error())
+}
+
+// encodeGRPCMessage percent-encodes a status message for the grpc-message trailer.
+// As required by the gRPC protocol, only '%' and bytes outside of printable ASCII are encoded
+func encodeGRPCMessage(msg string) string {
+	var b strings.Builder
+	for i := 0; i < len(msg); i++ {
+		c := msg[i]
+		if c < ' ' || c > '~' || c == '%' {
+			fmt.Fprintf(&b, "%%%02X", c)
+		} else {
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
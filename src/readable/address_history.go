@@ -0,0 +1,98 @@
+package readable
+
+import (
+	"errors"
+	"math"
+
+	"github.com/skycoin/skycoin/src/visor"
+)
+
+// AddressHistory is the transaction history of an address, most recent first
+type AddressHistory struct {
+	Address string                `json:"address"`
+	Entries []AddressHistoryEntry `json:"entries"`
+}
+
+// AddressHistoryEntry is a transaction that changed the balance of an address.
+// All coin values are in droplets.
+type AddressHistoryEntry struct {
+	Txid          string `json:"txid"`
+	Direction     string `json:"direction"`
+	Confirmed     bool   `json:"confirmed"`
+	Confirmations uint64 `json:"confirmations"`
+	BlockSeq      uint64 `json:"block_seq"`
+	Time          uint64 `json:"time"`
+	// Net change of the address's coins, negative if coins were sent away
+	CoinsDelta int64 `json:"coins_delta"`
+	// Net change of the address's coin hours, negative if hours were sent away or burned
+	HoursDelta int64   `json:"hours_delta"`
+	Received   Balance `json:"received"`
+	Spent      Balance `json:"spent"`
+	Balance    Balance `json:"balance"`
+}
+
+// NewAddressHistory creates an AddressHistory from visor.AddressHistory
+func NewAddressHistory(h visor.AddressHistory) (*AddressHistory, error) {
+	entries := make([]AddressHistoryEntry, len(h.Entries))
+	for i, e := range h.Entries {
+		coinsDelta, err := signedDelta(e.Received.Coins, e.Spent.Coins)
+		if err != nil {
+			return nil, err
+		}
+
+		hoursDelta, err := signedDelta(e.Received.Hours, e.Spent.Hours)
+		if err != nil {
+			return nil, err
+		}
+
+		entries[i] = AddressHistoryEntry{
+			Txid:          e.Transaction.Transaction.Hash().Hex(),
+			Direction:     string(e.Direction),
+			Confirmed:     e.Transaction.Status.Confirmed,
+			Confirmations: e.Transaction.Status.Height,
+			BlockSeq:      e.Transaction.Status.BlockSeq,
+			Time:          e.Transaction.Time,
+			CoinsDelta:    coinsDelta,
+			HoursDelta:    hoursDelta,
+			Received:      NewBalance(e.Received),
+			Spent:         NewBalance(e.Spent),
+			Balance:       NewBalance(e.Balance),
+		}
+	}
+
+	return &AddressHistory{
+		Address: h.Address.String(),
+		Entries: entries,
+	}, nil
+}
+
+// NewAddressHistories converts []visor.AddressHistory to []AddressHistory
+func NewAddressHistories(hs []visor.AddressHistory) ([]AddressHistory, error) {
+	rhs := make([]AddressHistory, len(hs))
+	for i, h := range hs {
+		rh, err := NewAddressHistory(h)
+		if err != nil {
+			return nil, err
+		}
+		rhs[i] = *rh
+	}
+
+	return rhs, nil
+}
+
+// signedDelta returns a - b as an int64
+func signedDelta(a, b uint64) (int64, error) {
+	if a >= b {
+		d := a - b
+		if d > math.MaxInt64 {
+			return 0, errors.New("delta overflows int64")
+		}
+		return int64(d), nil
+	}
+
+	d := b - a
+	if d > math.MaxInt64 {
+		return 0, errors.New("delta overflows int64")
+	}
+	return -int64(d), nil
+}
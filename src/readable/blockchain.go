@@ -32,6 +32,30 @@ type BlockchainProgress struct {
 	Highest uint64 `json:"highest"`
 	// Individual blockchain length reports from peers
 	Peers []PeerBlockchainHeight `json:"peers"`
+	// Block download status
+	Sync SyncProgress `json:"sync"`
+}
+
+// SyncProgress is the status of the parallel block download
+type SyncProgress struct {
+	// Number of block requests waiting for a response
+	RequestsInFlight int `json:"requests_in_flight"`
+	// Number of blocks in requests waiting for a response
+	BlocksInFlight uint64 `json:"blocks_in_flight"`
+	// Number of received blocks waiting for earlier blocks to be received
+	BlocksBuffered int `json:"blocks_buffered"`
+	// Number of block requests that timed out
+	Timeouts uint64 `json:"timeouts"`
+	// Block download status of each peer
+	Peers []PeerSyncProgress `json:"peers"`
+}
+
+// PeerSyncProgress is the block download status of a peer
+type PeerSyncProgress struct {
+	Address          string `json:"address"`
+	RequestsInFlight int    `json:"requests_in_flight"`
+	BlocksReceived   uint64 `json:"blocks_received"`
+	Timeouts         uint64 `json:"timeouts"`
 }
 
 // PeerBlockchainHeight is a peer's IP address with their reported blockchain height
@@ -50,9 +74,26 @@ func NewBlockchainProgress(bp *daemon.BlockchainProgress) BlockchainProgress {
 		}
 	}
 
+	syncPeers := make([]PeerSyncProgress, len(bp.Sync.Peers))
+	for i, p := range bp.Sync.Peers {
+		syncPeers[i] = PeerSyncProgress{
+			Address:          p.Address,
+			RequestsInFlight: p.RequestsInFlight,
+			BlocksReceived:   p.BlocksReceived,
+			Timeouts:         p.Timeouts,
+		}
+	}
+
 	return BlockchainProgress{
 		Current: bp.Current,
 		Highest: bp.Highest,
 		Peers:   peers,
+		Sync: SyncProgress{
+			RequestsInFlight: bp.Sync.RequestsInFlight,
+			BlocksInFlight:   bp.Sync.BlocksInFlight,
+			BlocksBuffered:   bp.Sync.BlocksBuffered,
+			Timeouts:         bp.Sync.Timeouts,
+			Peers:            syncPeers,
+		},
 	}
 }
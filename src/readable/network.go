@@ -2,6 +2,8 @@ package readable
 
 import (
 	"github.com/skycoin/skycoin/src/daemon"
+	"github.com/skycoin/skycoin/src/daemon/gnet"
+	"github.com/skycoin/skycoin/src/daemon/pex"
 	"github.com/skycoin/skycoin/src/params"
 	"github.com/skycoin/skycoin/src/util/useragent"
 )
@@ -21,6 +23,8 @@ type Connection struct {
 	UserAgent            useragent.Data         `json:"user_agent"`
 	IsTrustedPeer        bool                   `json:"is_trusted_peer"`
 	UnconfirmedVerifyTxn VerifyTxn              `json:"unconfirmed_verify_transaction"`
+	Encrypted            bool                   `json:"encrypted"`
+	Stats                NetworkStats           `json:"stats"`
 }
 
 // NewConnection copies daemon.Connection to a struct with json tags
@@ -53,6 +57,50 @@ func NewConnection(c *daemon.Connection) Connection {
 		UserAgent:            c.UserAgent,
 		IsTrustedPeer:        c.Pex.Trusted,
 		UnconfirmedVerifyTxn: NewVerifyTxn(c.UnconfirmedVerifyTxn),
+		Encrypted:            c.Encrypted,
+		Stats:                NewNetworkStats(c.Gnet.Stats),
+	}
+}
+
+// TrafficStats counts the messages and bytes sent and received
+type TrafficStats struct {
+	MessagesSent     uint64 `json:"messages_sent"`
+	BytesSent        uint64 `json:"bytes_sent"`
+	MessagesReceived uint64 `json:"messages_received"`
+	BytesReceived    uint64 `json:"bytes_received"`
+}
+
+// NewTrafficStats copies gnet.TrafficStats to a struct with json tags
+func NewTrafficStats(s gnet.TrafficStats) TrafficStats {
+	return TrafficStats{
+		MessagesSent:     s.MessagesSent,
+		BytesSent:        s.BytesSent,
+		MessagesReceived: s.MessagesReceived,
+		BytesReceived:    s.BytesReceived,
+	}
+}
+
+// NetworkStats is the traffic of a connection or of all connections, with a breakdown by message prefix
+type NetworkStats struct {
+	TrafficStats
+	// Bytes per second, averaged over the last minute
+	SendRate    float64                 `json:"send_rate"`
+	ReceiveRate float64                 `json:"receive_rate"`
+	Messages    map[string]TrafficStats `json:"messages"`
+}
+
+// NewNetworkStats copies gnet.NetworkStats to a struct with json tags
+func NewNetworkStats(s gnet.NetworkStats) NetworkStats {
+	messages := make(map[string]TrafficStats, len(s.Messages))
+	for prefix, m := range s.Messages {
+		messages[prefix] = NewTrafficStats(m)
+	}
+
+	return NetworkStats{
+		TrafficStats: NewTrafficStats(s.TrafficStats),
+		SendRate:     s.SendRate,
+		ReceiveRate:  s.ReceiveRate,
+		Messages:     messages,
 	}
 }
 
@@ -71,3 +119,30 @@ func NewVerifyTxn(p params.VerifyTxn) VerifyTxn {
 		MaxDropletPrecision: p.MaxDropletPrecision,
 	}
 }
+
+// Ban is a timed ban of an IP address
+type Ban struct {
+	IP      string `json:"ip"`
+	Reason  string `json:"reason"`
+	Created int64  `json:"created"`
+	Expires int64  `json:"expires"`
+}
+
+// NewBan copies pex.Ban to a struct with json tags
+func NewBan(b pex.Ban) Ban {
+	return Ban{
+		IP:      b.IP,
+		Reason:  b.Reason,
+		Created: b.Created.Unix(),
+		Expires: b.Expires.Unix(),
+	}
+}
+
+// NewBans copies []pex.Ban to []Ban
+func NewBans(bans []pex.Ban) []Ban {
+	rbans := make([]Ban, len(bans))
+	for i, b := range bans {
+		rbans[i] = NewBan(b)
+	}
+	return rbans
+}
@@ -0,0 +1,52 @@
+package readable
+
+import (
+	"strconv"
+
+	"github.com/skycoin/skycoin/src/util/droplet"
+	"github.com/skycoin/skycoin/src/visor"
+)
+
+// StatsSeries is the time series of a chain statistic
+type StatsSeries struct {
+	Metric   string       `json:"metric"`
+	Interval string       `json:"interval"`
+	Points   []StatsPoint `json:"points"`
+}
+
+// StatsPoint is a point of a chain statistic time series
+type StatsPoint struct {
+	// Time is the time of the block, or the start of the UTC day for daily points
+	Time uint64 `json:"time"`
+	// Seq is the seq of the block, or of the last block of the day for daily points
+	Seq uint64 `json:"seq"`
+	// Value is the value of the metric. The coin supply is formatted as a decimal coin amount, the other metrics as integers.
+	Value string `json:"value"`
+}
+
+// NewStatsSeries creates a StatsSeries from visor.StatsPoints
+func NewStatsSeries(metric visor.StatsMetric, interval visor.StatsInterval, points []visor.StatsPoint) (*StatsSeries, error) {
+	rPoints := make([]StatsPoint, len(points))
+	for i, p := range points {
+		value := strconv.FormatUint(p.Value, 10)
+		if metric == visor.StatsMetricCoinSupply {
+			var err error
+			value, err = droplet.ToString(p.Value)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		rPoints[i] = StatsPoint{
+			Time:  p.Time,
+			Seq:   p.Seq,
+			Value: value,
+		}
+	}
+
+	return &StatsSeries{
+		Metric:   string(metric),
+		Interval: string(interval),
+		Points:   rPoints,
+	}, nil
+}
@@ -5,6 +5,7 @@ import (
 	"flag"
 	"fmt"
 	"math"
+	"net"
 	"os"
 	"path/filepath"
 	"runtime"
@@ -15,10 +16,12 @@ import (
 
 	"github.com/skycoin/skycoin/src/api"
 	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/daemon"
 	"github.com/skycoin/skycoin/src/params"
 	"github.com/skycoin/skycoin/src/readable"
 	"github.com/skycoin/skycoin/src/util/droplet"
 	"github.com/skycoin/skycoin/src/util/file"
+	"github.com/skycoin/skycoin/src/util/iputil"
 	"github.com/skycoin/skycoin/src/util/useragent"
 	"github.com/skycoin/skycoin/src/wallet"
 )
@@ -91,6 +94,39 @@ type NodeConfig struct {
 	MaxIncomingMessageLength int
 	// PeerlistSize represents the maximum number of peers that the pex would maintain
 	PeerlistSize int
+	// Misbehaviour score at which a peer is banned
+	BanScoreThreshold int
+	// How long a misbehaving peer is banned for
+	BanDuration time.Duration
+	// How often a peer's misbehaviour score decreases by one point
+	BanScoreDecayInterval time.Duration
+	// Encrypt connections with peers that support connection encryption
+	EncryptConnections bool
+	// Connect to peers that don't support connection encryption when EncryptConnections is enabled
+	AllowUnencryptedPeers bool
+	// Don't relay or accept new blocks as compact blocks
+	DisableCompactBlocks bool
+	// Broadcast transactions created by this node to all peers instead of relaying them through one outgoing peer
+	DisableTxnStemRelay bool
+	// Mean random delay before announcing transactions received from peers
+	TxnAnnounceDelay time.Duration
+	// Max number of transaction and block hashes remembered per peer, to avoid announcing them back
+	MaxPeerInventory int
+	// Max outgoing connections to peers in the same IPv4 /16 or IPv6 /32 network
+	MaxOutgoingPerNetGroup int
+	// Max outgoing connections to peers in the same autonomous system, according to ASMapFile
+	MaxOutgoingPerASN int
+	// File that maps IP ranges to autonomous systems, with one "cidr name" entry per line
+	ASMapFile string
+	// How often to make a feeler connection to check that a peer we have not connected to is reachable
+	FeelerRate time.Duration
+	// SOCKS5 proxy to make outgoing peer connections and download the peers list through, host:port
+	Proxy string
+	// Authenticate each proxied connection with random credentials, for stream isolation
+	ProxyIsolateAuth bool
+	// Comma separated networks to make outgoing peer connections to, ipv4 and/or ipv6
+	OnlyNet string
+	onlyNet []string
 	// Wallet Address Version
 	// AddressVersion string
 	// Remote web interface
@@ -117,6 +153,13 @@ type NodeConfig struct {
 	// If true, print the configured client web interface address and exit
 	PrintWebInterfaceAddress bool
 
+	// gRPC server for backend integrations. It uses the web interface certificate and key for TLS
+	GRPC bool
+	// gRPC server port
+	GRPCPort int
+	// gRPC server address
+	GRPCAddr string
+
 	// Data directory holds app data -- defaults to ~/.skycoin
 	DataDirectory string
 	// GUI directory contains assets for the HTML interface
@@ -126,6 +169,8 @@ type NodeConfig struct {
 	HTTPReadTimeout  time.Duration
 	HTTPWriteTimeout time.Duration
 	HTTPIdleTimeout  time.Duration
+	// Number of block, transaction and uxout API responses to cache. 0 disables the cache
+	HTTPResponseCacheSize int
 
 	// Remark to include in user agent sent in the wire protocol introduction
 	UserAgentRemark string
@@ -142,6 +187,10 @@ type NodeConfig struct {
 	VerifyDB bool
 	// Reset the database if integrity checks fail, and continue running
 	ResetCorruptDB bool
+	// Copy the database file before applying schema migrations
+	DBMigrationBackup bool
+	// Block file to import at startup, before connecting to peers
+	ImportBlocksFile string
 
 	// Transaction verification parameters for unconfirmed transactions
 	UnconfirmedVerifyTxn params.VerifyTxn
@@ -184,10 +233,16 @@ type NodeConfig struct {
 
 	DBPath      string
 	DBReadOnly  bool
+	DBInMemory  bool
 	Arbitrating bool
 	LogToFile   bool
 	Version     bool // show node version
 
+	// Discard the bodies of old blocks and the history indexes
+	Prune bool
+	// Number of most recent block bodies kept in pruned mode
+	PruneKeepBlocks uint64
+
 	GenesisSignatureStr string
 	GenesisAddressStr   string
 	BlockchainPubkeyStr string
@@ -250,6 +305,24 @@ func NewNodeConfig(mode string, node NodeParameters) NodeConfig {
 		MaxOutgoingMessageLength: 256 * 1024,
 		MaxIncomingMessageLength: 1024 * 1024,
 		PeerlistSize:             65535,
+		BanScoreThreshold:        100,
+		BanDuration:              time.Hour * 24,
+		BanScoreDecayInterval:    time.Minute,
+		EncryptConnections:       false,
+		AllowUnencryptedPeers:    false,
+		DisableCompactBlocks:     false,
+		DisableTxnStemRelay:      false,
+		TxnAnnounceDelay:         time.Second * 2,
+		MaxPeerInventory:         5000,
+		MaxOutgoingPerNetGroup:   1,
+		MaxOutgoingPerASN:        2,
+		ASMapFile:                "",
+		FeelerRate:               time.Minute * 2,
+		Prune:                    false,
+		PruneKeepBlocks:          1000,
+		Proxy:                    "",
+		ProxyIsolateAuth:         false,
+		OnlyNet:                  "",
 		// Wallet Address Version
 		// AddressVersion: "test",
 		// Remote web interface
@@ -259,6 +332,9 @@ func NewNodeConfig(mode string, node NodeParameters) NodeConfig {
 		WebInterfaceCert:  "",
 		WebInterfaceKey:   "",
 		WebInterfaceHTTPS: false,
+		GRPC:              false,
+		GRPCPort:          6421,
+		GRPCAddr:          "127.0.0.1",
 		EnabledAPISets:    strings.Join([]string{api.EndpointsRead, api.EndpointsTransaction}, ","),
 		DisabledAPISets:   "",
 		EnableAllAPISets:  false,
@@ -274,8 +350,9 @@ func NewNodeConfig(mode string, node NodeParameters) NodeConfig {
 		LogToFile:       false,
 		DisablePingPong: false,
 
-		VerifyDB:       false,
-		ResetCorruptDB: false,
+		VerifyDB:          false,
+		ResetCorruptDB:    false,
+		DBMigrationBackup: true,
 
 		// Blockchain/transaction validation
 		UnconfirmedVerifyTxn:     params.UserVerifyTxn,
@@ -292,6 +369,8 @@ func NewNodeConfig(mode string, node NodeParameters) NodeConfig {
 		HTTPWriteTimeout: time.Second * 60,
 		HTTPIdleTimeout:  time.Second * 120,
 
+		HTTPResponseCacheSize: 1000,
+
 		RunBlockPublisher: false,
 
 		// Enable cpu profiling
@@ -413,6 +492,21 @@ func (c *Config) postProcess() error {
 		c.Node.hostWhitelist = strings.Split(c.Node.HostWhitelist, ",")
 	}
 
+	if c.Node.Proxy != "" {
+		if _, _, err := net.SplitHostPort(c.Node.Proxy); err != nil {
+			return fmt.Errorf("Invalid -proxy %q: %v", c.Node.Proxy, err)
+		}
+	}
+
+	if c.Node.OnlyNet != "" {
+		c.Node.onlyNet = strings.Split(c.Node.OnlyNet, ",")
+		for _, n := range c.Node.onlyNet {
+			if err := iputil.ValidateNetwork(n); err != nil {
+				return fmt.Errorf("Invalid -onlynet %q: %v", n, err)
+			}
+		}
+	}
+
 	httpAuthEnabled := c.Node.WebInterfaceUsername != "" || c.Node.WebInterfacePassword != ""
 	if httpAuthEnabled && !c.Node.WebInterfaceHTTPS && !c.Node.WebInterfacePlaintextAuth {
 		return errors.New("Web interface auth enabled but HTTPS is not enabled. Use -web-interface-plaintext-auth=true if this is desired")
@@ -426,6 +520,31 @@ func (c *Config) postProcess() error {
 		return errors.New("-max-outgoing-connections cannot be higher than -max-connections")
 	}
 
+	if c.Node.BanScoreThreshold <= 0 {
+		return errors.New("-ban-score-threshold must be > 0")
+	}
+
+	if c.Node.BanDuration <= 0 {
+		return errors.New("-ban-duration must be > 0")
+	}
+
+	if c.Node.DBInMemory && c.Node.DBReadOnly {
+		return errors.New("-db-read-only can not be used with -db-in-memory")
+	}
+
+	// A corrupt in-memory database can not be moved aside and recreated from a file
+	if c.Node.DBInMemory && c.Node.ResetCorruptDB {
+		return errors.New("-reset-corrupt-db can not be used with -db-in-memory")
+	}
+
+	if c.Node.ImportBlocksFile != "" && c.Node.DBReadOnly {
+		return errors.New("-import-blocks can not be used with -db-read-only")
+	}
+
+	if c.Node.Prune && c.Node.PruneKeepBlocks < daemon.PrunedPeerKeepBlocks {
+		return fmt.Errorf("-prune-keep-blocks must be >= %d", daemon.PrunedPeerKeepBlocks)
+	}
+
 	if c.Node.maxBlockSize > math.MaxUint32 {
 		return errors.New("-max-block-size exceeds MaxUint32")
 	}
@@ -535,6 +654,7 @@ func buildAPISets(c NodeConfig) (map[string]struct{}, error) {
 		api.EndpointsTransaction,
 		api.EndpointsPrometheus,
 		api.EndpointsNetCtrl,
+		api.EndpointsDBAdmin,
 		// Do not include insecure or deprecated API sets, they must always
 		// be explicitly enabled through -enable-api-sets
 	}
@@ -568,7 +688,8 @@ func validateAPISets(opt string, apiSets []string) error {
 			api.EndpointsWallet,
 			api.EndpointsInsecureWalletSeed,
 			api.EndpointsPrometheus,
-			api.EndpointsNetCtrl:
+			api.EndpointsNetCtrl,
+			api.EndpointsDBAdmin:
 		case "":
 			continue
 		default:
@@ -591,6 +712,7 @@ func (c *NodeConfig) RegisterFlags() {
 	flag.BoolVar(&c.DisableCSRF, "disable-csrf", c.DisableCSRF, "disable CSRF check")
 	flag.BoolVar(&c.DisableHeaderCheck, "disable-header-check", c.DisableHeaderCheck, "disables the host, origin and referer header checks.")
 	flag.BoolVar(&c.DisableCSP, "disable-csp", c.DisableCSP, "disable content-security-policy in http response")
+	flag.IntVar(&c.HTTPResponseCacheSize, "http-cache-size", c.HTTPResponseCacheSize, "number of block, transaction and uxout API responses to cache. 0 disables the cache")
 	flag.StringVar(&c.Address, "address", c.Address, "IP Address to run application on. Leave empty to default to a public interface")
 	flag.IntVar(&c.Port, "port", c.Port, "Port to run application on")
 
@@ -609,6 +731,7 @@ func (c *NodeConfig) RegisterFlags() {
 		api.EndpointsTransaction,
 		api.EndpointsPrometheus,
 		api.EndpointsNetCtrl,
+		api.EndpointsDBAdmin,
 		api.EndpointsInsecureWalletSeed,
 	}
 	flag.StringVar(&c.EnabledAPISets, "enable-api-sets", c.EnabledAPISets, fmt.Sprintf("enable API set. Options are %s. Multiple values should be separated by comma", strings.Join(allAPISets, ", ")))
@@ -620,10 +743,18 @@ func (c *NodeConfig) RegisterFlags() {
 	flag.BoolVar(&c.WebInterfacePlaintextAuth, "web-interface-plaintext-auth", c.WebInterfacePlaintextAuth, "allow web interface auth without https")
 
 	flag.BoolVar(&c.LaunchBrowser, "launch-browser", c.LaunchBrowser, "launch system default webbrowser at client startup")
+
+	flag.BoolVar(&c.GRPC, "grpc", c.GRPC, "enable the gRPC server. It uses -web-interface-cert and -web-interface-key for TLS")
+	flag.IntVar(&c.GRPCPort, "grpc-port", c.GRPCPort, "port to serve gRPC on")
+	flag.StringVar(&c.GRPCAddr, "grpc-addr", c.GRPCAddr, "addr to serve gRPC on")
+
 	flag.BoolVar(&c.PrintWebInterfaceAddress, "print-web-interface-address", c.PrintWebInterfaceAddress, "print configured web interface address and exit")
 	flag.StringVar(&c.DataDirectory, "data-dir", c.DataDirectory, "directory to store app data (defaults to ~/.skycoin)")
 	flag.StringVar(&c.DBPath, "db-path", c.DBPath, "path of database file (defaults to ~/.skycoin/data.db)")
 	flag.BoolVar(&c.DBReadOnly, "db-read-only", c.DBReadOnly, "open bolt db read-only")
+	flag.BoolVar(&c.DBInMemory, "db-in-memory", c.DBInMemory, "keep the database in memory instead of the db file. The blockchain is downloaded again every time the node starts")
+	flag.BoolVar(&c.Prune, "prune", c.Prune, "Run as a pruned node, which discards the bodies of old blocks and does not keep the transaction history. The database can not be used by a full node after pruning")
+	flag.Uint64Var(&c.PruneKeepBlocks, "prune-keep-blocks", c.PruneKeepBlocks, fmt.Sprintf("Number of most recent block bodies kept by a pruned node. Must be >= %d", daemon.PrunedPeerKeepBlocks))
 	flag.BoolVar(&c.ProfileCPU, "profile-cpu", c.ProfileCPU, "enable cpu profiling")
 	flag.StringVar(&c.ProfileCPUFile, "profile-cpu-file", c.ProfileCPUFile, "where to write the cpu profile file")
 	flag.BoolVar(&c.HTTPProf, "http-prof", c.HTTPProf, "run the HTTP profiling interface")
@@ -636,6 +767,8 @@ func (c *NodeConfig) RegisterFlags() {
 
 	flag.BoolVar(&c.VerifyDB, "verify-db", c.VerifyDB, "check the database for corruption")
 	flag.BoolVar(&c.ResetCorruptDB, "reset-corrupt-db", c.ResetCorruptDB, "reset the database if corrupted, and continue running instead of exiting")
+	flag.BoolVar(&c.DBMigrationBackup, "db-migration-backup", c.DBMigrationBackup, "copy the database file before applying schema migrations")
+	flag.StringVar(&c.ImportBlocksFile, "import-blocks", c.ImportBlocksFile, "import a block file created with the CLI exportBlocks command at startup, before connecting to peers. Blocks that are already in the blockchain are skipped")
 
 	flag.BoolVar(&c.DisableDefaultPeers, "disable-default-peers", c.DisableDefaultPeers, "disable the hardcoded default peers")
 	flag.StringVar(&c.CustomPeersFile, "custom-peers-file", c.CustomPeersFile, "load custom peers from a newline separate list of ip:port in a file. Note that this is different from the peers.json file in the data directory")
@@ -663,6 +796,22 @@ func (c *NodeConfig) RegisterFlags() {
 	flag.IntVar(&c.MaxOutgoingConnections, "max-outgoing-connections", c.MaxOutgoingConnections, "Maximum number of outgoing connections allowed")
 	flag.IntVar(&c.MaxDefaultPeerOutgoingConnections, "max-default-peer-outgoing-connections", c.MaxDefaultPeerOutgoingConnections, "The maximum default peer outgoing connections allowed")
 	flag.IntVar(&c.PeerlistSize, "peerlist-size", c.PeerlistSize, "Max number of peers to track in peerlist")
+	flag.IntVar(&c.BanScoreThreshold, "ban-score-threshold", c.BanScoreThreshold, "Misbehaviour score at which a peer is banned")
+	flag.DurationVar(&c.BanDuration, "ban-duration", c.BanDuration, "How long a misbehaving peer is banned for")
+	flag.DurationVar(&c.BanScoreDecayInterval, "ban-score-decay-interval", c.BanScoreDecayInterval, "How often a peer's misbehaviour score decreases by one point")
+	flag.BoolVar(&c.EncryptConnections, "encrypt-connections", c.EncryptConnections, "Encrypt peer connections. Peers that don't support encryption are disconnected, unless -allow-unencrypted-peers is set")
+	flag.BoolVar(&c.AllowUnencryptedPeers, "allow-unencrypted-peers", c.AllowUnencryptedPeers, "Allow unencrypted connections to peers that don't support encryption when -encrypt-connections is set")
+	flag.BoolVar(&c.DisableCompactBlocks, "disable-compact-blocks", c.DisableCompactBlocks, "Relay new blocks in full instead of as compact blocks with short transaction IDs")
+	flag.BoolVar(&c.DisableTxnStemRelay, "disable-txn-stem-relay", c.DisableTxnStemRelay, "Broadcast transactions created by this node to all peers, instead of relaying them through a single outgoing peer first")
+	flag.StringVar(&c.Proxy, "proxy", c.Proxy, "Connect to peers and download the peers list through this SOCKS5 proxy, host:port")
+	flag.BoolVar(&c.ProxyIsolateAuth, "proxy-isolate-auth", c.ProxyIsolateAuth, "Authenticate each connection through -proxy with random credentials, so that proxies like Tor use a separate circuit for each connection")
+	flag.StringVar(&c.OnlyNet, "onlynet", c.OnlyNet, "Only make outgoing connections to peers in these comma separated networks, ipv4 and/or ipv6")
+	flag.IntVar(&c.MaxOutgoingPerNetGroup, "max-outgoing-per-netgroup", c.MaxOutgoingPerNetGroup, "Maximum number of outgoing connections to peers in the same IPv4 /16 or IPv6 /32 network. Set to 0 to disable")
+	flag.IntVar(&c.MaxOutgoingPerASN, "max-outgoing-per-asn", c.MaxOutgoingPerASN, "Maximum number of outgoing connections to peers in the same autonomous system, according to -asmap. Set to 0 to disable")
+	flag.StringVar(&c.ASMapFile, "asmap", c.ASMapFile, "load a map of IP ranges to autonomous systems from a file with one \"cidr name\" entry per line, to diversify outgoing connections by autonomous system")
+	flag.DurationVar(&c.FeelerRate, "feeler-rate", c.FeelerRate, "How often to make a short-lived connection to a peer we have not connected to, to check that it is reachable. Set to 0 to disable")
+	flag.IntVar(&c.MaxPeerInventory, "max-peer-inventory", c.MaxPeerInventory, "Number of transaction and block hashes remembered for each peer, so that they are not announced to peers that already have them. Set to 0 to disable")
+	flag.DurationVar(&c.TxnAnnounceDelay, "txn-announce-delay", c.TxnAnnounceDelay, "Mean random delay before announcing transactions received from peers, drawn independently for each peer. Set to 0 to announce immediately")
 	flag.DurationVar(&c.OutgoingConnectionsRate, "connection-rate", c.OutgoingConnectionsRate, "How often to make an outgoing connection")
 	flag.IntVar(&c.MaxOutgoingMessageLength, "max-out-msg-len", c.MaxOutgoingMessageLength, "Maximum length of outgoing wire messages")
 	flag.IntVar(&c.MaxIncomingMessageLength, "max-in-msg-len", c.MaxIncomingMessageLength, "Maximum length of incoming wire messages")
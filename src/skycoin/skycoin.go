@@ -23,6 +23,7 @@ import (
 	"github.com/skycoin/skycoin/src/cipher"
 	"github.com/skycoin/skycoin/src/coin"
 	"github.com/skycoin/skycoin/src/daemon"
+	"github.com/skycoin/skycoin/src/grpc"
 	"github.com/skycoin/skycoin/src/params"
 	"github.com/skycoin/skycoin/src/readable"
 	"github.com/skycoin/skycoin/src/util/apputil"
@@ -55,6 +56,7 @@ func (c *Coin) Run() error {
 	var d *daemon.Daemon
 	var gw *api.Gateway
 	var webInterface *api.Server
+	var grpcServer *grpc.Server
 	var retErr error
 	errC := make(chan error, 10)
 
@@ -143,11 +145,16 @@ func (c *Coin) Run() error {
 	vconf := c.ConfigureVisor()
 
 	// Open the database
-	c.logger.Infof("Opening database %s", c.config.Node.DBPath)
-	db, err = visor.OpenDB(c.config.Node.DBPath, c.config.Node.DBReadOnly)
-	if err != nil {
-		c.logger.Errorf("Database failed to open: %v. Is another skycoin instance running?", err)
-		return err
+	if c.config.Node.DBInMemory {
+		c.logger.Info("Opening in-memory database, it is discarded when the node stops")
+		db = dbutil.NewMemoryDB()
+	} else {
+		c.logger.Infof("Opening database %s", c.config.Node.DBPath)
+		db, err = visor.OpenDB(c.config.Node.DBPath, c.config.Node.DBReadOnly)
+		if err != nil {
+			c.logger.Errorf("Database failed to open: %v. Is another skycoin instance running?", err)
+			return err
+		}
 	}
 
 	// Look for saved app version
@@ -201,6 +208,34 @@ func (c *Coin) Run() error {
 		}
 	}
 
+	// Apply the pending schema migrations
+	if db.IsReadOnly() {
+		if pending, err := visor.PendingMigrations(db); err != nil {
+			c.logger.WithError(err).Error("visor.PendingMigrations failed")
+			retErr = err
+			goto earlyShutdown
+		} else if len(pending) != 0 {
+			c.logger.Warningf("The read-only database has %d pending schema migrations", len(pending))
+		}
+	} else {
+		result, err := visor.Migrate(db, visor.MigrateOptions{
+			Backup: c.config.Node.DBMigrationBackup,
+			Progress: func(m visor.Migration, msg string) {
+				c.logger.Infof("DB migration %d %s: %s", m.Version, m.Name, msg)
+			},
+		})
+		if err != nil {
+			c.logger.WithError(err).Error("visor.Migrate failed")
+			retErr = err
+			goto earlyShutdown
+		}
+
+		if result.BackupPath != "" {
+			c.logger.Infof("Copied the database to %s before migrating it", result.BackupPath)
+		}
+		c.logger.Infof("DB schema version: %d", result.To)
+	}
+
 	// Update the DB version
 	if !db.IsReadOnly() {
 		if err := visor.SetDBVersion(db, *appVersion); err != nil {
@@ -252,12 +287,31 @@ func (c *Coin) Run() error {
 		}
 	}
 
+	if c.config.Node.GRPC {
+		grpcServer, err = c.createGRPC(gw)
+		if err != nil {
+			c.logger.Error(err)
+			retErr = err
+			goto earlyShutdown
+		}
+	}
+
 	if err := v.Init(); err != nil {
 		c.logger.Error(err)
 		retErr = err
 		goto earlyShutdown
 	}
 
+	if c.config.Node.ImportBlocksFile != "" {
+		if err := c.importBlocks(v, quit); err != nil {
+			if err != visor.ErrImportBlocksStopped {
+				c.logger.WithError(err).Error("Import blocks failed")
+				retErr = err
+			}
+			goto earlyShutdown
+		}
+	}
+
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
@@ -299,6 +353,18 @@ func (c *Coin) Run() error {
 		}
 	}
 
+	if c.config.Node.GRPC {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			if err := grpcServer.Serve(); err != nil {
+				c.logger.Error(err)
+				errC <- err
+			}
+		}()
+	}
+
 	select {
 	case <-quit:
 	case retErr = <-errC:
@@ -312,6 +378,11 @@ func (c *Coin) Run() error {
 		webInterface.Shutdown()
 	}
 
+	if grpcServer != nil {
+		c.logger.Info("Closing gRPC server")
+		grpcServer.Shutdown()
+	}
+
 	c.logger.Info("Closing daemon")
 	d.Shutdown()
 
@@ -368,6 +439,28 @@ func (c *Coin) initLogFile() (*os.File, error) {
 	return f, nil
 }
 
+// importBlocks imports the -import-blocks block file before the daemon connects to peers
+func (c *Coin) importBlocks(v *visor.Visor, quit <-chan struct{}) error {
+	c.logger.Infof("Importing block file %s", c.config.Node.ImportBlocksFile)
+
+	f, err := os.Open(c.config.Node.ImportBlocksFile)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	result, err := v.ImportBlocks(f, quit, func(r visor.ImportBlocksResult) {
+		c.logger.Infof("Imported %d blocks, head block %d, %.1f blocks/s", r.Imported, r.HeadSeq, r.BlocksPerSecond())
+	})
+	if err != nil {
+		return err
+	}
+
+	c.logger.Infof("Imported %d blocks and skipped %d blocks in %s, head block %d, %.1f blocks/s",
+		result.Imported, result.Skipped, result.Elapsed, result.HeadSeq, result.BlocksPerSecond())
+	return nil
+}
+
 // ConfigureVisor sets the visor config values
 func (c *Coin) ConfigureVisor() visor.Config {
 	vc := visor.NewConfig()
@@ -386,6 +479,8 @@ func (c *Coin) ConfigureVisor() visor.Config {
 	vc.GenesisTimestamp = c.config.Node.GenesisTimestamp
 	vc.GenesisCoinVolume = c.config.Node.GenesisCoinVolume
 	vc.Arbitrating = c.config.Node.Arbitrating
+	vc.Prune = c.config.Node.Prune
+	vc.PruneKeepBlocks = c.config.Node.PruneKeepBlocks
 
 	return vc
 }
@@ -427,6 +522,7 @@ func (c *Coin) ConfigureDaemon() daemon.Config {
 	dc.Pex.DisableTrustedPeers = c.config.Node.DisableDefaultPeers
 	dc.Pex.CustomPeersFile = c.config.Node.CustomPeersFile
 	dc.Pex.DefaultConnections = c.config.Node.DefaultConnections
+	dc.Pex.ASMapFile = c.config.Node.ASMapFile
 
 	dc.Daemon.MaxOutgoingMessageLength = uint64(c.config.Node.MaxOutgoingMessageLength)
 	dc.Daemon.MaxIncomingMessageLength = uint64(c.config.Node.MaxIncomingMessageLength)
@@ -440,6 +536,22 @@ func (c *Coin) ConfigureDaemon() daemon.Config {
 	dc.Daemon.LocalhostOnly = c.config.Node.LocalhostOnly
 	dc.Daemon.MaxConnections = c.config.Node.MaxConnections
 	dc.Daemon.MaxOutgoingConnections = c.config.Node.MaxOutgoingConnections
+	dc.Daemon.BanScoreThreshold = c.config.Node.BanScoreThreshold
+	dc.Daemon.BanDuration = c.config.Node.BanDuration
+	dc.Daemon.BanScoreDecayInterval = c.config.Node.BanScoreDecayInterval
+	dc.Daemon.EncryptConnections = c.config.Node.EncryptConnections
+	dc.Daemon.AllowUnencryptedPeers = c.config.Node.AllowUnencryptedPeers
+	dc.Daemon.DisableCompactBlocks = c.config.Node.DisableCompactBlocks
+	dc.Daemon.DisableTxnStemRelay = c.config.Node.DisableTxnStemRelay
+	dc.Daemon.TxnAnnounceDelay = c.config.Node.TxnAnnounceDelay
+	dc.Daemon.MaxPeerInventory = c.config.Node.MaxPeerInventory
+	dc.Daemon.MaxOutgoingPerNetGroup = c.config.Node.MaxOutgoingPerNetGroup
+	dc.Daemon.MaxOutgoingPerASN = c.config.Node.MaxOutgoingPerASN
+	dc.Daemon.FeelerRate = c.config.Node.FeelerRate
+	dc.Daemon.Pruned = c.config.Node.Prune
+	dc.Daemon.Proxy = c.config.Node.Proxy
+	dc.Daemon.ProxyIsolateAuth = c.config.Node.ProxyIsolateAuth
+	dc.Daemon.OnlyNetworks = c.config.Node.onlyNet
 	dc.Daemon.DataDirectory = c.config.Node.DataDirectory
 	dc.Daemon.LogPings = !c.config.Node.DisablePingPong
 	dc.Daemon.BlockchainPubkey = c.config.Node.blockchainPubkey
@@ -475,30 +587,18 @@ func (c *Coin) createGUI(gw *api.Gateway, host string) (*api.Server, error) {
 			CoinName:        c.config.Node.CoinName,
 			DaemonUserAgent: c.config.Node.userAgent,
 		},
-		Username: c.config.Node.WebInterfaceUsername,
-		Password: c.config.Node.WebInterfacePassword,
+		Username:          c.config.Node.WebInterfaceUsername,
+		Password:          c.config.Node.WebInterfacePassword,
+		ResponseCacheSize: c.config.Node.HTTPResponseCacheSize,
 	}
 
 	var s *api.Server
 	if c.config.Node.WebInterfaceHTTPS {
-		// Verify cert/key parameters, and if neither exist, create them
-		exists, err := checkCertFiles(c.config.Node.WebInterfaceCert, c.config.Node.WebInterfaceKey)
-		if err != nil {
-			c.logger.Errorf("checkCertFiles failed: %v", err)
+		if err := c.ensureCertFiles(); err != nil {
 			return nil, err
 		}
 
-		if !exists {
-			c.logger.Infof("Autogenerating HTTP certificate and key files %s, %s", c.config.Node.WebInterfaceCert, c.config.Node.WebInterfaceKey)
-			if err := createCertFiles(c.config.Node.WebInterfaceCert, c.config.Node.WebInterfaceKey); err != nil {
-				c.logger.Errorf("createCertFiles failed: %v", err)
-				return nil, err
-			}
-
-			c.logger.Infof("Created cert file %s", c.config.Node.WebInterfaceCert)
-			c.logger.Infof("Created key file %s", c.config.Node.WebInterfaceKey)
-		}
-
+		var err error
 		s, err = api.CreateHTTPS(host, config, gw, c.config.Node.WebInterfaceCert, c.config.Node.WebInterfaceKey)
 		if err != nil {
 			c.logger.Errorf("Failed to start web GUI: %v", err)
@@ -516,6 +616,43 @@ func (c *Coin) createGUI(gw *api.Gateway, host string) (*api.Server, error) {
 	return s, nil
 }
 
+func (c *Coin) createGRPC(gw *api.Gateway) (*grpc.Server, error) {
+	if err := c.ensureCertFiles(); err != nil {
+		return nil, err
+	}
+
+	host := fmt.Sprintf("%s:%d", c.config.Node.GRPCAddr, c.config.Node.GRPCPort)
+	s, err := grpc.Create(host, grpc.Config{}, gw, c.config.Node.WebInterfaceCert, c.config.Node.WebInterfaceKey)
+	if err != nil {
+		c.logger.Errorf("Failed to start gRPC server: %v", err)
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// ensureCertFiles verifies the web interface cert/key parameters, and if neither exist, creates them
+func (c *Coin) ensureCertFiles() error {
+	exists, err := checkCertFiles(c.config.Node.WebInterfaceCert, c.config.Node.WebInterfaceKey)
+	if err != nil {
+		c.logger.Errorf("checkCertFiles failed: %v", err)
+		return err
+	}
+
+	if !exists {
+		c.logger.Infof("Autogenerating HTTP certificate and key files %s, %s", c.config.Node.WebInterfaceCert, c.config.Node.WebInterfaceKey)
+		if err := createCertFiles(c.config.Node.WebInterfaceCert, c.config.Node.WebInterfaceKey); err != nil {
+			c.logger.Errorf("createCertFiles failed: %v", err)
+			return err
+		}
+
+		c.logger.Infof("Created cert file %s", c.config.Node.WebInterfaceCert)
+		c.logger.Infof("Created key file %s", c.config.Node.WebInterfaceKey)
+	}
+
+	return nil
+}
+
 // checkCertFiles returns true if both cert and key files exist, false if neither exist,
 // or returns an error if only one does not exist
 func checkCertFiles(cert, key string) (bool, error) {
@@ -0,0 +1,269 @@
+package testutil
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// SOCKS5Request is a CONNECT request handled by a SOCKS5Server
+type SOCKS5Request struct {
+	Username string
+	Password string
+	// Requested destination, host:port. The host is an IP address or a host name
+	Addr string
+}
+
+// SOCKS5Server is an in-process SOCKS5 proxy for tests.
+// It supports the CONNECT command with no authentication or username/password authentication,
+// and records the requests it handles.
+type SOCKS5Server struct {
+	Addr string
+
+	requireAuth bool
+	username    string
+	password    string
+
+	ln       net.Listener
+	wg       sync.WaitGroup
+	mu       sync.Mutex
+	conns    map[net.Conn]struct{}
+	requests []SOCKS5Request
+}
+
+// NewSOCKS5Server starts a SOCKS5Server on a random localhost port.
+// If requireAuth is true, clients must authenticate with a username and password,
+// which must match username and password unless both are empty.
+// If requireAuth is false, clients may still authenticate with any username and password.
+func NewSOCKS5Server(t *testing.T, requireAuth bool, username, password string) *SOCKS5Server {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	s := &SOCKS5Server{
+		Addr:        ln.Addr().String(),
+		requireAuth: requireAuth,
+		username:    username,
+		password:    password,
+		ln:          ln,
+		conns:       make(map[net.Conn]struct{}),
+	}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+
+			s.mu.Lock()
+			s.conns[conn] = struct{}{}
+			s.mu.Unlock()
+
+			s.wg.Add(1)
+			go func() {
+				defer s.wg.Done()
+				defer s.closeConn(conn)
+				s.serve(conn)
+			}()
+		}
+	}()
+
+	return s
+}
+
+// Requests returns the CONNECT requests handled by the server
+func (s *SOCKS5Server) Requests() []SOCKS5Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	requests := make([]SOCKS5Request, len(s.requests))
+	copy(requests, s.requests)
+	return requests
+}
+
+// Close stops the server and closes all proxied connections
+func (s *SOCKS5Server) Close() {
+	s.ln.Close() // nolint: errcheck
+
+	s.mu.Lock()
+	for conn := range s.conns {
+		conn.Close() // nolint: errcheck
+	}
+	s.mu.Unlock()
+
+	s.wg.Wait()
+}
+
+func (s *SOCKS5Server) closeConn(conn net.Conn) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	conn.Close() // nolint: errcheck
+	delete(s.conns, conn)
+}
+
+func (s *SOCKS5Server) serve(conn net.Conn) {
+	// Method selection
+	hdr := make([]byte, 2)
+	if _, err := io.ReadFull(conn, hdr); err != nil || hdr[0] != 0x05 {
+		return
+	}
+
+	methods := make([]byte, hdr[1])
+	if _, err := io.ReadFull(conn, methods); err != nil {
+		return
+	}
+
+	offered := func(m byte) bool {
+		for _, x := range methods {
+			if x == m {
+				return true
+			}
+		}
+		return false
+	}
+
+	method := byte(0xff)
+	switch {
+	case !s.requireAuth && offered(0x00):
+		method = 0x00
+	case offered(0x02):
+		method = 0x02
+	}
+
+	if _, err := conn.Write([]byte{0x05, method}); err != nil || method == 0xff {
+		return
+	}
+
+	// Username/password authentication
+	var username, password string
+	if method == 0x02 {
+		var ok bool
+		username, password, ok = s.authenticate(conn)
+		if !ok {
+			return
+		}
+	}
+
+	// CONNECT request
+	req := make([]byte, 4)
+	if _, err := io.ReadFull(conn, req); err != nil || req[0] != 0x05 {
+		return
+	}
+
+	var host string
+	switch req[3] {
+	case 0x01, 0x04:
+		n := net.IPv4len
+		if req[3] == 0x04 {
+			n = net.IPv6len
+		}
+		ip := make([]byte, n)
+		if _, err := io.ReadFull(conn, ip); err != nil {
+			return
+		}
+		host = net.IP(ip).String()
+	case 0x03:
+		l := make([]byte, 1)
+		if _, err := io.ReadFull(conn, l); err != nil {
+			return
+		}
+		name := make([]byte, l[0])
+		if _, err := io.ReadFull(conn, name); err != nil {
+			return
+		}
+		host = string(name)
+	default:
+		conn.Write([]byte{0x05, 0x08, 0x00, 0x01, 0, 0, 0, 0, 0, 0}) // nolint: errcheck
+		return
+	}
+
+	port := make([]byte, 2)
+	if _, err := io.ReadFull(conn, port); err != nil {
+		return
+	}
+
+	addr := net.JoinHostPort(host, strconv.Itoa(int(binary.BigEndian.Uint16(port))))
+
+	s.mu.Lock()
+	s.requests = append(s.requests, SOCKS5Request{
+		Username: username,
+		Password: password,
+		Addr:     addr,
+	})
+	s.mu.Unlock()
+
+	if req[1] != 0x01 {
+		conn.Write([]byte{0x05, 0x07, 0x00, 0x01, 0, 0, 0, 0, 0, 0}) // nolint: errcheck
+		return
+	}
+
+	target, err := net.DialTimeout("tcp", addr, time.Second*5)
+	if err != nil {
+		conn.Write([]byte{0x05, 0x05, 0x00, 0x01, 0, 0, 0, 0, 0, 0}) // nolint: errcheck
+		return
+	}
+
+	s.mu.Lock()
+	s.conns[target] = struct{}{}
+	s.mu.Unlock()
+	defer s.closeConn(target)
+
+	if _, err := conn.Write([]byte{0x05, 0x00, 0x00, 0x01, 0, 0, 0, 0, 0, 0}); err != nil {
+		return
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		io.Copy(target, conn) // nolint: errcheck
+		target.Close()        // nolint: errcheck
+	}()
+
+	io.Copy(conn, target) // nolint: errcheck
+	conn.Close()          // nolint: errcheck
+	<-done
+}
+
+func (s *SOCKS5Server) authenticate(conn net.Conn) (string, string, bool) {
+	hdr := make([]byte, 2)
+	if _, err := io.ReadFull(conn, hdr); err != nil || hdr[0] != 0x01 {
+		return "", "", false
+	}
+
+	username := make([]byte, hdr[1])
+	if _, err := io.ReadFull(conn, username); err != nil {
+		return "", "", false
+	}
+
+	l := make([]byte, 1)
+	if _, err := io.ReadFull(conn, l); err != nil {
+		return "", "", false
+	}
+
+	password := make([]byte, l[0])
+	if _, err := io.ReadFull(conn, password); err != nil {
+		return "", "", false
+	}
+
+	ok := s.username == "" && s.password == "" || string(username) == s.username && string(password) == s.password
+
+	status := byte(0x00)
+	if !ok {
+		status = 0x01
+	}
+
+	if _, err := conn.Write([]byte{0x01, status}); err != nil {
+		return "", "", false
+	}
+
+	return string(username), string(password), ok
+}
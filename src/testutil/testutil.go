@@ -16,8 +16,34 @@ import (
 	"github.com/skycoin/skycoin/src/visor/dbutil"
 )
 
-// PrepareDB creates and opens a temporary test DB and returns it with a cleanup callback
+// PrepareDB creates and opens a temporary test DB and returns it with a cleanup callback.
+// The DB is a boltdb file, unless the SKYCOIN_TEST_DB environment variable is set to "memory",
+// which runs the tests against the in-memory storage backend instead.
 func PrepareDB(t *testing.T) (*dbutil.DB, func()) {
+	switch os.Getenv("SKYCOIN_TEST_DB") {
+	case "", "bolt":
+		return PrepareBoltDB(t)
+	case "memory":
+		return PrepareMemoryDB(t)
+	default:
+		t.Fatalf("Invalid SKYCOIN_TEST_DB %q, must be bolt or memory", os.Getenv("SKYCOIN_TEST_DB"))
+		return nil, nil
+	}
+}
+
+// PrepareMemoryDB creates an in-memory test DB and returns it with a cleanup callback
+func PrepareMemoryDB(t *testing.T) (*dbutil.DB, func()) {
+	db := dbutil.NewMemoryDB()
+
+	return db, func() {
+		if err := db.Close(); err != nil {
+			t.Logf("Failed to close database: %v", err)
+		}
+	}
+}
+
+// PrepareBoltDB creates and opens a temporary test boltdb file and returns it with a cleanup callback
+func PrepareBoltDB(t *testing.T) (*dbutil.DB, func()) {
 	f, err := ioutil.TempFile("", "testdb")
 	require.NoError(t, err)
 
@@ -16,6 +16,15 @@ var (
 	ErrInvalidPort = errors.New("Port invalid in ip:port address")
 	// ErrNoLocalIP no localhost IP found in system net interfaces
 	ErrNoLocalIP = errors.New("No local IP found")
+	// ErrInvalidNetwork network name is not ipv4 or ipv6
+	ErrInvalidNetwork = errors.New("Invalid network, must be ipv4 or ipv6")
+)
+
+const (
+	// NetworkIPv4 is the name of the IPv4 network
+	NetworkIPv4 = "ipv4"
+	// NetworkIPv6 is the name of the IPv6 network
+	NetworkIPv6 = "ipv6"
 )
 
 // LocalhostIP returns the address for localhost on the machine
@@ -64,3 +73,75 @@ func SplitAddr(addr string) (string, uint16, error) {
 
 	return ip, uint16(port64), nil
 }
+
+// JoinAddr joins an ip and port to an ip:port string.
+// IPv6 addresses are enclosed in brackets, e.g. [::1]:6000.
+func JoinAddr(ip string, port uint16) string {
+	return net.JoinHostPort(ip, strconv.FormatUint(uint64(port), 10))
+}
+
+// IPNetwork returns the network of an IP address, NetworkIPv4 or NetworkIPv6.
+// Returns an empty string if ip is not an IP address.
+func IPNetwork(ip string) string {
+	parsed := net.ParseIP(ip)
+	switch {
+	case parsed == nil:
+		return ""
+	case parsed.To4() != nil:
+		return NetworkIPv4
+	default:
+		return NetworkIPv6
+	}
+}
+
+// ValidateNetwork returns an error if network is not NetworkIPv4 or NetworkIPv6
+func ValidateNetwork(network string) error {
+	switch network {
+	case NetworkIPv4, NetworkIPv6:
+		return nil
+	default:
+		return ErrInvalidNetwork
+	}
+}
+
+// NetGroup returns the network group of an IP address, which is the IPv4 /16 or the IPv6 /32 prefix
+// that contains it, e.g. "85.56.0.0/16".
+// Peers in the same network group are likely to be operated by the same party.
+// Returns an empty string if ip is not an IP address, or if it is a loopback, private or otherwise
+// unroutable address, which does not belong to any network group.
+func NetGroup(ip string) string {
+	parsed := net.ParseIP(ip)
+	if parsed == nil || !parsed.IsGlobalUnicast() || isPrivateIP(parsed) {
+		return ""
+	}
+
+	if ip4 := parsed.To4(); ip4 != nil {
+		n := net.IPNet{IP: ip4.Mask(net.CIDRMask(16, 32)), Mask: net.CIDRMask(16, 32)}
+		return n.String()
+	}
+
+	n := net.IPNet{IP: parsed.Mask(net.CIDRMask(32, 128)), Mask: net.CIDRMask(32, 128)}
+	return n.String()
+}
+
+// privateNetworks are the IPv4 and IPv6 private address ranges (RFC 1918 and RFC 4193)
+var privateNetworks = func() []*net.IPNet {
+	var networks []*net.IPNet
+	for _, cidr := range []string{"10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16", "fc00::/7"} {
+		_, n, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks = append(networks, n)
+	}
+	return networks
+}()
+
+func isPrivateIP(ip net.IP) bool {
+	for _, n := range privateNetworks {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -121,3 +121,142 @@ func TestSplitAddr(t *testing.T) {
 		})
 	}
 }
+
+func TestJoinAddr(t *testing.T) {
+	testData := []struct {
+		ip   string
+		port uint16
+		addr string
+	}{
+		{
+			ip:   "127.0.0.1",
+			port: 6000,
+			addr: "127.0.0.1:6000",
+		},
+		{
+			ip:   "::1",
+			port: 6000,
+			addr: "[::1]:6000",
+		},
+		{
+			ip:   "2001:db8:85a3::8a2e:370:7334",
+			port: 1234,
+			addr: "[2001:db8:85a3::8a2e:370:7334]:1234",
+		},
+	}
+
+	for _, tc := range testData {
+		t.Run(tc.addr, func(t *testing.T) {
+			addr := JoinAddr(tc.ip, tc.port)
+			require.Equal(t, tc.addr, addr)
+
+			ip, port, err := SplitAddr(addr)
+			require.NoError(t, err)
+			require.Equal(t, tc.ip, ip)
+			require.Equal(t, tc.port, port)
+		})
+	}
+}
+
+func TestIPNetwork(t *testing.T) {
+	testData := []struct {
+		ip       string
+		expected string
+	}{
+		{
+			ip:       "127.0.0.1",
+			expected: NetworkIPv4,
+		},
+		{
+			ip:       "::ffff:85.56.12.34",
+			expected: NetworkIPv4,
+		},
+		{
+			ip:       "::1",
+			expected: NetworkIPv6,
+		},
+		{
+			ip:       "2001:db8::1",
+			expected: NetworkIPv6,
+		},
+		{
+			ip:       "localhost",
+			expected: "",
+		},
+		{
+			ip:       "",
+			expected: "",
+		},
+	}
+
+	for _, tc := range testData {
+		t.Run(tc.ip, func(t *testing.T) {
+			require.Equal(t, tc.expected, IPNetwork(tc.ip))
+		})
+	}
+}
+
+func TestValidateNetwork(t *testing.T) {
+	require.NoError(t, ValidateNetwork(NetworkIPv4))
+	require.NoError(t, ValidateNetwork(NetworkIPv6))
+	require.Equal(t, ErrInvalidNetwork, ValidateNetwork("onion"))
+	require.Equal(t, ErrInvalidNetwork, ValidateNetwork(""))
+}
+
+func TestNetGroup(t *testing.T) {
+	testData := []struct {
+		ip       string
+		expected string
+	}{
+		{
+			ip:       "85.56.12.34",
+			expected: "85.56.0.0/16",
+		},
+		{
+			ip:       "85.56.255.1",
+			expected: "85.56.0.0/16",
+		},
+		{
+			ip:       "::ffff:85.56.12.34",
+			expected: "85.56.0.0/16",
+		},
+		{
+			ip:       "2001:db8:1234::1",
+			expected: "2001:db8::/32",
+		},
+		{
+			ip:       "127.0.0.1",
+			expected: "",
+		},
+		{
+			ip:       "::1",
+			expected: "",
+		},
+		{
+			ip:       "192.168.1.1",
+			expected: "",
+		},
+		{
+			ip:       "10.1.2.3",
+			expected: "",
+		},
+		{
+			ip:       "fd00::1",
+			expected: "",
+		},
+		{
+			ip:       "0.0.0.0",
+			expected: "",
+		},
+		{
+			ip:       "localhost",
+			expected: "",
+		},
+	}
+
+	for _, tc := range testData {
+		t.Run(tc.ip, func(t *testing.T) {
+			require.Equal(t, tc.expected, NetGroup(tc.ip))
+		})
+	}
+}
@@ -0,0 +1,301 @@
+/*
+Package socks5 implements a SOCKS5 client (RFC 1928) for making TCP connections through a proxy,
+with optional username/password authentication (RFC 1929)
+*/
+package socks5
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"time"
+)
+
+const (
+	version5 = 0x05
+
+	authNone             = 0x00
+	authUsernamePassword = 0x02
+	authNoAcceptable     = 0xff
+
+	authUsernamePasswordVersion = 0x01
+	authSuccess                 = 0x00
+
+	cmdConnect = 0x01
+
+	atypIPv4   = 0x01
+	atypDomain = 0x03
+	atypIPv6   = 0x04
+
+	replySucceeded = 0x00
+
+	// isolationCredentialLength is the number of random bytes in the credentials used for auth isolation
+	isolationCredentialLength = 16
+)
+
+var (
+	// ErrUnsupportedNetwork the network is not a TCP network
+	ErrUnsupportedNetwork = errors.New("socks5: only tcp networks are supported")
+	// ErrInvalidVersion the proxy responded with a version other than SOCKS5
+	ErrInvalidVersion = errors.New("socks5: proxy responded with an invalid version")
+	// ErrNoAcceptableAuthMethod the proxy does not accept any of the offered authentication methods
+	ErrNoAcceptableAuthMethod = errors.New("socks5: proxy does not accept the offered authentication methods")
+	// ErrAuthFailed the proxy rejected the username and password
+	ErrAuthFailed = errors.New("socks5: proxy rejected the username and password")
+	// ErrCredentialsTooLong the username or password is longer than 255 bytes
+	ErrCredentialsTooLong = errors.New("socks5: username and password must be at most 255 bytes")
+	// ErrHostTooLong the destination host name is longer than 255 bytes
+	ErrHostTooLong = errors.New("socks5: host name must be at most 255 bytes")
+	// ErrInvalidAddressType the proxy responded with an unknown address type
+	ErrInvalidAddressType = errors.New("socks5: proxy responded with an invalid address type")
+)
+
+// ReplyError is the failure reply code of a CONNECT request
+type ReplyError byte
+
+func (e ReplyError) Error() string {
+	switch e {
+	case 0x01:
+		return "socks5: general SOCKS server failure"
+	case 0x02:
+		return "socks5: connection not allowed by ruleset"
+	case 0x03:
+		return "socks5: network unreachable"
+	case 0x04:
+		return "socks5: host unreachable"
+	case 0x05:
+		return "socks5: connection refused"
+	case 0x06:
+		return "socks5: TTL expired"
+	case 0x07:
+		return "socks5: command not supported"
+	case 0x08:
+		return "socks5: address type not supported"
+	default:
+		return fmt.Sprintf("socks5: unknown reply code %d", byte(e))
+	}
+}
+
+// Dialer makes TCP connections through a SOCKS5 proxy.
+// Destination host names are resolved by the proxy.
+type Dialer struct {
+	// Address of the proxy, host:port
+	ProxyAddr string
+	// Username and password to authenticate with. If empty, no authentication is used, unless IsolateAuth is set
+	Username string
+	Password string
+	// Authenticate each connection with random credentials. Proxies that isolate streams
+	// by credentials, such as Tor, then route each connection over a separate circuit
+	IsolateAuth bool
+	// Timeout for connecting to the proxy and completing the handshake. Zero means no timeout
+	Timeout time.Duration
+}
+
+// Dial connects to addr through the proxy
+func (d *Dialer) Dial(network, addr string) (net.Conn, error) {
+	return d.DialContext(context.Background(), network, addr)
+}
+
+// DialContext connects to addr through the proxy using the provided context
+func (d *Dialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	switch network {
+	case "tcp", "tcp4", "tcp6":
+	default:
+		return nil, ErrUnsupportedNetwork
+	}
+
+	if d.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, d.Timeout)
+		defer cancel()
+	}
+
+	var nd net.Dialer
+	conn, err := nd.DialContext(ctx, "tcp", d.ProxyAddr)
+	if err != nil {
+		return nil, err
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := conn.SetDeadline(deadline); err != nil {
+			conn.Close() // nolint: errcheck
+			return nil, err
+		}
+	}
+
+	if err := d.handshake(conn, addr); err != nil {
+		conn.Close() // nolint: errcheck
+		return nil, err
+	}
+
+	if err := conn.SetDeadline(time.Time{}); err != nil {
+		conn.Close() // nolint: errcheck
+		return nil, err
+	}
+
+	return conn, nil
+}
+
+// handshake authenticates with the proxy and requests a connection to addr
+func (d *Dialer) handshake(conn net.Conn, addr string) error {
+	req, err := connectRequest(addr)
+	if err != nil {
+		return err
+	}
+
+	username, password := d.Username, d.Password
+	if d.IsolateAuth {
+		username, password, err = randomCredentials()
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(username) > 255 || len(password) > 255 {
+		return ErrCredentialsTooLong
+	}
+
+	// Only offer username/password authentication if there are credentials, so that
+	// the proxy can't skip authentication and lose the stream isolation
+	method := byte(authNone)
+	if username != "" || password != "" {
+		method = authUsernamePassword
+	}
+
+	if _, err := conn.Write([]byte{version5, 1, method}); err != nil {
+		return err
+	}
+
+	resp := make([]byte, 2)
+	if _, err := io.ReadFull(conn, resp); err != nil {
+		return err
+	}
+
+	if resp[0] != version5 {
+		return ErrInvalidVersion
+	}
+
+	if resp[1] != method {
+		return ErrNoAcceptableAuthMethod
+	}
+
+	if method == authUsernamePassword {
+		if err := authenticate(conn, username, password); err != nil {
+			return err
+		}
+	}
+
+	if _, err := conn.Write(req); err != nil {
+		return err
+	}
+
+	return readConnectReply(conn)
+}
+
+// authenticate performs username/password authentication (RFC 1929)
+func authenticate(conn net.Conn, username, password string) error {
+	req := make([]byte, 0, 3+len(username)+len(password))
+	req = append(req, authUsernamePasswordVersion, byte(len(username)))
+	req = append(req, username...)
+	req = append(req, byte(len(password)))
+	req = append(req, password...)
+
+	if _, err := conn.Write(req); err != nil {
+		return err
+	}
+
+	resp := make([]byte, 2)
+	if _, err := io.ReadFull(conn, resp); err != nil {
+		return err
+	}
+
+	if resp[1] != authSuccess {
+		return ErrAuthFailed
+	}
+
+	return nil
+}
+
+// connectRequest returns a CONNECT request for a host:port address
+func connectRequest(addr string) ([]byte, error) {
+	host, portStr, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	port, err := strconv.ParseUint(portStr, 10, 16)
+	if err != nil {
+		return nil, fmt.Errorf("socks5: invalid port %q", portStr)
+	}
+
+	req := []byte{version5, cmdConnect, 0}
+
+	if ip := net.ParseIP(host); ip != nil {
+		if ip4 := ip.To4(); ip4 != nil {
+			req = append(req, atypIPv4)
+			req = append(req, ip4...)
+		} else {
+			req = append(req, atypIPv6)
+			req = append(req, ip.To16()...)
+		}
+	} else {
+		if len(host) > 255 {
+			return nil, ErrHostTooLong
+		}
+		req = append(req, atypDomain, byte(len(host)))
+		req = append(req, host...)
+	}
+
+	return append(req, byte(port>>8), byte(port)), nil
+}
+
+// readConnectReply reads the reply to a CONNECT request, including the bound address
+func readConnectReply(conn net.Conn) error {
+	resp := make([]byte, 4)
+	if _, err := io.ReadFull(conn, resp); err != nil {
+		return err
+	}
+
+	if resp[0] != version5 {
+		return ErrInvalidVersion
+	}
+
+	if resp[1] != replySucceeded {
+		return ReplyError(resp[1])
+	}
+
+	var n int
+	switch resp[3] {
+	case atypIPv4:
+		n = net.IPv4len
+	case atypIPv6:
+		n = net.IPv6len
+	case atypDomain:
+		l := make([]byte, 1)
+		if _, err := io.ReadFull(conn, l); err != nil {
+			return err
+		}
+		n = int(l[0])
+	default:
+		return ErrInvalidAddressType
+	}
+
+	// The bound address and port are not used
+	_, err := io.ReadFull(conn, make([]byte, n+2))
+	return err
+}
+
+// randomCredentials returns a random username and password
+func randomCredentials() (string, string, error) {
+	b := make([]byte, isolationCredentialLength*2)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+
+	return hex.EncodeToString(b[:isolationCredentialLength]), hex.EncodeToString(b[isolationCredentialLength:]), nil
+}
@@ -0,0 +1,259 @@
+package socks5
+
+import (
+	"bufio"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/testutil"
+)
+
+// startEchoServer starts a TCP server that echoes lines back to the client
+func startEchoServer(t *testing.T) (string, func()) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				r := bufio.NewReader(conn)
+				for {
+					line, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if _, err := conn.Write([]byte(line)); err != nil {
+						return
+					}
+				}
+			}()
+		}
+	}()
+
+	return ln.Addr().String(), func() {
+		ln.Close() // nolint: errcheck
+	}
+}
+
+func requireEcho(t *testing.T, conn net.Conn) {
+	_, err := conn.Write([]byte("hello\n"))
+	require.NoError(t, err)
+
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	require.NoError(t, err)
+	require.Equal(t, "hello\n", line)
+}
+
+func TestDialer(t *testing.T) {
+	echoAddr, closeEcho := startEchoServer(t)
+	defer closeEcho()
+
+	_, echoPort, err := net.SplitHostPort(echoAddr)
+	require.NoError(t, err)
+
+	cases := []struct {
+		name        string
+		requireAuth bool
+		username    string
+		password    string
+		dialer      Dialer
+		addr        string
+		request     testutil.SOCKS5Request
+		err         error
+	}{
+		{
+			name: "no auth ipv4",
+			addr: echoAddr,
+			request: testutil.SOCKS5Request{
+				Addr: echoAddr,
+			},
+		},
+		{
+			name: "no auth host name",
+			addr: net.JoinHostPort("localhost", echoPort),
+			request: testutil.SOCKS5Request{
+				Addr: net.JoinHostPort("localhost", echoPort),
+			},
+		},
+		{
+			name:        "username and password",
+			requireAuth: true,
+			username:    "user",
+			password:    "pass",
+			dialer: Dialer{
+				Username: "user",
+				Password: "pass",
+			},
+			addr: echoAddr,
+			request: testutil.SOCKS5Request{
+				Username: "user",
+				Password: "pass",
+				Addr:     echoAddr,
+			},
+		},
+		{
+			name:        "wrong password",
+			requireAuth: true,
+			username:    "user",
+			password:    "pass",
+			dialer: Dialer{
+				Username: "user",
+				Password: "wrong",
+			},
+			addr: echoAddr,
+			err:  ErrAuthFailed,
+		},
+		{
+			name:        "proxy requires auth",
+			requireAuth: true,
+			addr:        echoAddr,
+			err:         ErrNoAcceptableAuthMethod,
+		},
+		{
+			name: "connection refused",
+			addr: "127.0.0.1:1",
+			request: testutil.SOCKS5Request{
+				Addr: "127.0.0.1:1",
+			},
+			err: ReplyError(0x05),
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := testutil.NewSOCKS5Server(t, tc.requireAuth, tc.username, tc.password)
+			defer s.Close()
+
+			d := tc.dialer
+			d.ProxyAddr = s.Addr
+			d.Timeout = time.Second * 5
+
+			conn, err := d.Dial("tcp", tc.addr)
+			require.Equal(t, tc.err, err)
+
+			if tc.request.Addr != "" {
+				require.Equal(t, []testutil.SOCKS5Request{tc.request}, s.Requests())
+			} else {
+				require.Empty(t, s.Requests())
+			}
+
+			if err != nil {
+				return
+			}
+
+			defer conn.Close()
+			requireEcho(t, conn)
+		})
+	}
+}
+
+func TestDialerIsolateAuth(t *testing.T) {
+	echoAddr, closeEcho := startEchoServer(t)
+	defer closeEcho()
+
+	s := testutil.NewSOCKS5Server(t, true, "", "")
+	defer s.Close()
+
+	d := Dialer{
+		ProxyAddr:   s.Addr,
+		IsolateAuth: true,
+	}
+
+	for i := 0; i < 2; i++ {
+		conn, err := d.Dial("tcp", echoAddr)
+		require.NoError(t, err)
+		requireEcho(t, conn)
+		conn.Close() // nolint: errcheck
+	}
+
+	// Each connection uses its own credentials
+	requests := s.Requests()
+	require.Len(t, requests, 2)
+	for _, r := range requests {
+		require.Len(t, r.Username, isolationCredentialLength*2)
+		require.Len(t, r.Password, isolationCredentialLength*2)
+	}
+	require.NotEqual(t, requests[0].Username, requests[1].Username)
+	require.NotEqual(t, requests[0].Password, requests[1].Password)
+}
+
+func TestDialerUnsupportedNetwork(t *testing.T) {
+	d := Dialer{
+		ProxyAddr: "127.0.0.1:1",
+	}
+
+	_, err := d.Dial("udp", "127.0.0.1:6000")
+	require.Equal(t, ErrUnsupportedNetwork, err)
+}
+
+func TestDialerProxyUnreachable(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := ln.Addr().String()
+	require.NoError(t, ln.Close())
+
+	d := Dialer{
+		ProxyAddr: addr,
+	}
+
+	_, err = d.Dial("tcp", "127.0.0.1:6000")
+	require.Error(t, err)
+}
+
+func TestConnectRequest(t *testing.T) {
+	cases := []struct {
+		name string
+		addr string
+		req  []byte
+		err  error
+	}{
+		{
+			name: "ipv4",
+			addr: "1.2.3.4:6000",
+			req:  []byte{5, 1, 0, 1, 1, 2, 3, 4, 0x17, 0x70},
+		},
+		{
+			name: "ipv6",
+			addr: "[2001:db8::1]:6000",
+			req:  []byte{5, 1, 0, 4, 0x20, 0x01, 0x0d, 0xb8, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1, 0x17, 0x70},
+		},
+		{
+			name: "host name",
+			addr: "example.com:443",
+			req:  append(append([]byte{5, 1, 0, 3, 11}, "example.com"...), 0x01, 0xbb),
+		},
+		{
+			name: "host name too long",
+			addr: net.JoinHostPort(string(make([]byte, 256)), "443"),
+			err:  ErrHostTooLong,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req, err := connectRequest(tc.addr)
+			require.Equal(t, tc.err, err)
+			require.Equal(t, tc.req, req)
+		})
+	}
+
+	_, err := connectRequest("1.2.3.4")
+	require.Error(t, err)
+
+	_, err = connectRequest("1.2.3.4:" + strconv.Itoa(70000))
+	require.Error(t, err)
+}
+
+func TestReplyError(t *testing.T) {
+	require.Equal(t, "socks5: connection refused", ReplyError(0x05).Error())
+	require.Equal(t, "socks5: unknown reply code 99", ReplyError(99).Error())
+}
@@ -0,0 +1,373 @@
+package visor
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/util/mathutil"
+	"github.com/skycoin/skycoin/src/util/timeutil"
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+	"github.com/skycoin/skycoin/src/wallet"
+)
+
+// TxnDirection describes how a transaction moved value relative to an address
+type TxnDirection string
+
+const (
+	// TxnDirectionIn the address received coins but did not spend any
+	TxnDirectionIn TxnDirection = "in"
+	// TxnDirectionOut the address spent coins, some of which were sent to other addresses
+	TxnDirectionOut TxnDirection = "out"
+	// TxnDirectionSelf the address spent coins and all outputs were sent back to it
+	TxnDirectionSelf TxnDirection = "self"
+)
+
+// AddressHistory is the transaction history of an address, ordered with the most recent transaction first.
+// Unconfirmed transactions are placed before all confirmed transactions.
+type AddressHistory struct {
+	Address cipher.Address
+	Entries []AddressHistoryEntry
+}
+
+// AddressHistoryEntry is a transaction that changed the balance of an address
+type AddressHistoryEntry struct {
+	Transaction Transaction
+	Direction   TxnDirection
+	// Coins and hours of the address's outputs created by the transaction
+	Received wallet.Balance
+	// Coins and hours of the address's outputs spent by the transaction.
+	// Spent hours are calculated at the time of the previous block, like TransactionInput.CalculatedHours
+	Spent wallet.Balance
+	// Balance of the address after the transaction was applied.
+	// Hours are calculated at the time of the transaction's block, or the head block time if unconfirmed
+	Balance wallet.Balance
+}
+
+// GetAddressesHistory returns the transaction history of each address, with running balances
+func (vs *Visor) GetAddressesHistory(addrs []cipher.Address) ([]AddressHistory, error) {
+	var histories []AddressHistory
+
+	if err := vs.db.View("GetAddressesHistory", func(tx *dbutil.Tx) error {
+		// Unconfirmed transactions can only spend outputs from the unspent pool,
+		// so they are applied on top of the confirmed outputs in the order they were received
+		uTxns, err := vs.unconfirmed.GetFiltered(tx, All)
+		if err != nil {
+			return err
+		}
+
+		sort.Slice(uTxns, func(i, j int) bool {
+			return uTxns[i].Received < uTxns[j].Received
+		})
+
+		histories = make([]AddressHistory, len(addrs))
+		for i, a := range addrs {
+			entries, err := vs.getAddressHistory(tx, a, uTxns)
+			if err != nil {
+				return err
+			}
+
+			histories[i] = AddressHistory{
+				Address: a,
+				Entries: entries,
+			}
+		}
+
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return histories, nil
+}
+
+// getAddressHistory returns the history entries of an address, most recent first.
+// uTxns are the unconfirmed transactions, in the order they were received.
+func (vs *Visor) getAddressHistory(tx *dbutil.Tx, addr cipher.Address, uTxns []UnconfirmedTransaction) ([]AddressHistoryEntry, error) {
+	head, err := vs.blockchain.Head(tx)
+	if err != nil {
+		return nil, err
+	}
+	headSeq := head.Seq()
+
+	hTxns, err := vs.history.GetTransactionsForAddress(tx, addr)
+	if err != nil {
+		return nil, err
+	}
+
+	blocks := make(map[uint64]*coin.SignedBlock)
+	getBlock := func(seq uint64) (*coin.SignedBlock, error) {
+		if b, ok := blocks[seq]; ok {
+			return b, nil
+		}
+
+		b, err := vs.blockchain.GetSignedBlockBySeq(tx, seq)
+		if err != nil {
+			return nil, err
+		}
+		if b == nil {
+			return nil, fmt.Errorf("block seq=%d doesn't exist", seq)
+		}
+
+		blocks[seq] = b
+		return b, nil
+	}
+
+	// A transaction can spend the outputs of a transaction before it in the same block,
+	// so the transactions are replayed in the order of the blocks and of their index in the block
+	txnIndexes := make(map[cipher.SHA256]int, len(hTxns))
+	for _, hTxn := range hTxns {
+		if headSeq < hTxn.BlockSeq {
+			return nil, errors.New("Transaction block sequence is greater than the head block sequence")
+		}
+
+		b, err := getBlock(hTxn.BlockSeq)
+		if err != nil {
+			return nil, err
+		}
+
+		txnHash := hTxn.Hash()
+		idx := -1
+		for i, txn := range b.Body.Transactions {
+			if txn.Hash() == txnHash {
+				idx = i
+				break
+			}
+		}
+		if idx == -1 {
+			return nil, fmt.Errorf("transaction %s is not in block seq=%d", txnHash.Hex(), hTxn.BlockSeq)
+		}
+		txnIndexes[txnHash] = idx
+	}
+
+	sort.Slice(hTxns, func(i, j int) bool {
+		if hTxns[i].BlockSeq == hTxns[j].BlockSeq {
+			return txnIndexes[hTxns[i].Hash()] < txnIndexes[hTxns[j].Hash()]
+		}
+		return hTxns[i].BlockSeq < hTxns[j].BlockSeq
+	})
+
+	owned := newAddressHistoryOutputs()
+	entries := make([]AddressHistoryEntry, 0, len(hTxns))
+
+	for _, hTxn := range hTxns {
+		b, err := getBlock(hTxn.BlockSeq)
+		if err != nil {
+			return nil, err
+		}
+
+		// Spent hours are calculated against the previous block time,
+		// the genesis block has no previous block but also has no inputs
+		spentHoursTime := b.Time()
+		if hTxn.BlockSeq > 0 {
+			prevBlock, err := getBlock(hTxn.BlockSeq - 1)
+			if err != nil {
+				return nil, err
+			}
+			spentHoursTime = prevBlock.Time()
+		}
+
+		txn := Transaction{
+			Transaction: hTxn.Txn,
+			Status:      NewConfirmedTransactionStatus(headSeq-hTxn.BlockSeq+1, hTxn.BlockSeq),
+			Time:        b.Time(),
+		}
+
+		entry, ok, err := applyAddressHistoryTxn(addr, owned, txn, coin.CreateUnspents(b.Head, hTxn.Txn), spentHoursTime, b.Time())
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			entries = append(entries, entry)
+		}
+	}
+
+	for _, uTxn := range uTxns {
+		txn := Transaction{
+			Transaction: uTxn.Transaction,
+			Status:      NewUnconfirmedTransactionStatus(),
+			Time:        uint64(timeutil.NanoToTime(uTxn.Received).Unix()),
+		}
+
+		entry, ok, err := applyAddressHistoryTxn(addr, owned, txn, coin.CreateUnspents(head.Head, uTxn.Transaction), head.Time(), head.Time())
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			entries = append(entries, entry)
+		}
+	}
+
+	// Most recent first
+	for i, j := 0, len(entries)-1; i < j; i, j = i+1, j-1 {
+		entries[i], entries[j] = entries[j], entries[i]
+	}
+
+	return entries, nil
+}
+
+// addressHistoryOutputs are the outputs of an address which are unspent at the current point of the replay,
+// with their running coin total and their running hour total at hoursTime
+type addressHistoryOutputs struct {
+	uxs       map[cipher.SHA256]coin.UxOut
+	coins     uint64
+	hours     uint64
+	hoursTime uint64
+}
+
+func newAddressHistoryOutputs() *addressHistoryOutputs {
+	return &addressHistoryOutputs{
+		uxs: make(map[cipher.SHA256]coin.UxOut),
+	}
+}
+
+// addressHistoryUxHours returns the hours of an output at time t. Outputs whose hours overflow count as 0 hours.
+func addressHistoryUxHours(ux coin.UxOut, t uint64) (uint64, error) {
+	hours, err := ux.CoinHours(t)
+	if err != nil {
+		if err != coin.ErrAddEarnedCoinHoursAdditionOverflow {
+			return 0, err
+		}
+		return 0, nil
+	}
+	return hours, nil
+}
+
+// add adds an output to the set
+func (o *addressHistoryOutputs) add(ux coin.UxOut) error {
+	coins, err := mathutil.AddUint64(o.coins, ux.Body.Coins)
+	if err != nil {
+		return err
+	}
+
+	hours, err := addressHistoryUxHours(ux, o.hoursTime)
+	if err != nil {
+		return err
+	}
+
+	o.uxs[ux.Hash()] = ux
+	o.coins = coins
+	o.hours, err = mathutil.AddUint64(o.hours, hours)
+	return err
+}
+
+// remove removes an output from the set
+func (o *addressHistoryOutputs) remove(ux coin.UxOut) error {
+	hours, err := addressHistoryUxHours(ux, o.hoursTime)
+	if err != nil {
+		return err
+	}
+
+	delete(o.uxs, ux.Hash())
+	o.coins -= ux.Body.Coins
+	o.hours -= hours
+	return nil
+}
+
+// balance returns the coins and the hours of the set at time t.
+// The hours of every output change with time, so they are only recalculated when t differs from the time of the last balance.
+func (o *addressHistoryOutputs) balance(t uint64) (wallet.Balance, error) {
+	if t != o.hoursTime {
+		var hours uint64
+		for _, ux := range o.uxs {
+			uxHours, err := addressHistoryUxHours(ux, t)
+			if err != nil {
+				return wallet.Balance{}, err
+			}
+
+			if hours, err = mathutil.AddUint64(hours, uxHours); err != nil {
+				return wallet.Balance{}, err
+			}
+		}
+
+		o.hours = hours
+		o.hoursTime = t
+	}
+
+	return wallet.Balance{
+		Coins: o.coins,
+		Hours: o.hours,
+	}, nil
+}
+
+// applyAddressHistoryTxn updates the set of outputs owned by the address with a transaction,
+// returning the resulting history entry. If the transaction does not affect the address, returns false.
+func applyAddressHistoryTxn(addr cipher.Address, owned *addressHistoryOutputs, txn Transaction, outputs coin.UxArray, spentHoursTime, balanceTime uint64) (AddressHistoryEntry, bool, error) {
+	var entry AddressHistoryEntry
+
+	var spent coin.UxArray
+	for _, in := range txn.Transaction.In {
+		if ux, ok := owned.uxs[in]; ok {
+			spent = append(spent, ux)
+		}
+	}
+
+	var received coin.UxArray
+	for _, ux := range outputs {
+		if ux.Body.Address == addr {
+			received = append(received, ux)
+		}
+	}
+
+	if len(spent) == 0 && len(received) == 0 {
+		return entry, false, nil
+	}
+
+	// Bring the running hours to the balance time first, so that the outputs are added and removed at that time
+	if _, err := owned.balance(balanceTime); err != nil {
+		return entry, false, err
+	}
+
+	for _, ux := range spent {
+		hours, err := addressHistoryUxHours(ux, spentHoursTime)
+		if err != nil {
+			return entry, false, err
+		}
+
+		if entry.Spent.Coins, err = mathutil.AddUint64(entry.Spent.Coins, ux.Body.Coins); err != nil {
+			return entry, false, err
+		}
+		if entry.Spent.Hours, err = mathutil.AddUint64(entry.Spent.Hours, hours); err != nil {
+			return entry, false, err
+		}
+
+		if err := owned.remove(ux); err != nil {
+			return entry, false, err
+		}
+	}
+
+	for _, ux := range received {
+		var err error
+		if entry.Received.Coins, err = mathutil.AddUint64(entry.Received.Coins, ux.Body.Coins); err != nil {
+			return entry, false, err
+		}
+		if entry.Received.Hours, err = mathutil.AddUint64(entry.Received.Hours, ux.Body.Hours); err != nil {
+			return entry, false, err
+		}
+
+		if err := owned.add(ux); err != nil {
+			return entry, false, err
+		}
+	}
+
+	switch {
+	case len(spent) == 0:
+		entry.Direction = TxnDirectionIn
+	case len(received) == len(txn.Transaction.Out):
+		entry.Direction = TxnDirectionSelf
+	default:
+		entry.Direction = TxnDirectionOut
+	}
+
+	balance, err := owned.balance(balanceTime)
+	if err != nil {
+		return entry, false, err
+	}
+
+	entry.Transaction = txn
+	entry.Balance = balance
+
+	return entry, true, nil
+}
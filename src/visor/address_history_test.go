@@ -0,0 +1,311 @@
+package visor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/testutil"
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+	"github.com/skycoin/skycoin/src/visor/historydb"
+	"github.com/skycoin/skycoin/src/wallet"
+)
+
+func makeHistoryTxn(t *testing.T, in []cipher.SHA256, out []coin.TransactionOutput) coin.Transaction {
+	var txn coin.Transaction
+	for _, h := range in {
+		err := txn.PushInput(h)
+		require.NoError(t, err)
+	}
+	for _, o := range out {
+		err := txn.PushOutput(o.Address, o.Coins, o.Hours)
+		require.NoError(t, err)
+	}
+	err := txn.UpdateHeader()
+	require.NoError(t, err)
+	return txn
+}
+
+func TestGetAddressesHistory(t *testing.T) {
+	addr := testutil.MakeAddress()
+	other := testutil.MakeAddress()
+
+	var t0 uint64 = 1500000000
+	t1 := t0 + 3600
+	t2 := t1 + 3600
+
+	makeBlock := func(seq, time uint64, txn coin.Transaction) coin.SignedBlock {
+		return coin.SignedBlock{
+			Block: coin.Block{
+				Head: coin.BlockHeader{
+					BkSeq: seq,
+					Time:  time,
+				},
+				Body: coin.BlockBody{
+					Transactions: coin.Transactions{txn},
+				},
+			},
+		}
+	}
+
+	// Genesis sends 100 coins to addr
+	gTxn := makeHistoryTxn(t, nil, []coin.TransactionOutput{
+		{Address: addr, Coins: 100e6, Hours: 1000},
+	})
+	b0 := makeBlock(0, t0, gTxn)
+	gUxs := coin.CreateUnspents(b0.Head, gTxn)
+
+	// addr sends 30 coins to other, with 70 coins change
+	txn1 := makeHistoryTxn(t, []cipher.SHA256{gUxs[0].Hash()}, []coin.TransactionOutput{
+		{Address: other, Coins: 30e6, Hours: 400},
+		{Address: addr, Coins: 70e6, Hours: 500},
+	})
+	b1 := makeBlock(1, t1, txn1)
+	uxs1 := coin.CreateUnspents(b1.Head, txn1)
+
+	// addr sends its 70 coins to itself
+	txn2 := makeHistoryTxn(t, []cipher.SHA256{uxs1[1].Hash()}, []coin.TransactionOutput{
+		{Address: addr, Coins: 70e6, Hours: 200},
+	})
+	b2 := makeBlock(2, t2, txn2)
+	uxs2 := coin.CreateUnspents(b2.Head, txn2)
+
+	// Unconfirmed: addr sends all of its coins to other
+	uTxn1 := makeHistoryTxn(t, []cipher.SHA256{uxs2[0].Hash()}, []coin.TransactionOutput{
+		{Address: other, Coins: 70e6, Hours: 100},
+	})
+	// Unconfirmed: other sends 5 coins to addr
+	uTxn2 := makeHistoryTxn(t, []cipher.SHA256{uxs1[0].Hash()}, []coin.TransactionOutput{
+		{Address: addr, Coins: 5e6, Hours: 10},
+		{Address: other, Coins: 25e6, Hours: 10},
+	})
+
+	matchDBTx := mock.MatchedBy(func(tx *dbutil.Tx) bool {
+		return true
+	})
+
+	his := &MockHistoryer{}
+	// Returned out of order, to check that the history is sorted by block seq
+	his.On("GetTransactionsForAddress", matchDBTx, addr).Return([]historydb.Transaction{
+		{Txn: txn2, BlockSeq: 2},
+		{Txn: gTxn, BlockSeq: 0},
+		{Txn: txn1, BlockSeq: 1},
+	}, nil)
+
+	bc := &MockBlockchainer{}
+	bc.On("Head", matchDBTx).Return(&b2, nil)
+	for _, b := range []coin.SignedBlock{b0, b1, b2} {
+		b := b
+		bc.On("GetSignedBlockBySeq", matchDBTx, b.Seq()).Return(&b, nil)
+	}
+
+	uncfmTxnPool := &MockUnconfirmedTransactionPooler{}
+	uncfmTxnPool.On("GetFiltered", matchDBTx, mock.Anything).Return([]UnconfirmedTransaction{
+		{Transaction: uTxn2, Received: int64(t2+20) * 1e9},
+		{Transaction: uTxn1, Received: int64(t2+10) * 1e9},
+	}, nil)
+
+	db, shutdown := prepareDB(t)
+	defer shutdown()
+
+	v := &Visor{
+		db:          db,
+		history:     his,
+		unconfirmed: uncfmTxnPool,
+		blockchain:  bc,
+	}
+
+	histories, err := v.GetAddressesHistory([]cipher.Address{addr})
+	require.NoError(t, err)
+	require.Len(t, histories, 1)
+	require.Equal(t, addr, histories[0].Address)
+
+	expected := []AddressHistoryEntry{
+		{
+			Transaction: Transaction{
+				Transaction: uTxn2,
+				Status:      NewUnconfirmedTransactionStatus(),
+				Time:        t2 + 20,
+			},
+			Direction: TxnDirectionIn,
+			Received:  wallet.Balance{Coins: 5e6, Hours: 10},
+			Balance:   wallet.Balance{Coins: 5e6, Hours: 10},
+		},
+		{
+			Transaction: Transaction{
+				Transaction: uTxn1,
+				Status:      NewUnconfirmedTransactionStatus(),
+				Time:        t2 + 10,
+			},
+			Direction: TxnDirectionOut,
+			Spent:     wallet.Balance{Coins: 70e6, Hours: 200},
+			Balance:   wallet.Balance{},
+		},
+		{
+			Transaction: Transaction{
+				Transaction: txn2,
+				Status:      NewConfirmedTransactionStatus(1, 2),
+				Time:        t2,
+			},
+			Direction: TxnDirectionSelf,
+			Received:  wallet.Balance{Coins: 70e6, Hours: 200},
+			Spent:     wallet.Balance{Coins: 70e6, Hours: 500},
+			Balance:   wallet.Balance{Coins: 70e6, Hours: 200},
+		},
+		{
+			Transaction: Transaction{
+				Transaction: txn1,
+				Status:      NewConfirmedTransactionStatus(2, 1),
+				Time:        t1,
+			},
+			Direction: TxnDirectionOut,
+			Received:  wallet.Balance{Coins: 70e6, Hours: 500},
+			Spent:     wallet.Balance{Coins: 100e6, Hours: 1000},
+			Balance:   wallet.Balance{Coins: 70e6, Hours: 500},
+		},
+		{
+			Transaction: Transaction{
+				Transaction: gTxn,
+				Status:      NewConfirmedTransactionStatus(3, 0),
+				Time:        t0,
+			},
+			Direction: TxnDirectionIn,
+			Received:  wallet.Balance{Coins: 100e6, Hours: 1000},
+			Balance:   wallet.Balance{Coins: 100e6, Hours: 1000},
+		},
+	}
+
+	require.Equal(t, expected, histories[0].Entries)
+}
+
+func TestGetAddressesHistorySameBlock(t *testing.T) {
+	addr := testutil.MakeAddress()
+	other := testutil.MakeAddress()
+
+	var t0 uint64 = 1500000000
+	t1 := t0 + 3600
+
+	gTxn := makeHistoryTxn(t, nil, []coin.TransactionOutput{
+		{Address: other, Coins: 100e6, Hours: 1000},
+	})
+	b0 := coin.SignedBlock{
+		Block: coin.Block{
+			Head: coin.BlockHeader{
+				BkSeq: 0,
+				Time:  t0,
+			},
+			Body: coin.BlockBody{
+				Transactions: coin.Transactions{gTxn},
+			},
+		},
+	}
+	gUxs := coin.CreateUnspents(b0.Head, gTxn)
+
+	head1 := coin.BlockHeader{
+		BkSeq: 1,
+		Time:  t1,
+	}
+
+	// other sends 100 coins to addr, and addr sends them back to other in a later transaction of the same block.
+	// The second transaction's hash sorts before the first transaction's hash, so that sorting by hash would replay them in the wrong order.
+	txn1 := makeHistoryTxn(t, []cipher.SHA256{gUxs[0].Hash()}, []coin.TransactionOutput{
+		{Address: addr, Coins: 100e6, Hours: 500},
+	})
+	uxs1 := coin.CreateUnspents(head1, txn1)
+
+	var txn2 coin.Transaction
+	for hours := uint64(1); ; hours++ {
+		txn2 = makeHistoryTxn(t, []cipher.SHA256{uxs1[0].Hash()}, []coin.TransactionOutput{
+			{Address: other, Coins: 100e6, Hours: hours},
+		})
+		if txn2.Hash().Hex() < txn1.Hash().Hex() {
+			break
+		}
+	}
+
+	b1 := coin.SignedBlock{
+		Block: coin.Block{
+			Head: head1,
+			Body: coin.BlockBody{
+				Transactions: coin.Transactions{txn1, txn2},
+			},
+		},
+	}
+
+	matchDBTx := mock.MatchedBy(func(tx *dbutil.Tx) bool {
+		return true
+	})
+
+	his := &MockHistoryer{}
+	his.On("GetTransactionsForAddress", matchDBTx, addr).Return([]historydb.Transaction{
+		{Txn: txn2, BlockSeq: 1},
+		{Txn: txn1, BlockSeq: 1},
+	}, nil)
+	his.On("GetTransactionsForAddress", matchDBTx, other).Return([]historydb.Transaction{
+		{Txn: gTxn, BlockSeq: 0},
+		{Txn: txn1, BlockSeq: 1},
+		{Txn: txn2, BlockSeq: 1},
+	}, nil)
+
+	bc := &MockBlockchainer{}
+	bc.On("Head", matchDBTx).Return(&b1, nil)
+	for _, b := range []coin.SignedBlock{b0, b1} {
+		b := b
+		bc.On("GetSignedBlockBySeq", matchDBTx, b.Seq()).Return(&b, nil)
+	}
+
+	uncfmTxnPool := &MockUnconfirmedTransactionPooler{}
+	uncfmTxnPool.On("GetFiltered", matchDBTx, mock.Anything).Return(nil, nil)
+
+	db, shutdown := prepareDB(t)
+	defer shutdown()
+
+	v := &Visor{
+		db:          db,
+		history:     his,
+		unconfirmed: uncfmTxnPool,
+		blockchain:  bc,
+	}
+
+	histories, err := v.GetAddressesHistory([]cipher.Address{addr, other})
+	require.NoError(t, err)
+	require.Len(t, histories, 2)
+
+	// The unconfirmed transactions are loaded once for all of the addresses
+	uncfmTxnPool.AssertNumberOfCalls(t, "GetFiltered", 1)
+
+	status := NewConfirmedTransactionStatus(1, 1)
+	require.Equal(t, []AddressHistoryEntry{
+		{
+			Transaction: Transaction{
+				Transaction: txn2,
+				Status:      status,
+				Time:        t1,
+			},
+			Direction: TxnDirectionOut,
+			Spent:     wallet.Balance{Coins: 100e6, Hours: 500},
+			Balance:   wallet.Balance{},
+		},
+		{
+			Transaction: Transaction{
+				Transaction: txn1,
+				Status:      status,
+				Time:        t1,
+			},
+			Direction: TxnDirectionIn,
+			Received:  wallet.Balance{Coins: 100e6, Hours: 500},
+			Balance:   wallet.Balance{Coins: 100e6, Hours: 500},
+		},
+	}, histories[0].Entries)
+
+	require.Len(t, histories[1].Entries, 3)
+	require.Equal(t, txn2, histories[1].Entries[0].Transaction.Transaction)
+	require.Equal(t, TxnDirectionIn, histories[1].Entries[0].Direction)
+	require.Equal(t, wallet.Balance{Coins: 100e6, Hours: txn2.Out[0].Hours}, histories[1].Entries[0].Balance)
+	require.Equal(t, txn1, histories[1].Entries[1].Transaction.Transaction)
+	require.Equal(t, TxnDirectionOut, histories[1].Entries[1].Direction)
+	require.Equal(t, wallet.Balance{}, histories[1].Entries[1].Balance)
+}
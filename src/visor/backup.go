@@ -0,0 +1,289 @@
+package visor
+
+import (
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/skycoin/skycoin/src/util/file"
+	"github.com/skycoin/skycoin/src/visor/blockdb"
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+)
+
+var (
+	// ErrBackupUnsupported is returned when backing up a database that has no file, such as an in-memory database
+	ErrBackupUnsupported = errors.New("the database has no file to back up")
+)
+
+// BackupManifest describes a database backup. It is saved in a sidecar file next to the backup.
+type BackupManifest struct {
+	// CreatedAt is the unix time the backup was started
+	CreatedAt int64 `json:"created_at"`
+	// HeadSeq is the seq of the head block in the backup
+	HeadSeq uint64 `json:"head_seq"`
+	// HeadHash is the hash of the head block in the backup, empty if the backup has no blocks
+	HeadHash string `json:"head_hash"`
+	// SchemaVersion is the schema version of the backup
+	SchemaVersion uint64 `json:"schema_version"`
+	// DBSize is the size of the database file, before compression
+	DBSize int64 `json:"db_size"`
+	// Compressed is true if the backup is gzip compressed
+	Compressed bool `json:"compressed"`
+	// SHA256 is the hex-encoded checksum of the backup as written, after compression
+	SHA256 string `json:"sha256"`
+}
+
+// BackupDB writes a consistent copy of the database to w. The copy is made from a read-only transaction,
+// so the node keeps running and executing blocks while it is written.
+// If compress is true, the copy is gzip compressed.
+func BackupDB(db *dbutil.DB, w io.Writer, compress bool) (*BackupManifest, error) {
+	bc, err := NewBlockchain(db, BlockchainConfig{})
+	if err != nil {
+		return nil, err
+	}
+
+	var m BackupManifest
+	if err := db.View("BackupDB", func(tx *dbutil.Tx) error {
+		ftx, ok := tx.StorageTx.(dbutil.FileTx)
+		if !ok {
+			return ErrBackupUnsupported
+		}
+
+		m, err = newBackupManifest(tx, bc)
+		if err != nil {
+			return err
+		}
+
+		m.CreatedAt = time.Now().UTC().Unix()
+		m.DBSize = ftx.Size()
+		m.Compressed = compress
+
+		h := sha256.New()
+		out := io.MultiWriter(w, h)
+
+		if compress {
+			gz := gzip.NewWriter(out)
+			if _, err := ftx.WriteTo(gz); err != nil {
+				return err
+			}
+			if err := gz.Close(); err != nil {
+				return err
+			}
+		} else if _, err := ftx.WriteTo(out); err != nil {
+			return err
+		}
+
+		m.SHA256 = hex.EncodeToString(h.Sum(nil))
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return &m, nil
+}
+
+// newBackupManifest returns a manifest with the head block and schema version of the database
+func newBackupManifest(tx *dbutil.Tx, bc *Blockchain) (BackupManifest, error) {
+	var m BackupManifest
+
+	head, err := bc.Head(tx)
+	switch err {
+	case nil:
+		m.HeadSeq = head.Seq()
+		m.HeadHash = head.HashHeader().Hex()
+	case blockdb.ErrNoHeadBlock:
+	default:
+		return BackupManifest{}, err
+	}
+
+	version, ok, err := getSchemaVersion(tx)
+	if err != nil {
+		return BackupManifest{}, err
+	}
+
+	if ok {
+		m.SchemaVersion = version
+	}
+
+	return m, nil
+}
+
+// BackupManifestPath returns the path of the manifest of a backup file
+func BackupManifestPath(backupPath string) string {
+	return backupPath + ".manifest.json"
+}
+
+// SaveBackupManifest saves the manifest of a backup file next to it
+func SaveBackupManifest(backupPath string, m BackupManifest) error {
+	return file.SaveJSON(BackupManifestPath(backupPath), m, 0600)
+}
+
+// BackupDBToFile writes a backup of the database to path with WriteBackupFile
+func BackupDBToFile(db *dbutil.DB, path string, compress bool) (*BackupManifest, error) {
+	return WriteBackupFile(path, func(w io.Writer) (*BackupManifest, error) {
+		return BackupDB(db, w, compress)
+	})
+}
+
+// BackupDBToTempFile writes a backup of the database with BackupDB to a new temporary file
+// in the directory of the database file and returns its path. The read-only transaction of the backup
+// is released once the file is written, so the backup can be sent to a slow reader without holding it open.
+// The caller removes the file.
+func BackupDBToTempFile(db *dbutil.DB, compress bool) (string, *BackupManifest, error) {
+	if db.Path() == "" {
+		return "", nil, ErrBackupUnsupported
+	}
+
+	f, err := ioutil.TempFile(filepath.Dir(db.Path()), "backup")
+	if err != nil {
+		return "", nil, err
+	}
+
+	m, err := BackupDB(db, f, compress)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return "", nil, err
+	}
+
+	return f.Name(), m, nil
+}
+
+// WriteBackupFile writes a backup to path with write and saves its manifest next to it.
+// The backup is written to a temporary file that is verified with VerifyBackup and renamed once complete,
+// so a backup that fails verification is removed. An existing file is not overwritten.
+func WriteBackupFile(path string, write func(w io.Writer) (*BackupManifest, error)) (*BackupManifest, error) {
+	if _, err := os.Stat(path); err == nil {
+		return nil, fmt.Errorf("backup file %s already exists", path)
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	tmpPath := path + ".tmp"
+	f, err := os.OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := write(f)
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = VerifyBackup(tmpPath, *m)
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return nil, err
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return nil, err
+	}
+
+	if err := SaveBackupManifest(path, *m); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// VerifyBackup checks the checksum of a backup file, and that it can be opened with OpenDB
+// and has the head block of its manifest. A compressed backup is decompressed to a temporary file to be opened.
+func VerifyBackup(path string, m BackupManifest) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return err
+	}
+
+	if sum := hex.EncodeToString(h.Sum(nil)); sum != m.SHA256 {
+		return fmt.Errorf("backup checksum %s does not match the manifest checksum %s", sum, m.SHA256)
+	}
+
+	dbPath := path
+	if m.Compressed {
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+
+		dbPath, err = decompressBackup(f, filepath.Dir(path))
+		if err != nil {
+			return err
+		}
+		defer os.Remove(dbPath)
+	}
+
+	db, err := OpenDB(dbPath, true)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	bc, err := NewBlockchain(db, BlockchainConfig{})
+	if err != nil {
+		return err
+	}
+
+	return db.View("VerifyBackup", func(tx *dbutil.Tx) error {
+		bm, err := newBackupManifest(tx, bc)
+		if err != nil {
+			return err
+		}
+
+		if bm.HeadSeq != m.HeadSeq || bm.HeadHash != m.HeadHash {
+			return fmt.Errorf("backup head block %d %s does not match the manifest head block %d %s",
+				bm.HeadSeq, bm.HeadHash, m.HeadSeq, m.HeadHash)
+		}
+
+		return nil
+	})
+}
+
+// decompressBackup decompresses a gzip compressed backup to a temporary file in dir and returns its path
+func decompressBackup(r io.Reader, dir string) (string, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return "", err
+	}
+	defer gz.Close()
+
+	out, err := ioutil.TempFile(dir, "backup-verify")
+	if err != nil {
+		return "", err
+	}
+
+	_, err = io.Copy(out, gz)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(out.Name())
+		return "", err
+	}
+
+	return out.Name(), nil
+}
+
+// BackupDBToTempFile writes a backup of the database to a temporary file, see BackupDBToTempFile
+func (vs *Visor) BackupDBToTempFile(compress bool) (string, *BackupManifest, error) {
+	return BackupDBToTempFile(vs.db, compress)
+}
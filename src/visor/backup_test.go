@@ -0,0 +1,126 @@
+package visor
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/testutil"
+	"github.com/skycoin/skycoin/src/util/file"
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+)
+
+func TestBackupDB(t *testing.T) {
+	db, shutdown := testutil.PrepareBoltDB(t)
+	defer shutdown()
+
+	err := CreateBuckets(db)
+	require.NoError(t, err)
+
+	cfg := NewConfig()
+	cfg.BlockchainPubkey = genPublic
+	cfg.GenesisAddress = genAddress
+
+	v, err := New(cfg, db, nil)
+	require.NoError(t, err)
+	gb := addGenesisBlockToVisor(t, v)
+
+	_, err = Migrate(db, MigrateOptions{})
+	require.NoError(t, err)
+
+	dir, err := ioutil.TempDir("", "backup")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	for _, compress := range []bool{false, true} {
+		path := filepath.Join(dir, "data.db")
+		if compress {
+			path += ".gz"
+		}
+
+		m, err := BackupDBToFile(db, path, compress)
+		require.NoError(t, err)
+		require.Equal(t, uint64(0), m.HeadSeq)
+		require.Equal(t, gb.HashHeader().Hex(), m.HeadHash)
+		require.Equal(t, LatestSchemaVersion(), m.SchemaVersion)
+		require.Equal(t, compress, m.Compressed)
+		require.NotEmpty(t, m.SHA256)
+		require.NotEqual(t, int64(0), m.DBSize)
+
+		// The temporary file was renamed
+		_, err = os.Stat(path + ".tmp")
+		require.True(t, os.IsNotExist(err))
+
+		var saved BackupManifest
+		err = file.LoadJSON(BackupManifestPath(path), &saved)
+		require.NoError(t, err)
+		require.Equal(t, *m, saved)
+
+		fi, err := os.Stat(path)
+		require.NoError(t, err)
+		if !compress {
+			require.Equal(t, m.DBSize, fi.Size())
+		}
+
+		// An existing backup is not overwritten
+		_, err = BackupDBToFile(db, path, compress)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "already exists")
+
+		// A manifest that does not match the backup fails verification
+		bad := *m
+		bad.SHA256 = "00"
+		err = VerifyBackup(path, bad)
+		require.Error(t, err)
+
+		bad = *m
+		bad.HeadSeq = 1
+		err = VerifyBackup(path, bad)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "does not match the manifest head block")
+	}
+
+	// A backup that fails verification is removed and its manifest is not saved
+	path := filepath.Join(dir, "bad.db")
+	_, err = WriteBackupFile(path, func(w io.Writer) (*BackupManifest, error) {
+		m, err := BackupDB(db, w, false)
+		if err != nil {
+			return nil, err
+		}
+		m.HeadSeq = 1
+		return m, nil
+	})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "does not match the manifest head block")
+
+	for _, p := range []string{path, path + ".tmp", BackupManifestPath(path)} {
+		_, err = os.Stat(p)
+		require.True(t, os.IsNotExist(err), p)
+	}
+
+	// A temporary backup is written next to the database, and matches the database file
+	tmpPath, m, err := BackupDBToTempFile(db, false)
+	require.NoError(t, err)
+	defer os.Remove(tmpPath)
+	require.Equal(t, filepath.Dir(db.Path()), filepath.Dir(tmpPath))
+	require.Equal(t, gb.HashHeader().Hex(), m.HeadHash)
+
+	err = VerifyBackup(tmpPath, *m)
+	require.NoError(t, err)
+
+	// An in-memory database has no file to back up
+	mdb := dbutil.NewMemoryDB()
+	defer mdb.Close()
+
+	var buf bytes.Buffer
+	_, err = BackupDB(mdb, &buf, false)
+	require.Equal(t, ErrBackupUnsupported, err)
+
+	_, _, err = BackupDBToTempFile(mdb, false)
+	require.Equal(t, ErrBackupUnsupported, err)
+}
@@ -0,0 +1,362 @@
+package visor
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/visor/blockdb"
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+)
+
+//go:generate skyencoder -unexported -output-path . -package visor -struct SignedBlock github.com/skycoin/skycoin/src/coin
+
+// BlockFileVersion is the version of the block file format
+const BlockFileVersion uint32 = 1
+
+const (
+	// maxBlockFrameSize is the maximum size of an encoded block in a block file,
+	// checked before allocating the buffer for a frame
+	maxBlockFrameSize = 32 * 1024 * 1024
+	// importBlocksProgressInterval is the number of imported blocks between calls to the ImportBlocks progress callback
+	importBlocksProgressInterval = 1000
+)
+
+// blockFileMagic starts a block file
+var blockFileMagic = []byte("SKYBLOCK")
+
+var (
+	// ErrBlockFileVersion is returned when reading a block file of an unsupported version
+	ErrBlockFileVersion = errors.New("unsupported block file version")
+	// ErrBlockFileTruncated is returned when a block file ends in the middle of a block,
+	// for example if the export was interrupted
+	ErrBlockFileTruncated = errors.New("block file is truncated")
+	// ErrImportBlocksStopped is returned when ImportBlocks is stopped by its quit channel
+	ErrImportBlocksStopped = errors.New("import blocks stopped")
+)
+
+// A block file starts with the "SKYBLOCK" magic bytes and the uint32 file format version,
+// followed by one frame for each block. A frame is the uint32 length of the skyencoder-encoded coin.SignedBlock,
+// the encoded block, and the SHA256 checksum of the encoded block.
+// Integers are little endian, like the rest of the encoder package.
+
+// BlockFileWriter writes signed blocks to a block file
+type BlockFileWriter struct {
+	w io.Writer
+}
+
+// NewBlockFileWriter writes the header of a block file to w and returns a BlockFileWriter for its blocks
+func NewBlockFileWriter(w io.Writer) (*BlockFileWriter, error) {
+	header := make([]byte, len(blockFileMagic)+4)
+	copy(header, blockFileMagic)
+	binary.LittleEndian.PutUint32(header[len(blockFileMagic):], BlockFileVersion)
+
+	if _, err := w.Write(header); err != nil {
+		return nil, err
+	}
+
+	return &BlockFileWriter{
+		w: w,
+	}, nil
+}
+
+// Write writes a block frame
+func (bw *BlockFileWriter) Write(b *coin.SignedBlock) error {
+	buf, err := encodeSignedBlock(b)
+	if err != nil {
+		return err
+	}
+
+	if len(buf) > maxBlockFrameSize {
+		return fmt.Errorf("block %d is too large for a block file", b.Seq())
+	}
+
+	var length [4]byte
+	binary.LittleEndian.PutUint32(length[:], uint32(len(buf)))
+	if _, err := bw.w.Write(length[:]); err != nil {
+		return err
+	}
+
+	if _, err := bw.w.Write(buf); err != nil {
+		return err
+	}
+
+	sum := cipher.SumSHA256(buf)
+	_, err = bw.w.Write(sum[:])
+	return err
+}
+
+// BlockFileReader reads signed blocks from a block file
+type BlockFileReader struct {
+	r io.Reader
+}
+
+// NewBlockFileReader reads the header of a block file from r and returns a BlockFileReader for its blocks
+func NewBlockFileReader(r io.Reader) (*BlockFileReader, error) {
+	header := make([]byte, len(blockFileMagic)+4)
+	if _, err := io.ReadFull(r, header); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil, errors.New("not a block file")
+		}
+		return nil, err
+	}
+
+	if !bytes.Equal(header[:len(blockFileMagic)], blockFileMagic) {
+		return nil, errors.New("not a block file")
+	}
+
+	if binary.LittleEndian.Uint32(header[len(blockFileMagic):]) != BlockFileVersion {
+		return nil, ErrBlockFileVersion
+	}
+
+	return &BlockFileReader{
+		r: r,
+	}, nil
+}
+
+// Read reads the next block frame and verifies its checksum. Returns io.EOF after the last block,
+// or ErrBlockFileTruncated if the file ends in the middle of a block.
+func (br *BlockFileReader) Read() (*coin.SignedBlock, error) {
+	var length [4]byte
+	if _, err := io.ReadFull(br.r, length[:]); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return nil, ErrBlockFileTruncated
+		}
+		return nil, err
+	}
+
+	n := binary.LittleEndian.Uint32(length[:])
+	if n > maxBlockFrameSize {
+		return nil, fmt.Errorf("block file frame size %d exceeds the maximum %d", n, maxBlockFrameSize)
+	}
+
+	buf := make([]byte, int(n)+len(cipher.SHA256{}))
+	if _, err := io.ReadFull(br.r, buf); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil, ErrBlockFileTruncated
+		}
+		return nil, err
+	}
+
+	data := buf[:n]
+	var sum cipher.SHA256
+	copy(sum[:], buf[n:])
+	if cipher.SumSHA256(data) != sum {
+		return nil, errors.New("block file frame checksum does not match")
+	}
+
+	var b coin.SignedBlock
+	if err := decodeSignedBlockExact(data, &b); err != nil {
+		return nil, fmt.Errorf("decode block file frame failed: %v", err)
+	}
+
+	return &b, nil
+}
+
+// ExportBlocks writes the signed blocks from start to end, inclusive, to a block file.
+// If end is 0, the blocks up to the head block are written.
+// The blocks are read in a single read-only transaction, so the node can keep running.
+// Pruned blocks, and blocks that have not been backfilled after starting from a snapshot, can not be exported.
+// Returns the number of blocks that were written.
+func ExportBlocks(db *dbutil.DB, w io.Writer, start, end uint64) (uint64, error) {
+	bc, err := NewBlockchain(db, BlockchainConfig{})
+	if err != nil {
+		return 0, err
+	}
+
+	bw := bufio.NewWriter(w)
+	fw, err := NewBlockFileWriter(bw)
+	if err != nil {
+		return 0, err
+	}
+
+	var n uint64
+	if err := db.View("ExportBlocks", func(tx *dbutil.Tx) error {
+		headSeq, ok, err := bc.HeadSeq(tx)
+		if err != nil {
+			return err
+		} else if !ok {
+			return blockdb.ErrNoHeadBlock
+		}
+
+		if end == 0 {
+			end = headSeq
+		}
+
+		if end > headSeq {
+			return fmt.Errorf("end block seq %d is greater than the head block seq %d", end, headSeq)
+		}
+
+		if start > end {
+			return fmt.Errorf("start block seq %d is greater than the end block seq %d", start, end)
+		}
+
+		for seq := start; seq <= end; seq++ {
+			b, err := bc.GetSignedBlockBySeq(tx, seq)
+			if err != nil {
+				return err
+			} else if b == nil {
+				return fmt.Errorf("no block exists in depth: %d", seq)
+			}
+
+			if err := fw.Write(b); err != nil {
+				return err
+			}
+			n++
+		}
+
+		return nil
+	}); err != nil {
+		return n, err
+	}
+
+	return n, bw.Flush()
+}
+
+// ImportBlocksResult is the progress and result of importing a block file
+type ImportBlocksResult struct {
+	// Imported is the number of blocks that were executed
+	Imported uint64
+	// Skipped is the number of blocks of the file that were already in the blockchain
+	Skipped uint64
+	// HeadSeq is the seq of the head block of the blockchain
+	HeadSeq uint64
+	// Elapsed is the time spent importing
+	Elapsed time.Duration
+}
+
+// BlocksPerSecond returns the import throughput
+func (r ImportBlocksResult) BlocksPerSecond() float64 {
+	if r.Elapsed <= 0 {
+		return 0
+	}
+	return float64(r.Imported) / r.Elapsed.Seconds()
+}
+
+// ImportBlocks executes the blocks of a block file with ExecuteSignedBlock, which verifies their signatures.
+// Each block is executed in its own transaction, so an interrupted import is resumed by importing the file again:
+// the blocks that are already in the blockchain are skipped, after checking that the file has the same head block.
+// The blocks of the file must be sequential, and start at the genesis block if the blockchain is empty.
+// progress, if not nil, is called every 1000 imported blocks.
+// quit, if not nil, stops the import with ErrImportBlocksStopped.
+// The result is returned with the error, with the blocks that were imported before it.
+func (vs *Visor) ImportBlocks(r io.Reader, quit <-chan struct{}, progress func(ImportBlocksResult)) (*ImportBlocksResult, error) {
+	started := time.Now()
+	var result ImportBlocksResult
+
+	err := vs.importBlocks(bufio.NewReader(r), quit, &result, func() {
+		result.Elapsed = time.Since(started)
+		if progress != nil && result.Imported%importBlocksProgressInterval == 0 {
+			progress(result)
+		}
+	})
+
+	result.Elapsed = time.Since(started)
+	return &result, err
+}
+
+func (vs *Visor) importBlocks(r io.Reader, quit <-chan struct{}, result *ImportBlocksResult, imported func()) error {
+	fr, err := NewBlockFileReader(r)
+	if err != nil {
+		return err
+	}
+
+	var head *coin.SignedBlock
+	if err := vs.db.View("ImportBlocks", func(tx *dbutil.Tx) error {
+		var err error
+		head, err = vs.blockchain.Head(tx)
+		if err == blockdb.ErrNoHeadBlock {
+			return nil
+		}
+		return err
+	}); err != nil {
+		return err
+	}
+
+	if head != nil {
+		result.HeadSeq = head.Seq()
+	}
+
+	for {
+		select {
+		case <-quit:
+			return ErrImportBlocksStopped
+		default:
+		}
+
+		b, err := fr.Read()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		switch {
+		case head == nil:
+			if b.Seq() != 0 {
+				return fmt.Errorf("the blockchain is empty, the block file must start at the genesis block but starts at block %d", b.Seq())
+			}
+
+		case b.Seq() < head.Seq():
+			result.Skipped++
+			continue
+
+		case b.Seq() == head.Seq():
+			if b.HashHeader() != head.HashHeader() {
+				return fmt.Errorf("block %d of the block file does not match the head block of the blockchain", b.Seq())
+			}
+			result.Skipped++
+			continue
+
+		case b.Seq() != head.Seq()+1:
+			return fmt.Errorf("the block file skips from block %d to block %d", head.Seq(), b.Seq())
+
+		case b.Head.PrevHash != head.HashHeader():
+			return fmt.Errorf("block %d of the block file does not reference the hash of the head block", b.Seq())
+		}
+
+		if err := vs.ExecuteSignedBlock(*b); err != nil {
+			return fmt.Errorf("execute block %d failed: %v", b.Seq(), err)
+		}
+
+		head = b
+		result.HeadSeq = b.Seq()
+		result.Imported++
+		imported()
+	}
+}
+
+// ImportBlocks imports a block file into the database of a stopped node, see Visor.ImportBlocks.
+// A new database is created if it is empty. The blocks are verified against the blockchain pubkey.
+// The database must not have pending schema migrations.
+func ImportBlocks(db *dbutil.DB, pubkey cipher.PubKey, r io.Reader, quit <-chan struct{}, progress func(ImportBlocksResult)) (*ImportBlocksResult, error) {
+	pending, err := PendingMigrations(db)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(pending) != 0 {
+		return nil, fmt.Errorf("the database has %d pending schema migrations, apply them with migrateDB first", len(pending))
+	}
+
+	cfg := NewConfig()
+	cfg.BlockchainPubkey = pubkey
+
+	v, err := New(cfg, db, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// Mark a new database with the latest schema version
+	if _, err := Migrate(db, MigrateOptions{}); err != nil {
+		return nil, err
+	}
+
+	return v.ImportBlocks(r, quit, progress)
+}
@@ -0,0 +1,203 @@
+package visor
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/params"
+	"github.com/skycoin/skycoin/src/testutil"
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+)
+
+func TestExportImportBlocks(t *testing.T) {
+	db, shutdown := prepareDB(t)
+	defer shutdown()
+
+	cfg := NewConfig()
+	cfg.IsBlockPublisher = true
+	cfg.BlockchainPubkey = genPublic
+	cfg.BlockchainSeckey = genSecret
+	cfg.GenesisAddress = genAddress
+
+	v, err := New(cfg, db, nil)
+	require.NoError(t, err)
+
+	gb := addGenesisBlockToVisor(t, v)
+
+	// Create the blocks at increasing times, since a block time can not be equal to the previous block time
+	when := uint64(time.Now().UTC().Unix())
+	createAndExecuteBlock := func(txn coin.Transaction) coin.SignedBlock {
+		_, softErr, err := v.InjectForeignTransaction(txn)
+		require.NoError(t, err)
+		require.Nil(t, softErr)
+
+		when++
+		var sb coin.SignedBlock
+		err = db.Update("", func(tx *dbutil.Tx) error {
+			var err error
+			sb, err = v.createBlock(tx, when)
+			if err != nil {
+				return err
+			}
+
+			return v.executeSignedBlock(tx, sb)
+		})
+		require.NoError(t, err)
+		return sb
+	}
+
+	uxs := coin.CreateUnspents(gb.Head, gb.Body.Transactions[0])
+	sb := createAndExecuteBlock(makeUnspentsTxn(t, uxs, []cipher.SecKey{genSecret}, genAddress, 10, params.UserVerifyTxn.MaxDropletPrecision))
+	blocks := []coin.SignedBlock{*gb, sb}
+
+	uxs = coin.CreateUnspents(sb.Head, sb.Body.Transactions[0])
+	for i := 0; i < 3; i++ {
+		txn := makeSpendTxWithFee(t, coin.UxArray{uxs[i]}, []cipher.SecKey{genSecret}, testutil.MakeAddress(), uxs[i].Body.Coins, 0)
+		blocks = append(blocks, createAndExecuteBlock(txn))
+	}
+
+	exportBlocks := func(start, end uint64) []byte {
+		var buf bytes.Buffer
+		n, err := ExportBlocks(db, &buf, start, end)
+		require.NoError(t, err)
+		if end == 0 {
+			end = 4
+		}
+		require.Equal(t, end-start+1, n)
+		return buf.Bytes()
+	}
+
+	all := exportBlocks(0, 0)
+
+	// The file has the exported blocks
+	fr, err := NewBlockFileReader(bytes.NewReader(all))
+	require.NoError(t, err)
+	for _, b := range blocks {
+		rb, err := fr.Read()
+		require.NoError(t, err)
+		require.Equal(t, b, *rb)
+	}
+	_, err = fr.Read()
+	require.Equal(t, io.EOF, err)
+
+	// Invalid ranges
+	_, err = ExportBlocks(db, &bytes.Buffer{}, 0, 5)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "greater than the head block seq 4")
+	_, err = ExportBlocks(db, &bytes.Buffer{}, 3, 2)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "start block seq 3 is greater than the end block seq 2")
+
+	requireHead := func(db *dbutil.DB, b coin.SignedBlock) {
+		bc, err := NewBlockchain(db, BlockchainConfig{})
+		require.NoError(t, err)
+		err = db.View("", func(tx *dbutil.Tx) error {
+			head, err := bc.Head(tx)
+			require.NoError(t, err)
+			require.Equal(t, b.HashHeader(), head.HashHeader())
+			return nil
+		})
+		require.NoError(t, err)
+	}
+
+	t.Run("import", func(t *testing.T) {
+		db := dbutil.NewMemoryDB()
+		defer db.Close()
+
+		var progress []ImportBlocksResult
+		result, err := ImportBlocks(db, genPublic, bytes.NewReader(all), nil, func(r ImportBlocksResult) {
+			progress = append(progress, r)
+		})
+		require.NoError(t, err)
+		require.Equal(t, uint64(5), result.Imported)
+		require.Equal(t, uint64(0), result.Skipped)
+		require.Equal(t, uint64(4), result.HeadSeq)
+		require.Empty(t, progress)
+		requireHead(db, blocks[4])
+
+		version, ok, err := GetSchemaVersion(db)
+		require.NoError(t, err)
+		require.True(t, ok)
+		require.Equal(t, LatestSchemaVersion(), version)
+
+		// Importing the file again skips all of its blocks
+		result, err = ImportBlocks(db, genPublic, bytes.NewReader(all), nil, nil)
+		require.NoError(t, err)
+		require.Equal(t, uint64(0), result.Imported)
+		require.Equal(t, uint64(5), result.Skipped)
+	})
+
+	t.Run("resume", func(t *testing.T) {
+		db := dbutil.NewMemoryDB()
+		defer db.Close()
+
+		// The export of the first blocks can be interrupted in the middle of a block
+		partial := exportBlocks(0, 2)
+		result, err := ImportBlocks(db, genPublic, bytes.NewReader(partial[:len(partial)-10]), nil, nil)
+		require.Equal(t, ErrBlockFileTruncated, err)
+		require.Equal(t, uint64(2), result.Imported)
+		requireHead(db, blocks[1])
+
+		result, err = ImportBlocks(db, genPublic, bytes.NewReader(all), nil, nil)
+		require.NoError(t, err)
+		require.Equal(t, uint64(3), result.Imported)
+		require.Equal(t, uint64(2), result.Skipped)
+		requireHead(db, blocks[4])
+	})
+
+	t.Run("stopped", func(t *testing.T) {
+		db := dbutil.NewMemoryDB()
+		defer db.Close()
+
+		quit := make(chan struct{})
+		close(quit)
+		result, err := ImportBlocks(db, genPublic, bytes.NewReader(all), quit, nil)
+		require.Equal(t, ErrImportBlocksStopped, err)
+		require.Equal(t, uint64(0), result.Imported)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		importErr := func(setup []byte, file []byte, pubkey cipher.PubKey) error {
+			db := dbutil.NewMemoryDB()
+			defer db.Close()
+
+			if setup != nil {
+				_, err := ImportBlocks(db, genPublic, bytes.NewReader(setup), nil, nil)
+				require.NoError(t, err)
+			}
+
+			_, err := ImportBlocks(db, pubkey, bytes.NewReader(file), nil, nil)
+			require.Error(t, err)
+			return err
+		}
+
+		err := importErr(nil, []byte("not a block file"), genPublic)
+		require.Equal(t, "not a block file", err.Error())
+
+		version := append([]byte{}, all...)
+		version[len(blockFileMagic)] = 2
+		err = importErr(nil, version, genPublic)
+		require.Equal(t, ErrBlockFileVersion, err)
+
+		corrupt := append([]byte{}, all...)
+		corrupt[len(corrupt)-40] ^= 0xFF
+		err = importErr(nil, corrupt, genPublic)
+		require.Equal(t, "block file frame checksum does not match", err.Error())
+
+		err = importErr(nil, exportBlocks(2, 4), genPublic)
+		require.Contains(t, err.Error(), "must start at the genesis block but starts at block 2")
+
+		err = importErr(exportBlocks(0, 1), exportBlocks(3, 4), genPublic)
+		require.Equal(t, "the block file skips from block 1 to block 3", err.Error())
+
+		pubkey, _ := cipher.GenerateKeyPair()
+		err = importErr(nil, all, pubkey)
+		require.Contains(t, err.Error(), "execute block 0 failed")
+	})
+}
@@ -70,10 +70,17 @@ type chainStore interface {
 	GetBlockByHash(*dbutil.Tx, cipher.SHA256) (*coin.Block, error)
 	GetSignedBlockByHash(*dbutil.Tx, cipher.SHA256) (*coin.SignedBlock, error)
 	GetSignedBlockBySeq(*dbutil.Tx, uint64) (*coin.SignedBlock, error)
+	GetBlockHeaderBySeq(*dbutil.Tx, uint64) (*coin.BlockHeader, error)
 	UnspentPool() blockdb.UnspentPooler
 	GetGenesisBlock(*dbutil.Tx) (*coin.SignedBlock, error)
 	GetBlockSignature(*dbutil.Tx, *coin.Block) (cipher.Sig, bool, error)
 	ForEachBlock(*dbutil.Tx, func(*coin.Block) error) error
+	PruneSeq(*dbutil.Tx) (uint64, bool, error)
+	Prune(*dbutil.Tx, uint64) (uint64, error)
+	ImportSnapshot(*dbutil.Tx, []coin.SignedBlock, coin.UxArray) error
+	BackfillRange(*dbutil.Tx) (uint64, uint64, bool, error)
+	BackfillBlock(*dbutil.Tx, *coin.SignedBlock) error
+	RollbackHead(*dbutil.Tx) (*coin.SignedBlock, error)
 }
 
 // DefaultWalker default blockchain walker
@@ -147,6 +154,68 @@ func (bc *Blockchain) GetSignedBlockBySeq(tx *dbutil.Tx, seq uint64) (*coin.Sign
 	return bc.store.GetSignedBlockBySeq(tx, seq)
 }
 
+// GetBlockHeaderBySeq returns the header of the block of given seq, including the blocks whose bodies
+// have been pruned or not backfilled yet
+func (bc *Blockchain) GetBlockHeaderBySeq(tx *dbutil.Tx, seq uint64) (*coin.BlockHeader, error) {
+	return bc.store.GetBlockHeaderBySeq(tx, seq)
+}
+
+// PruneSeq returns the sequence of the most recent block whose body has been pruned.
+// Returns false if pruning is not enabled for the database.
+func (bc *Blockchain) PruneSeq(tx *dbutil.Tx) (uint64, bool, error) {
+	return bc.store.PruneSeq(tx)
+}
+
+// Prune discards the bodies of the blocks more than keep blocks below the head block.
+// Returns the number of blocks pruned.
+func (bc *Blockchain) Prune(tx *dbutil.Tx, keep uint64) (uint64, error) {
+	return bc.store.Prune(tx, keep)
+}
+
+// ImportSnapshot initializes an empty database from the blocks and unspent outputs of a snapshot.
+// The snapshot must have been verified.
+func (bc *Blockchain) ImportSnapshot(tx *dbutil.Tx, blocks []coin.SignedBlock, uxs coin.UxArray) error {
+	return bc.store.ImportSnapshot(tx, blocks, uxs)
+}
+
+// BackfillRange returns the inclusive range of block sequences whose bodies have not been downloaded yet
+// after starting from a snapshot. Returns false if no block needs to be backfilled.
+func (bc *Blockchain) BackfillRange(tx *dbutil.Tx) (uint64, uint64, bool, error) {
+	return bc.store.BackfillRange(tx)
+}
+
+// BackfillBlock stores the body of the next block that needs to be backfilled
+func (bc *Blockchain) BackfillBlock(tx *dbutil.Tx, b *coin.SignedBlock) error {
+	return bc.store.BackfillBlock(tx, b)
+}
+
+// RollbackTo removes the blocks after seq from the blockchain, restoring the unspent pool to its state
+// before they were executed. Returns the removed blocks, most recent first.
+func (bc *Blockchain) RollbackTo(tx *dbutil.Tx, seq uint64) ([]coin.SignedBlock, error) {
+	headSeq, ok, err := bc.store.HeadSeq(tx)
+	if err != nil {
+		return nil, err
+	} else if !ok {
+		return nil, blockdb.ErrNoHeadBlock
+	}
+
+	if seq > headSeq {
+		return nil, fmt.Errorf("can not roll back to block %d, the head block is %d", seq, headSeq)
+	}
+
+	var blocks []coin.SignedBlock
+	for i := headSeq; i > seq; i-- {
+		b, err := bc.store.RollbackHead(tx)
+		if err != nil {
+			return nil, fmt.Errorf("roll back block %d failed: %v", i, err)
+		}
+
+		blocks = append(blocks, *b)
+	}
+
+	return blocks, nil
+}
+
 // Head returns the most recent confirmed block
 func (bc Blockchain) Head(tx *dbutil.Tx) (*coin.SignedBlock, error) {
 	return bc.store.Head(tx)
@@ -142,6 +142,39 @@ func (fcs *fakeChainStore) ForEachBlock(tx *dbutil.Tx, f func(*coin.Block) error
 	return nil
 }
 
+func (fcs *fakeChainStore) PruneSeq(tx *dbutil.Tx) (uint64, bool, error) {
+	return 0, false, nil
+}
+
+func (fcs *fakeChainStore) Prune(tx *dbutil.Tx, keep uint64) (uint64, error) {
+	return 0, nil
+}
+
+func (fcs *fakeChainStore) ImportSnapshot(tx *dbutil.Tx, blocks []coin.SignedBlock, uxs coin.UxArray) error {
+	return nil
+}
+
+func (fcs *fakeChainStore) BackfillRange(tx *dbutil.Tx) (uint64, uint64, bool, error) {
+	return 0, 0, false, nil
+}
+
+func (fcs *fakeChainStore) GetBlockHeaderBySeq(tx *dbutil.Tx, seq uint64) (*coin.BlockHeader, error) {
+	b, err := fcs.GetSignedBlockBySeq(tx, seq)
+	if err != nil || b == nil {
+		return nil, err
+	}
+
+	return &b.Head, nil
+}
+
+func (fcs *fakeChainStore) RollbackHead(tx *dbutil.Tx) (*coin.SignedBlock, error) {
+	return nil, nil
+}
+
+func (fcs *fakeChainStore) BackfillBlock(tx *dbutil.Tx, b *coin.SignedBlock) error {
+	return nil
+}
+
 func makeBlock(t *testing.T, preBlock coin.Block, tm uint64) *coin.Block {
 	uxHash := testutil.RandSHA256(t)
 	tx := coin.Transaction{}
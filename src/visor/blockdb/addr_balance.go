@@ -0,0 +1,402 @@
+package blockdb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/util/mathutil"
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+)
+
+//go:generate skyencoder -unexported -struct AddressBalance
+
+var (
+	addrBalanceIndexHeightKey = []byte("addr_balance_index_height")
+	addrBalanceCountKey       = []byte("addr_balance_count")
+
+	// UnspentPoolAddrBalanceBkt maps addresses to the balance of their unspent outputs
+	UnspentPoolAddrBalanceBkt = []byte("unspent_pool_addr_balance")
+	// UnspentPoolRichlistBkt indexes the balances of UnspentPoolAddrBalanceBkt by coins.
+	// The keys are the complement of the coins, so that the addresses with the most coins are first,
+	// followed by the address bytes.
+	UnspentPoolRichlistBkt = []byte("unspent_pool_richlist_index")
+)
+
+// AddressBalance is the balance of the unspent outputs of an address
+type AddressBalance struct {
+	Coins uint64
+	// Outputs is the number of unspent outputs
+	Outputs uint64
+}
+
+// add adds the outputs to the balance
+func (b AddressBalance) add(uxs coin.UxArray) (AddressBalance, error) {
+	for _, ux := range uxs {
+		var err error
+		b.Coins, err = mathutil.AddUint64(b.Coins, ux.Body.Coins)
+		if err != nil {
+			return AddressBalance{}, err
+		}
+
+		b.Outputs++
+	}
+
+	return b, nil
+}
+
+// sub subtracts the outputs from the balance
+func (b AddressBalance) sub(uxs coin.UxArray) (AddressBalance, error) {
+	for _, ux := range uxs {
+		if b.Coins < ux.Body.Coins || b.Outputs == 0 {
+			return AddressBalance{}, errors.New("address balance is less than the outputs subtracted from it")
+		}
+
+		b.Coins -= ux.Body.Coins
+		b.Outputs--
+	}
+
+	return b, nil
+}
+
+// richlistKey returns the UnspentPoolRichlistBkt key of an address
+func richlistKey(addr cipher.Address, coins uint64) []byte {
+	addrBytes := addr.Bytes()
+	key := make([]byte, 8+len(addrBytes))
+	binary.BigEndian.PutUint64(key[:8], ^coins)
+	copy(key[8:], addrBytes)
+	return key
+}
+
+type addrBalanceIndex struct{}
+
+func (idx addrBalanceIndex) get(tx *dbutil.Tx, addr cipher.Address) (AddressBalance, bool, error) {
+	v, err := dbutil.GetBucketValueNoCopy(tx, UnspentPoolAddrBalanceBkt, addr.Bytes())
+	if err != nil {
+		return AddressBalance{}, false, err
+	} else if v == nil {
+		return AddressBalance{}, false, nil
+	}
+
+	var b AddressBalance
+	if err := decodeAddressBalanceExact(v, &b); err != nil {
+		return AddressBalance{}, false, err
+	}
+
+	return b, true, nil
+}
+
+func (idx addrBalanceIndex) put(tx *dbutil.Tx, addr cipher.Address, b AddressBalance) error {
+	if b.Outputs == 0 {
+		return errors.New("addrBalanceIndex.put cannot put a balance without outputs")
+	}
+
+	buf, err := encodeAddressBalance(&b)
+	if err != nil {
+		return err
+	}
+
+	if err := dbutil.PutBucketValue(tx, UnspentPoolAddrBalanceBkt, addr.Bytes(), buf); err != nil {
+		return err
+	}
+
+	return dbutil.PutBucketValue(tx, UnspentPoolRichlistBkt, richlistKey(addr, b.Coins), buf)
+}
+
+func (idx addrBalanceIndex) getCount(tx *dbutil.Tx) (uint64, error) {
+	v, err := dbutil.GetBucketValue(tx, UnspentMetaBkt, addrBalanceCountKey)
+	if err != nil {
+		return 0, err
+	} else if v == nil {
+		return 0, nil
+	}
+
+	return dbutil.Btoi(v), nil
+}
+
+func (idx addrBalanceIndex) setCount(tx *dbutil.Tx, n uint64) error {
+	return dbutil.PutBucketValue(tx, UnspentMetaBkt, addrBalanceCountKey, dbutil.Itob(n))
+}
+
+// adjust adds and removes outputs from the balance of an address.
+// The row is deleted when the address has no outputs left, and the address count is updated.
+func (idx addrBalanceIndex) adjust(tx *dbutil.Tx, addr cipher.Address, addUxs, rmUxs coin.UxArray) error {
+	if len(addUxs) == 0 && len(rmUxs) == 0 {
+		return nil
+	}
+
+	b, ok, err := idx.get(tx, addr)
+	if err != nil {
+		return err
+	}
+
+	if ok {
+		if err := dbutil.Delete(tx, UnspentPoolRichlistBkt, richlistKey(addr, b.Coins)); err != nil {
+			return err
+		}
+	}
+
+	nb, err := b.add(addUxs)
+	if err != nil {
+		return err
+	}
+
+	nb, err = nb.sub(rmUxs)
+	if err != nil {
+		return fmt.Errorf("addrBalanceIndex.adjust: %v for address %s", err, addr.String())
+	}
+
+	count, err := idx.getCount(tx)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case nb.Outputs == 0:
+		if !ok {
+			return nil
+		}
+
+		if err := dbutil.Delete(tx, UnspentPoolAddrBalanceBkt, addr.Bytes()); err != nil {
+			return err
+		}
+
+		return idx.setCount(tx, count-1)
+
+	case !ok:
+		if err := idx.setCount(tx, count+1); err != nil {
+			return err
+		}
+	}
+
+	return idx.put(tx, addr, nb)
+}
+
+// build rebuilds the index from the unspent pool
+func (idx addrBalanceIndex) build(tx *dbutil.Tx) error {
+	// The buckets do not exist in databases created before the index was introduced
+	if err := dbutil.CreateBuckets(tx, [][]byte{
+		UnspentPoolAddrBalanceBkt,
+		UnspentPoolRichlistBkt,
+	}); err != nil {
+		return err
+	}
+
+	if err := dbutil.Reset(tx, UnspentPoolAddrBalanceBkt); err != nil {
+		return err
+	}
+
+	if err := dbutil.Reset(tx, UnspentPoolRichlistBkt); err != nil {
+		return err
+	}
+
+	balances := make(map[cipher.Address]AddressBalance)
+	if err := dbutil.ForEach(tx, UnspentPoolBkt, func(_, v []byte) error {
+		var ux coin.UxOut
+		if err := decodeUxOutExact(v, &ux); err != nil {
+			return err
+		}
+
+		b, err := balances[ux.Body.Address].add(coin.UxArray{ux})
+		if err != nil {
+			return err
+		}
+		balances[ux.Body.Address] = b
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	for addr, b := range balances {
+		if err := idx.put(tx, addr, b); err != nil {
+			return err
+		}
+	}
+
+	return idx.setCount(tx, uint64(len(balances)))
+}
+
+// adjustAddrBalances updates the address balance index with the outputs added to and removed from the pool
+func (up *Unspents) adjustAddrBalances(tx *dbutil.Tx, addUxs, rmUxs coin.UxArray) error {
+	addAddrUxs := make(map[cipher.Address]coin.UxArray)
+	for _, ux := range addUxs {
+		addAddrUxs[ux.Body.Address] = append(addAddrUxs[ux.Body.Address], ux)
+	}
+
+	rmAddrUxs := make(map[cipher.Address]coin.UxArray)
+	for _, ux := range rmUxs {
+		rmAddrUxs[ux.Body.Address] = append(rmAddrUxs[ux.Body.Address], ux)
+	}
+
+	for addr, uxs := range rmAddrUxs {
+		if err := up.addrBalanceIndex.adjust(tx, addr, addAddrUxs[addr], uxs); err != nil {
+			return err
+		}
+
+		delete(addAddrUxs, addr)
+	}
+
+	for addr, uxs := range addAddrUxs {
+		if err := up.addrBalanceIndex.adjust(tx, addr, uxs, nil); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// BuildAddrBalanceIndex rebuilds the address balance index from the unspent pool,
+// which is at the block headSeq, and marks it as up to date.
+// The index of databases created before it was introduced is built by a schema migration.
+func (up *Unspents) BuildAddrBalanceIndex(tx *dbutil.Tx, headSeq uint64) error {
+	logger.Info("Building unspent address balance index")
+
+	if err := up.addrBalanceIndex.build(tx); err != nil {
+		return err
+	}
+
+	return up.meta.setAddrBalanceIndexHeight(tx, headSeq)
+}
+
+// AddrBalanceIndexReady returns true if the address balance index is up to date with the unspent pool.
+// The index of an empty unspent pool is empty until the genesis block is processed.
+func (up *Unspents) AddrBalanceIndexReady(tx *dbutil.Tx) (bool, error) {
+	height, ok, err := up.meta.getAddrBalanceIndexHeight(tx)
+	if err != nil {
+		return false, err
+	}
+
+	if !ok {
+		if !dbutil.Exists(tx, UnspentPoolAddrBalanceBkt) {
+			return false, nil
+		}
+
+		return dbutil.IsEmpty(tx, UnspentPoolBkt)
+	}
+
+	addrIndexHeight, ok, err := up.meta.getAddrIndexHeight(tx)
+	if err != nil || !ok {
+		return false, err
+	}
+
+	return height == addrIndexHeight, nil
+}
+
+// GetAddrBalances returns the balances of addresses from the address balance index.
+// Addresses without unspent outputs have a zero balance.
+func (up *Unspents) GetAddrBalances(tx *dbutil.Tx, addrs []cipher.Address) ([]AddressBalance, error) {
+	balances := make([]AddressBalance, len(addrs))
+	for i, addr := range addrs {
+		b, _, err := up.addrBalanceIndex.get(tx, addr)
+		if err != nil {
+			return nil, err
+		}
+		balances[i] = b
+	}
+
+	return balances, nil
+}
+
+// ForEachAddrBalanceByCoins calls f with the balance of each address with unspent outputs,
+// from the most coins to the least. Addresses with the same coins are ordered by their bytes.
+func (up *Unspents) ForEachAddrBalanceByCoins(tx *dbutil.Tx, f func(cipher.Address, AddressBalance) error) error {
+	return dbutil.ForEach(tx, UnspentPoolRichlistBkt, func(k, v []byte) error {
+		if len(k) < 8 {
+			return errors.New("invalid UnspentPoolRichlistBkt key")
+		}
+
+		addr, err := cipher.AddressFromBytes(k[8:])
+		if err != nil {
+			return err
+		}
+
+		var b AddressBalance
+		if err := decodeAddressBalanceExact(v, &b); err != nil {
+			return err
+		}
+
+		return f(addr, b)
+	})
+}
+
+// VerifyAddrBalanceIndex checks that the address balance index matches the unspent pool.
+// An index that is not up to date is not checked, since it is rebuilt by a schema migration.
+func (up *Unspents) VerifyAddrBalanceIndex(tx *dbutil.Tx) error {
+	if ready, err := up.AddrBalanceIndexReady(tx); err != nil || !ready {
+		return err
+	}
+
+	balances := make(map[cipher.Address]AddressBalance)
+	if err := dbutil.ForEach(tx, UnspentPoolBkt, func(_, v []byte) error {
+		var ux coin.UxOut
+		if err := decodeUxOutExact(v, &ux); err != nil {
+			return err
+		}
+
+		b, err := balances[ux.Body.Address].add(coin.UxArray{ux})
+		if err != nil {
+			return err
+		}
+		balances[ux.Body.Address] = b
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	var n int
+	if err := dbutil.ForEach(tx, UnspentPoolAddrBalanceBkt, func(k, v []byte) error {
+		addr, err := cipher.AddressFromBytes(k)
+		if err != nil {
+			return err
+		}
+
+		var b AddressBalance
+		if err := decodeAddressBalanceExact(v, &b); err != nil {
+			return err
+		}
+
+		if balances[addr] != b {
+			return fmt.Errorf("address balance index of %s does not match its unspent outputs", addr)
+		}
+
+		rv, err := dbutil.GetBucketValueNoCopy(tx, UnspentPoolRichlistBkt, richlistKey(addr, b.Coins))
+		if err != nil {
+			return err
+		} else if !bytes.Equal(rv, v) {
+			return fmt.Errorf("richlist index of %s does not match its address balance index", addr)
+		}
+
+		n++
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	if n != len(balances) {
+		return fmt.Errorf("address balance index has %d addresses, the unspent pool has %d", n, len(balances))
+	}
+
+	// The keys are counted instead of using dbutil.Len, which does not count the keys
+	// written in the current transaction with the boltdb backend
+	var richlistLen int
+	if err := dbutil.ForEach(tx, UnspentPoolRichlistBkt, func(_, _ []byte) error {
+		richlistLen++
+		return nil
+	}); err != nil {
+		return err
+	} else if richlistLen != n {
+		return fmt.Errorf("richlist index has %d addresses, the address balance index has %d", richlistLen, n)
+	}
+
+	count, err := up.addrBalanceIndex.getCount(tx)
+	if err != nil {
+		return err
+	} else if count != uint64(n) {
+		return fmt.Errorf("address balance count is %d, the address balance index has %d addresses", count, n)
+	}
+
+	return nil
+}
@@ -0,0 +1,195 @@
+package blockdb
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/testutil"
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+)
+
+func TestAddrBalanceIndex(t *testing.T) {
+	db, closedb := prepareDB(t)
+	defer closedb()
+
+	up := NewUnspentPool()
+
+	var uxs coin.UxArray
+	for i := 0; i < 4; i++ {
+		ux := makeUxOut(t)
+		ux.Body.Coins = uint64(i+1) * 1e6
+		uxs = append(uxs, ux)
+	}
+
+	// The last address has two outputs
+	ux := makeUxOut(t)
+	ux.Body.Address = uxs[3].Body.Address
+	ux.Body.Coins = 2e6
+	uxs = append(uxs, ux)
+
+	addr := testutil.MakeAddress()
+
+	// Spend the outputs of the first and last addresses to a new address and to the second address
+	txn := coin.Transaction{}
+	for _, in := range []coin.UxOut{uxs[0], uxs[3]} {
+		require.NoError(t, txn.PushInput(in.Hash()))
+	}
+	require.NoError(t, txn.PushOutput(addr, 3e6, 50))
+	require.NoError(t, txn.PushOutput(uxs[1].Body.Address, 2e6, 50))
+
+	type addrBalance struct {
+		addr    cipher.Address
+		balance AddressBalance
+	}
+
+	richlist := func(tx *dbutil.Tx) []addrBalance {
+		var balances []addrBalance
+		err := up.ForEachAddrBalanceByCoins(tx, func(addr cipher.Address, b AddressBalance) error {
+			balances = append(balances, addrBalance{
+				addr:    addr,
+				balance: b,
+			})
+			return nil
+		})
+		require.NoError(t, err)
+		return balances
+	}
+
+	var b *coin.SignedBlock
+	var before []addrBalance
+	err := db.Update("", func(tx *dbutil.Tx) error {
+		require.NoError(t, up.Import(tx, uxs, 0))
+
+		ready, err := up.AddrBalanceIndexReady(tx)
+		require.NoError(t, err)
+		require.True(t, ready)
+		require.NoError(t, up.VerifyAddrBalanceIndex(tx))
+
+		before = richlist(tx)
+		require.Equal(t, []addrBalance{
+			{uxs[3].Body.Address, AddressBalance{Coins: 6e6, Outputs: 2}},
+			{uxs[2].Body.Address, AddressBalance{Coins: 3e6, Outputs: 1}},
+			{uxs[1].Body.Address, AddressBalance{Coins: 2e6, Outputs: 1}},
+			{uxs[0].Body.Address, AddressBalance{Coins: 1e6, Outputs: 1}},
+		}, before)
+
+		count, err := up.AddressCount(tx)
+		require.NoError(t, err)
+		require.Equal(t, uint64(4), count)
+
+		uxHash, err := up.GetUxHash(tx)
+		require.NoError(t, err)
+
+		block, err := coin.NewBlock(coin.Block{}, uint64(time.Now().Unix()), uxHash, coin.Transactions{txn}, feeCalc)
+		require.NoError(t, err)
+		b = &coin.SignedBlock{
+			Block: *block,
+		}
+
+		return up.ProcessBlock(tx, b)
+	})
+	require.NoError(t, err)
+
+	err = db.Update("", func(tx *dbutil.Tx) error {
+		require.NoError(t, up.VerifyAddrBalanceIndex(tx))
+
+		balances, err := up.GetAddrBalances(tx, []cipher.Address{uxs[0].Body.Address, uxs[1].Body.Address, uxs[3].Body.Address, addr})
+		require.NoError(t, err)
+		require.Equal(t, []AddressBalance{
+			{},
+			{Coins: 4e6, Outputs: 2},
+			{Coins: 2e6, Outputs: 1},
+			{Coins: 3e6, Outputs: 1},
+		}, balances)
+
+		// Addresses with the same coins are ordered by their bytes
+		rl := richlist(tx)
+		require.Len(t, rl, 4)
+		require.Equal(t, addrBalance{uxs[1].Body.Address, balances[1]}, rl[0])
+		require.Equal(t, uint64(3e6), rl[1].balance.Coins)
+		require.Equal(t, uint64(3e6), rl[2].balance.Coins)
+		require.True(t, string(rl[1].addr.Bytes()) < string(rl[2].addr.Bytes()))
+		require.Equal(t, addrBalance{uxs[3].Body.Address, balances[2]}, rl[3])
+
+		count, err := up.AddressCount(tx)
+		require.NoError(t, err)
+		require.Equal(t, uint64(4), count)
+
+		return up.Rollback(tx, b, coin.UxArray{uxs[0], uxs[3]})
+	})
+	require.NoError(t, err)
+
+	err = db.Update("", func(tx *dbutil.Tx) error {
+		require.NoError(t, up.VerifyAddrBalanceIndex(tx))
+		require.Equal(t, before, richlist(tx))
+
+		height, ok, err := up.meta.getAddrBalanceIndexHeight(tx)
+		require.NoError(t, err)
+		require.True(t, ok)
+		require.Equal(t, uint64(0), height)
+
+		// A balance that does not match the unspent outputs is detected
+		require.NoError(t, up.addrBalanceIndex.put(tx, uxs[2].Body.Address, AddressBalance{
+			Coins:   1,
+			Outputs: 1,
+		}))
+		err = up.VerifyAddrBalanceIndex(tx)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "does not match its unspent outputs")
+
+		return nil
+	})
+	require.NoError(t, err)
+}
+
+func TestBuildAddrBalanceIndex(t *testing.T) {
+	db, closedb := prepareDB(t)
+	defer closedb()
+
+	up := NewUnspentPool()
+
+	err := db.Update("", func(tx *dbutil.Tx) error {
+		// The index of an empty unspent pool is empty and up to date
+		ready, err := up.AddrBalanceIndexReady(tx)
+		require.NoError(t, err)
+		require.True(t, ready)
+		require.NoError(t, up.VerifyAddrBalanceIndex(tx))
+
+		count, err := up.AddressCount(tx)
+		require.NoError(t, err)
+		require.Equal(t, uint64(0), count)
+
+		var uxs coin.UxArray
+		for i := 0; i < 3; i++ {
+			uxs = append(uxs, makeUxOut(t))
+		}
+		require.NoError(t, up.Import(tx, uxs, 5))
+
+		// Remove the index, as in a database created before it was introduced
+		require.NoError(t, dbutil.Delete(tx, UnspentMetaBkt, addrBalanceIndexHeightKey))
+		require.NoError(t, dbutil.Reset(tx, UnspentPoolAddrBalanceBkt))
+		require.NoError(t, dbutil.Reset(tx, UnspentPoolRichlistBkt))
+
+		ready, err = up.AddrBalanceIndexReady(tx)
+		require.NoError(t, err)
+		require.False(t, ready)
+
+		require.NoError(t, up.BuildAddrBalanceIndex(tx, 5))
+
+		ready, err = up.AddrBalanceIndexReady(tx)
+		require.NoError(t, err)
+		require.True(t, ready)
+		require.NoError(t, up.VerifyAddrBalanceIndex(tx))
+
+		count, err = up.AddressCount(tx)
+		require.NoError(t, err)
+		require.Equal(t, uint64(3), count)
+
+		return nil
+	})
+	require.NoError(t, err)
+}
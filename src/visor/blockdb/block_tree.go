@@ -15,6 +15,8 @@ var (
 	errWrongParent = errors.New("wrong parent")
 	errHasChild    = errors.New("remove block failed, it has children")
 
+	errBlockNotExist = errors.New("block does not exist")
+
 	// BlocksBkt holds coin.Blocks
 	BlocksBkt = []byte("blocks")
 	// TreeBkt maps block height to a (prev, hash) pair for a block
@@ -162,6 +164,44 @@ func (bt *blockTree) GetBlockInDepth(tx *dbutil.Tx, depth uint64, filter Walker)
 	return bt.GetBlock(tx, hash)
 }
 
+// PruneBlockInDepth discards the body of the block in depth, keeping its header.
+// The filter is used to choose the appropriate block.
+func (bt *blockTree) PruneBlockInDepth(tx *dbutil.Tx, depth uint64, filter Walker) error {
+	b, err := bt.GetBlockInDepth(tx, depth, filter)
+	if err != nil {
+		return err
+	} else if b == nil {
+		return fmt.Errorf("no block exists in depth: %d", depth)
+	}
+
+	b.Body = coin.BlockBody{}
+
+	buf, err := encodeBlock(b)
+	if err != nil {
+		return err
+	}
+
+	hash := b.HashHeader()
+	return dbutil.PutBucketValue(tx, BlocksBkt, hash[:], buf)
+}
+
+// RestoreBlock stores the body of a block whose header is already stored, replacing its pruned body
+func (bt *blockTree) RestoreBlock(tx *dbutil.Tx, b *coin.Block) error {
+	hash := b.HashHeader()
+	if ok, err := dbutil.BucketHasKey(tx, BlocksBkt, hash[:]); err != nil {
+		return err
+	} else if !ok {
+		return errBlockNotExist
+	}
+
+	buf, err := encodeBlock(b)
+	if err != nil {
+		return err
+	}
+
+	return dbutil.PutBucketValue(tx, BlocksBkt, hash[:], buf)
+}
+
 // ForEachBlock iterates all blocks and calls f on them
 func (bt *blockTree) ForEachBlock(tx *dbutil.Tx, f func(b *coin.Block) error) error {
 	return dbutil.ForEach(tx, BlocksBkt, func(_, v []byte) error {
@@ -0,0 +1,73 @@
+package blockdb
+
+import (
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+)
+
+var (
+	// BlockUndoBkt holds the unspent outputs spent by each block, indexed by block sequence
+	BlockUndoBkt = []byte("block_undo")
+)
+
+// blockUndo stores the undo records of the blocks. The undo record of a block is the list of
+// unspent outputs spent by its transactions, which are restored to the unspent pool when the block is rolled back.
+type blockUndo struct{}
+
+// Get returns the unspent outputs spent by the block at seq. Returns false if no undo record exists.
+func (bu *blockUndo) Get(tx *dbutil.Tx, seq uint64) (coin.UxArray, bool, error) {
+	var uxs uxOutsWrapper
+
+	v, err := dbutil.GetBucketValueNoCopy(tx, BlockUndoBkt, dbutil.Itob(seq))
+	if err != nil {
+		return nil, false, err
+	} else if v == nil {
+		return nil, false, nil
+	}
+
+	if err := decodeUxOutsWrapperExact(v, &uxs); err != nil {
+		return nil, false, err
+	}
+
+	return uxs.UxOuts, true, nil
+}
+
+// Put saves the unspent outputs spent by the block at seq
+func (bu *blockUndo) Put(tx *dbutil.Tx, seq uint64, uxs coin.UxArray) error {
+	buf, err := encodeUxOutsWrapper(&uxOutsWrapper{
+		UxOuts: uxs,
+	})
+	if err != nil {
+		return err
+	}
+
+	return dbutil.PutBucketValue(tx, BlockUndoBkt, dbutil.Itob(seq), buf)
+}
+
+// Delete removes the undo record of the block at seq
+func (bu *blockUndo) Delete(tx *dbutil.Tx, seq uint64) error {
+	return dbutil.Delete(tx, BlockUndoBkt, dbutil.Itob(seq))
+}
+
+// DeleteTo removes the undo records of the blocks up to and including seq
+func (bu *blockUndo) DeleteTo(tx *dbutil.Tx, seq uint64) error {
+	bkt := tx.Bucket(BlockUndoBkt)
+	if bkt == nil {
+		return dbutil.NewErrBucketNotExist(BlockUndoBkt)
+	}
+
+	// The bucket must not be modified while it is iterated
+	var keys [][]byte
+	c := bkt.Cursor()
+	for k, _ := c.First(); k != nil && dbutil.Btoi(k) <= seq; k, _ = c.Next() {
+		keys = append(keys, append([]byte(nil), k...))
+	}
+
+	for _, k := range keys {
+		if err := bkt.Delete(k); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -13,11 +13,31 @@ import (
 	"github.com/skycoin/skycoin/src/visor/dbutil"
 )
 
+// MaxRollbackDepth is the number of blocks below the head block that can be rolled back.
+// The undo records of older blocks are removed.
+const MaxRollbackDepth = 10000
+
 var (
 	logger = logging.MustGetLogger("blockdb")
 
 	// ErrNoHeadBlock is returned when calling Blockchain.Head() when no head block exists
 	ErrNoHeadBlock = fmt.Errorf("found no head block")
+
+	// ErrBlockPruned is returned when the body of a requested block has been discarded by a pruned node
+	ErrBlockPruned = errors.New("block has been pruned")
+
+	// ErrBlockNotBackfilled is returned when the body of a requested block has not been downloaded yet
+	// by a node that was started from a snapshot
+	ErrBlockNotBackfilled = errors.New("block has not been downloaded yet")
+
+	// ErrBackfillBlockMismatch is returned when a backfilled block does not match the stored block header
+	ErrBackfillBlockMismatch = errors.New("backfilled block does not match the stored block header")
+
+	// ErrNoBlockUndo is returned when rolling back a block that has no undo record.
+	// Undo records are not kept for the genesis block, for pruned blocks, for the blocks before an imported snapshot,
+	// for the blocks more than MaxRollbackDepth blocks below the head block
+	// and for the blocks executed before undo records were introduced.
+	ErrNoBlockUndo = errors.New("block can not be rolled back, its undo record does not exist")
 )
 
 //go:generate skyencoder -unexported -struct Block -output-path . -package blockdb github.com/skycoin/skycoin/src/coin
@@ -25,6 +45,7 @@ var (
 //go:generate skyencoder -unexported -struct hashPairsWrapper
 //go:generate skyencoder -unexported -struct hashesWrapper
 //go:generate skyencoder -unexported -struct sigWrapper
+//go:generate skyencoder -unexported -struct uxOutsWrapper
 
 // hashesWrapper wraps []cipher.SHA256 so it can be used by skyencoder
 type hashesWrapper struct {
@@ -41,6 +62,11 @@ type hashPairsWrapper struct {
 	HashPairs []coin.HashPair
 }
 
+// uxOutsWrapper wraps []coin.UxOut so it can be used by skyencoder
+type uxOutsWrapper struct {
+	UxOuts []coin.UxOut
+}
+
 // ErrMissingSignature is returned if a block in the db does not have a corresponding signature in the db
 type ErrMissingSignature struct {
 	b *coin.Block
@@ -57,7 +83,7 @@ func (e ErrMissingSignature) Error() string {
 	return fmt.Sprintf("Signature not found for block seq=%d hash=%s", e.b.Head.BkSeq, e.b.HashHeader().Hex())
 }
 
-// CreateBuckets creates bolt.DB buckets used by the blockdb
+// CreateBuckets creates the storage buckets used by the blockdb
 func CreateBuckets(tx *dbutil.Tx) error {
 	return dbutil.CreateBuckets(tx, [][]byte{
 		BlockSigsBkt,
@@ -66,7 +92,10 @@ func CreateBuckets(tx *dbutil.Tx) error {
 		BlockchainMetaBkt,
 		UnspentPoolBkt,
 		UnspentPoolAddrIndexBkt,
+		UnspentPoolAddrBalanceBkt,
+		UnspentPoolRichlistBkt,
 		UnspentMetaBkt,
+		BlockUndoBkt,
 	})
 }
 
@@ -75,6 +104,9 @@ type BlockTree interface {
 	AddBlock(*dbutil.Tx, *coin.Block) error
 	GetBlock(*dbutil.Tx, cipher.SHA256) (*coin.Block, error)
 	GetBlockInDepth(*dbutil.Tx, uint64, Walker) (*coin.Block, error)
+	PruneBlockInDepth(*dbutil.Tx, uint64, Walker) error
+	RestoreBlock(*dbutil.Tx, *coin.Block) error
+	RemoveBlock(*dbutil.Tx, *coin.Block) error
 	ForEachBlock(*dbutil.Tx, func(*coin.Block) error) error
 }
 
@@ -82,6 +114,7 @@ type BlockTree interface {
 type BlockSigs interface {
 	Add(*dbutil.Tx, cipher.SHA256, cipher.Sig) error
 	Get(*dbutil.Tx, cipher.SHA256) (cipher.Sig, bool, error)
+	Delete(*dbutil.Tx, cipher.SHA256) error
 	ForEach(*dbutil.Tx, func(cipher.SHA256, cipher.Sig) error) error
 }
 
@@ -99,13 +132,33 @@ type UnspentPooler interface {
 	GetUnspentsOfAddrs(*dbutil.Tx, []cipher.Address) (coin.AddressUxOuts, error)
 	GetUnspentHashesOfAddrs(*dbutil.Tx, []cipher.Address) (AddressHashes, error)
 	ProcessBlock(*dbutil.Tx, *coin.SignedBlock) error
+	Import(*dbutil.Tx, coin.UxArray, uint64) error
+	Rollback(*dbutil.Tx, *coin.SignedBlock, coin.UxArray) error
 	AddressCount(*dbutil.Tx) (uint64, error)
+	BuildAddrBalanceIndex(*dbutil.Tx, uint64) error
+	AddrBalanceIndexReady(*dbutil.Tx) (bool, error)
+	GetAddrBalances(*dbutil.Tx, []cipher.Address) ([]AddressBalance, error)
+	ForEachAddrBalanceByCoins(*dbutil.Tx, func(cipher.Address, AddressBalance) error) error
+	VerifyAddrBalanceIndex(*dbutil.Tx) error
 }
 
 // ChainMeta blockchain metadata
 type ChainMeta interface {
 	GetHeadSeq(*dbutil.Tx) (uint64, bool, error)
 	SetHeadSeq(*dbutil.Tx, uint64) error
+	GetPruneSeq(*dbutil.Tx) (uint64, bool, error)
+	SetPruneSeq(*dbutil.Tx, uint64) error
+	GetBackfillRange(*dbutil.Tx) (uint64, uint64, bool, error)
+	SetBackfillRange(*dbutil.Tx, uint64, uint64) error
+	DeleteBackfillRange(*dbutil.Tx) error
+}
+
+// BlockUndo block undo record storage
+type BlockUndo interface {
+	Get(*dbutil.Tx, uint64) (coin.UxArray, bool, error)
+	Put(*dbutil.Tx, uint64, coin.UxArray) error
+	Delete(*dbutil.Tx, uint64) error
+	DeleteTo(*dbutil.Tx, uint64) error
 }
 
 // Blockchain maintain the buckets for blockchain
@@ -115,7 +168,11 @@ type Blockchain struct {
 	unspent UnspentPooler
 	tree    BlockTree
 	sigs    BlockSigs
+	undo    BlockUndo
 	walker  Walker
+
+	// rollbackDepth is the number of blocks below the head block whose undo records are kept
+	rollbackDepth uint64
 }
 
 // NewBlockchain creates a new blockchain instance
@@ -134,7 +191,10 @@ func NewBlockchain(db *dbutil.DB, walker Walker) (*Blockchain, error) {
 		meta:    &chainMeta{},
 		tree:    &blockTree{},
 		sigs:    &blockSigs{},
+		undo:    &blockUndo{},
 		walker:  walker,
+
+		rollbackDepth: MaxRollbackDepth,
 	}, nil
 }
 
@@ -163,6 +223,30 @@ func (bc *Blockchain) AddBlock(tx *dbutil.Tx, sb *coin.SignedBlock) error {
 
 // processBlock processes a block and updates the db
 func (bc *Blockchain) processBlock(tx *dbutil.Tx, b *coin.SignedBlock) error {
+	// Save the outputs spent by the block, so that it can be rolled back
+	if b.Seq() > 0 {
+		var inputs []cipher.SHA256
+		for _, txn := range b.Body.Transactions {
+			inputs = append(inputs, txn.In...)
+		}
+
+		uxs, err := bc.unspent.GetArray(tx, inputs)
+		if err != nil {
+			return err
+		}
+
+		if err := bc.undo.Put(tx, b.Seq(), uxs); err != nil {
+			return fmt.Errorf("save block undo record failed: %v", err)
+		}
+
+		// The blocks more than rollbackDepth blocks below the head block can not be rolled back
+		if b.Seq() > bc.rollbackDepth {
+			if err := bc.undo.DeleteTo(tx, b.Seq()-bc.rollbackDepth); err != nil {
+				return fmt.Errorf("delete old block undo records failed: %v", err)
+			}
+		}
+	}
+
 	if err := bc.unspent.ProcessBlock(tx, b); err != nil {
 		return err
 	}
@@ -219,6 +303,13 @@ func (bc *Blockchain) GetBlockByHash(tx *dbutil.Tx, hash cipher.SHA256) (*coin.B
 	if err != nil {
 		return nil, err
 	}
+	if b == nil {
+		return nil, nil
+	}
+
+	if err := bc.checkPruned(tx, b.Seq()); err != nil {
+		return nil, err
+	}
 
 	return b, nil
 }
@@ -233,6 +324,10 @@ func (bc *Blockchain) GetSignedBlockByHash(tx *dbutil.Tx, hash cipher.SHA256) (*
 		return nil, nil
 	}
 
+	if err := bc.checkPruned(tx, b.Seq()); err != nil {
+		return nil, err
+	}
+
 	// get signature
 	sig, ok, err := bc.sigs.Get(tx, hash)
 	if err != nil {
@@ -251,6 +346,10 @@ func (bc *Blockchain) GetSignedBlockByHash(tx *dbutil.Tx, hash cipher.SHA256) (*
 
 // GetSignedBlockBySeq returns signed block of given seq
 func (bc *Blockchain) GetSignedBlockBySeq(tx *dbutil.Tx, seq uint64) (*coin.SignedBlock, error) {
+	if err := bc.checkPruned(tx, seq); err != nil {
+		return nil, err
+	}
+
 	b, err := bc.tree.GetBlockInDepth(tx, seq, bc.walker)
 	if err != nil {
 		return nil, fmt.Errorf("bc.tree.GetBlockInDepth failed: %v", err)
@@ -274,12 +373,262 @@ func (bc *Blockchain) GetSignedBlockBySeq(tx *dbutil.Tx, seq uint64) (*coin.Sign
 	}, nil
 }
 
+// GetBlockHeaderBySeq returns the header of the block of given seq.
+// The headers of the blocks whose bodies have been pruned or not backfilled yet are available.
+func (bc *Blockchain) GetBlockHeaderBySeq(tx *dbutil.Tx, seq uint64) (*coin.BlockHeader, error) {
+	b, err := bc.tree.GetBlockInDepth(tx, seq, bc.walker)
+	if err != nil {
+		return nil, fmt.Errorf("bc.tree.GetBlockInDepth failed: %v", err)
+	}
+	if b == nil {
+		return nil, nil
+	}
+
+	return &b.Head, nil
+}
+
 // GetGenesisBlock returns genesis block
 func (bc *Blockchain) GetGenesisBlock(tx *dbutil.Tx) (*coin.SignedBlock, error) {
 	return bc.GetSignedBlockBySeq(tx, 0)
 }
 
-// ForEachBlock iterates all blocks and calls f on them
+// ForEachBlock iterates all blocks and calls f on them.
+// The blocks whose bodies have been pruned are included, with empty bodies.
 func (bc *Blockchain) ForEachBlock(tx *dbutil.Tx, f func(b *coin.Block) error) error {
 	return bc.tree.ForEachBlock(tx, f)
 }
+
+// PruneSeq returns the sequence of the most recent block whose body has been pruned.
+// Returns false if pruning is not enabled for the database.
+func (bc *Blockchain) PruneSeq(tx *dbutil.Tx) (uint64, bool, error) {
+	return bc.meta.GetPruneSeq(tx)
+}
+
+// EnablePruning marks the database as pruned.
+// Pruning can not be disabled once enabled, because the discarded block bodies can not be recovered.
+func (bc *Blockchain) EnablePruning(tx *dbutil.Tx) error {
+	_, ok, err := bc.meta.GetPruneSeq(tx)
+	if err != nil || ok {
+		return err
+	}
+
+	return bc.meta.SetPruneSeq(tx, 0)
+}
+
+// Prune discards the bodies of the blocks more than keep blocks below the head block,
+// keeping their headers and signatures, and enables pruning for the database. The genesis block is never pruned.
+// Returns the number of blocks pruned.
+func (bc *Blockchain) Prune(tx *dbutil.Tx, keep uint64) (uint64, error) {
+	pruneSeq, _, err := bc.meta.GetPruneSeq(tx)
+	if err != nil {
+		return 0, err
+	}
+
+	headSeq, ok, err := bc.meta.GetHeadSeq(tx)
+	if err != nil {
+		return 0, err
+	}
+
+	if !ok || headSeq <= keep || headSeq-keep <= pruneSeq {
+		return 0, bc.EnablePruning(tx)
+	}
+
+	target := headSeq - keep
+	for seq := pruneSeq + 1; seq <= target; seq++ {
+		if err := bc.tree.PruneBlockInDepth(tx, seq, bc.walker); err != nil {
+			return 0, fmt.Errorf("prune block %d failed: %v", seq, err)
+		}
+
+		// A pruned block can not be rolled back
+		if err := bc.undo.Delete(tx, seq); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := bc.meta.SetPruneSeq(tx, target); err != nil {
+		return 0, err
+	}
+
+	// The pruned blocks do not need to be backfilled anymore
+	start, end, ok, err := bc.meta.GetBackfillRange(tx)
+	if err != nil {
+		return 0, err
+	}
+
+	if ok && start <= target {
+		if end <= target {
+			err = bc.meta.DeleteBackfillRange(tx)
+		} else {
+			err = bc.meta.SetBackfillRange(tx, target+1, end)
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return target - pruneSeq, nil
+}
+
+// ImportSnapshot initializes an empty database from a snapshot of the blockchain.
+// blocks are the blocks from the genesis block to the snapshot block, and uxs are the unspent outputs
+// before the snapshot block is executed. Only the bodies of the genesis block and of the snapshot block are required,
+// the bodies of the blocks between them are missing until they are stored with BackfillBlock.
+func (bc *Blockchain) ImportSnapshot(tx *dbutil.Tx, blocks []coin.SignedBlock, uxs coin.UxArray) error {
+	if _, ok, err := bc.meta.GetHeadSeq(tx); err != nil {
+		return err
+	} else if ok {
+		return errors.New("can not import a snapshot into a database that has blocks")
+	}
+
+	if len(blocks) < 2 {
+		return errors.New("snapshot must include the genesis block and at least one block after it")
+	}
+
+	sb := &blocks[len(blocks)-1]
+	for i := range blocks[:len(blocks)-1] {
+		b := &blocks[i]
+		if err := bc.sigs.Add(tx, b.HashHeader(), b.Sig); err != nil {
+			return fmt.Errorf("save signature failed: %v", err)
+		}
+
+		if err := bc.tree.AddBlock(tx, &b.Block); err != nil {
+			return fmt.Errorf("save block failed: %v", err)
+		}
+	}
+
+	if err := bc.unspent.Import(tx, uxs, sb.Seq()-1); err != nil {
+		return err
+	}
+
+	uxHash, err := bc.unspent.GetUxHash(tx)
+	if err != nil {
+		return err
+	}
+
+	if uxHash != sb.Head.UxHash {
+		return errors.New("snapshot unspent outputs do not match the UxHash of the snapshot block")
+	}
+
+	if err := bc.AddBlock(tx, sb); err != nil {
+		return err
+	}
+
+	if sb.Seq() == 1 {
+		return nil
+	}
+
+	return bc.meta.SetBackfillRange(tx, 1, sb.Seq()-1)
+}
+
+// BackfillRange returns the inclusive range of block sequences whose bodies are missing
+// after importing a snapshot. Returns false if no block needs to be backfilled.
+func (bc *Blockchain) BackfillRange(tx *dbutil.Tx) (uint64, uint64, bool, error) {
+	return bc.meta.GetBackfillRange(tx)
+}
+
+// BackfillBlock stores the body of the first block of the backfill range.
+// The block must match the block header that was stored when importing the snapshot.
+func (bc *Blockchain) BackfillBlock(tx *dbutil.Tx, b *coin.SignedBlock) error {
+	start, end, ok, err := bc.meta.GetBackfillRange(tx)
+	if err != nil {
+		return err
+	} else if !ok {
+		return errors.New("no block needs to be backfilled")
+	}
+
+	if b.Seq() != start {
+		return fmt.Errorf("expected to backfill block %d, got block %d", start, b.Seq())
+	}
+
+	stored, err := bc.tree.GetBlockInDepth(tx, start, bc.walker)
+	if err != nil {
+		return err
+	} else if stored == nil {
+		return fmt.Errorf("no block exists in depth: %d", start)
+	}
+
+	if stored.HashHeader() != b.HashHeader() || b.Body.Hash() != b.Head.BodyHash {
+		return ErrBackfillBlockMismatch
+	}
+
+	if err := bc.tree.RestoreBlock(tx, &b.Block); err != nil {
+		return err
+	}
+
+	if start == end {
+		return bc.meta.DeleteBackfillRange(tx)
+	}
+
+	return bc.meta.SetBackfillRange(tx, start+1, end)
+}
+
+// RollbackHead removes the head block from the blockchain, restoring the unspent outputs that it spent
+// and removing the unspent outputs that it created. Returns the removed block.
+// The genesis block can not be rolled back.
+func (bc *Blockchain) RollbackHead(tx *dbutil.Tx) (*coin.SignedBlock, error) {
+	b, err := bc.Head(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	if b.Seq() == 0 {
+		return nil, errors.New("the genesis block can not be rolled back")
+	}
+
+	uxs, ok, err := bc.undo.Get(tx, b.Seq())
+	if err != nil {
+		return nil, err
+	} else if !ok {
+		return nil, ErrNoBlockUndo
+	}
+
+	if err := bc.unspent.Rollback(tx, b, uxs); err != nil {
+		return nil, err
+	}
+
+	if err := bc.tree.RemoveBlock(tx, &b.Block); err != nil {
+		return nil, fmt.Errorf("remove block failed: %v", err)
+	}
+
+	if err := bc.sigs.Delete(tx, b.HashHeader()); err != nil {
+		return nil, fmt.Errorf("remove signature failed: %v", err)
+	}
+
+	if err := bc.undo.Delete(tx, b.Seq()); err != nil {
+		return nil, err
+	}
+
+	if err := bc.meta.SetHeadSeq(tx, b.Seq()-1); err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// checkPruned returns ErrBlockPruned if the body of the block at seq has been pruned,
+// or ErrBlockNotBackfilled if it has not been backfilled yet
+func (bc *Blockchain) checkPruned(tx *dbutil.Tx, seq uint64) error {
+	if seq == 0 {
+		return nil
+	}
+
+	pruneSeq, ok, err := bc.meta.GetPruneSeq(tx)
+	if err != nil {
+		return err
+	}
+
+	if ok && seq <= pruneSeq {
+		return ErrBlockPruned
+	}
+
+	start, end, ok, err := bc.meta.GetBackfillRange(tx)
+	if err != nil {
+		return err
+	}
+
+	if ok && seq >= start && seq <= end {
+		return ErrBlockNotBackfilled
+	}
+
+	return nil
+}
@@ -104,6 +104,32 @@ func (bt *fakeBlockTree) GetBlockInDepth(tx *dbutil.Tx, depth uint64, filter Wal
 	return nil, nil
 }
 
+func (bt *fakeBlockTree) PruneBlockInDepth(tx *dbutil.Tx, depth uint64, filter Walker) error {
+	for _, b := range bt.blocks {
+		if b.Head.BkSeq == depth {
+			b.Body = coin.BlockBody{}
+			return nil
+		}
+	}
+
+	return fmt.Errorf("no block exists in depth: %d", depth)
+}
+
+func (bt *fakeBlockTree) RestoreBlock(tx *dbutil.Tx, b *coin.Block) error {
+	hash := b.HashHeader().Hex()
+	if _, ok := bt.blocks[hash]; !ok {
+		return errors.New("block does not exist")
+	}
+
+	bt.blocks[hash] = b
+	return nil
+}
+
+func (bt *fakeBlockTree) RemoveBlock(tx *dbutil.Tx, b *coin.Block) error {
+	delete(bt.blocks, b.HashHeader().Hex())
+	return nil
+}
+
 func (bt *fakeBlockTree) ForEachBlock(tx *dbutil.Tx, f func(*coin.Block) error) error {
 	return nil
 }
@@ -150,6 +176,11 @@ func (ss *fakeSignatureStore) Get(tx *dbutil.Tx, hash cipher.SHA256) (cipher.Sig
 	return sig, ok, nil
 }
 
+func (ss *fakeSignatureStore) Delete(tx *dbutil.Tx, hash cipher.SHA256) error {
+	delete(ss.sigs, hash.Hex())
+	return nil
+}
+
 func (ss *fakeSignatureStore) ForEach(tx *dbutil.Tx, f func(cipher.SHA256, cipher.Sig) error) error {
 	return nil
 }
@@ -253,6 +284,18 @@ func (fup *fakeUnspentPool) ProcessBlock(tx *dbutil.Tx, b *coin.SignedBlock) err
 	return nil
 }
 
+func (fup *fakeUnspentPool) Import(tx *dbutil.Tx, uxs coin.UxArray, height uint64) error {
+	for _, ux := range uxs {
+		fup.outs[ux.Hash()] = ux
+		fup.uxHash = fup.uxHash.Xor(ux.SnapshotHash())
+	}
+	return nil
+}
+
+func (fup *fakeUnspentPool) Rollback(tx *dbutil.Tx, b *coin.SignedBlock, spent coin.UxArray) error {
+	return nil
+}
+
 func (fup *fakeUnspentPool) Contains(tx *dbutil.Tx, h cipher.SHA256) (bool, error) {
 	_, ok := fup.outs[h]
 	return ok, nil
@@ -267,9 +310,34 @@ func (fup *fakeUnspentPool) AddressCount(tx *dbutil.Tx) (uint64, error) {
 	return uint64(len(addrs)), nil
 }
 
+func (fup *fakeUnspentPool) BuildAddrBalanceIndex(tx *dbutil.Tx, headSeq uint64) error {
+	return nil
+}
+
+func (fup *fakeUnspentPool) AddrBalanceIndexReady(tx *dbutil.Tx) (bool, error) {
+	return false, nil
+}
+
+func (fup *fakeUnspentPool) GetAddrBalances(tx *dbutil.Tx, addrs []cipher.Address) ([]AddressBalance, error) {
+	return nil, nil
+}
+
+func (fup *fakeUnspentPool) ForEachAddrBalanceByCoins(tx *dbutil.Tx, f func(cipher.Address, AddressBalance) error) error {
+	return nil
+}
+
+func (fup *fakeUnspentPool) VerifyAddrBalanceIndex(tx *dbutil.Tx) error {
+	return nil
+}
+
 type fakeChainMeta struct {
-	headSeq   uint64
-	didSetSeq bool
+	headSeq        uint64
+	didSetSeq      bool
+	pruneSeq       uint64
+	didSetPruneSeq bool
+	backfillStart  uint64
+	backfillEnd    uint64
+	didSetBackfill bool
 }
 
 func newFakeChainMeta() *fakeChainMeta {
@@ -290,6 +358,32 @@ func (fcm *fakeChainMeta) SetHeadSeq(tx *dbutil.Tx, seq uint64) error {
 	return nil
 }
 
+func (fcm *fakeChainMeta) GetPruneSeq(tx *dbutil.Tx) (uint64, bool, error) {
+	return fcm.pruneSeq, fcm.didSetPruneSeq, nil
+}
+
+func (fcm *fakeChainMeta) SetPruneSeq(tx *dbutil.Tx, seq uint64) error {
+	fcm.pruneSeq = seq
+	fcm.didSetPruneSeq = true
+	return nil
+}
+
+func (fcm *fakeChainMeta) GetBackfillRange(tx *dbutil.Tx) (uint64, uint64, bool, error) {
+	return fcm.backfillStart, fcm.backfillEnd, fcm.didSetBackfill, nil
+}
+
+func (fcm *fakeChainMeta) SetBackfillRange(tx *dbutil.Tx, start, end uint64) error {
+	fcm.backfillStart = start
+	fcm.backfillEnd = end
+	fcm.didSetBackfill = true
+	return nil
+}
+
+func (fcm *fakeChainMeta) DeleteBackfillRange(tx *dbutil.Tx) error {
+	fcm.didSetBackfill = false
+	return nil
+}
+
 func DefaultWalker(tx *dbutil.Tx, hps []coin.HashPair) (cipher.SHA256, bool) {
 	return hps[0].Hash, true
 }
@@ -615,3 +709,327 @@ func TestBlockchainGetBlockByHash(t *testing.T) {
 		})
 	}
 }
+
+// makeChain creates n signed blocks after gb, the last of which has the given UxHash
+func makeChain(t *testing.T, gb coin.SignedBlock, n uint64, uxHash cipher.SHA256) []coin.SignedBlock {
+	blocks := []coin.SignedBlock{gb}
+	for i := uint64(1); i <= n; i++ {
+		b := coin.Block{
+			Head: coin.BlockHeader{
+				BkSeq:    i,
+				Time:     genTime + i,
+				PrevHash: blocks[i-1].HashHeader(),
+			},
+			Body: coin.BlockBody{
+				Transactions: coin.Transactions{
+					{
+						Length: uint32(i),
+					},
+				},
+			},
+		}
+		if i == n {
+			b.Head.UxHash = uxHash
+		}
+		b.Head.BodyHash = b.Body.Hash()
+		blocks = append(blocks, coin.SignedBlock{
+			Block: b,
+			Sig:   cipher.MustSignHash(b.HashHeader(), genSecret),
+		})
+	}
+
+	return blocks
+}
+
+func TestBlockchainPrune(t *testing.T) {
+	db, closeDB := prepareDB(t)
+	defer closeDB()
+
+	bc, err := NewBlockchain(db, DefaultWalker)
+	require.NoError(t, err)
+	bc.unspent = newFakeUnspentPool(nil)
+
+	gb := makeGenesisBlock(t)
+	blocks := makeChain(t, gb, 5, cipher.SHA256{})
+
+	err = db.Update("", func(tx *dbutil.Tx) error {
+		for i := range blocks {
+			require.NoError(t, bc.AddBlock(tx, &blocks[i]))
+		}
+
+		_, ok, err := bc.PruneSeq(tx)
+		require.NoError(t, err)
+		require.False(t, ok)
+
+		// Nothing is pruned when the chain is not longer than the number of kept blocks
+		n, err := bc.Prune(tx, 5)
+		require.NoError(t, err)
+		require.Equal(t, uint64(0), n)
+
+		pruneSeq, ok, err := bc.PruneSeq(tx)
+		require.NoError(t, err)
+		require.True(t, ok)
+		require.Equal(t, uint64(0), pruneSeq)
+
+		n, err = bc.Prune(tx, 2)
+		require.NoError(t, err)
+		require.Equal(t, uint64(3), n)
+
+		pruneSeq, ok, err = bc.PruneSeq(tx)
+		require.NoError(t, err)
+		require.True(t, ok)
+		require.Equal(t, uint64(3), pruneSeq)
+
+		// Pruning again does nothing until the chain grows
+		n, err = bc.Prune(tx, 2)
+		require.NoError(t, err)
+		require.Equal(t, uint64(0), n)
+
+		// The genesis block and the recent blocks are kept
+		b, err := bc.GetGenesisBlock(tx)
+		require.NoError(t, err)
+		require.Equal(t, gb, *b)
+
+		for _, i := range []uint64{4, 5} {
+			b, err := bc.GetSignedBlockBySeq(tx, i)
+			require.NoError(t, err)
+			require.Equal(t, blocks[i], *b)
+		}
+
+		head, err := bc.Head(tx)
+		require.NoError(t, err)
+		require.Equal(t, blocks[5], *head)
+
+		// The bodies of the pruned blocks are not available
+		for _, i := range []uint64{1, 2, 3} {
+			_, err := bc.GetSignedBlockBySeq(tx, i)
+			require.Equal(t, ErrBlockPruned, err)
+
+			_, err = bc.GetSignedBlockByHash(tx, blocks[i].HashHeader())
+			require.Equal(t, ErrBlockPruned, err)
+
+			_, err = bc.GetBlockByHash(tx, blocks[i].HashHeader())
+			require.Equal(t, ErrBlockPruned, err)
+		}
+
+		// The headers and signatures of the pruned blocks are kept
+		var headers []coin.BlockHeader
+		require.NoError(t, bc.ForEachBlock(tx, func(b *coin.Block) error {
+			if b.Seq() >= 1 && b.Seq() <= 3 {
+				require.Empty(t, b.Body.Transactions)
+			}
+
+			sig, ok, err := bc.GetBlockSignature(tx, b)
+			require.NoError(t, err)
+			require.True(t, ok)
+			require.NoError(t, cipher.VerifyPubKeySignedHash(genPublic, sig, b.HashHeader()))
+
+			headers = append(headers, b.Head)
+			return nil
+		}))
+		require.Len(t, headers, len(blocks))
+
+		return nil
+	})
+	require.NoError(t, err)
+}
+
+func TestBlockchainImportSnapshot(t *testing.T) {
+	db, closeDB := prepareDB(t)
+	defer closeDB()
+
+	bc, err := NewBlockchain(db, DefaultWalker)
+	require.NoError(t, err)
+	bc.unspent = newFakeUnspentPool(nil)
+
+	uxs := coin.UxArray{makeUxOut(t), makeUxOut(t)}
+	uxHash := uxs[0].SnapshotHash()
+	uxHash = uxHash.Xor(uxs[1].SnapshotHash())
+
+	gb := makeGenesisBlock(t)
+	blocks := makeChain(t, gb, 5, uxHash)
+
+	// Only the bodies of the genesis block and the snapshot block are imported
+	snapshot := make([]coin.SignedBlock, len(blocks))
+	copy(snapshot, blocks)
+	for i := 1; i < 5; i++ {
+		snapshot[i].Body = coin.BlockBody{}
+	}
+
+	err = db.Update("", func(tx *dbutil.Tx) error {
+		require.NoError(t, bc.ImportSnapshot(tx, snapshot, uxs))
+
+		head, err := bc.Head(tx)
+		require.NoError(t, err)
+		require.Equal(t, blocks[5], *head)
+
+		b, err := bc.GetGenesisBlock(tx)
+		require.NoError(t, err)
+		require.Equal(t, gb, *b)
+
+		start, end, ok, err := bc.BackfillRange(tx)
+		require.NoError(t, err)
+		require.True(t, ok)
+		require.Equal(t, uint64(1), start)
+		require.Equal(t, uint64(4), end)
+
+		_, err = bc.GetSignedBlockBySeq(tx, 2)
+		require.Equal(t, ErrBlockNotBackfilled, err)
+
+		// The blocks must be backfilled in order
+		err = bc.BackfillBlock(tx, &blocks[2])
+		require.Error(t, err)
+
+		// The backfilled block must match the stored header
+		bad := blocks[1]
+		bad.Body = coin.BlockBody{}
+		err = bc.BackfillBlock(tx, &bad)
+		require.Equal(t, ErrBackfillBlockMismatch, err)
+
+		for i := 1; i < 5; i++ {
+			require.NoError(t, bc.BackfillBlock(tx, &blocks[i]))
+		}
+
+		_, _, ok, err = bc.BackfillRange(tx)
+		require.NoError(t, err)
+		require.False(t, ok)
+
+		for i := range blocks {
+			b, err := bc.GetSignedBlockBySeq(tx, uint64(i))
+			require.NoError(t, err)
+			require.Equal(t, blocks[i], *b)
+		}
+
+		// A snapshot can only be imported into an empty database
+		err = bc.ImportSnapshot(tx, snapshot, uxs)
+		require.Error(t, err)
+
+		return nil
+	})
+	require.NoError(t, err)
+}
+
+func TestBlockchainImportSnapshotUxHashMismatch(t *testing.T) {
+	db, closeDB := prepareDB(t)
+	defer closeDB()
+
+	bc, err := NewBlockchain(db, DefaultWalker)
+	require.NoError(t, err)
+	bc.unspent = newFakeUnspentPool(nil)
+
+	uxs := coin.UxArray{makeUxOut(t), makeUxOut(t)}
+	blocks := makeChain(t, makeGenesisBlock(t), 2, uxs[0].SnapshotHash())
+
+	err = db.Update("", func(tx *dbutil.Tx) error {
+		return bc.ImportSnapshot(tx, blocks, uxs)
+	})
+	require.EqualError(t, err, "snapshot unspent outputs do not match the UxHash of the snapshot block")
+}
+
+func TestBlockchainRollbackHead(t *testing.T) {
+	db, closeDB := prepareDB(t)
+	defer closeDB()
+
+	bc, err := NewBlockchain(db, DefaultWalker)
+	require.NoError(t, err)
+	bc.unspent = newFakeUnspentPool(nil)
+
+	gb := makeGenesisBlock(t)
+	blocks := makeChain(t, gb, 5, cipher.SHA256{})
+
+	err = db.Update("", func(tx *dbutil.Tx) error {
+		for i := range blocks {
+			require.NoError(t, bc.AddBlock(tx, &blocks[i]))
+		}
+
+		for _, i := range []uint64{5, 4} {
+			b, err := bc.RollbackHead(tx)
+			require.NoError(t, err)
+			require.Equal(t, blocks[i], *b)
+
+			headSeq, ok, err := bc.HeadSeq(tx)
+			require.NoError(t, err)
+			require.True(t, ok)
+			require.Equal(t, i-1, headSeq)
+
+			_, ok, err = bc.GetBlockSignature(tx, &blocks[i].Block)
+			require.NoError(t, err)
+			require.False(t, ok)
+
+			b, err = bc.GetSignedBlockBySeq(tx, i)
+			require.NoError(t, err)
+			require.Nil(t, b)
+
+			_, ok, err = bc.undo.Get(tx, i)
+			require.NoError(t, err)
+			require.False(t, ok)
+		}
+
+		// The rolled back blocks can be added again
+		require.NoError(t, bc.AddBlock(tx, &blocks[4]))
+
+		head, err := bc.Head(tx)
+		require.NoError(t, err)
+		require.Equal(t, blocks[4], *head)
+
+		// Pruned blocks can not be rolled back
+		_, err = bc.Prune(tx, 1)
+		require.NoError(t, err)
+
+		_, err = bc.RollbackHead(tx)
+		require.NoError(t, err)
+
+		_, err = bc.RollbackHead(tx)
+		require.Equal(t, ErrBlockPruned, err)
+
+		return nil
+	})
+	require.NoError(t, err)
+
+	// The blocks below the rollback depth can not be rolled back
+	db3, closeDB3 := prepareDB(t)
+	defer closeDB3()
+
+	bc, err = NewBlockchain(db3, DefaultWalker)
+	require.NoError(t, err)
+	bc.unspent = newFakeUnspentPool(nil)
+	bc.rollbackDepth = 2
+
+	err = db3.Update("", func(tx *dbutil.Tx) error {
+		for i := range blocks {
+			require.NoError(t, bc.AddBlock(tx, &blocks[i]))
+		}
+
+		for i := uint64(1); i <= 5; i++ {
+			_, ok, err := bc.undo.Get(tx, i)
+			require.NoError(t, err)
+			require.Equal(t, i > 3, ok)
+		}
+
+		for i := 0; i < 2; i++ {
+			_, err := bc.RollbackHead(tx)
+			require.NoError(t, err)
+		}
+
+		_, err := bc.RollbackHead(tx)
+		require.Equal(t, ErrNoBlockUndo, err)
+		return nil
+	})
+	require.NoError(t, err)
+
+	// The genesis block can not be rolled back
+	db2, closeDB2 := prepareDB(t)
+	defer closeDB2()
+
+	bc, err = NewBlockchain(db2, DefaultWalker)
+	require.NoError(t, err)
+
+	err = db2.Update("", func(tx *dbutil.Tx) error {
+		require.NoError(t, bc.AddBlock(tx, &gb))
+
+		_, err := bc.RollbackHead(tx)
+		require.EqualError(t, err, "the genesis block can not be rolled back")
+		return nil
+	})
+	require.NoError(t, err)
+}
@@ -67,3 +67,8 @@ func (bs *blockSigs) ForEach(tx *dbutil.Tx, f func(cipher.SHA256, cipher.Sig) er
 		return f(hash, sig.Sig)
 	})
 }
+
+// Delete removes the signature of a block
+func (bs *blockSigs) Delete(tx *dbutil.Tx, hash cipher.SHA256) error {
+	return dbutil.Delete(tx, BlockSigsBkt, hash[:])
+}
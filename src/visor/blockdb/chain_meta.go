@@ -1,6 +1,8 @@
 package blockdb
 
 import (
+	"errors"
+
 	"github.com/skycoin/skycoin/src/visor/dbutil"
 )
 
@@ -9,6 +11,10 @@ var (
 	BlockchainMetaBkt = []byte("blockchain_meta")
 	// blockchain head sequence number
 	headSeqKey = []byte("head_seq")
+	// sequence number of the most recent block whose body has been pruned
+	pruneSeqKey = []byte("prune_seq")
+	// inclusive range of block sequence numbers whose bodies are missing after importing a snapshot
+	backfillRangeKey = []byte("backfill_range")
 )
 
 type chainMeta struct{}
@@ -27,3 +33,42 @@ func (m chainMeta) GetHeadSeq(tx *dbutil.Tx) (uint64, bool, error) {
 
 	return dbutil.Btoi(v), true, nil
 }
+
+func (m chainMeta) SetPruneSeq(tx *dbutil.Tx, seq uint64) error {
+	return dbutil.PutBucketValue(tx, BlockchainMetaBkt, pruneSeqKey, dbutil.Itob(seq))
+}
+
+func (m chainMeta) GetPruneSeq(tx *dbutil.Tx) (uint64, bool, error) {
+	v, err := dbutil.GetBucketValue(tx, BlockchainMetaBkt, pruneSeqKey)
+	if err != nil {
+		return 0, false, err
+	} else if v == nil {
+		return 0, false, nil
+	}
+
+	return dbutil.Btoi(v), true, nil
+}
+
+func (m chainMeta) SetBackfillRange(tx *dbutil.Tx, start, end uint64) error {
+	v := append(dbutil.Itob(start), dbutil.Itob(end)...)
+	return dbutil.PutBucketValue(tx, BlockchainMetaBkt, backfillRangeKey, v)
+}
+
+func (m chainMeta) GetBackfillRange(tx *dbutil.Tx) (uint64, uint64, bool, error) {
+	v, err := dbutil.GetBucketValue(tx, BlockchainMetaBkt, backfillRangeKey)
+	if err != nil {
+		return 0, 0, false, err
+	} else if v == nil {
+		return 0, 0, false, nil
+	}
+
+	if len(v) != 16 {
+		return 0, 0, false, errors.New("invalid backfill range length")
+	}
+
+	return dbutil.Btoi(v[:8]), dbutil.Btoi(v[8:]), true, nil
+}
+
+func (m chainMeta) DeleteBackfillRange(tx *dbutil.Tx) error {
+	return dbutil.Delete(tx, BlockchainMetaBkt, backfillRangeKey)
+}
@@ -91,6 +91,21 @@ func (m *unspentMeta) setAddrIndexHeight(tx *dbutil.Tx, height uint64) error {
 	return dbutil.PutBucketValue(tx, UnspentMetaBkt, addrIndexHeightKey, dbutil.Itob(height))
 }
 
+func (m *unspentMeta) getAddrBalanceIndexHeight(tx *dbutil.Tx) (uint64, bool, error) {
+	v, err := dbutil.GetBucketValue(tx, UnspentMetaBkt, addrBalanceIndexHeightKey)
+	if err != nil {
+		return 0, false, err
+	} else if v == nil {
+		return 0, false, nil
+	}
+
+	return dbutil.Btoi(v), true, nil
+}
+
+func (m *unspentMeta) setAddrBalanceIndexHeight(tx *dbutil.Tx, height uint64) error {
+	return dbutil.PutBucketValue(tx, UnspentMetaBkt, addrBalanceIndexHeightKey, dbutil.Itob(height))
+}
+
 type pool struct{}
 
 func (pl pool) get(tx *dbutil.Tx, hash cipher.SHA256) (*coin.UxOut, error) {
@@ -252,17 +267,19 @@ func (p poolAddrIndex) adjust(tx *dbutil.Tx, addr cipher.Address, addHashes, rmH
 
 // Unspents unspent outputs pool
 type Unspents struct {
-	pool          *pool
-	poolAddrIndex *poolAddrIndex
-	meta          *unspentMeta
+	pool             *pool
+	poolAddrIndex    *poolAddrIndex
+	addrBalanceIndex *addrBalanceIndex
+	meta             *unspentMeta
 }
 
 // NewUnspentPool creates new unspent pool instance
 func NewUnspentPool() *Unspents {
 	return &Unspents{
-		pool:          &pool{},
-		poolAddrIndex: &poolAddrIndex{},
-		meta:          &unspentMeta{},
+		pool:             &pool{},
+		poolAddrIndex:    &poolAddrIndex{},
+		addrBalanceIndex: &addrBalanceIndex{},
+		meta:             &unspentMeta{},
 	}
 }
 
@@ -427,6 +444,22 @@ func (up *Unspents) ProcessBlock(tx *dbutil.Tx, b *coin.SignedBlock) error {
 		}
 	}
 
+	// Update the address balance index, unless it has not been built by the schema migration yet
+	addrBalanceHeight, ok, err := up.meta.getAddrBalanceIndexHeight(tx)
+	if err != nil {
+		return err
+	}
+
+	if (b.Block.Head.BkSeq == 0 && !ok) || (ok && addrBalanceHeight+1 == b.Block.Head.BkSeq) {
+		if err := up.adjustAddrBalances(tx, txnUxs, uxs); err != nil {
+			return err
+		}
+
+		if err := up.meta.setAddrBalanceIndexHeight(tx, b.Block.Head.BkSeq); err != nil {
+			return err
+		}
+	}
+
 	// Check that the addrIndexHeight is incremental
 	addrIndexHeight, ok, err := up.meta.getAddrIndexHeight(tx)
 	if err != nil {
@@ -449,6 +482,147 @@ func (up *Unspents) ProcessBlock(tx *dbutil.Tx, b *coin.SignedBlock) error {
 	return up.meta.setAddrIndexHeight(tx, b.Block.Head.BkSeq)
 }
 
+// Import fills an empty unspent pool with the unspent outputs of the blockchain at height
+func (up *Unspents) Import(tx *dbutil.Tx, uxs coin.UxArray, height uint64) error {
+	if empty, err := dbutil.IsEmpty(tx, UnspentPoolBkt); err != nil {
+		return err
+	} else if !empty {
+		return errors.New("unspent pool is not empty")
+	}
+
+	var xorHash cipher.SHA256
+	for _, ux := range uxs {
+		h := ux.Hash()
+
+		if hasKey, err := up.Contains(tx, h); err != nil {
+			return err
+		} else if hasKey {
+			return fmt.Errorf("attempted to insert uxout:%v twice into the unspent pool", h.Hex())
+		}
+
+		if err := up.pool.put(tx, h, ux); err != nil {
+			return err
+		}
+
+		xorHash = xorHash.Xor(ux.SnapshotHash())
+	}
+
+	if err := up.meta.setXorHash(tx, xorHash); err != nil {
+		return err
+	}
+
+	if err := up.buildAddrIndex(tx); err != nil {
+		return err
+	}
+
+	if err := up.BuildAddrBalanceIndex(tx, height); err != nil {
+		return err
+	}
+
+	return up.meta.setAddrIndexHeight(tx, height)
+}
+
+// Rollback reverts the changes that ProcessBlock made for the head block b.
+// spent are the unspent outputs that b spent, which are restored to the pool,
+// and the unspent outputs that b created are removed from the pool.
+func (up *Unspents) Rollback(tx *dbutil.Tx, b *coin.SignedBlock, spent coin.UxArray) error {
+	addrIndexHeight, ok, err := up.meta.getAddrIndexHeight(tx)
+	if err != nil {
+		return err
+	}
+
+	if b.Block.Head.BkSeq == 0 || !ok || addrIndexHeight != b.Block.Head.BkSeq {
+		err := errors.New("unspent pool rolling back a block that is not the head block")
+		logger.Critical().Error(err.Error())
+		return err
+	}
+
+	xorHash, err := up.meta.getXorHash(tx)
+	if err != nil {
+		return err
+	}
+
+	// Remove the outputs created by the block
+	rmAddrHashes := make(map[cipher.Address][]cipher.SHA256)
+	var created coin.UxArray
+	for _, txn := range b.Body.Transactions {
+		for _, ux := range coin.CreateUnspents(b.Head, txn) {
+			created = append(created, ux)
+
+			h := ux.Hash()
+
+			if hasKey, err := up.Contains(tx, h); err != nil {
+				return err
+			} else if !hasKey {
+				return NewErrUnspentNotExist(h.Hex())
+			}
+
+			if err := up.pool.delete(tx, h); err != nil {
+				return err
+			}
+
+			xorHash = xorHash.Xor(ux.SnapshotHash())
+			rmAddrHashes[ux.Body.Address] = append(rmAddrHashes[ux.Body.Address], h)
+		}
+	}
+
+	// Restore the outputs spent by the block
+	addAddrHashes := make(map[cipher.Address][]cipher.SHA256)
+	for _, ux := range spent {
+		h := ux.Hash()
+
+		if hasKey, err := up.Contains(tx, h); err != nil {
+			return err
+		} else if hasKey {
+			return fmt.Errorf("attempted to insert uxout:%v twice into the unspent pool", h.Hex())
+		}
+
+		if err := up.pool.put(tx, h, ux); err != nil {
+			return err
+		}
+
+		xorHash = xorHash.Xor(ux.SnapshotHash())
+		addAddrHashes[ux.Body.Address] = append(addAddrHashes[ux.Body.Address], h)
+	}
+
+	if err := up.meta.setXorHash(tx, xorHash); err != nil {
+		return err
+	}
+
+	// Update indexes
+	for addr, rmHashes := range rmAddrHashes {
+		if err := up.poolAddrIndex.adjust(tx, addr, addAddrHashes[addr], rmHashes); err != nil {
+			return err
+		}
+
+		delete(addAddrHashes, addr)
+	}
+
+	for addr, addHashes := range addAddrHashes {
+		if err := up.poolAddrIndex.adjust(tx, addr, addHashes, nil); err != nil {
+			return err
+		}
+	}
+
+	// Update the address balance index, unless it has not been built by the schema migration yet
+	addrBalanceHeight, ok, err := up.meta.getAddrBalanceIndexHeight(tx)
+	if err != nil {
+		return err
+	}
+
+	if ok && addrBalanceHeight == b.Block.Head.BkSeq {
+		if err := up.adjustAddrBalances(tx, spent, created); err != nil {
+			return err
+		}
+
+		if err := up.meta.setAddrBalanceIndexHeight(tx, b.Block.Head.BkSeq-1); err != nil {
+			return err
+		}
+	}
+
+	return up.meta.setAddrIndexHeight(tx, b.Block.Head.BkSeq-1)
+}
+
 // GetArray returns UxOut for a set of hashes, will return error if any of the hashes do not exist in the pool.
 func (up *Unspents) GetArray(tx *dbutil.Tx, hashes []cipher.SHA256) (coin.UxArray, error) {
 	var uxa coin.UxArray
@@ -535,7 +709,14 @@ func (up *Unspents) GetUxHash(tx *dbutil.Tx) (cipher.SHA256, error) {
 	return up.meta.getXorHash(tx)
 }
 
-// AddressCount returns the total number of addresses with unspents
+// AddressCount returns the total number of addresses with unspents.
+// The count is kept by the address balance index, if it is not up to date the address index is counted.
 func (up *Unspents) AddressCount(tx *dbutil.Tx) (uint64, error) {
+	if ready, err := up.AddrBalanceIndexReady(tx); err != nil {
+		return 0, err
+	} else if ready {
+		return up.addrBalanceIndex.getCount(tx)
+	}
+
 	return dbutil.Len(tx, UnspentPoolAddrIndexBkt)
 }
@@ -1153,3 +1153,141 @@ func TestAddressHashesFlatten(t *testing.T) {
 	require.Equal(t, len(expectedHashes), len(flattenedHashes))
 	require.Equal(t, expectedHashes, flattenedHashes)
 }
+
+func TestUnspentImport(t *testing.T) {
+	db, closedb := prepareDB(t)
+	defer closedb()
+
+	up := NewUnspentPool()
+
+	var uxs coin.UxArray
+	var xorHash cipher.SHA256
+	for i := 0; i < 5; i++ {
+		ux := makeUxOut(t)
+		uxs = append(uxs, ux)
+		xorHash = xorHash.Xor(ux.SnapshotHash())
+	}
+
+	err := db.Update("", func(tx *dbutil.Tx) error {
+		require.NoError(t, up.Import(tx, uxs, 4))
+
+		uxHash, err := up.GetUxHash(tx)
+		require.NoError(t, err)
+		require.Equal(t, xorHash, uxHash)
+
+		height, ok, err := up.meta.getAddrIndexHeight(tx)
+		require.NoError(t, err)
+		require.True(t, ok)
+		require.Equal(t, uint64(4), height)
+
+		addrUxs, err := up.GetUnspentsOfAddrs(tx, []cipher.Address{uxs[0].Body.Address})
+		require.NoError(t, err)
+		require.Equal(t, coin.UxArray{uxs[0]}, addrUxs[uxs[0].Body.Address])
+		return nil
+	})
+	require.NoError(t, err)
+
+	err = db.Update("", func(tx *dbutil.Tx) error {
+		n, err := up.Len(tx)
+		require.NoError(t, err)
+		require.Equal(t, uint64(len(uxs)), n)
+
+		// A pool that is not empty can not be imported into
+		err = up.Import(tx, coin.UxArray{makeUxOut(t)}, 4)
+		require.Error(t, err)
+		return nil
+	})
+	require.NoError(t, err)
+}
+
+func TestUnspentRollback(t *testing.T) {
+	db, closedb := prepareDB(t)
+	defer closedb()
+
+	up := NewUnspentPool()
+
+	var uxs coin.UxArray
+	for i := 0; i < 5; i++ {
+		uxs = append(uxs, makeUxOut(t))
+	}
+
+	addr := testutil.MakeAddress()
+	addrs := []cipher.Address{addr}
+	for _, ux := range uxs {
+		addrs = append(addrs, ux.Body.Address)
+	}
+
+	txn := coin.Transaction{}
+	for _, in := range uxs[:2] {
+		require.NoError(t, txn.PushInput(in.Hash()))
+	}
+	require.NoError(t, txn.PushOutput(addr, 1e6, 50))
+	require.NoError(t, txn.PushOutput(uxs[0].Body.Address, 1e6, 50))
+
+	type poolState struct {
+		uxs     coin.UxArray
+		uxHash  cipher.SHA256
+		addrUxs coin.AddressUxOuts
+	}
+
+	getState := func(tx *dbutil.Tx) poolState {
+		all, err := up.GetAll(tx)
+		require.NoError(t, err)
+		all.Sort()
+
+		uxHash, err := up.GetUxHash(tx)
+		require.NoError(t, err)
+
+		addrUxs, err := up.GetUnspentsOfAddrs(tx, addrs)
+		require.NoError(t, err)
+		for a, uxa := range addrUxs {
+			uxa.Sort()
+			addrUxs[a] = uxa
+		}
+
+		return poolState{
+			uxs:     all,
+			uxHash:  uxHash,
+			addrUxs: addrUxs,
+		}
+	}
+
+	var before poolState
+	var b *coin.SignedBlock
+	err := db.Update("", func(tx *dbutil.Tx) error {
+		require.NoError(t, up.Import(tx, uxs, 0))
+
+		before = getState(tx)
+
+		block, err := coin.NewBlock(coin.Block{}, uint64(time.Now().Unix()), before.uxHash, coin.Transactions{txn}, feeCalc)
+		require.NoError(t, err)
+		b = &coin.SignedBlock{
+			Block: *block,
+		}
+
+		return up.ProcessBlock(tx, b)
+	})
+	require.NoError(t, err)
+
+	err = db.Update("", func(tx *dbutil.Tx) error {
+		require.NotEqual(t, before, getState(tx))
+
+		// Only the head block can be rolled back
+		genesis := &coin.SignedBlock{}
+		require.Error(t, up.Rollback(tx, genesis, nil))
+
+		return up.Rollback(tx, b, uxs[:2])
+	})
+	require.NoError(t, err)
+
+	err = db.View("", func(tx *dbutil.Tx) error {
+		require.Equal(t, before, getState(tx))
+
+		height, ok, err := up.meta.getAddrIndexHeight(tx)
+		require.NoError(t, err)
+		require.True(t, ok)
+		require.Equal(t, uint64(0), height)
+		return nil
+	})
+	require.NoError(t, err)
+}
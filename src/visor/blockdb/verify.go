@@ -152,5 +152,65 @@ func VerifyDBSkyencoderSafe(tx *dbutil.Tx, quit <-chan struct{}) error {
 		return err
 	}
 
+	// Databases created before the address balance index was introduced do not have its bucket until they are opened for writing
+	if dbutil.Exists(tx, UnspentPoolAddrBalanceBkt) {
+		if err := dbutil.ForEach(tx, UnspentPoolAddrBalanceBkt, func(_, v []byte) error {
+			select {
+			case <-quit:
+				return ErrVerifyStopped
+			default:
+			}
+
+			var b1 AddressBalance
+			if err := decodeAddressBalanceExact(v, &b1); err != nil {
+				return err
+			}
+
+			var b2 AddressBalance
+			if err := encoder.DeserializeRawExact(v, &b2); err != nil {
+				return err
+			}
+
+			if b1 != b2 {
+				return errors.New("UnspentPoolAddrBalanceBkt address balance mismatch")
+			}
+
+			return nil
+		}); err != nil {
+			return err
+		}
+	}
+
+	// Databases created before undo records were introduced do not have the block undo bucket
+	if !dbutil.Exists(tx, BlockUndoBkt) {
+		return nil
+	}
+
+	if err := dbutil.ForEach(tx, BlockUndoBkt, func(_, v []byte) error {
+		select {
+		case <-quit:
+			return ErrVerifyStopped
+		default:
+		}
+
+		var b1 uxOutsWrapper
+		if err := decodeUxOutsWrapperExact(v, &b1); err != nil {
+			return err
+		}
+
+		var b2 []coin.UxOut
+		if err := encoder.DeserializeRawExact(v, &b2); err != nil {
+			return err
+		}
+
+		if !reflect.DeepEqual(b1.UxOuts, b2) {
+			return errors.New("BlockUndoBkt ux outs mismatch")
+		}
+
+		return nil
+	}); err != nil {
+		return err
+	}
+
 	return nil
 }
@@ -0,0 +1,140 @@
+package visor
+
+import (
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+	"github.com/skycoin/skycoin/src/wallet"
+)
+
+// AddressBalances are the balances of addresses, read in a single database transaction
+type AddressBalances struct {
+	HeadBlock *coin.SignedBlock
+	// Balances[i] is the balance of the i-th requested address
+	Balances []wallet.BalancePair
+}
+
+// GetAddressBalances returns the confirmed and predicted balances of addresses and the head block they were read at.
+// All balances are read in one database transaction, so they are consistent with each other
+// and with the unconfirmed transaction pool, however many addresses are requested.
+func (vs *Visor) GetAddressBalances(addrs []cipher.Address) (*AddressBalances, error) {
+	var head *coin.SignedBlock
+	var bps []wallet.BalancePair
+
+	if err := vs.db.View("GetAddressBalances", func(tx *dbutil.Tx) error {
+		var err error
+		head, bps, err = vs.balancesOfAddrs(tx, addrs)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+
+	return &AddressBalances{
+		HeadBlock: head,
+		Balances:  bps,
+	}, nil
+}
+
+// GetUnspentOutputsSummaryOfAddrs returns the confirmed, outgoing and incoming unspent outputs of addresses.
+// Unlike GetUnspentOutputsSummary with an address filter, the confirmed outputs are read from the address index
+// instead of scanning the whole unspent pool.
+func (vs *Visor) GetUnspentOutputsSummaryOfAddrs(addrs []cipher.Address) (*UnspentOutputsSummary, error) {
+	return vs.getUnspentOutputsSummary("GetUnspentOutputsSummaryOfAddrs", func(tx *dbutil.Tx) (coin.UxArray, error) {
+		auxs, err := vs.blockchain.Unspent().GetUnspentsOfAddrs(tx, addrs)
+		if err != nil {
+			return nil, err
+		}
+
+		var uxa coin.UxArray
+		seen := make(map[cipher.Address]struct{}, len(addrs))
+		for _, addr := range addrs {
+			if _, ok := seen[addr]; ok {
+				continue
+			}
+			seen[addr] = struct{}{}
+			uxa = append(uxa, auxs[addr]...)
+		}
+
+		return uxa, nil
+	}, FbyAddresses(addrs))
+}
+
+// GetUnspentOutputsSummaryOfHashes returns the unspent outputs with the given hashes, confirmed or not.
+// Hashes that are not unspent outputs are ignored.
+func (vs *Visor) GetUnspentOutputsSummaryOfHashes(hashes []cipher.SHA256) (*UnspentOutputsSummary, error) {
+	return vs.getUnspentOutputsSummary("GetUnspentOutputsSummaryOfHashes", func(tx *dbutil.Tx) (coin.UxArray, error) {
+		var uxa coin.UxArray
+		seen := make(map[cipher.SHA256]struct{}, len(hashes))
+		for _, h := range hashes {
+			if _, ok := seen[h]; ok {
+				continue
+			}
+			seen[h] = struct{}{}
+
+			ux, err := vs.blockchain.Unspent().Get(tx, h)
+			if err != nil {
+				return nil, err
+			}
+
+			if ux != nil {
+				uxa = append(uxa, *ux)
+			}
+		}
+
+		return uxa, nil
+	}, FbyHashes(hashes))
+}
+
+// getUnspentOutputsSummary builds an UnspentOutputsSummary in one database transaction.
+// getConfirmed returns the confirmed outputs and flt selects the outgoing and incoming outputs
+func (vs *Visor) getUnspentOutputsSummary(name string, getConfirmed func(*dbutil.Tx) (coin.UxArray, error), flt OutputsFilter) (*UnspentOutputsSummary, error) {
+	var confirmedOutputs coin.UxArray
+	var outgoingOutputs coin.UxArray
+	var incomingOutputs coin.UxArray
+	var head *coin.SignedBlock
+
+	if err := vs.db.View(name, func(tx *dbutil.Tx) error {
+		var err error
+		head, err = vs.blockchain.Head(tx)
+		if err != nil {
+			return err
+		}
+
+		confirmedOutputs, err = getConfirmed(tx)
+		if err != nil {
+			return err
+		}
+
+		outgoingOutputs, err = vs.unconfirmedOutgoingOutputs(tx)
+		if err != nil {
+			return err
+		}
+
+		incomingOutputs, err = vs.unconfirmed.GetIncomingOutputs(tx, head.Head)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+
+	confirmed, err := NewUnspentOutputs(confirmedOutputs, head.Time())
+	if err != nil {
+		return nil, err
+	}
+
+	outgoing, err := NewUnspentOutputs(flt(outgoingOutputs), head.Time())
+	if err != nil {
+		return nil, err
+	}
+
+	incoming, err := NewUnspentOutputs(flt(incomingOutputs), head.Time())
+	if err != nil {
+		return nil, err
+	}
+
+	return &UnspentOutputsSummary{
+		HeadBlock: head,
+		Confirmed: confirmed,
+		Outgoing:  outgoing,
+		Incoming:  incoming,
+	}, nil
+}
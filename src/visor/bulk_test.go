@@ -0,0 +1,171 @@
+package visor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/testutil"
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+	"github.com/skycoin/skycoin/src/wallet"
+)
+
+type bulkFixture struct {
+	v        *Visor
+	shutdown func()
+	head     coin.SignedBlock
+	addr     cipher.Address
+	other    cipher.Address
+	empty    cipher.Address
+	addrUxs  coin.UxArray
+	spentUx  coin.UxOut
+	incoming coin.UxArray
+}
+
+// newBulkFixture creates a Visor where addr has two confirmed outputs, one of which is spent by an
+// unconfirmed transaction that sends coins to addr and other. other has no confirmed outputs.
+func newBulkFixture(t *testing.T) *bulkFixture {
+	addr := testutil.MakeAddress()
+	other := testutil.MakeAddress()
+	empty := testutil.MakeAddress()
+
+	var headTime uint64 = 1500000000
+
+	gTxn := makeHistoryTxn(t, nil, []coin.TransactionOutput{
+		{Address: addr, Coins: 10e6, Hours: 100},
+		{Address: addr, Coins: 20e6, Hours: 200},
+	})
+	head := coin.SignedBlock{
+		Block: coin.Block{
+			Head: coin.BlockHeader{
+				BkSeq: 0,
+				Time:  headTime,
+			},
+			Body: coin.BlockBody{
+				Transactions: coin.Transactions{gTxn},
+			},
+		},
+	}
+	addrUxs := coin.CreateUnspents(head.Head, gTxn)
+
+	uTxn := makeHistoryTxn(t, []cipher.SHA256{addrUxs[1].Hash()}, []coin.TransactionOutput{
+		{Address: addr, Coins: 5e6, Hours: 50},
+		{Address: other, Coins: 15e6, Hours: 50},
+	})
+	incoming := coin.CreateUnspents(head.Head, uTxn)
+
+	matchDBTx := mock.MatchedBy(func(tx *dbutil.Tx) bool {
+		return true
+	})
+
+	unspent := &MockUnspentPooler{}
+	unspent.On("AddrBalanceIndexReady", matchDBTx).Return(false, nil)
+	unspent.On("GetArray", matchDBTx, uTxn.In).Return(coin.UxArray{addrUxs[1]}, nil)
+	unspent.On("GetUnspentsOfAddrs", matchDBTx, mock.Anything).Return(coin.AddressUxOuts{
+		addr: addrUxs,
+	}, nil)
+	for _, ux := range addrUxs {
+		ux := ux
+		unspent.On("Get", matchDBTx, ux.Hash()).Return(&ux, nil)
+	}
+	unspent.On("Get", matchDBTx, mock.Anything).Return(nil, nil)
+
+	bc := &MockBlockchainer{}
+	bc.On("Head", matchDBTx).Return(&head, nil)
+	bc.On("Unspent").Return(unspent)
+
+	uncfmTxnPool := &MockUnconfirmedTransactionPooler{}
+	uncfmTxnPool.On("AllRawTransactions", matchDBTx).Return(coin.Transactions{uTxn}, nil)
+	uncfmTxnPool.On("GetIncomingOutputs", matchDBTx, head.Head).Return(incoming, nil)
+
+	db, shutdown := prepareDB(t)
+
+	return &bulkFixture{
+		v: &Visor{
+			db:          db,
+			unconfirmed: uncfmTxnPool,
+			blockchain:  bc,
+		},
+		shutdown: shutdown,
+		head:     head,
+		addr:     addr,
+		other:    other,
+		empty:    empty,
+		addrUxs:  addrUxs,
+		spentUx:  addrUxs[1],
+		incoming: incoming,
+	}
+}
+
+func TestGetAddressBalances(t *testing.T) {
+	f := newBulkFixture(t)
+	defer f.shutdown()
+
+	balances, err := f.v.GetAddressBalances([]cipher.Address{f.addr, f.other, f.empty})
+	require.NoError(t, err)
+	require.Equal(t, &f.head, balances.HeadBlock)
+	require.Equal(t, []wallet.BalancePair{
+		{
+			Confirmed: wallet.Balance{Coins: 30e6, Hours: 300},
+			Predicted: wallet.Balance{Coins: 15e6, Hours: 150},
+		},
+		{
+			// other only has unconfirmed incoming coins
+			Predicted: wallet.Balance{Coins: 15e6, Hours: 50},
+		},
+		{},
+	}, balances.Balances)
+
+	// GetBalanceOfAddrs returns the same balances
+	bps, err := f.v.GetBalanceOfAddrs([]cipher.Address{f.addr, f.other, f.empty})
+	require.NoError(t, err)
+	require.Equal(t, balances.Balances, bps)
+}
+
+func TestGetUnspentOutputsSummaryOfAddrs(t *testing.T) {
+	f := newBulkFixture(t)
+	defer f.shutdown()
+
+	summary, err := f.v.GetUnspentOutputsSummaryOfAddrs([]cipher.Address{f.addr, f.addr, f.empty})
+	require.NoError(t, err)
+	require.Equal(t, &f.head, summary.HeadBlock)
+
+	expectedConfirmed, err := NewUnspentOutputs(f.addrUxs, f.head.Time())
+	require.NoError(t, err)
+	require.Equal(t, expectedConfirmed, summary.Confirmed)
+
+	expectedOutgoing, err := NewUnspentOutputs(coin.UxArray{f.spentUx}, f.head.Time())
+	require.NoError(t, err)
+	require.Equal(t, expectedOutgoing, summary.Outgoing)
+
+	expectedIncoming, err := NewUnspentOutputs(coin.UxArray{f.incoming[0]}, f.head.Time())
+	require.NoError(t, err)
+	require.Equal(t, expectedIncoming, summary.Incoming)
+}
+
+func TestGetUnspentOutputsSummaryOfHashes(t *testing.T) {
+	f := newBulkFixture(t)
+	defer f.shutdown()
+
+	summary, err := f.v.GetUnspentOutputsSummaryOfHashes([]cipher.SHA256{
+		f.addrUxs[0].Hash(),
+		f.incoming[1].Hash(),
+		testutil.RandSHA256(t),
+		f.addrUxs[0].Hash(),
+	})
+	require.NoError(t, err)
+	require.Equal(t, &f.head, summary.HeadBlock)
+
+	expectedConfirmed, err := NewUnspentOutputs(coin.UxArray{f.addrUxs[0]}, f.head.Time())
+	require.NoError(t, err)
+	require.Equal(t, expectedConfirmed, summary.Confirmed)
+
+	require.Empty(t, summary.Outgoing)
+
+	expectedIncoming, err := NewUnspentOutputs(coin.UxArray{f.incoming[1]}, f.head.Time())
+	require.NoError(t, err)
+	require.Equal(t, expectedIncoming, summary.Incoming)
+}
@@ -41,6 +41,11 @@ type Config struct {
 	GenesisCoinVolume uint64
 	// enable arbitrating mode
 	Arbitrating bool
+
+	// Discard the bodies of old blocks and the history indexes
+	Prune bool
+	// Number of most recent block bodies kept in pruned mode
+	PruneKeepBlocks uint64
 }
 
 // NewConfig creates Config
@@ -108,5 +113,9 @@ func (c Config) Verify() error {
 		return errors.New("MaxBlockTransactionsSize must be >= CreateBlockVerifyTxn.MaxTransactionSize")
 	}
 
+	if c.Prune && c.PruneKeepBlocks == 0 {
+		return errors.New("PruneKeepBlocks must be > 0 in pruned mode")
+	}
+
 	return nil
 }
@@ -58,6 +58,22 @@ func CheckDatabase(db *dbutil.DB, pubkey cipher.PubKey, quit chan struct{}) erro
 		return err
 	}
 
+	// Pruned databases and databases imported from a snapshot that have not been backfilled yet
+	// do not have all the block bodies and history indexes, only the block signatures are verified
+	var pruned, backfilling bool
+	if err := db.View("CheckDatabase pruned", func(tx *dbutil.Tx) error {
+		var err error
+		_, pruned, err = bc.PruneSeq(tx)
+		if err != nil {
+			return err
+		}
+
+		_, _, backfilling, err = bc.BackfillRange(tx)
+		return err
+	}); err != nil {
+		return err
+	}
+
 	history := historydb.New()
 	indexesMap := historydb.NewIndexesMap()
 
@@ -72,6 +88,10 @@ func CheckDatabase(db *dbutil.DB, pubkey cipher.PubKey, quit chan struct{}) erro
 		// Verify historydb, we don't return the error of history.Verify here,
 		// as we have to check all signature, if we return error early here, the
 		// potential bad signature won't be detected.
+		if pruned || backfilling {
+			return nil
+		}
+
 		lock.Lock()
 		defer lock.Unlock()
 		if historyVerifyErr == nil {
@@ -86,16 +106,26 @@ func CheckDatabase(db *dbutil.DB, pubkey cipher.PubKey, quit chan struct{}) erro
 		lock.Lock()
 		err = historyVerifyErr
 		lock.Unlock()
-		return err
+		if err != nil {
+			return err
+		}
 	default:
 		return err
 	}
+
+	// Verify the address balance index against the unspent outputs
+	return db.View("CheckDatabase address balance index", func(tx *dbutil.Tx) error {
+		if !dbutil.Exists(tx, blockdb.UnspentPoolAddrBalanceBkt) {
+			return nil
+		}
+
+		return bc.Unspent().VerifyAddrBalanceIndex(tx)
+	})
 }
 
 // backup the corrypted db first, then rebuild the history DB.
 func rebuildHistoryDB(db *dbutil.DB, history *historydb.HistoryDB, bc *Blockchain, quit chan struct{}) (*dbutil.DB, error) { // nolint: unused,megacheck
-	db, err := backupDB(db)
-	if err != nil {
+	if err := backupDB(db); err != nil {
 		return nil, err
 	}
 
@@ -139,26 +169,20 @@ func rebuildHistoryDB(db *dbutil.DB, history *historydb.HistoryDB, bc *Blockchai
 	return db, nil
 }
 
-// backupDB makes a backup copy of the DB
-func backupDB(db *dbutil.DB) (*dbutil.DB, error) { // nolint: unused,megacheck
-	// backup the corrupted database
-	dbReadOnly := db.IsReadOnly()
-
-	dbPath := db.Path()
-
-	if err := db.Close(); err != nil {
-		return nil, fmt.Errorf("Failed to close db: %v", err)
+// backupDB makes a backup copy of the DB at makeCorruptDBPath with BackupDBToFile
+func backupDB(db *dbutil.DB) error { // nolint: unused,megacheck
+	corruptDBPath, err := makeCorruptDBPath(db.Path())
+	if err != nil {
+		return err
 	}
 
-	corruptDBPath, err := copyCorruptDB(dbPath)
-	if err != nil {
-		return nil, fmt.Errorf("Failed to copy corrupted db: %v", err)
+	if _, err := BackupDBToFile(db, corruptDBPath, false); err != nil {
+		return fmt.Errorf("Failed to copy corrupted db: %v", err)
 	}
 
 	logger.Critical().Infof("Copy corrupted db to %s", corruptDBPath)
 
-	// Open the database again
-	return OpenDB(dbPath, dbReadOnly)
+	return nil
 }
 
 // ResetCorruptDB checks the database for corruption and if corrupted and
@@ -236,38 +260,6 @@ func moveCorruptDB(dbPath string) (string, error) {
 	return newDBPath, nil
 }
 
-// copyCorruptDB copy a file to makeCorruptDBPath(dbPath)
-func copyCorruptDB(dbPath string) (string, error) { // nolint: unused,megacheck
-	newDBPath, err := makeCorruptDBPath(dbPath)
-	if err != nil {
-		return "", err
-	}
-
-	in, err := os.Open(dbPath)
-	if err != nil {
-		return "", err
-	}
-	defer in.Close()
-
-	out, err := os.Create(newDBPath)
-	if err != nil {
-		return "", err
-	}
-	defer out.Close()
-	logger.Critical().Info(out.Name())
-
-	_, err = io.Copy(in, out)
-	if err != nil {
-		return "", err
-	}
-
-	if err := out.Close(); err != nil {
-		return "", err
-	}
-
-	return newDBPath, nil
-}
-
 // makeCorruptDBPath creates a $FILE.corrupt.$HASH string based on dbPath,
 // where $HASH is truncated SHA1 of $FILE.
 func makeCorruptDBPath(dbPath string) (string, error) {
@@ -0,0 +1,100 @@
+package dbutil
+
+import (
+	"io"
+
+	"github.com/boltdb/bolt"
+)
+
+// boltStorage is a Storage backed by a boltdb file
+type boltStorage struct {
+	db *bolt.DB
+}
+
+// NewBoltStorage returns a Storage backed by a boltdb file
+func NewBoltStorage(db *bolt.DB) Storage {
+	return &boltStorage{
+		db: db,
+	}
+}
+
+func (s *boltStorage) View(f func(StorageTx) error) error {
+	return s.db.View(func(tx *bolt.Tx) error {
+		return f(boltTx{tx})
+	})
+}
+
+func (s *boltStorage) Update(f func(StorageTx) error) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return f(boltTx{tx})
+	})
+}
+
+func (s *boltStorage) Close() error {
+	return s.db.Close()
+}
+
+func (s *boltStorage) Path() string {
+	return s.db.Path()
+}
+
+func (s *boltStorage) IsReadOnly() bool {
+	return s.db.IsReadOnly()
+}
+
+type boltTx struct {
+	tx *bolt.Tx
+}
+
+func (tx boltTx) Bucket(name []byte) Bucket {
+	// Do not wrap a nil *bolt.Bucket, the interface value would not be nil
+	bkt := tx.tx.Bucket(name)
+	if bkt == nil {
+		return nil
+	}
+	return boltBucket{bkt}
+}
+
+func (tx boltTx) CreateBucket(name []byte) (Bucket, error) {
+	bkt, err := tx.tx.CreateBucket(name)
+	if err != nil {
+		return nil, err
+	}
+	return boltBucket{bkt}, nil
+}
+
+func (tx boltTx) CreateBucketIfNotExists(name []byte) (Bucket, error) {
+	bkt, err := tx.tx.CreateBucketIfNotExists(name)
+	if err != nil {
+		return nil, err
+	}
+	return boltBucket{bkt}, nil
+}
+
+func (tx boltTx) DeleteBucket(name []byte) error {
+	return tx.tx.DeleteBucket(name)
+}
+
+func (tx boltTx) Writable() bool {
+	return tx.tx.Writable()
+}
+
+func (tx boltTx) Size() int64 {
+	return tx.tx.Size()
+}
+
+func (tx boltTx) WriteTo(w io.Writer) (int64, error) {
+	return tx.tx.WriteTo(w)
+}
+
+type boltBucket struct {
+	*bolt.Bucket
+}
+
+func (b boltBucket) Cursor() Cursor {
+	return b.Bucket.Cursor()
+}
+
+func (b boltBucket) KeyN() int {
+	return b.Bucket.Stats().KeyN
+}
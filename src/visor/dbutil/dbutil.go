@@ -1,5 +1,5 @@
 /*
-Package dbutil provides boltdb utility methods
+Package dbutil provides key-value storage utility methods, with boltdb and in-memory storage backends
 */
 package dbutil
 
@@ -28,19 +28,19 @@ var (
 	txDurationReportingThreshold = time.Millisecond * 100
 )
 
-// Tx wraps a Tx
+// Tx wraps a StorageTx
 type Tx struct {
-	*bolt.Tx
+	StorageTx
 }
 
 // String is implemented to prevent a panic when mocking methods with *Tx arguments.
 // The mock library forces arguments to be printed with %s which causes Tx to panic.
 // See https://github.com/stretchr/testify/pull/596
 func (tx *Tx) String() string {
-	return fmt.Sprintf("%v", tx.Tx)
+	return fmt.Sprintf("%v", tx.StorageTx)
 }
 
-// DB wraps a bolt.DB to add logging
+// DB wraps a Storage to add logging
 type DB struct {
 	ViewLog                    bool
 	ViewTrace                  bool
@@ -49,7 +49,7 @@ type DB struct {
 	DurationLog                bool
 	DurationReportingThreshold time.Duration
 
-	*bolt.DB
+	Storage
 
 	// shutdownLock is added to prevent closing the database while a View transaction is in progress
 	// bolt.DB will block for Update transactions but not for View transactions, and if
@@ -60,8 +60,18 @@ type DB struct {
 	shutdownLock sync.RWMutex
 }
 
-// WrapDB returns WrapDB
+// WrapDB returns a DB backed by a boltdb file
 func WrapDB(db *bolt.DB) *DB {
+	return NewDB(NewBoltStorage(db))
+}
+
+// NewMemoryDB returns an empty DB that keeps its data in memory
+func NewMemoryDB() *DB {
+	return NewDB(NewMemoryStorage())
+}
+
+// NewDB returns a DB backed by a Storage
+func NewDB(s Storage) *DB {
 	return &DB{
 		ViewLog:                    txViewLog,
 		UpdateLog:                  txUpdateLog,
@@ -69,11 +79,11 @@ func WrapDB(db *bolt.DB) *DB {
 		UpdateTrace:                txUpdateTrace,
 		DurationLog:                txDurationLog,
 		DurationReportingThreshold: txDurationReportingThreshold,
-		DB:                         db,
+		Storage:                    s,
 	}
 }
 
-// View wraps *bolt.DB.View to add logging
+// View wraps Storage.View to add logging
 func (db *DB) View(name string, f func(*Tx) error) error {
 	db.shutdownLock.RLock()
 	defer db.shutdownLock.RUnlock()
@@ -88,7 +98,7 @@ func (db *DB) View(name string, f func(*Tx) error) error {
 
 	t0 := time.Now()
 
-	err := db.DB.View(func(tx *bolt.Tx) error {
+	err := db.Storage.View(func(tx StorageTx) error {
 		return f(&Tx{tx})
 	})
 
@@ -101,7 +111,7 @@ func (db *DB) View(name string, f func(*Tx) error) error {
 	return err
 }
 
-// Update wraps *bolt.DB.Update to add logging
+// Update wraps Storage.Update to add logging
 func (db *DB) Update(name string, f func(*Tx) error) error {
 	db.shutdownLock.RLock()
 	defer db.shutdownLock.RUnlock()
@@ -116,7 +126,7 @@ func (db *DB) Update(name string, f func(*Tx) error) error {
 
 	t0 := time.Now()
 
-	err := db.DB.Update(func(tx *bolt.Tx) error {
+	err := db.Storage.Update(func(tx StorageTx) error {
 		return f(&Tx{tx})
 	})
 
@@ -129,15 +139,15 @@ func (db *DB) Update(name string, f func(*Tx) error) error {
 	return err
 }
 
-// Close closes the underlying *bolt.DB
+// Close closes the underlying Storage
 func (db *DB) Close() error {
 	db.shutdownLock.Lock()
 	defer db.shutdownLock.Unlock()
 
-	return db.DB.Close()
+	return db.Storage.Close()
 }
 
-// ErrCreateBucketFailed is returned if creating a bolt.DB bucket fails
+// ErrCreateBucketFailed is returned if creating a bucket fails
 type ErrCreateBucketFailed struct {
 	Bucket string
 	Err    error
@@ -155,7 +165,7 @@ func NewErrCreateBucketFailed(bucket []byte, err error) error {
 	}
 }
 
-// ErrBucketNotExist is returned if a bolt.DB bucket does not exist
+// ErrBucketNotExist is returned if a bucket does not exist
 type ErrBucketNotExist struct {
 	Bucket string
 }
@@ -307,29 +317,33 @@ func Delete(tx *Tx, bktName, key []byte) error {
 	return bkt.Delete(key)
 }
 
-// Len returns the number of keys in a bucket
+// Len returns the number of keys in a bucket.
+// With the boltdb backend, the keys written in the current transaction are not counted.
 func Len(tx *Tx, bktName []byte) (uint64, error) {
 	bkt := tx.Bucket(bktName)
 	if bkt == nil {
 		return 0, NewErrBucketNotExist(bktName)
 	}
 
-	bstats := bkt.Stats()
+	n := bkt.KeyN()
 
-	if bstats.KeyN < 0 {
+	if n < 0 {
 		return 0, errors.New("Negative length queried from db stats")
 	}
 
-	return uint64(bstats.KeyN), nil
+	return uint64(n), nil
 }
 
-// IsEmpty returns true if the bucket is empty
+// IsEmpty returns true if the bucket is empty.
+// Unlike Len, it sees the keys written in the current transaction with every storage backend.
 func IsEmpty(tx *Tx, bktName []byte) (bool, error) {
-	length, err := Len(tx, bktName)
-	if err != nil {
-		return false, err
+	bkt := tx.Bucket(bktName)
+	if bkt == nil {
+		return false, NewErrBucketNotExist(bktName)
 	}
-	return length == 0, nil
+
+	k, _ := bkt.Cursor().First()
+	return k == nil, nil
 }
 
 // Exists returns true if the bucket exists
@@ -0,0 +1,411 @@
+package dbutil
+
+import (
+	"errors"
+	"sort"
+	"sync"
+)
+
+var (
+	// ErrStorageClosed is returned when using a closed in-memory storage
+	ErrStorageClosed = errors.New("storage is closed")
+	// ErrTxNotWritable is returned when writing in a read-only transaction of an in-memory storage
+	ErrTxNotWritable = errors.New("tx not writable")
+	// ErrTxClosed is returned when using an in-memory storage transaction after it ended
+	ErrTxClosed = errors.New("tx closed")
+	// ErrBucketExists is returned when creating a bucket that already exists in an in-memory storage
+	ErrBucketExists = errors.New("bucket already exists")
+	// ErrBucketNotFound is returned when deleting a bucket that does not exist in an in-memory storage
+	ErrBucketNotFound = errors.New("bucket not found")
+	// ErrBucketNameRequired is returned when creating a bucket with an empty name in an in-memory storage
+	ErrBucketNameRequired = errors.New("bucket name required")
+	// ErrKeyRequired is returned when putting an empty key in an in-memory storage
+	ErrKeyRequired = errors.New("key required")
+)
+
+// memoryStorage is a Storage that keeps its data in memory, for tests and ephemeral nodes.
+// Read-write transactions are applied in place and reverted from an undo log if they fail.
+// A read-write transaction excludes all other transactions, so that read-only transactions
+// never see uncommitted data.
+type memoryStorage struct {
+	lock    sync.RWMutex
+	buckets map[string]*memoryBucket
+	closed  bool
+}
+
+// NewMemoryStorage returns an empty Storage that keeps its data in memory.
+// The data is lost when the storage is closed.
+func NewMemoryStorage() Storage {
+	return &memoryStorage{
+		buckets: make(map[string]*memoryBucket),
+	}
+}
+
+func (s *memoryStorage) View(f func(StorageTx) error) error {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	if s.closed {
+		return ErrStorageClosed
+	}
+
+	tx := &memoryTx{
+		storage: s,
+	}
+	defer tx.close()
+
+	return f(tx)
+}
+
+func (s *memoryStorage) Update(f func(StorageTx) error) (err error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.closed {
+		return ErrStorageClosed
+	}
+
+	tx := &memoryTx{
+		storage:  s,
+		writable: true,
+	}
+	defer tx.close()
+
+	// Roll back if f panics
+	committed := false
+	defer func() {
+		if !committed {
+			tx.rollback()
+		}
+	}()
+
+	if err := f(tx); err != nil {
+		return err
+	}
+
+	committed = true
+	return nil
+}
+
+func (s *memoryStorage) Close() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.closed = true
+	s.buckets = nil
+	return nil
+}
+
+func (s *memoryStorage) Path() string {
+	return ""
+}
+
+func (s *memoryStorage) IsReadOnly() bool {
+	return false
+}
+
+type memoryTx struct {
+	storage  *memoryStorage
+	writable bool
+	closed   bool
+	// undo reverts the changes made by the transaction, in reverse order
+	undo []func()
+}
+
+func (tx *memoryTx) close() {
+	tx.closed = true
+	tx.undo = nil
+}
+
+func (tx *memoryTx) rollback() {
+	for i := len(tx.undo) - 1; i >= 0; i-- {
+		tx.undo[i]()
+	}
+}
+
+func (tx *memoryTx) checkWritable() error {
+	if tx.closed {
+		return ErrTxClosed
+	}
+	if !tx.writable {
+		return ErrTxNotWritable
+	}
+	return nil
+}
+
+func (tx *memoryTx) Bucket(name []byte) Bucket {
+	if tx.closed {
+		return nil
+	}
+
+	bkt, ok := tx.storage.buckets[string(name)]
+	if !ok {
+		return nil
+	}
+
+	return &memoryBucketTx{
+		tx:     tx,
+		bucket: bkt,
+	}
+}
+
+func (tx *memoryTx) CreateBucket(name []byte) (Bucket, error) {
+	if err := tx.checkWritable(); err != nil {
+		return nil, err
+	}
+
+	if len(name) == 0 {
+		return nil, ErrBucketNameRequired
+	}
+
+	key := string(name)
+	if _, ok := tx.storage.buckets[key]; ok {
+		return nil, ErrBucketExists
+	}
+
+	bkt := newMemoryBucket()
+	tx.storage.buckets[key] = bkt
+	tx.undo = append(tx.undo, func() {
+		delete(tx.storage.buckets, key)
+	})
+
+	return &memoryBucketTx{
+		tx:     tx,
+		bucket: bkt,
+	}, nil
+}
+
+func (tx *memoryTx) CreateBucketIfNotExists(name []byte) (Bucket, error) {
+	if err := tx.checkWritable(); err != nil {
+		return nil, err
+	}
+
+	if bkt := tx.Bucket(name); bkt != nil {
+		return bkt, nil
+	}
+
+	return tx.CreateBucket(name)
+}
+
+func (tx *memoryTx) DeleteBucket(name []byte) error {
+	if err := tx.checkWritable(); err != nil {
+		return err
+	}
+
+	key := string(name)
+	bkt, ok := tx.storage.buckets[key]
+	if !ok {
+		return ErrBucketNotFound
+	}
+
+	delete(tx.storage.buckets, key)
+	tx.undo = append(tx.undo, func() {
+		tx.storage.buckets[key] = bkt
+	})
+
+	return nil
+}
+
+func (tx *memoryTx) Writable() bool {
+	return tx.writable
+}
+
+// memoryBucket holds the data of a bucket. keys is kept sorted.
+type memoryBucket struct {
+	keys     []string
+	values   map[string][]byte
+	sequence uint64
+}
+
+func newMemoryBucket() *memoryBucket {
+	return &memoryBucket{
+		values: make(map[string][]byte),
+	}
+}
+
+// search returns the index of the first key that is not less than key
+func (b *memoryBucket) search(key string) int {
+	return sort.SearchStrings(b.keys, key)
+}
+
+func (b *memoryBucket) put(key string, value []byte) {
+	if _, ok := b.values[key]; !ok {
+		i := b.search(key)
+		b.keys = append(b.keys, "")
+		copy(b.keys[i+1:], b.keys[i:])
+		b.keys[i] = key
+	}
+
+	b.values[key] = value
+}
+
+func (b *memoryBucket) delete(key string) {
+	if _, ok := b.values[key]; !ok {
+		return
+	}
+
+	i := b.search(key)
+	b.keys = append(b.keys[:i], b.keys[i+1:]...)
+	delete(b.values, key)
+}
+
+// memoryBucketTx is a bucket accessed in a transaction
+type memoryBucketTx struct {
+	tx     *memoryTx
+	bucket *memoryBucket
+}
+
+func (b *memoryBucketTx) Get(key []byte) []byte {
+	if b.tx.closed {
+		return nil
+	}
+
+	return b.bucket.values[string(key)]
+}
+
+func (b *memoryBucketTx) Put(key, value []byte) error {
+	if err := b.tx.checkWritable(); err != nil {
+		return err
+	}
+
+	if len(key) == 0 {
+		return ErrKeyRequired
+	}
+
+	k := string(key)
+	old, existed := b.bucket.values[k]
+	bkt := b.bucket
+	b.tx.undo = append(b.tx.undo, func() {
+		if existed {
+			bkt.put(k, old)
+		} else {
+			bkt.delete(k)
+		}
+	})
+
+	// The caller may reuse the value's memory
+	v := make([]byte, len(value))
+	copy(v, value)
+	b.bucket.put(k, v)
+
+	return nil
+}
+
+func (b *memoryBucketTx) Delete(key []byte) error {
+	if err := b.tx.checkWritable(); err != nil {
+		return err
+	}
+
+	k := string(key)
+	old, existed := b.bucket.values[k]
+	if !existed {
+		return nil
+	}
+
+	bkt := b.bucket
+	b.tx.undo = append(b.tx.undo, func() {
+		bkt.put(k, old)
+	})
+
+	b.bucket.delete(k)
+
+	return nil
+}
+
+func (b *memoryBucketTx) ForEach(f func(k, v []byte) error) error {
+	if b.tx.closed {
+		return ErrTxClosed
+	}
+
+	c := b.Cursor()
+	for k, v := c.First(); k != nil; k, v = c.Next() {
+		if err := f(k, v); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (b *memoryBucketTx) Cursor() Cursor {
+	return &memoryCursor{
+		bucket: b,
+	}
+}
+
+func (b *memoryBucketTx) Sequence() uint64 {
+	return b.bucket.sequence
+}
+
+func (b *memoryBucketTx) NextSequence() (uint64, error) {
+	if err := b.tx.checkWritable(); err != nil {
+		return 0, err
+	}
+
+	bkt := b.bucket
+	old := bkt.sequence
+	b.tx.undo = append(b.tx.undo, func() {
+		bkt.sequence = old
+	})
+
+	bkt.sequence++
+	return bkt.sequence, nil
+}
+
+func (b *memoryBucketTx) KeyN() int {
+	return len(b.bucket.keys)
+}
+
+// memoryCursor remembers the key it is positioned at, instead of an index,
+// so that it stays valid when keys are put or deleted while iterating
+type memoryCursor struct {
+	bucket *memoryBucketTx
+	key    string
+	valid  bool
+}
+
+// at positions the cursor at the key with index i
+func (c *memoryCursor) at(i int) ([]byte, []byte) {
+	keys := c.bucket.bucket.keys
+	if c.bucket.tx.closed || i < 0 || i >= len(keys) {
+		c.valid = false
+		return nil, nil
+	}
+
+	c.key = keys[i]
+	c.valid = true
+	return []byte(c.key), c.bucket.bucket.values[c.key]
+}
+
+func (c *memoryCursor) First() ([]byte, []byte) {
+	return c.at(0)
+}
+
+func (c *memoryCursor) Last() ([]byte, []byte) {
+	return c.at(len(c.bucket.bucket.keys) - 1)
+}
+
+func (c *memoryCursor) Next() ([]byte, []byte) {
+	if !c.valid {
+		return nil, nil
+	}
+
+	i := c.bucket.bucket.search(c.key)
+	if i < len(c.bucket.bucket.keys) && c.bucket.bucket.keys[i] == c.key {
+		i++
+	}
+
+	return c.at(i)
+}
+
+func (c *memoryCursor) Prev() ([]byte, []byte) {
+	if !c.valid {
+		return nil, nil
+	}
+
+	return c.at(c.bucket.bucket.search(c.key) - 1)
+}
+
+func (c *memoryCursor) Seek(seek []byte) ([]byte, []byte) {
+	return c.at(c.bucket.bucket.search(string(seek)))
+}
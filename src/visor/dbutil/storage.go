@@ -0,0 +1,82 @@
+package dbutil
+
+import (
+	"io"
+)
+
+// Storage is a transactional key-value store. Keys and values are stored in buckets,
+// and the keys of a bucket are ordered bytewise.
+// Any number of read-only transactions and one read-write transaction can be open at once.
+type Storage interface {
+	// View executes f in a read-only transaction
+	View(f func(StorageTx) error) error
+	// Update executes f in a read-write transaction. The transaction is committed if f returns nil
+	// and rolled back if f returns an error or panics.
+	Update(f func(StorageTx) error) error
+	// Close closes the storage
+	Close() error
+	// Path returns the path of the storage file, or an empty string if the storage has no file
+	Path() string
+	// IsReadOnly returns true if the storage was opened read-only
+	IsReadOnly() bool
+}
+
+// StorageTx is a transaction of a Storage.
+// Byte slices returned by a transaction are only valid until the transaction ends.
+type StorageTx interface {
+	// Bucket returns the bucket with the given name, or nil if it does not exist
+	Bucket(name []byte) Bucket
+	// CreateBucket creates a bucket, returns an error if it already exists
+	CreateBucket(name []byte) (Bucket, error)
+	// CreateBucketIfNotExists creates a bucket if it does not exist, and returns it
+	CreateBucketIfNotExists(name []byte) (Bucket, error)
+	// DeleteBucket deletes a bucket, returns an error if it does not exist
+	DeleteBucket(name []byte) error
+	// Writable returns true if the transaction is read-write
+	Writable() bool
+}
+
+// FileTx is implemented by the transactions of storages backed by a file
+type FileTx interface {
+	// Size returns the size of the file as seen by the transaction
+	Size() int64
+	// WriteTo writes a consistent copy of the file as seen by the transaction to w
+	WriteTo(w io.Writer) (int64, error)
+}
+
+// Bucket is a collection of key-value pairs in a Storage
+type Bucket interface {
+	// Get returns the value of a key, or nil if the key does not exist
+	Get(key []byte) []byte
+	// Put sets the value of a key. The key must not be empty.
+	Put(key, value []byte) error
+	// Delete deletes a key. Deleting a key that does not exist is not an error.
+	Delete(key []byte) error
+	// ForEach calls f for each key-value pair in key order.
+	// The bucket must not be modified by f.
+	ForEach(f func(k, v []byte) error) error
+	// Cursor returns a cursor to iterate the bucket in key order
+	Cursor() Cursor
+	// Sequence returns the current sequence number of the bucket
+	Sequence() uint64
+	// NextSequence increments and returns the sequence number of the bucket
+	NextSequence() (uint64, error)
+	// KeyN returns the number of keys in the bucket.
+	// The boltdb backend does not count the keys written in the current transaction.
+	KeyN() int
+}
+
+// Cursor iterates the key-value pairs of a bucket in key order.
+// The methods return a nil key when the cursor moves past the first or the last key.
+type Cursor interface {
+	// First moves to the first key
+	First() (key, value []byte)
+	// Last moves to the last key
+	Last() (key, value []byte)
+	// Next moves to the next key
+	Next() (key, value []byte)
+	// Prev moves to the previous key
+	Prev() (key, value []byte)
+	// Seek moves to the given key, or to the next key if it does not exist
+	Seek(seek []byte) (key, value []byte)
+}
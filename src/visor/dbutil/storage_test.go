@@ -0,0 +1,505 @@
+package dbutil
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/boltdb/bolt"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	testBkt    = []byte("test")
+	errTesting = errors.New("testing")
+)
+
+func prepareBoltStorage(t *testing.T) (Storage, func()) {
+	f, err := ioutil.TempFile("", "testdb")
+	require.NoError(t, err)
+
+	db, err := bolt.Open(f.Name(), 0700, nil)
+	require.NoError(t, err)
+
+	return NewBoltStorage(db), func() {
+		db.Close()
+		f.Close()
+		os.Remove(f.Name())
+	}
+}
+
+func prepareMemoryStorage(t *testing.T) (Storage, func()) {
+	s := NewMemoryStorage()
+	return s, func() {
+		s.Close()
+	}
+}
+
+// TestStorage is the conformance test suite of the Storage backends
+func TestStorage(t *testing.T) {
+	backends := []struct {
+		name    string
+		prepare func(*testing.T) (Storage, func())
+	}{
+		{
+			name:    "bolt",
+			prepare: prepareBoltStorage,
+		},
+		{
+			name:    "memory",
+			prepare: prepareMemoryStorage,
+		},
+	}
+
+	tests := []struct {
+		name string
+		f    func(*testing.T, Storage)
+	}{
+		{"buckets", testStorageBuckets},
+		{"get put delete", testStorageGetPutDelete},
+		{"iteration", testStorageIteration},
+		{"sequence", testStorageSequence},
+		{"rollback", testStorageRollback},
+		{"read only tx", testStorageReadOnlyTx},
+		{"db helpers", testStorageDBHelpers},
+	}
+
+	for _, b := range backends {
+		for _, tc := range tests {
+			t.Run(b.name+" "+tc.name, func(t *testing.T) {
+				s, shutdown := b.prepare(t)
+				defer shutdown()
+
+				tc.f(t, s)
+			})
+		}
+	}
+}
+
+func testStorageBuckets(t *testing.T, s Storage) {
+	err := s.Update(func(tx StorageTx) error {
+		require.True(t, tx.Writable())
+		require.Nil(t, tx.Bucket(testBkt))
+
+		bkt, err := tx.CreateBucket(testBkt)
+		require.NoError(t, err)
+		require.NotNil(t, bkt)
+		require.NotNil(t, tx.Bucket(testBkt))
+
+		_, err = tx.CreateBucket(testBkt)
+		require.Error(t, err)
+
+		_, err = tx.CreateBucket(nil)
+		require.Error(t, err)
+
+		require.NoError(t, bkt.Put([]byte("a"), []byte("1")))
+
+		// CreateBucketIfNotExists returns the existing bucket
+		bkt, err = tx.CreateBucketIfNotExists(testBkt)
+		require.NoError(t, err)
+		require.Equal(t, []byte("1"), bkt.Get([]byte("a")))
+		return nil
+	})
+	require.NoError(t, err)
+
+	err = s.Update(func(tx StorageTx) error {
+		require.NotNil(t, tx.Bucket(testBkt))
+
+		require.NoError(t, tx.DeleteBucket(testBkt))
+		require.Nil(t, tx.Bucket(testBkt))
+		require.Error(t, tx.DeleteBucket(testBkt))
+
+		// A recreated bucket is empty
+		bkt, err := tx.CreateBucket(testBkt)
+		require.NoError(t, err)
+		require.Nil(t, bkt.Get([]byte("a")))
+		return nil
+	})
+	require.NoError(t, err)
+}
+
+func testStorageGetPutDelete(t *testing.T, s Storage) {
+	value := []byte("1")
+	err := s.Update(func(tx StorageTx) error {
+		bkt, err := tx.CreateBucket(testBkt)
+		require.NoError(t, err)
+
+		require.Nil(t, bkt.Get([]byte("a")))
+
+		require.NoError(t, bkt.Put([]byte("a"), value))
+		require.Equal(t, []byte("1"), bkt.Get([]byte("a")))
+
+		require.NoError(t, bkt.Put([]byte("a"), []byte("2")))
+		require.Equal(t, []byte("2"), bkt.Get([]byte("a")))
+
+		require.NoError(t, bkt.Put([]byte("b"), value))
+
+		// Empty values are not nil
+		require.NoError(t, bkt.Put([]byte("c"), []byte{}))
+		require.NotNil(t, bkt.Get([]byte("c")))
+		require.Empty(t, bkt.Get([]byte("c")))
+
+		require.Error(t, bkt.Put(nil, value))
+
+		require.NoError(t, bkt.Delete([]byte("c")))
+		require.Nil(t, bkt.Get([]byte("c")))
+
+		// Deleting a missing key is not an error
+		require.NoError(t, bkt.Delete([]byte("c")))
+		return nil
+	})
+	require.NoError(t, err)
+
+	// The stored values do not share memory with the caller
+	value[0] = '9'
+
+	err = s.View(func(tx StorageTx) error {
+		bkt := tx.Bucket(testBkt)
+		require.Equal(t, []byte("2"), bkt.Get([]byte("a")))
+		require.Equal(t, []byte("1"), bkt.Get([]byte("b")))
+		require.Equal(t, 2, bkt.KeyN())
+		return nil
+	})
+	require.NoError(t, err)
+}
+
+func testStorageIteration(t *testing.T, s Storage) {
+	keys := []string{"d", "a", "c", "ab", "b"}
+	sorted := []string{"a", "ab", "b", "c", "d"}
+
+	err := s.Update(func(tx StorageTx) error {
+		bkt, err := tx.CreateBucket(testBkt)
+		require.NoError(t, err)
+
+		for _, k := range keys {
+			require.NoError(t, bkt.Put([]byte(k), []byte("v"+k)))
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	err = s.View(func(tx StorageTx) error {
+		bkt := tx.Bucket(testBkt)
+
+		var got []string
+		require.NoError(t, bkt.ForEach(func(k, v []byte) error {
+			require.Equal(t, "v"+string(k), string(v))
+			got = append(got, string(k))
+			return nil
+		}))
+		require.Equal(t, sorted, got)
+
+		// ForEach stops at the first error
+		n := 0
+		err := bkt.ForEach(func(k, v []byte) error {
+			n++
+			return errTesting
+		})
+		require.Equal(t, errTesting, err)
+		require.Equal(t, 1, n)
+
+		c := bkt.Cursor()
+
+		got = nil
+		for k, v := c.First(); k != nil; k, v = c.Next() {
+			require.Equal(t, "v"+string(k), string(v))
+			got = append(got, string(k))
+		}
+		require.Equal(t, sorted, got)
+
+		got = nil
+		for k, _ := c.Last(); k != nil; k, _ = c.Prev() {
+			got = append(got, string(k))
+		}
+		require.Equal(t, []string{"d", "c", "b", "ab", "a"}, got)
+
+		k, v := c.Seek([]byte("ab"))
+		require.Equal(t, []byte("ab"), k)
+		require.Equal(t, []byte("vab"), v)
+
+		k, _ = c.Seek([]byte("aa"))
+		require.Equal(t, []byte("ab"), k)
+
+		k, _ = c.Next()
+		require.Equal(t, []byte("b"), k)
+
+		k, _ = c.Seek([]byte("e"))
+		require.Nil(t, k)
+		return nil
+	})
+	require.NoError(t, err)
+
+	// Keys can be deleted while iterating with a cursor
+	err = s.Update(func(tx StorageTx) error {
+		bkt := tx.Bucket(testBkt)
+		c := bkt.Cursor()
+		for k, _ := c.First(); k != nil; k, _ = c.Next() {
+			if k[0] != 'a' {
+				break
+			}
+			require.NoError(t, bkt.Delete(k))
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	err = s.View(func(tx StorageTx) error {
+		k, _ := tx.Bucket(testBkt).Cursor().First()
+		require.Equal(t, []byte("b"), k)
+		require.Equal(t, 3, tx.Bucket(testBkt).KeyN())
+		return nil
+	})
+	require.NoError(t, err)
+
+	// An empty bucket has no keys
+	err = s.Update(func(tx StorageTx) error {
+		bkt, err := tx.CreateBucket([]byte("empty"))
+		require.NoError(t, err)
+
+		k, _ := bkt.Cursor().First()
+		require.Nil(t, k)
+		k, _ = bkt.Cursor().Last()
+		require.Nil(t, k)
+		return nil
+	})
+	require.NoError(t, err)
+}
+
+func testStorageSequence(t *testing.T, s Storage) {
+	err := s.Update(func(tx StorageTx) error {
+		bkt, err := tx.CreateBucket(testBkt)
+		require.NoError(t, err)
+		require.Equal(t, uint64(0), bkt.Sequence())
+
+		for i := uint64(1); i <= 3; i++ {
+			n, err := bkt.NextSequence()
+			require.NoError(t, err)
+			require.Equal(t, i, n)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	// The sequence is rolled back with the transaction
+	err = s.Update(func(tx StorageTx) error {
+		n, err := tx.Bucket(testBkt).NextSequence()
+		require.NoError(t, err)
+		require.Equal(t, uint64(4), n)
+		return errTesting
+	})
+	require.Equal(t, errTesting, err)
+
+	err = s.View(func(tx StorageTx) error {
+		require.Equal(t, uint64(3), tx.Bucket(testBkt).Sequence())
+		return nil
+	})
+	require.NoError(t, err)
+}
+
+func testStorageRollback(t *testing.T, s Storage) {
+	err := s.Update(func(tx StorageTx) error {
+		bkt, err := tx.CreateBucket(testBkt)
+		require.NoError(t, err)
+		require.NoError(t, bkt.Put([]byte("a"), []byte("1")))
+		require.NoError(t, bkt.Put([]byte("b"), []byte("2")))
+		return nil
+	})
+	require.NoError(t, err)
+
+	check := func() {
+		err := s.View(func(tx StorageTx) error {
+			bkt := tx.Bucket(testBkt)
+			require.NotNil(t, bkt)
+
+			var got []string
+			require.NoError(t, bkt.ForEach(func(k, v []byte) error {
+				got = append(got, string(k)+"="+string(v))
+				return nil
+			}))
+			require.Equal(t, []string{"a=1", "b=2"}, got)
+
+			require.Nil(t, tx.Bucket([]byte("other")))
+			return nil
+		})
+		require.NoError(t, err)
+	}
+
+	modify := func(tx StorageTx) {
+		bkt := tx.Bucket(testBkt)
+		require.NoError(t, bkt.Put([]byte("a"), []byte("3")))
+		require.NoError(t, bkt.Put([]byte("c"), []byte("4")))
+		require.NoError(t, bkt.Delete([]byte("b")))
+
+		_, err := tx.CreateBucket([]byte("other"))
+		require.NoError(t, err)
+
+		// Reset the bucket
+		require.NoError(t, tx.DeleteBucket(testBkt))
+		bkt, err = tx.CreateBucket(testBkt)
+		require.NoError(t, err)
+		require.NoError(t, bkt.Put([]byte("d"), []byte("5")))
+	}
+
+	// The changes are discarded if the transaction returns an error
+	err = s.Update(func(tx StorageTx) error {
+		modify(tx)
+		return errTesting
+	})
+	require.Equal(t, errTesting, err)
+	check()
+
+	// The changes are discarded if the transaction panics
+	require.Panics(t, func() {
+		s.Update(func(tx StorageTx) error { // nolint: errcheck
+			modify(tx)
+			panic("testing")
+		})
+	})
+	check()
+}
+
+func testStorageReadOnlyTx(t *testing.T, s Storage) {
+	err := s.Update(func(tx StorageTx) error {
+		_, err := tx.CreateBucket(testBkt)
+		return err
+	})
+	require.NoError(t, err)
+
+	err = s.View(func(tx StorageTx) error {
+		require.False(t, tx.Writable())
+
+		bkt := tx.Bucket(testBkt)
+		require.Error(t, bkt.Put([]byte("a"), []byte("1")))
+		require.Error(t, bkt.Delete([]byte("a")))
+		_, err := bkt.NextSequence()
+		require.Error(t, err)
+
+		_, err = tx.CreateBucket([]byte("other"))
+		require.Error(t, err)
+		_, err = tx.CreateBucketIfNotExists([]byte("other"))
+		require.Error(t, err)
+		require.Error(t, tx.DeleteBucket(testBkt))
+		return nil
+	})
+	require.NoError(t, err)
+
+	// Errors are returned from View
+	err = s.View(func(tx StorageTx) error {
+		return errTesting
+	})
+	require.Equal(t, errTesting, err)
+}
+
+func testStorageDBHelpers(t *testing.T, s Storage) {
+	db := NewDB(s)
+	db.DurationLog = false
+
+	err := db.Update("", func(tx *Tx) error {
+		require.NoError(t, CreateBuckets(tx, [][]byte{testBkt}))
+		require.True(t, Exists(tx, testBkt))
+		require.False(t, Exists(tx, []byte("other")))
+
+		_, err := GetBucketValue(tx, []byte("other"), []byte("a"))
+		require.Equal(t, NewErrBucketNotExist([]byte("other")), err)
+
+		require.NoError(t, PutBucketValue(tx, testBkt, Itob(1), []byte("1")))
+		require.NoError(t, PutBucketValue(tx, testBkt, Itob(2), []byte("2")))
+
+		v, err := GetBucketValue(tx, testBkt, Itob(1))
+		require.NoError(t, err)
+		require.Equal(t, []byte("1"), v)
+
+		ok, err := BucketHasKey(tx, testBkt, Itob(2))
+		require.NoError(t, err)
+		require.True(t, ok)
+
+		n, err := NextSequence(tx, testBkt)
+		require.NoError(t, err)
+		require.Equal(t, uint64(1), n)
+		return nil
+	})
+	require.NoError(t, err)
+
+	err = db.Update("", func(tx *Tx) error {
+		n, err := Len(tx, testBkt)
+		require.NoError(t, err)
+		require.Equal(t, uint64(2), n)
+
+		require.NoError(t, Delete(tx, testBkt, Itob(1)))
+
+		var keys []uint64
+		require.NoError(t, ForEach(tx, testBkt, func(k, v []byte) error {
+			keys = append(keys, Btoi(k))
+			return nil
+		}))
+		require.Equal(t, []uint64{2}, keys)
+
+		return Reset(tx, testBkt)
+	})
+	require.NoError(t, err)
+
+	err = db.View("", func(tx *Tx) error {
+		empty, err := IsEmpty(tx, testBkt)
+		require.NoError(t, err)
+		require.True(t, empty)
+		return nil
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, db.Close())
+}
+
+func TestStorageFileTx(t *testing.T) {
+	s, shutdown := prepareBoltStorage(t)
+	defer shutdown()
+
+	err := s.Update(func(tx StorageTx) error {
+		bkt, err := tx.CreateBucket(testBkt)
+		require.NoError(t, err)
+		return bkt.Put([]byte("a"), []byte("1"))
+	})
+	require.NoError(t, err)
+
+	f, err := ioutil.TempFile("", "testdb-copy")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+
+	err = s.View(func(tx StorageTx) error {
+		ftx, ok := tx.(FileTx)
+		require.True(t, ok)
+
+		n, err := ftx.WriteTo(f)
+		require.NoError(t, err)
+		require.Equal(t, ftx.Size(), n)
+		return nil
+	})
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	// The copy is a valid bolt file with the same data
+	db, err := bolt.Open(f.Name(), 0600, &bolt.Options{
+		ReadOnly: true,
+	})
+	require.NoError(t, err)
+	defer db.Close()
+
+	err = NewBoltStorage(db).View(func(tx StorageTx) error {
+		bkt := tx.Bucket(testBkt)
+		require.NotNil(t, bkt)
+		require.Equal(t, []byte("1"), bkt.Get([]byte("a")))
+		return nil
+	})
+	require.NoError(t, err)
+
+	// The in-memory storage has no file
+	m, shutdown := prepareMemoryStorage(t)
+	defer shutdown()
+
+	err = m.View(func(tx StorageTx) error {
+		_, ok := tx.(FileTx)
+		require.False(t, ok)
+		return nil
+	})
+	require.NoError(t, err)
+}
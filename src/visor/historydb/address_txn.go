@@ -63,6 +63,38 @@ func (atx *addressTxns) add(tx *dbutil.Tx, addr cipher.Address, hash cipher.SHA2
 	return dbutil.PutBucketValue(tx, AddressTxnsBkt, addr.Bytes(), buf)
 }
 
+// remove removes a hash from an address's hash list
+func (atx *addressTxns) remove(tx *dbutil.Tx, addr cipher.Address, hash cipher.SHA256) error {
+	hashes, err := atx.get(tx, addr)
+	if err != nil {
+		return err
+	}
+
+	newHashes := make([]cipher.SHA256, 0, len(hashes))
+	for _, h := range hashes {
+		if h != hash {
+			newHashes = append(newHashes, h)
+		}
+	}
+
+	if len(newHashes) == len(hashes) {
+		return nil
+	}
+
+	if len(newHashes) == 0 {
+		return dbutil.Delete(tx, AddressTxnsBkt, addr.Bytes())
+	}
+
+	buf, err := encodeHashesWrapper(&hashesWrapper{
+		Hashes: newHashes,
+	})
+	if err != nil {
+		return err
+	}
+
+	return dbutil.PutBucketValue(tx, AddressTxnsBkt, addr.Bytes(), buf)
+}
+
 // isEmpty checks if address transactions bucket is empty
 func (atx *addressTxns) isEmpty(tx *dbutil.Tx) (bool, error) {
 	return dbutil.IsEmpty(tx, AddressTxnsBkt)
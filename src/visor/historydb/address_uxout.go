@@ -55,6 +55,38 @@ func (au *addressUx) add(tx *dbutil.Tx, address cipher.Address, uxHash cipher.SH
 	return dbutil.PutBucketValue(tx, AddressUxBkt, address.Bytes(), buf)
 }
 
+// remove removes a hash from an address's hash list
+func (au *addressUx) remove(tx *dbutil.Tx, address cipher.Address, hash cipher.SHA256) error {
+	hashes, err := au.get(tx, address)
+	if err != nil {
+		return err
+	}
+
+	newHashes := make([]cipher.SHA256, 0, len(hashes))
+	for _, h := range hashes {
+		if h != hash {
+			newHashes = append(newHashes, h)
+		}
+	}
+
+	if len(newHashes) == len(hashes) {
+		return nil
+	}
+
+	if len(newHashes) == 0 {
+		return dbutil.Delete(tx, AddressUxBkt, address.Bytes())
+	}
+
+	buf, err := encodeHashesWrapper(&hashesWrapper{
+		Hashes: newHashes,
+	})
+	if err != nil {
+		return err
+	}
+
+	return dbutil.PutBucketValue(tx, AddressUxBkt, address.Bytes(), buf)
+}
+
 // isEmpty checks if the addressUx bucket is empty
 func (au *addressUx) isEmpty(tx *dbutil.Tx) (bool, error) {
 	return dbutil.IsEmpty(tx, AddressUxBkt)
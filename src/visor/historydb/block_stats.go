@@ -0,0 +1,427 @@
+package historydb
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/params"
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+)
+
+//go:generate skyencoder -unexported -struct BlockStats
+//go:generate skyencoder -unexported -struct DailyStats
+
+const secondsPerDay = 24 * 60 * 60
+
+var (
+	// BlockStatsBkt maps block seqs to the statistics of the chain at the block
+	BlockStatsBkt = []byte("block_stats")
+	// DailyStatsBkt maps the big-endian unix time of the start of a day (UTC) to the statistics of the blocks of the day
+	DailyStatsBkt = []byte("daily_stats")
+	// DailyActiveAddrsBkt counts the blocks of a day in which an address was active.
+	// The keys are the big-endian unix time of the start of the day followed by the address bytes.
+	DailyActiveAddrsBkt = []byte("daily_active_addresses")
+)
+
+// BlockStats are the statistics of a block and of the unspent outputs after the block was executed
+type BlockStats struct {
+	Seq  uint64
+	Time uint64
+	// Transactions is the number of transactions in the block
+	Transactions uint64
+	// FeesBurned is the number of coin hours burned by the transactions in the block
+	FeesBurned uint64
+	// ActiveAddresses is the number of distinct addresses that sent or received coins in the block
+	ActiveAddresses uint64
+	// Coins is the number of coins in the unspent outputs
+	Coins uint64
+	// DistributionCoins is the number of coins in the unspent outputs of the distribution addresses
+	DistributionCoins uint64
+	// Hours is the sum of the coin hours of the unspent outputs when they were created
+	Hours uint64
+	// WholeCoins is the sum of the whole coins of each unspent output
+	WholeCoins uint64
+	// WholeCoinTimes is the sum of the whole coins of each unspent output multiplied by its creation time
+	WholeCoinTimes uint64
+}
+
+// CoinSupply returns the number of coins that are not held by the distribution addresses
+func (s BlockStats) CoinSupply() uint64 {
+	return s.Coins - s.DistributionCoins
+}
+
+// CoinHourSupply returns the coin hours of the unspent outputs at the block time.
+// The hours earned by each output are approximated from its whole coins, the same as coin.UxOut.CoinHours
+// does for the whole coins of an output, but the hours earned by fractional coins are ignored
+// and the earned coin seconds are summed before being converted to hours.
+func (s BlockStats) CoinHourSupply() uint64 {
+	coinSeconds := s.WholeCoins*s.Time - s.WholeCoinTimes
+	return s.Hours + coinSeconds/3600
+}
+
+// DailyStats are the statistics of the blocks of a day (UTC)
+type DailyStats struct {
+	// Day is the unix time of the start of the day
+	Day uint64
+	// Blocks is the number of blocks of the day
+	Blocks uint64
+	// LastSeq is the seq of the last block of the day
+	LastSeq uint64
+	// Transactions is the number of transactions in the blocks of the day
+	Transactions uint64
+	// FeesBurned is the number of coin hours burned by the transactions in the blocks of the day
+	FeesBurned uint64
+	// ActiveAddresses is the number of distinct addresses that sent or received coins during the day
+	ActiveAddresses uint64
+}
+
+// DayStart returns the unix time of the start of the day (UTC) of t
+func DayStart(t uint64) uint64 {
+	return t - t%secondsPerDay
+}
+
+func dailyActiveAddrKey(day uint64, addr cipher.Address) []byte {
+	addrBytes := addr.Bytes()
+	key := make([]byte, 8+len(addrBytes))
+	binary.BigEndian.PutUint64(key[:8], day)
+	copy(key[8:], addrBytes)
+	return key
+}
+
+// blockStats buckets for the block and daily statistics of the chain
+type blockStats struct{}
+
+func (bs *blockStats) get(tx *dbutil.Tx, seq uint64) (*BlockStats, error) {
+	var s BlockStats
+	v, err := dbutil.GetBucketValueNoCopy(tx, BlockStatsBkt, dbutil.Itob(seq))
+	if err != nil {
+		return nil, err
+	} else if v == nil {
+		return nil, nil
+	}
+
+	if err := decodeBlockStatsExact(v, &s); err != nil {
+		return nil, err
+	}
+
+	return &s, nil
+}
+
+func (bs *blockStats) getDay(tx *dbutil.Tx, day uint64) (*DailyStats, error) {
+	var s DailyStats
+	v, err := dbutil.GetBucketValueNoCopy(tx, DailyStatsBkt, dbutil.Itob(day))
+	if err != nil {
+		return nil, err
+	} else if v == nil {
+		return nil, nil
+	}
+
+	if err := decodeDailyStatsExact(v, &s); err != nil {
+		return nil, err
+	}
+
+	return &s, nil
+}
+
+func (bs *blockStats) putDay(tx *dbutil.Tx, s DailyStats) error {
+	buf, err := encodeDailyStats(&s)
+	if err != nil {
+		return err
+	}
+
+	return dbutil.PutBucketValue(tx, DailyStatsBkt, dbutil.Itob(s.Day), buf)
+}
+
+// add computes the statistics of a block from the statistics of the previous block and adds them.
+// The block's inputs must be in outputs. The statistics are not added if the previous block has none,
+// which is the case for histories parsed before the statistics were added, until they are backfilled.
+func (bs *blockStats) add(tx *dbutil.Tx, outputs *uxOuts, b coin.Block) error {
+	var s BlockStats
+	if b.Seq() > 0 {
+		prev, err := bs.get(tx, b.Seq()-1)
+		if err != nil {
+			return err
+		}
+
+		if prev == nil {
+			return nil
+		}
+
+		s = *prev
+	}
+
+	prevTime := s.Time
+	s.Seq = b.Seq()
+	s.Time = b.Time()
+	s.Transactions = uint64(len(b.Body.Transactions))
+	s.FeesBurned = 0
+
+	distributionAddrs := make(map[cipher.Address]struct{})
+	for _, a := range params.GetDistributionAddressesDecoded() {
+		distributionAddrs[a] = struct{}{}
+	}
+
+	var addrs []cipher.Address
+	addrsMap := make(map[cipher.Address]struct{})
+	addAddr := func(a cipher.Address) {
+		if _, ok := addrsMap[a]; !ok {
+			addrsMap[a] = struct{}{}
+			addrs = append(addrs, a)
+		}
+	}
+
+	for _, t := range b.Body.Transactions {
+		var inHours uint64
+		for _, in := range t.In {
+			o, err := outputs.get(tx, in)
+			if err != nil {
+				return err
+			}
+
+			if o == nil {
+				return errors.New("blockStats.add: transaction input not found in outputs bucket")
+			}
+
+			ux := o.Out
+			addAddr(ux.Body.Address)
+
+			// Fees are calculated from the previous block's time
+			hours, err := ux.CoinHours(prevTime)
+			if err != nil {
+				return err
+			}
+			inHours += hours
+
+			wholeCoins := ux.Body.Coins / 1e6
+			s.Coins -= ux.Body.Coins
+			s.Hours -= ux.Body.Hours
+			s.WholeCoins -= wholeCoins
+			s.WholeCoinTimes -= wholeCoins * ux.Head.Time
+			if _, ok := distributionAddrs[ux.Body.Address]; ok {
+				s.DistributionCoins -= ux.Body.Coins
+			}
+		}
+
+		var outHours uint64
+		for _, ux := range coin.CreateUnspents(b.Head, t) {
+			addAddr(ux.Body.Address)
+			outHours += ux.Body.Hours
+
+			wholeCoins := ux.Body.Coins / 1e6
+			s.Coins += ux.Body.Coins
+			s.Hours += ux.Body.Hours
+			s.WholeCoins += wholeCoins
+			s.WholeCoinTimes += wholeCoins * ux.Head.Time
+			if _, ok := distributionAddrs[ux.Body.Address]; ok {
+				s.DistributionCoins += ux.Body.Coins
+			}
+		}
+
+		// The genesis transaction has no inputs and creates the hours of its output
+		if inHours > outHours {
+			s.FeesBurned += inHours - outHours
+		}
+	}
+
+	s.ActiveAddresses = uint64(len(addrs))
+
+	buf, err := encodeBlockStats(&s)
+	if err != nil {
+		return err
+	}
+
+	if err := dbutil.PutBucketValue(tx, BlockStatsBkt, dbutil.Itob(s.Seq), buf); err != nil {
+		return err
+	}
+
+	// Add the block to the statistics of its day
+	day := DayStart(s.Time)
+	ds, err := bs.getDay(tx, day)
+	if err != nil {
+		return err
+	}
+
+	if ds == nil {
+		ds = &DailyStats{
+			Day: day,
+		}
+	}
+
+	ds.Blocks++
+	ds.LastSeq = s.Seq
+	ds.Transactions += s.Transactions
+	ds.FeesBurned += s.FeesBurned
+
+	for _, a := range addrs {
+		key := dailyActiveAddrKey(day, a)
+		v, err := dbutil.GetBucketValueNoCopy(tx, DailyActiveAddrsBkt, key)
+		if err != nil {
+			return err
+		}
+
+		var n uint64
+		if v != nil {
+			n = dbutil.Btoi(v)
+		} else {
+			ds.ActiveAddresses++
+		}
+
+		if err := dbutil.PutBucketValue(tx, DailyActiveAddrsBkt, key, dbutil.Itob(n+1)); err != nil {
+			return err
+		}
+	}
+
+	return bs.putDay(tx, *ds)
+}
+
+// remove removes the statistics of the most recently added block.
+// The block's inputs must be in outputs.
+func (bs *blockStats) remove(tx *dbutil.Tx, outputs *uxOuts, b coin.Block) error {
+	s, err := bs.get(tx, b.Seq())
+	if err != nil {
+		return err
+	}
+
+	if s == nil {
+		return nil
+	}
+
+	if err := dbutil.Delete(tx, BlockStatsBkt, dbutil.Itob(b.Seq())); err != nil {
+		return err
+	}
+
+	day := DayStart(s.Time)
+	ds, err := bs.getDay(tx, day)
+	if err != nil {
+		return err
+	}
+
+	if ds == nil || ds.LastSeq != s.Seq {
+		return fmt.Errorf("blockStats.remove: block %d is not the last block of its day", s.Seq)
+	}
+
+	// Blocks are ordered by time, so the previous block is the last block of the day if the day has another block
+	ds.Blocks--
+	ds.LastSeq--
+	ds.Transactions -= s.Transactions
+	ds.FeesBurned -= s.FeesBurned
+
+	addrsMap := make(map[cipher.Address]struct{})
+	for _, t := range b.Body.Transactions {
+		for _, in := range t.In {
+			o, err := outputs.get(tx, in)
+			if err != nil {
+				return err
+			}
+
+			if o == nil {
+				return errors.New("blockStats.remove: transaction input not found in outputs bucket")
+			}
+
+			addrsMap[o.Out.Body.Address] = struct{}{}
+		}
+
+		for _, o := range t.Out {
+			addrsMap[o.Address] = struct{}{}
+		}
+	}
+
+	for a := range addrsMap {
+		key := dailyActiveAddrKey(day, a)
+		v, err := dbutil.GetBucketValueNoCopy(tx, DailyActiveAddrsBkt, key)
+		if err != nil {
+			return err
+		}
+
+		if v == nil {
+			return fmt.Errorf("blockStats.remove: address %s is not active on day %d", a, day)
+		}
+
+		n := dbutil.Btoi(v)
+		if n > 1 {
+			if err := dbutil.PutBucketValue(tx, DailyActiveAddrsBkt, key, dbutil.Itob(n-1)); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := dbutil.Delete(tx, DailyActiveAddrsBkt, key); err != nil {
+			return err
+		}
+		ds.ActiveAddresses--
+	}
+
+	if ds.Blocks == 0 {
+		return dbutil.Delete(tx, DailyStatsBkt, dbutil.Itob(day))
+	}
+
+	return bs.putDay(tx, *ds)
+}
+
+// forEach calls f with the statistics of the blocks from seq start to end, inclusive
+func (bs *blockStats) forEach(tx *dbutil.Tx, start, end uint64, f func(BlockStats) error) error {
+	if start > end {
+		return nil
+	}
+
+	bkt := tx.Bucket(BlockStatsBkt)
+	if bkt == nil {
+		return dbutil.NewErrBucketNotExist(BlockStatsBkt)
+	}
+
+	c := bkt.Cursor()
+	for k, v := c.Seek(dbutil.Itob(start)); k != nil && dbutil.Btoi(k) <= end; k, v = c.Next() {
+		var s BlockStats
+		if err := decodeBlockStatsExact(v, &s); err != nil {
+			return err
+		}
+
+		if err := f(s); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// forEachDay calls f with the statistics of the days that start between start and end, inclusive
+func (bs *blockStats) forEachDay(tx *dbutil.Tx, start, end uint64, f func(DailyStats) error) error {
+	if start > end {
+		return nil
+	}
+
+	bkt := tx.Bucket(DailyStatsBkt)
+	if bkt == nil {
+		return dbutil.NewErrBucketNotExist(DailyStatsBkt)
+	}
+
+	c := bkt.Cursor()
+	for k, v := c.Seek(dbutil.Itob(start)); k != nil && dbutil.Btoi(k) <= end; k, v = c.Next() {
+		var s DailyStats
+		if err := decodeDailyStatsExact(v, &s); err != nil {
+			return err
+		}
+
+		if err := f(s); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// reset resets the buckets
+func (bs *blockStats) reset(tx *dbutil.Tx) error {
+	if err := dbutil.Reset(tx, BlockStatsBkt); err != nil {
+		return err
+	}
+
+	if err := dbutil.Reset(tx, DailyStatsBkt); err != nil {
+		return err
+	}
+
+	return dbutil.Reset(tx, DailyActiveAddrsBkt)
+}
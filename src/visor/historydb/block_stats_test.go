@@ -0,0 +1,210 @@
+package historydb
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/params"
+	"github.com/skycoin/skycoin/src/testutil"
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+)
+
+func makeStatsBlock(t *testing.T, prev coin.Block, tm uint64, in cipher.SHA256, outs ...coin.TransactionOutput) coin.Block {
+	txn := coin.Transaction{}
+	require.NoError(t, txn.PushInput(in))
+	for _, o := range outs {
+		require.NoError(t, txn.PushOutput(o.Address, o.Coins, o.Hours))
+	}
+
+	return coin.Block{
+		Head: coin.BlockHeader{
+			BkSeq:    prev.Seq() + 1,
+			Time:     tm,
+			PrevHash: prev.HashHeader(),
+		},
+		Body: coin.BlockBody{
+			Transactions: coin.Transactions{txn},
+		},
+	}
+}
+
+func TestBlockStats(t *testing.T) {
+	db, td := prepareDB(t)
+	defer td()
+
+	addrA := testutil.MakeAddress()
+	addrB := testutil.MakeAddress()
+	distAddr := params.GetDistributionAddressesDecoded()[0]
+
+	// The genesis block and block 1 are on the first day, block 2 is on the next day
+	genTime := uint64(100 * secondsPerDay)
+	gb, err := coin.NewGenesisBlock(addrA, 1000e6, genTime)
+	require.NoError(t, err)
+	genUx := coin.CreateUnspents(gb.Head, gb.Body.Transactions[0])[0]
+
+	b1 := makeStatsBlock(t, *gb, genTime+10*3600, genUx.Hash(),
+		coin.TransactionOutput{Address: addrB, Coins: 400e6, Hours: 100},
+		coin.TransactionOutput{Address: addrA, Coins: 600e6, Hours: 200},
+	)
+	b1Uxs := coin.CreateUnspents(b1.Head, b1.Body.Transactions[0])
+
+	b2 := makeStatsBlock(t, b1, genTime+secondsPerDay+3600, b1Uxs[0].Hash(),
+		coin.TransactionOutput{Address: distAddr, Coins: 400e6, Hours: 50},
+	)
+	b2Uxs := coin.CreateUnspents(b2.Head, b2.Body.Transactions[0])
+
+	blocks := []coin.Block{*gb, b1, b2}
+
+	expectedStats := []BlockStats{
+		{
+			Seq:             0,
+			Time:            genTime,
+			Transactions:    1,
+			ActiveAddresses: 1,
+			Coins:           1000e6,
+			Hours:           1000e6,
+			WholeCoins:      1000,
+			WholeCoinTimes:  1000 * genTime,
+		},
+		{
+			Seq:             1,
+			Time:            b1.Time(),
+			Transactions:    1,
+			FeesBurned:      1000e6 - 300,
+			ActiveAddresses: 2,
+			Coins:           1000e6,
+			Hours:           300,
+			WholeCoins:      1000,
+			WholeCoinTimes:  1000 * b1.Time(),
+		},
+		{
+			Seq:               2,
+			Time:              b2.Time(),
+			Transactions:      1,
+			FeesBurned:        50,
+			ActiveAddresses:   2,
+			Coins:             1000e6,
+			DistributionCoins: 400e6,
+			Hours:             250,
+			WholeCoins:        1000,
+			WholeCoinTimes:    600*b1.Time() + 400*b2.Time(),
+		},
+	}
+
+	expectedDays := []DailyStats{
+		{
+			Day:             genTime,
+			Blocks:          2,
+			LastSeq:         1,
+			Transactions:    2,
+			FeesBurned:      1000e6 - 300,
+			ActiveAddresses: 2,
+		},
+		{
+			Day:             genTime + secondsPerDay,
+			Blocks:          1,
+			LastSeq:         2,
+			Transactions:    1,
+			FeesBurned:      50,
+			ActiveAddresses: 2,
+		},
+	}
+
+	hd := New()
+
+	requireStats := func(tx *dbutil.Tx, stats []BlockStats, days []DailyStats) {
+		var gotStats []BlockStats
+		require.NoError(t, hd.ForEachBlockStats(tx, 0, 10, func(s BlockStats) error {
+			gotStats = append(gotStats, s)
+			return nil
+		}))
+		require.Equal(t, stats, gotStats)
+
+		var gotDays []DailyStats
+		require.NoError(t, hd.ForEachDailyStats(tx, 0, genTime+10*secondsPerDay, func(s DailyStats) error {
+			gotDays = append(gotDays, s)
+			return nil
+		}))
+		require.Equal(t, days, gotDays)
+	}
+
+	err = db.Update("", func(tx *dbutil.Tx) error {
+		for _, b := range blocks {
+			require.NoError(t, hd.ParseBlock(tx, b))
+		}
+
+		ready, err := hd.BlockStatsReady(tx)
+		require.NoError(t, err)
+		require.True(t, ready)
+
+		requireStats(tx, expectedStats, expectedDays)
+
+		// The supplies match the supplies of the unspent outputs
+		last := expectedStats[2]
+		require.Equal(t, uint64(600e6), last.CoinSupply())
+		var hours uint64
+		for _, ux := range []coin.UxOut{b1Uxs[1], b2Uxs[0]} {
+			h, err := ux.CoinHours(last.Time)
+			require.NoError(t, err)
+			hours += h
+		}
+		require.Equal(t, hours, last.CoinHourSupply())
+		require.Equal(t, uint64(250+600*15), last.CoinHourSupply())
+
+		// The ranges are inclusive
+		var seqs []uint64
+		require.NoError(t, hd.ForEachBlockStats(tx, 1, 1, func(s BlockStats) error {
+			seqs = append(seqs, s.Seq)
+			return nil
+		}))
+		require.Equal(t, []uint64{1}, seqs)
+
+		var days []uint64
+		require.NoError(t, hd.ForEachDailyStats(tx, genTime+1, genTime+secondsPerDay, func(s DailyStats) error {
+			days = append(days, s.Day)
+			return nil
+		}))
+		require.Equal(t, []uint64{genTime + secondsPerDay}, days)
+
+		// Backfilling the statistics of a parsed history recreates them
+		require.NoError(t, hd.ResetBlockStats(tx))
+		ready, err = hd.BlockStatsReady(tx)
+		require.NoError(t, err)
+		require.False(t, ready)
+
+		err = hd.ParseBlockStats(tx, blocks[1])
+		require.Error(t, err)
+
+		for _, b := range blocks {
+			require.NoError(t, hd.ParseBlockStats(tx, b))
+		}
+		requireStats(tx, expectedStats, expectedDays)
+
+		// Rolling back the blocks removes their statistics from the block and daily statistics
+		require.NoError(t, hd.RollbackBlock(tx, b2))
+		requireStats(tx, expectedStats[:2], expectedDays[:1])
+
+		require.NoError(t, hd.RollbackBlock(tx, b1))
+		requireStats(tx, expectedStats[:1], []DailyStats{
+			{
+				Day:             genTime,
+				Blocks:          1,
+				LastSeq:         0,
+				Transactions:    1,
+				ActiveAddresses: 1,
+			},
+		})
+
+		// Parsing the blocks again gives the same statistics
+		for _, b := range blocks[1:] {
+			require.NoError(t, hd.ParseBlock(tx, b))
+		}
+		requireStats(tx, expectedStats, expectedDays)
+
+		return nil
+	})
+	require.NoError(t, err)
+}
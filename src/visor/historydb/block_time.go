@@ -0,0 +1,78 @@
+package historydb
+
+import (
+	"encoding/binary"
+
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+)
+
+// BlockTimesBkt indexes the block seqs by block time.
+// The keys are the big-endian block time followed by the big-endian block seq, the values are the block seq.
+var BlockTimesBkt = []byte("block_times")
+
+// blockTimes bucket for looking up blocks by time
+type blockTimes struct{}
+
+func blockTimeKey(time, seq uint64) []byte {
+	key := make([]byte, 16)
+	binary.BigEndian.PutUint64(key[:8], time)
+	binary.BigEndian.PutUint64(key[8:], seq)
+	return key
+}
+
+// add adds a block to the index
+func (bt *blockTimes) add(tx *dbutil.Tx, b coin.Block) error {
+	return dbutil.PutBucketValue(tx, BlockTimesBkt, blockTimeKey(b.Time(), b.Seq()), dbutil.Itob(b.Seq()))
+}
+
+// remove removes a block from the index
+func (bt *blockTimes) remove(tx *dbutil.Tx, b coin.Block) error {
+	return dbutil.Delete(tx, BlockTimesBkt, blockTimeKey(b.Time(), b.Seq()))
+}
+
+// has returns true if the block is in the index
+func (bt *blockTimes) has(tx *dbutil.Tx, b coin.Block) (bool, error) {
+	return dbutil.BucketHasKey(tx, BlockTimesBkt, blockTimeKey(b.Time(), b.Seq()))
+}
+
+// seqRange returns the seqs of the first and the last block with a time between start and end, inclusive.
+// Returns false if no block has a time in the range.
+func (bt *blockTimes) seqRange(tx *dbutil.Tx, start, end uint64) (uint64, uint64, bool, error) {
+	if start > end {
+		return 0, 0, false, nil
+	}
+
+	bkt := tx.Bucket(BlockTimesBkt)
+	if bkt == nil {
+		return 0, 0, false, dbutil.NewErrBucketNotExist(BlockTimesBkt)
+	}
+
+	c := bkt.Cursor()
+
+	k, v := c.Seek(blockTimeKey(start, 0))
+	if k == nil || binary.BigEndian.Uint64(k[:8]) > end {
+		return 0, 0, false, nil
+	}
+	first := dbutil.Btoi(v)
+
+	// Seek to the first block after end, the last block of the range is the one before it
+	if end == ^uint64(0) {
+		k, v = c.Last()
+	} else if k, _ = c.Seek(blockTimeKey(end+1, 0)); k == nil {
+		k, v = c.Last()
+	} else {
+		k, v = c.Prev()
+	}
+
+	if k == nil {
+		return 0, 0, false, nil
+	}
+
+	return first, dbutil.Btoi(v), true, nil
+}
+
+// reset resets the bucket
+func (bt *blockTimes) reset(tx *dbutil.Tx) error {
+	return dbutil.Reset(tx, BlockTimesBkt)
+}
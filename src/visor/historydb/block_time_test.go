@@ -0,0 +1,139 @@
+package historydb
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+)
+
+func makeTimeBlocks(times ...uint64) []coin.Block {
+	blocks := make([]coin.Block, len(times))
+	for i, t := range times {
+		blocks[i] = coin.Block{
+			Head: coin.BlockHeader{
+				BkSeq: uint64(i),
+				Time:  t,
+			},
+		}
+	}
+	return blocks
+}
+
+func TestBlockTimesSeqRange(t *testing.T) {
+	blocks := makeTimeBlocks(100, 110, 120, 130)
+
+	cases := []struct {
+		name        string
+		start       uint64
+		end         uint64
+		first, last uint64
+		ok          bool
+	}{
+		{name: "all", start: 0, end: ^uint64(0), first: 0, last: 3, ok: true},
+		{name: "exact", start: 110, end: 120, first: 1, last: 2, ok: true},
+		{name: "between", start: 101, end: 129, first: 1, last: 2, ok: true},
+		{name: "one block", start: 130, end: 130, first: 3, last: 3, ok: true},
+		{name: "before the block at time", start: 0, end: 115, first: 0, last: 1, ok: true},
+		{name: "after end", start: 131, end: 200},
+		{name: "before start", start: 0, end: 99},
+		{name: "gap", start: 111, end: 119},
+		{name: "start after end", start: 120, end: 110},
+	}
+
+	db, td := prepareDB(t)
+	defer td()
+
+	hd := New()
+	err := db.Update("", func(tx *dbutil.Tx) error {
+		for _, b := range blocks {
+			require.NoError(t, hd.ParseBlock(tx, b))
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := db.View("", func(tx *dbutil.Tx) error {
+				first, last, ok, err := hd.GetBlockSeqsInTimeRange(tx, tc.start, tc.end)
+				require.NoError(t, err)
+				require.Equal(t, tc.ok, ok)
+				if ok {
+					require.Equal(t, tc.first, first)
+					require.Equal(t, tc.last, last)
+				}
+				return nil
+			})
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestBlockTimesIndexed(t *testing.T) {
+	blocks := makeTimeBlocks(100, 110, 120)
+
+	db, td := prepareDB(t)
+	defer td()
+
+	hd := New()
+	err := db.Update("", func(tx *dbutil.Tx) error {
+		indexed, err := hd.BlockTimesIndexed(tx)
+		require.NoError(t, err)
+		require.False(t, indexed)
+
+		// A history parsed before the index was added does not index its first blocks
+		require.NoError(t, hd.SetParsedBlockSeq(tx, 0))
+		require.NoError(t, hd.ParseBlock(tx, blocks[1]))
+
+		indexed, err = hd.BlockTimesIndexed(tx)
+		require.NoError(t, err)
+		require.False(t, indexed)
+
+		// The blocks parsed before the index was added are added from the first indexed block down
+		err = hd.AddBlockTime(tx, blocks[2])
+		require.Error(t, err)
+		require.NoError(t, hd.AddBlockTime(tx, blocks[0]))
+
+		indexed, err = hd.BlockTimesIndexed(tx)
+		require.NoError(t, err)
+		require.True(t, indexed)
+
+		first, last, ok, err := hd.GetBlockSeqsInTimeRange(tx, 0, 200)
+		require.NoError(t, err)
+		require.True(t, ok)
+		require.Equal(t, uint64(0), first)
+		require.Equal(t, uint64(1), last)
+
+		// Erasing and parsing the history again indexes all of the blocks
+		require.NoError(t, hd.Erase(tx))
+		for _, b := range blocks {
+			require.NoError(t, hd.ParseBlock(tx, b))
+		}
+
+		indexed, err = hd.BlockTimesIndexed(tx)
+		require.NoError(t, err)
+		require.True(t, indexed)
+
+		for _, b := range blocks {
+			sb := &coin.SignedBlock{Block: b}
+			require.NoError(t, hd.Verify(tx, sb, NewIndexesMap()))
+		}
+
+		// Rolling back a block removes it from the index
+		require.NoError(t, hd.RollbackBlock(tx, blocks[2]))
+		_, last, ok, err = hd.GetBlockSeqsInTimeRange(tx, 0, 200)
+		require.NoError(t, err)
+		require.True(t, ok)
+		require.Equal(t, uint64(1), last)
+
+		err = hd.Verify(tx, &coin.SignedBlock{Block: blocks[2]}, NewIndexesMap())
+		require.Error(t, err)
+		require.IsType(t, ErrHistoryDBCorrupted{}, err)
+
+		return nil
+	})
+	require.NoError(t, err)
+}
@@ -8,6 +8,8 @@ var (
 	// HistoryMetaBkt holds history metadata
 	HistoryMetaBkt  = []byte("history_meta")
 	parsedHeightKey = []byte("parsed_height")
+	// blockTimesStartKey is the seq of the first block added to the block time index
+	blockTimesStartKey = []byte("block_times_start")
 )
 
 // historyMeta bucket for storing block history meta info
@@ -30,6 +32,23 @@ func (hm *historyMeta) setParsedBlockSeq(tx *dbutil.Tx, h uint64) error {
 	return dbutil.PutBucketValue(tx, HistoryMetaBkt, parsedHeightKey, dbutil.Itob(h))
 }
 
+// blockTimesStart returns the seq of the first block added to the block time index
+func (hm *historyMeta) blockTimesStart(tx *dbutil.Tx) (uint64, bool, error) {
+	v, err := dbutil.GetBucketValue(tx, HistoryMetaBkt, blockTimesStartKey)
+	if err != nil {
+		return 0, false, err
+	} else if v == nil {
+		return 0, false, nil
+	}
+
+	return dbutil.Btoi(v), true, nil
+}
+
+// setBlockTimesStart sets the seq of the first block added to the block time index
+func (hm *historyMeta) setBlockTimesStart(tx *dbutil.Tx, seq uint64) error {
+	return dbutil.PutBucketValue(tx, HistoryMetaBkt, blockTimesStartKey, dbutil.Itob(seq))
+}
+
 // reset resets the bucket
 func (hm *historyMeta) reset(tx *dbutil.Tx) error {
 	return dbutil.Reset(tx, HistoryMetaBkt)
@@ -16,7 +16,7 @@ import (
 
 var logger = logging.MustGetLogger("historydb")
 
-// CreateBuckets creates bolt.DB buckets used by the historydb
+// CreateBuckets creates the storage buckets used by the historydb
 func CreateBuckets(tx *dbutil.Tx) error {
 	return dbutil.CreateBuckets(tx, [][]byte{
 		AddressTxnsBkt,
@@ -24,6 +24,10 @@ func CreateBuckets(tx *dbutil.Tx) error {
 		HistoryMetaBkt,
 		UxOutsBkt,
 		TransactionsBkt,
+		BlockTimesBkt,
+		BlockStatsBkt,
+		DailyStatsBkt,
+		DailyActiveAddrsBkt,
 	})
 }
 
@@ -33,6 +37,8 @@ type HistoryDB struct {
 	txns     *transactions // transactions bucket
 	addrUx   *addressUx    // bucket which stores all UxOuts that address received
 	addrTxns *addressTxns  // address related transaction bucket
+	times    *blockTimes   // block seqs indexed by block time
+	stats    *blockStats   // block and daily statistics of the chain
 	meta     *historyMeta  // stores history meta info
 }
 
@@ -43,6 +49,8 @@ func New() *HistoryDB {
 		txns:     &transactions{},
 		addrUx:   &addressUx{},
 		addrTxns: &addressTxns{},
+		times:    &blockTimes{},
+		stats:    &blockStats{},
 		meta:     &historyMeta{},
 	}
 }
@@ -105,6 +113,14 @@ func (hd *HistoryDB) Erase(tx *dbutil.Tx) error {
 		return err
 	}
 
+	if err := hd.times.reset(tx); err != nil {
+		return err
+	}
+
+	if err := hd.stats.reset(tx); err != nil {
+		return err
+	}
+
 	return hd.txns.reset(tx)
 }
 
@@ -179,9 +195,206 @@ func (hd *HistoryDB) ParseBlock(tx *dbutil.Tx, b coin.Block) error {
 		}
 	}
 
+	if err := hd.times.add(tx, b); err != nil {
+		return err
+	}
+
+	if err := hd.stats.add(tx, hd.outputs, b); err != nil {
+		return err
+	}
+
+	// Histories parsed before the block time index was added only index the blocks parsed since then
+	if _, ok, err := hd.meta.blockTimesStart(tx); err != nil {
+		return err
+	} else if !ok {
+		if err := hd.meta.setBlockTimesStart(tx, b.Seq()); err != nil {
+			return err
+		}
+	}
+
 	return hd.SetParsedBlockSeq(tx, b.Seq())
 }
 
+// RollbackBlock removes the indexes that ParseBlock built out of the block data.
+// b must be the most recently parsed block.
+func (hd *HistoryDB) RollbackBlock(tx *dbutil.Tx, b coin.Block) error {
+	parsedSeq, ok, err := hd.meta.parsedBlockSeq(tx)
+	if err != nil {
+		return err
+	}
+
+	if b.Seq() == 0 || !ok || parsedSeq != b.Seq() {
+		return fmt.Errorf("HistoryDB.RollbackBlock: block %d is not the most recently parsed block", b.Seq())
+	}
+
+	if err := hd.stats.remove(tx, hd.outputs, b); err != nil {
+		return err
+	}
+
+	txns := b.Body.Transactions
+	for i := len(txns) - 1; i >= 0; i-- {
+		t := txns[i]
+		spentTxnID := t.Hash()
+
+		// remove the tx out
+		uxArray := coin.CreateUnspents(b.Head, t)
+		for _, ux := range uxArray {
+			if err := hd.outputs.delete(tx, ux.Hash()); err != nil {
+				return err
+			}
+
+			if err := hd.addrUx.remove(tx, ux.Body.Address, ux.Hash()); err != nil {
+				return err
+			}
+
+			if err := hd.addrTxns.remove(tx, ux.Body.Address, spentTxnID); err != nil {
+				return err
+			}
+		}
+
+		for _, in := range t.In {
+			o, err := hd.outputs.get(tx, in)
+			if err != nil {
+				return err
+			}
+
+			if o == nil {
+				return errors.New("HistoryDB.RollbackBlock: transaction input not found in outputs bucket")
+			}
+
+			// the output is unspent again
+			o.SpentBlockSeq = 0
+			o.SpentTxnID = cipher.SHA256{}
+			if err := hd.outputs.put(tx, *o); err != nil {
+				return err
+			}
+
+			if err := hd.addrTxns.remove(tx, o.Out.Body.Address, spentTxnID); err != nil {
+				return err
+			}
+		}
+
+		if err := hd.txns.delete(tx, spentTxnID); err != nil {
+			return err
+		}
+	}
+
+	if err := hd.times.remove(tx, b); err != nil {
+		return err
+	}
+
+	return hd.SetParsedBlockSeq(tx, b.Seq()-1)
+}
+
+// BlockTimesIndexed returns true if all of the parsed blocks are in the block time index.
+// Histories parsed before the block time index was added only index the blocks parsed since then.
+func (hd *HistoryDB) BlockTimesIndexed(tx *dbutil.Tx) (bool, error) {
+	start, ok, err := hd.meta.blockTimesStart(tx)
+	if err != nil {
+		return false, err
+	}
+
+	return ok && start == 0, nil
+}
+
+// BlockTimesStart returns the seq of the first block in the block time index.
+// Returns false if no block has been added to the index.
+func (hd *HistoryDB) BlockTimesStart(tx *dbutil.Tx) (uint64, bool, error) {
+	return hd.meta.blockTimesStart(tx)
+}
+
+// AddBlockTime adds an already parsed block to the block time index of a history parsed before the index was added.
+// b must be the block before the first block in the index, or the last parsed block if the index is empty,
+// so the blocks are added from the most recent down to the genesis block.
+func (hd *HistoryDB) AddBlockTime(tx *dbutil.Tx, b coin.Block) error {
+	start, ok, err := hd.meta.blockTimesStart(tx)
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		parsedSeq, parsed, err := hd.meta.parsedBlockSeq(tx)
+		if err != nil {
+			return err
+		}
+
+		if !parsed {
+			return errors.New("HistoryDB.AddBlockTime: no block has been parsed")
+		}
+
+		start = parsedSeq + 1
+	}
+
+	if b.Seq()+1 != start {
+		return fmt.Errorf("HistoryDB.AddBlockTime: block %d is not the block before the first indexed block %d", b.Seq(), start)
+	}
+
+	if err := hd.times.add(tx, b); err != nil {
+		return err
+	}
+
+	return hd.meta.setBlockTimesStart(tx, b.Seq())
+}
+
+// GetBlockSeqsInTimeRange returns the seqs of the first and the last parsed block with a time between start and end, inclusive.
+// Returns false if no block has a time in the range. The result is incomplete if BlockTimesIndexed is false.
+func (hd *HistoryDB) GetBlockSeqsInTimeRange(tx *dbutil.Tx, start, end uint64) (uint64, uint64, bool, error) {
+	return hd.times.seqRange(tx, start, end)
+}
+
+// BlockStatsReady returns true if the statistics of all of the parsed blocks have been added.
+// Histories parsed before the statistics were added have none until they are backfilled with ResetBlockStats and ParseBlockStats.
+func (hd *HistoryDB) BlockStatsReady(tx *dbutil.Tx) (bool, error) {
+	seq, ok, err := hd.meta.parsedBlockSeq(tx)
+	if err != nil || !ok {
+		return false, err
+	}
+
+	s, err := hd.stats.get(tx, seq)
+	if err != nil {
+		return false, err
+	}
+
+	return s != nil, nil
+}
+
+// ResetBlockStats erases the block and daily statistics
+func (hd *HistoryDB) ResetBlockStats(tx *dbutil.Tx) error {
+	return hd.stats.reset(tx)
+}
+
+// ParseBlockStats adds the statistics of an already parsed block.
+// The statistics of the previous block must have been added.
+func (hd *HistoryDB) ParseBlockStats(tx *dbutil.Tx, b coin.Block) error {
+	if b.Seq() > 0 {
+		prev, err := hd.stats.get(tx, b.Seq()-1)
+		if err != nil {
+			return err
+		}
+
+		if prev == nil {
+			return fmt.Errorf("HistoryDB.ParseBlockStats: the statistics of block %d have not been added", b.Seq()-1)
+		}
+	}
+
+	return hd.stats.add(tx, hd.outputs, b)
+}
+
+// GetBlockStats returns the statistics of a block. Returns nil if the block has none.
+func (hd *HistoryDB) GetBlockStats(tx *dbutil.Tx, seq uint64) (*BlockStats, error) {
+	return hd.stats.get(tx, seq)
+}
+
+// ForEachBlockStats calls f with the statistics of the blocks from seq start to end, inclusive
+func (hd *HistoryDB) ForEachBlockStats(tx *dbutil.Tx, start, end uint64, f func(BlockStats) error) error {
+	return hd.stats.forEach(tx, start, end, f)
+}
+
+// ForEachDailyStats calls f with the statistics of the days that start between the unix times start and end, inclusive
+func (hd *HistoryDB) ForEachDailyStats(tx *dbutil.Tx, start, end uint64, f func(DailyStats) error) error {
+	return hd.stats.forEachDay(tx, start, end, f)
+}
+
 // GetTransaction get transaction by hash.
 func (hd HistoryDB) GetTransaction(tx *dbutil.Tx, hash cipher.SHA256) (*Transaction, error) {
 	return hd.txns.get(tx, hash)
@@ -212,6 +425,11 @@ func (hd HistoryDB) ForEachTxn(tx *dbutil.Tx, f func(cipher.SHA256, *Transaction
 	return hd.txns.forEach(tx, f)
 }
 
+// ForEachUxOut traverses the outputs bucket
+func (hd HistoryDB) ForEachUxOut(tx *dbutil.Tx, f func(*UxOut) error) error {
+	return hd.outputs.forEach(tx, f)
+}
+
 // IndexesMap is a goroutine safe address indexes map
 type IndexesMap struct {
 	value map[cipher.Address]AddressIndexes
@@ -375,6 +593,25 @@ func (hd HistoryDB) Verify(tx *dbutil.Tx, b *coin.SignedBlock, indexesMap *Index
 			}
 		}
 	}
+
+	// Checks the block time index
+	indexed, err := hd.BlockTimesIndexed(tx)
+	if err != nil {
+		return err
+	}
+
+	if indexed {
+		ok, err := hd.times.has(tx, b.Block)
+		if err != nil {
+			return err
+		}
+
+		if !ok {
+			err := fmt.Errorf("HistoryDB.Verify: block %d does not exist in the block time index", b.Seq())
+			return ErrHistoryDBCorrupted{err}
+		}
+	}
+
 	return nil
 }
 
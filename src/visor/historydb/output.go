@@ -74,6 +74,11 @@ func (ux *uxOuts) get(tx *dbutil.Tx, uxID cipher.SHA256) (*UxOut, error) {
 	return &out, nil
 }
 
+// delete removes the UxOut of given id
+func (ux *uxOuts) delete(tx *dbutil.Tx, uxID cipher.SHA256) error {
+	return dbutil.Delete(tx, UxOutsBkt, uxID[:])
+}
+
 // getArray returns uxOuts for a set of uxids, will return error if any of the uxids do not exist
 func (ux *uxOuts) getArray(tx *dbutil.Tx, uxIDs []cipher.SHA256) ([]UxOut, error) {
 	var outs []UxOut
@@ -91,6 +96,18 @@ func (ux *uxOuts) getArray(tx *dbutil.Tx, uxIDs []cipher.SHA256) ([]UxOut, error
 	return outs, nil
 }
 
+// forEach traverses the uxouts in the bucket
+func (ux *uxOuts) forEach(tx *dbutil.Tx, f func(*UxOut) error) error {
+	return dbutil.ForEach(tx, UxOutsBkt, func(_, v []byte) error {
+		var out UxOut
+		if err := decodeUxOutExact(v, &out); err != nil {
+			return err
+		}
+
+		return f(&out)
+	})
+}
+
 // isEmpty checks if the uxout bucekt is empty
 func (ux *uxOuts) isEmpty(tx *dbutil.Tx) (bool, error) {
 	return dbutil.IsEmpty(tx, UxOutsBkt)
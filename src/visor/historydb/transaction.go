@@ -79,6 +79,11 @@ func (txs *transactions) getArray(tx *dbutil.Tx, hashes []cipher.SHA256) ([]Tran
 	return txns, nil
 }
 
+// delete removes the transaction of given hash
+func (txs *transactions) delete(tx *dbutil.Tx, hash cipher.SHA256) error {
+	return dbutil.Delete(tx, TransactionsBkt, hash[:])
+}
+
 // isEmpty checks if transaction bucket is empty
 func (txs *transactions) isEmpty(tx *dbutil.Tx) (bool, error) {
 	return dbutil.IsEmpty(tx, TransactionsBkt)
@@ -24,6 +24,12 @@ type Historyer interface {
 	Erase(tx *dbutil.Tx) error
 	ParsedBlockSeq(tx *dbutil.Tx) (uint64, bool, error)
 	ForEachTxn(tx *dbutil.Tx, f func(cipher.SHA256, *historydb.Transaction) error) error
+	BlockTimesIndexed(tx *dbutil.Tx) (bool, error)
+	GetBlockSeqsInTimeRange(tx *dbutil.Tx, start, end uint64) (uint64, uint64, bool, error)
+	BlockStatsReady(tx *dbutil.Tx) (bool, error)
+	GetBlockStats(tx *dbutil.Tx, seq uint64) (*historydb.BlockStats, error)
+	ForEachBlockStats(tx *dbutil.Tx, start, end uint64, f func(historydb.BlockStats) error) error
+	ForEachDailyStats(tx *dbutil.Tx, start, end uint64, f func(historydb.DailyStats) error) error
 }
 
 // Blockchainer is the interface that provides methods for accessing the blockchain data
@@ -34,6 +40,7 @@ type Blockchainer interface {
 	GetLastBlocks(tx *dbutil.Tx, n uint64) ([]coin.SignedBlock, error)
 	GetSignedBlockByHash(tx *dbutil.Tx, hash cipher.SHA256) (*coin.SignedBlock, error)
 	GetSignedBlockBySeq(tx *dbutil.Tx, seq uint64) (*coin.SignedBlock, error)
+	GetBlockHeaderBySeq(tx *dbutil.Tx, seq uint64) (*coin.BlockHeader, error)
 	Unspent() blockdb.UnspentPooler
 	Len(tx *dbutil.Tx) (uint64, error)
 	Head(tx *dbutil.Tx) (*coin.SignedBlock, error)
@@ -45,6 +52,10 @@ type Blockchainer interface {
 	VerifySingleTxnHardConstraints(tx *dbutil.Tx, txn coin.Transaction, signed TxnSignedFlag) error
 	VerifySingleTxnSoftHardConstraints(tx *dbutil.Tx, txn coin.Transaction, verifyParams params.VerifyTxn, signed TxnSignedFlag) (*coin.SignedBlock, coin.UxArray, error)
 	TransactionFee(tx *dbutil.Tx, hours uint64) coin.FeeCalculator
+	PruneSeq(tx *dbutil.Tx) (uint64, bool, error)
+	Prune(tx *dbutil.Tx, keep uint64) (uint64, error)
+	BackfillRange(tx *dbutil.Tx) (uint64, uint64, bool, error)
+	BackfillBlock(tx *dbutil.Tx, b *coin.SignedBlock) error
 }
 
 // UnconfirmedTransactionPooler is the interface that provides methods for
@@ -0,0 +1,513 @@
+package visor
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/visor/blockdb"
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+	"github.com/skycoin/skycoin/src/visor/historydb"
+)
+
+var (
+	schemaVersionKey = []byte("schema_version")
+
+	// errMigrationDryRun is returned from a dry run's transaction to roll it back
+	errMigrationDryRun = errors.New("migration dry run")
+)
+
+// Migration is a step that upgrades the database schema to Version.
+// Migrations must be idempotent, because databases created before schema versions
+// were introduced run all of the migrations, whatever their actual state is.
+type Migration struct {
+	// Version is the schema version of the database after the migration is applied
+	Version uint64
+	// Name identifies the migration
+	Name string
+	// Description explains what the migration changes
+	Description string
+	// Apply migrates the database. progress can be called to report the progress of long running migrations.
+	Apply func(tx *dbutil.Tx, bc *Blockchain, progress func(msg string)) error
+}
+
+// migrations are the schema migrations, in the order they are applied.
+// The version of each migration must be one higher than the previous one.
+var migrations = []Migration{
+	{
+		Version:     1,
+		Name:        "reparse_history",
+		Description: "Rebuilds the transaction history if any of its buckets is empty",
+		Apply:       migrateReparseHistory,
+	},
+	{
+		Version:     2,
+		Name:        "create_block_undo_bucket",
+		Description: "Creates the bucket of block undo records. Blocks executed before the migration can not be rolled back",
+		Apply:       migrateCreateBlockUndoBucket,
+	},
+	{
+		Version:     3,
+		Name:        "backfill_block_stats",
+		Description: "Adds the block and daily chain statistics of the blocks parsed before the statistics were added",
+		Apply:       migrateBackfillBlockStats,
+	},
+	{
+		Version:     4,
+		Name:        "build_addr_balance_index",
+		Description: "Builds the address balance index of the rich list and address count from the unspent outputs",
+		Apply:       migrateBuildAddrBalanceIndex,
+	},
+	{
+		Version:     5,
+		Name:        "backfill_block_times",
+		Description: "Adds the blocks parsed before the block time index was added to the index",
+		Apply:       migrateBackfillBlockTimes,
+	},
+}
+
+// LatestSchemaVersion returns the schema version of a fully migrated database
+func LatestSchemaVersion() uint64 {
+	if len(migrations) == 0 {
+		return 0
+	}
+	return migrations[len(migrations)-1].Version
+}
+
+// GetSchemaVersion returns the schema version of the database.
+// Returns false if the schema version is not set, which is the case for new databases
+// and for databases created before schema versions were introduced.
+func GetSchemaVersion(db *dbutil.DB) (uint64, bool, error) {
+	var v uint64
+	var ok bool
+	if err := db.View("GetSchemaVersion", func(tx *dbutil.Tx) error {
+		var err error
+		v, ok, err = getSchemaVersion(tx)
+		return err
+	}); err != nil {
+		return 0, false, err
+	}
+
+	return v, ok, nil
+}
+
+func getSchemaVersion(tx *dbutil.Tx) (uint64, bool, error) {
+	v, err := dbutil.GetBucketValue(tx, MetaBkt, schemaVersionKey)
+	if err != nil {
+		switch err.(type) {
+		case dbutil.ErrBucketNotExist:
+			return 0, false, nil
+		default:
+			return 0, false, err
+		}
+	} else if v == nil {
+		return 0, false, nil
+	}
+
+	if len(v) != 8 {
+		return 0, false, fmt.Errorf("invalid schema version length %d", len(v))
+	}
+
+	return dbutil.Btoi(v), true, nil
+}
+
+func setSchemaVersion(tx *dbutil.Tx, version uint64) error {
+	if _, err := tx.CreateBucketIfNotExists(MetaBkt); err != nil {
+		return err
+	}
+
+	return dbutil.PutBucketValue(tx, MetaBkt, schemaVersionKey, dbutil.Itob(version))
+}
+
+// pendingMigrations returns the migrations that have not been applied to the database,
+// and the schema version of the database. A database without blocks is new and has nothing to migrate.
+func pendingMigrations(tx *dbutil.Tx) ([]Migration, uint64, error) {
+	version, ok, err := getSchemaVersion(tx)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if !ok {
+		empty := true
+		if dbutil.Exists(tx, blockdb.BlocksBkt) {
+			empty, err = dbutil.IsEmpty(tx, blockdb.BlocksBkt)
+			if err != nil {
+				return nil, 0, err
+			}
+		}
+
+		if empty {
+			return nil, LatestSchemaVersion(), nil
+		}
+	}
+
+	if version > LatestSchemaVersion() {
+		return nil, 0, fmt.Errorf("db schema version %d is newer than the latest supported schema version %d", version, LatestSchemaVersion())
+	}
+
+	var pending []Migration
+	for _, m := range migrations {
+		if m.Version > version {
+			pending = append(pending, m)
+		}
+	}
+
+	return pending, version, nil
+}
+
+// PendingMigrations returns the migrations that have not been applied to the database
+func PendingMigrations(db *dbutil.DB) ([]Migration, error) {
+	var pending []Migration
+	if err := db.View("PendingMigrations", func(tx *dbutil.Tx) error {
+		var err error
+		pending, _, err = pendingMigrations(tx)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+
+	return pending, nil
+}
+
+// MigrateOptions configures Migrate
+type MigrateOptions struct {
+	// DryRun applies the pending migrations in a single transaction that is rolled back
+	DryRun bool
+	// Backup copies the database file before applying the migrations.
+	// In-memory databases are not backed up.
+	Backup bool
+	// Progress is called when a migration starts, reports its progress and finishes
+	Progress func(m Migration, msg string)
+}
+
+// MigrateResult is the result of migrating the database
+type MigrateResult struct {
+	// From is the schema version before the migration
+	From uint64
+	// To is the schema version after the migration. For a dry run, it is the version the database would have.
+	To uint64
+	// Applied are the applied migrations
+	Applied []Migration
+	// BackupPath is the path of the backup copy of the database, if one was made
+	BackupPath string
+}
+
+// Migrate applies the pending schema migrations to the database.
+// Each migration is applied in its own transaction and records the new schema version,
+// so an interrupted migration resumes from the first migration that was not completed.
+func Migrate(db *dbutil.DB, opts MigrateOptions) (*MigrateResult, error) {
+	if db.IsReadOnly() {
+		return nil, errors.New("can not migrate a read-only database")
+	}
+
+	var pending []Migration
+	var version uint64
+	if err := db.View("Migrate pending", func(tx *dbutil.Tx) error {
+		var err error
+		pending, version, err = pendingMigrations(tx)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+
+	result := &MigrateResult{
+		From: version,
+		To:   version,
+	}
+
+	if len(pending) == 0 {
+		if opts.DryRun {
+			return result, nil
+		}
+
+		// Stamp new databases with the latest schema version
+		if err := db.Update("Migrate set schema version", func(tx *dbutil.Tx) error {
+			return setSchemaVersion(tx, version)
+		}); err != nil {
+			return nil, err
+		}
+
+		return result, nil
+	}
+
+	bc, err := NewBlockchain(db, BlockchainConfig{})
+	if err != nil {
+		return nil, err
+	}
+
+	progress := func(m Migration) func(string) {
+		return func(msg string) {
+			if opts.Progress != nil {
+				opts.Progress(m, msg)
+			}
+		}
+	}
+
+	if opts.DryRun {
+		// Later migrations may depend on the changes of earlier ones, so all of them are applied in one transaction
+		if err := db.Update("Migrate dry run", func(tx *dbutil.Tx) error {
+			for _, m := range pending {
+				if err := applyMigration(tx, bc, m, progress(m)); err != nil {
+					return err
+				}
+			}
+			return errMigrationDryRun
+		}); err != nil && err != errMigrationDryRun {
+			return nil, err
+		}
+
+		result.To = pending[len(pending)-1].Version
+		result.Applied = pending
+		return result, nil
+	}
+
+	if opts.Backup && db.Path() != "" {
+		backupPath := fmt.Sprintf("%s.schema-%d.%s.bak", db.Path(), version, time.Now().UTC().Format("20060102T150405"))
+		if _, err := BackupDBToFile(db, backupPath, false); err != nil {
+			return nil, fmt.Errorf("backup db failed: %v", err)
+		}
+		result.BackupPath = backupPath
+	}
+
+	for _, m := range pending {
+		if err := db.Update(fmt.Sprintf("Migrate %d %s", m.Version, m.Name), func(tx *dbutil.Tx) error {
+			if err := applyMigration(tx, bc, m, progress(m)); err != nil {
+				return err
+			}
+
+			return setSchemaVersion(tx, m.Version)
+		}); err != nil {
+			return result, fmt.Errorf("migration %d %s failed: %v", m.Version, m.Name, err)
+		}
+
+		result.To = m.Version
+		result.Applied = append(result.Applied, m)
+	}
+
+	return result, nil
+}
+
+func applyMigration(tx *dbutil.Tx, bc *Blockchain, m Migration, progress func(string)) error {
+	progress("started")
+	if err := m.Apply(tx, bc, progress); err != nil {
+		return err
+	}
+	progress("finished")
+	return nil
+}
+
+// migrateReparseHistory rebuilds the history if it is incomplete. Before schema versions were introduced,
+// the history was rebuilt at every startup where one of its buckets was empty.
+func migrateReparseHistory(tx *dbutil.Tx, bc *Blockchain, progress func(string)) error {
+	if err := historydb.CreateBuckets(tx); err != nil {
+		return err
+	}
+
+	// Pruned nodes and nodes that are backfilling do not parse the history
+	_, pruned, err := bc.PruneSeq(tx)
+	if err != nil {
+		return err
+	}
+
+	_, _, backfilling, err := bc.BackfillRange(tx)
+	if err != nil {
+		return err
+	}
+
+	if pruned || backfilling {
+		progress("history is not parsed by pruned or backfilling nodes, skipped")
+		return nil
+	}
+
+	history := historydb.New()
+	needsReset, err := history.NeedsReset(tx)
+	if err != nil {
+		return err
+	}
+
+	if !needsReset {
+		return nil
+	}
+
+	if err := history.Erase(tx); err != nil {
+		return err
+	}
+
+	headSeq, ok, err := bc.HeadSeq(tx)
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		return nil
+	}
+
+	progress(fmt.Sprintf("reparsing the history of %d blocks", headSeq+1))
+
+	return parseHistoryTo(tx, history, bc, headSeq, func(seq uint64) {
+		if (seq+1)%1000 == 0 || seq == headSeq {
+			progress(fmt.Sprintf("parsed block %d/%d", seq, headSeq))
+		}
+	})
+}
+
+func migrateCreateBlockUndoBucket(tx *dbutil.Tx, _ *Blockchain, _ func(string)) error {
+	_, err := tx.CreateBucketIfNotExists(blockdb.BlockUndoBkt)
+	return err
+}
+
+// migrateBackfillBlockStats adds the statistics of the parsed blocks to histories parsed before the statistics were added
+func migrateBackfillBlockStats(tx *dbutil.Tx, bc *Blockchain, progress func(string)) error {
+	if err := historydb.CreateBuckets(tx); err != nil {
+		return err
+	}
+
+	_, pruned, err := bc.PruneSeq(tx)
+	if err != nil {
+		return err
+	}
+
+	_, _, backfilling, err := bc.BackfillRange(tx)
+	if err != nil {
+		return err
+	}
+
+	if pruned || backfilling {
+		progress("history is not parsed by pruned or backfilling nodes, skipped")
+		return nil
+	}
+
+	history := historydb.New()
+	parsedSeq, ok, err := history.ParsedBlockSeq(tx)
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		return nil
+	}
+
+	ready, err := history.BlockStatsReady(tx)
+	if err != nil {
+		return err
+	}
+
+	if ready {
+		return nil
+	}
+
+	if err := history.ResetBlockStats(tx); err != nil {
+		return err
+	}
+
+	progress(fmt.Sprintf("adding the statistics of %d blocks", parsedSeq+1))
+
+	for seq := uint64(0); seq <= parsedSeq; seq++ {
+		b, err := bc.GetSignedBlockBySeq(tx, seq)
+		if err != nil {
+			return err
+		}
+
+		if b == nil {
+			return fmt.Errorf("no block exists in depth: %d", seq)
+		}
+
+		if err := history.ParseBlockStats(tx, b.Block); err != nil {
+			return err
+		}
+
+		if (seq+1)%1000 == 0 || seq == parsedSeq {
+			progress(fmt.Sprintf("added the statistics of block %d/%d", seq, parsedSeq))
+		}
+	}
+
+	return nil
+}
+
+// migrateBuildAddrBalanceIndex builds the address balance index of databases created before it was introduced.
+// The unspent pool is complete on pruned and backfilling nodes, so their index is built too.
+func migrateBuildAddrBalanceIndex(tx *dbutil.Tx, bc *Blockchain, progress func(string)) error {
+	headSeq, ok, err := bc.HeadSeq(tx)
+	if err != nil {
+		return err
+	}
+
+	// The index of an empty unspent pool is empty, it is updated when the genesis block is executed
+	if !ok {
+		return nil
+	}
+
+	progress(fmt.Sprintf("indexing the unspent outputs at block %d", headSeq))
+
+	return bc.Unspent().BuildAddrBalanceIndex(tx, headSeq)
+}
+
+// migrateBackfillBlockTimes adds the blocks parsed before the block time index was added to the index,
+// so that the index covers the whole chain and the blocks do not need to be searched
+func migrateBackfillBlockTimes(tx *dbutil.Tx, bc *Blockchain, progress func(string)) error {
+	if err := historydb.CreateBuckets(tx); err != nil {
+		return err
+	}
+
+	_, pruned, err := bc.PruneSeq(tx)
+	if err != nil {
+		return err
+	}
+
+	_, _, backfilling, err := bc.BackfillRange(tx)
+	if err != nil {
+		return err
+	}
+
+	if pruned || backfilling {
+		progress("history is not parsed by pruned or backfilling nodes, skipped")
+		return nil
+	}
+
+	history := historydb.New()
+	parsedSeq, ok, err := history.ParsedBlockSeq(tx)
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		return nil
+	}
+
+	start, ok, err := history.BlockTimesStart(tx)
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		start = parsedSeq + 1
+	}
+
+	if start == 0 {
+		return nil
+	}
+
+	progress(fmt.Sprintf("indexing the times of %d blocks", start))
+
+	for seq := start; seq > 0; seq-- {
+		h, err := bc.GetBlockHeaderBySeq(tx, seq-1)
+		if err != nil {
+			return err
+		}
+
+		if h == nil {
+			return fmt.Errorf("no block exists in depth: %d", seq-1)
+		}
+
+		if err := history.AddBlockTime(tx, coin.Block{Head: *h}); err != nil {
+			return err
+		}
+
+		if n := start - seq + 1; n%1000 == 0 || n == start {
+			progress(fmt.Sprintf("indexed the times of %d/%d blocks", n, start))
+		}
+	}
+
+	return nil
+}
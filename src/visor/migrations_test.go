@@ -0,0 +1,439 @@
+package visor
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/params"
+	"github.com/skycoin/skycoin/src/testutil"
+	"github.com/skycoin/skycoin/src/visor/blockdb"
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+	"github.com/skycoin/skycoin/src/visor/historydb"
+)
+
+// makeLegacyDB creates a database with a genesis block, as it was before schema versions were introduced,
+// with an empty history bucket and without the block undo bucket and the address balance index
+func makeLegacyDB(t *testing.T, db *dbutil.DB) {
+	cfg := NewConfig()
+	cfg.BlockchainPubkey = genPublic
+	cfg.GenesisAddress = genAddress
+
+	v, err := New(cfg, db, nil)
+	require.NoError(t, err)
+	addGenesisBlockToVisor(t, v)
+
+	err = db.Update("", func(tx *dbutil.Tx) error {
+		if err := dbutil.Reset(tx, historydb.AddressTxnsBkt); err != nil {
+			return err
+		}
+		if err := tx.DeleteBucket(blockdb.UnspentPoolAddrBalanceBkt); err != nil {
+			return err
+		}
+		if err := tx.DeleteBucket(blockdb.UnspentPoolRichlistBkt); err != nil {
+			return err
+		}
+		if err := dbutil.Delete(tx, blockdb.UnspentMetaBkt, []byte("addr_balance_index_height")); err != nil {
+			return err
+		}
+		return tx.DeleteBucket(blockdb.BlockUndoBkt)
+	})
+	require.NoError(t, err)
+}
+
+func requireSchemaVersion(t *testing.T, db *dbutil.DB, version uint64, ok bool) {
+	v, vOk, err := GetSchemaVersion(db)
+	require.NoError(t, err)
+	require.Equal(t, ok, vOk)
+	require.Equal(t, version, v)
+}
+
+func TestMigrateNewDB(t *testing.T) {
+	db, shutdown := prepareDB(t)
+	defer shutdown()
+
+	requireSchemaVersion(t, db, 0, false)
+
+	pending, err := PendingMigrations(db)
+	require.NoError(t, err)
+	require.Empty(t, pending)
+
+	result, err := Migrate(db, MigrateOptions{})
+	require.NoError(t, err)
+	require.Equal(t, LatestSchemaVersion(), result.From)
+	require.Equal(t, LatestSchemaVersion(), result.To)
+	require.Empty(t, result.Applied)
+
+	requireSchemaVersion(t, db, LatestSchemaVersion(), true)
+}
+
+func TestMigrateLegacyDB(t *testing.T) {
+	db, shutdown := prepareDB(t)
+	defer shutdown()
+
+	makeLegacyDB(t, db)
+
+	pending, err := PendingMigrations(db)
+	require.NoError(t, err)
+	require.Len(t, pending, len(migrations))
+
+	needsReset := func() bool {
+		var reset bool
+		err := db.View("", func(tx *dbutil.Tx) error {
+			var err error
+			reset, err = historydb.New().NeedsReset(tx)
+			return err
+		})
+		require.NoError(t, err)
+		return reset
+	}
+
+	undoBktExists := func() bool {
+		var exists bool
+		err := db.View("", func(tx *dbutil.Tx) error {
+			exists = dbutil.Exists(tx, blockdb.BlockUndoBkt)
+			return nil
+		})
+		require.NoError(t, err)
+		return exists
+	}
+
+	addrBalanceIndexReady := func() bool {
+		var ready bool
+		err := db.View("", func(tx *dbutil.Tx) error {
+			bc, err := NewBlockchain(db, BlockchainConfig{})
+			if err != nil {
+				return err
+			}
+			ready, err = bc.Unspent().AddrBalanceIndexReady(tx)
+			return err
+		})
+		require.NoError(t, err)
+		return ready
+	}
+
+	require.True(t, needsReset())
+	require.False(t, undoBktExists())
+	require.False(t, addrBalanceIndexReady())
+
+	// A dry run applies the migrations and rolls them back
+	var progress []string
+	result, err := Migrate(db, MigrateOptions{
+		DryRun: true,
+		Progress: func(m Migration, msg string) {
+			progress = append(progress, m.Name+": "+msg)
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, uint64(0), result.From)
+	require.Equal(t, LatestSchemaVersion(), result.To)
+	require.Len(t, result.Applied, len(migrations))
+	require.Equal(t, []string{
+		"reparse_history: started",
+		"reparse_history: reparsing the history of 1 blocks",
+		"reparse_history: parsed block 0/0",
+		"reparse_history: finished",
+		"create_block_undo_bucket: started",
+		"create_block_undo_bucket: finished",
+		"backfill_block_stats: started",
+		"backfill_block_stats: finished",
+		"build_addr_balance_index: started",
+		"build_addr_balance_index: indexing the unspent outputs at block 0",
+		"build_addr_balance_index: finished",
+		"backfill_block_times: started",
+		"backfill_block_times: finished",
+	}, progress)
+
+	requireSchemaVersion(t, db, 0, false)
+	require.True(t, needsReset())
+	require.False(t, undoBktExists())
+	require.False(t, addrBalanceIndexReady())
+
+	result, err = Migrate(db, MigrateOptions{})
+	require.NoError(t, err)
+	require.Equal(t, uint64(0), result.From)
+	require.Equal(t, LatestSchemaVersion(), result.To)
+	require.Len(t, result.Applied, len(migrations))
+	require.Empty(t, result.BackupPath)
+
+	requireSchemaVersion(t, db, LatestSchemaVersion(), true)
+	require.False(t, needsReset())
+	require.True(t, undoBktExists())
+	require.True(t, addrBalanceIndexReady())
+
+	pending, err = PendingMigrations(db)
+	require.NoError(t, err)
+	require.Empty(t, pending)
+
+	// Nothing is left to migrate
+	result, err = Migrate(db, MigrateOptions{})
+	require.NoError(t, err)
+	require.Empty(t, result.Applied)
+}
+
+func TestMigrateBackfillBlockStats(t *testing.T) {
+	db, shutdown := prepareDB(t)
+	defer shutdown()
+
+	makeLegacyDB(t, db)
+
+	_, err := Migrate(db, MigrateOptions{})
+	require.NoError(t, err)
+
+	history := historydb.New()
+	getStats := func() *historydb.BlockStats {
+		var s *historydb.BlockStats
+		err := db.View("", func(tx *dbutil.Tx) error {
+			var err error
+			s, err = history.GetBlockStats(tx, 0)
+			return err
+		})
+		require.NoError(t, err)
+		return s
+	}
+
+	stats := getStats()
+	require.NotNil(t, stats)
+
+	// A history parsed before the statistics were added has none
+	err = db.Update("", func(tx *dbutil.Tx) error {
+		return history.ResetBlockStats(tx)
+	})
+	require.NoError(t, err)
+	require.Nil(t, getStats())
+
+	var progress []string
+	err = db.Update("", func(tx *dbutil.Tx) error {
+		bc, err := NewBlockchain(db, BlockchainConfig{})
+		require.NoError(t, err)
+		return migrateBackfillBlockStats(tx, bc, func(msg string) {
+			progress = append(progress, msg)
+		})
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		"adding the statistics of 1 blocks",
+		"added the statistics of block 0/0",
+	}, progress)
+	require.Equal(t, stats, getStats())
+
+	err = db.View("", func(tx *dbutil.Tx) error {
+		ready, err := history.BlockStatsReady(tx)
+		require.NoError(t, err)
+		require.True(t, ready)
+		return nil
+	})
+	require.NoError(t, err)
+}
+
+func TestMigrateBackfillBlockTimes(t *testing.T) {
+	db, shutdown := prepareDB(t)
+	defer shutdown()
+
+	cfg := NewConfig()
+	cfg.IsBlockPublisher = true
+	cfg.BlockchainPubkey = genPublic
+	cfg.BlockchainSeckey = genSecret
+	cfg.GenesisAddress = genAddress
+	cfg.GenesisCoinVolume = genCoins
+	cfg.GenesisTimestamp = genTime
+
+	v, err := New(cfg, db, nil)
+	require.NoError(t, err)
+	gb := addGenesisBlockToVisor(t, v)
+
+	uxs := coin.CreateUnspents(gb.Head, gb.Body.Transactions[0])
+	txn := makeUnspentsTxn(t, uxs, []cipher.SecKey{genSecret}, genAddress, 2, params.UserVerifyTxn.MaxDropletPrecision)
+	_, softErr, err := v.InjectForeignTransaction(txn)
+	require.NoError(t, err)
+	require.Nil(t, softErr)
+
+	err = db.Update("", func(tx *dbutil.Tx) error {
+		sb, err := v.createBlock(tx, genTime+1000)
+		if err != nil {
+			return err
+		}
+		return v.executeSignedBlock(tx, sb)
+	})
+	require.NoError(t, err)
+
+	history := historydb.New()
+	indexed := func() bool {
+		var ok bool
+		err := db.View("", func(tx *dbutil.Tx) error {
+			var err error
+			ok, err = history.BlockTimesIndexed(tx)
+			return err
+		})
+		require.NoError(t, err)
+		return ok
+	}
+
+	require.True(t, indexed())
+
+	backfill := func() []string {
+		var progress []string
+		err := db.Update("", func(tx *dbutil.Tx) error {
+			bc, err := NewBlockchain(db, BlockchainConfig{})
+			require.NoError(t, err)
+			return migrateBackfillBlockTimes(tx, bc, func(msg string) {
+				progress = append(progress, msg)
+			})
+		})
+		require.NoError(t, err)
+		return progress
+	}
+
+	// A history parsed before the block time index was added has an empty index
+	err = db.Update("", func(tx *dbutil.Tx) error {
+		if err := dbutil.Reset(tx, historydb.BlockTimesBkt); err != nil {
+			return err
+		}
+		return dbutil.Delete(tx, historydb.HistoryMetaBkt, []byte("block_times_start"))
+	})
+	require.NoError(t, err)
+	require.False(t, indexed())
+
+	require.Equal(t, []string{
+		"indexing the times of 2 blocks",
+		"indexed the times of 2/2 blocks",
+	}, backfill())
+	require.True(t, indexed())
+
+	err = db.View("", func(tx *dbutil.Tx) error {
+		first, last, ok, err := history.GetBlockSeqsInTimeRange(tx, genTime+1, genTime+1000)
+		require.NoError(t, err)
+		require.True(t, ok)
+		require.Equal(t, uint64(1), first)
+		require.Equal(t, uint64(1), last)
+		return nil
+	})
+	require.NoError(t, err)
+
+	// An index that covers the whole chain is left as is
+	require.Empty(t, backfill())
+}
+
+func TestMigrateFailure(t *testing.T) {
+	db, shutdown := prepareDB(t)
+	defer shutdown()
+
+	makeLegacyDB(t, db)
+
+	testBkt := []byte("migration_test")
+	errFailed := errors.New("migration failed")
+
+	defer func(m []Migration) {
+		migrations = m
+	}(migrations)
+	migrations = []Migration{
+		{
+			Version: 1,
+			Name:    "create_test_bucket",
+			Apply: func(tx *dbutil.Tx, _ *Blockchain, _ func(string)) error {
+				_, err := tx.CreateBucketIfNotExists(testBkt)
+				return err
+			},
+		},
+		{
+			Version: 2,
+			Name:    "fail",
+			Apply: func(tx *dbutil.Tx, _ *Blockchain, _ func(string)) error {
+				return errFailed
+			},
+		},
+	}
+
+	testBktExists := func() bool {
+		var exists bool
+		err := db.View("", func(tx *dbutil.Tx) error {
+			exists = dbutil.Exists(tx, testBkt)
+			return nil
+		})
+		require.NoError(t, err)
+		return exists
+	}
+
+	// A failed dry run changes nothing
+	_, err := Migrate(db, MigrateOptions{
+		DryRun: true,
+	})
+	require.Equal(t, errFailed, err)
+	requireSchemaVersion(t, db, 0, false)
+	require.False(t, testBktExists())
+
+	// The migrations before the failed one are kept
+	result, err := Migrate(db, MigrateOptions{})
+	require.Error(t, err)
+	require.Equal(t, "migration 2 fail failed: migration failed", err.Error())
+	require.Equal(t, uint64(1), result.To)
+	require.Len(t, result.Applied, 1)
+	requireSchemaVersion(t, db, 1, true)
+	require.True(t, testBktExists())
+
+	pending, err := PendingMigrations(db)
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+	require.Equal(t, "fail", pending[0].Name)
+
+	// A newer schema version is rejected
+	err = db.Update("", func(tx *dbutil.Tx) error {
+		return setSchemaVersion(tx, 3)
+	})
+	require.NoError(t, err)
+
+	_, err = PendingMigrations(db)
+	require.Error(t, err)
+	require.Equal(t, "db schema version 3 is newer than the latest supported schema version 2", err.Error())
+
+	_, err = Migrate(db, MigrateOptions{})
+	require.Error(t, err)
+}
+
+func TestMigrateBackup(t *testing.T) {
+	db, shutdown := testutil.PrepareBoltDB(t)
+	defer shutdown()
+
+	err := CreateBuckets(db)
+	require.NoError(t, err)
+
+	makeLegacyDB(t, db)
+
+	result, err := Migrate(db, MigrateOptions{
+		Backup: true,
+	})
+	require.NoError(t, err)
+	require.Len(t, result.Applied, len(migrations))
+	require.NotEmpty(t, result.BackupPath)
+	defer os.Remove(result.BackupPath)
+	defer os.Remove(BackupManifestPath(result.BackupPath))
+
+	// The backup is a copy of the db before the migration
+	backupDB, err := OpenDB(result.BackupPath, true)
+	require.NoError(t, err)
+	defer backupDB.Close()
+
+	requireSchemaVersion(t, backupDB, 0, false)
+	requireSchemaVersion(t, db, LatestSchemaVersion(), true)
+
+	// An in-memory db is not backed up
+	memDB, memShutdown := testutil.PrepareMemoryDB(t)
+	defer memShutdown()
+
+	err = CreateBuckets(memDB)
+	require.NoError(t, err)
+
+	makeLegacyDB(t, memDB)
+
+	result, err = Migrate(memDB, MigrateOptions{
+		Backup: true,
+	})
+	require.NoError(t, err)
+	require.Len(t, result.Applied, len(migrations))
+	require.Empty(t, result.BackupPath)
+	requireSchemaVersion(t, memDB, LatestSchemaVersion(), true)
+}
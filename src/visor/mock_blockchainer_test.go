@@ -14,6 +14,55 @@ type MockBlockchainer struct {
 	mock.Mock
 }
 
+// BackfillBlock provides a mock function with given fields: tx, b
+func (_m *MockBlockchainer) BackfillBlock(tx *dbutil.Tx, b *coin.SignedBlock) error {
+	ret := _m.Called(tx, b)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*dbutil.Tx, *coin.SignedBlock) error); ok {
+		r0 = rf(tx, b)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// BackfillRange provides a mock function with given fields: tx
+func (_m *MockBlockchainer) BackfillRange(tx *dbutil.Tx) (uint64, uint64, bool, error) {
+	ret := _m.Called(tx)
+
+	var r0 uint64
+	if rf, ok := ret.Get(0).(func(*dbutil.Tx) uint64); ok {
+		r0 = rf(tx)
+	} else {
+		r0 = ret.Get(0).(uint64)
+	}
+
+	var r1 uint64
+	if rf, ok := ret.Get(1).(func(*dbutil.Tx) uint64); ok {
+		r1 = rf(tx)
+	} else {
+		r1 = ret.Get(1).(uint64)
+	}
+
+	var r2 bool
+	if rf, ok := ret.Get(2).(func(*dbutil.Tx) bool); ok {
+		r2 = rf(tx)
+	} else {
+		r2 = ret.Get(2).(bool)
+	}
+
+	var r3 error
+	if rf, ok := ret.Get(3).(func(*dbutil.Tx) error); ok {
+		r3 = rf(tx)
+	} else {
+		r3 = ret.Error(3)
+	}
+
+	return r0, r1, r2, r3
+}
+
 // ExecuteBlock provides a mock function with given fields: tx, sb
 func (_m *MockBlockchainer) ExecuteBlock(tx *dbutil.Tx, sb *coin.SignedBlock) error {
 	ret := _m.Called(tx, sb)
@@ -28,6 +77,29 @@ func (_m *MockBlockchainer) ExecuteBlock(tx *dbutil.Tx, sb *coin.SignedBlock) er
 	return r0
 }
 
+// GetBlockHeaderBySeq provides a mock function with given fields: tx, seq
+func (_m *MockBlockchainer) GetBlockHeaderBySeq(tx *dbutil.Tx, seq uint64) (*coin.BlockHeader, error) {
+	ret := _m.Called(tx, seq)
+
+	var r0 *coin.BlockHeader
+	if rf, ok := ret.Get(0).(func(*dbutil.Tx, uint64) *coin.BlockHeader); ok {
+		r0 = rf(tx, seq)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*coin.BlockHeader)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*dbutil.Tx, uint64) error); ok {
+		r1 = rf(tx, seq)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetBlocks provides a mock function with given fields: tx, seqs
 func (_m *MockBlockchainer) GetBlocks(tx *dbutil.Tx, seqs []uint64) ([]coin.SignedBlock, error) {
 	ret := _m.Called(tx, seqs)
@@ -261,6 +333,55 @@ func (_m *MockBlockchainer) NewBlock(tx *dbutil.Tx, txns coin.Transactions, curr
 	return r0, r1
 }
 
+// Prune provides a mock function with given fields: tx, keep
+func (_m *MockBlockchainer) Prune(tx *dbutil.Tx, keep uint64) (uint64, error) {
+	ret := _m.Called(tx, keep)
+
+	var r0 uint64
+	if rf, ok := ret.Get(0).(func(*dbutil.Tx, uint64) uint64); ok {
+		r0 = rf(tx, keep)
+	} else {
+		r0 = ret.Get(0).(uint64)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*dbutil.Tx, uint64) error); ok {
+		r1 = rf(tx, keep)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// PruneSeq provides a mock function with given fields: tx
+func (_m *MockBlockchainer) PruneSeq(tx *dbutil.Tx) (uint64, bool, error) {
+	ret := _m.Called(tx)
+
+	var r0 uint64
+	if rf, ok := ret.Get(0).(func(*dbutil.Tx) uint64); ok {
+		r0 = rf(tx)
+	} else {
+		r0 = ret.Get(0).(uint64)
+	}
+
+	var r1 bool
+	if rf, ok := ret.Get(1).(func(*dbutil.Tx) bool); ok {
+		r1 = rf(tx)
+	} else {
+		r1 = ret.Get(1).(bool)
+	}
+
+	var r2 error
+	if rf, ok := ret.Get(2).(func(*dbutil.Tx) error); ok {
+		r2 = rf(tx)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
 // Time provides a mock function with given fields: tx
 func (_m *MockBlockchainer) Time(tx *dbutil.Tx) (uint64, error) {
 	ret := _m.Called(tx)
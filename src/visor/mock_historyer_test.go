@@ -13,6 +13,48 @@ type MockHistoryer struct {
 	mock.Mock
 }
 
+// BlockStatsReady provides a mock function with given fields: tx
+func (_m *MockHistoryer) BlockStatsReady(tx *dbutil.Tx) (bool, error) {
+	ret := _m.Called(tx)
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func(*dbutil.Tx) bool); ok {
+		r0 = rf(tx)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*dbutil.Tx) error); ok {
+		r1 = rf(tx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// BlockTimesIndexed provides a mock function with given fields: tx
+func (_m *MockHistoryer) BlockTimesIndexed(tx *dbutil.Tx) (bool, error) {
+	ret := _m.Called(tx)
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func(*dbutil.Tx) bool); ok {
+		r0 = rf(tx)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*dbutil.Tx) error); ok {
+		r1 = rf(tx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // Erase provides a mock function with given fields: tx
 func (_m *MockHistoryer) Erase(tx *dbutil.Tx) error {
 	ret := _m.Called(tx)
@@ -27,6 +69,34 @@ func (_m *MockHistoryer) Erase(tx *dbutil.Tx) error {
 	return r0
 }
 
+// ForEachBlockStats provides a mock function with given fields: tx, start, end, f
+func (_m *MockHistoryer) ForEachBlockStats(tx *dbutil.Tx, start uint64, end uint64, f func(historydb.BlockStats) error) error {
+	ret := _m.Called(tx, start, end, f)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*dbutil.Tx, uint64, uint64, func(historydb.BlockStats) error) error); ok {
+		r0 = rf(tx, start, end, f)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ForEachDailyStats provides a mock function with given fields: tx, start, end, f
+func (_m *MockHistoryer) ForEachDailyStats(tx *dbutil.Tx, start uint64, end uint64, f func(historydb.DailyStats) error) error {
+	ret := _m.Called(tx, start, end, f)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*dbutil.Tx, uint64, uint64, func(historydb.DailyStats) error) error); ok {
+		r0 = rf(tx, start, end, f)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // ForEachTxn provides a mock function with given fields: tx, f
 func (_m *MockHistoryer) ForEachTxn(tx *dbutil.Tx, f func(cipher.SHA256, *historydb.Transaction) error) error {
 	ret := _m.Called(tx, f)
@@ -41,6 +111,64 @@ func (_m *MockHistoryer) ForEachTxn(tx *dbutil.Tx, f func(cipher.SHA256, *histor
 	return r0
 }
 
+// GetBlockSeqsInTimeRange provides a mock function with given fields: tx, start, end
+func (_m *MockHistoryer) GetBlockSeqsInTimeRange(tx *dbutil.Tx, start uint64, end uint64) (uint64, uint64, bool, error) {
+	ret := _m.Called(tx, start, end)
+
+	var r0 uint64
+	if rf, ok := ret.Get(0).(func(*dbutil.Tx, uint64, uint64) uint64); ok {
+		r0 = rf(tx, start, end)
+	} else {
+		r0 = ret.Get(0).(uint64)
+	}
+
+	var r1 uint64
+	if rf, ok := ret.Get(1).(func(*dbutil.Tx, uint64, uint64) uint64); ok {
+		r1 = rf(tx, start, end)
+	} else {
+		r1 = ret.Get(1).(uint64)
+	}
+
+	var r2 bool
+	if rf, ok := ret.Get(2).(func(*dbutil.Tx, uint64, uint64) bool); ok {
+		r2 = rf(tx, start, end)
+	} else {
+		r2 = ret.Get(2).(bool)
+	}
+
+	var r3 error
+	if rf, ok := ret.Get(3).(func(*dbutil.Tx, uint64, uint64) error); ok {
+		r3 = rf(tx, start, end)
+	} else {
+		r3 = ret.Error(3)
+	}
+
+	return r0, r1, r2, r3
+}
+
+// GetBlockStats provides a mock function with given fields: tx, seq
+func (_m *MockHistoryer) GetBlockStats(tx *dbutil.Tx, seq uint64) (*historydb.BlockStats, error) {
+	ret := _m.Called(tx, seq)
+
+	var r0 *historydb.BlockStats
+	if rf, ok := ret.Get(0).(func(*dbutil.Tx, uint64) *historydb.BlockStats); ok {
+		r0 = rf(tx, seq)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*historydb.BlockStats)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*dbutil.Tx, uint64) error); ok {
+		r1 = rf(tx, seq)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetOutputsForAddress provides a mock function with given fields: tx, address
 func (_m *MockHistoryer) GetOutputsForAddress(tx *dbutil.Tx, address cipher.Address) ([]historydb.UxOut, error) {
 	ret := _m.Called(tx, address)
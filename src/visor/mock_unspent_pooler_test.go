@@ -17,6 +17,27 @@ type MockUnspentPooler struct {
 	mock.Mock
 }
 
+// AddrBalanceIndexReady provides a mock function with given fields: _a0
+func (_m *MockUnspentPooler) AddrBalanceIndexReady(_a0 *dbutil.Tx) (bool, error) {
+	ret := _m.Called(_a0)
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func(*dbutil.Tx) bool); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*dbutil.Tx) error); ok {
+		r1 = rf(_a0)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // AddressCount provides a mock function with given fields: _a0
 func (_m *MockUnspentPooler) AddressCount(_a0 *dbutil.Tx) (uint64, error) {
 	ret := _m.Called(_a0)
@@ -38,6 +59,20 @@ func (_m *MockUnspentPooler) AddressCount(_a0 *dbutil.Tx) (uint64, error) {
 	return r0, r1
 }
 
+// BuildAddrBalanceIndex provides a mock function with given fields: _a0, _a1
+func (_m *MockUnspentPooler) BuildAddrBalanceIndex(_a0 *dbutil.Tx, _a1 uint64) error {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*dbutil.Tx, uint64) error); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // Contains provides a mock function with given fields: _a0, _a1
 func (_m *MockUnspentPooler) Contains(_a0 *dbutil.Tx, _a1 cipher.SHA256) (bool, error) {
 	ret := _m.Called(_a0, _a1)
@@ -59,6 +94,20 @@ func (_m *MockUnspentPooler) Contains(_a0 *dbutil.Tx, _a1 cipher.SHA256) (bool,
 	return r0, r1
 }
 
+// ForEachAddrBalanceByCoins provides a mock function with given fields: _a0, _a1
+func (_m *MockUnspentPooler) ForEachAddrBalanceByCoins(_a0 *dbutil.Tx, _a1 func(cipher.Address, blockdb.AddressBalance) error) error {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*dbutil.Tx, func(cipher.Address, blockdb.AddressBalance) error) error); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // Get provides a mock function with given fields: _a0, _a1
 func (_m *MockUnspentPooler) Get(_a0 *dbutil.Tx, _a1 cipher.SHA256) (*coin.UxOut, error) {
 	ret := _m.Called(_a0, _a1)
@@ -82,6 +131,29 @@ func (_m *MockUnspentPooler) Get(_a0 *dbutil.Tx, _a1 cipher.SHA256) (*coin.UxOut
 	return r0, r1
 }
 
+// GetAddrBalances provides a mock function with given fields: _a0, _a1
+func (_m *MockUnspentPooler) GetAddrBalances(_a0 *dbutil.Tx, _a1 []cipher.Address) ([]blockdb.AddressBalance, error) {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 []blockdb.AddressBalance
+	if rf, ok := ret.Get(0).(func(*dbutil.Tx, []cipher.Address) []blockdb.AddressBalance); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]blockdb.AddressBalance)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*dbutil.Tx, []cipher.Address) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetAll provides a mock function with given fields: _a0
 func (_m *MockUnspentPooler) GetAll(_a0 *dbutil.Tx) (coin.UxArray, error) {
 	ret := _m.Called(_a0)
@@ -197,6 +269,20 @@ func (_m *MockUnspentPooler) GetUxHash(_a0 *dbutil.Tx) (cipher.SHA256, error) {
 	return r0, r1
 }
 
+// Import provides a mock function with given fields: _a0, _a1, _a2
+func (_m *MockUnspentPooler) Import(_a0 *dbutil.Tx, _a1 coin.UxArray, _a2 uint64) error {
+	ret := _m.Called(_a0, _a1, _a2)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*dbutil.Tx, coin.UxArray, uint64) error); ok {
+		r0 = rf(_a0, _a1, _a2)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // Len provides a mock function with given fields: _a0
 func (_m *MockUnspentPooler) Len(_a0 *dbutil.Tx) (uint64, error) {
 	ret := _m.Called(_a0)
@@ -245,3 +331,31 @@ func (_m *MockUnspentPooler) ProcessBlock(_a0 *dbutil.Tx, _a1 *coin.SignedBlock)
 
 	return r0
 }
+
+// Rollback provides a mock function with given fields: _a0, _a1, _a2
+func (_m *MockUnspentPooler) Rollback(_a0 *dbutil.Tx, _a1 *coin.SignedBlock, _a2 coin.UxArray) error {
+	ret := _m.Called(_a0, _a1, _a2)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*dbutil.Tx, *coin.SignedBlock, coin.UxArray) error); ok {
+		r0 = rf(_a0, _a1, _a2)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// VerifyAddrBalanceIndex provides a mock function with given fields: _a0
+func (_m *MockUnspentPooler) VerifyAddrBalanceIndex(_a0 *dbutil.Tx) error {
+	ret := _m.Called(_a0)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*dbutil.Tx) error); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
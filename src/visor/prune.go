@@ -0,0 +1,125 @@
+package visor
+
+import (
+	"errors"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/visor/blockdb"
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+	"github.com/skycoin/skycoin/src/visor/historydb"
+)
+
+var (
+	// ErrHistoryPruned is returned when transaction history is requested from a pruned node
+	ErrHistoryPruned = errors.New("transaction history is not available on a pruned node")
+	// ErrDBPruned is returned when a pruned database is opened by a node that is not in pruned mode
+	ErrDBPruned = errors.New("database has been pruned, enable pruned mode or remove the database to resync the full blockchain")
+)
+
+// IsErrPruned returns true if err is caused by requesting data that a pruned node does not keep
+func IsErrPruned(err error) bool {
+	return err == blockdb.ErrBlockPruned || err == ErrHistoryPruned
+}
+
+// prunedHistory is the Historyer of a pruned node.
+// Pruned nodes do not keep the history indexes, so blocks are not parsed and history queries return ErrHistoryPruned.
+type prunedHistory struct{}
+
+func (h prunedHistory) GetUxOuts(tx *dbutil.Tx, uxids []cipher.SHA256) ([]historydb.UxOut, error) {
+	return nil, ErrHistoryPruned
+}
+
+func (h prunedHistory) ParseBlock(tx *dbutil.Tx, b coin.Block) error {
+	return nil
+}
+
+func (h prunedHistory) GetTransaction(tx *dbutil.Tx, hash cipher.SHA256) (*historydb.Transaction, error) {
+	return nil, ErrHistoryPruned
+}
+
+func (h prunedHistory) GetOutputsForAddress(tx *dbutil.Tx, address cipher.Address) ([]historydb.UxOut, error) {
+	return nil, ErrHistoryPruned
+}
+
+func (h prunedHistory) GetTransactionsForAddress(tx *dbutil.Tx, address cipher.Address) ([]historydb.Transaction, error) {
+	return nil, ErrHistoryPruned
+}
+
+func (h prunedHistory) NeedsReset(tx *dbutil.Tx) (bool, error) {
+	return false, nil
+}
+
+func (h prunedHistory) Erase(tx *dbutil.Tx) error {
+	return nil
+}
+
+func (h prunedHistory) ParsedBlockSeq(tx *dbutil.Tx) (uint64, bool, error) {
+	return 0, false, nil
+}
+
+func (h prunedHistory) ForEachTxn(tx *dbutil.Tx, f func(cipher.SHA256, *historydb.Transaction) error) error {
+	return ErrHistoryPruned
+}
+
+func (h prunedHistory) BlockTimesIndexed(tx *dbutil.Tx) (bool, error) {
+	return false, nil
+}
+
+func (h prunedHistory) GetBlockSeqsInTimeRange(tx *dbutil.Tx, start, end uint64) (uint64, uint64, bool, error) {
+	return 0, 0, false, ErrHistoryPruned
+}
+
+func (h prunedHistory) BlockStatsReady(tx *dbutil.Tx) (bool, error) {
+	return false, ErrHistoryPruned
+}
+
+func (h prunedHistory) GetBlockStats(tx *dbutil.Tx, seq uint64) (*historydb.BlockStats, error) {
+	return nil, ErrHistoryPruned
+}
+
+func (h prunedHistory) ForEachBlockStats(tx *dbutil.Tx, start, end uint64, f func(historydb.BlockStats) error) error {
+	return ErrHistoryPruned
+}
+
+func (h prunedHistory) ForEachDailyStats(tx *dbutil.Tx, start, end uint64, f func(historydb.DailyStats) error) error {
+	return ErrHistoryPruned
+}
+
+// initPruning enables pruning for the database, erases the history indexes
+// and discards the bodies of all but the most recent keep blocks
+func initPruning(tx *dbutil.Tx, bc *Blockchain, keep uint64) error {
+	logger.Info("Visor initPruning")
+
+	if err := historydb.New().Erase(tx); err != nil {
+		return err
+	}
+
+	n, err := bc.Prune(tx, keep)
+	if err != nil {
+		return err
+	}
+
+	if n > 0 {
+		logger.Infof("Pruned the bodies of %d blocks", n)
+	}
+
+	return nil
+}
+
+// PruneSeq returns the sequence of the most recent block whose body has been pruned.
+// Returns false if the node is not pruned.
+func (vs *Visor) PruneSeq() (uint64, bool, error) {
+	var pruneSeq uint64
+	var ok bool
+
+	if err := vs.db.View("PruneSeq", func(tx *dbutil.Tx) error {
+		var err error
+		pruneSeq, ok, err = vs.blockchain.PruneSeq(tx)
+		return err
+	}); err != nil {
+		return 0, false, err
+	}
+
+	return pruneSeq, ok, nil
+}
@@ -0,0 +1,187 @@
+package visor
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/params"
+	"github.com/skycoin/skycoin/src/testutil"
+	"github.com/skycoin/skycoin/src/visor/blockdb"
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+	"github.com/skycoin/skycoin/src/wallet"
+)
+
+func TestVisorPrune(t *testing.T) {
+	db, shutdown := prepareDB(t)
+	defer shutdown()
+
+	cfg := NewConfig()
+	cfg.IsBlockPublisher = true
+	cfg.BlockchainPubkey = genPublic
+	cfg.BlockchainSeckey = genSecret
+	cfg.GenesisAddress = genAddress
+	cfg.Prune = true
+	cfg.PruneKeepBlocks = 2
+
+	ws, err := wallet.NewService(wallet.Config{
+		EnableWalletAPI: true,
+		CryptoType:      wallet.CryptoTypeScryptChacha20poly1305Insecure,
+		WalletDir:       prepareWltDir(),
+	})
+	require.NoError(t, err)
+
+	v, err := New(cfg, db, ws)
+	require.NoError(t, err)
+
+	_, pruned, err := v.PruneSeq()
+	require.NoError(t, err)
+	require.True(t, pruned)
+
+	gb := addGenesisBlockToVisor(t, v)
+
+	uxs := coin.CreateUnspents(gb.Head, gb.Body.Transactions[0])
+	txn := makeUnspentsTxn(t, uxs, []cipher.SecKey{genSecret}, genAddress, 10, params.UserVerifyTxn.MaxDropletPrecision)
+	_, softErr, err := v.InjectForeignTransaction(txn)
+	require.NoError(t, err)
+	require.Nil(t, softErr)
+
+	// Create the blocks at increasing times, since a block time can not be equal to the previous block time
+	when := uint64(time.Now().UTC().Unix())
+	createAndExecuteBlock := func() coin.SignedBlock {
+		when++
+		var sb coin.SignedBlock
+		err := db.Update("", func(tx *dbutil.Tx) error {
+			var err error
+			sb, err = v.createBlock(tx, when)
+			if err != nil {
+				return err
+			}
+
+			return v.executeSignedBlock(tx, sb)
+		})
+		require.NoError(t, err)
+		return sb
+	}
+
+	sb := createAndExecuteBlock()
+
+	uxs = coin.CreateUnspents(sb.Head, sb.Body.Transactions[0])
+	for i := 0; i < 3; i++ {
+		txn := makeSpendTxWithFee(t, coin.UxArray{uxs[i]}, []cipher.SecKey{genSecret}, testutil.MakeAddress(), 1e6, 0)
+		_, softErr, err := v.InjectForeignTransaction(txn)
+		require.NoError(t, err)
+		require.Nil(t, softErr)
+
+		createAndExecuteBlock()
+	}
+
+	// The bodies of blocks 1 and 2 were discarded
+	pruneSeq, pruned, err := v.PruneSeq()
+	require.NoError(t, err)
+	require.True(t, pruned)
+	require.Equal(t, uint64(2), pruneSeq)
+
+	b, err := v.GetBlock(0)
+	require.NoError(t, err)
+	require.Equal(t, *gb, *b)
+
+	_, err = v.GetBlock(1)
+	require.Equal(t, blockdb.ErrBlockPruned, err)
+
+	b, err = v.GetBlock(3)
+	require.NoError(t, err)
+	require.Len(t, b.Body.Transactions, 1)
+
+	_, err = v.GetSignedBlocksSince(0, 10)
+	require.Equal(t, blockdb.ErrBlockPruned, err)
+
+	blocks, err := v.GetSignedBlocksSince(2, 10)
+	require.NoError(t, err)
+	require.Len(t, blocks, 2)
+
+	// The pruned blocks are found by time from their headers
+	err = db.View("", func(tx *dbutil.Tx) error {
+		first, last, ok, err := v.blockSeqsInTimeRange(tx, 0, math.MaxUint64)
+		require.NoError(t, err)
+		require.True(t, ok)
+		require.Equal(t, uint64(0), first)
+		require.Equal(t, uint64(4), last)
+
+		first, last, ok, err = v.blockSeqsInTimeRange(tx, sb.Time(), sb.Time())
+		require.NoError(t, err)
+		require.True(t, ok)
+		require.Equal(t, uint64(1), first)
+		require.Equal(t, uint64(1), last)
+		return nil
+	})
+	require.NoError(t, err)
+
+	// The unspent outputs are kept
+	uxa, err := v.GetUnspentOutputsSummary(nil)
+	require.NoError(t, err)
+	require.NotEmpty(t, uxa.Confirmed)
+
+	// The history is not available
+	_, err = v.GetTransaction(txn.Hash())
+	require.Equal(t, ErrHistoryPruned, err)
+	require.True(t, IsErrPruned(err))
+
+	_, err = v.GetAddressesHistory([]cipher.Address{genAddress})
+	require.Equal(t, ErrHistoryPruned, err)
+
+	// Transactions spending unspent outputs can be signed, and their inputs are read from the unspent pool
+	_, err = ws.CreateWallet("foo.wlt", wallet.Options{
+		Coin:       wallet.CoinTypeSkycoin,
+		Seed:       "foo",
+		CryptoType: wallet.CryptoTypeScryptChacha20poly1305Insecure,
+		GenerateN:  1,
+	}, nil)
+	require.NoError(t, err)
+
+	err = ws.UpdateSecrets("foo.wlt", nil, func(w *wallet.Wallet) error {
+		return w.AddEntry(wallet.Entry{
+			Address: genAddress,
+			Public:  genPublic,
+			Secret:  genSecret,
+		})
+	})
+	require.NoError(t, err)
+
+	unsignedTxn := makeSpendTxWithFee(t, coin.UxArray{uxs[3]}, []cipher.SecKey{genSecret}, testutil.MakeAddress(), 1e6, 0)
+	unsignedTxn.Sigs = make([]cipher.Sig, len(unsignedTxn.In))
+
+	signedTxn, inputs, err := v.WalletSignTransaction("foo.wlt", nil, &unsignedTxn, nil)
+	require.NoError(t, err)
+	require.True(t, signedTxn.IsFullySigned())
+	require.Len(t, inputs, 1)
+	require.Equal(t, uxs[3], inputs[0].UxOut)
+
+	_, softErr, err = v.InjectForeignTransaction(*signedTxn)
+	require.NoError(t, err)
+	require.Nil(t, softErr)
+
+	uTxns, uInputs, err := v.GetAllUnconfirmedTransactionsVerbose()
+	require.NoError(t, err)
+	require.Len(t, uTxns, 1)
+	require.Equal(t, inputs, uInputs[0])
+
+	// The inputs of confirmed transactions have been spent and are not available
+	err = db.View("", func(tx *dbutil.Tx) error {
+		_, err := v.getTransactionInputs(tx, 0, []cipher.SHA256{uxs[0].Hash()})
+		return err
+	})
+	require.Equal(t, ErrHistoryPruned, err)
+
+	// The pruned database passes verification
+	require.NoError(t, CheckDatabase(db, genPublic, nil))
+
+	// A pruned database can not be used without pruned mode
+	cfg.Prune = false
+	_, err = New(cfg, db, nil)
+	require.Equal(t, ErrDBPruned, err)
+}
@@ -62,3 +62,17 @@ func (r Richlist) FilterAddresses(addrs map[cipher.Address]struct{}) Richlist {
 	}
 	return s
 }
+
+// Page returns n balances of the richlist starting at offset. If n is 0, the balances after offset are returned
+func (r Richlist) Page(offset, n uint64) Richlist {
+	if offset >= uint64(len(r)) {
+		return nil
+	}
+
+	r = r[offset:]
+	if n != 0 && n < uint64(len(r)) {
+		r = r[:n]
+	}
+
+	return r
+}
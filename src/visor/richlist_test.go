@@ -2,10 +2,15 @@ package visor
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 
 	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/params"
+	"github.com/skycoin/skycoin/src/testutil"
+	"github.com/skycoin/skycoin/src/visor/dbutil"
 )
 
 func getLockedMap(distributionAddresses [4]cipher.Address) map[cipher.Address]struct{} {
@@ -122,3 +127,122 @@ func TestRichlist(t *testing.T) {
 		})
 	}
 }
+
+func TestGetRichlist(t *testing.T) {
+	db, shutdown := prepareDB(t)
+	defer shutdown()
+
+	cfg := NewConfig()
+	cfg.IsBlockPublisher = true
+	cfg.BlockchainPubkey = genPublic
+	cfg.BlockchainSeckey = genSecret
+	cfg.GenesisAddress = genAddress
+
+	v, err := New(cfg, db, nil)
+	require.NoError(t, err)
+
+	gb := addGenesisBlockToVisor(t, v)
+
+	when := uint64(time.Now().UTC().Unix())
+	createAndExecuteBlock := func(txn coin.Transaction) coin.SignedBlock {
+		_, softErr, err := v.InjectForeignTransaction(txn)
+		require.NoError(t, err)
+		require.Nil(t, softErr)
+
+		when++
+		var sb coin.SignedBlock
+		err = db.Update("", func(tx *dbutil.Tx) error {
+			var err error
+			sb, err = v.createBlock(tx, when)
+			if err != nil {
+				return err
+			}
+
+			return v.executeSignedBlock(tx, sb)
+		})
+		require.NoError(t, err)
+		return sb
+	}
+
+	uxs := coin.CreateUnspents(gb.Head, gb.Body.Transactions[0])
+	sb := createAndExecuteBlock(makeUnspentsTxn(t, uxs, []cipher.SecKey{genSecret}, genAddress, 10, params.UserVerifyTxn.MaxDropletPrecision))
+	uxs = coin.CreateUnspents(sb.Head, sb.Body.Transactions[0])
+	coins := uxs[0].Body.Coins
+
+	// The locked address and addrA have the same coins, the locked address is ordered first
+	lockedAddr := params.GetLockedDistributionAddressesDecoded()[0]
+	addrA := testutil.MakeAddress()
+	addrB := testutil.MakeAddress()
+	createAndExecuteBlock(makeSpendTxWithFee(t, coin.UxArray{uxs[0]}, []cipher.SecKey{genSecret}, lockedAddr, coins, 0))
+	createAndExecuteBlock(makeSpendTxWithFee(t, coin.UxArray{uxs[1]}, []cipher.SecKey{genSecret}, addrA, coins, 0))
+	createAndExecuteBlock(makeSpendTxWithFee(t, coin.UxArray{uxs[2], uxs[3]}, []cipher.SecKey{genSecret, genSecret}, addrB, 2*coins, 0))
+
+	genCoins, err := uxs[4:].Coins()
+	require.NoError(t, err)
+
+	richlist := Richlist{
+		{Address: genAddress, Coins: genCoins},
+		{Address: addrB, Coins: 2 * coins},
+		{Address: lockedAddr, Coins: coins, Locked: true},
+		{Address: addrA, Coins: coins},
+	}
+
+	// The richlist of the address balance index matches the richlist aggregated from the unspent outputs
+	err = db.View("", func(tx *dbutil.Tx) error {
+		ready, err := v.blockchain.Unspent().AddrBalanceIndexReady(tx)
+		require.NoError(t, err)
+		require.True(t, ready)
+
+		rl, err := v.getRichlistFromUnspents(tx, getLockedMap([4]cipher.Address{lockedAddr}), nil)
+		require.NoError(t, err)
+		require.Equal(t, richlist, rl)
+		return nil
+	})
+	require.NoError(t, err)
+
+	cases := []struct {
+		includeDistribution bool
+		offset              uint64
+		n                   uint64
+		richlist            Richlist
+		total               uint64
+	}{
+		{true, 0, 0, richlist, 4},
+		{true, 0, 2, richlist[:2], 4},
+		{true, 1, 2, richlist[1:3], 4},
+		{true, 2, 1, richlist[2:3], 4},
+		{true, 3, 0, richlist[3:], 4},
+		{true, 4, 2, nil, 4},
+		{false, 0, 0, Richlist{richlist[0], richlist[1], richlist[3]}, 3},
+		{false, 2, 2, Richlist{richlist[3]}, 3},
+	}
+
+	for _, tc := range cases {
+		rl, total, err := v.GetRichlist(tc.includeDistribution, tc.offset, tc.n)
+		require.NoError(t, err)
+		require.Equal(t, tc.richlist, rl)
+		require.Equal(t, tc.total, total)
+	}
+
+	// The address count is the number of addresses in the richlist
+	count, err := v.AddressCount()
+	require.NoError(t, err)
+	require.Equal(t, uint64(4), count)
+
+	require.NoError(t, CheckDatabase(db, genPublic, nil))
+}
+
+func TestRichlistPage(t *testing.T) {
+	r := Richlist{
+		{Coins: 3},
+		{Coins: 2},
+		{Coins: 1},
+	}
+
+	require.Equal(t, r, r.Page(0, 0))
+	require.Equal(t, r[:2], r.Page(0, 2))
+	require.Equal(t, r[1:], r.Page(1, 5))
+	require.Equal(t, r[2:], r.Page(2, 0))
+	require.Nil(t, r.Page(3, 1))
+	require.Nil(t, Richlist{}.Page(0, 0))
+}
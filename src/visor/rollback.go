@@ -0,0 +1,90 @@
+package visor
+
+import (
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/params"
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+	"github.com/skycoin/skycoin/src/visor/historydb"
+)
+
+// RollbackResult is the result of rolling back the blockchain
+type RollbackResult struct {
+	// Blocks are the removed blocks, most recent first
+	Blocks []coin.SignedBlock
+	// Returned are the transactions of the removed blocks that were returned to the unconfirmed pool
+	Returned []cipher.SHA256
+	// Dropped are the transactions of the removed blocks that are no longer valid
+	Dropped []cipher.SHA256
+}
+
+// Rollback removes the blocks after seq from the blockchain of a stopped node's database.
+// The unspent pool is restored from the undo records of the removed blocks, their history entries are removed,
+// and their transactions are returned to the unconfirmed pool if they are still valid.
+// A transaction that spends an output created by another removed block is dropped,
+// because the output does not exist anymore.
+func Rollback(db *dbutil.DB, seq uint64, verifyParams params.VerifyTxn) (*RollbackResult, error) {
+	if err := CreateBuckets(db); err != nil {
+		return nil, err
+	}
+
+	bc, err := NewBlockchain(db, BlockchainConfig{})
+	if err != nil {
+		return nil, err
+	}
+
+	unconfirmed, err := NewUnconfirmedTransactionPool(db)
+	if err != nil {
+		return nil, err
+	}
+
+	history := historydb.New()
+
+	var result RollbackResult
+	if err := db.Update("Rollback", func(tx *dbutil.Tx) error {
+		blocks, err := bc.RollbackTo(tx, seq)
+		if err != nil {
+			return err
+		}
+
+		// Pruned nodes and nodes that are backfilling do not have the history of the blocks
+		parsedSeq, ok, err := history.ParsedBlockSeq(tx)
+		if err != nil {
+			return err
+		}
+
+		for _, b := range blocks {
+			if !ok || parsedSeq < b.Seq() {
+				continue
+			}
+
+			if err := history.RollbackBlock(tx, b.Block); err != nil {
+				return err
+			}
+		}
+
+		// Return the transactions to the unconfirmed pool, oldest first
+		for i := len(blocks) - 1; i >= 0; i-- {
+			for _, txn := range blocks[i].Body.Transactions {
+				if _, _, err := unconfirmed.InjectTransaction(tx, bc, txn, verifyParams); err != nil {
+					switch err.(type) {
+					case ErrTxnViolatesHardConstraint:
+						result.Dropped = append(result.Dropped, txn.Hash())
+						continue
+					default:
+						return err
+					}
+				}
+
+				result.Returned = append(result.Returned, txn.Hash())
+			}
+		}
+
+		result.Blocks = blocks
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
@@ -0,0 +1,146 @@
+package visor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/params"
+	"github.com/skycoin/skycoin/src/testutil"
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+)
+
+func TestVisorRollback(t *testing.T) {
+	db, shutdown := prepareDB(t)
+	defer shutdown()
+
+	cfg := NewConfig()
+	cfg.IsBlockPublisher = true
+	cfg.BlockchainPubkey = genPublic
+	cfg.BlockchainSeckey = genSecret
+	cfg.GenesisAddress = genAddress
+
+	v, err := New(cfg, db, nil)
+	require.NoError(t, err)
+
+	gb := addGenesisBlockToVisor(t, v)
+
+	uxs := coin.CreateUnspents(gb.Head, gb.Body.Transactions[0])
+	txn := makeUnspentsTxn(t, uxs, []cipher.SecKey{genSecret}, genAddress, 10, params.UserVerifyTxn.MaxDropletPrecision)
+	_, softErr, err := v.InjectForeignTransaction(txn)
+	require.NoError(t, err)
+	require.Nil(t, softErr)
+
+	// Create the blocks at increasing times, since a block time can not be equal to the previous block time
+	when := uint64(time.Now().UTC().Unix())
+	createAndExecuteBlock := func(v *Visor) coin.SignedBlock {
+		when++
+		var sb coin.SignedBlock
+		err := db.Update("", func(tx *dbutil.Tx) error {
+			var err error
+			sb, err = v.createBlock(tx, when)
+			if err != nil {
+				return err
+			}
+
+			return v.executeSignedBlock(tx, sb)
+		})
+		require.NoError(t, err)
+		return sb
+	}
+
+	injectTxn := func(txn coin.Transaction) {
+		_, softErr, err := v.InjectForeignTransaction(txn)
+		require.NoError(t, err)
+		require.Nil(t, softErr)
+	}
+
+	sb := createAndExecuteBlock(v)
+	uxs = coin.CreateUnspents(sb.Head, sb.Body.Transactions[0])
+
+	txn2 := makeSpendTxWithFee(t, coin.UxArray{uxs[0]}, []cipher.SecKey{genSecret}, testutil.MakeAddress(), 1e6, 0)
+	injectTxn(txn2)
+	createAndExecuteBlock(v)
+
+	unspentsBefore := getAllUnspents(t, v)
+
+	// The transaction of block 4 spends an output created by block 3
+	txn3 := makeSpendTxWithFee(t, coin.UxArray{uxs[1]}, []cipher.SecKey{genSecret}, genAddress, 1e6, 0)
+	injectTxn(txn3)
+	sb3 := createAndExecuteBlock(v)
+
+	txn4 := makeSpendTxWithFee(t, coin.CreateUnspents(sb3.Head, txn3)[:1], []cipher.SecKey{genSecret}, testutil.MakeAddress(), 1e6, 0)
+	injectTxn(txn4)
+	sb4 := createAndExecuteBlock(v)
+
+	// The head block can not be rolled forward
+	_, err = Rollback(db, 5, params.UserVerifyTxn)
+	require.Error(t, err)
+
+	result, err := Rollback(db, 2, params.UserVerifyTxn)
+	require.NoError(t, err)
+	require.Equal(t, []coin.SignedBlock{sb4, sb3}, result.Blocks)
+	require.Equal(t, []cipher.SHA256{txn3.Hash()}, result.Returned)
+	require.Equal(t, []cipher.SHA256{txn4.Hash()}, result.Dropped)
+
+	// Start a node from the rolled back database
+	v2, err := New(cfg, db, nil)
+	require.NoError(t, err)
+	require.NoError(t, v2.Init())
+
+	headSeq, ok, err := v2.HeadBkSeq()
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, uint64(2), headSeq)
+
+	require.Equal(t, unspentsBefore, getAllUnspents(t, v2))
+
+	// The history of the removed blocks was removed
+	htxn, err := v2.GetTransaction(txn4.Hash())
+	require.NoError(t, err)
+	require.Nil(t, htxn)
+
+	htxn, err = v2.GetTransaction(txn3.Hash())
+	require.NoError(t, err)
+	require.NotNil(t, htxn)
+	require.False(t, htxn.Status.Confirmed)
+
+	htxn, err = v2.GetTransaction(txn2.Hash())
+	require.NoError(t, err)
+	require.NotNil(t, htxn)
+
+	// The valid transactions were returned to the unconfirmed pool
+	utxns, err := v2.GetAllUnconfirmedTransactions()
+	require.NoError(t, err)
+	require.Len(t, utxns, 1)
+	require.Equal(t, txn3, utxns[0].Transaction)
+
+	require.NoError(t, CheckDatabase(db, genPublic, nil))
+
+	// The returned transactions are executed again
+	sb = createAndExecuteBlock(v2)
+	require.Equal(t, uint64(3), sb.Seq())
+	require.Equal(t, coin.Transactions{txn3}, sb.Body.Transactions)
+
+	htxn, err = v2.GetTransaction(txn3.Hash())
+	require.NoError(t, err)
+	require.NotNil(t, htxn)
+	require.Equal(t, uint64(3), htxn.Status.BlockSeq)
+
+	require.NoError(t, CheckDatabase(db, genPublic, nil))
+
+	// Rolling back to the head block does nothing
+	result, err = Rollback(db, 3, params.UserVerifyTxn)
+	require.NoError(t, err)
+	require.Empty(t, result.Blocks)
+
+	// All the blocks after the genesis block can be rolled back
+	result, err = Rollback(db, 0, params.UserVerifyTxn)
+	require.NoError(t, err)
+	require.Len(t, result.Blocks, 3)
+
+	require.NoError(t, CheckDatabase(db, genPublic, nil))
+}
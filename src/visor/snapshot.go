@@ -0,0 +1,482 @@
+package visor
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"sync/atomic"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/cipher/encoder"
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/visor/blockdb"
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+	"github.com/skycoin/skycoin/src/visor/historydb"
+)
+
+// SnapshotVersion is the version of the snapshot file format
+const SnapshotVersion uint32 = 1
+
+var (
+	// ErrSnapshotVersion is returned when reading a snapshot file of an unsupported version
+	ErrSnapshotVersion = errors.New("unsupported snapshot version")
+	// ErrHistoryBackfilling is returned when transaction history is requested from a node that was started
+	// from a snapshot, before the blocks before the snapshot have been downloaded
+	ErrHistoryBackfilling = errors.New("transaction history is not available until the blocks before the snapshot have been downloaded")
+)
+
+// IsErrBackfilling returns true if err is caused by requesting data that a node started from a snapshot
+// has not downloaded yet
+func IsErrBackfilling(err error) bool {
+	return err == blockdb.ErrBlockNotBackfilled || err == ErrHistoryBackfilling
+}
+
+// Snapshot is the unspent output set of the blockchain at a block, with the signed block headers that commit to it.
+// The UxHash of a block header is the hash of the unspent outputs before the block is executed,
+// so the snapshot includes the body of the snapshot block, which is executed after the unspent outputs are imported.
+type Snapshot struct {
+	Version uint32
+	Genesis coin.SignedBlock
+	// Headers and signatures of the blocks between the genesis block and the snapshot block
+	Headers []coin.BlockHeader
+	Sigs    []cipher.Sig
+	Block   coin.SignedBlock
+	// Unspent outputs before Block is executed
+	Unspents coin.UxArray
+}
+
+// Blocks returns the blocks of the snapshot, from the genesis block to the snapshot block.
+// The blocks between them have empty bodies.
+func (s *Snapshot) Blocks() []coin.SignedBlock {
+	blocks := make([]coin.SignedBlock, 0, len(s.Headers)+2)
+	blocks = append(blocks, s.Genesis)
+	for i, h := range s.Headers {
+		blocks = append(blocks, coin.SignedBlock{
+			Block: coin.Block{
+				Head: h,
+			},
+			Sig: s.Sigs[i],
+		})
+	}
+	return append(blocks, s.Block)
+}
+
+// Verify checks that the blocks of the snapshot are chained and signed by the blockchain pubkey,
+// and that the unspent outputs match the UxHash of the snapshot block
+func (s *Snapshot) Verify(pubkey cipher.PubKey) error {
+	if s.Version != SnapshotVersion {
+		return ErrSnapshotVersion
+	}
+
+	if len(s.Headers) != len(s.Sigs) {
+		return errors.New("snapshot has a different number of block headers and signatures")
+	}
+
+	verifyBlock := func(b *coin.SignedBlock) error {
+		if b.Head.BodyHash != b.Body.Hash() {
+			return fmt.Errorf("block %d body hash does not match its header", b.Seq())
+		}
+		if err := cipher.VerifyPubKeySignedHash(pubkey, b.Sig, b.HashHeader()); err != nil {
+			return fmt.Errorf("block %d signature verification failed: %v", b.Seq(), err)
+		}
+		return nil
+	}
+
+	if s.Genesis.Seq() != 0 {
+		return errors.New("snapshot genesis block seq is not 0")
+	}
+	if err := verifyBlock(&s.Genesis); err != nil {
+		return err
+	}
+
+	prevHash := s.Genesis.HashHeader()
+	for i, h := range s.Headers {
+		if h.BkSeq != uint64(i)+1 {
+			return fmt.Errorf("snapshot block header %d has seq %d", i+1, h.BkSeq)
+		}
+		if h.PrevHash != prevHash {
+			return fmt.Errorf("block %d does not reference the hash of the previous block", h.BkSeq)
+		}
+
+		prevHash = h.Hash()
+		if err := cipher.VerifyPubKeySignedHash(pubkey, s.Sigs[i], prevHash); err != nil {
+			return fmt.Errorf("block %d signature verification failed: %v", h.BkSeq, err)
+		}
+	}
+
+	if s.Block.Seq() != uint64(len(s.Headers))+1 {
+		return fmt.Errorf("snapshot block has seq %d", s.Block.Seq())
+	}
+	if s.Block.Head.PrevHash != prevHash {
+		return fmt.Errorf("block %d does not reference the hash of the previous block", s.Block.Seq())
+	}
+	if err := verifyBlock(&s.Block); err != nil {
+		return err
+	}
+
+	var uxHash cipher.SHA256
+	for _, ux := range s.Unspents {
+		if ux.Head.BkSeq >= s.Block.Seq() {
+			return fmt.Errorf("snapshot unspent output %s was created after the snapshot", ux.Hash().Hex())
+		}
+		uxHash = uxHash.Xor(ux.SnapshotHash())
+	}
+
+	if uxHash != s.Block.Head.UxHash {
+		return errors.New("snapshot unspent outputs do not match the UxHash of the snapshot block")
+	}
+
+	return nil
+}
+
+// ReadSnapshot reads a snapshot file
+func ReadSnapshot(r io.Reader) (*Snapshot, error) {
+	buf, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	version, _, err := encoder.DeserializeUint32(buf)
+	if err != nil {
+		return nil, err
+	}
+
+	if version != SnapshotVersion {
+		return nil, ErrSnapshotVersion
+	}
+
+	var s Snapshot
+	if err := encoder.DeserializeRawExact(buf, &s); err != nil {
+		return nil, err
+	}
+
+	return &s, nil
+}
+
+// WriteSnapshot writes a snapshot file
+func WriteSnapshot(w io.Writer, s *Snapshot) error {
+	_, err := w.Write(encoder.Serialize(s))
+	return err
+}
+
+// CreateSnapshot creates a snapshot of the blockchain at the block seq, or at the head block if seq is 0.
+// The unspent outputs before the block are rebuilt from the history indexes,
+// so a snapshot can not be created from a pruned database.
+func CreateSnapshot(db *dbutil.DB, seq uint64) (*Snapshot, error) {
+	bc, err := NewBlockchain(db, BlockchainConfig{})
+	if err != nil {
+		return nil, err
+	}
+
+	history := historydb.New()
+
+	s := &Snapshot{
+		Version: SnapshotVersion,
+	}
+
+	if err := db.View("CreateSnapshot", func(tx *dbutil.Tx) error {
+		headSeq, ok, err := bc.HeadSeq(tx)
+		if err != nil {
+			return err
+		} else if !ok {
+			return blockdb.ErrNoHeadBlock
+		}
+
+		if seq == 0 {
+			seq = headSeq
+		}
+
+		if seq == 0 || seq > headSeq {
+			return fmt.Errorf("snapshot block seq must be between 1 and the head block seq %d", headSeq)
+		}
+
+		if _, pruned, err := bc.PruneSeq(tx); err != nil {
+			return err
+		} else if pruned {
+			return ErrHistoryPruned
+		}
+
+		if _, _, backfilling, err := bc.BackfillRange(tx); err != nil {
+			return err
+		} else if backfilling {
+			return ErrHistoryBackfilling
+		}
+
+		parsedSeq, ok, err := history.ParsedBlockSeq(tx)
+		if err != nil {
+			return err
+		} else if !ok || parsedSeq < seq-1 {
+			return errors.New("history is not parsed up to the snapshot block")
+		}
+
+		for i := uint64(0); i <= seq; i++ {
+			b, err := bc.GetSignedBlockBySeq(tx, i)
+			if err != nil {
+				return err
+			} else if b == nil {
+				return fmt.Errorf("no block exists in depth: %d", i)
+			}
+
+			switch i {
+			case 0:
+				s.Genesis = *b
+			case seq:
+				s.Block = *b
+			default:
+				s.Headers = append(s.Headers, b.Head)
+				s.Sigs = append(s.Sigs, b.Sig)
+			}
+		}
+
+		// The outputs that were created before the snapshot block and not spent before it
+		return history.ForEachUxOut(tx, func(ux *historydb.UxOut) error {
+			if ux.Out.Head.BkSeq < seq && (ux.SpentBlockSeq == 0 || ux.SpentBlockSeq >= seq) {
+				s.Unspents = append(s.Unspents, ux.Out)
+			}
+			return nil
+		})
+	}); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(s.Unspents, func(i, j int) bool {
+		a := s.Unspents[i].Hash()
+		b := s.Unspents[j].Hash()
+		return bytes.Compare(a[:], b[:]) < 0
+	})
+
+	return s, nil
+}
+
+// ImportSnapshot verifies a snapshot and initializes an empty database from it.
+// The node starts at the snapshot block, and downloads the bodies of the blocks before it from peers.
+func ImportSnapshot(db *dbutil.DB, pubkey cipher.PubKey, s *Snapshot) error {
+	if err := s.Verify(pubkey); err != nil {
+		return err
+	}
+
+	if err := CreateBuckets(db); err != nil {
+		return err
+	}
+
+	bc, err := NewBlockchain(db, BlockchainConfig{
+		Pubkey: pubkey,
+	})
+	if err != nil {
+		return err
+	}
+
+	return db.Update("ImportSnapshot", func(tx *dbutil.Tx) error {
+		return bc.ImportSnapshot(tx, s.Blocks(), s.Unspents)
+	})
+}
+
+// backfillHistory is the Historyer of a node that was started from a snapshot.
+// Until the bodies of the blocks before the snapshot have been downloaded, blocks are not parsed
+// and history queries return ErrHistoryBackfilling. Then the history is parsed up to the head block.
+type backfillHistory struct {
+	*historydb.HistoryDB
+	done int32
+}
+
+func (h *backfillHistory) ready() bool {
+	return atomic.LoadInt32(&h.done) == 1
+}
+
+func (h *backfillHistory) GetUxOuts(tx *dbutil.Tx, uxids []cipher.SHA256) ([]historydb.UxOut, error) {
+	if !h.ready() {
+		return nil, ErrHistoryBackfilling
+	}
+	return h.HistoryDB.GetUxOuts(tx, uxids)
+}
+
+func (h *backfillHistory) ParseBlock(tx *dbutil.Tx, b coin.Block) error {
+	if !h.ready() {
+		return nil
+	}
+	return h.HistoryDB.ParseBlock(tx, b)
+}
+
+func (h *backfillHistory) GetTransaction(tx *dbutil.Tx, hash cipher.SHA256) (*historydb.Transaction, error) {
+	if !h.ready() {
+		return nil, ErrHistoryBackfilling
+	}
+	return h.HistoryDB.GetTransaction(tx, hash)
+}
+
+func (h *backfillHistory) GetOutputsForAddress(tx *dbutil.Tx, address cipher.Address) ([]historydb.UxOut, error) {
+	if !h.ready() {
+		return nil, ErrHistoryBackfilling
+	}
+	return h.HistoryDB.GetOutputsForAddress(tx, address)
+}
+
+func (h *backfillHistory) GetTransactionsForAddress(tx *dbutil.Tx, address cipher.Address) ([]historydb.Transaction, error) {
+	if !h.ready() {
+		return nil, ErrHistoryBackfilling
+	}
+	return h.HistoryDB.GetTransactionsForAddress(tx, address)
+}
+
+func (h *backfillHistory) ForEachTxn(tx *dbutil.Tx, f func(cipher.SHA256, *historydb.Transaction) error) error {
+	if !h.ready() {
+		return ErrHistoryBackfilling
+	}
+	return h.HistoryDB.ForEachTxn(tx, f)
+}
+
+func (h *backfillHistory) BlockTimesIndexed(tx *dbutil.Tx) (bool, error) {
+	if !h.ready() {
+		return false, nil
+	}
+	return h.HistoryDB.BlockTimesIndexed(tx)
+}
+
+func (h *backfillHistory) GetBlockSeqsInTimeRange(tx *dbutil.Tx, start, end uint64) (uint64, uint64, bool, error) {
+	if !h.ready() {
+		return 0, 0, false, ErrHistoryBackfilling
+	}
+	return h.HistoryDB.GetBlockSeqsInTimeRange(tx, start, end)
+}
+
+func (h *backfillHistory) BlockStatsReady(tx *dbutil.Tx) (bool, error) {
+	if !h.ready() {
+		return false, ErrHistoryBackfilling
+	}
+	return h.HistoryDB.BlockStatsReady(tx)
+}
+
+func (h *backfillHistory) GetBlockStats(tx *dbutil.Tx, seq uint64) (*historydb.BlockStats, error) {
+	if !h.ready() {
+		return nil, ErrHistoryBackfilling
+	}
+	return h.HistoryDB.GetBlockStats(tx, seq)
+}
+
+func (h *backfillHistory) ForEachBlockStats(tx *dbutil.Tx, start, end uint64, f func(historydb.BlockStats) error) error {
+	if !h.ready() {
+		return ErrHistoryBackfilling
+	}
+	return h.HistoryDB.ForEachBlockStats(tx, start, end, f)
+}
+
+func (h *backfillHistory) ForEachDailyStats(tx *dbutil.Tx, start, end uint64, f func(historydb.DailyStats) error) error {
+	if !h.ready() {
+		return ErrHistoryBackfilling
+	}
+	return h.HistoryDB.ForEachDailyStats(tx, start, end, f)
+}
+
+// finish parses the history from the genesis block to the head block, after the last block was backfilled.
+// The history must be marked as ready with setReady once tx has been committed.
+func (h *backfillHistory) finish(tx *dbutil.Tx, bc Blockchainer) error {
+	logger.Info("All blocks have been backfilled, parsing the history")
+
+	if err := h.HistoryDB.Erase(tx); err != nil {
+		return err
+	}
+
+	headSeq, _, err := bc.HeadSeq(tx)
+	if err != nil {
+		return err
+	}
+
+	for seq := uint64(0); seq <= headSeq; seq++ {
+		b, err := bc.GetSignedBlockBySeq(tx, seq)
+		if err != nil {
+			return err
+		} else if b == nil {
+			return fmt.Errorf("no block exists in depth: %d", seq)
+		}
+
+		if err := h.HistoryDB.ParseBlock(tx, b.Block); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (h *backfillHistory) setReady() {
+	atomic.StoreInt32(&h.done, 1)
+}
+
+// BackfillRange returns the inclusive range of block sequences whose bodies have not been downloaded yet,
+// after starting from a snapshot. Returns false if no block needs to be backfilled.
+func (vs *Visor) BackfillRange() (uint64, uint64, bool, error) {
+	var start, end uint64
+	var ok bool
+
+	if err := vs.db.View("BackfillRange", func(tx *dbutil.Tx) error {
+		var err error
+		start, end, ok, err = vs.blockchain.BackfillRange(tx)
+		return err
+	}); err != nil {
+		return 0, 0, false, err
+	}
+
+	return start, end, ok, nil
+}
+
+// ExecuteBackfillBlocks stores the bodies of blocks received from a peer that are missing after starting from a snapshot.
+// The blocks must be sequential, blocks that were already backfilled are skipped.
+// When the last block has been backfilled, the history is parsed.
+// Returns the number of blocks that were backfilled.
+func (vs *Visor) ExecuteBackfillBlocks(blocks []coin.SignedBlock) (int, error) {
+	var n int
+	var finished *backfillHistory
+
+	if err := vs.db.Update("ExecuteBackfillBlocks", func(tx *dbutil.Tx) error {
+		n = 0
+		finished = nil
+
+		for i := range blocks {
+			start, _, ok, err := vs.blockchain.BackfillRange(tx)
+			if err != nil {
+				return err
+			} else if !ok {
+				break
+			}
+
+			if blocks[i].Seq() < start {
+				continue
+			}
+
+			if err := vs.blockchain.BackfillBlock(tx, &blocks[i]); err != nil {
+				return err
+			}
+			n++
+		}
+
+		if n == 0 {
+			return nil
+		}
+
+		if _, _, ok, err := vs.blockchain.BackfillRange(tx); err != nil || ok {
+			return err
+		}
+
+		h, ok := vs.history.(*backfillHistory)
+		if !ok {
+			return nil
+		}
+
+		if err := h.finish(tx, vs.blockchain); err != nil {
+			return err
+		}
+
+		finished = h
+		return nil
+	}); err != nil {
+		return 0, err
+	}
+
+	if finished != nil {
+		finished.setReady()
+	}
+
+	return n, nil
+}
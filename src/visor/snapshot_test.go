@@ -0,0 +1,201 @@
+package visor
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/params"
+	"github.com/skycoin/skycoin/src/testutil"
+	"github.com/skycoin/skycoin/src/visor/blockdb"
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+)
+
+func getAllUnspents(t *testing.T, v *Visor) coin.UxArray {
+	var uxs coin.UxArray
+	err := v.db.View("", func(tx *dbutil.Tx) error {
+		var err error
+		uxs, err = v.blockchain.Unspent().GetAll(tx)
+		return err
+	})
+	require.NoError(t, err)
+	uxs.Sort()
+	return uxs
+}
+
+func TestVisorSnapshot(t *testing.T) {
+	db, shutdown := prepareDB(t)
+	defer shutdown()
+
+	cfg := NewConfig()
+	cfg.IsBlockPublisher = true
+	cfg.BlockchainPubkey = genPublic
+	cfg.BlockchainSeckey = genSecret
+	cfg.GenesisAddress = genAddress
+
+	v, err := New(cfg, db, nil)
+	require.NoError(t, err)
+
+	gb := addGenesisBlockToVisor(t, v)
+
+	uxs := coin.CreateUnspents(gb.Head, gb.Body.Transactions[0])
+	txn := makeUnspentsTxn(t, uxs, []cipher.SecKey{genSecret}, genAddress, 10, params.UserVerifyTxn.MaxDropletPrecision)
+	_, softErr, err := v.InjectForeignTransaction(txn)
+	require.NoError(t, err)
+	require.Nil(t, softErr)
+
+	// Create the blocks at increasing times, since a block time can not be equal to the previous block time
+	when := uint64(time.Now().UTC().Unix())
+	createAndExecuteBlock := func() {
+		when++
+		err := db.Update("", func(tx *dbutil.Tx) error {
+			sb, err := v.createBlock(tx, when)
+			if err != nil {
+				return err
+			}
+
+			return v.executeSignedBlock(tx, sb)
+		})
+		require.NoError(t, err)
+	}
+
+	createAndExecuteBlock()
+
+	b, err := v.GetBlock(1)
+	require.NoError(t, err)
+	uxs = coin.CreateUnspents(b.Head, b.Body.Transactions[0])
+	for i := 0; i < 3; i++ {
+		txn = makeSpendTxWithFee(t, coin.UxArray{uxs[i]}, []cipher.SecKey{genSecret}, testutil.MakeAddress(), 1e6, 0)
+		_, softErr, err := v.InjectForeignTransaction(txn)
+		require.NoError(t, err)
+		require.Nil(t, softErr)
+
+		createAndExecuteBlock()
+	}
+
+	// A snapshot of an earlier block is rebuilt from the history
+	s, err := CreateSnapshot(db, 2)
+	require.NoError(t, err)
+	require.NoError(t, s.Verify(genPublic))
+	require.Len(t, s.Headers, 1)
+
+	_, err = CreateSnapshot(db, 5)
+	require.Error(t, err)
+
+	// The snapshot is created at the head block by default
+	s, err = CreateSnapshot(db, 0)
+	require.NoError(t, err)
+	require.NoError(t, s.Verify(genPublic))
+	require.Equal(t, uint64(4), s.Block.Seq())
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteSnapshot(&buf, s))
+	s2, err := ReadSnapshot(&buf)
+	require.NoError(t, err)
+	require.Equal(t, s, s2)
+
+	// Tampered snapshots do not verify
+	tampered := *s
+	tampered.Unspents = append(coin.UxArray{}, s.Unspents...)
+	tampered.Unspents[0].Body.Coins++
+	require.EqualError(t, tampered.Verify(genPublic), "snapshot unspent outputs do not match the UxHash of the snapshot block")
+
+	tampered = *s
+	tampered.Headers = append([]coin.BlockHeader{}, s.Headers...)
+	tampered.Headers[1].Time++
+	require.Error(t, tampered.Verify(genPublic))
+
+	tampered = *s
+	tampered.Version = SnapshotVersion + 1
+	require.Equal(t, ErrSnapshotVersion, tampered.Verify(genPublic))
+
+	// Start a node from the snapshot
+	db2, shutdown2 := prepareDB(t)
+	defer shutdown2()
+
+	require.NoError(t, ImportSnapshot(db2, genPublic, s))
+
+	cfg2 := NewConfig()
+	cfg2.BlockchainPubkey = genPublic
+	cfg2.GenesisAddress = genAddress
+	cfg2.GenesisSignature = gb.Sig
+	cfg2.GenesisTimestamp = gb.Head.Time
+
+	v2, err := New(cfg2, db2, nil)
+	require.NoError(t, err)
+	require.NoError(t, v2.Init())
+
+	headSeq, ok, err := v2.HeadBkSeq()
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, uint64(4), headSeq)
+
+	require.Equal(t, getAllUnspents(t, v), getAllUnspents(t, v2))
+
+	start, end, ok, err := v2.BackfillRange()
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, uint64(1), start)
+	require.Equal(t, uint64(3), end)
+
+	_, err = v2.GetBlock(2)
+	require.Equal(t, blockdb.ErrBlockNotBackfilled, err)
+	require.True(t, IsErrBackfilling(err))
+
+	_, err = v2.GetTransaction(txn.Hash())
+	require.Equal(t, ErrHistoryBackfilling, err)
+
+	require.NoError(t, CheckDatabase(db2, genPublic, nil))
+
+	// Backfill the blocks before the snapshot
+	blocks, err := v.GetSignedBlocksSince(0, 10)
+	require.NoError(t, err)
+	require.Len(t, blocks, 4)
+
+	n, err := v2.ExecuteBackfillBlocks(blocks[:2])
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+
+	// Only the blocks before the first block that is not backfilled yet are returned
+	since, err := v2.GetSignedBlocksSince(0, 10)
+	require.NoError(t, err)
+	require.Equal(t, blocks[:2], since)
+
+	_, err = v2.GetSignedBlocksSince(2, 10)
+	require.Equal(t, blockdb.ErrBlockNotBackfilled, err)
+
+	_, err = v2.GetTransaction(txn.Hash())
+	require.Equal(t, ErrHistoryBackfilling, err)
+
+	n, err = v2.ExecuteBackfillBlocks(blocks)
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+
+	_, _, ok, err = v2.BackfillRange()
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	for i := uint64(0); i <= 4; i++ {
+		b, err := v.GetBlock(i)
+		require.NoError(t, err)
+		b2, err := v2.GetBlock(i)
+		require.NoError(t, err)
+		require.Equal(t, b, b2)
+	}
+
+	// The history was parsed once all the blocks were backfilled
+	htxn, err := v2.GetTransaction(txn.Hash())
+	require.NoError(t, err)
+	require.NotNil(t, htxn)
+	require.Equal(t, txn, htxn.Transaction)
+	require.Equal(t, uint64(4), htxn.Status.BlockSeq)
+
+	require.NoError(t, CheckDatabase(db2, genPublic, nil))
+
+	// A snapshot can only be imported into an empty database
+	require.Error(t, ImportSnapshot(db2, genPublic, s))
+}
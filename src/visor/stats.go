@@ -0,0 +1,223 @@
+package visor
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+	"github.com/skycoin/skycoin/src/visor/historydb"
+)
+
+// StatsMetric is a chain statistic that can be queried as a time series
+type StatsMetric string
+
+const (
+	// StatsMetricCoinSupply is the coin supply in droplets, excluding the coins of the distribution addresses
+	StatsMetricCoinSupply StatsMetric = "coin_supply"
+	// StatsMetricCoinHourSupply is the approximate coin hour supply
+	StatsMetricCoinHourSupply StatsMetric = "coin_hour_supply"
+	// StatsMetricTransactions is the number of transactions
+	StatsMetricTransactions StatsMetric = "transactions"
+	// StatsMetricFeesBurned is the number of coin hours burned as transaction fees
+	StatsMetricFeesBurned StatsMetric = "fees_burned"
+	// StatsMetricActiveAddresses is the number of addresses that received or spent outputs
+	StatsMetricActiveAddresses StatsMetric = "active_addresses"
+)
+
+// StatsMetrics are the valid stats metrics
+var StatsMetrics = []StatsMetric{
+	StatsMetricCoinSupply,
+	StatsMetricCoinHourSupply,
+	StatsMetricTransactions,
+	StatsMetricFeesBurned,
+	StatsMetricActiveAddresses,
+}
+
+// StatsInterval is the interval of the points of a stats time series
+type StatsInterval string
+
+const (
+	// StatsIntervalBlock has one point per block
+	StatsIntervalBlock StatsInterval = "block"
+	// StatsIntervalDay has one point per UTC day with blocks
+	StatsIntervalDay StatsInterval = "day"
+)
+
+// StatsIntervals are the valid stats intervals
+var StatsIntervals = []StatsInterval{
+	StatsIntervalBlock,
+	StatsIntervalDay,
+}
+
+var (
+	// ErrBlockStatsNotReady is returned when chain statistics are requested before the block statistics have been added.
+	// The block statistics of an existing database are added by the backfill_block_stats migration.
+	ErrBlockStatsNotReady = errors.New("chain statistics are not available until the block statistics have been backfilled")
+
+	// ErrStatsTooManyBlocks is returned when a block interval series would have more than maxStatsBlockPoints points
+	ErrStatsTooManyBlocks = errors.New("the time range has too many blocks for the block interval, use a shorter time range or the day interval")
+)
+
+// maxStatsBlockPoints is the maximum number of points of a block interval series
+var maxStatsBlockPoints uint64 = 10000
+
+// IsValidStatsMetric returns true if m is one of StatsMetrics
+func IsValidStatsMetric(m StatsMetric) bool {
+	for _, x := range StatsMetrics {
+		if x == m {
+			return true
+		}
+	}
+	return false
+}
+
+// IsValidStatsInterval returns true if i is one of StatsIntervals
+func IsValidStatsInterval(i StatsInterval) bool {
+	for _, x := range StatsIntervals {
+		if x == i {
+			return true
+		}
+	}
+	return false
+}
+
+// StatsPoint is a point of a stats time series
+type StatsPoint struct {
+	// Time is the time of the block, or the start of the day for daily points
+	Time uint64
+	// Seq is the seq of the block, or of the last block of the day for daily points
+	Seq uint64
+	// Value is the value of the metric
+	Value uint64
+}
+
+// blockStatsValue returns the value of a metric for a block
+func blockStatsValue(metric StatsMetric, s historydb.BlockStats) (uint64, error) {
+	switch metric {
+	case StatsMetricCoinSupply:
+		return s.CoinSupply(), nil
+	case StatsMetricCoinHourSupply:
+		return s.CoinHourSupply(), nil
+	case StatsMetricTransactions:
+		return s.Transactions, nil
+	case StatsMetricFeesBurned:
+		return s.FeesBurned, nil
+	case StatsMetricActiveAddresses:
+		return s.ActiveAddresses, nil
+	default:
+		return 0, fmt.Errorf("invalid stats metric %q", metric)
+	}
+}
+
+// GetStatsSeries returns the time series of a chain statistic for the blocks with a time between start and end, inclusive.
+// Daily points are returned for the days that overlap the time range. The supply metrics of a day are
+// the supplies after its last block, the other metrics are the totals of the day.
+// Returns ErrBlockStatsNotReady if the block statistics of an existing database have not been backfilled yet,
+// and ErrStatsTooManyBlocks if a block interval series would have more than 10000 points.
+func (vs *Visor) GetStatsSeries(metric StatsMetric, interval StatsInterval, start, end uint64) ([]StatsPoint, error) {
+	if !IsValidStatsMetric(metric) {
+		return nil, fmt.Errorf("invalid stats metric %q", metric)
+	}
+
+	if !IsValidStatsInterval(interval) {
+		return nil, fmt.Errorf("invalid stats interval %q", interval)
+	}
+
+	var points []StatsPoint
+	if err := vs.db.View("GetStatsSeries", func(tx *dbutil.Tx) error {
+		ready, err := vs.history.BlockStatsReady(tx)
+		if err != nil {
+			return err
+		}
+
+		if !ready {
+			return ErrBlockStatsNotReady
+		}
+
+		switch interval {
+		case StatsIntervalBlock:
+			points, err = vs.getBlockStatsSeries(tx, metric, start, end)
+		case StatsIntervalDay:
+			points, err = vs.getDailyStatsSeries(tx, metric, start, end)
+		}
+		return err
+	}); err != nil {
+		return nil, err
+	}
+
+	return points, nil
+}
+
+func (vs *Visor) getBlockStatsSeries(tx *dbutil.Tx, metric StatsMetric, start, end uint64) ([]StatsPoint, error) {
+	first, last, ok, err := vs.blockSeqsInTimeRange(tx, start, end)
+	if err != nil || !ok {
+		return []StatsPoint{}, err
+	}
+
+	if last-first+1 > maxStatsBlockPoints {
+		return nil, ErrStatsTooManyBlocks
+	}
+
+	points := make([]StatsPoint, 0, last-first+1)
+	if err := vs.history.ForEachBlockStats(tx, first, last, func(s historydb.BlockStats) error {
+		v, err := blockStatsValue(metric, s)
+		if err != nil {
+			return err
+		}
+
+		points = append(points, StatsPoint{
+			Time:  s.Time,
+			Seq:   s.Seq,
+			Value: v,
+		})
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return points, nil
+}
+
+func (vs *Visor) getDailyStatsSeries(tx *dbutil.Tx, metric StatsMetric, start, end uint64) ([]StatsPoint, error) {
+	points := []StatsPoint{}
+	if start > end {
+		return points, nil
+	}
+
+	if err := vs.history.ForEachDailyStats(tx, historydb.DayStart(start), end, func(d historydb.DailyStats) error {
+		p := StatsPoint{
+			Time: d.Day,
+			Seq:  d.LastSeq,
+		}
+
+		switch metric {
+		case StatsMetricTransactions:
+			p.Value = d.Transactions
+		case StatsMetricFeesBurned:
+			p.Value = d.FeesBurned
+		case StatsMetricActiveAddresses:
+			p.Value = d.ActiveAddresses
+		default:
+			s, err := vs.history.GetBlockStats(tx, d.LastSeq)
+			if err != nil {
+				return err
+			}
+
+			if s == nil {
+				return fmt.Errorf("the statistics of block %d do not exist", d.LastSeq)
+			}
+
+			p.Value, err = blockStatsValue(metric, *s)
+			if err != nil {
+				return err
+			}
+		}
+
+		points = append(points, p)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return points, nil
+}
@@ -0,0 +1,146 @@
+package visor
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/params"
+	"github.com/skycoin/skycoin/src/testutil"
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+	"github.com/skycoin/skycoin/src/visor/historydb"
+)
+
+func TestGetStatsSeries(t *testing.T) {
+	db, shutdown := prepareDB(t)
+	defer shutdown()
+
+	cfg := NewConfig()
+	cfg.IsBlockPublisher = true
+	cfg.BlockchainPubkey = genPublic
+	cfg.BlockchainSeckey = genSecret
+	cfg.GenesisAddress = genAddress
+	cfg.GenesisCoinVolume = genCoins
+	cfg.GenesisTimestamp = genTime
+
+	v, err := New(cfg, db, nil)
+	require.NoError(t, err)
+
+	gb := addGenesisBlockToVisor(t, v)
+
+	createAndExecuteBlock := func(txn coin.Transaction, when uint64) coin.SignedBlock {
+		_, softErr, err := v.InjectForeignTransaction(txn)
+		require.NoError(t, err)
+		require.Nil(t, softErr)
+
+		var sb coin.SignedBlock
+		err = db.Update("", func(tx *dbutil.Tx) error {
+			var err error
+			sb, err = v.createBlock(tx, when)
+			if err != nil {
+				return err
+			}
+
+			return v.executeSignedBlock(tx, sb)
+		})
+		require.NoError(t, err)
+		return sb
+	}
+
+	// The genesis block and block 1 are on the first day, blocks 2 and 3 are on the next day
+	day := uint64(86400)
+	uxs := coin.CreateUnspents(gb.Head, gb.Body.Transactions[0])
+	sb := createAndExecuteBlock(makeUnspentsTxn(t, uxs, []cipher.SecKey{genSecret}, genAddress, 4, params.UserVerifyTxn.MaxDropletPrecision), genTime+1000)
+	uxs = coin.CreateUnspents(sb.Head, sb.Body.Transactions[0])
+	coins := uxs[0].Body.Coins
+
+	for i, when := range []uint64{day + 100, day + 200} {
+		txn := makeSpendTxWithFee(t, coin.UxArray{uxs[i]}, []cipher.SecKey{genSecret}, testutil.MakeAddress(), coins, 0)
+		createAndExecuteBlock(txn, when)
+	}
+
+	blockTimes := []uint64{genTime, genTime + 1000, day + 100, day + 200}
+
+	series := func(metric StatsMetric, interval StatsInterval, start, end uint64) []StatsPoint {
+		points, err := v.GetStatsSeries(metric, interval, start, end)
+		require.NoError(t, err)
+		return points
+	}
+
+	values := func(points []StatsPoint) []uint64 {
+		vs := make([]uint64, len(points))
+		for i, p := range points {
+			vs[i] = p.Value
+		}
+		return vs
+	}
+
+	// Block points
+	txnPoints := series(StatsMetricTransactions, StatsIntervalBlock, 0, math.MaxUint64)
+	require.Len(t, txnPoints, 4)
+	for i, p := range txnPoints {
+		require.Equal(t, uint64(i), p.Seq)
+		require.Equal(t, blockTimes[i], p.Time)
+	}
+	require.Equal(t, []uint64{1, 1, 1, 1}, values(txnPoints))
+
+	require.Equal(t, []uint64{1, 1, 2, 2}, values(series(StatsMetricActiveAddresses, StatsIntervalBlock, 0, math.MaxUint64)))
+	require.Equal(t, []uint64{genCoins, genCoins, genCoins, genCoins}, values(series(StatsMetricCoinSupply, StatsIntervalBlock, 0, math.MaxUint64)))
+
+	require.Equal(t, txnPoints[1:3], series(StatsMetricTransactions, StatsIntervalBlock, genTime+1, day+100))
+	require.Empty(t, series(StatsMetricTransactions, StatsIntervalBlock, day+201, math.MaxUint64))
+
+	// Daily points
+	dayPoints := series(StatsMetricTransactions, StatsIntervalDay, 0, math.MaxUint64)
+	require.Equal(t, []StatsPoint{
+		{Time: 0, Seq: 1, Value: 2},
+		{Time: day, Seq: 3, Value: 2},
+	}, dayPoints)
+
+	// The days that overlap the time range are included
+	require.Equal(t, dayPoints[1:], series(StatsMetricTransactions, StatsIntervalDay, day+150, day+150))
+	require.Empty(t, series(StatsMetricTransactions, StatsIntervalDay, day+150, day))
+
+	require.Equal(t, []uint64{1, 3}, values(series(StatsMetricActiveAddresses, StatsIntervalDay, 0, math.MaxUint64)))
+
+	// The fees of a day are the total of its blocks
+	blockFees := values(series(StatsMetricFeesBurned, StatsIntervalBlock, 0, math.MaxUint64))
+	require.Equal(t, []uint64{blockFees[0] + blockFees[1], blockFees[2] + blockFees[3]}, values(series(StatsMetricFeesBurned, StatsIntervalDay, 0, math.MaxUint64)))
+	require.NotEqual(t, uint64(0), blockFees[1])
+
+	// The supplies of a day are the supplies after its last block
+	blockHours := values(series(StatsMetricCoinHourSupply, StatsIntervalBlock, 0, math.MaxUint64))
+	require.Equal(t, []uint64{blockHours[1], blockHours[3]}, values(series(StatsMetricCoinHourSupply, StatsIntervalDay, 0, math.MaxUint64)))
+
+	// A block interval series is limited to maxStatsBlockPoints points
+	defer func(n uint64) {
+		maxStatsBlockPoints = n
+	}(maxStatsBlockPoints)
+	maxStatsBlockPoints = 2
+
+	require.Len(t, series(StatsMetricTransactions, StatsIntervalBlock, genTime+1, day+100), 2)
+	_, err = v.GetStatsSeries(StatsMetricTransactions, StatsIntervalBlock, 0, math.MaxUint64)
+	require.Equal(t, ErrStatsTooManyBlocks, err)
+	require.Len(t, series(StatsMetricTransactions, StatsIntervalDay, 0, math.MaxUint64), 2)
+
+	// Invalid metrics and intervals are rejected
+	_, err = v.GetStatsSeries(StatsMetric("foo"), StatsIntervalDay, 0, math.MaxUint64)
+	require.Error(t, err)
+	require.Equal(t, `invalid stats metric "foo"`, err.Error())
+
+	_, err = v.GetStatsSeries(StatsMetricTransactions, StatsInterval("week"), 0, math.MaxUint64)
+	require.Error(t, err)
+	require.Equal(t, `invalid stats interval "week"`, err.Error())
+
+	// The statistics are not available until they are backfilled
+	err = db.Update("", func(tx *dbutil.Tx) error {
+		return historydb.New().ResetBlockStats(tx)
+	})
+	require.NoError(t, err)
+
+	_, err = v.GetStatsSeries(StatsMetricTransactions, StatsIntervalDay, 0, math.MaxUint64)
+	require.Equal(t, ErrBlockStatsNotReady, err)
+}
@@ -13,6 +13,7 @@ package visor
 import (
 	"errors"
 	"fmt"
+	"math"
 	"sort"
 
 	"time"
@@ -79,6 +80,23 @@ func New(c Config, db *dbutil.DB, wltServ *wallet.Service) (*Visor, error) {
 		return nil, err
 	}
 
+	var backfilling bool
+	if err := db.View("check pruned", func(tx *dbutil.Tx) error {
+		_, pruned, err := bc.PruneSeq(tx)
+		if err != nil {
+			return err
+		}
+
+		if pruned && !c.Prune {
+			return ErrDBPruned
+		}
+
+		_, _, backfilling, err = bc.BackfillRange(tx)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+
 	history := historydb.New()
 
 	if !db.IsReadOnly() {
@@ -92,6 +110,15 @@ func New(c Config, db *dbutil.DB, wltServ *wallet.Service) (*Visor, error) {
 				return err
 			}
 
+			if c.Prune {
+				return initPruning(tx, bc, c.PruneKeepBlocks)
+			}
+
+			// The history is parsed once the blocks before the snapshot have been backfilled
+			if backfilling {
+				return nil
+			}
+
 			return initHistory(tx, bc, history)
 		}); err != nil {
 			return nil, err
@@ -113,6 +140,16 @@ func New(c Config, db *dbutil.DB, wltServ *wallet.Service) (*Visor, error) {
 		wallets:     wltServ,
 	}
 
+	if c.Prune {
+		logger.Infof("Visor running in pruned mode, keeping the last %d block bodies", c.PruneKeepBlocks)
+		v.history = prunedHistory{}
+	} else if backfilling {
+		logger.Info("Visor was started from a snapshot, the history is not available until the blocks before the snapshot have been downloaded")
+		v.history = &backfillHistory{
+			HistoryDB: history,
+		}
+	}
+
 	return v, nil
 }
 
@@ -142,12 +179,14 @@ func (vs *Visor) Init() error {
 func initHistory(tx *dbutil.Tx, bc *Blockchain, history *historydb.HistoryDB) error {
 	logger.Info("Visor initHistory")
 
-	shouldReset, err := history.NeedsReset(tx)
+	// Incomplete histories of existing databases are rebuilt by the reparse_history migration,
+	// here the history is only parsed if it has never been
+	_, parsed, err := history.ParsedBlockSeq(tx)
 	if err != nil {
 		return err
 	}
 
-	if !shouldReset {
+	if parsed {
 		return nil
 	}
 
@@ -158,12 +197,16 @@ func initHistory(tx *dbutil.Tx, bc *Blockchain, history *historydb.HistoryDB) er
 	}
 
 	// Reparse the history up to the blockchain head
-	headSeq, _, err := bc.HeadSeq(tx)
+	headSeq, ok, err := bc.HeadSeq(tx)
 	if err != nil {
 		return err
 	}
 
-	if err := parseHistoryTo(tx, history, bc, headSeq); err != nil {
+	if !ok {
+		return nil
+	}
+
+	if err := parseHistoryTo(tx, history, bc, headSeq, nil); err != nil {
 		logger.WithError(err).Error("parseHistoryTo failed")
 		return err
 	}
@@ -171,27 +214,39 @@ func initHistory(tx *dbutil.Tx, bc *Blockchain, history *historydb.HistoryDB) er
 	return nil
 }
 
-func parseHistoryTo(tx *dbutil.Tx, history *historydb.HistoryDB, bc *Blockchain, height uint64) error {
+// parseHistoryTo parses the blocks after the parsed block seq up to height,
+// starting from the genesis block if no block has been parsed.
+// progress, if not nil, is called with the seq of each parsed block.
+func parseHistoryTo(tx *dbutil.Tx, history *historydb.HistoryDB, bc *Blockchain, height uint64, progress func(seq uint64)) error {
 	logger.Info("Visor parseHistoryTo")
 
-	parsedBlockSeq, _, err := history.ParsedBlockSeq(tx)
+	parsedBlockSeq, parsed, err := history.ParsedBlockSeq(tx)
 	if err != nil {
 		return err
 	}
 
-	for i := uint64(0); i < height-parsedBlockSeq; i++ {
-		b, err := bc.GetSignedBlockBySeq(tx, parsedBlockSeq+i+1)
+	start := uint64(0)
+	if parsed {
+		start = parsedBlockSeq + 1
+	}
+
+	for seq := start; seq <= height; seq++ {
+		b, err := bc.GetSignedBlockBySeq(tx, seq)
 		if err != nil {
 			return err
 		}
 
 		if b == nil {
-			return fmt.Errorf("no block exists in depth: %d", parsedBlockSeq+i+1)
+			return fmt.Errorf("no block exists in depth: %d", seq)
 		}
 
 		if err := history.ParseBlock(tx, b.Block); err != nil {
 			return err
 		}
+
+		if progress != nil {
+			progress(b.Seq())
+		}
 	}
 
 	return nil
@@ -405,7 +460,17 @@ func (vs *Visor) executeSignedBlock(tx *dbutil.Tx, b coin.SignedBlock) error {
 	}
 
 	// Update the HistoryDB
-	return vs.history.ParseBlock(tx, b.Block)
+	if err := vs.history.ParseBlock(tx, b.Block); err != nil {
+		return err
+	}
+
+	if vs.Config.Prune {
+		if _, err := vs.blockchain.Prune(tx, vs.Config.PruneKeepBlocks); err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
 
 // signBlock signs a block for a block publisher node. Will panic if anything is invalid
@@ -509,6 +574,8 @@ func (vs *Visor) unconfirmedIncomingOutputs(tx *dbutil.Tx) (coin.UxArray, error)
 }
 
 // GetSignedBlocksSince returns N signed blocks more recent than Seq. Does not return nil.
+// If a block was pruned or has not been backfilled, the blocks before it are returned,
+// unless it is the first block, in which case the error is returned.
 func (vs *Visor) GetSignedBlocksSince(seq, ct uint64) ([]coin.SignedBlock, error) {
 	var blocks []coin.SignedBlock
 
@@ -535,6 +602,9 @@ func (vs *Visor) GetSignedBlocksSince(seq, ct uint64) ([]coin.SignedBlock, error
 			i := seq + 1 + j
 			b, err := vs.blockchain.GetSignedBlockBySeq(tx, i)
 			if err != nil {
+				if len(blocks) != 0 && (IsErrPruned(err) || IsErrBackfilling(err)) {
+					return nil
+				}
 				return err
 			}
 
@@ -684,6 +754,126 @@ func (vs *Visor) GetBlocksInRangeVerbose(start, end uint64) ([]coin.SignedBlock,
 	return blocks, inputs, nil
 }
 
+// GetBlocksInTimeRange returns the blocks with a time between start and end, including both start and end.
+// Returns the empty slice if no block has a time in the range.
+func (vs *Visor) GetBlocksInTimeRange(start, end uint64) ([]coin.SignedBlock, error) {
+	var blocks []coin.SignedBlock
+
+	if err := vs.db.View("GetBlocksInTimeRange", func(tx *dbutil.Tx) error {
+		first, last, ok, err := vs.blockSeqsInTimeRange(tx, start, end)
+		if err != nil || !ok {
+			return err
+		}
+
+		blocks, err = vs.blockchain.GetBlocksInRange(tx, first, last)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+
+	return blocks, nil
+}
+
+// GetBlocksInTimeRangeVerbose returns the blocks with a time between start and end, including both start and end.
+// Also returns the verbose transaction input data for transactions in these blocks.
+// Returns the empty slice if no block has a time in the range.
+func (vs *Visor) GetBlocksInTimeRangeVerbose(start, end uint64) ([]coin.SignedBlock, [][][]TransactionInput, error) {
+	var blocks []coin.SignedBlock
+	var inputs [][][]TransactionInput
+
+	if err := vs.db.View("GetBlocksInTimeRangeVerbose", func(tx *dbutil.Tx) error {
+		first, last, ok, err := vs.blockSeqsInTimeRange(tx, start, end)
+		if err != nil || !ok {
+			return err
+		}
+
+		blocks, inputs, err = vs.getBlocksVerbose(tx, func(tx *dbutil.Tx) ([]coin.SignedBlock, error) {
+			return vs.blockchain.GetBlocksInRange(tx, first, last)
+		})
+		return err
+	}); err != nil {
+		return nil, nil, err
+	}
+
+	return blocks, inputs, nil
+}
+
+// blockSeqsInTimeRange returns the seqs of the first and the last block with a time between start and end, inclusive.
+// Returns false if no block has a time in the range.
+// The historydb block time index is used if it covers the whole chain, otherwise the blocks are binary searched,
+// which is valid because block times never decrease.
+func (vs *Visor) blockSeqsInTimeRange(tx *dbutil.Tx, start, end uint64) (uint64, uint64, bool, error) {
+	if start > end {
+		return 0, 0, false, nil
+	}
+
+	indexed, err := vs.history.BlockTimesIndexed(tx)
+	if err != nil {
+		return 0, 0, false, err
+	}
+
+	if indexed {
+		return vs.history.GetBlockSeqsInTimeRange(tx, start, end)
+	}
+
+	return vs.searchBlockSeqsInTimeRange(tx, start, end)
+}
+
+// searchBlockSeqsInTimeRange binary searches the blockchain for the blocks with a time between start and end, inclusive.
+// The block headers are searched, since they are kept for the blocks whose bodies have been pruned or not backfilled yet.
+func (vs *Visor) searchBlockSeqsInTimeRange(tx *dbutil.Tx, start, end uint64) (uint64, uint64, bool, error) {
+	headSeq, ok, err := vs.blockchain.HeadSeq(tx)
+	if err != nil {
+		return 0, 0, false, err
+	}
+	if !ok {
+		return 0, 0, false, nil
+	}
+
+	n := int(headSeq + 1)
+
+	// searchErr records the first error from the search predicate, since sort.Search can't return it
+	var searchErr error
+	blockTimeAtLeast := func(t uint64) func(int) bool {
+		return func(i int) bool {
+			if searchErr != nil {
+				return true
+			}
+
+			h, err := vs.blockchain.GetBlockHeaderBySeq(tx, uint64(i))
+			if err != nil {
+				searchErr = err
+				return true
+			}
+			if h == nil {
+				searchErr = fmt.Errorf("block seq=%d doesn't exist", i)
+				return true
+			}
+
+			return h.Time >= t
+		}
+	}
+
+	first := sort.Search(n, blockTimeAtLeast(start))
+	if searchErr != nil {
+		return 0, 0, false, searchErr
+	}
+
+	after := n
+	if end != math.MaxUint64 {
+		after = sort.Search(n, blockTimeAtLeast(end+1))
+		if searchErr != nil {
+			return 0, 0, false, searchErr
+		}
+	}
+
+	if first >= after {
+		return 0, 0, false, nil
+	}
+
+	return uint64(first), uint64(after - 1), true, nil
+}
+
 // GetLastBlocks returns last N blocks
 func (vs *Visor) GetLastBlocks(num uint64) ([]coin.SignedBlock, error) {
 	var blocks []coin.SignedBlock
@@ -955,6 +1145,27 @@ func NewConfirmedTxFilter(isConfirmed bool) TxFilter {
 	}}
 }
 
+// NewTimeRangeTxFilter collects the transactions with a time between start and end, including both start and end.
+// The time of a confirmed transaction is the time of its block, the time of an unconfirmed transaction
+// is the time it was received.
+func NewTimeRangeTxFilter(start, end uint64) TxFilter {
+	return TimeRangeFilter{
+		Start: start,
+		End:   end,
+	}
+}
+
+// TimeRangeFilter filters by transaction time
+type TimeRangeFilter struct {
+	Start uint64
+	End   uint64
+}
+
+// Match implements the TxFilter interface
+func (tf TimeRangeFilter) Match(tx *Transaction) bool {
+	return tx.Time >= tf.Start && tx.Time <= tf.End
+}
+
 // GetTransactions returns transactions that can pass the filters.
 // If no filters is provided, returns all transactions.
 func (vs *Visor) GetTransactions(flts []TxFilter) ([]Transaction, error) {
@@ -1025,8 +1236,12 @@ func (vs *Visor) getTransactions(tx *dbutil.Tx, flts []TxFilter) ([]Transaction,
 	// Accumulates all addresses in address filters
 	addrs := accumulateAddressInFilter(addrFlts)
 
-	// Traverses all transactions to do collection if there's no address filter.
+	// Traverses the transactions of the blocks in the time range if there's no address filter
+	// but there is a time range filter, otherwise traverses all transactions.
 	if len(addrs) == 0 {
+		if start, end, ok := intersectTimeRangeFilters(otherFlts); ok {
+			return vs.traverseTxnsInTimeRange(tx, start, end, otherFlts)
+		}
 		return vs.traverseTxns(tx, otherFlts)
 	}
 
@@ -1088,6 +1303,30 @@ func accumulateAddressInFilter(afs []AddrsFilter) []cipher.Address {
 	return addrs
 }
 
+// intersectTimeRangeFilters returns the time range matched by all of the TimeRangeFilters.
+// Returns false if there are no TimeRangeFilters.
+func intersectTimeRangeFilters(flts []TxFilter) (uint64, uint64, bool) {
+	var start uint64
+	end := uint64(math.MaxUint64)
+	found := false
+	for _, f := range flts {
+		tf, ok := f.(TimeRangeFilter)
+		if !ok {
+			continue
+		}
+
+		found = true
+		if tf.Start > start {
+			start = tf.Start
+		}
+		if tf.End < end {
+			end = tf.End
+		}
+	}
+
+	return start, end, found
+}
+
 // getTransactionsForAddresses returns all addresses related transactions.
 // Including both confirmed and unconfirmed transactions.
 func (vs *Visor) getTransactionsForAddresses(tx *dbutil.Tx, addrs []cipher.Address) (map[cipher.Address][]Transaction, error) {
@@ -1248,6 +1487,81 @@ func (vs *Visor) traverseTxns(tx *dbutil.Tx, flts []TxFilter) ([]Transaction, er
 	return txns, nil
 }
 
+// traverseTxnsInTimeRange traverses the transactions of the blocks with a time between start and end
+// and the unconfirmed tx pool in db, returns transactions that can pass the filters.
+func (vs *Visor) traverseTxnsInTimeRange(tx *dbutil.Tx, start, end uint64, flts []TxFilter) ([]Transaction, error) {
+	// Get the head block seq, for calculating the tx status
+	headBkSeq, ok, err := vs.blockchain.HeadSeq(tx)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, errors.New("No head block seq")
+	}
+
+	matchAll := func(txn *Transaction) bool {
+		for _, f := range flts {
+			if !f.Match(txn) {
+				return false
+			}
+		}
+		return true
+	}
+
+	var txns []Transaction
+
+	first, last, ok, err := vs.blockSeqsInTimeRange(tx, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	if ok {
+		blocks, err := vs.blockchain.GetBlocksInRange(tx, first, last)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, b := range blocks {
+			h := headBkSeq - b.Seq() + 1
+			for _, t := range b.Body.Transactions {
+				txn := Transaction{
+					Transaction: t,
+					Status:      NewConfirmedTransactionStatus(h, b.Seq()),
+					Time:        b.Time(),
+				}
+
+				if matchAll(&txn) {
+					txns = append(txns, txn)
+				}
+			}
+		}
+	}
+
+	txns = sortTxns(txns)
+
+	// Gets all unconfirmed transactions
+	unconfirmedTxns, err := vs.unconfirmed.GetFiltered(tx, func(txn UnconfirmedTransaction) bool {
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, ux := range unconfirmedTxns {
+		txn := Transaction{
+			Transaction: ux.Transaction,
+			Status:      NewUnconfirmedTransactionStatus(),
+			Time:        uint64(timeutil.NanoToTime(ux.Received).Unix()),
+		}
+
+		if matchAll(&txn) {
+			txns = append(txns, txn)
+		}
+	}
+
+	return txns, nil
+}
+
 // Sort transactions by block seq, if equal then compare hash
 func sortTxns(txns []Transaction) []Transaction {
 	sort.Slice(txns, func(i, j int) bool {
@@ -1611,6 +1925,11 @@ func (vs *Visor) getTransactionInputs(tx *dbutil.Tx, feeCalcTime uint64, inputs
 	}
 
 	uxOuts, err := vs.history.GetUxOuts(tx, inputs)
+	if IsErrPruned(err) || IsErrBackfilling(err) {
+		// Without the history indexes, only the inputs that are still unspent can be resolved.
+		// The inputs of unconfirmed transactions and of transactions being created are always unspent.
+		uxOuts, err = vs.getUnspentTransactionInputs(tx, inputs, err)
+	}
 	if err != nil {
 		logger.WithError(err).Error("getTransactionInputs GetUxOuts failed")
 		return nil, err
@@ -1629,6 +1948,27 @@ func (vs *Visor) getTransactionInputs(tx *dbutil.Tx, feeCalcTime uint64, inputs
 	return ret, nil
 }
 
+// getUnspentTransactionInputs returns the inputs from the unspent pool.
+// If an input has been spent, historyErr is returned, since spent outputs are only kept in the history.
+func (vs *Visor) getUnspentTransactionInputs(tx *dbutil.Tx, inputs []cipher.SHA256, historyErr error) ([]historydb.UxOut, error) {
+	uxOuts := make([]historydb.UxOut, len(inputs))
+	for i, h := range inputs {
+		ux, err := vs.blockchain.Unspent().Get(tx, h)
+		if err != nil {
+			return nil, err
+		}
+		if ux == nil {
+			return nil, historyErr
+		}
+
+		uxOuts[i] = historydb.UxOut{
+			Out: *ux,
+		}
+	}
+
+	return uxOuts, nil
+}
+
 // GetHeadBlock gets head block.
 func (vs Visor) GetHeadBlock() (*coin.SignedBlock, error) {
 	var b *coin.SignedBlock
@@ -1848,50 +2188,78 @@ func (vs Visor) GetBalanceOfAddrs(addrs []cipher.Address) ([]wallet.BalancePair,
 		return nil, nil
 	}
 
-	auxs := make(coin.AddressUxOuts, len(addrs))
-	recvUxs := make(coin.AddressUxOuts, len(addrs))
-	var uxa coin.UxArray
-	var head *coin.SignedBlock
+	var bps []wallet.BalancePair
 
 	if err := vs.db.View("GetBalanceOfAddrs", func(tx *dbutil.Tx) error {
 		var err error
-		head, err = vs.blockchain.Head(tx)
-		if err != nil {
-			return err
-		}
+		_, bps, err = vs.balancesOfAddrs(tx, addrs)
+		return err
+	}); err != nil {
+		return nil, err
+	}
 
-		// Get all transactions from the unconfirmed pool
-		txns, err := vs.unconfirmed.AllRawTransactions(tx)
-		if err != nil {
-			return err
-		}
+	return bps, nil
+}
 
-		// Create predicted unspent outputs from the unconfirmed transactions
-		recvUxs, err = txnOutputsForAddrs(head.Head, addrs, txns)
-		if err != nil {
-			return err
-		}
+// balancesOfAddrs returns the head block and the balance pairs of given addresses.
+// The predicted balance subtracts the outputs spent by unconfirmed transactions and adds the outputs they create.
+func (vs Visor) balancesOfAddrs(tx *dbutil.Tx, addrs []cipher.Address) (*coin.SignedBlock, []wallet.BalancePair, error) {
+	head, err := vs.blockchain.Head(tx)
+	if err != nil {
+		return nil, nil, err
+	}
 
-		var inputs []cipher.SHA256
-		for _, txn := range txns {
-			inputs = append(inputs, txn.In...)
-		}
+	// Get all transactions from the unconfirmed pool
+	txns, err := vs.unconfirmed.AllRawTransactions(tx)
+	if err != nil {
+		return nil, nil, err
+	}
 
-		// Get unspents for the inputs being spent
-		uxa, err = vs.blockchain.Unspent().GetArray(tx, inputs)
+	// Create predicted unspent outputs from the unconfirmed transactions
+	recvUxs, err := txnOutputsForAddrs(head.Head, addrs, txns)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var inputs []cipher.SHA256
+	for _, txn := range txns {
+		inputs = append(inputs, txn.In...)
+	}
+
+	// Get unspents for the inputs being spent
+	uxa, err := vs.blockchain.Unspent().GetArray(tx, inputs)
+	if err != nil {
+		return nil, nil, fmt.Errorf("GetArray failed when checking addresses balance: %v", err)
+	}
+
+	// Get unspents owned by the addresses. The coin hours depend on the age of each output,
+	// so the outputs are still needed, but addresses without outputs in the address balance index are skipped
+	lookupAddrs := addrs
+	ready, err := vs.blockchain.Unspent().AddrBalanceIndexReady(tx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if ready {
+		balances, err := vs.blockchain.Unspent().GetAddrBalances(tx, addrs)
 		if err != nil {
-			return fmt.Errorf("GetArray failed when checking addresses balance: %v", err)
+			return nil, nil, fmt.Errorf("GetAddrBalances failed when checking addresses balance: %v", err)
 		}
 
-		// Get unspents owned by the addresses
-		auxs, err = vs.blockchain.Unspent().GetUnspentsOfAddrs(tx, addrs)
-		if err != nil {
-			return fmt.Errorf("GetUnspentsOfAddrs failed when checking addresses balance: %v", err)
+		lookupAddrs = make([]cipher.Address, 0, len(addrs))
+		for i, b := range balances {
+			if b.Outputs != 0 {
+				lookupAddrs = append(lookupAddrs, addrs[i])
+			}
 		}
+	}
 
-		return nil
-	}); err != nil {
-		return nil, err
+	auxs := make(coin.AddressUxOuts)
+	if len(lookupAddrs) != 0 {
+		auxs, err = vs.blockchain.Unspent().GetUnspentsOfAddrs(tx, lookupAddrs)
+		if err != nil {
+			return nil, nil, fmt.Errorf("GetUnspentsOfAddrs failed when checking addresses balance: %v", err)
+		}
 	}
 
 	// Build all unconfirmed transaction inputs that are associated with the addresses
@@ -1908,23 +2276,19 @@ func (vs Visor) GetBalanceOfAddrs(addrs []cipher.Address) ([]wallet.BalancePair,
 		}
 	}
 
-	var bps []wallet.BalancePair
+	bps := make([]wallet.BalancePair, 0, len(addrs))
 
 	headTime := head.Time()
 	for _, addr := range addrs {
-		uxs, ok := auxs[addr]
-		if !ok {
-			bps = append(bps, wallet.BalancePair{})
-			continue
-		}
-
+		// Addresses skipped by the lookup have no confirmed outputs, but may still receive unconfirmed outputs
+		uxs := auxs[addr]
 		outUxs := spendUxs[addr]
 		inUxs := recvUxs[addr]
 		predictedUxs := uxs.Sub(outUxs).Add(inUxs)
 
 		coins, err := uxs.Coins()
 		if err != nil {
-			return nil, fmt.Errorf("uxs.Coins failed: %v", err)
+			return nil, nil, fmt.Errorf("uxs.Coins failed: %v", err)
 		}
 
 		coinHours, err := uxs.CoinHours(headTime)
@@ -1933,26 +2297,26 @@ func (vs Visor) GetBalanceOfAddrs(addrs []cipher.Address) ([]wallet.BalancePair,
 			case coin.ErrAddEarnedCoinHoursAdditionOverflow:
 				coinHours = 0
 			default:
-				return nil, fmt.Errorf("uxs.CoinHours failed: %v", err)
+				return nil, nil, fmt.Errorf("uxs.CoinHours failed: %v", err)
 			}
 		}
 
 		pcoins, err := predictedUxs.Coins()
 		if err != nil {
-			return nil, fmt.Errorf("predictedUxs.Coins failed: %v", err)
+			return nil, nil, fmt.Errorf("predictedUxs.Coins failed: %v", err)
 		}
 
 		pcoinHours, err := predictedUxs.CoinHours(headTime)
 		if err != nil {
 			switch err {
 			case coin.ErrAddEarnedCoinHoursAdditionOverflow:
-				coinHours = 0
+				pcoinHours = 0
 			default:
-				return nil, fmt.Errorf("predictedUxs.CoinHours failed: %v", err)
+				return nil, nil, fmt.Errorf("predictedUxs.CoinHours failed: %v", err)
 			}
 		}
 
-		bp := wallet.BalancePair{
+		bps = append(bps, wallet.BalancePair{
 			Confirmed: wallet.Balance{
 				Coins: coins,
 				Hours: coinHours,
@@ -1961,12 +2325,10 @@ func (vs Visor) GetBalanceOfAddrs(addrs []cipher.Address) ([]wallet.BalancePair,
 				Coins: pcoins,
 				Hours: pcoinHours,
 			},
-		}
-
-		bps = append(bps, bp)
+		})
 	}
 
-	return bps, nil
+	return head, bps, nil
 }
 
 // GetUnspentsOfAddrs returns unspent outputs of multiple addresses
@@ -2298,16 +2660,139 @@ func (vs *Visor) GetUnspentOutputsSummary(filters []OutputsFilter) (*UnspentOutp
 	}, nil
 }
 
-// GetRichlist returns a Richlist
-func (vs *Visor) GetRichlist(includeDistribution bool) (Richlist, error) {
-	rbOuts, err := vs.GetUnspentOutputsSummary(nil)
+// GetRichlist returns n addresses of the Richlist, starting at offset, and the number of addresses in the Richlist.
+// If n is 0, all of the addresses after offset are returned.
+// If includeDistribution is false, the distribution addresses are excluded.
+func (vs *Visor) GetRichlist(includeDistribution bool, offset, n uint64) (Richlist, uint64, error) {
+	lockedAddrs := params.GetLockedDistributionAddressesDecoded()
+	lockedAddrsMap := make(map[cipher.Address]struct{}, len(lockedAddrs))
+	for _, a := range lockedAddrs {
+		lockedAddrsMap[a] = struct{}{}
+	}
+
+	excludedAddrs := make(map[cipher.Address]struct{})
+	if !includeDistribution {
+		for _, a := range lockedAddrs {
+			excludedAddrs[a] = struct{}{}
+		}
+		for _, a := range params.GetUnlockedDistributionAddressesDecoded() {
+			excludedAddrs[a] = struct{}{}
+		}
+	}
+
+	var richlist Richlist
+	var total uint64
+	if err := vs.db.View("GetRichlist", func(tx *dbutil.Tx) error {
+		ready, err := vs.blockchain.Unspent().AddrBalanceIndexReady(tx)
+		if err != nil {
+			return err
+		}
+
+		if ready {
+			richlist, total, err = vs.getRichlistPage(tx, lockedAddrsMap, excludedAddrs, offset, n)
+			return err
+		}
+
+		// The address balance index of a database opened read-only may not be built,
+		// aggregate the unspent outputs instead
+		richlist, err = vs.getRichlistFromUnspents(tx, lockedAddrsMap, excludedAddrs)
+		if err != nil {
+			return err
+		}
+
+		total = uint64(len(richlist))
+		richlist = richlist.Page(offset, n)
+		return nil
+	}); err != nil {
+		return nil, 0, err
+	}
+
+	return richlist, total, nil
+}
+
+// errRichlistPageDone stops the iteration of the address balance index once a richlist page is complete
+var errRichlistPageDone = errors.New("richlist page done")
+
+// getRichlistPage returns a page of the richlist from the address balance index, and the number of addresses in the richlist
+func (vs *Visor) getRichlistPage(tx *dbutil.Tx, lockedAddrs, excludedAddrs map[cipher.Address]struct{}, offset, n uint64) (Richlist, uint64, error) {
+	total, err := vs.blockchain.Unspent().AddressCount(tx)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	excluded := make([]cipher.Address, 0, len(excludedAddrs))
+	for a := range excludedAddrs {
+		excluded = append(excluded, a)
+	}
+
+	balances, err := vs.blockchain.Unspent().GetAddrBalances(tx, excluded)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	for _, b := range balances {
+		if b.Outputs != 0 {
+			total--
+		}
+	}
+
+	// The index orders addresses with the same coins by their bytes, but the richlist orders locked
+	// addresses first, so addresses with the same coins are collected before they are added to the page
+	var richlist Richlist
+	var sameCoins Richlist
+	var i uint64
+	addSameCoins := func() bool {
+		sort.SliceStable(sameCoins, func(a, b int) bool {
+			return sameCoins[a].Locked && !sameCoins[b].Locked
+		})
+
+		for _, b := range sameCoins {
+			if i >= offset && (n == 0 || i < offset+n) {
+				richlist = append(richlist, b)
+			}
+			i++
+		}
+		sameCoins = sameCoins[:0]
+
+		return n != 0 && i >= offset+n
+	}
+
+	if err := vs.blockchain.Unspent().ForEachAddrBalanceByCoins(tx, func(addr cipher.Address, b blockdb.AddressBalance) error {
+		if _, ok := excludedAddrs[addr]; ok {
+			return nil
+		}
+
+		if len(sameCoins) != 0 && sameCoins[0].Coins != b.Coins && addSameCoins() {
+			return errRichlistPageDone
+		}
+
+		_, locked := lockedAddrs[addr]
+		sameCoins = append(sameCoins, RichlistBalance{
+			Address: addr,
+			Coins:   b.Coins,
+			Locked:  locked,
+		})
+
+		return nil
+	}); err != nil && err != errRichlistPageDone {
+		return nil, 0, err
+	}
+
+	addSameCoins()
+
+	return richlist, total, nil
+}
+
+// getRichlistFromUnspents returns the richlist aggregated from all of the unspent outputs
+func (vs *Visor) getRichlistFromUnspents(tx *dbutil.Tx, lockedAddrs, excludedAddrs map[cipher.Address]struct{}) (Richlist, error) {
+	uxs, err := vs.blockchain.Unspent().GetAll(tx)
 	if err != nil {
 		return nil, err
 	}
 
 	// Build a map from addresses to total coins held
 	allAccounts := map[cipher.Address]uint64{}
-	for _, out := range rbOuts.Confirmed {
+	for _, out := range uxs {
 		if _, ok := allAccounts[out.Body.Address]; ok {
 			var err error
 			allAccounts[out.Body.Address], err = mathutil.AddUint64(allAccounts[out.Body.Address], out.Body.Coins)
@@ -2319,26 +2804,12 @@ func (vs *Visor) GetRichlist(includeDistribution bool) (Richlist, error) {
 		}
 	}
 
-	lockedAddrs := params.GetLockedDistributionAddressesDecoded()
-	addrsMap := make(map[cipher.Address]struct{}, len(lockedAddrs))
-	for _, a := range lockedAddrs {
-		addrsMap[a] = struct{}{}
-	}
-
-	richlist, err := NewRichlist(allAccounts, addrsMap)
+	richlist, err := NewRichlist(allAccounts, lockedAddrs)
 	if err != nil {
 		return nil, err
 	}
 
-	if !includeDistribution {
-		unlockedAddrs := params.GetUnlockedDistributionAddressesDecoded()
-		for _, a := range unlockedAddrs {
-			addrsMap[a] = struct{}{}
-		}
-		richlist = richlist.FilterAddresses(addrsMap)
-	}
-
-	return richlist, nil
+	return richlist.FilterAddresses(excludedAddrs), nil
 }
 
 // WithUpdateTx executes a function inside of a db.Update transaction.
@@ -2720,3 +2720,137 @@ func TestFbyHashes(t *testing.T) {
 		require.Equal(t, outs, tt.want)
 	}
 }
+
+func TestGetBlocksInTimeRange(t *testing.T) {
+	db, shutdown := prepareDB(t)
+	defer shutdown()
+
+	cfg := NewConfig()
+	cfg.IsBlockPublisher = true
+	cfg.BlockchainPubkey = genPublic
+	cfg.BlockchainSeckey = genSecret
+	cfg.GenesisAddress = genAddress
+	cfg.GenesisCoinVolume = genCoins
+	cfg.GenesisTimestamp = genTime
+
+	v, err := New(cfg, db, nil)
+	require.NoError(t, err)
+
+	gb := addGenesisBlockToVisor(t, v)
+
+	createAndExecuteBlock := func(txn coin.Transaction, when uint64) coin.SignedBlock {
+		_, softErr, err := v.InjectForeignTransaction(txn)
+		require.NoError(t, err)
+		require.Nil(t, softErr)
+
+		var sb coin.SignedBlock
+		err = db.Update("", func(tx *dbutil.Tx) error {
+			var err error
+			sb, err = v.createBlock(tx, when)
+			if err != nil {
+				return err
+			}
+
+			return v.executeSignedBlock(tx, sb)
+		})
+		require.NoError(t, err)
+		return sb
+	}
+
+	uxs := coin.CreateUnspents(gb.Head, gb.Body.Transactions[0])
+	sb := createAndExecuteBlock(makeUnspentsTxn(t, uxs, []cipher.SecKey{genSecret}, genAddress, 4, params.UserVerifyTxn.MaxDropletPrecision), genTime+1000)
+	uxs = coin.CreateUnspents(sb.Head, sb.Body.Transactions[0])
+	coins := uxs[0].Body.Coins
+
+	blocks := []coin.SignedBlock{*gb, sb}
+	for i, when := range []uint64{genTime + 1010, genTime + 1020} {
+		txn := makeSpendTxWithFee(t, coin.UxArray{uxs[i]}, []cipher.SecKey{genSecret}, testutil.MakeAddress(), coins, 0)
+		blocks = append(blocks, createAndExecuteBlock(txn, when))
+	}
+
+	// An unconfirmed transaction, received now
+	unconfirmedTxn := makeSpendTxWithFee(t, coin.UxArray{uxs[2]}, []cipher.SecKey{genSecret}, testutil.MakeAddress(), coins, 0)
+	_, softErr, err := v.InjectForeignTransaction(unconfirmedTxn)
+	require.NoError(t, err)
+	require.Nil(t, softErr)
+
+	cases := []struct {
+		name   string
+		start  uint64
+		end    uint64
+		blocks []coin.SignedBlock
+	}{
+		{name: "all", start: 0, end: math.MaxUint64, blocks: blocks},
+		{name: "exact", start: genTime + 1000, end: genTime + 1010, blocks: blocks[1:3]},
+		{name: "between", start: genTime + 1, end: genTime + 1015, blocks: blocks[1:3]},
+		{name: "after", start: genTime + 1020, end: math.MaxUint64, blocks: blocks[3:]},
+		{name: "gap", start: genTime + 1, end: genTime + 999},
+		{name: "start after end", start: genTime + 1020, end: genTime},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			bs, err := v.GetBlocksInTimeRange(tc.start, tc.end)
+			require.NoError(t, err)
+			require.Equal(t, tc.blocks, bs)
+
+			bs, inputs, err := v.GetBlocksInTimeRangeVerbose(tc.start, tc.end)
+			require.NoError(t, err)
+			require.Equal(t, tc.blocks, bs)
+			require.Len(t, inputs, len(tc.blocks))
+
+			// The binary search over the blocks finds the same range as the block time index
+			err = db.View("", func(tx *dbutil.Tx) error {
+				indexed, err := v.history.BlockTimesIndexed(tx)
+				require.NoError(t, err)
+				require.True(t, indexed)
+
+				first, last, ok, err := v.history.GetBlockSeqsInTimeRange(tx, tc.start, tc.end)
+				require.NoError(t, err)
+
+				searchFirst, searchLast, searchOk, err := v.searchBlockSeqsInTimeRange(tx, tc.start, tc.end)
+				require.NoError(t, err)
+				require.Equal(t, ok, searchOk)
+				require.Equal(t, ok, len(tc.blocks) != 0)
+				if ok {
+					require.Equal(t, first, searchFirst)
+					require.Equal(t, last, searchLast)
+				}
+				return nil
+			})
+			require.NoError(t, err)
+		})
+	}
+
+	// The time range filter returns the transactions of the blocks in the range,
+	// and the unconfirmed transactions received in the range
+	txns, err := v.GetTransactions([]TxFilter{NewTimeRangeTxFilter(genTime+1, genTime+1015)})
+	require.NoError(t, err)
+	require.Len(t, txns, 2)
+	require.Equal(t, blocks[1].Body.Transactions[0], txns[0].Transaction)
+	require.Equal(t, genTime+1000, txns[0].Time)
+	require.Equal(t, NewConfirmedTransactionStatus(3, 1), txns[0].Status)
+	require.Equal(t, blocks[2].Body.Transactions[0], txns[1].Transaction)
+	require.Equal(t, NewConfirmedTransactionStatus(2, 2), txns[1].Status)
+
+	txns, err = v.GetTransactions([]TxFilter{
+		NewTimeRangeTxFilter(genTime+1010, math.MaxUint64),
+		NewTimeRangeTxFilter(0, genTime+1010),
+	})
+	require.NoError(t, err)
+	require.Len(t, txns, 1)
+	require.Equal(t, blocks[2].Body.Transactions[0], txns[0].Transaction)
+
+	txns, err = v.GetTransactions([]TxFilter{NewTimeRangeTxFilter(genTime+1021, math.MaxUint64)})
+	require.NoError(t, err)
+	require.Len(t, txns, 1)
+	require.Equal(t, unconfirmedTxn, txns[0].Transaction)
+	require.False(t, txns[0].Status.Confirmed)
+
+	txns, err = v.GetTransactions([]TxFilter{
+		NewTimeRangeTxFilter(genTime+1, genTime+1015),
+		NewConfirmedTxFilter(false),
+	})
+	require.NoError(t, err)
+	require.Empty(t, txns)
+}